@@ -7,7 +7,12 @@ Application Server bootstrapper.
 package main
 
 import (
+	"flag"
+	"fmt"
+	"log"
 	"math/rand"
+	"os"
+	"strings"
 	"time"
 
 	"main/src/goadmin"
@@ -18,6 +23,106 @@ func main() {
 	// it is a good idea to initialize random seed
 	rand.Seed(time.Now().UnixNano())
 
+	if len(os.Args) > 1 && os.Args[1] == "upload-assets" {
+		// upload static resources to the CDN origin on deploy, then exit
+		if err := myapp.UploadAssets(goadmin.LoadAppConfig()); err != nil {
+			log.Fatalf("Error uploading static resources: %s", err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		// run (or print, with --dry-run) schema migrations of the SQL database, then exit
+		opts := &myapp.MigrateOptions{}
+		for _, arg := range os.Args[2:] {
+			switch arg {
+			case "--dry-run":
+				opts.DryRun = true
+			case "--allow-destructive":
+				opts.AllowDestructive = true
+			default:
+				log.Fatalf("Usage: %s migrate [--dry-run] [--allow-destructive]", os.Args[0])
+			}
+		}
+		if err := myapp.Migrate(goadmin.LoadAppConfig(), opts); err != nil {
+			log.Fatalf("Error migrating database schema: %s", err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "reencrypt" {
+		// encrypt sensitive fields with the current key (e.g. after a key rotation), then exit
+		if _, err := myapp.ReencryptFields(goadmin.LoadAppConfig()); err != nil {
+			log.Fatalf("Error re-encrypting fields: %s", err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "verify-audit" {
+		// verify the chain of signed audit entries found in log files, then exit
+		if len(os.Args) < 3 {
+			log.Fatalf("Usage: %s verify-audit <log file>... (oldest first)", os.Args[0])
+		}
+		result, err := myapp.VerifyAuditLog(goadmin.LoadAppConfig(), os.Args[2:])
+		if err != nil {
+			log.Fatalf("Error verifying audit log: %s", err)
+		}
+		for _, p := range result.Problems {
+			fmt.Println(p)
+		}
+		for _, chain := range result.Chains {
+			fmt.Printf("chain [%s]: %d signed audit entries, from #%d to #%d (last signature %s)\n",
+				chain.Id, chain.Entries, chain.FirstSeq, chain.LastSeq, chain.LastSig)
+		}
+		fmt.Printf("%d signed audit entries, %d problem(s)\n", result.Entries, len(result.Problems))
+		if len(result.Problems) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "gen-fixtures" {
+		// seed groups and users for load tests and benchmarks, then exit
+		opts := &myapp.FixtureOptions{}
+		flags := flag.NewFlagSet("gen-fixtures", flag.ExitOnError)
+		flags.IntVar(&opts.Groups, "groups", 100, "number of groups")
+		flags.IntVar(&opts.Users, "users", 10000, "number of users, spread over the groups")
+		flags.StringVar(&opts.Password, "password", "fixture", "password of the users")
+		flags.BoolVar(&opts.Force, "force", false, "generate fixtures even if dev_mode is off")
+		flags.Parse(os.Args[2:])
+		if _, err := myapp.GenerateFixtures(goadmin.LoadAppConfig(), opts); err != nil {
+			log.Fatalf("Error generating fixtures: %s", err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "gen-api-client" {
+		// generate client packages of the JSON API from its OpenAPI spec, then exit
+		opts := &myapp.ApiClientOptions{}
+		var langs string
+		flags := flag.NewFlagSet("gen-api-client", flag.ExitOnError)
+		flags.StringVar(&opts.OutDir, "out", "api-client", "directory generated files are written to")
+		flags.StringVar(&langs, "lang", "go,ts", "comma-separated languages of the clients: go, ts")
+		flags.StringVar(&opts.GoPackage, "go-package", "adminapi", "package name of the Go client")
+		flags.StringVar(&opts.Spec, "spec", "", "JSON file of the OpenAPI spec to generate clients from, the spec of this build if empty")
+		flags.Parse(os.Args[2:])
+		opts.Langs = strings.Split(langs, ",")
+		files, err := myapp.GenerateApiClients(opts)
+		if err != nil {
+			log.Fatalf("Error generating API clients: %s", err)
+		}
+		for _, file := range files {
+			fmt.Println(file)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "print-config" {
+		// print the effective configuration (configuration file + profile of APP_ENV), secrets masked, then exit
+		fmt.Print(goadmin.EffectiveConfig(goadmin.LoadAppConfig()))
+		return
+	}
+
 	// start Echo server with custom bootstrappers
 	var bootstrappers = []goadmin.IBootstrapper{
 		myapp.Bootstrapper,
@@ -0,0 +1,263 @@
+/*
+Package apptest provides a test harness to write integration tests for HTTP handlers of goadmin applications.
+
+A Harness bootstraps the application (without starting the HTTP server) from an inline configuration, keeps cookies
+(and thus the session) between requests, and records the last rendered template so that tests can assert on it.
+
+	h := apptest.New(t, apptest.SqliteInMemoryConfig, myapp.Bootstrapper)
+	h.PostForm("/cp/login", url.Values{"username": {"admin@local"}, "password": {"S3cr3t"}})
+	resp := h.Get("/cp/groups")
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertTemplate("myapp:layout:cp_groups")
+
+@author Thanh Nguyen <btnguyen2k@gmail.com>
+@since template-r5
+*/
+package apptest
+
+import (
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"main/src/goadmin"
+)
+
+// SqliteInMemoryConfig is a ready-to-use configuration that stores myapp's data in an in-memory SQLite database.
+const SqliteInMemoryConfig = `
+app {
+  name     : "apptest"
+  shortname: "apptest"
+  version  : "0.0.0"
+  desc     : "apptest"
+}
+timezone = "UTC"
+dev_mode = false
+goadmin {
+  session_key: "apptest_s3ss10n_k3y_apptest_s3ss"
+}
+http {
+  listen_addr = "127.0.0.1"
+  listen_port = 8080
+}
+myapp {
+  init {
+    admin_username = "admin@local"
+    admin_password = "S3cr3t"
+    admin_name     = "Administrator"
+  }
+  db {
+    type = "sqlite"
+    sqlite.root = ":memory:"
+  }
+  # forms are submitted without being rendered first
+  bot_guard.enabled = false
+}
+`
+
+var chdirLock sync.Mutex
+
+// ChdirProjectRoot changes the current working directory to the project root (the nearest parent directory
+// containing go.mod), so that relative paths of views, i18n and static files are resolved correctly.
+//
+// The original working directory is restored when the test finishes.
+func ChdirProjectRoot(t testing.TB) {
+	chdirLock.Lock()
+	defer chdirLock.Unlock()
+	curDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("cannot get current directory: %s", err)
+	}
+	for dir := curDir; ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			if err := os.Chdir(dir); err != nil {
+				t.Fatalf("cannot change directory to [%s]: %s", dir, err)
+			}
+			t.Cleanup(func() { os.Chdir(curDir) })
+			return
+		}
+		if dir == filepath.Dir(dir) {
+			t.Fatalf("cannot find project root from [%s]", curDir)
+		}
+	}
+}
+
+// New bootstraps the application from the supplied configurations and returns a Harness to send requests to it.
+func New(t testing.TB, appConfig string, bootstrappers ...goadmin.IBootstrapper) *Harness {
+	ChdirProjectRoot(t)
+	registry := goadmin.Bootstrap(goadmin.ParseAppConfig(appConfig), bootstrappers...)
+	t.Cleanup(registry.Close)
+	jar, _ := cookiejar.New(nil)
+	h := &Harness{t: t, Registry: registry, Echo: registry.EchoServer, jar: jar}
+	h.renderer = &recordingRenderer{Renderer: h.Echo.Renderer}
+	h.Echo.Renderer = h.renderer
+	return h
+}
+
+// Harness sends requests to the application and keeps cookies between requests, like a browser does.
+type Harness struct {
+	t        testing.TB
+	Registry *goadmin.Registry
+	Echo     *echo.Echo
+	jar      *cookiejar.Jar
+	renderer *recordingRenderer
+}
+
+var baseUrl, _ = url.Parse("http://localhost/")
+
+// csrfCookieName is name of the cookie Echo's CSRF middleware stores the token in.
+const csrfCookieName = "_csrf"
+
+// csrfToken returns the CSRF token stored in the cookie jar, creating one if there is none yet.
+func (h *Harness) csrfToken() string {
+	for _, cookie := range h.jar.Cookies(baseUrl) {
+		if cookie.Name == csrfCookieName {
+			return cookie.Value
+		}
+	}
+	token := "apptest-csrf-token"
+	h.jar.SetCookies(baseUrl, []*http.Cookie{{Name: csrfCookieName, Value: token, Path: "/"}})
+	return token
+}
+
+// Do sends a request to the application and returns the recorded response.
+//
+// Like a browser submitting a form rendered by the application, state-changing requests carry the CSRF token
+// (header X-CSRF-Token) unless the header has been set by the caller.
+func (h *Harness) Do(req *http.Request) *httptest.ResponseRecorder {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		if req.Header.Get(echo.HeaderXCSRFToken) == "" {
+			req.Header.Set(echo.HeaderXCSRFToken, h.csrfToken())
+		}
+	}
+	for _, cookie := range h.jar.Cookies(baseUrl) {
+		req.AddCookie(cookie)
+	}
+	h.renderer.reset()
+	resp := httptest.NewRecorder()
+	h.Echo.ServeHTTP(resp, req)
+	h.jar.SetCookies(baseUrl, resp.Result().Cookies())
+	return resp
+}
+
+// Get sends a GET request to the application.
+func (h *Harness) Get(path string) *httptest.ResponseRecorder {
+	return h.Do(httptest.NewRequest(http.MethodGet, path, nil))
+}
+
+// PostForm submits a form to the application.
+func (h *Harness) PostForm(path string, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	return h.Do(req)
+}
+
+// Login submits the login form with the supplied credentials and fails the test if login is not successful
+// (i.e. the response is not a redirect).
+func (h *Harness) Login(path, username, password string) {
+	resp := h.PostForm(path, url.Values{"username": {username}, "password": {password}})
+	if resp.Code != http.StatusFound {
+		h.t.Fatalf("login as [%s] failed: expected status %d but received %d", username, http.StatusFound, resp.Code)
+	}
+}
+
+// Reverse generates the URL path of a named route, prefixed with the base path the application is mounted under.
+func (h *Harness) Reverse(name string, params ...interface{}) string {
+	return h.Registry.Reverse(name, params...)
+}
+
+// LastTemplate returns name of the template rendered by the last request, empty if no template was rendered.
+func (h *Harness) LastTemplate() string {
+	return h.renderer.name
+}
+
+// LastData returns data passed to the template rendered by the last request.
+func (h *Harness) LastData() map[string]interface{} {
+	if m, ok := h.renderer.data.(map[string]interface{}); ok {
+		return m
+	}
+	return nil
+}
+
+// AssertStatus fails the test if the response status code does not match.
+func (h *Harness) AssertStatus(resp *httptest.ResponseRecorder, expected int) {
+	h.t.Helper()
+	if resp.Code != expected {
+		h.t.Fatalf("expected status %d but received %d", expected, resp.Code)
+	}
+}
+
+// AssertRedirect fails the test if the response is not a redirect to the expected path (query string is ignored).
+func (h *Harness) AssertRedirect(resp *httptest.ResponseRecorder, expectedPath string) {
+	h.t.Helper()
+	if resp.Code != http.StatusFound && resp.Code != http.StatusSeeOther {
+		h.t.Fatalf("expected a redirect but received status %d", resp.Code)
+	}
+	location := resp.Header().Get(echo.HeaderLocation)
+	if u, err := url.Parse(location); err != nil || u.Path != expectedPath {
+		h.t.Fatalf("expected redirect to [%s] but received [%s]", expectedPath, location)
+	}
+}
+
+// AssertBodyContains fails the test if the response body does not contain the expected string.
+func (h *Harness) AssertBodyContains(resp *httptest.ResponseRecorder, expected string) {
+	h.t.Helper()
+	if !strings.Contains(resp.Body.String(), expected) {
+		h.t.Fatalf("expected response body to contain [%s]", expected)
+	}
+}
+
+// AssertTemplate fails the test if the last request did not render the expected template.
+func (h *Harness) AssertTemplate(expected string) {
+	h.t.Helper()
+	if h.renderer.name != expected {
+		h.t.Fatalf("expected template [%s] but received [%s]", expected, h.renderer.name)
+	}
+}
+
+// AssertData fails the test if the data entry passed to the last rendered template does not contain the
+// expected string (e.g. AssertData("flashInfo", "has been created")).
+func (h *Harness) AssertData(key, expected string) {
+	h.t.Helper()
+	data := h.LastData()
+	if data == nil {
+		h.t.Fatalf("no template data rendered")
+	}
+	if v, ok := data[key].(string); !ok || !strings.Contains(v, expected) {
+		h.t.Fatalf("expected data [%s] to contain [%s] but received %#v", key, expected, data[key])
+	}
+}
+
+/*----------------------------------------------------------------------*/
+
+// recordingRenderer records name and data of the last rendered template before delegating to the real renderer.
+type recordingRenderer struct {
+	echo.Renderer
+	lock sync.Mutex
+	name string
+	data interface{}
+}
+
+func (r *recordingRenderer) reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.name, r.data = "", nil
+}
+
+// Render implements echo.Renderer.Render
+func (r *recordingRenderer) Render(w io.Writer, name string, data interface{}, c echo.Context) error {
+	r.lock.Lock()
+	r.name, r.data = name, data
+	r.lock.Unlock()
+	return r.Renderer.Render(w, name, data, c)
+}
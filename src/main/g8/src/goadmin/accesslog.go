@@ -0,0 +1,105 @@
+package goadmin
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// CtxAccessLogUser is the key under which modules store the username of the signed-in user in echo.Context, so
+	// that it is written to the access log (the "%u" field).
+	//
+	// Available since template-r5
+	CtxAccessLogUser = "access_log_user"
+
+	accessLogTimeFormat = "02/Jan/2006:15:04:05 -0700"
+)
+
+// initAccessLog installs the access log (configuration block http.access_log) if a destination is configured.
+func initAccessLog(registry *Registry) error {
+	conf := registry.AppConfig
+	var out io.Writer
+	switch file := conf.GetString("http.access_log.file", ""); file {
+	case "":
+		return nil
+	case "stdout", "-":
+		out = os.Stdout
+	case "stderr":
+		out = os.Stderr
+	default:
+		opts, err := rotatingFileOptions(conf, "http.access_log")
+		if err != nil {
+			return err
+		}
+		f, err := NewRotatingFile(file, opts)
+		if err != nil {
+			return err
+		}
+		registry.accessLog = f
+		out = f
+	}
+	registry.EchoServer.Pre(AccessLogMiddleware(out))
+	return nil
+}
+
+// AccessLogMiddleware writes a line per request to out in Combined Log Format, as nginx and Apache do:
+//
+//	remote_addr - remote_user [time_local] "request" status body_bytes_sent "http_referer" "http_user_agent"
+//
+// It should be installed with Echo.Pre, so that the original request URI is logged and requests not matching any
+// route are logged too.
+//
+// Available since template-r5
+func AccessLogMiddleware(out io.Writer) echo.MiddlewareFunc {
+	var lock sync.Mutex
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			if err := next(c); err != nil {
+				// the error response must be written before the status can be logged
+				c.Error(err)
+			}
+			req, resp := c.Request(), c.Response()
+			user, _ := c.Get(CtxAccessLogUser).(string)
+			line := fmt.Sprintf("%s - %s [%s] \"%s %s %s\" %d %d \"%s\" \"%s\"\n",
+				c.RealIP(), accessLogField(user), start.Format(accessLogTimeFormat),
+				req.Method, accessLogEscape(req.RequestURI), req.Proto, resp.Status, resp.Size,
+				accessLogField(req.Referer()), accessLogField(req.UserAgent()))
+			lock.Lock()
+			defer lock.Unlock()
+			io.WriteString(out, line)
+			return nil
+		}
+	}
+}
+
+// accessLogField returns the value of an optional field, "-" if empty.
+func accessLogField(v string) string {
+	if v == "" {
+		return "-"
+	}
+	return accessLogEscape(v)
+}
+
+// accessLogEscape escapes quotes, backslashes and control characters, so that a field can not break the line format.
+func accessLogEscape(v string) string {
+	var sb strings.Builder
+	for _, r := range v {
+		switch {
+		case r == '"' || r == '\\':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&sb, "\\x%02X", r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
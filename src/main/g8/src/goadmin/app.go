@@ -1,69 +1,250 @@
 package goadmin
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	hocon "github.com/go-akka/configuration"
 	"github.com/labstack/echo-contrib/session"
 	"github.com/labstack/echo/v4"
-	"github.com/labstack/echo/v4/middleware"
 	"main/src/cocostore"
 	"main/src/utils"
 )
 
 const (
-	defaultConfigFile = "./config/application.conf"
+	defaultConfigFile    = "./config/application.conf"
+	defaultI18nCommonDir = "./config/i18n_common"
+	defaultI18nLocale    = "en"
+	shutdownTimeout      = 10 * time.Second
 )
 
 var (
+	// AppConfig is the application's configurations.
+	//
+	// Deprecated: use Registry.AppConfig instead.
 	AppConfig *hocon.Config
 
-	EchoServer           *echo.Echo
-	echoServerListenAddr string
-	echoServerListenPort int32
+	// EchoServer is the application's Echo server.
+	//
+	// Deprecated: use Registry.EchoServer instead.
+	EchoServer *echo.Echo
 )
 
-// Start bootstraps the application.
+// Start bootstraps the application and starts the HTTP server.
 func Start(bootstrappers ...IBootstrapper) {
-	var err error
+	registry := Bootstrap(initAppConfig(), bootstrappers...)
+	log.Printf("Build info: %s", registry.BuildInfo())
+	startEchoServer(registry)
+}
 
-	AppConfig = initAppConfig()
-	utils.DevMode = AppConfig.GetBoolean("dev_mode", utils.DevMode)
-	if utils.Location, err = time.LoadLocation(AppConfig.GetString("timezone")); err != nil {
-		panic(err)
+// Bootstrap initializes the application's Registry from the supplied configurations and invokes bootstrappers.
+// HTTP server is not started.
+//
+// Startup self-checks are recorded in Registry.Diagnostics; if any of them fails, Bootstrap panics with a report of
+// the failed checks.
+//
+// Available since template-r5
+func Bootstrap(appConfig *hocon.Config, bootstrappers ...IBootstrapper) *Registry {
+	AppConfig = appConfig
+	registry := NewRegistry(appConfig)
+	diag := registry.Diagnostics
+	utils.DevMode = appConfig.GetBoolean("dev_mode", utils.DevMode)
+	diag.Check("goadmin.logging", func() error { return initLogging(registry) })
+	diag.Check("goadmin.timezone", func() error {
+		loc, err := time.LoadLocation(appConfig.GetString("timezone"))
+		if err != nil {
+			return fmt.Errorf("invalid [timezone] setting: %s", err)
+		}
+		utils.Location = loc
+		return nil
+	})
+	if appConfig.GetBoolean("goadmin.e2e.enabled", false) {
+		diag.Check("goadmin.e2e", func() error { return initE2eMode(appConfig) })
 	}
 
-	EchoServer, echoServerListenAddr, echoServerListenPort = initEchoServer()
+	EchoServer = initEchoServer(registry)
+	TemplateRenderer = registry.Renderer
 
 	// map static resources
-	if confV := AppConfig.GetValue("static_resources"); confV != nil && confV.IsObject() {
-		for uri, dirO := range confV.GetObject().Items() {
-			if dirO.IsString() {
-				dir := dirO.GetString()
-				log.Printf("Mapping static resources: %s -> %s", uri, dir)
-				if !strings.HasPrefix(uri, "/") {
-					uri = "/" + uri
+	if confV := appConfig.GetValue("static_resources"); confV != nil && confV.IsObject() {
+		diag.Check("goadmin.static_resources", func() error {
+			for uri, dirO := range confV.GetObject().Items() {
+				if dirO.IsString() {
+					dir := dirO.GetString()
+					if err := CheckDir(dir); err != nil {
+						return err
+					}
+					log.Printf("Mapping static resources: %s -> %s", uri, dir)
+					if !strings.HasPrefix(uri, "/") {
+						uri = "/" + uri
+					}
+					registry.EchoServer.Static(uri, dir)
+					registry.SharePath(uri)
 				}
-				EchoServer.Static(uri, dir)
+			}
+			return nil
+		})
+	}
+
+	// protected resources are served to signed-in users or via signed URLs (see ServeProtected)
+	if confV := appConfig.GetValue("protected_resources"); confV != nil && confV.IsObject() {
+		diag.Check("goadmin.protected_resources", func() error { return initProtectedResources(registry, confV) })
+	}
+
+	// build information, served on both the public site and the control panel
+	if path := appConfig.GetString("goadmin.version_path", "/version"); path != "" {
+		registry.EchoServer.GET(path, registry.actionVersion).Name = ActionNameVersion
+		registry.SharePath(path)
+	}
+
+	// robots.txt and security.txt, generated from settings goadmin.robots_txt and goadmin.security_txt
+	initWellKnown(registry)
+
+	if dbPath := appConfig.GetString("goadmin.geoip.db_path", ""); dbPath != "" {
+		diag.Check("goadmin.geoip", func() error { return initGeoIp(registry, dbPath) })
+	}
+
+	// sensitive fields are encrypted at rest by modules' DAOs if keys are configured
+	diag.Check("goadmin.field_encryption", func() error {
+		fc, err := NewFieldCipherFromConfig(appConfig, "goadmin.field_encryption")
+		registry.FieldCipher = fc
+		return err
+	})
+
+	// key-value cache shared by modules, falls back to the in-memory driver if the configured one is not available
+	registry.Cache = NewMemoryCache(0)
+	// locks are shared by all instances if the Redis cache is used, modules may set a database-backed locker instead
+	registry.Locker = NewMemoryLocker()
+	diag.Check("goadmin.cache", func() error {
+		cache, err := NewCache(appConfig, "goadmin.cache")
+		if err == nil {
+			registry.Cache = cache
+			if locker, ok := cache.(Locker); ok {
+				registry.Locker = locker
 			}
 		}
+		return err
+	})
+
+	// outgoing webhooks and emails, modules should set a persistent store
+	registry.Outbox = newOutboxFromConfig(appConfig)
+	diag.Check("goadmin.sms", func() error { return initSms(registry) })
+	// events are mirrored to a message bus (Kafka or NATS) if configured, failed ones are retried via the outbox
+	diag.Check("goadmin.event_bus", func() error { return initEventBus(registry) })
+
+	// shared i18n bundle, modules' bundles are merged on top of it
+	if dir := appConfig.GetString("goadmin.i18n_common_dir", defaultI18nCommonDir); dir != "" {
+		diag.Check("goadmin.i18n", func() error {
+			return registry.I18n.Load(I18nCommonNamespace, dir, defaultI18nLocale)
+		})
 	}
 
 	// bootstrapping
-	if bootstrappers != nil {
-		for _, b := range bootstrappers {
-			log.Println("Bootstrapping", b)
-			if err := b.Bootstrap(AppConfig, EchoServer); err != nil {
-				log.Println(err)
-			}
+	for _, b := range bootstrappers {
+		log.Println("Bootstrapping", b)
+		diag.Check(fmt.Sprintf("bootstrap %T", b), func() error { return b.Bootstrap(registry) })
+	}
+
+	// receivers dispatch to handlers registered by bootstrappers
+	diag.Check("goadmin.webhook_receivers", func() error { return initWebhookReceivers(registry) })
+
+	// bootstrappers may have replaced the locker (e.g. by a database-backed one), the election must use the final one
+	registry.Leader = NewLeaderElection(registry.Locker, appConfig.GetTimeDuration("goadmin.leader_lease", 30*time.Second))
+	registry.Leader.Start()
+	// the outbox is dispatched by the leader only, so that messages are not delivered by several instances at once
+	registry.Outbox.Start(appConfig.GetTimeDuration("goadmin.outbox.interval", 10*time.Second), registry.IsLeader)
+
+	// middleware chains of sites run after middlewares registered by bootstrappers
+	registry.EchoServer.Use(registry.siteMiddleware)
+
+	// middlewares added by WrapRoute to routes outside of control panel route groups
+	registry.EchoServer.Use(registry.routeHooksMiddleware(false))
+
+	// bootstrappers have declared their configuration keys by now
+	diag.Check("goadmin.listeners", func() error {
+		if _, err := listenSpecs(appConfig, "http.listeners"); err != nil {
+			return err
+		}
+		if _, err := parseListenSpecs(appConfig, "http.cp_listeners"); err != nil {
+			return err
+		}
+		_, err := unixSocketMode(appConfig.GetString("http.unix_socket_mode", ""))
+		return err
+	})
+	diag.Check("goadmin.config", func() error { return validateAppConfig(registry) })
+
+	reportDiagnostics(registry)
+	return registry
+}
+
+// initGeoIp opens the GeoIP database (downloading it first if the file does not exist and a download URL is
+// configured) and starts the background job refreshing it.
+func initGeoIp(registry *Registry, dbPath string) error {
+	conf := registry.AppConfig
+	downloadUrl := conf.GetString("goadmin.geoip.download_url", "")
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) && downloadUrl != "" {
+		log.Printf("Downloading GeoIP database to [%s]...", dbPath)
+		if err := downloadGeoIpDb(downloadUrl, dbPath); err != nil {
+			return err
+		}
+	}
+	geoIp, err := OpenGeoIp(dbPath)
+	if err != nil {
+		return err
+	}
+	registry.GeoIp = geoIp
+	if interval := conf.GetTimeDuration("goadmin.geoip.refresh_interval", 24*time.Hour); interval > 0 {
+		geoIp.StartRefresher(interval, downloadUrl)
+	}
+	return nil
+}
+
+func validateAppConfig(registry *Registry) error {
+	errs := registry.ConfigSchema.Validate(registry.AppConfig)
+	if len(errs) == 0 {
+		return nil
+	}
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return errors.New(strings.Join(messages, "; "))
+}
+
+// reportDiagnostics logs the startup self-check report (and writes it to the file specified by setting
+// goadmin.diagnostics_report, if any), then fails fast if any check failed.
+func reportDiagnostics(registry *Registry) {
+	diag := registry.Diagnostics
+	log.Printf("Startup self-check report:\n%s", diag.Report())
+	if reportFile := registry.AppConfig.GetString("goadmin.diagnostics_report", ""); reportFile != "" {
+		if err := diag.WriteReport(reportFile); err != nil {
+			log.Printf("[WARN] cannot write startup self-check report to [%s]: %s", reportFile, err)
+		}
+	}
+	if failed := diag.Failed(); len(failed) > 0 {
+		messages := make([]string, len(failed))
+		for i, r := range failed {
+			messages[i] = fmt.Sprintf("- %s: %s", r.Name, r.Message)
 		}
+		panic("startup self-check failed:\n" + strings.Join(messages, "\n"))
 	}
+}
 
-	startEchoServer(EchoServer, echoServerListenAddr, echoServerListenPort)
+// LoadAppConfig loads application's configurations from the file specified by env APP_CONFIG (default
+// "./config/application.conf"), completed by the generated configuration in zero-config mode (see ZeroConfig).
+//
+// Available since template-r5
+func LoadAppConfig() *hocon.Config {
+	return initAppConfig()
 }
 
 func initAppConfig() *hocon.Config {
@@ -72,39 +253,193 @@ func initAppConfig() *hocon.Config {
 		log.Printf("No environment APP_CONFIG found, fallback to [%s]", defaultConfigFile)
 		configFile = defaultConfigFile
 	}
-	return loadAppConfig(configFile)
+	if !zeroConfigMode(configFile) {
+		return loadAppConfig(configFile, "")
+	}
+	if _, err := os.Stat(configFile); os.IsNotExist(err) {
+		log.Printf("Configuration file [%s] not found, booting in zero-config mode", configFile)
+		configFile = ""
+	}
+	extra, err := buildZeroConfig()
+	if err != nil {
+		panic(err)
+	}
+	return loadAppConfig(configFile, extra)
 }
 
-func initEchoServer() (*echo.Echo, string, int32) {
-	listenPort := AppConfig.GetInt32("http.listen_port", 0)
-	if listenPort <= 0 {
-		panic("No valid [http.listen_port] configured")
+func initEchoServer(registry *Registry) *echo.Echo {
+	appConfig := registry.AppConfig
+	e := echo.New()
+	registry.EchoServer = e
+	e.HTTPErrorHandler = registry.httpErrorHandler
+
+	// access log in Combined Log Format, first so that the original request URI is logged
+	registry.Diagnostics.Check("goadmin.access_log", func() error { return initAccessLog(registry) })
+
+	// mount the application under a sub-path, e.g. behind a shared reverse proxy
+	registry.BasePath = normalizeBasePath(appConfig.GetString("http.base_path", ""))
+	if registry.BasePath != "" {
+		log.Printf("Mounting application under base path [%s]", registry.BasePath)
+		e.Pre(basePathMiddleware(registry.BasePath))
 	}
-	listenAddr := AppConfig.GetString("http.listen_addr", "127.0.0.1")
 
-	e := echo.New()
+	// client IPs (c.RealIP()) are taken from X-Forwarded-For of trusted reverse proxies only
+	registry.Diagnostics.Check("goadmin.trusted_proxies", func() error {
+		extractor, err := newIPExtractor(appConfig)
+		e.IPExtractor = extractor
+		return err
+	})
+
+	// public site and control panel can be served on separate listeners (see SiteOf)
+	e.Pre(registry.siteFilter())
+
+	// HTML forms can submit PUT/PATCH/DELETE requests (see RegisterMutation)
+	e.Pre(methodOverride())
+
+	// panics of handlers are logged as server errors rather than crashing the request with a raw stack trace
+	e.Use(recoverPanics)
+
+	// make the registry available to all handlers
+	e.Use(registry.Middleware)
 
 	// register session middleware
-	sessionKey := AppConfig.GetString("goadmin.session_key", "s3cr3t_s3ssion_2uth3ntic2tion_k3y")
-	// e.Use(session.Middleware(sessions.NewCookieStore([]byte(sessionKey))))
-	e.Use(session.Middleware(cocostore.NewCompressedCookieStore(cocostore.CompressionLevelBestCompression, []byte(sessionKey))))
+	registry.Diagnostics.Check("goadmin.session", func() error {
+		keyPairs, err := sessionKeyPairs(appConfig, utils.DevMode)
+		if err != nil {
+			return err
+		}
+		// e.Use(session.Middleware(sessions.NewCookieStore(keyPairs...)))
+		registry.SessionStore = cocostore.NewCompressedCookieStore(cocostore.CompressionLevelBestCompression, keyPairs...)
+		e.Use(session.Middleware(registry.SessionStore))
+		return nil
+	})
 
-	requestTimeout := AppConfig.GetTimeDuration("http.request_timeout", time.Duration(0))
-	if requestTimeout > 0 {
-		e.Server.ReadTimeout = requestTimeout
-	}
-	bodyLimit := AppConfig.GetByteSize("http.max_request_size")
-	if bodyLimit != nil && bodyLimit.Int64() > 0 {
-		e.Use(middleware.BodyLimit(bodyLimit.String()))
+	// signed URLs for sensitive GET actions, key defaults to the session key
+	urlSigningKey := appConfig.GetString("goadmin.url_signing_key", "")
+	if urlSigningKey == "" {
+		urlSigningKey = appConfig.GetString("goadmin.session_key", "")
 	}
+	registry.UrlSigner = NewUrlSigner([]byte(urlSigningKey), appConfig.GetTimeDuration("goadmin.signed_url_ttl", time.Hour))
+
+	// processed uploads and other generated files
+	registry.FileStore = NewDirFileStore(appConfig.GetString("goadmin.file_store_dir", "./data/files"))
 
-	TemplateRenderer = newGoadminRenderer()
-	e.Renderer = TemplateRenderer
+	// timeouts, header size limit and keep-alives of HTTP servers (configuration block "server")
+	registry.serverTuning = &serverTuning{maxHeaderBytes: http.DefaultMaxHeaderBytes, keepAlives: true, maxProcs: MaxProcsOff}
+	registry.Diagnostics.Check("goadmin.server", func() error {
+		tuning, err := serverTuningFromConfig(appConfig)
+		if err == nil {
+			registry.serverTuning = tuning
+		}
+		return err
+	})
+	registry.serverTuning.apply(e.Server)
+	// request body size limits, global and per-route (see Registry.SetBodyLimit)
+	registry.Diagnostics.Check("goadmin.body_limits", func() error { return initBodyLimits(registry) })
+	e.Use(registry.bodyLimitMiddleware)
 
-	return e, listenAddr, listenPort
+	registry.Renderer = newGoadminRenderer()
+	e.Renderer = registry.Renderer
+
+	return e
 }
 
-func startEchoServer(echoServer *echo.Echo, listenAddr string, listenPort int32) {
-	log.Printf("Starting [%s] on [%s:%d]...\n", AppConfig.GetString("app.name")+" v"+AppConfig.GetString("app.version"), listenAddr, listenPort)
-	go echoServer.Logger.Fatal(echoServer.Start(fmt.Sprintf("%s:%d", listenAddr, listenPort)))
+func startEchoServer(registry *Registry) {
+	appConfig := registry.AppConfig
+	appName := appConfig.GetString("app.name") + " v" + appConfig.GetString("app.version")
+	e := registry.EchoServer
+	sdMode := systemdMode(appConfig.GetString("http.systemd", SystemdAuto))
+
+	var listeners []siteListener
+	if sdMode != SystemdOff {
+		sdListeners, names, err := systemdListeners()
+		if err != nil {
+			panic(err)
+		}
+		if len(sdListeners) == 0 && sdMode == SystemdOn {
+			log.Printf("[WARN] [http.systemd] is on but no socket was passed by systemd, listening on configured address")
+		}
+		listeners = systemdSiteListeners(sdListeners, names)
+	}
+	if len(listeners) == 0 {
+		var err error
+		if listeners, err = openSiteListeners(appConfig); err != nil {
+			e.Logger.Fatal(err)
+		}
+	}
+	for _, l := range listeners {
+		if l.site != "" {
+			log.Printf("Starting [%s] (%s) on [%s]...\n", appName, l.site, l.Addr())
+		} else {
+			log.Printf("Starting [%s] on [%s]...\n", appName, l.Addr())
+		}
+	}
+
+	// listeners are bound at this point, requests are queued until the server starts serving
+	if sdMode != SystemdOff {
+		notifySystemdReady()
+	}
+	// CPU quota of the container, if any, caps the number of threads running Go code
+	registry.serverTuning.applyMaxProcs()
+	// SIGUSR1 toggles debug logging at runtime (not supported on Windows)
+	handleLogLevelSignal(registry)
+	servers, errChan := serveListeners(registry, listeners)
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+		<-sigChan
+		log.Printf("Shutting down [%s]...", appName)
+		if sdMode != SystemdOff {
+			notifySystemdStopping()
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		for _, server := range servers {
+			if err := server.Shutdown(ctx); err != nil {
+				log.Printf("[WARN] error shutting down HTTP server: %s", err)
+			}
+		}
+		registry.Close()
+		// another instance takes over scheduled jobs, undelivered messages stay in the outbox
+		registry.Outbox.Stop()
+		registry.Leader.Stop()
+		FlushLogSinks()
+		if registry.accessLog != nil {
+			registry.accessLog.Close()
+		}
+	}()
+	if err := <-errChan; err != http.ErrServerClosed {
+		e.Logger.Fatal(err)
+	}
+	// wait for in-flight requests to complete
+	<-shutdownDone
+}
+
+// serveListeners serves HTTP requests on all supplied listeners, with one HTTP server per site (the Echo server's
+// one is used for listeners serving all sites). Errors of the servers are sent to the returned channel.
+func serveListeners(registry *Registry, listeners []siteListener) ([]*http.Server, <-chan error) {
+	e := registry.EchoServer
+	e.Listener = listeners[0].Listener
+	servers := make(map[string]*http.Server)
+	result := make([]*http.Server, 0, 2)
+	errChan := make(chan error, len(listeners))
+	for _, l := range listeners {
+		server := servers[l.site]
+		if server == nil {
+			server = e.Server
+			if l.site != "" {
+				server = &http.Server{}
+				registry.serverTuning.apply(server)
+			}
+			server.Handler = registry.siteHandler(l.site)
+			servers[l.site] = server
+			result = append(result, server)
+		}
+		go func(server *http.Server, l net.Listener) {
+			errChan <- server.Serve(l)
+		}(server, l.Listener)
+	}
+	return result, errChan
 }
@@ -0,0 +1,112 @@
+package goadmin
+
+import (
+	"io"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// AssetOrigin tells where static resources are served from: a CDN origin (e.g. an S3 bucket behind a CDN) if
+// configured, otherwise the application's local static path.
+//
+// Available since template-r5
+type AssetOrigin struct {
+	LocalPath      string // path static resources are served from by the application, e.g. "/static_v1.0.0"
+	CdnBaseUrl     string // base URL of the CDN origin, empty to serve static resources locally
+	Version        string // value of the cache-busting query parameter, usually the application's version
+	CacheBustParam string // name of the cache-busting query parameter, empty to disable cache busting
+}
+
+// NewAssetOrigin builds an AssetOrigin from configurations at confPath:
+//
+//   - env: name of the current environment (e.g. "production"), which selects the CDN base URL.
+//   - cdn_base_urls: CDN base URLs per environment; if the current environment has none, static resources are served
+//     from localPath.
+//   - cache_bust_param: name of the cache-busting query parameter, its value is the application's version.
+//
+// localPath is the route path static resources are mapped to (e.g. "/static_v1.0.0"), it is prefixed with the base
+// path the application is mounted under.
+//
+// Available since template-r5
+func (r *Registry) NewAssetOrigin(confPath, localPath string) *AssetOrigin {
+	conf := r.AppConfig
+	origin := &AssetOrigin{
+		LocalPath:      r.Url(localPath),
+		Version:        conf.GetString("app.version", ""),
+		CacheBustParam: conf.GetString(confPath+".cache_bust_param", ""),
+	}
+	if env := conf.GetString(confPath+".env", ""); env != "" {
+		origin.CdnBaseUrl = strings.TrimSuffix(conf.GetString(confPath+".cdn_base_urls."+env, ""), "/")
+	}
+	return origin
+}
+
+// IsCdn returns true if static resources are served from the CDN origin.
+func (a *AssetOrigin) IsCdn() bool {
+	return a.CdnBaseUrl != ""
+}
+
+// BaseUrl returns the base URL of static resources: the CDN base URL if configured, otherwise the local static path.
+func (a *AssetOrigin) BaseUrl() string {
+	if a.IsCdn() {
+		return a.CdnBaseUrl
+	}
+	return a.LocalPath
+}
+
+// Url returns the URL of a static resource (path relative to the static directory, e.g. "css/app.css"), with the
+// cache-busting query parameter appended.
+func (a *AssetOrigin) Url(path string) string {
+	result := a.BaseUrl() + "/" + strings.TrimPrefix(path, "/")
+	if a.CacheBustParam == "" || a.Version == "" {
+		return result
+	}
+	sep := "?"
+	if strings.Contains(result, "?") {
+		sep = "&"
+	}
+	return result + sep + url.QueryEscape(a.CacheBustParam) + "=" + url.QueryEscape(a.Version)
+}
+
+// AssetUploader uploads a static resource to the CDN origin. key is the slash-separated path of the resource relative
+// to the static directory.
+//
+// Available since template-r5
+type AssetUploader func(key, contentType string, content io.Reader) error
+
+// UploadAssets uploads all files under the supplied directory to the CDN origin (usually called on deploy) and
+// returns the number of uploaded files.
+//
+// Available since template-r5
+func UploadAssets(dir string, upload AssetUploader) (int, error) {
+	if err := CheckDir(dir); err != nil {
+		return 0, err
+	}
+	count := 0
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		contentType := mime.TypeByExtension(filepath.Ext(path))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		if err = upload(filepath.ToSlash(rel), contentType, f); err == nil {
+			count++
+		}
+		return err
+	})
+	return count, err
+}
@@ -0,0 +1,77 @@
+package goadmin
+
+import (
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// normalizeBasePath returns the base path in the form "/prefix" (no trailing slash), or empty string if the
+// application is mounted at the root.
+func normalizeBasePath(basePath string) string {
+	basePath = strings.Trim(strings.TrimSpace(basePath), "/")
+	if basePath == "" {
+		return ""
+	}
+	return "/" + basePath
+}
+
+// basePathMiddleware returns a pre-routing middleware that mounts the application under the supplied base path:
+//
+//   - the base path is stripped from request URLs before routing, so routes are registered as if the application
+//     was mounted at the root; requests outside of the base path are answered with 404.
+//   - local redirects (Location header starting with "/") are prefixed with the base path, so handlers can keep
+//     redirecting to c.Echo().Reverse(...).
+func basePathMiddleware(basePath string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if !hasBasePath(req.URL.Path, basePath) {
+				return echo.ErrNotFound
+			}
+			req.URL.Path = stripBasePath(req.URL.Path, basePath)
+			if req.URL.RawPath != "" && hasBasePath(req.URL.RawPath, basePath) {
+				req.URL.RawPath = stripBasePath(req.URL.RawPath, basePath)
+			}
+			resp := c.Response()
+			resp.Before(func() {
+				loc := resp.Header().Get(echo.HeaderLocation)
+				if strings.HasPrefix(loc, "/") && !strings.HasPrefix(loc, "//") && !hasBasePath(loc, basePath) {
+					resp.Header().Set(echo.HeaderLocation, basePath+loc)
+				}
+			})
+			return next(c)
+		}
+	}
+}
+
+func hasBasePath(path, basePath string) bool {
+	if !strings.HasPrefix(path, basePath) {
+		return false
+	}
+	rest := path[len(basePath):]
+	return rest == "" || rest[0] == '/' || rest[0] == '?' || rest[0] == '#'
+}
+
+func stripBasePath(path, basePath string) string {
+	if path = path[len(basePath):]; path == "" {
+		return "/"
+	}
+	return path
+}
+
+// Url returns the public URL of an application path (e.g. "/cp/login"), i.e. the path prefixed with the base path
+// the application is mounted under (setting http.base_path).
+//
+// Available since template-r5
+func (r *Registry) Url(path string) string {
+	return r.BasePath + path
+}
+
+// Reverse is similar to echo.Echo.Reverse, but the returned URL is prefixed with the base path the application is
+// mounted under (setting http.base_path). Use it to generate links in views.
+//
+// Available since template-r5
+func (r *Registry) Reverse(name string, params ...interface{}) string {
+	return r.Url(r.EchoServer.Reverse(name, params...))
+}
@@ -0,0 +1,105 @@
+package goadmin
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+
+	"github.com/labstack/echo/v4"
+)
+
+// SetBodyLimit overrides the maximum request body size (setting http.max_request_size) for a route, identified by
+// its path as registered with Echo (e.g. "/cp/import"). Use it for routes that accept large uploads; limit <= 0 means
+// no limit.
+//
+// Limits of routes can also be configured via setting http.body_limits.
+//
+// Available since template-r5
+func (r *Registry) SetBodyLimit(path string, limit int64) *Registry {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.bodyLimits == nil {
+		r.bodyLimits = make(map[string]int64)
+	}
+	r.bodyLimits[path] = limit
+	return r
+}
+
+// BodyLimit returns the maximum request body size of a route (see SetBodyLimit), falling back to the global limit
+// (setting http.max_request_size). Zero or negative value means no limit.
+//
+// Available since template-r5
+func (r *Registry) BodyLimit(path string) int64 {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	if limit, ok := r.bodyLimits[path]; ok {
+		return limit
+	}
+	return r.bodyLimit
+}
+
+// initBodyLimits loads the global body limit (setting http.max_request_size) and per-route limits (setting
+// http.body_limits, a map of route paths to sizes).
+func initBodyLimits(registry *Registry) error {
+	appConfig := registry.AppConfig
+	if bodyLimit := appConfig.GetByteSize("http.max_request_size"); bodyLimit != nil {
+		registry.bodyLimit = bodyLimit.Int64()
+	}
+	if v := appConfig.GetValue("http.body_limits"); v != nil && v.IsObject() {
+		for path, sizeV := range v.GetObject().Items() {
+			size, err := byteSizeOf(sizeV.GetByteSize)
+			if err != nil {
+				return fmt.Errorf("invalid [http.body_limits] of route [%s]: %s", path, err)
+			}
+			log.Printf("Request body limit of route [%s]: %d bytes", path, size)
+			registry.SetBodyLimit(path, size)
+		}
+	}
+	return nil
+}
+
+// byteSizeOf calls the supplied byte-size getter, recovering from its panic on invalid values.
+func byteSizeOf(getter func() *big.Int) (size int64, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	if v := getter(); v != nil {
+		size = v.Int64()
+	}
+	return size, nil
+}
+
+// bodyLimitMiddleware rejects requests whose body exceeds the limit of the matched route (see Registry.BodyLimit)
+// with 413. It runs after routing, so that the route's own limit is known before any byte of the body is read.
+func (r *Registry) bodyLimitMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		limit := r.BodyLimit(c.Path())
+		if limit <= 0 {
+			return next(c)
+		}
+		req := c.Request()
+		if req.ContentLength > limit {
+			return echo.ErrStatusRequestEntityTooLarge
+		}
+		req.Body = &limitedReadCloser{ReadCloser: req.Body, remaining: limit}
+		return next(c)
+	}
+}
+
+// limitedReadCloser fails with 413 once more than the allowed number of bytes have been read.
+type limitedReadCloser struct {
+	io.ReadCloser
+	remaining int64
+}
+
+// Read implements io.Reader.
+func (l *limitedReadCloser) Read(p []byte) (int, error) {
+	n, err := l.ReadCloser.Read(p)
+	if l.remaining -= int64(n); l.remaining < 0 {
+		return n, echo.ErrStatusRequestEntityTooLarge
+	}
+	return n, err
+}
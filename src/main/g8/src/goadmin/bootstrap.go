@@ -1,11 +1,10 @@
 package goadmin
 
-import (
-	"github.com/go-akka/configuration"
-	"github.com/labstack/echo/v4"
-)
-
 // IBootstrapper defines an interface for application to hook bootstrapping routines.
+//
+// The registry carries application's configurations, the Echo server and other shared components. Bootstrappers
+// can store their own components in the registry (see Registry.Set) to make them available to handlers, and should
+// declare the configuration keys they use in Registry.ConfigSchema so that configurations are validated at startup.
 type IBootstrapper interface {
-	Bootstrap(appConfig *configuration.Config, echoServer *echo.Echo) error
+	Bootstrap(registry *Registry) error
 }
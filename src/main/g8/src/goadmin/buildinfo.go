@@ -0,0 +1,93 @@
+package goadmin
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+
+	hocon "github.com/go-akka/configuration"
+	"github.com/labstack/echo/v4"
+)
+
+// Build information, embedded at build time via ldflags, e.g.
+//
+//	go build -ldflags "-X main/src/goadmin.BuildCommit=`git rev-parse --short HEAD` -X main/src/goadmin.BuildTime=`date -u +%Y-%m-%dT%H:%M:%SZ`"
+//
+// Available since template-r5
+var (
+	// BuildVersion overrides the version string of setting app.version if not empty.
+	BuildVersion = ""
+	// BuildCommit is the commit hash the application was built from.
+	BuildCommit = ""
+	// BuildTime is the time the application was built.
+	BuildTime = ""
+)
+
+const (
+	// ActionNameVersion is the name of the route that exposes build information (setting goadmin.version_path).
+	//
+	// Available since template-r5
+	ActionNameVersion = "goadmin_version"
+
+	buildInfoUnknown = "unknown"
+)
+
+// BuildInfo describes the running build of the application.
+//
+// Available since template-r5
+type BuildInfo struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// String implements fmt.Stringer.
+func (b BuildInfo) String() string {
+	return fmt.Sprintf("%s v%s (commit %s, built at %s with %s)", b.Name, b.Version, b.Commit, b.BuildTime, b.GoVersion)
+}
+
+// AppInfo exposes settings of the "app" block (e.g. GetString "version") together with build information to views.
+//
+// Available since template-r5
+type AppInfo struct {
+	*hocon.Config
+	Build BuildInfo
+}
+
+// BuildInfo returns build information of the running application.
+//
+// Available since template-r5
+func (r *Registry) BuildInfo() BuildInfo {
+	info := BuildInfo{
+		Name:      r.AppConfig.GetString("app.name", ""),
+		Version:   BuildVersion,
+		Commit:    BuildCommit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+	if info.Version == "" {
+		info.Version = r.AppConfig.GetString("app.version", buildInfoUnknown)
+	}
+	if info.Commit == "" {
+		info.Commit = buildInfoUnknown
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = buildInfoUnknown
+	}
+	return info
+}
+
+// AppInfo returns the application's information, to be passed to views.
+//
+// Available since template-r5
+func (r *Registry) AppInfo() *AppInfo {
+	return &AppInfo{Config: r.AppConfig.GetConfig("app"), Build: r.BuildInfo()}
+}
+
+// actionVersion responds with build information in JSON format.
+func (r *Registry) actionVersion(c echo.Context) error {
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	return c.JSON(http.StatusOK, r.BuildInfo())
+}
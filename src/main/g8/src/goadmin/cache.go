@@ -0,0 +1,198 @@
+package goadmin
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	hocon "github.com/go-akka/configuration"
+)
+
+const (
+	// CacheDriverMemory keeps cached entries in the memory of the application instance.
+	//
+	// Available since template-r5
+	CacheDriverMemory = "memory"
+
+	// CacheDriverRedis keeps cached entries in a Redis server, shared by all application instances.
+	//
+	// Available since template-r5
+	CacheDriverRedis = "redis"
+)
+
+// ErrCacheMiss is returned by Cache.Get if the key is not cached or its entry has expired.
+//
+// Available since template-r5
+var ErrCacheMiss = errors.New("cache miss")
+
+// Cache is the key-value caching layer shared by modules (see Registry.Cache), so that they do not need to maintain
+// their own maps. Values are byte slices, modules usually cache JSON-encoded objects; keys should be prefixed with the
+// module's namespace (e.g. "myapp:setting:security") to not clash.
+//
+// The driver is selected by setting goadmin.cache.driver: CacheDriverMemory (default) or CacheDriverRedis.
+//
+// Available since template-r5
+type Cache interface {
+	// Get returns the cached value of key, or ErrCacheMiss.
+	Get(key string) ([]byte, error)
+	// Set caches value under key for ttl, zero or negative ttl means the entry does not expire.
+	Set(key string, value []byte, ttl time.Duration) error
+	// Delete removes key from the cache, deleting a non-cached key is not an error.
+	Delete(key string) error
+	// Remember returns the cached value of key; on miss, the value is loaded by fn and cached for ttl. Errors returned
+	// by fn are passed to the caller and nothing is cached.
+	Remember(key string, ttl time.Duration, fn func() ([]byte, error)) ([]byte, error)
+}
+
+// ExpiredPurger is implemented by caches and lockers that keep expired entries until they are purged (e.g. the
+// in-memory ones), so that modules can reclaim them periodically instead of letting them grow unbounded. Stores
+// expiring entries on their own (e.g. Redis) do not need to implement it.
+//
+// Available since template-r5
+type ExpiredPurger interface {
+	// PurgeExpired removes entries expired at now and returns the number of removed ones.
+	PurgeExpired(now time.Time) (int, error)
+}
+
+// remember implements Cache.Remember on top of Get and Set. Cache errors other than misses are not fatal: the value is
+// loaded by fn as if it were not cached.
+func remember(cache Cache, key string, ttl time.Duration, fn func() ([]byte, error)) ([]byte, error) {
+	if value, err := cache.Get(key); err == nil {
+		return value, nil
+	}
+	value, err := fn()
+	if err != nil {
+		return nil, err
+	}
+	cache.Set(key, value, ttl)
+	return value, nil
+}
+
+// NewCache creates a Cache from configuration block confPath (e.g. "goadmin.cache"):
+//   - driver: CacheDriverMemory (default) or CacheDriverRedis.
+//   - max_entries: (memory) maximum number of cached entries, 0 means no limit.
+//   - redis.addr, redis.password, redis.db, redis.key_prefix, redis.timeout, redis.pool_size: (redis) connection
+//     settings, see NewRedisCache.
+//
+// Available since template-r5
+func NewCache(conf *hocon.Config, confPath string) (Cache, error) {
+	switch driver := conf.GetString(confPath+".driver", CacheDriverMemory); driver {
+	case CacheDriverMemory, "":
+		return NewMemoryCache(int(conf.GetInt32(confPath+".max_entries", 0))), nil
+	case CacheDriverRedis:
+		cache := NewRedisCache(RedisCacheOptions{
+			Addr:      conf.GetString(confPath+".redis.addr", "localhost:6379"),
+			Password:  conf.GetString(confPath+".redis.password", ""),
+			Db:        int(conf.GetInt32(confPath+".redis.db", 0)),
+			KeyPrefix: conf.GetString(confPath+".redis.key_prefix", ""),
+			Timeout:   conf.GetTimeDuration(confPath+".redis.timeout", 5*time.Second),
+			PoolSize:  int(conf.GetInt32(confPath+".redis.pool_size", 8)),
+		})
+		// fail fast (and report in diagnostics) if the server can not be reached
+		if err := cache.Ping(); err != nil {
+			return nil, fmt.Errorf("cannot connect to redis server [%s]: %s", cache.opts.Addr, err)
+		}
+		return cache, nil
+	default:
+		return nil, fmt.Errorf("unknown cache driver [%s]", driver)
+	}
+}
+
+/*----------------------------------------------------------------------*/
+
+// NewMemoryCache creates a Cache that keeps entries in memory. If maxEntries is positive, expired entries are purged
+// when the limit is reached, then the entries closest to expiry are evicted.
+//
+// Available since template-r5
+func NewMemoryCache(maxEntries int) Cache {
+	return &memoryCache{entries: make(map[string]memoryCacheEntry), maxEntries: maxEntries}
+}
+
+type memoryCacheEntry struct {
+	value  []byte
+	expiry time.Time // zero value means the entry does not expire
+}
+
+func (e memoryCacheEntry) expired(now time.Time) bool {
+	return !e.expiry.IsZero() && !now.Before(e.expiry)
+}
+
+type memoryCache struct {
+	lock       sync.RWMutex
+	entries    map[string]memoryCacheEntry
+	maxEntries int
+}
+
+// Get implements Cache.Get
+func (c *memoryCache) Get(key string) ([]byte, error) {
+	c.lock.RLock()
+	entry, ok := c.entries[key]
+	c.lock.RUnlock()
+	if !ok || entry.expired(time.Now()) {
+		return nil, ErrCacheMiss
+	}
+	// callers may modify the returned slice, the cached value must not change
+	return append([]byte(nil), entry.value...), nil
+}
+
+// Set implements Cache.Set
+func (c *memoryCache) Set(key string, value []byte, ttl time.Duration) error {
+	entry := memoryCacheEntry{value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		entry.expiry = time.Now().Add(ttl)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, ok := c.entries[key]; !ok && c.maxEntries > 0 && len(c.entries) >= c.maxEntries {
+		c.evict()
+	}
+	c.entries[key] = entry
+	return nil
+}
+
+// evict makes room for a new entry: expired entries are purged, if none the entry closest to expiry is removed.
+// Entries without expiry are evicted last. Must be called with the lock held.
+func (c *memoryCache) evict() {
+	now := time.Now()
+	victim, victimExpiry := "", time.Time{}
+	for key, entry := range c.entries {
+		if entry.expired(now) {
+			delete(c.entries, key)
+			continue
+		}
+		if victim == "" || (!entry.expiry.IsZero() && (victimExpiry.IsZero() || entry.expiry.Before(victimExpiry))) {
+			victim, victimExpiry = key, entry.expiry
+		}
+	}
+	if len(c.entries) >= c.maxEntries {
+		delete(c.entries, victim)
+	}
+}
+
+// PurgeExpired implements ExpiredPurger.PurgeExpired
+func (c *memoryCache) PurgeExpired(now time.Time) (int, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	numDeleted := 0
+	for key, entry := range c.entries {
+		if entry.expired(now) {
+			delete(c.entries, key)
+			numDeleted++
+		}
+	}
+	return numDeleted, nil
+}
+
+// Delete implements Cache.Delete
+func (c *memoryCache) Delete(key string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.entries, key)
+	return nil
+}
+
+// Remember implements Cache.Remember
+func (c *memoryCache) Remember(key string, ttl time.Duration, fn func() ([]byte, error)) ([]byte, error) {
+	return remember(c, key, ttl, fn)
+}
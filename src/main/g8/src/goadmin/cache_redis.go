@@ -0,0 +1,245 @@
+package goadmin
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// RedisCacheOptions holds connection settings of a Redis cache.
+//
+// Available since template-r5
+type RedisCacheOptions struct {
+	Addr      string        // host:port of the Redis server
+	Password  string        // password to AUTH with, empty if the server does not require authentication
+	Db        int           // database number to SELECT
+	KeyPrefix string        // prepended to all keys, so that several applications can share a server
+	Timeout   time.Duration // dial, read and write timeout
+	PoolSize  int           // maximum number of idle connections kept open
+}
+
+// NewRedisCache creates a Cache backed by a Redis server. Entries are shared by all application instances using the
+// same server, database and key prefix.
+//
+// The driver implements the few commands it needs (GET, SET, DEL, EVAL) over the Redis protocol, connections are opened on
+// demand and kept in a small pool.
+//
+// Available since template-r5
+func NewRedisCache(opts RedisCacheOptions) *RedisCache {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = 1
+	}
+	return &RedisCache{opts: opts, pool: make(chan *redisConn, opts.PoolSize)}
+}
+
+// RedisCache is the CacheDriverRedis implementation of Cache.
+//
+// Available since template-r5
+type RedisCache struct {
+	opts RedisCacheOptions
+	pool chan *redisConn
+}
+
+// Ping checks that the Redis server can be reached.
+func (c *RedisCache) Ping() error {
+	_, err := c.do("PING")
+	return err
+}
+
+// Get implements Cache.Get
+func (c *RedisCache) Get(key string) ([]byte, error) {
+	reply, err := c.do("GET", c.opts.KeyPrefix+key)
+	if err != nil {
+		return nil, err
+	}
+	if reply == nil {
+		return nil, ErrCacheMiss
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("unexpected redis reply %T", reply)
+	}
+	return value, nil
+}
+
+// Set implements Cache.Set
+func (c *RedisCache) Set(key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", c.opts.KeyPrefix + key, string(value)}
+	if ms := ttl.Milliseconds(); ms > 0 {
+		args = append(args, "PX", strconv.FormatInt(ms, 10))
+	}
+	_, err := c.do(args...)
+	return err
+}
+
+// Delete implements Cache.Delete
+func (c *RedisCache) Delete(key string) error {
+	_, err := c.do("DEL", c.opts.KeyPrefix+key)
+	return err
+}
+
+// Remember implements Cache.Remember
+func (c *RedisCache) Remember(key string, ttl time.Duration, fn func() ([]byte, error)) ([]byte, error) {
+	return remember(c, key, ttl, fn)
+}
+
+// redisUnlockScript deletes a lock only if it is still held by the owner, it may have expired and been acquired by
+// another owner meanwhile.
+const redisUnlockScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) end return 0`
+
+// redisRefreshScript extends a lock only if it is still held by the owner.
+const redisRefreshScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("PEXPIRE", KEYS[1], ARGV[2]) end return 0`
+
+// TryLock implements Locker.TryLock, so that locks are shared by all instances using the Redis cache.
+func (c *RedisCache) TryLock(name, owner string, ttl time.Duration) (bool, error) {
+	ms := ttl.Milliseconds()
+	if ms <= 0 {
+		ms = 1
+	}
+	reply, err := c.do("SET", c.opts.KeyPrefix+"lock:"+name, owner, "NX", "PX", strconv.FormatInt(ms, 10))
+	return reply != nil, err
+}
+
+// Refresh implements Locker.Refresh
+func (c *RedisCache) Refresh(name, owner string, ttl time.Duration) (bool, error) {
+	ms := ttl.Milliseconds()
+	if ms <= 0 {
+		ms = 1
+	}
+	reply, err := c.do("EVAL", redisRefreshScript, "1", c.opts.KeyPrefix+"lock:"+name, owner, strconv.FormatInt(ms, 10))
+	n, _ := reply.(int64)
+	return n > 0, err
+}
+
+// Unlock implements Locker.Unlock
+func (c *RedisCache) Unlock(name, owner string) error {
+	_, err := c.do("EVAL", redisUnlockScript, "1", c.opts.KeyPrefix+"lock:"+name, owner)
+	return err
+}
+
+// do sends a command and reads its reply. The connection is returned to the pool unless an I/O error occurred.
+func (c *RedisCache) do(args ...string) (interface{}, error) {
+	conn, err := c.conn()
+	if err != nil {
+		return nil, err
+	}
+	reply, err := conn.do(c.opts.Timeout, args...)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		conn.Close()
+		return nil, err
+	}
+	select {
+	case c.pool <- conn:
+	default:
+		conn.Close()
+	}
+	return reply, err
+}
+
+// conn takes an idle connection from the pool, or opens a new one.
+func (c *RedisCache) conn() (*redisConn, error) {
+	select {
+	case conn := <-c.pool:
+		return conn, nil
+	default:
+	}
+	netConn, err := net.DialTimeout("tcp", c.opts.Addr, c.opts.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	conn := &redisConn{Conn: netConn, reader: bufio.NewReader(netConn)}
+	if c.opts.Password != "" {
+		if _, err := conn.do(c.opts.Timeout, "AUTH", c.opts.Password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if c.opts.Db != 0 {
+		if _, err := conn.do(c.opts.Timeout, "SELECT", strconv.Itoa(c.opts.Db)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+/*----------------------------------------------------------------------*/
+
+// redisError is an error reply sent by the server, the connection is still usable.
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+type redisConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// do writes a command as an array of bulk strings and reads the reply.
+func (conn *redisConn) do(timeout time.Duration, args ...string) (interface{}, error) {
+	conn.SetDeadline(time.Now().Add(timeout))
+	buf := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		buf = append(buf, "$"+strconv.Itoa(len(arg))+"\r\n"...)
+		buf = append(buf, arg...)
+		buf = append(buf, "\r\n"...)
+	}
+	if _, err := conn.Write(buf); err != nil {
+		return nil, err
+	}
+	return conn.readReply()
+}
+
+// readReply reads a reply: simple strings are returned as string, bulk strings as []byte (nil if the key does not
+// exist), integers as int64 and arrays as []interface{}.
+func (conn *redisConn) readReply() (interface{}, error) {
+	line, err := conn.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("invalid redis reply %q", line)
+	}
+	kind, payload := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return payload, nil
+	case '-':
+		return nil, redisError(payload)
+	case ':':
+		return strconv.ParseInt(payload, 10, 64)
+	case '$':
+		size, err := strconv.Atoi(payload)
+		if err != nil || size < 0 {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(conn.reader, data); err != nil {
+			return nil, err
+		}
+		return data[:size], nil
+	case '*':
+		count, err := strconv.Atoi(payload)
+		if err != nil || count < 0 {
+			return nil, err
+		}
+		items := make([]interface{}, count)
+		for i := range items {
+			if items[i], err = conn.readReply(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("invalid redis reply %q", line)
+}
@@ -0,0 +1,33 @@
+package goadmin
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryCache(t *testing.T) {
+	testName := "TestMemoryCache"
+	cache := NewMemoryCache(2)
+	cache.Set("a", []byte("1"), 0)
+	cache.Set("b", []byte("2"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if _, err := cache.Get("b"); err != ErrCacheMiss {
+		t.Fatalf("%s failed: expected expired entry but received %v", testName, err)
+	}
+	cache.Set("c", []byte("3"), time.Minute)
+	cache.Set("d", []byte("4"), time.Minute)
+	if v, err := cache.Get("a"); err != nil || string(v) != "1" {
+		t.Fatalf("%s failed: entry without expiry must be evicted last, received %q / %v", testName, v, err)
+	}
+
+	calls := 0
+	load := func() ([]byte, error) { calls++; return []byte("loaded"), nil }
+	for i := 0; i < 2; i++ {
+		if v, err := cache.Remember("e", time.Minute, load); err != nil || string(v) != "loaded" {
+			t.Fatalf("%s failed: unexpected value %q / %v", testName, v, err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("%s failed: expected value to be loaded once but was loaded %d times", testName, calls)
+	}
+}
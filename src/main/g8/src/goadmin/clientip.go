@@ -0,0 +1,54 @@
+package goadmin
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	hocon "github.com/go-akka/configuration"
+	"github.com/labstack/echo/v4"
+)
+
+// newIPExtractor builds the function c.RealIP() gets client IPs with from setting http.trusted_proxies:
+//
+//   - empty (default): the IP of the peer is used, headers X-Forwarded-For and X-Real-IP are ignored as any client
+//     can send them.
+//   - list of IPs or CIDR ranges of reverse proxies: the client IP is the right-most address of X-Forwarded-For
+//     not in these ranges, the peer IP if it is not a trusted proxy.
+//
+// Client IPs are used to ban and rate-limit clients, so that headers of untrusted peers must never be used.
+func newIPExtractor(conf *hocon.Config) (echo.IPExtractor, error) {
+	proxies := conf.GetStringList("http.trusted_proxies")
+	if len(proxies) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, proxy := range proxies {
+		ipRange, err := parseIPRange(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid [http.trusted_proxies] entry [%s]: %s", proxy, err)
+		}
+		options = append(options, echo.TrustIPRange(ipRange))
+	}
+	return echo.ExtractIPFromXFFHeader(options...), nil
+}
+
+// parseIPRange parses either a CIDR range or a single IP.
+func parseIPRange(value string) (*net.IPNet, error) {
+	value = strings.TrimSpace(value)
+	if strings.Contains(value, "/") {
+		_, ipRange, err := net.ParseCIDR(value)
+		return ipRange, err
+	}
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, fmt.Errorf("not an IP or CIDR range")
+	}
+	bits := 8 * net.IPv4len
+	if ip.To4() == nil {
+		bits = 8 * net.IPv6len
+	} else {
+		ip = ip.To4()
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
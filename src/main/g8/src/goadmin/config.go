@@ -11,7 +11,12 @@ import (
 	"github.com/go-akka/configuration/hocon"
 )
 
-func loadAppConfig(file string) *hoconf.Config {
+// loadAppConfig loads configurations from a file, followed by the configuration profile selected by env APP_ENV (see
+// AppEnv) and extra configurations (if any). The file is optional if extra configurations are supplied.
+func loadAppConfig(file, extra string) *hoconf.Config {
+	if file == "" {
+		return hoconf.ParseString(extra, myIncludeCallback)
+	}
 	// save the current directory and chdir back to it when done
 	if curDir, err := os.Getwd(); err != nil {
 		panic(err)
@@ -23,11 +28,22 @@ func loadAppConfig(file string) *hoconf.Config {
 	confDir, confFile := path.Split(file)
 	os.Chdir(confDir)
 
-	if data, err := ioutil.ReadFile(confFile); err != nil {
+	data, err := ioutil.ReadFile(confFile)
+	if err != nil {
+		panic(err)
+	}
+	profile, err := readConfigProfile()
+	if err != nil {
 		panic(err)
-	} else {
-		return hoconf.ParseString(string(data), myIncludeCallback)
 	}
+	return hoconf.ParseString(string(data)+"\n"+profile+"\n"+extra, myIncludeCallback)
+}
+
+// ParseAppConfig parses application's configurations from a HOCON string.
+//
+// Include directives are resolved relative to the current working directory.
+func ParseAppConfig(content string) *hoconf.Config {
+	return hoconf.ParseString(content, myIncludeCallback)
 }
 
 func myIncludeCallback(filename string) *hocon.HoconRoot {
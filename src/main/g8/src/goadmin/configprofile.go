@@ -0,0 +1,118 @@
+package goadmin
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	hoconf "github.com/go-akka/configuration"
+	"github.com/go-akka/configuration/hocon"
+)
+
+// configProfileDir is the directory, relative to the configuration file, holding configuration profiles.
+const configProfileDir = "profiles"
+
+var reConfigProfile = regexp.MustCompile(`^[A-Za-z0-9_\-]+$`)
+
+// effectiveConfigMaskedPatterns are, in addition to setting goadmin.log_masking.field_patterns, patterns of keys whose
+// values are masked when printing the effective configuration (see EffectiveConfig).
+var effectiveConfigMaskedPatterns = []string{"(^|_)key$", "dsn", "url$", "credential", "private"}
+
+// AppEnv returns the environment the application runs in (env APP_ENV, e.g. "staging"), empty if not set. The
+// configuration profile of the environment, file "profiles/<APP_ENV>.conf" next to the configuration file, is loaded
+// on top of the configuration file: objects are deep-merged and other values replaced.
+//
+// Available since template-r5
+func AppEnv() string {
+	return strings.TrimSpace(os.Getenv("APP_ENV"))
+}
+
+// readConfigProfile returns the content of the configuration profile selected by env APP_ENV, empty if none is
+// selected. It must be called from the directory of the configuration file.
+func readConfigProfile() (string, error) {
+	env := AppEnv()
+	if env == "" {
+		return "", nil
+	}
+	if !reConfigProfile.MatchString(env) {
+		return "", fmt.Errorf("invalid APP_ENV [%s]: only letters, digits, '-' and '_' are allowed", env)
+	}
+	file := filepath.Join(configProfileDir, env+".conf")
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		// a typo in APP_ENV must not silently boot with the base configuration
+		return "", fmt.Errorf("configuration profile of APP_ENV [%s] not found: %s", env, err)
+	}
+	log.Printf("Loading configuration profile [%s] from file [%s]", env, file)
+	return string(data), nil
+}
+
+// EffectiveConfig renders the resolved configurations as sorted "path = value" lines, one per setting. Values of
+// secrets (keys matching setting goadmin.log_masking.field_patterns, or looking like keys, DSNs, URLs or
+// credentials) are masked, so that the output can be shared when troubleshooting.
+//
+// Available since template-r5
+func EffectiveConfig(conf *hoconf.Config) string {
+	patterns := defaultMaskedFieldPatterns
+	if conf.HasPath("goadmin.log_masking.field_patterns") {
+		patterns = conf.GetStringList("goadmin.log_masking.field_patterns")
+	}
+	sanitizer, err := NewLogSanitizer(append(append([]string{}, patterns...), effectiveConfigMaskedPatterns...), false, "***")
+	if err != nil {
+		// invalid patterns are reported by the startup self-checks, mask with the default ones
+		sanitizer, _ = NewLogSanitizer(append(append([]string{}, defaultMaskedFieldPatterns...), effectiveConfigMaskedPatterns...), false, "***")
+	}
+	lines := make([]string, 0)
+	collectConfigLeaves(conf.Root(), "", func(path string) {
+		value := "***"
+		name := path[strings.LastIndex(path, ".")+1:]
+		if !sanitizer.IsSensitiveField(name) {
+			value = renderConfigValue(conf.GetValue(path))
+		}
+		lines = append(lines, path+" = "+value)
+	})
+	sort.Strings(lines)
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func renderConfigValue(v *hocon.HoconValue) string {
+	if v == nil || v.IsEmpty() {
+		return "null"
+	}
+	if v.IsString() {
+		s := v.GetString()
+		if s == "true" || s == "false" {
+			return s
+		}
+		if _, err := strconv.ParseFloat(s, 64); err == nil {
+			return s
+		}
+		return strconv.Quote(s)
+	}
+	if v.IsArray() {
+		items := make([]string, 0)
+		for _, item := range v.GetArray() {
+			items = append(items, renderConfigValue(item))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	if v.IsObject() {
+		keys := make([]string, 0)
+		for key := range v.GetObject().Items() {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		items := make([]string, 0, len(keys))
+		for _, key := range keys {
+			items = append(items, strconv.Quote(key)+": "+renderConfigValue(v.GetChildObject(key)))
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	}
+	return v.String()
+}
@@ -0,0 +1,58 @@
+package goadmin
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigProfiles(t *testing.T) {
+	testName := "TestConfigProfiles"
+	confDir := t.TempDir()
+	os.MkdirAll(filepath.Join(confDir, "profiles"), 0750)
+	ioutil.WriteFile(filepath.Join(confDir, "application.conf"), []byte(`
+goadmin {
+  log_level: "DEBUG"
+  session_key: "base-s3ss10n-k3y"
+}
+myapp {
+  db {
+    type: "sqlite"
+    sqlite.root: ":memory:"
+  }
+}
+`), 0600)
+	ioutil.WriteFile(filepath.Join(confDir, "profiles", "staging.conf"), []byte(`
+myapp {
+  db {
+    type: "memory"
+  }
+}
+`), 0600)
+	for k, v := range map[string]string{"APP_CONFIG": filepath.Join(confDir, "application.conf"), "APP_ENV": "staging", "GA_ZERO_CONFIG": "false"} {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+	conf := LoadAppConfig()
+	// profile's values replace the base ones, objects are deep-merged
+	if conf.GetString("myapp.db.type") != "memory" || conf.GetString("myapp.db.sqlite.root") != ":memory:" || conf.GetString("goadmin.log_level") != "DEBUG" {
+		t.Fatalf("%s failed: unexpected configuration %s", testName, conf.String())
+	}
+
+	effective := EffectiveConfig(conf)
+	if !strings.Contains(effective, `myapp.db.type = "memory"`) || !strings.Contains(effective, "goadmin.session_key = ***") || strings.Contains(effective, "base-s3ss10n-k3y") {
+		t.Fatalf("%s failed: unexpected effective configuration %s", testName, effective)
+	}
+
+	os.Setenv("APP_ENV", "stagging")
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatalf("%s failed: a missing profile must not be ignored", testName)
+			}
+		}()
+		LoadAppConfig()
+	}()
+}
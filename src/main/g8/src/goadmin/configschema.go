@@ -0,0 +1,369 @@
+package goadmin
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	hoconf "github.com/go-akka/configuration"
+	"github.com/go-akka/configuration/hocon"
+)
+
+// ConfigType is type of a configuration value.
+//
+// Available since template-r5
+type ConfigType int
+
+const (
+	ConfigTypeString ConfigType = iota
+	ConfigTypeBool
+	ConfigTypeInt
+	ConfigTypeDuration
+	ConfigTypeByteSize
+	ConfigTypeList
+	ConfigTypeObject
+)
+
+var configTypeNames = map[ConfigType]string{
+	ConfigTypeString:   "string",
+	ConfigTypeBool:     "boolean",
+	ConfigTypeInt:      "integer",
+	ConfigTypeDuration: "duration",
+	ConfigTypeByteSize: "byte size",
+	ConfigTypeList:     "list",
+	ConfigTypeObject:   "object",
+}
+
+// String implements fmt.Stringer.String
+func (t ConfigType) String() string {
+	return configTypeNames[t]
+}
+
+// ConfigKey describes an expected configuration key.
+//
+// Available since template-r5
+type ConfigKey struct {
+	Path     string      // full path of the key, e.g. "http.listen_port"
+	Type     ConfigType  // expected type of the value
+	Required bool        // if true, the key must be present
+	Default  interface{} // default value used by the application when the key is absent (for documentation only)
+	Desc     string      // short description of the key
+}
+
+// ConfigSchema describes the configuration keys an application expects, so that AppConfig can be validated at boot:
+// missing required keys, type mismatches and unknown keys (e.g. typos like "myapp.db.tpye") are reported rather than
+// silently falling back to defaults.
+//
+// Available since template-r5
+type ConfigSchema struct {
+	lock      sync.RWMutex
+	keys      map[string]ConfigKey
+	freeForms []string
+}
+
+// NewConfigSchema creates a new empty ConfigSchema.
+func NewConfigSchema() *ConfigSchema {
+	return &ConfigSchema{keys: make(map[string]ConfigKey)}
+}
+
+// newGoadminConfigSchema creates a ConfigSchema that declares configuration keys used by goadmin.
+func newGoadminConfigSchema() *ConfigSchema {
+	return NewConfigSchema().Add(
+		ConfigKey{Path: "app.name", Type: ConfigTypeString, Required: true, Desc: "application's name"},
+		ConfigKey{Path: "app.shortname", Type: ConfigTypeString, Desc: "application's short name"},
+		ConfigKey{Path: "app.version", Type: ConfigTypeString, Required: true, Desc: "application's version"},
+		ConfigKey{Path: "app.desc", Type: ConfigTypeString, Desc: "application's description"},
+		ConfigKey{Path: "timezone", Type: ConfigTypeString, Required: true, Desc: "application's timezone"},
+		ConfigKey{Path: "dev_mode", Type: ConfigTypeBool, Default: false, Desc: "enable/disable development mode"},
+		ConfigKey{Path: "static_resources", Type: ConfigTypeObject, Desc: "mappings of static resource paths to directories"},
+		ConfigKey{Path: "protected_resources", Type: ConfigTypeObject, Desc: "mappings of protected resource paths to directories"},
+		ConfigKey{Path: "goadmin.session_key", Type: ConfigTypeString, Required: true, Desc: "secret key to authenticate sessions"},
+		ConfigKey{Path: "goadmin.session_encryption_key", Type: ConfigTypeString, Default: "", Desc: "secret key to encrypt sessions, either 16, 24 or 32 bytes"},
+		ConfigKey{Path: "goadmin.session_previous_keys", Type: ConfigTypeList, Desc: "retired session keys still accepted to decode sessions"},
+		ConfigKey{Path: "goadmin.url_signing_key", Type: ConfigTypeString, Default: "", Desc: "secret key to sign URLs, default to session_key"},
+		ConfigKey{Path: "goadmin.signed_url_ttl", Type: ConfigTypeDuration, Default: "1h", Desc: "validity duration of signed URLs"},
+		ConfigKey{Path: "goadmin.file_store_dir", Type: ConfigTypeString, Default: "./data/files", Desc: "directory of the file store (processed uploads)"},
+		ConfigKey{Path: "goadmin.cache.driver", Type: ConfigTypeString, Default: CacheDriverMemory, Desc: "cache driver: memory or redis"},
+		ConfigKey{Path: "goadmin.cache.max_entries", Type: ConfigTypeInt, Default: 0, Desc: "maximum number of entries of the memory cache, 0 means no limit"},
+		ConfigKey{Path: "goadmin.cache.redis.addr", Type: ConfigTypeString, Default: "localhost:6379", Desc: "address of the Redis server"},
+		ConfigKey{Path: "goadmin.cache.redis.password", Type: ConfigTypeString, Default: "", Desc: "password of the Redis server"},
+		ConfigKey{Path: "goadmin.cache.redis.db", Type: ConfigTypeInt, Default: 0, Desc: "Redis database number"},
+		ConfigKey{Path: "goadmin.cache.redis.key_prefix", Type: ConfigTypeString, Default: "", Desc: "prefix of cache keys in Redis"},
+		ConfigKey{Path: "goadmin.cache.redis.timeout", Type: ConfigTypeDuration, Default: "5s", Desc: "timeout of Redis operations"},
+		ConfigKey{Path: "goadmin.cache.redis.pool_size", Type: ConfigTypeInt, Default: 8, Desc: "maximum number of idle Redis connections"},
+		ConfigKey{Path: "goadmin.leader_lease", Type: ConfigTypeDuration, Default: "30s", Desc: "lease of the instance elected to run scheduled jobs"},
+		ConfigKey{Path: "goadmin.outbox.interval", Type: ConfigTypeDuration, Default: "10s", Desc: "interval between dispatches of the outbox"},
+		ConfigKey{Path: "goadmin.outbox.max_attempts", Type: ConfigTypeInt, Default: 10, Desc: "attempts to deliver an outbox message before giving up"},
+		ConfigKey{Path: "goadmin.outbox.backoff", Type: ConfigTypeDuration, Default: "30s", Desc: "delay before retrying a failed delivery, doubled on every attempt"},
+		ConfigKey{Path: "goadmin.webhook.secret", Type: ConfigTypeString, Default: "", Desc: "secret key to sign webhook requests"},
+		ConfigKey{Path: "goadmin.webhook.timeout", Type: ConfigTypeDuration, Default: "10s", Desc: "timeout of webhook requests"},
+		ConfigKey{Path: "goadmin.webhook.receive_path", Type: ConfigTypeString, Default: "/hooks", Desc: "path inbound webhooks are received under, at <receive_path>/<receiver>"},
+		ConfigKey{Path: "goadmin.webhook.receivers", Type: ConfigTypeObject, Desc: "receivers of inbound webhooks: {<name>: {handler, secret, signature_header}}"},
+		ConfigKey{Path: "goadmin.smtp.addr", Type: ConfigTypeString, Default: "", Desc: "SMTP server (host:port) to send emails through, empty to disable emails"},
+		ConfigKey{Path: "goadmin.smtp.username", Type: ConfigTypeString, Default: "", Desc: "SMTP username"},
+		ConfigKey{Path: "goadmin.smtp.password", Type: ConfigTypeString, Default: "", Desc: "SMTP password"},
+		ConfigKey{Path: "goadmin.smtp.from", Type: ConfigTypeString, Default: "", Desc: "sender address of emails"},
+		ConfigKey{Path: "goadmin.sms.provider", Type: ConfigTypeString, Default: "", Desc: "provider SMS are sent through: twilio, sns or http, empty to disable SMS"},
+		ConfigKey{Path: "goadmin.sms.from", Type: ConfigTypeString, Default: "", Desc: "sender number or id of SMS"},
+		ConfigKey{Path: "goadmin.sms.timeout", Type: ConfigTypeDuration, Default: "10s", Desc: "timeout of requests to the SMS provider"},
+		ConfigKey{Path: "goadmin.sms.twilio.account_sid", Type: ConfigTypeString, Default: "", Desc: "Twilio account SID"},
+		ConfigKey{Path: "goadmin.sms.twilio.auth_token", Type: ConfigTypeString, Default: "", Desc: "Twilio auth token"},
+		ConfigKey{Path: "goadmin.sms.twilio.api_url", Type: ConfigTypeString, Default: "https://api.twilio.com", Desc: "base URL of the Twilio API"},
+		ConfigKey{Path: "goadmin.sms.sns.region", Type: ConfigTypeString, Default: "", Desc: "AWS region of Amazon SNS"},
+		ConfigKey{Path: "goadmin.sms.sns.access_key_id", Type: ConfigTypeString, Default: "", Desc: "AWS access key id, read from env AWS_ACCESS_KEY_ID if empty"},
+		ConfigKey{Path: "goadmin.sms.sns.secret_access_key", Type: ConfigTypeString, Default: "", Desc: "AWS secret access key"},
+		ConfigKey{Path: "goadmin.sms.http.url", Type: ConfigTypeString, Default: "", Desc: "URL of the generic HTTP SMS gateway"},
+		ConfigKey{Path: "goadmin.sms.http.authorization", Type: ConfigTypeString, Default: "", Desc: "value of header Authorization sent to the HTTP SMS gateway"},
+		ConfigKey{Path: "goadmin.event_bus.driver", Type: ConfigTypeString, Default: "", Desc: "message bus events are published to: nats or kafka_rest, empty to disable"},
+		ConfigKey{Path: "goadmin.event_bus.topic_prefix", Type: ConfigTypeString, Default: "", Desc: "prefix of topics events are published to"},
+		ConfigKey{Path: "goadmin.event_bus.timeout", Type: ConfigTypeDuration, Default: "5s", Desc: "timeout of publishing an event"},
+		ConfigKey{Path: "goadmin.event_bus.nats.url", Type: ConfigTypeString, Default: "nats://localhost:4222", Desc: "URL of the NATS server"},
+		ConfigKey{Path: "goadmin.event_bus.kafka_rest.url", Type: ConfigTypeString, Default: "http://localhost:8082", Desc: "base URL of the Kafka REST proxy"},
+		ConfigKey{Path: "goadmin.i18n_common_dir", Type: ConfigTypeString, Default: "./config/i18n_common", Desc: "directory of the i18n bundle shared by all modules"},
+		ConfigKey{Path: "goadmin.geoip.db_path", Type: ConfigTypeString, Default: "", Desc: "MaxMind GeoIP database file, empty to disable GeoIP lookup"},
+		ConfigKey{Path: "goadmin.geoip.download_url", Type: ConfigTypeString, Default: "", Desc: "URL to download the GeoIP database from"},
+		ConfigKey{Path: "goadmin.geoip.refresh_interval", Type: ConfigTypeDuration, Default: "24h", Desc: "interval to refresh the GeoIP database"},
+		ConfigKey{Path: "goadmin.field_encryption.current_key", Type: ConfigTypeString, Default: "", Desc: "id of the key sensitive fields are encrypted with, empty to disable encryption"},
+		ConfigKey{Path: "goadmin.diagnostics_report", Type: ConfigTypeString, Default: "", Desc: "file to write startup self-check report to"},
+		ConfigKey{Path: "goadmin.log_level", Type: ConfigTypeString, Default: "", Desc: "minimum level of log messages"},
+		ConfigKey{Path: "goadmin.log_masking.enabled", Type: ConfigTypeBool, Default: true, Desc: "redact personal and secret data from logs and error pages"},
+		ConfigKey{Path: "goadmin.log_masking.field_patterns", Type: ConfigTypeList, Desc: "patterns of names of fields whose values are masked"},
+		ConfigKey{Path: "goadmin.log_masking.mask_emails", Type: ConfigTypeBool, Default: true, Desc: "mask email addresses"},
+		ConfigKey{Path: "goadmin.log_masking.mask", Type: ConfigTypeString, Default: "***", Desc: "replacement of masked values"},
+		ConfigKey{Path: "goadmin.log_sinks", Type: ConfigTypeString, Default: "console", Desc: "destinations of log messages: console, file, syslog, gelf and/or loki"},
+		ConfigKey{Path: "goadmin.log_file.path", Type: ConfigTypeString, Default: "./logs/goadmin.log", Desc: "log file"},
+		ConfigKey{Path: "goadmin.log_file.format", Type: ConfigTypeString, Default: "json", Desc: "format of the log file: json or text"},
+		ConfigKey{Path: "goadmin.log_file.max_size", Type: ConfigTypeByteSize, Default: "100MB", Desc: "size the log file is rotated at, 0 to disable"},
+		ConfigKey{Path: "goadmin.log_file.rotate_interval", Type: ConfigTypeDuration, Default: "0", Desc: "interval the log file is rotated at, 0 to disable"},
+		ConfigKey{Path: "goadmin.log_file.max_backups", Type: ConfigTypeInt, Default: 7, Desc: "maximum number of rotated log files kept"},
+		ConfigKey{Path: "goadmin.log_file.max_age", Type: ConfigTypeDuration, Default: "0", Desc: "rotated log files older than this are removed"},
+		ConfigKey{Path: "goadmin.log_file.compress", Type: ConfigTypeBool, Default: false, Desc: "compress rotated log files with gzip"},
+		ConfigKey{Path: "goadmin.log_syslog.network", Type: ConfigTypeString, Default: "udp", Desc: "network of the syslog server: udp, tcp, unix or unixgram"},
+		ConfigKey{Path: "goadmin.log_syslog.addr", Type: ConfigTypeString, Default: "localhost:514", Desc: "address of the syslog server"},
+		ConfigKey{Path: "goadmin.log_syslog.facility", Type: ConfigTypeString, Default: "local0", Desc: "syslog facility of log messages"},
+		ConfigKey{Path: "goadmin.log_syslog.app_name", Type: ConfigTypeString, Desc: "APP-NAME of syslog messages"},
+		ConfigKey{Path: "goadmin.log_syslog.timeout", Type: ConfigTypeDuration, Default: "5s", Desc: "timeout of sending log messages to syslog"},
+		ConfigKey{Path: "goadmin.log_gelf.url", Type: ConfigTypeString, Default: "http://localhost:12201/gelf", Desc: "URL of the GELF HTTP input"},
+		ConfigKey{Path: "goadmin.log_gelf.timeout", Type: ConfigTypeDuration, Default: "5s", Desc: "timeout of shipping log messages to GELF"},
+		ConfigKey{Path: "goadmin.log_loki.url", Type: ConfigTypeString, Default: "http://localhost:3100", Desc: "base URL of Loki"},
+		ConfigKey{Path: "goadmin.log_loki.timeout", Type: ConfigTypeDuration, Default: "5s", Desc: "timeout of shipping log messages to Loki"},
+		ConfigKey{Path: "goadmin.log_loki.labels", Type: ConfigTypeObject, Desc: "labels of log streams shipped to Loki"},
+		ConfigKey{Path: "goadmin.version_path", Type: ConfigTypeString, Default: "/version", Desc: "path of the build information endpoint"},
+		ConfigKey{Path: "goadmin.e2e.enabled", Type: ConfigTypeBool, Default: false, Desc: "end-to-end test mode: frozen clock and seeded random strings and ids"},
+		ConfigKey{Path: "goadmin.e2e.time", Type: ConfigTypeString, Default: "", Desc: "time the clock is frozen at in end-to-end test mode (RFC 3339)"},
+		ConfigKey{Path: "goadmin.e2e.seed", Type: ConfigTypeInt, Default: 1, Desc: "seed of random strings and ids in end-to-end test mode"},
+		ConfigKey{Path: "goadmin.robots_txt.enabled", Type: ConfigTypeBool, Default: true, Desc: "serve the built-in /robots.txt"},
+		ConfigKey{Path: "goadmin.robots_txt.disallow_cp", Type: ConfigTypeBool, Default: true, Desc: "disallow control panel paths in robots.txt"},
+		ConfigKey{Path: "goadmin.robots_txt.disallow", Type: ConfigTypeList, Desc: "additional paths disallowed in robots.txt"},
+		ConfigKey{Path: "goadmin.robots_txt.allow", Type: ConfigTypeList, Desc: "paths allowed in robots.txt"},
+		ConfigKey{Path: "goadmin.robots_txt.sitemap", Type: ConfigTypeString, Default: "", Desc: "URL(s) of the sitemap, comma-separated"},
+		ConfigKey{Path: "goadmin.security_txt.contact", Type: ConfigTypeString, Default: "", Desc: "contacts of security.txt (comma-separated), empty to disable security.txt"},
+		ConfigKey{Path: "goadmin.security_txt.expires_after", Type: ConfigTypeDuration, Default: "180d", Desc: "Expires field of security.txt, relative to now"},
+		ConfigKey{Path: "goadmin.security_txt.encryption", Type: ConfigTypeString, Default: "", Desc: "Encryption field(s) of security.txt"},
+		ConfigKey{Path: "goadmin.security_txt.acknowledgments", Type: ConfigTypeString, Default: "", Desc: "Acknowledgments field(s) of security.txt"},
+		ConfigKey{Path: "goadmin.security_txt.preferred_languages", Type: ConfigTypeString, Default: "", Desc: "Preferred-Languages field of security.txt"},
+		ConfigKey{Path: "goadmin.security_txt.canonical", Type: ConfigTypeString, Default: "", Desc: "Canonical field(s) of security.txt"},
+		ConfigKey{Path: "goadmin.security_txt.policy", Type: ConfigTypeString, Default: "", Desc: "Policy field(s) of security.txt"},
+		ConfigKey{Path: "goadmin.security_txt.hiring", Type: ConfigTypeString, Default: "", Desc: "Hiring field(s) of security.txt"},
+		ConfigKey{Path: "http.listen_addr", Type: ConfigTypeString, Default: "127.0.0.1", Desc: "HTTP listen address"},
+		ConfigKey{Path: "http.listen_port", Type: ConfigTypeInt, Required: true, Desc: "HTTP listen port"},
+		ConfigKey{Path: "http.listeners", Type: ConfigTypeList, Desc: "addresses and unix sockets to listen on"},
+		ConfigKey{Path: "http.cp_listeners", Type: ConfigTypeList, Desc: "addresses and unix sockets to serve the control panel on"},
+		ConfigKey{Path: "http.unix_socket_mode", Type: ConfigTypeString, Default: "", Desc: "permissions of unix socket files"},
+		ConfigKey{Path: "http.systemd", Type: ConfigTypeString, Default: "auto", Desc: "systemd integration: auto, on or off"},
+		ConfigKey{Path: "http.trusted_proxies", Type: ConfigTypeList, Desc: "IPs or CIDR ranges of reverse proxies whose X-Forwarded-For header is trusted"},
+		ConfigKey{Path: "http.base_path", Type: ConfigTypeString, Default: "", Desc: "path the application is mounted under"},
+		ConfigKey{Path: "http.request_timeout", Type: ConfigTypeDuration, Default: "0", Desc: "timeout to parse request data (deprecated: use server.read_timeout)"},
+		ConfigKey{Path: "http.max_request_size", Type: ConfigTypeByteSize, Desc: "maximum size of request data"},
+		ConfigKey{Path: "http.body_limits", Type: ConfigTypeObject, Desc: "maximum request body sizes, per route path"},
+		ConfigKey{Path: "http.access_log.file", Type: ConfigTypeString, Default: "", Desc: "access log destination: file path, stdout or stderr, empty to disable"},
+		ConfigKey{Path: "http.access_log.max_size", Type: ConfigTypeByteSize, Default: "100MB", Desc: "size the access log file is rotated at, 0 to disable"},
+		ConfigKey{Path: "http.access_log.rotate_interval", Type: ConfigTypeDuration, Default: "0", Desc: "interval the access log file is rotated at, 0 to disable"},
+		ConfigKey{Path: "http.access_log.compress", Type: ConfigTypeBool, Default: false, Desc: "compress rotated access log files with gzip"},
+		ConfigKey{Path: "http.access_log.max_backups", Type: ConfigTypeInt, Default: 7, Desc: "maximum number of rotated access log files kept"},
+		ConfigKey{Path: "http.access_log.max_age", Type: ConfigTypeDuration, Default: "0", Desc: "rotated access log files older than this are removed"},
+		ConfigKey{Path: "server.read_timeout", Type: ConfigTypeDuration, Default: "0", Desc: "timeout to read requests, including the body"},
+		ConfigKey{Path: "server.read_header_timeout", Type: ConfigTypeDuration, Default: "0", Desc: "timeout to read request headers"},
+		ConfigKey{Path: "server.write_timeout", Type: ConfigTypeDuration, Default: "0", Desc: "timeout to write responses"},
+		ConfigKey{Path: "server.idle_timeout", Type: ConfigTypeDuration, Default: "0", Desc: "time to wait for the next request on a keep-alive connection"},
+		ConfigKey{Path: "server.max_header_bytes", Type: ConfigTypeByteSize, Default: "1MiB", Desc: "maximum size of request headers"},
+		ConfigKey{Path: "server.keep_alives", Type: ConfigTypeBool, Default: true, Desc: "keep connections open between requests"},
+		ConfigKey{Path: "server.tcp_keep_alive", Type: ConfigTypeDuration, Default: "0", Desc: "period of TCP keep-alive probes, 0 for the Go default, negative to disable"},
+		ConfigKey{Path: "server.maxprocs", Type: ConfigTypeString, Default: "auto", Desc: "GOMAXPROCS: auto (CPU quota of the container), off or a number"},
+	).AllowAny("static_resources", "protected_resources", "http.body_limits", "goadmin.log_loki.labels", "goadmin.field_encryption.keys",
+		"goadmin.webhook.receivers")
+}
+
+// Add declares expected configuration keys.
+func (s *ConfigSchema) Add(keys ...ConfigKey) *ConfigSchema {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, k := range keys {
+		s.keys[k.Path] = k
+	}
+	return s
+}
+
+// AllowAny declares a configuration block whose content is free-form (e.g. "static_resources"): keys under it are
+// not reported as unknown.
+func (s *ConfigSchema) AllowAny(paths ...string) *ConfigSchema {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.freeForms = append(s.freeForms, paths...)
+	return s
+}
+
+// Keys returns the declared configuration keys, sorted by path.
+func (s *ConfigSchema) Keys() []ConfigKey {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	result := make([]ConfigKey, 0, len(s.keys))
+	for _, k := range s.keys {
+		result = append(result, k)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Path < result[j].Path })
+	return result
+}
+
+func (s *ConfigSchema) isKnown(path string) bool {
+	if _, ok := s.keys[path]; ok {
+		return true
+	}
+	for _, prefix := range s.freeForms {
+		if path == prefix || strings.HasPrefix(path, prefix+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the supplied configurations against the schema and returns all violations found.
+func (s *ConfigSchema) Validate(conf *hoconf.Config) []error {
+	errs := make([]error, 0)
+	keys := s.Keys()
+	for _, k := range keys {
+		if !conf.HasPath(k.Path) {
+			if k.Required {
+				errs = append(errs, fmt.Errorf("missing required key [%s] (%s)", k.Path, k.Desc))
+			}
+			continue
+		}
+		if err := checkConfigType(conf.GetValue(k.Path), k.Type); err != nil {
+			errs = append(errs, fmt.Errorf("invalid value for key [%s]: expected %s, %s", k.Path, k.Type, err))
+		}
+	}
+
+	unknown := make([]string, 0)
+	s.lock.RLock()
+	collectConfigLeaves(conf.Root(), "", func(path string) {
+		if !s.isKnown(path) {
+			unknown = append(unknown, path)
+		}
+	})
+	s.lock.RUnlock()
+	sort.Strings(unknown)
+	for _, path := range unknown {
+		errs = append(errs, fmt.Errorf("unknown key [%s]%s", path, suggestConfigKey(keys, path)))
+	}
+	return errs
+}
+
+// suggestConfigKey returns a hint to the declared key that is likely what the supplied (unknown) key meant, if any.
+func suggestConfigKey(keys []ConfigKey, path string) string {
+	parent := path[:strings.LastIndex(path, ".")+1]
+	for _, k := range keys {
+		if strings.HasPrefix(k.Path, parent) && !strings.Contains(k.Path[len(parent):], ".") &&
+			isTypo(path[len(parent):], k.Path[len(parent):]) {
+			return ", did you mean [" + k.Path + "]?"
+		}
+	}
+	return ""
+}
+
+// isTypo returns true if b can be obtained from a by changing at most two characters (e.g. "tpye" vs "type") or by
+// inserting/removing one character.
+func isTypo(a, b string) bool {
+	if len(a) == len(b) {
+		diff := 0
+		for i := range a {
+			if a[i] != b[i] {
+				diff++
+			}
+		}
+		return diff <= 2
+	}
+	if len(a) < len(b) {
+		a, b = b, a
+	}
+	if len(a)-len(b) != 1 {
+		return false
+	}
+	for i := range b {
+		if a[i] != b[i] {
+			return a[i+1:] == b[i:]
+		}
+	}
+	return true
+}
+
+func collectConfigLeaves(node *hocon.HoconValue, path string, callback func(path string)) {
+	if node == nil {
+		return
+	}
+	if node.IsObject() {
+		for key, child := range node.GetObject().Items() {
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			collectConfigLeaves(child, childPath, callback)
+		}
+		return
+	}
+	if path != "" {
+		callback(path)
+	}
+}
+
+func checkConfigType(v *hocon.HoconValue, t ConfigType) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	switch t {
+	case ConfigTypeObject:
+		if !v.IsObject() {
+			return fmt.Errorf("received [%s]", v.String())
+		}
+		return nil
+	case ConfigTypeList:
+		// an empty list is neither an array nor a string/object
+		if !v.IsArray() && (v.IsString() || v.IsObject()) {
+			return fmt.Errorf("received [%s]", v.String())
+		}
+		return nil
+	}
+	if !v.IsString() {
+		return fmt.Errorf("received [%s]", v.String())
+	}
+	str := strings.TrimSpace(v.GetString())
+	switch t {
+	case ConfigTypeBool:
+		v.GetBoolean()
+	case ConfigTypeInt:
+		_, err = strconv.ParseInt(str, 10, 64)
+	case ConfigTypeDuration:
+		v.GetTimeDuration(true)
+	case ConfigTypeByteSize:
+		if _, e := strconv.ParseInt(str, 10, 64); e != nil {
+			v.GetByteSize()
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("received [%s]", str)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package goadmin
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+const (
+	// CsrfFormField is the form field HTML forms of control panel routes use to submit the CSRF token.
+	//
+	// Available since template-r5
+	CsrfFormField = "_csrf"
+
+	// CtxCsrfToken is the key under which the current CSRF token is stored in echo.Context, so that it can be
+	// passed to templates.
+	//
+	// Available since template-r5
+	CtxCsrfToken = "csrf"
+)
+
+// CPMiddlewares are middlewares attached to control panel route groups (see Registry.CPGroup), in the order they are
+// executed. Nil middlewares are skipped.
+//
+// Available since template-r5
+type CPMiddlewares struct {
+	Auth       echo.MiddlewareFunc // authenticates the current user, e.g. redirects to the login page if not signed in
+	Csrf       echo.MiddlewareFunc // rejects state-changing requests that do not carry a valid CSRF token
+	Audit      echo.MiddlewareFunc // records the actions performed by users
+	Permission echo.MiddlewareFunc // checks if the current user is allowed to access the route
+}
+
+func (m CPMiddlewares) list() []echo.MiddlewareFunc {
+	result := make([]echo.MiddlewareFunc, 0, 4)
+	for _, mw := range []echo.MiddlewareFunc{m.Auth, m.Csrf, m.Audit, m.Permission} {
+		if mw != nil {
+			result = append(result, mw)
+		}
+	}
+	return result
+}
+
+// newCsrfMiddleware creates the default CSRF middleware: the token is read from header X-CSRF-Token or form field
+// CsrfFormField.
+func newCsrfMiddleware() echo.MiddlewareFunc {
+	return middleware.CSRFWithConfig(middleware.CSRFConfig{
+		TokenLookup:    "header:" + echo.HeaderXCSRFToken + ",form:" + CsrfFormField,
+		ContextKey:     CtxCsrfToken,
+		CookieHTTPOnly: true,
+		CookieSameSite: http.SameSiteLaxMode,
+	})
+}
+
+// CPGroup creates a route group for control panel modules, pre-configured with the middlewares of Registry.CP (auth,
+// CSRF, audit and permission), followed by the supplied middlewares.
+//
+// Bootstrappers should set Registry.CP.Auth (and optionally Audit/Permission) before calling CPGroup. The CSRF
+// middleware is provided by goadmin; forms of control panel routes must include the token (see CsrfFormField).
+//
+// Available since template-r5
+func (r *Registry) CPGroup(prefix string, middlewares ...echo.MiddlewareFunc) *echo.Group {
+	r.cpPrefixes = append(r.cpPrefixes, prefix)
+	// middlewares added by WrapRoute run last, after the user has been authenticated
+	middlewares = append(append(r.CP.list(), middlewares...), r.routeHooksMiddleware(true))
+	// a request with a method the route does not support is answered before the user is redirected to the login page
+	middlewares = append([]echo.MiddlewareFunc{r.methodNotAllowedMiddleware(prefix)}, middlewares...)
+	return r.EchoServer.Group(prefix, middlewares...)
+}
+
+// cpGroupMethods are the methods checked by methodNotAllowedMiddleware to build the header Allow.
+var cpGroupMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// methodNotAllowedMiddleware answers 405 to requests of a control panel route group that match a route of the group
+// with another method only (e.g. GET on a mutation, see RegisterMutation). Echo routes such requests to the catch-all
+// route it adds to route groups with middlewares, which would answer 404 (or redirect to the login page), so the
+// check runs before the other middlewares of the group.
+func (r *Registry) methodNotAllowedMiddleware(prefix string) echo.MiddlewareFunc {
+	catchAll := prefix + "/*"
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if c.Path() != catchAll {
+				return next(c)
+			}
+			path := echo.GetPath(c.Request())
+			allowed := make([]string, 0, len(cpGroupMethods))
+			for _, method := range cpGroupMethods {
+				ctx := r.EchoServer.NewContext(nil, nil)
+				if r.EchoServer.Router().Find(method, path, ctx); ctx.Path() != catchAll {
+					allowed = append(allowed, method)
+				}
+			}
+			if len(allowed) == 0 {
+				return next(c)
+			}
+			c.Response().Header().Set(echo.HeaderAllow, strings.Join(allowed, ", "))
+			return echo.ErrMethodNotAllowed
+		}
+	}
+}
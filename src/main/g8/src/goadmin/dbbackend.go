@@ -0,0 +1,70 @@
+package goadmin
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DbBackendFactory creates a storage backend (e.g. a set of DAOs) from application's configurations.
+//
+// confPath is the path of the configuration block the backend should read its settings from (e.g. "myapp.db"). The
+// returned value is application-specific; the application that requests the backend asserts it to the type it expects.
+//
+// Available since template-r5
+type DbBackendFactory func(registry *Registry, confPath string) (interface{}, error)
+
+var (
+	dbBackendsLock sync.RWMutex
+	dbBackends     = make(map[string]DbBackendFactory)
+)
+
+// RegisterDbBackend makes a storage backend available by name, so that it can be selected via configuration
+// (e.g. db.type = "name") without modifying application's code. Names are case-insensitive.
+//
+// Like database/sql.Register, it is meant to be called from init functions and panics if factory is nil or a backend
+// with the same name has already been registered.
+//
+// Available since template-r5
+func RegisterDbBackend(name string, factory DbBackendFactory) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if factory == nil {
+		panic("goadmin: database backend factory is nil for [" + name + "]")
+	}
+	dbBackendsLock.Lock()
+	defer dbBackendsLock.Unlock()
+	if _, ok := dbBackends[name]; ok {
+		panic("goadmin: database backend [" + name + "] has already been registered")
+	}
+	dbBackends[name] = factory
+}
+
+// DbBackendNames returns the sorted list of registered storage backend names.
+//
+// Available since template-r5
+func DbBackendNames() []string {
+	dbBackendsLock.RLock()
+	defer dbBackendsLock.RUnlock()
+	names := make([]string, 0, len(dbBackends))
+	for name := range dbBackends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewDbBackend creates a storage backend using the factory registered under the supplied name.
+//
+// An error listing all registered backends is returned if no backend has been registered under that name.
+//
+// Available since template-r5
+func NewDbBackend(registry *Registry, name, confPath string) (interface{}, error) {
+	dbBackendsLock.RLock()
+	factory, ok := dbBackends[strings.ToLower(strings.TrimSpace(name))]
+	dbBackendsLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported database type [%s], registered backends: %s", name, strings.Join(DbBackendNames(), ", "))
+	}
+	return factory(registry, confPath)
+}
@@ -0,0 +1,148 @@
+package goadmin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"main/src/utils"
+)
+
+// DiagnosticResult is the outcome of a startup self-check.
+//
+// Available since template-r5
+type DiagnosticResult struct {
+	Name     string        `json:"name"`
+	Ok       bool          `json:"ok"`
+	Message  string        `json:"message,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Diagnostics collects results of startup self-checks (configurations, database connectivity, template and i18n
+// directories, etc) performed by goadmin and bootstrappers.
+//
+// Available since template-r5
+type Diagnostics struct {
+	lock    sync.Mutex
+	results []DiagnosticResult
+}
+
+// Check runs a self-check and records its result. A panic raised by the check is recovered and recorded as a failure.
+//
+// The error returned by the check should be actionable, e.g. "directory [./views/myapp] not found, the application
+// must be started from the project's root directory".
+func (d *Diagnostics) Check(name string, check func() error) (ok bool) {
+	start := time.Now()
+	var err error
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+		ok = err == nil
+		d.Record(DiagnosticResult{Name: name, Ok: ok, Message: errorMessage(err), Duration: time.Since(start)})
+	}()
+	err = check()
+	return
+}
+
+func errorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// Record adds a result to the report.
+func (d *Diagnostics) Record(result DiagnosticResult) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.results = append(d.results, result)
+}
+
+// Results returns all recorded results, in the order the checks were performed.
+func (d *Diagnostics) Results() []DiagnosticResult {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return append([]DiagnosticResult{}, d.results...)
+}
+
+// Failed returns results of the failed checks.
+func (d *Diagnostics) Failed() []DiagnosticResult {
+	result := make([]DiagnosticResult, 0)
+	for _, r := range d.Results() {
+		if !r.Ok {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// Report returns a human-readable report, one line per check.
+func (d *Diagnostics) Report() string {
+	lines := make([]string, 0)
+	for _, r := range d.Results() {
+		status := "OK  "
+		if !r.Ok {
+			status = "FAIL"
+		}
+		line := fmt.Sprintf("[%s] %s (%s)", status, r.Name, r.Duration.Round(time.Millisecond))
+		if r.Message != "" {
+			line += ": " + r.Message
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// WriteReport writes the report, in JSON format, to a file.
+func (d *Diagnostics) WriteReport(file string) error {
+	data, err := json.MarshalIndent(map[string]interface{}{
+		"time":    utils.Now().Format(time.RFC3339),
+		"ok":      len(d.Failed()) == 0,
+		"results": d.Results(),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0644)
+}
+
+/*----------------------------------------------------------------------*/
+
+// CheckDir returns an error if the supplied path does not exist or is not a directory.
+//
+// Available since template-r5
+func CheckDir(path string) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			curDir, _ := os.Getwd()
+			return fmt.Errorf("directory [%s] not found (current directory is [%s]), make sure the application is started from the project's root directory", path, curDir)
+		}
+		return err
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("[%s] is not a directory", path)
+	}
+	return nil
+}
+
+// CheckConfigKeys returns an error listing the supplied configuration keys that are missing or empty.
+//
+// Available since template-r5
+func (r *Registry) CheckConfigKeys(keys ...string) error {
+	missing := make([]string, 0)
+	for _, key := range keys {
+		if !r.AppConfig.HasPath(key) || strings.TrimSpace(r.AppConfig.GetString(key, "")) == "" {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing configuration key(s): %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package goadmin
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	hocon "github.com/go-akka/configuration"
+	"main/src/utils"
+)
+
+// defaultE2eTime is the time the clock is frozen at in end-to-end test mode, if setting goadmin.e2e.time is empty.
+const defaultE2eTime = "2022-01-01T09:00:00Z"
+
+// e2eAppEnv is the environment (env APP_ENV) end-to-end test mode is allowed in without dev_mode.
+const e2eAppEnv = "e2e"
+
+// initE2eMode turns on end-to-end test mode (setting goadmin.e2e): the clock is frozen and random strings and ids are
+// generated from a fixed seed, so that browser-based tests (e.g. Playwright, Cypress) get the same pages on every run.
+// Combine with an in-memory database so that every run starts from the same data, see configuration profile "e2e".
+//
+// A frozen clock never expires anything: the mode is refused unless dev_mode is on or env APP_ENV is "e2e", so that it
+// cannot be turned on in production by mistake.
+func initE2eMode(conf *hocon.Config) error {
+	if !utils.DevMode && AppEnv() != e2eAppEnv {
+		return fmt.Errorf("end-to-end test mode [goadmin.e2e.enabled] requires dev_mode or APP_ENV=%s", e2eAppEnv)
+	}
+	value := conf.GetString("goadmin.e2e.time", "")
+	if value == "" {
+		value = defaultE2eTime
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return fmt.Errorf("invalid [goadmin.e2e.time] setting, expecting RFC 3339 format: %s", err)
+	}
+	seed := conf.GetInt64("goadmin.e2e.seed", 1)
+	utils.FreezeClock(t)
+	utils.SeedRandom(seed)
+	log.Printf("[WARN] end-to-end test mode is on: clock frozen at %s, random seed %d", t.Format(time.RFC3339), seed)
+	return nil
+}
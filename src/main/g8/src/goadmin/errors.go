@@ -0,0 +1,94 @@
+package goadmin
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ErrorLocalizer translates the message of an HTTP error generated by the framework (e.g. route not found, method not
+// allowed, binding or CSRF errors) to the locale of the current request. Empty string means "keep the original
+// message".
+//
+// Available since template-r5
+type ErrorLocalizer func(c echo.Context, err *echo.HTTPError) string
+
+// httpErrorHandler is Echo's HTTP error handler: the error message is translated via Registry.ErrorLocalizer (if any)
+// and redacted by the log sanitizer before the error page or JSON envelope is rendered by Echo's default error
+// handler. Internal server errors are logged, see logServerError.
+func (r *Registry) httpErrorHandler(err error, c echo.Context) {
+	he, ok := err.(*echo.HTTPError)
+	if !ok {
+		he = &echo.HTTPError{Code: http.StatusInternalServerError, Message: http.StatusText(http.StatusInternalServerError), Internal: err}
+	} else if internal, ok := he.Internal.(*echo.HTTPError); ok {
+		he = internal
+	}
+	if he.Code == http.StatusInternalServerError {
+		logServerError(c, he)
+	}
+	if r.ErrorLocalizer != nil && !c.Response().Committed {
+		if msg := r.ErrorLocalizer(c, he); msg != "" {
+			he = &echo.HTTPError{Code: he.Code, Message: msg, Internal: he.Internal}
+		}
+	}
+	if msg, ok := he.Message.(string); ok {
+		// messages may echo user input, e.g. an invalid email address
+		he = &echo.HTTPError{Code: he.Code, Message: SanitizeLog(msg), Internal: he.Internal}
+	}
+	c.Echo().DefaultHTTPErrorHandler(he, c)
+}
+
+// logServerError logs an internal server error with the submitted form data and, if caused by a panic, the stack
+// trace. Values of sensitive fields are masked (see LogSanitizer.SanitizeValues), the rest is redacted when written
+// to the log.
+func logServerError(c echo.Context, he *echo.HTTPError) {
+	req := c.Request()
+	cause := he.Internal
+	if cause == nil {
+		cause = fmt.Errorf("%v", he.Message)
+	}
+	msg := fmt.Sprintf("[ERROR] %s %s: %s", req.Method, req.RequestURI, cause)
+	// the body is not read again, only form data already parsed by the handler is logged
+	if form := req.PostForm; len(form) > 0 {
+		if s := GetLogSanitizer(); s != nil {
+			msg += " (form: " + s.SanitizeValues(form) + ")"
+		} else {
+			msg += " (form: " + form.Encode() + ")"
+		}
+	}
+	if pe, ok := cause.(*panicError); ok {
+		msg += "\n" + string(pe.stack)
+	}
+	log.Print(msg)
+}
+
+// panicError is the error a request handler panicked with, see recoverPanics.
+type panicError struct {
+	value interface{}
+	stack []byte
+}
+
+// Error implements error.Error
+func (e *panicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.value)
+}
+
+// recoverPanics turns panics of request handlers into internal server errors, logged with their stack trace by the
+// HTTP error handler.
+func recoverPanics(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		defer func() {
+			if v := recover(); v != nil {
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+				err = &echo.HTTPError{Code: http.StatusInternalServerError, Message: http.StatusText(http.StatusInternalServerError),
+					Internal: &panicError{value: v, stack: debug.Stack()}}
+			}
+		}()
+		return next(c)
+	}
+}
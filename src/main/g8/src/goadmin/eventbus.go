@@ -0,0 +1,262 @@
+package goadmin
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// EventBusDriverNats publishes events to a NATS server.
+	//
+	// Available since template-r5
+	EventBusDriverNats = "nats"
+
+	// EventBusDriverKafkaRest publishes events to Kafka through a Kafka REST Proxy (v2 API).
+	//
+	// Available since template-r5
+	EventBusDriverKafkaRest = "kafka_rest"
+
+	// OutboxChannelEventBus is the outbox channel retrying EventBusPayload messages that could not be published
+	// right away.
+	//
+	// Available since template-r5
+	OutboxChannelEventBus = "event_bus"
+)
+
+// EventPublisher publishes events to a message bus (e.g. Kafka or NATS), so that other systems can consume changes
+// made in the application in real time.
+//
+// Available since template-r5
+type EventPublisher interface {
+	// Publish sends body (a JSON document) to topic, key identifies the entity the event is about (e.g. a username)
+	// and is used for partitioning if the bus supports it.
+	Publish(topic, key string, body []byte) error
+}
+
+// EventBusPayload is the payload of OutboxChannelEventBus messages.
+//
+// Available since template-r5
+type EventBusPayload struct {
+	Topic string          `json:"topic"`
+	Key   string          `json:"key"`
+	Body  json.RawMessage `json:"body"`
+}
+
+// initEventBus creates the event publisher from configuration block goadmin.event_bus, Registry.EventBus is left nil
+// if no driver is configured. Events that could not be published are retried via the outbox.
+func initEventBus(registry *Registry) error {
+	conf := registry.AppConfig
+	confPath := "goadmin.event_bus"
+	var publisher EventPublisher
+	timeout := conf.GetTimeDuration(confPath+".timeout", 5*time.Second)
+	switch driver := conf.GetString(confPath+".driver", ""); driver {
+	case "":
+		return nil
+	case EventBusDriverNats:
+		publisher = NewNatsPublisher(conf.GetString(confPath+".nats.url", "nats://localhost:4222"), timeout)
+	case EventBusDriverKafkaRest:
+		publisher = NewKafkaRestPublisher(conf.GetString(confPath+".kafka_rest.url", "http://localhost:8082"), timeout)
+	default:
+		return fmt.Errorf("unknown event bus driver [%s]", driver)
+	}
+	registry.EventBus = publisher
+	registry.eventTopicPrefix = conf.GetString(confPath+".topic_prefix", "")
+	registry.Outbox.RegisterSender(OutboxChannelEventBus, func(payload json.RawMessage) error {
+		msg := &EventBusPayload{}
+		if err := json.Unmarshal(payload, msg); err != nil {
+			return err
+		}
+		return publisher.Publish(msg.Topic, msg.Key, msg.Body)
+	})
+	return nil
+}
+
+// PublishEvent mirrors an event to the message bus (if configured, setting goadmin.event_bus), topic is prefixed with
+// setting goadmin.event_bus.topic_prefix. The event is published in background so that requests are not slowed down;
+// if publishing fails, it is retried via the outbox, hence events may arrive out of order.
+//
+// Available since template-r5
+func (r *Registry) PublishEvent(topic, key string, body []byte) {
+	if r.EventBus == nil {
+		return
+	}
+	topic = r.eventTopicPrefix + topic
+	go func() {
+		err := r.EventBus.Publish(topic, key, body)
+		if err == nil {
+			return
+		}
+		log.Printf("[WARN] cannot publish event to [%s], will retry: %s", topic, err)
+		if _, err := r.Outbox.Enqueue(OutboxChannelEventBus, &EventBusPayload{Topic: topic, Key: key, Body: body}); err != nil {
+			log.Printf("[ERROR] cannot enqueue event to [%s]: %s", topic, err)
+		}
+	}()
+}
+
+/*----------------------------------------------------------------------*/
+
+// NewNatsPublisher creates an EventPublisher sending events to a NATS server (core NATS, no JetStream). natsUrl is
+// "nats://[user:password@]host:port" or "nats://token@host:port".
+//
+// Only the few protocol operations needed to publish (CONNECT, PUB, PING/PONG) are implemented; the connection is
+// opened on demand and reopened after errors.
+//
+// Available since template-r5
+func NewNatsPublisher(natsUrl string, timeout time.Duration) EventPublisher {
+	return &natsPublisher{url: natsUrl, timeout: timeout}
+}
+
+type natsPublisher struct {
+	url     string
+	timeout time.Duration
+	lock    sync.Mutex
+	conn    net.Conn
+	reader  *bufio.Reader
+}
+
+// Publish implements EventPublisher.Publish
+func (p *natsPublisher) Publish(topic, _ string, body []byte) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.conn == nil {
+		if err := p.connect(); err != nil {
+			return err
+		}
+	}
+	// PING after PUB: the server answers PONG once the message is processed, or -ERR if it is rejected
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "PUB %s %d\r\n", topic, len(body))
+	buf.Write(body)
+	buf.WriteString("\r\nPING\r\n")
+	err := p.roundTrip(buf.Bytes())
+	if err != nil {
+		p.conn.Close()
+		p.conn = nil
+	}
+	return err
+}
+
+func (p *natsPublisher) connect() error {
+	u, err := url.Parse(p.url)
+	if err != nil {
+		return err
+	}
+	conn, err := net.DialTimeout("tcp", u.Host, p.timeout)
+	if err != nil {
+		return err
+	}
+	p.conn, p.reader = conn, bufio.NewReader(conn)
+	conn.SetDeadline(time.Now().Add(p.timeout))
+	// the server greets with INFO {...}
+	if line, err := p.reader.ReadString('\n'); err != nil || !strings.HasPrefix(line, "INFO") {
+		conn.Close()
+		p.conn = nil
+		return fmt.Errorf("unexpected NATS greeting %q: %v", line, err)
+	}
+	options := map[string]interface{}{"verbose": false, "pedantic": false, "name": "goadmin", "lang": "go", "version": "1.0.0"}
+	if u.User != nil {
+		if pwd, ok := u.User.Password(); ok {
+			options["user"], options["pass"] = u.User.Username(), pwd
+		} else {
+			options["auth_token"] = u.User.Username()
+		}
+	}
+	js, _ := json.Marshal(options)
+	if err := p.roundTrip([]byte("CONNECT " + string(js) + "\r\nPING\r\n")); err != nil {
+		conn.Close()
+		p.conn = nil
+		return err
+	}
+	return nil
+}
+
+// roundTrip writes data ending with PING and waits for the server's PONG.
+func (p *natsPublisher) roundTrip(data []byte) error {
+	p.conn.SetDeadline(time.Now().Add(p.timeout))
+	if _, err := p.conn.Write(data); err != nil {
+		return err
+	}
+	for {
+		line, err := p.reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		switch line = strings.TrimSpace(line); {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			p.conn.Write([]byte("PONG\r\n"))
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("NATS error: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+/*----------------------------------------------------------------------*/
+
+// NewKafkaRestPublisher creates an EventPublisher sending events to Kafka through a Kafka REST Proxy, proxyUrl is
+// the base URL of the proxy (e.g. "http://localhost:8082"). Events are produced with the v2 JSON API, the key and the
+// value being JSON documents.
+//
+// Available since template-r5
+func NewKafkaRestPublisher(proxyUrl string, timeout time.Duration) EventPublisher {
+	return &kafkaRestPublisher{url: strings.TrimSuffix(proxyUrl, "/"), client: &http.Client{Timeout: timeout}}
+}
+
+type kafkaRestPublisher struct {
+	url    string
+	client *http.Client
+}
+
+// Publish implements EventPublisher.Publish
+func (p *kafkaRestPublisher) Publish(topic, key string, body []byte) error {
+	record := map[string]interface{}{"value": json.RawMessage(body)}
+	if key != "" {
+		record["key"] = key
+	}
+	js, err := json.Marshal(map[string]interface{}{"records": []interface{}{record}})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, p.url+"/topics/"+url.PathEscape(topic), bytes.NewReader(js))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Kafka REST proxy responded with status %d: %s", resp.StatusCode, respBody)
+	}
+	// records may fail individually, errors are reported in the offsets of the response
+	result := struct {
+		Offsets []struct {
+			ErrorCode *int   `json:"error_code"`
+			Error     string `json:"error"`
+		} `json:"offsets"`
+	}{}
+	if err := json.Unmarshal(respBody, &result); err == nil {
+		for _, o := range result.Offsets {
+			if o.ErrorCode != nil {
+				return fmt.Errorf("Kafka REST proxy error %d: %s", *o.ErrorCode, o.Error)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package goadmin
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	hocon "github.com/go-akka/configuration"
+)
+
+// encryptedFieldPrefix marks encrypted values: "enc:<key id>:<base64 of nonce and sealed value>".
+const encryptedFieldPrefix = "enc:"
+
+// ErrFieldKeyNotFound is returned by FieldCipher.Decrypt if a value was encrypted with a key that is not configured.
+//
+// Available since template-r5
+var ErrFieldKeyNotFound = errors.New("encryption key of the value not found")
+
+// NewFieldCipher creates a FieldCipher from keys (AES keys of 16, 24 or 32 bytes, by id). New values are encrypted
+// with the key currentKey; other keys are kept to decrypt values encrypted before a key rotation.
+//
+// Available since template-r5
+func NewFieldCipher(keys map[string][]byte, currentKey string) (*FieldCipher, error) {
+	fc := &FieldCipher{aeads: make(map[string]cipher.AEAD), current: currentKey}
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid encryption key id [%s]", id)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryption key [%s]: %s", id, err)
+		}
+		if fc.aeads[id], err = cipher.NewGCM(block); err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := fc.aeads[currentKey]; !ok {
+		return nil, fmt.Errorf("current encryption key [%s] not found", currentKey)
+	}
+	return fc, nil
+}
+
+// FieldCipher encrypts values of sensitive fields (e.g. users' names) before they are stored, with AES-GCM. Encrypted
+// values carry the id of their key, so that keys can be rotated: values encrypted with previous keys are still
+// decrypted, and can be re-encrypted with the current key (see NeedsReencryption).
+//
+// Values that are not encrypted (e.g. stored before encryption was enabled) are returned as-is by Decrypt.
+//
+// Available since template-r5
+type FieldCipher struct {
+	aeads   map[string]cipher.AEAD
+	current string
+}
+
+// CurrentKey returns the id of the key new values are encrypted with.
+func (fc *FieldCipher) CurrentKey() string {
+	return fc.current
+}
+
+// Encrypt encrypts a value with the current key, empty values are not encrypted.
+func (fc *FieldCipher) Encrypt(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	aead := fc.aeads[fc.current]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	// the key id is authenticated, so that a value can not be moved to another key's id
+	sealed := aead.Seal(nonce, nonce, []byte(value), []byte(fc.current))
+	return encryptedFieldPrefix + fc.current + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a value returned by Encrypt, values that are not encrypted are returned as-is.
+func (fc *FieldCipher) Decrypt(value string) (string, error) {
+	id, data, ok := parseEncryptedField(value)
+	if !ok {
+		return value, nil
+	}
+	aead, ok := fc.aeads[id]
+	if !ok {
+		return "", fmt.Errorf("%w: [%s]", ErrFieldKeyNotFound, id)
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(data)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", errors.New("malformed encrypted value")
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(id))
+	if err != nil {
+		return "", fmt.Errorf("cannot decrypt value encrypted with key [%s]: %s", id, err)
+	}
+	return string(plain), nil
+}
+
+// NeedsReencryption checks if a stored value is not encrypted, or encrypted with a key other than the current one.
+func (fc *FieldCipher) NeedsReencryption(value string) bool {
+	if value == "" {
+		return false
+	}
+	id, _, ok := parseEncryptedField(value)
+	return !ok || id != fc.current
+}
+
+func parseEncryptedField(value string) (keyId, data string, ok bool) {
+	if !strings.HasPrefix(value, encryptedFieldPrefix) {
+		return "", "", false
+	}
+	tokens := strings.SplitN(value[len(encryptedFieldPrefix):], ":", 2)
+	if len(tokens) != 2 {
+		return "", "", false
+	}
+	return tokens[0], tokens[1], true
+}
+
+// NewFieldCipherFromConfig creates the FieldCipher configured at confPath (e.g. goadmin.field_encryption): keys is a
+// map of key ids to base64-encoded AES keys, current_key the id of the key new values are encrypted with. It returns
+// nil if current_key is empty (encryption disabled).
+//
+// Available since template-r5
+func NewFieldCipherFromConfig(conf *hocon.Config, confPath string) (*FieldCipher, error) {
+	current := conf.GetString(confPath+".current_key", "")
+	if current == "" {
+		return nil, nil
+	}
+	keys := make(map[string][]byte)
+	ids := make([]string, 0)
+	if v := conf.GetValue(confPath + ".keys"); v != nil && v.IsObject() {
+		for id := range v.GetObject().Items() {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		encoded := conf.GetString(confPath+".keys."+id, "")
+		if encoded == "" {
+			// e.g. key read from an environment variable that is not set
+			continue
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key [%s] is not base64-encoded: %s", id, err)
+		}
+		keys[id] = key
+	}
+	return NewFieldCipher(keys, current)
+}
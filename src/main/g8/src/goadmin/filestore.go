@@ -0,0 +1,98 @@
+package goadmin
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrInvalidFileName is returned by FileStore operations if the file name is empty or escapes the store (e.g.
+// "../secret").
+//
+// Available since template-r5
+var ErrInvalidFileName = errors.New("invalid file name")
+
+// FileStore stores files produced by the application, e.g. processed uploads. Names are slash-separated relative paths
+// (e.g. "avatars/abc_small.jpg").
+//
+// Available since template-r5
+type FileStore interface {
+	// Put stores the content under name, replacing the existing file (if any).
+	Put(name string, content io.Reader) error
+	// Open opens a stored file, the error satisfies os.IsNotExist if the file does not exist.
+	Open(name string) (io.ReadCloser, error)
+	// Delete removes a stored file, deleting a non-existing file is not an error.
+	Delete(name string) error
+}
+
+// NewDirFileStore creates a FileStore that keeps files in a local directory.
+//
+// Available since template-r5
+func NewDirFileStore(dir string) FileStore {
+	return &dirFileStore{dir: dir}
+}
+
+type dirFileStore struct {
+	dir string
+}
+
+// filePath returns the local path of a stored file.
+func (s *dirFileStore) filePath(name string) (string, error) {
+	cleaned := strings.TrimPrefix(path.Clean("/"+name), "/")
+	if cleaned == "" || cleaned != strings.TrimPrefix(name, "/") {
+		return "", ErrInvalidFileName
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(cleaned)), nil
+}
+
+// Put implements FileStore.Put
+func (s *dirFileStore) Put(name string, content io.Reader) error {
+	target, err := s.filePath(name)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(target), 0711); err != nil {
+		return err
+	}
+	// content is written to a temporary file first, so that readers never see a partially written file
+	tmp, err := ioutil.TempFile(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tmp, content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), target)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// Open implements FileStore.Open
+func (s *dirFileStore) Open(name string) (io.ReadCloser, error) {
+	target, err := s.filePath(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(target)
+}
+
+// Delete implements FileStore.Delete
+func (s *dirFileStore) Delete(name string) error {
+	target, err := s.filePath(name)
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(target); os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,459 @@
+package goadmin
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GeoLocation is the geographical location of an IP address.
+//
+// Available since template-r5
+type GeoLocation struct {
+	CountryCode string            // ISO 3166-1 country code, e.g. "VN"
+	Country     map[string]string // country names, indexed by locale
+	City        map[string]string // city names, indexed by locale (empty if the database has no city data)
+}
+
+// CountryName returns name of the country in the supplied locale, falling back to English.
+func (l *GeoLocation) CountryName(locale string) string {
+	return geoName(l.Country, locale)
+}
+
+// CityName returns name of the city in the supplied locale, falling back to English.
+func (l *GeoLocation) CityName(locale string) string {
+	return geoName(l.City, locale)
+}
+
+// String returns "City, Country" (or "Country" if the city is unknown) in English.
+func (l *GeoLocation) String() string {
+	result := l.CountryName("en")
+	if result == "" {
+		result = l.CountryCode
+	}
+	if city := l.CityName("en"); city != "" {
+		result = city + ", " + result
+	}
+	return result
+}
+
+func geoName(names map[string]string, locale string) string {
+	if name, ok := names[locale]; ok {
+		return name
+	}
+	return names["en"]
+}
+
+// GeoIp looks up geographical locations of IP addresses from a MaxMind database (GeoLite2/GeoIP2 Country or City,
+// .mmdb format). The database file can be refreshed in background, see GeoIp.StartRefresher.
+//
+// Available since template-r5
+type GeoIp struct {
+	path string
+	lock sync.RWMutex
+	db   *mmdbReader
+	mod  time.Time
+}
+
+// OpenGeoIp opens the MaxMind database file at the supplied path.
+//
+// Available since template-r5
+func OpenGeoIp(path string) (*GeoIp, error) {
+	g := &GeoIp{path: path}
+	return g, g.Reload()
+}
+
+// Reload re-reads the database file.
+func (g *GeoIp) Reload() error {
+	info, err := os.Stat(g.path)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(g.path)
+	if err != nil {
+		return err
+	}
+	db, err := newMmdbReader(data)
+	if err != nil {
+		return fmt.Errorf("error reading GeoIP database [%s]: %s", g.path, err)
+	}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.db, g.mod = db, info.ModTime()
+	return nil
+}
+
+// Lookup returns the geographical location of the IP address, nil if not found.
+func (g *GeoIp) Lookup(ip string) (*GeoLocation, error) {
+	parsed := net.ParseIP(strings.TrimSpace(ip))
+	if parsed == nil {
+		return nil, fmt.Errorf("invalid IP address [%s]", ip)
+	}
+	g.lock.RLock()
+	db := g.db
+	g.lock.RUnlock()
+	record, err := db.lookup(parsed)
+	if record == nil || err != nil {
+		return nil, err
+	}
+	loc := &GeoLocation{}
+	if country, ok := record["country"].(map[string]interface{}); ok {
+		loc.CountryCode, _ = country["iso_code"].(string)
+		loc.Country = geoNames(country["names"])
+	}
+	if city, ok := record["city"].(map[string]interface{}); ok {
+		loc.City = geoNames(city["names"])
+	}
+	return loc, nil
+}
+
+func geoNames(v interface{}) map[string]string {
+	result := make(map[string]string)
+	if names, ok := v.(map[string]interface{}); ok {
+		for locale, name := range names {
+			if s, ok := name.(string); ok {
+				result[locale] = s
+			}
+		}
+	}
+	return result
+}
+
+// Describe returns the location of the IP address as "City, Country", or empty string if unknown.
+func (g *GeoIp) Describe(ip string) string {
+	if g == nil {
+		return ""
+	}
+	if loc, err := g.Lookup(ip); err == nil && loc != nil {
+		return loc.String()
+	}
+	return ""
+}
+
+// StartRefresher starts a background job that refreshes the database every interval, until the returned function
+// is called:
+//
+//   - if downloadUrl is not empty, the database is downloaded from the URL (either a .mmdb file or a .tar.gz archive
+//     containing one, e.g. MaxMind's download permalink) and replaces the database file.
+//   - otherwise, the database file is reloaded if it has been modified (e.g. by MaxMind's geoipupdate tool).
+func (g *GeoIp) StartRefresher(interval time.Duration, downloadUrl string) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := g.refresh(downloadUrl); err != nil {
+					log.Printf("[ERROR] error refreshing GeoIP database [%s]: %s", g.path, err)
+				}
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+func (g *GeoIp) refresh(downloadUrl string) error {
+	if downloadUrl != "" {
+		if err := downloadGeoIpDb(downloadUrl, g.path); err != nil {
+			return err
+		}
+		log.Printf("GeoIP database [%s] has been downloaded", g.path)
+		return g.Reload()
+	}
+	info, err := os.Stat(g.path)
+	if err != nil {
+		return err
+	}
+	g.lock.RLock()
+	modified := info.ModTime().After(g.mod)
+	g.lock.RUnlock()
+	if !modified {
+		return nil
+	}
+	log.Printf("GeoIP database [%s] has been modified, reloading...", g.path)
+	return g.Reload()
+}
+
+func downloadGeoIpDb(url, path string) error {
+	client := &http.Client{Timeout: 5 * time.Minute}
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download failed: %s", resp.Status)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b {
+		if data, err = extractMmdb(data); err != nil {
+			return err
+		}
+	}
+	if _, err = newMmdbReader(data); err != nil {
+		return fmt.Errorf("downloaded file is not a valid GeoIP database: %s", err)
+	}
+	// write to a temp file then rename, so that the database file is never partially written
+	tmp := path + ".tmp"
+	if err = ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// extractMmdb extracts the first .mmdb file of a .tar.gz archive.
+func extractMmdb(archive []byte) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, errors.New("no .mmdb file found in the archive")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasSuffix(filepath.Base(header.Name), ".mmdb") {
+			return ioutil.ReadAll(tr)
+		}
+	}
+}
+
+/*----------------------------------------------------------------------*/
+
+// mmdbReader reads MaxMind DB files, see https://maxmind.github.io/MaxMind-DB/
+type mmdbReader struct {
+	data       []byte
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	dataStart  uint
+	ipv4Start  uint
+}
+
+var mmdbMetadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+func newMmdbReader(data []byte) (*mmdbReader, error) {
+	pos := bytes.LastIndex(data, mmdbMetadataMarker)
+	if pos < 0 {
+		return nil, errors.New("metadata not found")
+	}
+	metaStart := uint(pos + len(mmdbMetadataMarker))
+	meta, _, err := (&mmdbDecoder{data: data[metaStart:]}).decode(0)
+	if err != nil {
+		return nil, err
+	}
+	metaMap, ok := meta.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("invalid metadata")
+	}
+	r := &mmdbReader{data: data}
+	r.nodeCount, r.recordSize, r.ipVersion = mmdbUint(metaMap["node_count"]), mmdbUint(metaMap["record_size"]), mmdbUint(metaMap["ip_version"])
+	if r.recordSize != 24 && r.recordSize != 28 && r.recordSize != 32 {
+		return nil, fmt.Errorf("unsupported record size %d", r.recordSize)
+	}
+	r.dataStart = r.nodeCount*r.recordSize/4 + 16
+	if r.dataStart > metaStart {
+		return nil, errors.New("invalid search tree size")
+	}
+	// IPv4 addresses are looked up in IPv6 trees as ::a.b.c.d
+	if r.ipVersion == 6 {
+		for i := 0; i < 96 && r.ipv4Start < r.nodeCount; i++ {
+			r.ipv4Start = r.readNode(r.ipv4Start, 0)
+		}
+	}
+	return r, nil
+}
+
+func mmdbUint(v interface{}) uint {
+	switch n := v.(type) {
+	case uint64:
+		return uint(n)
+	case int:
+		return uint(n)
+	}
+	return 0
+}
+
+func (r *mmdbReader) readNode(node, bit uint) uint {
+	offset := node * r.recordSize / 4
+	b := r.data[offset : offset+r.recordSize/4]
+	switch r.recordSize {
+	case 24:
+		if bit == 0 {
+			return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3])<<16 | uint(b[4])<<8 | uint(b[5])
+	case 28:
+		if bit == 0 {
+			return uint(b[3]&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	}
+	if bit == 0 {
+		return uint(binary.BigEndian.Uint32(b[0:4]))
+	}
+	return uint(binary.BigEndian.Uint32(b[4:8]))
+}
+
+// lookup returns the record of the IP address, nil if not found.
+func (r *mmdbReader) lookup(ip net.IP) (map[string]interface{}, error) {
+	node, ip4 := uint(0), ip.To4()
+	if ip4 != nil {
+		ip, node = ip4, r.ipv4Start
+	} else if r.ipVersion == 4 {
+		return nil, errors.New("IPv6 lookup in an IPv4-only database")
+	}
+	for i := 0; i < len(ip)*8 && node < r.nodeCount; i++ {
+		node = r.readNode(node, uint(ip[i/8]>>(7-uint(i%8))&1))
+	}
+	if node == r.nodeCount {
+		return nil, nil
+	}
+	if node < r.nodeCount {
+		return nil, errors.New("invalid search tree")
+	}
+	dec := &mmdbDecoder{data: r.data[r.dataStart:]}
+	v, _, err := dec.decode(node - r.nodeCount - 16)
+	if err != nil {
+		return nil, err
+	}
+	record, _ := v.(map[string]interface{})
+	return record, nil
+}
+
+type mmdbDecoder struct {
+	data []byte
+}
+
+func (d *mmdbDecoder) bytes(offset, size uint) ([]byte, error) {
+	if offset+size > uint(len(d.data)) {
+		return nil, errors.New("unexpected end of data")
+	}
+	return d.data[offset : offset+size], nil
+}
+
+func (d *mmdbDecoder) uint(offset, size uint) (uint64, error) {
+	b, err := d.bytes(offset, size)
+	var result uint64
+	for _, v := range b {
+		result = result<<8 | uint64(v)
+	}
+	return result, err
+}
+
+// decode decodes the value at the supplied offset and returns it along with the offset of the next value.
+func (d *mmdbDecoder) decode(offset uint) (interface{}, uint, error) {
+	ctrl, err := d.bytes(offset, 1)
+	if err != nil {
+		return nil, 0, err
+	}
+	offset++
+	typ, size := uint(ctrl[0]>>5), uint(ctrl[0]&0x1F)
+	if typ == 1 {
+		// pointer
+		ss, vvv := size>>3, uint64(size&0x7)
+		p, err := d.uint(offset, ss+1)
+		if err != nil {
+			return nil, 0, err
+		}
+		switch ss {
+		case 0:
+			p = vvv<<8 | p
+		case 1:
+			p = (vvv<<16 | p) + 2048
+		case 2:
+			p = (vvv<<24 | p) + 526336
+		}
+		v, _, err := d.decode(uint(p))
+		return v, offset + ss + 1, err
+	}
+	if typ == 0 {
+		ext, err := d.bytes(offset, 1)
+		if err != nil {
+			return nil, 0, err
+		}
+		typ, offset = 7+uint(ext[0]), offset+1
+	}
+	if size >= 29 {
+		n := size - 28
+		extra, err := d.uint(offset, n)
+		if err != nil {
+			return nil, 0, err
+		}
+		size, offset = []uint{29, 285, 65821}[n-1]+uint(extra), offset+n
+	}
+	switch typ {
+	case 2: // string
+		b, err := d.bytes(offset, size)
+		return string(b), offset + size, err
+	case 3: // double
+		v, err := d.uint(offset, 8)
+		return math.Float64frombits(v), offset + 8, err
+	case 4: // bytes
+		b, err := d.bytes(offset, size)
+		return b, offset + size, err
+	case 5, 6, 9: // unsigned integers
+		v, err := d.uint(offset, size)
+		return v, offset + size, err
+	case 8: // int32
+		v, err := d.uint(offset, size)
+		return int(int32(uint32(v))), offset + size, err
+	case 10: // uint128
+		b, err := d.bytes(offset, size)
+		return b, offset + size, err
+	case 7: // map
+		result := make(map[string]interface{}, size)
+		for i := uint(0); i < size; i++ {
+			var k, v interface{}
+			if k, offset, err = d.decode(offset); err != nil {
+				return nil, 0, err
+			}
+			if v, offset, err = d.decode(offset); err != nil {
+				return nil, 0, err
+			}
+			key, _ := k.(string)
+			result[key] = v
+		}
+		return result, offset, nil
+	case 11: // array
+		result := make([]interface{}, size)
+		for i := uint(0); i < size; i++ {
+			if result[i], offset, err = d.decode(offset); err != nil {
+				return nil, 0, err
+			}
+		}
+		return result, offset, nil
+	case 14: // boolean
+		return size != 0, offset, nil
+	case 15: // float
+		v, err := d.uint(offset, 4)
+		return float64(math.Float32frombits(uint32(v))), offset + 4, err
+	}
+	return nil, 0, fmt.Errorf("unsupported data type %d", typ)
+}
@@ -0,0 +1,274 @@
+package goadmin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/btnguyen2k/goyai"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// I18nCommonNamespace is the namespace of the i18n bundle shared by all modules: messages not found in a module's
+	// own bundle are resolved in the common bundle.
+	//
+	// Available since template-r5
+	I18nCommonNamespace = "common"
+
+	// I18nNamespaceSeparator separates the namespace from the key of namespaced message ids, e.g. "common:home".
+	//
+	// Available since template-r5
+	I18nNamespaceSeparator = ":"
+
+	// ViewDataI18n is the key of view data under which Registry.Renderer passes the i18n helper of the rendering
+	// namespace to templates (see I18nBundles.Namespace), unless the key is already set.
+	//
+	// Available since template-r5
+	ViewDataI18n = "i18n"
+)
+
+// NewI18nBundles creates a new empty I18nBundles.
+//
+// Available since template-r5
+func NewI18nBundles() *I18nBundles {
+	return &I18nBundles{bundles: make(map[string][]*i18nBundle)}
+}
+
+type i18nBundle struct {
+	i18n     goyai.I18n
+	messages map[string]map[string]string // locale -> key -> text
+}
+
+func (b *i18nBundle) hasKey(key string) bool {
+	for _, texts := range b.messages {
+		if _, ok := texts[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// I18nBundles holds i18n bundles registered by modules, one or more per namespace, so that keys of different modules
+// neither collide nor silently shadow each other:
+//
+//   - a message id is resolved in the caller's namespace first, then in the common namespace (I18nCommonNamespace).
+//   - a namespaced message id ("namespace:key", e.g. "common:home") is resolved in the specified namespace only.
+//   - bundles loaded into an existing namespace are merged into it, later bundles take precedence.
+//   - conflicting keys are reported as warnings on load.
+//
+// Available since template-r5
+type I18nBundles struct {
+	lock    sync.RWMutex
+	bundles map[string][]*i18nBundle // namespace -> bundles, in loading order
+}
+
+// Load builds an i18n bundle from the files (YAML or JSON) in the supplied directory and merges it into the
+// namespace.
+func (b *I18nBundles) Load(namespace, dir, defaultLocale string) error {
+	if err := CheckDir(dir); err != nil {
+		return err
+	}
+	i18n, err := goyai.BuildI18n(goyai.I18nOptions{
+		ConfigFileOrDir: dir,
+		DefaultLocale:   defaultLocale,
+		I18nFileFormat:  goyai.Auto,
+	})
+	if err != nil {
+		return err
+	}
+	messages, err := LoadI18nMessages(dir)
+	if err != nil {
+		return err
+	}
+	b.Register(namespace, i18n, messages)
+	return nil
+}
+
+// Register merges an i18n bundle, whose messages are supplied as a map of locale -> key -> text, into the namespace.
+// Keys that conflict with the ones already registered are reported as warnings.
+func (b *I18nBundles) Register(namespace string, i18n goyai.I18n, messages map[string]map[string]string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for _, warning := range b.conflicts(namespace, messages) {
+		log.Printf("[WARN] i18n: %s", warning)
+	}
+	b.bundles[namespace] = append(b.bundles[namespace], &i18nBundle{i18n: i18n, messages: messages})
+}
+
+// conflicts lists keys of the supplied messages that are already defined, in the same namespace (overridden), in
+// the common namespace (shadowed) or in another module's namespace (ambiguous without the namespace prefix).
+func (b *I18nBundles) conflicts(namespace string, messages map[string]map[string]string) []string {
+	keys := make(map[string]bool)
+	for _, texts := range messages {
+		for key := range texts {
+			keys[key] = true
+		}
+	}
+	result := make([]string, 0)
+	for key := range keys {
+		for ns := range b.bundles {
+			if !b.hasKey(ns, key) {
+				continue
+			}
+			switch {
+			case ns == namespace:
+				result = append(result, fmt.Sprintf("key [%s] of namespace [%s] is overridden by the newly loaded bundle", key, ns))
+			case ns == I18nCommonNamespace:
+				result = append(result, fmt.Sprintf("key [%s] of namespace [%s] shadows the common one", key, namespace))
+			case namespace == I18nCommonNamespace:
+				result = append(result, fmt.Sprintf("common key [%s] is shadowed by namespace [%s]", key, ns))
+			default:
+				result = append(result, fmt.Sprintf("key [%s] is defined in both namespaces [%s] and [%s], use [%s%s%s] or [%s%s%s] to disambiguate",
+					key, ns, namespace, ns, I18nNamespaceSeparator, key, namespace, I18nNamespaceSeparator, key))
+			}
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func (b *I18nBundles) hasKey(namespace, key string) bool {
+	for _, bundle := range b.bundles[namespace] {
+		if bundle.hasKey(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve finds the bundle that defines the message id, searching the caller's namespace then the common one, and
+// returns it along with the key without namespace prefix.
+func (b *I18nBundles) resolve(namespace, msgId string) (goyai.I18n, string) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	lookup := []string{namespace, I18nCommonNamespace}
+	if i := strings.Index(msgId, I18nNamespaceSeparator); i > 0 {
+		if _, ok := b.bundles[msgId[:i]]; ok {
+			lookup, msgId = []string{msgId[:i]}, msgId[i+len(I18nNamespaceSeparator):]
+		}
+	}
+	for _, ns := range lookup {
+		// later bundles take precedence
+		bundles := b.bundles[ns]
+		for i := len(bundles) - 1; i >= 0; i-- {
+			if bundles[i].hasKey(msgId) {
+				return bundles[i].i18n, msgId
+			}
+		}
+	}
+	return nil, msgId
+}
+
+// Localize resolves the message id in the caller's namespace first, then in the common namespace. Namespaced message
+// ids ("namespace:key") are resolved in the specified namespace only. The message id is returned if not found.
+func (b *I18nBundles) Localize(namespace, locale, msgId string, params ...interface{}) string {
+	i18n, key := b.resolve(namespace, msgId)
+	if i18n == nil {
+		return msgId
+	}
+	return i18n.Localize(locale, key, params...)
+}
+
+// Messages returns messages visible from the namespace (i.e. the common ones overlaid by the namespace's own), as a
+// map of locale -> key -> text.
+func (b *I18nBundles) Messages(namespace string) map[string]map[string]string {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	result := make(map[string]map[string]string)
+	for _, ns := range []string{I18nCommonNamespace, namespace} {
+		for _, bundle := range b.bundles[ns] {
+			for locale, texts := range bundle.messages {
+				if result[locale] == nil {
+					result[locale] = make(map[string]string)
+				}
+				for key, text := range texts {
+					result[locale][key] = text
+				}
+			}
+		}
+	}
+	return result
+}
+
+// Namespace returns a goyai.I18n that resolves messages in the supplied namespace first (see I18nBundles.Localize).
+// Other functions (e.g. AvailableLocales) are served by the namespace's first bundle, or the common bundle if the
+// namespace has none; thus Namespace must be called after the bundles have been loaded.
+func (b *I18nBundles) Namespace(namespace string) *NamespacedI18n {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	result := &NamespacedI18n{bundles: b, namespace: namespace}
+	if bundles := b.bundles[namespace]; len(bundles) > 0 {
+		result.I18n = bundles[0].i18n
+	} else if bundles = b.bundles[I18nCommonNamespace]; len(bundles) > 0 {
+		result.I18n = bundles[0].i18n
+	}
+	return result
+}
+
+// NamespacedI18n is a goyai.I18n bound to a namespace of I18nBundles.
+//
+// Available since template-r5
+type NamespacedI18n struct {
+	goyai.I18n
+	bundles   *I18nBundles
+	namespace string
+}
+
+// Localize overrides goyai.I18n.Localize: the message id is resolved in the bound namespace first, then in the common
+// namespace.
+func (n *NamespacedI18n) Localize(locale, msgId string, params ...interface{}) string {
+	return n.bundles.Localize(n.namespace, locale, msgId, params...)
+}
+
+// Messages returns messages visible from the bound namespace (see I18nBundles.Messages).
+func (n *NamespacedI18n) Messages() map[string]map[string]string {
+	return n.bundles.Messages(n.namespace)
+}
+
+// LoadI18nMessages reads messages of the i18n files (YAML or JSON) in the supplied directory, as a map of
+// locale -> key -> text. Metadata keys (prefixed with "_") are ignored.
+//
+// Available since template-r5
+func LoadI18nMessages(dir string) (map[string]map[string]string, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]map[string]string)
+	for _, f := range files {
+		ext := strings.ToLower(filepath.Ext(f.Name()))
+		if f.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".json") {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		bundle := make(map[string]map[string]interface{})
+		if ext == ".json" {
+			err = json.Unmarshal(data, &bundle)
+		} else {
+			err = yaml.Unmarshal(data, &bundle)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error parsing i18n file [%s]: %s", f.Name(), err)
+		}
+		for locale, messages := range bundle {
+			if result[locale] == nil {
+				result[locale] = make(map[string]string)
+			}
+			for key, text := range messages {
+				if !strings.HasPrefix(key, "_") {
+					result[locale][key] = fmt.Sprintf("%v", text)
+				}
+			}
+		}
+	}
+	return result, nil
+}
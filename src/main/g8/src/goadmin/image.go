@@ -0,0 +1,289 @@
+package goadmin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"math"
+)
+
+const (
+	// ImageFormatJpeg, ImageFormatPng and ImageFormatWebp are the output formats supported by ProcessImage. WebP
+	// images are encoded losslessly. WebP is accepted as an input format only if a decoder has been registered (e.g.
+	// by importing golang.org/x/image/webp).
+	//
+	// Available since template-r5
+	ImageFormatJpeg = "jpeg"
+	ImageFormatPng  = "png"
+	ImageFormatWebp = "webp"
+
+	// MaxImagePixels limits the dimensions of decoded images, so that small files declaring huge dimensions can not
+	// exhaust memory.
+	//
+	// Available since template-r5
+	MaxImagePixels = 40 * 1000 * 1000
+
+	jpegQuality = 85
+)
+
+// ErrImageTooLarge is returned by ProcessImage if the image exceeds MaxImagePixels.
+//
+// Available since template-r5
+var ErrImageTooLarge = errors.New("image dimensions are too large")
+
+// ImageSize describes a variant generated from an uploaded image. Zero Width or Height means no constraint on that
+// dimension.
+//
+// Available since template-r5
+type ImageSize struct {
+	Name   string // suffix of the variant's file name, e.g. "small"
+	Width  int
+	Height int
+	Crop   bool // true: crop to fill exactly Width x Height (e.g. thumbnails); false: fit within, keeping aspect ratio
+}
+
+// ImageFileName returns the file name of an image variant, e.g. "avatars/abc_small.jpg".
+//
+// Available since template-r5
+func ImageFileName(baseName, sizeName, format string) string {
+	ext := "." + format
+	if format == ImageFormatJpeg {
+		ext = ".jpg"
+	}
+	return baseName + "_" + sizeName + ext
+}
+
+// ProcessImage decodes an uploaded image (JPEG, PNG or GIF), applies its EXIF orientation, and stores one variant per
+// size in the file store (see ImageFileName), encoded in format (ImageFormatJpeg, ImageFormatPng or
+// ImageFormatWebp). Variants are re-encoded from pixels, hence EXIF and other metadata (e.g. GPS location) of the
+// upload are stripped.
+//
+// The names of the stored files are returned, in the order of sizes.
+//
+// Available since template-r5
+func ProcessImage(store FileStore, baseName string, content io.Reader, sizes []ImageSize, format string) ([]string, error) {
+	if format != ImageFormatJpeg && format != ImageFormatPng && format != ImageFormatWebp {
+		return nil, fmt.Errorf("unsupported output image format [%s]", format)
+	}
+	data, err := ioutil.ReadAll(content)
+	if err != nil {
+		return nil, err
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width*config.Height > MaxImagePixels {
+		return nil, ErrImageTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	src := applyOrientation(toNRGBA(img), exifOrientation(data))
+	names := make([]string, 0, len(sizes))
+	for _, size := range sizes {
+		buf := &bytes.Buffer{}
+		if err := encodeImage(buf, resizeImage(src, size), format); err != nil {
+			return names, err
+		}
+		name := ImageFileName(baseName, size.Name, format)
+		if err := store.Put(name, buf); err != nil {
+			return names, err
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func toNRGBA(img image.Image) *image.NRGBA {
+	b := img.Bounds()
+	result := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(result, result.Bounds(), img, b.Min, draw.Src)
+	return result
+}
+
+func encodeImage(w io.Writer, img *image.NRGBA, format string) error {
+	switch format {
+	case ImageFormatPng:
+		return png.Encode(w, img)
+	case ImageFormatWebp:
+		return encodeWebp(w, img)
+	}
+	// JPEG has no alpha channel: transparent areas are rendered on a white background
+	opaque := image.NewRGBA(img.Bounds())
+	draw.Draw(opaque, opaque.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(opaque, opaque.Bounds(), img, image.Point{}, draw.Over)
+	return jpeg.Encode(w, opaque, &jpeg.Options{Quality: jpegQuality})
+}
+
+/*----------------------------------------------------------------------*/
+
+// resizeImage scales (and crops if requested) an image to the supplied size. Images are never upscaled when fitting.
+func resizeImage(src *image.NRGBA, size ImageSize) *image.NRGBA {
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	crop := image.Rect(0, 0, w, h)
+	dw, dh := size.Width, size.Height
+	if size.Crop && dw > 0 && dh > 0 {
+		// keep the centered region having the target's aspect ratio
+		if w*dh > h*dw {
+			cw := h * dw / dh
+			crop = image.Rect((w-cw)/2, 0, (w-cw)/2+cw, h)
+		} else {
+			ch := w * dh / dw
+			crop = image.Rect(0, (h-ch)/2, w, (h-ch)/2+ch)
+		}
+	} else {
+		scale := 1.0
+		if dw > 0 {
+			scale = math.Min(scale, float64(dw)/float64(w))
+		}
+		if dh > 0 {
+			scale = math.Min(scale, float64(dh)/float64(h))
+		}
+		dw, dh = int(math.Max(1, math.Round(float64(w)*scale))), int(math.Max(1, math.Round(float64(h)*scale)))
+	}
+	return scaleRegion(src, crop, dw, dh)
+}
+
+// scaleRegion scales a region of src to dw x dh: each destination pixel is the average of the source pixels it covers
+// (box filter), which gives smooth results when downscaling.
+func scaleRegion(src *image.NRGBA, region image.Rectangle, dw, dh int) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	sw, sh := region.Dx(), region.Dy()
+	for dy := 0; dy < dh; dy++ {
+		y0 := region.Min.Y + dy*sh/dh
+		y1 := region.Min.Y + (dy+1)*sh/dh
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for dx := 0; dx < dw; dx++ {
+			x0 := region.Min.X + dx*sw/dw
+			x1 := region.Min.X + (dx+1)*sw/dw
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			var r, g, b, a, n uint64
+			for y := y0; y < y1; y++ {
+				i := src.PixOffset(x0, y)
+				for x := x0; x < x1; x++ {
+					pa := uint64(src.Pix[i+3])
+					// colors are weighted by alpha, so that transparent pixels do not darken edges
+					r += uint64(src.Pix[i]) * pa
+					g += uint64(src.Pix[i+1]) * pa
+					b += uint64(src.Pix[i+2]) * pa
+					a += pa
+					n++
+					i += 4
+				}
+			}
+			j := dst.PixOffset(dx, dy)
+			if a > 0 {
+				dst.Pix[j], dst.Pix[j+1], dst.Pix[j+2] = uint8(r/a), uint8(g/a), uint8(b/a)
+			}
+			dst.Pix[j+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+/*----------------------------------------------------------------------*/
+
+// exifOrientation returns the EXIF orientation (1-8) of a JPEG image, 1 (normal) if the image is not a JPEG or has
+// no orientation tag.
+func exifOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data) && data[i] == 0xFF; {
+		marker, segLen := data[i+1], int(binary.BigEndian.Uint16(data[i+2:]))
+		if marker == 0xDA || segLen < 2 || i+2+segLen > len(data) {
+			// start of scan: no more metadata segments
+			break
+		}
+		seg := data[i+4 : i+2+segLen]
+		if marker == 0xE1 && len(seg) > 14 && string(seg[:6]) == "Exif\x00\x00" {
+			return tiffOrientation(seg[6:])
+		}
+		i += 2 + segLen
+	}
+	return 1
+}
+
+// tiffOrientation reads tag 0x0112 (orientation) of the first IFD of a TIFF structure.
+func tiffOrientation(tiff []byte) int {
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	numEntries := int(order.Uint16(tiff[ifd:]))
+	for e := 0; e < numEntries; e++ {
+		entry := ifd + 2 + e*12
+		if entry+12 > len(tiff) {
+			break
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			if o := int(order.Uint16(tiff[entry+8:])); o >= 1 && o <= 8 {
+				return o
+			}
+			break
+		}
+	}
+	return 1
+}
+
+// applyOrientation transforms an image so that it is displayed upright, according to its EXIF orientation.
+func applyOrientation(src *image.NRGBA, orientation int) *image.NRGBA {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		// orientations 5-8 swap width and height
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var tx, ty int
+			switch orientation {
+			case 2: // mirrored horizontally
+				tx, ty = w-1-x, y
+			case 3: // rotated 180
+				tx, ty = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				tx, ty = x, h-1-y
+			case 5: // transposed
+				tx, ty = y, x
+			case 6: // rotated 90 clockwise
+				tx, ty = h-1-y, x
+			case 7: // transversed
+				tx, ty = h-1-y, w-1-x
+			case 8: // rotated 90 counter-clockwise
+				tx, ty = y, w-1-x
+			}
+			i, j := src.PixOffset(x, y), dst.PixOffset(tx, ty)
+			copy(dst.Pix[j:j+4], src.Pix[i:i+4])
+		}
+	}
+	return dst
+}
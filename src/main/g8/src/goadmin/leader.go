@@ -0,0 +1,110 @@
+package goadmin
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// leaderLockName is the name of the lock held by the elected leader.
+const leaderLockName = "goadmin:leader"
+
+// LeaderElection elects one instance, among those sharing the registry's Locker, as the leader that runs scheduled
+// jobs (see Registry.IsLeader), so that jobs do not fire on every node of a clustered deployment.
+//
+// The leader holds a lease (a lock that expires, setting goadmin.leader_lease) and renews it periodically. If the
+// leader dies, another instance takes over once the lease has expired; if it stops gracefully, the lease is released
+// and another instance takes over within a third of the lease.
+//
+// With the default, process-local Locker every instance is its own leader: locks must be shared (Redis cache or
+// database) for the election to span instances.
+//
+// Available since template-r5
+type LeaderElection struct {
+	locker Locker
+	owner  string
+	lease  time.Duration
+	leader int32 // accessed atomically
+	stop   chan struct{}
+	once   sync.Once
+}
+
+// NewLeaderElection creates a LeaderElection using locker, call Start to take part in the election.
+//
+// Available since template-r5
+func NewLeaderElection(locker Locker, lease time.Duration) *LeaderElection {
+	if lease <= 0 {
+		lease = 30 * time.Second
+	}
+	return &LeaderElection{locker: locker, owner: lockOwner(), lease: lease, stop: make(chan struct{})}
+}
+
+// IsLeader checks if this instance currently is the leader.
+func (e *LeaderElection) IsLeader() bool {
+	return atomic.LoadInt32(&e.leader) != 0
+}
+
+// Start runs a first round of the election, then keeps renewing (or trying to acquire) the lease in background.
+func (e *LeaderElection) Start() {
+	e.campaign()
+	go func() {
+		ticker := time.NewTicker(e.lease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-e.stop:
+				return
+			case <-ticker.C:
+				e.campaign()
+			}
+		}
+	}()
+}
+
+// Stop leaves the election, the lease is released so that another instance takes over quickly.
+func (e *LeaderElection) Stop() {
+	e.once.Do(func() {
+		close(e.stop)
+		if atomic.SwapInt32(&e.leader, 0) != 0 {
+			if err := e.locker.Unlock(leaderLockName, e.owner); err != nil {
+				log.Printf("[WARN] cannot release leadership: %s", err)
+			}
+		}
+	})
+}
+
+// campaign renews the lease if this instance is the leader, or tries to acquire it otherwise.
+func (e *LeaderElection) campaign() {
+	var ok bool
+	var err error
+	wasLeader := e.IsLeader()
+	if wasLeader {
+		ok, err = e.locker.Refresh(leaderLockName, e.owner, e.lease)
+	} else {
+		ok, err = e.locker.TryLock(leaderLockName, e.owner, e.lease)
+	}
+	if err != nil {
+		// the lease may still be valid, but it can not be told: step down rather than risking two leaders
+		log.Printf("[ERROR] leader election failed: %s", err)
+		ok = false
+	}
+	if ok {
+		atomic.StoreInt32(&e.leader, 1)
+	} else {
+		atomic.StoreInt32(&e.leader, 0)
+	}
+	if ok && !wasLeader {
+		log.Printf("This instance [%s] is now the leader, scheduled jobs run here", e.owner)
+	} else if !ok && wasLeader {
+		log.Printf("[WARN] this instance [%s] is no longer the leader", e.owner)
+	}
+}
+
+// IsLeader checks if this instance is the elected leader (see LeaderElection), scheduled jobs should only run if it
+// is. It returns true if leader election has not been started yet.
+//
+// Available since template-r5
+func (r *Registry) IsLeader() bool {
+	return r.Leader == nil || r.Leader.IsLeader()
+}
@@ -0,0 +1,27 @@
+package goadmin
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLeaderElection(t *testing.T) {
+	testName := "TestLeaderElection"
+	locker := NewMemoryLocker()
+	first, second := NewLeaderElection(locker, time.Minute), NewLeaderElection(locker, time.Minute)
+	first.Start()
+	defer first.Stop()
+	second.Start()
+	defer second.Stop()
+	if !first.IsLeader() || second.IsLeader() {
+		t.Fatalf("%s failed: expected exactly one leader (%v / %v)", testName, first.IsLeader(), second.IsLeader())
+	}
+	first.Stop()
+	second.Stop()
+	third := NewLeaderElection(locker, time.Minute)
+	third.Start()
+	defer third.Stop()
+	if !third.IsLeader() {
+		t.Fatalf("%s failed: leadership must be taken over after the leader stopped", testName)
+	}
+}
@@ -0,0 +1,193 @@
+package goadmin
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	hocon "github.com/go-akka/configuration"
+)
+
+// ListenSpec describes an address the HTTP server listens on.
+//
+// Available since template-r5
+type ListenSpec struct {
+	Network string // "tcp" or "unix"
+	Address string // host:port for tcp, socket file path for unix
+}
+
+// String implements fmt.Stringer.
+func (s ListenSpec) String() string {
+	return s.Network + "://" + s.Address
+}
+
+// ParseListenSpec parses a listen address in one of the following formats:
+//
+//   - "host:port", ":port" or "tcp://host:port": TCP address
+//   - "unix:/path/to/socket" or "unix:///path/to/socket": unix domain socket
+//
+// Available since template-r5
+func ParseListenSpec(spec string) (ListenSpec, error) {
+	spec = strings.TrimSpace(spec)
+	switch {
+	case strings.HasPrefix(spec, "unix:"):
+		path := strings.TrimPrefix(strings.TrimPrefix(spec, "unix:"), "//")
+		if path == "" {
+			return ListenSpec{}, fmt.Errorf("empty socket path in [%s]", spec)
+		}
+		return ListenSpec{Network: "unix", Address: path}, nil
+	case strings.HasPrefix(spec, "tcp://"):
+		spec = strings.TrimPrefix(spec, "tcp://")
+	case strings.Contains(spec, "://"):
+		return ListenSpec{}, fmt.Errorf("unsupported network in [%s]", spec)
+	}
+	if _, port, err := net.SplitHostPort(spec); err != nil {
+		return ListenSpec{}, fmt.Errorf("invalid address [%s]: %s", spec, err)
+	} else if p, err := strconv.Atoi(port); err != nil || p <= 0 || p > 65535 {
+		return ListenSpec{}, fmt.Errorf("invalid port in [%s]", spec)
+	}
+	return ListenSpec{Network: "tcp", Address: spec}, nil
+}
+
+// siteListener is a listener dedicated to a site (SitePublic or SiteCP), empty site means all sites.
+type siteListener struct {
+	net.Listener
+	site string
+}
+
+// parseListenSpecs parses the list of addresses at the configuration path (e.g. "http.cp_listeners").
+func parseListenSpecs(appConfig *hocon.Config, path string) ([]ListenSpec, error) {
+	var specs []ListenSpec
+	if v := appConfig.GetValue(path); v != nil && v.IsArray() {
+		for i, item := range v.GetArray() {
+			spec, err := ParseListenSpec(item.GetString())
+			if err != nil {
+				return nil, fmt.Errorf("[%s[%d]]: %s", path, i, err)
+			}
+			specs = append(specs, spec)
+		}
+	}
+	return specs, nil
+}
+
+// listenSpecs builds the list of addresses to listen on from the configuration at path (e.g. "http.listeners"); if
+// the list is empty, the single TCP address made of http.listen_addr and http.listen_port is returned.
+func listenSpecs(appConfig *hocon.Config, path string) ([]ListenSpec, error) {
+	specs, err := parseListenSpecs(appConfig, path)
+	if err != nil || len(specs) > 0 {
+		return specs, err
+	}
+	listenPort := appConfig.GetInt32("http.listen_port", 0)
+	if listenPort <= 0 {
+		return nil, fmt.Errorf("no valid [http.listen_port] configured")
+	}
+	listenAddr := appConfig.GetString("http.listen_addr", "127.0.0.1")
+	return []ListenSpec{{Network: "tcp", Address: net.JoinHostPort(listenAddr, strconv.Itoa(int(listenPort)))}}, nil
+}
+
+// openListeners opens listeners for all specs; on error, already-opened listeners are closed.
+//
+// A stale unix socket file (left over by a previous run) is removed before listening. If socketMode is not zero, the
+// permissions of unix socket files are set accordingly. tcpKeepAlive is the period of keep-alive probes of accepted
+// TCP connections: zero means the Go default (15s), negative disables them.
+func openListeners(specs []ListenSpec, socketMode os.FileMode, tcpKeepAlive time.Duration) ([]net.Listener, error) {
+	lc := net.ListenConfig{KeepAlive: tcpKeepAlive}
+	listeners := make([]net.Listener, 0, len(specs))
+	closeAll := func() {
+		for _, l := range listeners {
+			l.Close()
+		}
+	}
+	for _, spec := range specs {
+		if spec.Network == "unix" {
+			if fi, err := os.Stat(spec.Address); err == nil && fi.Mode()&os.ModeSocket != 0 {
+				os.Remove(spec.Address)
+			}
+		}
+		l, err := lc.Listen(context.Background(), spec.Network, spec.Address)
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		listeners = append(listeners, l)
+		if spec.Network == "unix" && socketMode != 0 {
+			if err := os.Chmod(spec.Address, socketMode); err != nil {
+				closeAll()
+				return nil, err
+			}
+		}
+	}
+	return listeners, nil
+}
+
+// openSiteListeners opens listeners configured by http.listeners (or http.listen_addr/http.listen_port) and
+// http.cp_listeners. If the latter is not empty, the control panel is served on its listeners only and the former
+// serve the public site; otherwise all listeners serve both sites.
+func openSiteListeners(appConfig *hocon.Config) ([]siteListener, error) {
+	specs, err := listenSpecs(appConfig, "http.listeners")
+	if err != nil {
+		return nil, err
+	}
+	cpSpecs, err := parseListenSpecs(appConfig, "http.cp_listeners")
+	if err != nil {
+		return nil, err
+	}
+	socketMode, err := unixSocketMode(appConfig.GetString("http.unix_socket_mode", ""))
+	if err != nil {
+		return nil, err
+	}
+	listeners, err := openListeners(append(specs, cpSpecs...), socketMode, appConfig.GetTimeDuration("server.tcp_keep_alive", 0))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]siteListener, len(listeners))
+	for i, l := range listeners {
+		result[i] = siteListener{Listener: l}
+		if len(cpSpecs) > 0 {
+			result[i].site = SitePublic
+			if i >= len(specs) {
+				result[i].site = SiteCP
+			}
+		}
+	}
+	return result, nil
+}
+
+// systemdSiteListeners assigns sites to sockets passed by systemd: if any socket is named "cp" (FileDescriptorName=cp
+// in the socket unit), it serves the control panel and other sockets serve the public site; otherwise all sockets
+// serve both sites.
+func systemdSiteListeners(listeners []net.Listener, names []string) []siteListener {
+	hasCp := false
+	for _, name := range names {
+		hasCp = hasCp || name == SiteCP
+	}
+	result := make([]siteListener, len(listeners))
+	for i, l := range listeners {
+		result[i] = siteListener{Listener: l}
+		if hasCp {
+			result[i].site = SitePublic
+			if names[i] == SiteCP {
+				result[i].site = SiteCP
+			}
+		}
+	}
+	return result
+}
+
+// unixSocketMode parses the octal permission string (e.g. "0660") of unix socket files, empty string means 0 (keep
+// the default permissions).
+func unixSocketMode(mode string) (os.FileMode, error) {
+	mode = strings.TrimSpace(mode)
+	if mode == "" {
+		return 0, nil
+	}
+	m, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || m > 0777 {
+		return 0, fmt.Errorf("invalid unix socket mode [%s]", mode)
+	}
+	return os.FileMode(m), nil
+}
@@ -0,0 +1,157 @@
+package goadmin
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"main/src/utils"
+)
+
+// lockRetryDelay is the delay between attempts to acquire a lock held by another owner.
+const lockRetryDelay = 250 * time.Millisecond
+
+// ErrLockTimeout is returned by Registry.WithLock if the lock could not be acquired in time.
+//
+// Available since template-r5
+var ErrLockTimeout = errors.New("timeout waiting for lock")
+
+// Locker acquires named, expiring locks shared by application instances, see Registry.WithLock.
+//
+// Available since template-r5
+type Locker interface {
+	// TryLock acquires the lock name for owner if it is free (or expired), without waiting. The lock expires after ttl
+	// in case its owner dies without releasing it.
+	TryLock(name, owner string, ttl time.Duration) (bool, error)
+	// Refresh extends the lock name by ttl if it is still held by owner, false is returned if it is not (e.g. it has
+	// expired and been acquired by another owner).
+	Refresh(name, owner string, ttl time.Duration) (bool, error)
+	// Unlock releases the lock name if it is held by owner.
+	Unlock(name, owner string) error
+}
+
+// lockOwner returns a unique owner id, identifying the instance for troubleshooting.
+func lockOwner() string {
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("%s:%d:%s", hostname, os.Getpid(), utils.RandomString(8))
+}
+
+// WithLock runs fn while holding the lock name, so that it runs on one instance at a time even if several replicas
+// start simultaneously (e.g. migrations, seeding initial data). If the lock is held by another instance, WithLock
+// waits for it to be released, at most ttl (the lock expires by then); ErrLockTimeout is returned if it is still not
+// available. fn should complete within ttl, otherwise another instance may acquire the lock meanwhile.
+//
+// Locks are shared by all instances if Registry.Locker is (see setting goadmin.cache): the Redis cache or the
+// application's database (set by modules); the default locker only serializes calls within the process.
+//
+// Available since template-r5
+func (r *Registry) WithLock(name string, ttl time.Duration, fn func() error) error {
+	owner := lockOwner()
+	deadline := time.Now().Add(ttl)
+	for {
+		ok, err := r.Locker.TryLock(name, owner, ttl)
+		if err != nil {
+			return err
+		}
+		if ok {
+			break
+		}
+		if !time.Now().Before(deadline) {
+			return ErrLockTimeout
+		}
+		time.Sleep(lockRetryDelay)
+	}
+	defer r.unlock(name, owner)
+	return fn()
+}
+
+// TryWithLock is like WithLock, but does not wait: fn is not run and false is returned if the lock is held by another
+// instance. It suits scheduled jobs that need not run on every instance (e.g. purging old data).
+//
+// Available since template-r5
+func (r *Registry) TryWithLock(name string, ttl time.Duration, fn func() error) (bool, error) {
+	owner := lockOwner()
+	ok, err := r.Locker.TryLock(name, owner, ttl)
+	if !ok || err != nil {
+		return false, err
+	}
+	defer r.unlock(name, owner)
+	return true, fn()
+}
+
+func (r *Registry) unlock(name, owner string) {
+	if err := r.Locker.Unlock(name, owner); err != nil {
+		log.Printf("[ERROR] cannot release lock [%s]: %s", name, err)
+	}
+}
+
+/*----------------------------------------------------------------------*/
+
+// NewMemoryLocker creates a Locker whose locks live in memory: they serialize calls within the process only, which is
+// enough for single-instance deployments.
+//
+// Available since template-r5
+func NewMemoryLocker() Locker {
+	return &memoryLocker{locks: make(map[string]memoryLock)}
+}
+
+type memoryLock struct {
+	owner  string
+	expiry time.Time
+}
+
+type memoryLocker struct {
+	lock  sync.Mutex
+	locks map[string]memoryLock
+}
+
+// TryLock implements Locker.TryLock
+func (l *memoryLocker) TryLock(name, owner string, ttl time.Duration) (bool, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := time.Now()
+	if current, ok := l.locks[name]; ok && current.owner != owner && now.Before(current.expiry) {
+		return false, nil
+	}
+	l.locks[name] = memoryLock{owner: owner, expiry: now.Add(ttl)}
+	return true, nil
+}
+
+// Refresh implements Locker.Refresh
+func (l *memoryLocker) Refresh(name, owner string, ttl time.Duration) (bool, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := time.Now()
+	if current, ok := l.locks[name]; !ok || current.owner != owner || !now.Before(current.expiry) {
+		return false, nil
+	}
+	l.locks[name] = memoryLock{owner: owner, expiry: now.Add(ttl)}
+	return true, nil
+}
+
+// Unlock implements Locker.Unlock
+func (l *memoryLocker) Unlock(name, owner string) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if current, ok := l.locks[name]; ok && current.owner == owner {
+		delete(l.locks, name)
+	}
+	return nil
+}
+
+// PurgeExpired implements ExpiredPurger.PurgeExpired
+func (l *memoryLocker) PurgeExpired(now time.Time) (int, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	numDeleted := 0
+	for name, current := range l.locks {
+		if !now.Before(current.expiry) {
+			delete(l.locks, name)
+			numDeleted++
+		}
+	}
+	return numDeleted, nil
+}
@@ -0,0 +1,29 @@
+package goadmin
+
+import (
+	"testing"
+	"time"
+
+	hocon "github.com/go-akka/configuration"
+)
+
+func TestWithLock(t *testing.T) {
+	testName := "TestWithLock"
+	r := NewRegistry(hocon.ParseString(""))
+	r.Locker = NewMemoryLocker()
+	err := r.WithLock("test", time.Minute, func() error {
+		if ok, err := r.TryWithLock("test", time.Minute, func() error { return nil }); ok || err != nil {
+			t.Fatalf("%s failed: lock must not be acquired twice (%v / %v)", testName, ok, err)
+		}
+		if err := r.WithLock("test", 300*time.Millisecond, func() error { return nil }); err != ErrLockTimeout {
+			t.Fatalf("%s failed: expected error [%s] but received [%v]", testName, ErrLockTimeout, err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("%s failed: %s", testName, err)
+	}
+	if ok, err := r.TryWithLock("test", time.Minute, func() error { return nil }); !ok || err != nil {
+		t.Fatalf("%s failed: lock must be released (%v / %v)", testName, ok, err)
+	}
+}
@@ -0,0 +1,179 @@
+package goadmin
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync/atomic"
+
+	"main/src/utils"
+)
+
+// LogLevel is the minimum severity of log messages that are written to the log output.
+//
+// Log messages are leveled by their tag, e.g. log.Printf("[WARN] ...") is a warning. Messages without a known tag
+// are informational.
+//
+// Available since template-r5
+type LogLevel int32
+
+const (
+	LogLevelDebug LogLevel = iota
+	LogLevelInfo
+	LogLevelWarn
+	LogLevelError
+)
+
+var logLevelNames = []string{"debug", "info", "warn", "error"}
+
+// logLevelTags maps tags of log messages to their levels.
+var logLevelTags = map[string]LogLevel{
+	"[DEBUG]": LogLevelDebug,
+	"[INFO]":  LogLevelInfo,
+	"[WARN]":  LogLevelWarn,
+	"[ERROR]": LogLevelError,
+}
+
+// String implements fmt.Stringer.
+func (l LogLevel) String() string {
+	if l < LogLevelDebug || l > LogLevelError {
+		return fmt.Sprintf("LogLevel(%d)", int32(l))
+	}
+	return logLevelNames[l]
+}
+
+// ParseLogLevel parses a log level name ("debug", "info", "warn" or "error", case-insensitive).
+//
+// Available since template-r5
+func ParseLogLevel(name string) (LogLevel, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "warning" {
+		name = "warn"
+	}
+	for i, n := range logLevelNames {
+		if n == name {
+			return LogLevel(i), nil
+		}
+	}
+	return LogLevelInfo, fmt.Errorf("invalid log level [%s], valid values are %v", name, logLevelNames)
+}
+
+// LogLevels returns all log levels, from the most to the least verbose.
+//
+// Available since template-r5
+func LogLevels() []LogLevel {
+	return []LogLevel{LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError}
+}
+
+var currentLogLevel = int32(LogLevelInfo)
+
+// GetLogLevel returns the current log level.
+//
+// Available since template-r5
+func GetLogLevel() LogLevel {
+	return LogLevel(atomic.LoadInt32(&currentLogLevel))
+}
+
+// SetLogLevel changes the log level at runtime.
+//
+// Available since template-r5
+func SetLogLevel(level LogLevel) {
+	atomic.StoreInt32(&currentLogLevel, int32(level))
+}
+
+// IsDebugEnabled returns true if debug messages are logged. Wrap expensive debug logging with it.
+//
+// Available since template-r5
+func IsDebugEnabled() bool {
+	return GetLogLevel() <= LogLevelDebug
+}
+
+// levelFilterWriter drops log messages below the current log level, and redacts the others (see SetLogSanitizer).
+type levelFilterWriter struct {
+	out io.Writer
+}
+
+// Write implements io.Writer.
+func (w *levelFilterWriter) Write(p []byte) (int, error) {
+	if levelOf(p) < GetLogLevel() {
+		return len(p), nil
+	}
+	if s := GetLogSanitizer(); s != nil {
+		if _, err := w.out.Write([]byte(s.Sanitize(string(p)))); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	return w.out.Write(p)
+}
+
+// levelOf returns level of a log message, determined by the first bracketed token of the message (the date/time
+// prefix written by the standard logger contains no bracket).
+func levelOf(msg []byte) LogLevel {
+	start := bytes.IndexByte(msg, '[')
+	if start < 0 {
+		return LogLevelInfo
+	}
+	end := bytes.IndexByte(msg[start:], ']')
+	if end < 0 {
+		return LogLevelInfo
+	}
+	if level, ok := logLevelTags[string(msg[start:start+end+1])]; ok {
+		return level
+	}
+	return LogLevelInfo
+}
+
+// initLogging sets the initial log level from setting goadmin.log_level (default "debug" in development mode, "info"
+// otherwise), the log sanitizer (setting goadmin.log_masking) and installs the level filter and the log sinks (setting
+// goadmin.log_sinks) on the standard logger.
+func initLogging(registry *Registry) error {
+	defaultLevel := LogLevelInfo
+	if utils.DevMode {
+		defaultLevel = LogLevelDebug
+	}
+	level := defaultLevel
+	if name := registry.AppConfig.GetString("goadmin.log_level", ""); name != "" {
+		var err error
+		if level, err = ParseLogLevel(name); err != nil {
+			return err
+		}
+	}
+	registry.DefaultLogLevel = level
+	SetLogLevel(level)
+	sanitizer, err := newLogSanitizerFromConfig(registry.AppConfig)
+	if err != nil {
+		return err
+	}
+	SetLogSanitizer(sanitizer)
+	return initLogOutput(registry.AppConfig)
+}
+
+// ChangeLogLevel changes the log level at runtime and logs the change, source describes who requested it (e.g. the
+// user's name).
+//
+// Available since template-r5
+func ChangeLogLevel(level LogLevel, source string) {
+	msg := fmt.Sprintf("[WARN] log level changed from [%s] to [%s] by [%s]", GetLogLevel(), level, source)
+	if level > LogLevelWarn {
+		// the message would be filtered out by the new level
+		log.Print(msg)
+	}
+	SetLogLevel(level)
+	if level <= LogLevelWarn {
+		log.Print(msg)
+	}
+}
+
+// toggleDebugLogging switches between debug logging and the configured log level (see Registry.DefaultLogLevel).
+func toggleDebugLogging(registry *Registry) {
+	level := LogLevelDebug
+	if IsDebugEnabled() {
+		if level = registry.DefaultLogLevel; level == LogLevelDebug {
+			level = LogLevelInfo
+		}
+	}
+	ChangeLogLevel(level, "signal")
+}
@@ -0,0 +1,21 @@
+//go:build !windows
+// +build !windows
+
+package goadmin
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// handleLogLevelSignal toggles debug logging on SIGUSR1, e.g. "kill -USR1 <pid>".
+func handleLogLevelSignal(registry *Registry) {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGUSR1)
+	go func() {
+		for range sigChan {
+			toggleDebugLogging(registry)
+		}
+	}()
+}
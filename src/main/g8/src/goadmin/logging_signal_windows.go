@@ -0,0 +1,4 @@
+package goadmin
+
+// handleLogLevelSignal is a no-op: SIGUSR1 is not available on Windows.
+func handleLogLevelSignal(registry *Registry) {}
@@ -0,0 +1,140 @@
+package goadmin
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	hocon "github.com/go-akka/configuration"
+)
+
+// defaultMaskedFieldPatterns are the default field name patterns of setting goadmin.log_masking.field_patterns.
+var defaultMaskedFieldPatterns = []string{"pass", "pwd", "secret", "token", "api_?key", "authorization", "cookie", "session"}
+
+var (
+	// reLogField matches "name=value", "name: value" and "name":"value" pairs, as well as url.Values printed with %v
+	// ("name:[value]"); a value is quoted, bracketed, an authorization scheme followed by credentials, or a word.
+	reLogField = regexp.MustCompile(`("?)([A-Za-z0-9_.\-]+)("?\s*[:=]\s*)("(?:[^"\\]|\\.)*"|\[[^\]]*\]|(?i:bearer|basic)\s+[^\s&,;"']+|[^\s&,;"'}\]]+)`)
+	reLogEmail = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@([A-Za-z0-9\-]+\.)+[A-Za-z]{2,}`)
+)
+
+// NewLogSanitizer creates a LogSanitizer that masks values of fields whose names match any of fieldPatterns (regular
+// expressions, case-insensitive) and, if maskEmails is true, email addresses.
+//
+// Available since template-r5
+func NewLogSanitizer(fieldPatterns []string, maskEmails bool, mask string) (*LogSanitizer, error) {
+	s := &LogSanitizer{maskEmails: maskEmails, mask: mask}
+	if len(fieldPatterns) > 0 {
+		re, err := regexp.Compile("(?i)" + strings.Join(fieldPatterns, "|"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid field name pattern: %s", err)
+		}
+		s.fields = re
+	}
+	return s, nil
+}
+
+// LogSanitizer redacts personal and secret data (passwords, tokens, emails...) from log messages, error pages and
+// logged form data, see setting goadmin.log_masking. Masked values are replaced by the mask, the domain of masked
+// email addresses is kept (e.g. "***@example.com"). Sanitizing a sanitized text leaves it unchanged.
+//
+// Available since template-r5
+type LogSanitizer struct {
+	fields     *regexp.Regexp // names of fields whose values are masked, nil if none
+	maskEmails bool
+	mask       string
+}
+
+// IsSensitiveField checks if values of a field are masked.
+func (s *LogSanitizer) IsSensitiveField(name string) bool {
+	return s.fields != nil && s.fields.MatchString(name)
+}
+
+// Sanitize redacts a text, e.g. a log message or a stack trace.
+func (s *LogSanitizer) Sanitize(text string) string {
+	if s.fields != nil {
+		text = reLogField.ReplaceAllStringFunc(text, func(pair string) string {
+			m := reLogField.FindStringSubmatch(pair)
+			if !s.IsSensitiveField(m[2]) {
+				return pair
+			}
+			value := s.mask
+			switch {
+			case strings.HasPrefix(m[4], `"`):
+				value = `"` + s.mask + `"`
+			case strings.HasPrefix(m[4], "["):
+				value = "[" + s.mask + "]"
+			}
+			return m[1] + m[2] + m[3] + value
+		})
+	}
+	if s.maskEmails {
+		text = reLogEmail.ReplaceAllStringFunc(text, func(email string) string {
+			return s.mask + email[strings.LastIndex(email, "@"):]
+		})
+	}
+	return text
+}
+
+// SanitizeValues returns form data (or query parameters) as a string, with values of sensitive fields masked.
+func (s *LogSanitizer) SanitizeValues(values url.Values) string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		for _, v := range values[name] {
+			if s.IsSensitiveField(name) {
+				v = s.mask
+			}
+			pairs = append(pairs, name+"="+v)
+		}
+	}
+	return s.Sanitize(strings.Join(pairs, "&"))
+}
+
+// newLogSanitizerFromConfig creates the LogSanitizer configured by block goadmin.log_masking, nil if disabled.
+func newLogSanitizerFromConfig(conf *hocon.Config) (*LogSanitizer, error) {
+	if !conf.GetBoolean("goadmin.log_masking.enabled", true) {
+		return nil, nil
+	}
+	patterns := defaultMaskedFieldPatterns
+	if conf.HasPath("goadmin.log_masking.field_patterns") {
+		patterns = conf.GetStringList("goadmin.log_masking.field_patterns")
+	}
+	return NewLogSanitizer(patterns, conf.GetBoolean("goadmin.log_masking.mask_emails", true),
+		conf.GetString("goadmin.log_masking.mask", "***"))
+}
+
+var currentLogSanitizer atomic.Value // holds a *LogSanitizer, nil if masking is disabled
+
+// GetLogSanitizer returns the sanitizer applied to log messages, nil if masking is disabled.
+//
+// Available since template-r5
+func GetLogSanitizer() *LogSanitizer {
+	s, _ := currentLogSanitizer.Load().(*LogSanitizer)
+	return s
+}
+
+// SetLogSanitizer changes the sanitizer applied to log messages, nil disables masking.
+//
+// Available since template-r5
+func SetLogSanitizer(s *LogSanitizer) {
+	currentLogSanitizer.Store(s)
+}
+
+// SanitizeLog redacts a text with the current log sanitizer (see GetLogSanitizer). Texts that are signed or hashed
+// before being logged (e.g. audit entries) must be sanitized first, as log messages are sanitized when written.
+//
+// Available since template-r5
+func SanitizeLog(text string) string {
+	if s := GetLogSanitizer(); s != nil {
+		return s.Sanitize(text)
+	}
+	return text
+}
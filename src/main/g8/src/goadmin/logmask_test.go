@@ -0,0 +1,27 @@
+package goadmin
+
+import "testing"
+
+func TestLogSanitizer(t *testing.T) {
+	testName := "TestLogSanitizer"
+	s, err := NewLogSanitizer([]string{"pass", "token", "api_?key", "authorization"}, true, "***")
+	if err != nil {
+		t.Fatalf("%s failed: %s", testName, err)
+	}
+	cases := map[string]string{
+		`login failed for john.doe@example.com`:           `login failed for ***@example.com`,
+		`password=s3cret&username=admin`:                  `password=***&username=admin`,
+		`{"apikey":"abc\"def","name":"x"}`:                `{"apikey":"***","name":"x"}`,
+		`form map[new_password:[s3cret] uname:[admin]]`:   `form map[new_password:[***] uname:[admin]]`,
+		`Authorization: Bearer eyJhbGciOi.x.y, next=page`: `Authorization: ***, next=page`,
+		`access_token: t0k3n seq=42`:                      `access_token: *** seq=42`,
+	}
+	for in, expected := range cases {
+		if out := s.Sanitize(in); out != expected {
+			t.Fatalf("%s failed: expected [%s] for [%s], got [%s]", testName, expected, in, out)
+		}
+		if out := s.Sanitize(expected); out != expected {
+			t.Fatalf("%s failed: sanitized text [%s] changed to [%s]", testName, expected, out)
+		}
+	}
+}
@@ -0,0 +1,505 @@
+package goadmin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	hocon "github.com/go-akka/configuration"
+	"main/src/utils"
+)
+
+const (
+	// LogSinkConsole writes log messages to the standard error (the default).
+	//
+	// Available since template-r5
+	LogSinkConsole = "console"
+
+	// LogSinkFile writes log messages to a file rotated by size and/or time, as JSON lines (structured) or text.
+	//
+	// Available since template-r5
+	LogSinkFile = "file"
+
+	// LogSinkSyslog sends log messages to a syslog server in RFC5424 format.
+	//
+	// Available since template-r5
+	LogSinkSyslog = "syslog"
+
+	// LogSinkGelf ships log messages to Graylog via a GELF HTTP input.
+	//
+	// Available since template-r5
+	LogSinkGelf = "gelf"
+
+	// LogSinkLoki ships log messages to Grafana Loki via its push API.
+	//
+	// Available since template-r5
+	LogSinkLoki = "loki"
+
+	logSinkBufferSize = 1000
+	logSinkBatchSize  = 100
+	logSinkInterval   = time.Second
+)
+
+// LogRecord is a log message passed to log sinks.
+//
+// Available since template-r5
+type LogRecord struct {
+	Time    time.Time
+	Level   LogLevel
+	Message string // the message without the date/time prefix written by the standard logger
+}
+
+// LogSink receives log messages written by the standard logger, in addition to (or instead of) the console, e.g. to
+// ship them to centralized logging.
+//
+// Available since template-r5
+type LogSink interface {
+	// Send delivers a batch of log messages.
+	Send(records []*LogRecord) error
+}
+
+var (
+	consoleLock sync.Mutex
+	console     io.Writer       // writer of the standard logger before goadmin installs its own (the standard error)
+	activeSinks []*asyncLogSink // remote sinks currently installed
+
+	hostname, _   = os.Hostname()
+	syslogLevelOf = map[LogLevel]int{LogLevelDebug: 7, LogLevelInfo: 6, LogLevelWarn: 4, LogLevelError: 3}
+)
+
+// initLogOutput sets the output of the standard logger from setting goadmin.log_sinks, a comma-separated list of
+// sinks (e.g. "console,syslog"). Remote sinks receive messages in background, so that logging never blocks on the
+// network; messages are dropped if a sink can not keep up.
+func initLogOutput(conf *hocon.Config) error {
+	consoleLock.Lock()
+	defer consoleLock.Unlock()
+	if console == nil {
+		console = log.Writer()
+		if w, ok := console.(*levelFilterWriter); ok {
+			console = w.out
+		}
+	}
+	writers := make([]io.Writer, 0)
+	sinks := make([]*asyncLogSink, 0)
+	for _, name := range strings.Split(conf.GetString("goadmin.log_sinks", LogSinkConsole), ",") {
+		var sink LogSink
+		var err error
+		switch name = strings.ToLower(strings.TrimSpace(name)); name {
+		case "":
+			continue
+		case LogSinkConsole:
+			writers = append(writers, console)
+			continue
+		case LogSinkFile:
+			sink, err = newFileLogSink(conf, "goadmin.log_file")
+		case LogSinkSyslog:
+			sink, err = newSyslogSink(conf, "goadmin.log_syslog")
+		case LogSinkGelf:
+			sink = NewGelfSink(conf.GetString("goadmin.log_gelf.url", "http://localhost:12201/gelf"),
+				conf.GetTimeDuration("goadmin.log_gelf.timeout", 5*time.Second))
+		case LogSinkLoki:
+			sink = NewLokiSink(conf.GetString("goadmin.log_loki.url", "http://localhost:3100"),
+				configStringMap(conf, "goadmin.log_loki.labels"), conf.GetTimeDuration("goadmin.log_loki.timeout", 5*time.Second))
+		default:
+			err = fmt.Errorf("unknown log sink [%s], valid values are %v", name,
+				[]string{LogSinkConsole, LogSinkFile, LogSinkSyslog, LogSinkGelf, LogSinkLoki})
+		}
+		if err != nil {
+			closeLogSinks(sinks)
+			return err
+		}
+		async := newAsyncLogSink(name, sink)
+		sinks = append(sinks, async)
+		writers = append(writers, async)
+	}
+	closeLogSinks(activeSinks)
+	activeSinks = sinks
+	log.SetOutput(&levelFilterWriter{out: io.MultiWriter(writers...)})
+	return nil
+}
+
+// FlushLogSinks delivers pending messages of remote log sinks, it is called on shutdown.
+//
+// Available since template-r5
+func FlushLogSinks() {
+	consoleLock.Lock()
+	defer consoleLock.Unlock()
+	closeLogSinks(activeSinks)
+	activeSinks = nil
+}
+
+func closeLogSinks(sinks []*asyncLogSink) {
+	for _, s := range sinks {
+		s.close()
+	}
+}
+
+// configStringMap reads a configuration block of string values, e.g. Loki labels.
+func configStringMap(conf *hocon.Config, path string) map[string]string {
+	result := make(map[string]string)
+	if v := conf.GetValue(path); v != nil && v.IsObject() {
+		for k := range v.GetObject().Items() {
+			result[k] = conf.GetString(path+"."+k, "")
+		}
+	}
+	return result
+}
+
+// reportSinkError writes errors of log sinks to the console: logging them would feed them back to the failing sink.
+func reportSinkError(name string, err error) {
+	consoleLock.Lock()
+	out := console
+	consoleLock.Unlock()
+	if out != nil {
+		fmt.Fprintf(out, "%s [ERROR] log sink [%s] failed: %s\n", time.Now().Format("2006/01/02 15:04:05"), name, err)
+	}
+}
+
+/*----------------------------------------------------------------------*/
+
+// asyncLogSink buffers messages written by the standard logger and sends them to a LogSink in batches, from a
+// background goroutine.
+type asyncLogSink struct {
+	name    string
+	sink    LogSink
+	records chan *LogRecord
+	done    chan struct{}
+	once    sync.Once
+}
+
+func newAsyncLogSink(name string, sink LogSink) *asyncLogSink {
+	s := &asyncLogSink{name: name, sink: sink, records: make(chan *LogRecord, logSinkBufferSize), done: make(chan struct{})}
+	go s.run()
+	return s
+}
+
+// Write implements io.Writer, p is one message written by the standard logger.
+func (s *asyncLogSink) Write(p []byte) (n int, err error) {
+	n = len(p)
+	defer func() {
+		// the sink has been closed
+		recover()
+	}()
+	record := &LogRecord{Time: utils.Now(), Level: levelOf(p), Message: strings.TrimRight(string(stripLogPrefix(p)), "\n")}
+	select {
+	case s.records <- record:
+	default:
+		// buffer is full, the message is dropped rather than blocking the application
+	}
+	return n, nil
+}
+
+func (s *asyncLogSink) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(logSinkInterval)
+	defer ticker.Stop()
+	batch := make([]*LogRecord, 0, logSinkBatchSize)
+	flush := func() {
+		if len(batch) > 0 {
+			if err := s.sink.Send(batch); err != nil {
+				reportSinkError(s.name, err)
+			}
+			batch = make([]*LogRecord, 0, logSinkBatchSize)
+		}
+	}
+	for {
+		select {
+		case r, ok := <-s.records:
+			if !ok {
+				flush()
+				return
+			}
+			if batch = append(batch, r); len(batch) >= logSinkBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// close sends pending messages, stops the background goroutine and closes the sink if it is an io.Closer.
+func (s *asyncLogSink) close() {
+	s.once.Do(func() {
+		close(s.records)
+		select {
+		case <-s.done:
+			if closer, ok := s.sink.(io.Closer); ok {
+				closer.Close()
+			}
+		case <-time.After(5 * time.Second):
+		}
+	})
+}
+
+// stripLogPrefix removes the prefix and the date/time written by the standard logger (according to its flags).
+func stripLogPrefix(p []byte) []byte {
+	flags := log.Flags()
+	prefix := log.Prefix()
+	if flags&log.Lmsgprefix == 0 {
+		p = bytes.TrimPrefix(p, []byte(prefix))
+	}
+	n := 0
+	if flags&log.Ldate != 0 {
+		n += len("2006/01/02 ")
+	}
+	if flags&(log.Ltime|log.Lmicroseconds) != 0 {
+		n += len("15:04:05 ")
+		if flags&log.Lmicroseconds != 0 {
+			n += len(".000000")
+		}
+	}
+	if n > len(p) {
+		return p
+	}
+	p = p[n:]
+	if flags&log.Lmsgprefix != 0 {
+		p = bytes.TrimPrefix(p, []byte(prefix))
+	}
+	return p
+}
+
+/*----------------------------------------------------------------------*/
+
+func newFileLogSink(conf *hocon.Config, confPath string) (LogSink, error) {
+	opts, err := rotatingFileOptions(conf, confPath)
+	if err != nil {
+		return nil, err
+	}
+	format := conf.GetString(confPath+".format", "json")
+	if format != "json" && format != "text" {
+		return nil, fmt.Errorf("invalid log file format [%s], valid values are [json text]", format)
+	}
+	file, err := NewRotatingFile(conf.GetString(confPath+".path", "./logs/goadmin.log"), opts)
+	if err != nil {
+		return nil, err
+	}
+	return &fileLogSink{file: file, json: format == "json"}, nil
+}
+
+// fileLogSink writes log messages to a RotatingFile, one JSON document {"time", "level", "message"} or one text line
+// per message.
+type fileLogSink struct {
+	file *RotatingFile
+	json bool
+}
+
+// Send implements LogSink.Send
+func (s *fileLogSink) Send(records []*LogRecord) error {
+	buf := &bytes.Buffer{}
+	for _, r := range records {
+		if s.json {
+			js, _ := json.Marshal(map[string]interface{}{
+				"time":    r.Time.Format(time.RFC3339Nano),
+				"level":   r.Level.String(),
+				"message": r.Message,
+			})
+			buf.Write(js)
+		} else {
+			buf.WriteString(r.Time.Format("2006/01/02 15:04:05 "))
+			buf.WriteString(r.Message)
+		}
+		buf.WriteByte('\n')
+	}
+	_, err := s.file.Write(buf.Bytes())
+	return err
+}
+
+// Close implements io.Closer.Close
+func (s *fileLogSink) Close() error {
+	return s.file.Close()
+}
+
+/*----------------------------------------------------------------------*/
+
+// syslogFacilities maps names of syslog facilities to their codes.
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7, "uucp": 8, "cron": 9,
+	"authpriv": 10, "ftp": 11, "local0": 16, "local1": 17, "local2": 18, "local3": 19, "local4": 20, "local5": 21,
+	"local6": 22, "local7": 23,
+}
+
+// SyslogOptions holds settings of the syslog sink (configuration block goadmin.log_syslog).
+//
+// Available since template-r5
+type SyslogOptions struct {
+	Network  string // "udp", "tcp" or "unixgram"/"unix" (e.g. addr "/dev/log")
+	Addr     string
+	Facility string // e.g. "local0"
+	AppName  string // APP-NAME field of messages
+	Timeout  time.Duration
+}
+
+func newSyslogSink(conf *hocon.Config, confPath string) (LogSink, error) {
+	return NewSyslogSink(SyslogOptions{
+		Network:  conf.GetString(confPath+".network", "udp"),
+		Addr:     conf.GetString(confPath+".addr", "localhost:514"),
+		Facility: conf.GetString(confPath+".facility", "local0"),
+		AppName:  conf.GetString(confPath+".app_name", conf.GetString("app.name", "goadmin")),
+		Timeout:  conf.GetTimeDuration(confPath+".timeout", 5*time.Second),
+	})
+}
+
+// NewSyslogSink creates a LogSink sending messages to a syslog server in RFC5424 format. Over stream connections (tcp,
+// unix) messages are framed with octet counting (RFC6587). The connection is opened on demand and reopened after
+// errors.
+//
+// Available since template-r5
+func NewSyslogSink(opts SyslogOptions) (LogSink, error) {
+	facility, ok := syslogFacilities[strings.ToLower(opts.Facility)]
+	if !ok {
+		return nil, fmt.Errorf("invalid syslog facility [%s]", opts.Facility)
+	}
+	switch opts.Network {
+	case "udp", "tcp", "unix", "unixgram":
+	default:
+		return nil, fmt.Errorf("invalid syslog network [%s], valid values are [udp tcp unix unixgram]", opts.Network)
+	}
+	if opts.AppName == "" {
+		opts.AppName = "-"
+	}
+	return &syslogSink{opts: opts, facility: facility}, nil
+}
+
+type syslogSink struct {
+	opts     SyslogOptions
+	facility int
+	conn     net.Conn
+}
+
+// Send implements LogSink.Send
+func (s *syslogSink) Send(records []*LogRecord) error {
+	if s.conn == nil {
+		conn, err := net.DialTimeout(s.opts.Network, s.opts.Addr, s.opts.Timeout)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	stream := s.opts.Network == "tcp" || s.opts.Network == "unix"
+	s.conn.SetWriteDeadline(time.Now().Add(s.opts.Timeout))
+	for _, r := range records {
+		msg := s.format(r)
+		if stream {
+			msg = strconv.Itoa(len(msg)) + " " + msg
+		}
+		if _, err := s.conn.Write([]byte(msg)); err != nil {
+			s.conn.Close()
+			s.conn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+// format returns the RFC5424 message: <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+func (s *syslogSink) format(r *LogRecord) string {
+	host := hostname
+	if host == "" {
+		host = "-"
+	}
+	return fmt.Sprintf("<%d>1 %s %s %s %d - - %s", s.facility*8+syslogLevelOf[r.Level],
+		r.Time.Format(time.RFC3339Nano), host, s.opts.AppName, os.Getpid(), r.Message)
+}
+
+/*----------------------------------------------------------------------*/
+
+// NewGelfSink creates a LogSink shipping messages to a Graylog GELF HTTP input, gelfUrl is e.g.
+// "http://graylog:12201/gelf".
+//
+// Available since template-r5
+func NewGelfSink(gelfUrl string, timeout time.Duration) LogSink {
+	return &gelfSink{url: gelfUrl, client: &http.Client{Timeout: timeout}}
+}
+
+type gelfSink struct {
+	url    string
+	client *http.Client
+}
+
+// Send implements LogSink.Send
+func (s *gelfSink) Send(records []*LogRecord) error {
+	// GELF HTTP inputs accept one message per request
+	for _, r := range records {
+		msg := map[string]interface{}{
+			"version":       "1.1",
+			"host":          hostname,
+			"short_message": r.Message,
+			"timestamp":     float64(r.Time.UnixNano()) / 1e9,
+			"level":         syslogLevelOf[r.Level],
+			"_level_name":   r.Level.String(),
+		}
+		js, _ := json.Marshal(msg)
+		if err := postLogs(s.client, s.url, js); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*----------------------------------------------------------------------*/
+
+// NewLokiSink creates a LogSink shipping messages to Grafana Loki, lokiUrl is the base URL of Loki (e.g.
+// "http://loki:3100"). Messages are labelled with labels plus "level".
+//
+// Available since template-r5
+func NewLokiSink(lokiUrl string, labels map[string]string, timeout time.Duration) LogSink {
+	return &lokiSink{url: strings.TrimSuffix(lokiUrl, "/") + "/loki/api/v1/push", labels: labels, client: &http.Client{Timeout: timeout}}
+}
+
+type lokiSink struct {
+	url    string
+	labels map[string]string
+	client *http.Client
+}
+
+// Send implements LogSink.Send
+func (s *lokiSink) Send(records []*LogRecord) error {
+	// one stream per level, entries of a stream must be in chronological order
+	streams := make(map[LogLevel][][2]string)
+	for _, r := range records {
+		streams[r.Level] = append(streams[r.Level], [2]string{strconv.FormatInt(r.Time.UnixNano(), 10), r.Message})
+	}
+	levels := make([]LogLevel, 0, len(streams))
+	for level := range streams {
+		levels = append(levels, level)
+	}
+	sort.Slice(levels, func(i, j int) bool { return levels[i] < levels[j] })
+	payload := make([]interface{}, 0, len(streams))
+	for _, level := range levels {
+		labels := map[string]string{"level": level.String()}
+		for k, v := range s.labels {
+			labels[k] = v
+		}
+		payload = append(payload, map[string]interface{}{"stream": labels, "values": streams[level]})
+	}
+	js, err := json.Marshal(map[string]interface{}{"streams": payload})
+	if err != nil {
+		return err
+	}
+	return postLogs(s.client, s.url, js)
+}
+
+func postLogs(client *http.Client, url string, body []byte) error {
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("[%s] responded with status %d: %s", url, resp.StatusCode, respBody)
+	}
+	return nil
+}
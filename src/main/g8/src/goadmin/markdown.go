@@ -0,0 +1,219 @@
+package goadmin
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RenderMarkdown converts Markdown text to HTML that is safe to embed in pages: raw HTML in the source is escaped
+// rather than passed through, and links/images may only point to http(s), mailto or relative URLs.
+//
+// A commonly used subset of Markdown is supported:
+//
+//   - ATX headings ("# Title"), paragraphs, hard line breaks (two trailing spaces) and horizontal rules ("---").
+//   - fenced code blocks ("```lang"), block quotes ("> ") and ordered/unordered lists (nested by indentation).
+//   - inline code, links, images, bold ("**" or "__") and italic ("*" or "_").
+//
+// Registry.Renderer makes it available to templates as function "markdown", e.g. {{markdown .announcement}}.
+//
+// Available since template-r5
+func RenderMarkdown(src string) template.HTML {
+	src = strings.NewReplacer("\r\n", "\n", "\r", "\n", "\x00", "").Replace(src)
+	buf := &strings.Builder{}
+	renderMarkdownBlocks(strings.Split(src, "\n"), buf)
+	return template.HTML(buf.String())
+}
+
+var (
+	mdReHeading   = regexp.MustCompile(`^ {0,3}(#{1,6})(?:\s+(.*?))?(?:\s+#+)?\s*$`)
+	mdReListItem  = regexp.MustCompile(`^ {0,3}([-*+]|\d{1,9}[.)])\s+(.*)$`)
+	mdReFence     = regexp.MustCompile("^ {0,3}```\\s*([\\w+-]*)")
+	mdReQuote     = regexp.MustCompile(`^ {0,3}> ?(.*)$`)
+	mdReCode      = regexp.MustCompile("`([^`]+)`")
+	mdReImage     = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)\)`)
+	mdReLink      = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	mdReBold      = regexp.MustCompile(`\*\*(\S(?:.*?\S)?)\*\*|__(\S(?:.*?\S)?)__`)
+	mdReItalicStr = regexp.MustCompile(`\*(\S(?:.*?\S)?)\*`)
+	mdReItalicUnd = regexp.MustCompile(`(^|\W)_(\S(?:.*?\S)?)_(\W|$)`)
+)
+
+func isMarkdownHr(line string) bool {
+	line = strings.ReplaceAll(strings.TrimSpace(line), " ", "")
+	if len(line) < 3 || strings.Trim(line, line[:1]) != "" {
+		return false
+	}
+	return line[0] == '-' || line[0] == '*' || line[0] == '_'
+}
+
+func isMarkdownBlockStart(line string) bool {
+	return mdReHeading.MatchString(line) || mdReFence.MatchString(line) || mdReQuote.MatchString(line) ||
+		isMarkdownHr(line) || mdReListItem.MatchString(line)
+}
+
+func renderMarkdownBlocks(lines []string, buf *strings.Builder) {
+	para := make([]string, 0)
+	flushPara := func() {
+		if len(para) > 0 {
+			buf.WriteString("<p>" + renderMarkdownInline(strings.Join(para, "\n")) + "</p>\n")
+			para = para[:0]
+		}
+	}
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" {
+			flushPara()
+			continue
+		}
+		if len(para) > 0 && !isMarkdownBlockStart(line) {
+			// lazy continuation of the current paragraph
+			para = append(para, line)
+			continue
+		}
+		flushPara()
+		switch {
+		case mdReFence.MatchString(line):
+			lang := mdReFence.FindStringSubmatch(line)[1]
+			code := make([]string, 0)
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			if lang != "" {
+				buf.WriteString(`<pre><code class="language-` + html.EscapeString(lang) + `">`)
+			} else {
+				buf.WriteString("<pre><code>")
+			}
+			buf.WriteString(html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+		case mdReHeading.MatchString(line):
+			m := mdReHeading.FindStringSubmatch(line)
+			level := strconv.Itoa(len(m[1]))
+			buf.WriteString("<h" + level + ">" + renderMarkdownInline(m[2]) + "</h" + level + ">\n")
+		case isMarkdownHr(line):
+			buf.WriteString("<hr/>\n")
+		case mdReQuote.MatchString(line):
+			quote := make([]string, 0)
+			for ; i < len(lines) && mdReQuote.MatchString(lines[i]); i++ {
+				quote = append(quote, mdReQuote.FindStringSubmatch(lines[i])[1])
+			}
+			i--
+			buf.WriteString("<blockquote>\n")
+			renderMarkdownBlocks(quote, buf)
+			buf.WriteString("</blockquote>\n")
+		case mdReListItem.MatchString(line):
+			i = renderMarkdownList(lines, i, buf) - 1
+		default:
+			para = append(para, line)
+		}
+	}
+	flushPara()
+}
+
+// renderMarkdownList renders the list starting at lines[start] and returns the index of the first line after the list.
+func renderMarkdownList(lines []string, start int, buf *strings.Builder) int {
+	ordered := !strings.ContainsAny(mdReListItem.FindStringSubmatch(lines[start])[1], "-*+")
+	tag := "ul"
+	if ordered {
+		tag = "ol"
+	}
+	buf.WriteString("<" + tag + ">\n")
+	i := start
+	for i < len(lines) {
+		m := mdReListItem.FindStringSubmatch(lines[i])
+		if m == nil || ordered == strings.ContainsAny(m[1], "-*+") {
+			break
+		}
+		// item's content: the first line and following lines that are indented
+		text := []string{m[2]}
+		children := make([]string, 0)
+		for i++; i < len(lines); i++ {
+			line := lines[i]
+			indented := strings.HasPrefix(line, "  ") || strings.HasPrefix(line, "\t")
+			if strings.TrimSpace(line) == "" {
+				if i+1 < len(lines) && (strings.HasPrefix(lines[i+1], "  ") || strings.HasPrefix(lines[i+1], "\t")) {
+					children = append(children, "")
+					continue
+				}
+				break
+			}
+			if !indented {
+				if isMarkdownBlockStart(line) || len(children) > 0 {
+					break
+				}
+				// lazy continuation of the item's text
+				text = append(text, line)
+				continue
+			}
+			line = strings.TrimPrefix(strings.TrimPrefix(line, "\t"), "  ")
+			if len(children) == 0 && !isMarkdownBlockStart(line) {
+				text = append(text, line)
+			} else {
+				children = append(children, line)
+			}
+		}
+		buf.WriteString("<li>" + renderMarkdownInline(strings.Join(text, "\n")))
+		if len(children) > 0 {
+			buf.WriteString("\n")
+			renderMarkdownBlocks(children, buf)
+		}
+		buf.WriteString("</li>\n")
+		if i < len(lines) && strings.TrimSpace(lines[i]) == "" {
+			// a blank line ends the list unless followed by another item
+			if i+1 >= len(lines) || !mdReListItem.MatchString(lines[i+1]) {
+				break
+			}
+			i++
+		}
+	}
+	buf.WriteString("</" + tag + ">\n")
+	return i
+}
+
+// sanitizeMarkdownUrl returns the (already HTML-escaped) URL if it is relative or uses a safe scheme, "#" otherwise.
+func sanitizeMarkdownUrl(url string) string {
+	colon := strings.Index(url, ":")
+	if colon < 0 || (strings.IndexAny(url, "/?#") >= 0 && strings.IndexAny(url, "/?#") < colon) {
+		// relative URL
+		return url
+	}
+	switch strings.ToLower(url[:colon]) {
+	case "http", "https", "mailto":
+		return url
+	}
+	return "#"
+}
+
+func renderMarkdownInline(text string) string {
+	// protect generated HTML (code spans, links and images) from further processing using placeholders
+	protected := make([]string, 0)
+	protect := func(html string) string {
+		protected = append(protected, html)
+		return fmt.Sprintf("\x00%d\x00", len(protected)-1)
+	}
+	text = html.EscapeString(text)
+	text = mdReCode.ReplaceAllStringFunc(text, func(s string) string {
+		return protect("<code>" + mdReCode.FindStringSubmatch(s)[1] + "</code>")
+	})
+	text = mdReImage.ReplaceAllStringFunc(text, func(s string) string {
+		m := mdReImage.FindStringSubmatch(s)
+		return protect(`<img src="` + sanitizeMarkdownUrl(m[2]) + `" alt="` + m[1] + `"/>`)
+	})
+	text = mdReLink.ReplaceAllStringFunc(text, func(s string) string {
+		m := mdReLink.FindStringSubmatch(s)
+		return protect(`<a href="` + sanitizeMarkdownUrl(m[2]) + `" rel="nofollow noopener">` + renderMarkdownEmphasis(m[1]) + `</a>`)
+	})
+	text = renderMarkdownEmphasis(text)
+	text = strings.ReplaceAll(text, "  \n", "<br/>\n")
+	for i := len(protected) - 1; i >= 0; i-- {
+		text = strings.ReplaceAll(text, fmt.Sprintf("\x00%d\x00", i), protected[i])
+	}
+	return text
+}
+
+func renderMarkdownEmphasis(text string) string {
+	text = mdReBold.ReplaceAllString(text, "<strong>$1$2</strong>")
+	text = mdReItalicStr.ReplaceAllString(text, "<em>$1</em>")
+	return mdReItalicUnd.ReplaceAllString(text, "$1<em>$2</em>$3")
+}
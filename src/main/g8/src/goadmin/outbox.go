@@ -0,0 +1,356 @@
+package goadmin
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	hocon "github.com/go-akka/configuration"
+	"main/src/utils"
+)
+
+const (
+	// OutboxChannelWebhook is the outbox channel delivering WebhookPayload messages.
+	//
+	// Available since template-r5
+	OutboxChannelWebhook = "webhook"
+
+	// OutboxChannelEmail is the outbox channel delivering EmailPayload messages, available if SMTP is configured
+	// (setting goadmin.smtp.addr).
+	//
+	// Available since template-r5
+	OutboxChannelEmail = "email"
+
+	// OutboxChannelSms is the outbox channel delivering SmsPayload messages, available if an SMS provider is configured
+	// (setting goadmin.sms.provider).
+	//
+	// Available since template-r5
+	OutboxChannelSms = "sms"
+
+	outboxBatchSize = 100
+)
+
+// OutboxMessage is an outgoing message (e.g. a webhook call or an email) waiting in the outbox to be delivered.
+//
+// Available since template-r5
+type OutboxMessage struct {
+	Id          string          `json:"id"`
+	Channel     string          `json:"channel"` // sender the message is delivered by, e.g. OutboxChannelWebhook
+	Payload     json.RawMessage `json:"payload"`
+	Created     time.Time       `json:"created"`
+	Attempts    int             `json:"attempts"`     // number of failed delivery attempts
+	NextAttempt time.Time       `json:"next_attempt"` // the message is not delivered before this time
+	LastError   string          `json:"last_error"`
+	GaveUp      time.Time       `json:"gave_up"` // when the message was given up (dead letter), zero if still pending
+}
+
+// IsDead checks if the message has been given up after too many failed attempts. Dead letters are kept in the store
+// until re-sent (see Outbox.Retry) or purged, so that lost messages can be investigated.
+func (m *OutboxMessage) IsDead() bool {
+	return !m.GaveUp.IsZero()
+}
+
+// OutboxStore persists outbox messages, so that they survive crashes and restarts.
+//
+// Available since template-r5
+type OutboxStore interface {
+	// Put stores a message, replacing the one with the same id (if any).
+	Put(msg *OutboxMessage) error
+	// Due returns at most limit messages whose next attempt is due at now, oldest first. Dead letters are not due.
+	Due(now time.Time, limit int) ([]*OutboxMessage, error)
+	// List returns all messages, dead letters included, oldest first.
+	List() ([]*OutboxMessage, error)
+	// Get returns a message, nil if not found.
+	Get(id string) (*OutboxMessage, error)
+	// Delete removes a message, once delivered or purged.
+	Delete(id string) error
+}
+
+// OutboxSender delivers the payload of a message, a non-nil error means the delivery must be retried.
+//
+// Available since template-r5
+type OutboxSender func(payload json.RawMessage) error
+
+// NewOutbox creates an Outbox whose messages are kept in store.
+//
+// Available since template-r5
+func NewOutbox(store OutboxStore) *Outbox {
+	return &Outbox{Store: store, MaxAttempts: 10, Backoff: 30 * time.Second, senders: make(map[string]OutboxSender)}
+}
+
+// Outbox implements the outbox pattern: outgoing messages are stored (see Enqueue) by the code making the triggering
+// change, then delivered by a background dispatcher (see Start) which retries failed deliveries with exponential
+// backoff. A message is only removed from the store once delivered, hence delivery is at-least-once even if the
+// application crashes in between: receivers should be idempotent (e.g. using the message id). Messages still failing
+// after MaxAttempts are kept as dead letters (see OutboxMessage.IsDead) until re-sent or purged.
+//
+// Available since template-r5
+type Outbox struct {
+	Store       OutboxStore
+	MaxAttempts int           // messages are given up (kept as dead letters) after this number of failed attempts
+	Backoff     time.Duration // delay before the first retry, doubled on every attempt (capped at 1 hour)
+
+	lock    sync.RWMutex
+	senders map[string]OutboxSender
+	stop    chan struct{}
+}
+
+// RegisterSender registers the sender delivering messages of a channel.
+func (o *Outbox) RegisterSender(channel string, sender OutboxSender) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.senders[channel] = sender
+}
+
+// HasSender checks if a sender is registered for the channel.
+func (o *Outbox) HasSender(channel string) bool {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+	return o.senders[channel] != nil
+}
+
+// Enqueue stores a message to be delivered by the channel's sender, payload is encoded as JSON.
+func (o *Outbox) Enqueue(channel string, payload interface{}) (*OutboxMessage, error) {
+	msg, err := o.NewMessage(channel, payload)
+	if err != nil {
+		return nil, err
+	}
+	return msg, o.Store.Put(msg)
+}
+
+// NewMessage creates a message to be delivered by the channel's sender without storing it, payload is encoded as JSON.
+// It lets applications store messages themselves, e.g. in the database transaction of the change they report.
+//
+// Available since template-r5
+func (o *Outbox) NewMessage(channel string, payload interface{}) (*OutboxMessage, error) {
+	if !o.HasSender(channel) {
+		return nil, fmt.Errorf("no sender registered for outbox channel [%s]", channel)
+	}
+	js, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	now := utils.Now()
+	return &OutboxMessage{Id: utils.UniqueId(), Channel: channel, Payload: js, Created: now, NextAttempt: now}, nil
+}
+
+// Send delivers a payload right away with the channel's sender, bypassing the store: failures are returned rather than
+// retried (e.g. for test messages).
+func (o *Outbox) Send(channel string, payload interface{}) error {
+	o.lock.RLock()
+	sender := o.senders[channel]
+	o.lock.RUnlock()
+	if sender == nil {
+		return fmt.Errorf("no sender registered for outbox channel [%s]", channel)
+	}
+	js, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return sender(js)
+}
+
+// Dispatch delivers the due messages and returns the number of delivered ones.
+func (o *Outbox) Dispatch() int {
+	msgs, err := o.Store.Due(utils.Now(), outboxBatchSize)
+	if err != nil {
+		log.Printf("[ERROR] cannot load outbox messages: %s", err)
+		return 0
+	}
+	delivered := 0
+	for _, msg := range msgs {
+		o.lock.RLock()
+		sender := o.senders[msg.Channel]
+		o.lock.RUnlock()
+		err := fmt.Errorf("no sender registered for outbox channel [%s]", msg.Channel)
+		if sender != nil {
+			err = sender(msg.Payload)
+		}
+		if err == nil {
+			delivered++
+			if err := o.Store.Delete(msg.Id); err != nil {
+				// the message will be delivered again, which at-least-once delivery allows
+				log.Printf("[ERROR] cannot remove delivered outbox message [%s]: %s", msg.Id, err)
+			}
+			continue
+		}
+		msg.Attempts++
+		msg.LastError = err.Error()
+		if o.MaxAttempts > 0 && msg.Attempts >= o.MaxAttempts {
+			log.Printf("[ERROR] giving up outbox message [%s] (%s) after %d attempts: %s", msg.Id, msg.Channel, msg.Attempts, err)
+			msg.GaveUp = utils.Now()
+		} else {
+			msg.NextAttempt = utils.Now().Add(o.backoff(msg.Attempts))
+			log.Printf("[WARN] delivery of outbox message [%s] (%s) failed, retrying at %s: %s", msg.Id, msg.Channel, msg.NextAttempt.Format(time.RFC3339), err)
+		}
+		if err := o.Store.Put(msg); err != nil {
+			log.Printf("[ERROR] cannot update outbox message [%s]: %s", msg.Id, err)
+		}
+	}
+	return delivered
+}
+
+// Messages returns messages of a channel waiting in the outbox, dead letters included, oldest first.
+func (o *Outbox) Messages(channel string) ([]*OutboxMessage, error) {
+	all, err := o.Store.List()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*OutboxMessage, 0, len(all))
+	for _, msg := range all {
+		if msg.Channel == channel {
+			result = append(result, msg)
+		}
+	}
+	return result, nil
+}
+
+// Retry schedules a message (e.g. a dead letter) to be delivered at the next dispatch, with a fresh count of attempts.
+// It returns false if the message is not in the outbox (e.g. it has been delivered meanwhile).
+func (o *Outbox) Retry(id string) (bool, error) {
+	msg, err := o.Store.Get(id)
+	if err != nil || msg == nil {
+		return false, err
+	}
+	msg.Attempts, msg.NextAttempt, msg.GaveUp = 0, utils.Now(), time.Time{}
+	return true, o.Store.Put(msg)
+}
+
+// backoff returns the delay before the next attempt of a message that failed attempts times.
+func (o *Outbox) backoff(attempts int) time.Duration {
+	delay := float64(o.Backoff) * math.Pow(2, float64(attempts-1))
+	if max := float64(time.Hour); delay > max {
+		return time.Hour
+	}
+	return time.Duration(delay)
+}
+
+// Start runs the dispatcher in background, every interval. Messages are only dispatched while active returns true
+// (e.g. Registry.IsLeader), so that a message is not delivered by several instances at the same time.
+func (o *Outbox) Start(interval time.Duration, active func() bool) {
+	o.stop = make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-o.stop:
+				return
+			case <-ticker.C:
+				if active() {
+					o.Dispatch()
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops the background dispatcher, undelivered messages are kept in the store.
+func (o *Outbox) Stop() {
+	if o.stop != nil {
+		close(o.stop)
+		o.stop = nil
+	}
+}
+
+// newOutboxFromConfig creates the outbox of the application from settings goadmin.outbox, goadmin.webhook and
+// goadmin.smtp. The SMS sender is registered separately (see initSms), its configuration can be invalid.
+func newOutboxFromConfig(conf *hocon.Config) *Outbox {
+	outbox := NewOutbox(NewMemoryOutboxStore())
+	outbox.MaxAttempts = int(conf.GetInt32("goadmin.outbox.max_attempts", 10))
+	outbox.Backoff = conf.GetTimeDuration("goadmin.outbox.backoff", 30*time.Second)
+	outbox.RegisterSender(OutboxChannelWebhook, NewWebhookSender(conf.GetString("goadmin.webhook.secret", ""),
+		conf.GetTimeDuration("goadmin.webhook.timeout", 10*time.Second)))
+	if addr := conf.GetString("goadmin.smtp.addr", ""); addr != "" {
+		outbox.RegisterSender(OutboxChannelEmail, NewSmtpSender(SmtpOptions{
+			Addr:     addr,
+			Username: conf.GetString("goadmin.smtp.username", ""),
+			Password: conf.GetString("goadmin.smtp.password", ""),
+			From:     conf.GetString("goadmin.smtp.from", ""),
+		}))
+	}
+	return outbox
+}
+
+/*----------------------------------------------------------------------*/
+
+// NewMemoryOutboxStore creates an OutboxStore keeping messages in memory: messages are lost if the application stops,
+// modules should set a persistent store (e.g. in their database).
+//
+// Available since template-r5
+func NewMemoryOutboxStore() OutboxStore {
+	return &memoryOutboxStore{messages: make(map[string]OutboxMessage)}
+}
+
+type memoryOutboxStore struct {
+	lock     sync.Mutex
+	messages map[string]OutboxMessage
+}
+
+// Put implements OutboxStore.Put
+func (s *memoryOutboxStore) Put(msg *OutboxMessage) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.messages[msg.Id] = *msg
+	return nil
+}
+
+// Due implements OutboxStore.Due
+func (s *memoryOutboxStore) Due(now time.Time, limit int) ([]*OutboxMessage, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	result := make([]*OutboxMessage, 0)
+	for _, msg := range s.messages {
+		if !msg.IsDead() && !msg.NextAttempt.After(now) {
+			m := msg
+			result = append(result, &m)
+		}
+	}
+	return SortOutboxMessages(result, limit), nil
+}
+
+// List implements OutboxStore.List
+func (s *memoryOutboxStore) List() ([]*OutboxMessage, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	result := make([]*OutboxMessage, 0, len(s.messages))
+	for _, msg := range s.messages {
+		m := msg
+		result = append(result, &m)
+	}
+	return SortOutboxMessages(result, 0), nil
+}
+
+// Get implements OutboxStore.Get
+func (s *memoryOutboxStore) Get(id string) (*OutboxMessage, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if msg, ok := s.messages[id]; ok {
+		return &msg, nil
+	}
+	return nil, nil
+}
+
+// Delete implements OutboxStore.Delete
+func (s *memoryOutboxStore) Delete(id string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.messages, id)
+	return nil
+}
+
+// SortOutboxMessages sorts messages oldest first and keeps at most limit of them, a helper for OutboxStore
+// implementations.
+//
+// Available since template-r5
+func SortOutboxMessages(msgs []*OutboxMessage, limit int) []*OutboxMessage {
+	sort.Slice(msgs, func(i, j int) bool { return msgs[i].Created.Before(msgs[j].Created) })
+	if limit > 0 && len(msgs) > limit {
+		msgs = msgs[:limit]
+	}
+	return msgs
+}
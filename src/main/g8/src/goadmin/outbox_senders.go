@@ -0,0 +1,111 @@
+package goadmin
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"main/src/utils"
+)
+
+// WebhookPayload is the payload of OutboxChannelWebhook messages: Body is POSTed as JSON to Url.
+//
+// Available since template-r5
+type WebhookPayload struct {
+	Id   string          `json:"id"`   // sent in header X-Webhook-Id, so that receivers can drop duplicated deliveries
+	Url  string          `json:"url"`  // URL of the receiver
+	Body json.RawMessage `json:"body"` // JSON document to send
+}
+
+// NewWebhookSender creates the OutboxSender of webhooks. If secret is not empty, requests carry header
+// X-Webhook-Signature: "sha256=" followed by the hex-encoded HMAC-SHA256 of the body, so that receivers can verify
+// their origin. Responses with status other than 2xx are failed deliveries.
+//
+// Available since template-r5
+func NewWebhookSender(secret string, timeout time.Duration) OutboxSender {
+	client := &http.Client{Timeout: timeout}
+	return func(payload json.RawMessage) error {
+		webhook := &WebhookPayload{}
+		if err := json.Unmarshal(payload, webhook); err != nil {
+			return err
+		}
+		req, err := http.NewRequest(http.MethodPost, webhook.Url, bytes.NewReader(webhook.Body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Webhook-Id", webhook.Id)
+		if secret != "" {
+			mac := hmac.New(sha256.New, []byte(secret))
+			mac.Write(webhook.Body)
+			req.Header.Set("X-Webhook-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		io.Copy(ioutil.Discard, resp.Body)
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("webhook [%s] responded with status %d", webhook.Url, resp.StatusCode)
+		}
+		return nil
+	}
+}
+
+/*----------------------------------------------------------------------*/
+
+// EmailPayload is the payload of OutboxChannelEmail messages, Body is plain text.
+//
+// Available since template-r5
+type EmailPayload struct {
+	To      []string `json:"to"`
+	Subject string   `json:"subject"`
+	Body    string   `json:"body"`
+}
+
+// SmtpOptions holds settings of the SMTP server emails are sent through (configuration block goadmin.smtp).
+//
+// Available since template-r5
+type SmtpOptions struct {
+	Addr     string // host:port of the SMTP server
+	Username string // empty if the server does not require authentication
+	Password string
+	From     string // sender address
+}
+
+// NewSmtpSender creates the OutboxSender of emails.
+//
+// Available since template-r5
+func NewSmtpSender(opts SmtpOptions) OutboxSender {
+	return func(payload json.RawMessage) error {
+		email := &EmailPayload{}
+		if err := json.Unmarshal(payload, email); err != nil {
+			return err
+		}
+		var auth smtp.Auth
+		if opts.Username != "" {
+			host, _, _ := net.SplitHostPort(opts.Addr)
+			auth = smtp.PlainAuth("", opts.Username, opts.Password, host)
+		}
+		msg := &bytes.Buffer{}
+		fmt.Fprintf(msg, "From: %s\r\n", opts.From)
+		fmt.Fprintf(msg, "To: %s\r\n", strings.Join(email.To, ", "))
+		fmt.Fprintf(msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", email.Subject))
+		fmt.Fprintf(msg, "Date: %s\r\n", utils.Now().Format(time.RFC1123Z))
+		msg.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+		msg.WriteString(strings.ReplaceAll(strings.ReplaceAll(email.Body, "\r\n", "\n"), "\n", "\r\n"))
+		return smtp.SendMail(opts.Addr, auth, opts.From, email.To, msg.Bytes())
+	}
+}
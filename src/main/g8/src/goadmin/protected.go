@@ -0,0 +1,101 @@
+package goadmin
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-akka/configuration/hocon"
+	"github.com/labstack/echo/v4"
+)
+
+// ProtectedResource is a directory whose files are served to authorized requests only, unlike static resources
+// (setting static_resources) which are public. Typical use: generated exports, uploaded avatars.
+//
+// Available since template-r5
+type ProtectedResource struct {
+	Uri        string // URI path prefix, e.g. "/files/exports"
+	Dir        string // directory files are served from
+	Permission string // application-defined permission required to access the files, empty means any signed-in user
+}
+
+// ResourceAuthorizer decides if the current request is allowed to access a file (path relative to the resource's
+// directory) of a protected resource. It returns nil to grant access, or the error to respond with (e.g.
+// echo.ErrForbidden).
+//
+// Available since template-r5
+type ResourceAuthorizer func(c echo.Context, res *ProtectedResource, file string) error
+
+// ServeProtected serves files of a protected resource. A request is granted access if its URL carries a valid, unexpired
+// signature (see SignResourceUrl), otherwise Registry.ResourceAuthorizer is consulted; access is denied if no
+// authorizer has been set.
+//
+// Protected resources are available on both the public site and the control panel (see SharePath).
+//
+// Available since template-r5
+func (r *Registry) ServeProtected(res *ProtectedResource) {
+	uri := "/" + strings.Trim(res.Uri, "/")
+	r.EchoServer.GET(uri+"/*", r.protectedResourceHandler(res))
+	r.SharePath(uri)
+}
+
+// SignResourceUrl returns a temporary URL of a protected resource's file (path relative to the base path, e.g.
+// "/files/exports/report.csv"), valid for ttl, that can be accessed without a session, e.g. to share a download link.
+//
+// Available since template-r5
+func (r *Registry) SignResourceUrl(path string, ttl time.Duration) string {
+	return r.UrlSigner.SignWithTtl(path, ttl)
+}
+
+func (r *Registry) protectedResourceHandler(res *ProtectedResource) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		name, err := url.PathUnescape(c.Param("*"))
+		if err != nil {
+			return echo.ErrNotFound
+		}
+		// cleaning the rooted path drops ".." elements, so that files outside of the directory can not be reached
+		name = strings.TrimPrefix(path.Clean("/"+name), "/")
+		if err := r.UrlSigner.Verify(c.Request().URL); err != nil {
+			if r.ResourceAuthorizer == nil {
+				return echo.ErrForbidden
+			}
+			if err := r.ResourceAuthorizer(c, res, name); err != nil {
+				return err
+			}
+		}
+		c.Response().Header().Set(echo.HeaderCacheControl, "private, max-age=0")
+		return c.File(filepath.Join(res.Dir, filepath.FromSlash(name)))
+	}
+}
+
+// initProtectedResources serves protected resources of setting protected_resources, a map of URI path prefixes to
+// either a directory or an object {dir, permission}.
+func initProtectedResources(registry *Registry, confV *hocon.HoconValue) error {
+	for uri, resV := range confV.GetObject().Items() {
+		res := &ProtectedResource{Uri: uri}
+		if resV.IsString() {
+			res.Dir = resV.GetString()
+		} else if resV.IsObject() {
+			obj := resV.GetObject()
+			if v := obj.GetKey("dir"); v != nil {
+				res.Dir = v.GetString()
+			}
+			if v := obj.GetKey("permission"); v != nil {
+				res.Permission = v.GetString()
+			}
+		}
+		if res.Dir == "" {
+			return fmt.Errorf("no directory configured for protected resource [%s]", uri)
+		}
+		if err := CheckDir(res.Dir); err != nil {
+			return err
+		}
+		log.Printf("Mapping protected resources: %s -> %s (permission: %q)", uri, res.Dir, res.Permission)
+		registry.ServeProtected(res)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package goadmin
+
+import (
+	"io"
+	"sync"
+
+	hocon "github.com/go-akka/configuration"
+	"github.com/gorilla/sessions"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	ctxRegistry = "_goadmin_registry_"
+)
+
+// NewRegistry creates a new Registry instance.
+func NewRegistry(appConfig *hocon.Config) *Registry {
+	return &Registry{
+		AppConfig:    appConfig,
+		ConfigSchema: newGoadminConfigSchema(),
+		CP:           CPMiddlewares{Csrf: newCsrfMiddleware()},
+		Diagnostics:  &Diagnostics{},
+		I18n:         NewI18nBundles(),
+		Webhooks:     NewWebhookReceivers(),
+		components:   make(map[string]interface{}),
+		done:         make(chan struct{}),
+
+		siteMiddlewares: make(map[string][]echo.MiddlewareFunc),
+		routeHooks:      make(map[string][]echo.MiddlewareFunc),
+	}
+}
+
+// Registry is the application container that carries shared components (application's configurations, Echo server,
+// template renderer, session store and application-specific components such as DAOs) so that they are passed to
+// bootstrappers and handlers rather than being stored in package-level variables.
+//
+// Available since template-r5
+type Registry struct {
+	AppConfig    *hocon.Config
+	BasePath     string // path the application is mounted under (e.g. "/admin"), empty if mounted at the root
+	EchoServer   *echo.Echo
+	Renderer     *GoadminRenderer
+	SessionStore sessions.Store
+	UrlSigner    *UrlSigner
+	FileStore    FileStore         // stores files produced by the application, see setting goadmin.file_store_dir
+	Cache        Cache             // key-value cache shared by modules, see setting goadmin.cache
+	Locker       Locker            // distributed locks, see WithLock
+	Leader       *LeaderElection   // election of the instance running scheduled jobs, see IsLeader
+	Outbox       *Outbox           // outgoing webhooks and emails, see setting goadmin.outbox
+	EventBus     EventPublisher    // message bus events are mirrored to, nil if disabled (see PublishEvent)
+	Webhooks     *WebhookReceivers // inbound webhooks, modules register their handlers (see setting goadmin.webhook.receivers)
+	CP           CPMiddlewares
+	ConfigSchema *ConfigSchema
+	Diagnostics  *Diagnostics
+	I18n         *I18nBundles // i18n bundles of modules, per namespace
+	GeoIp        *GeoIp       // GeoIP lookup, nil if disabled (setting goadmin.geoip.db_path)
+	FieldCipher  *FieldCipher // encryption of sensitive fields at rest, nil if disabled (setting goadmin.field_encryption)
+
+	// DefaultLogLevel is the log level configured at startup (setting goadmin.log_level), see SetLogLevel
+	DefaultLogLevel LogLevel
+
+	// ErrorLocalizer (if set) translates messages of framework-generated HTTP errors
+	ErrorLocalizer ErrorLocalizer
+
+	// ResourceAuthorizer (if set) grants access to protected resources requested via unsigned URLs, see ServeProtected
+	ResourceAuthorizer ResourceAuthorizer
+
+	lock       sync.RWMutex
+	components map[string]interface{}
+	done       chan struct{} // closed by Close
+	closeOnce  sync.Once
+
+	cpPrefixes       []string                         // prefixes of control panel route groups
+	sharedPrefixes   []string                         // path prefixes shared by the public site and the control panel
+	siteMiddlewares  map[string][]echo.MiddlewareFunc // middleware chains, per site
+	bodyLimit        int64                            // maximum request body size, 0 means no limit
+	bodyLimits       map[string]int64                 // maximum request body sizes, per route path
+	routeHooks       map[string][]echo.MiddlewareFunc // middlewares added to routes, per route name (see WrapRoute)
+	routeNames       sync.Map                         // cache of route names, keyed by "<method> <path>"
+	eventTopicPrefix string                           // prepended to topics of published events
+	accessLog        io.Closer                        // file of the access log, nil if not written to a file
+	serverTuning     *serverTuning                    // timeouts and limits of HTTP servers (configuration block "server")
+}
+
+// Done returns a channel that is closed when the application shuts down (see Close), background jobs of modules stop
+// when it is closed.
+//
+// Available since template-r5
+func (r *Registry) Done() <-chan struct{} {
+	return r.done
+}
+
+// Close signals background jobs of modules to stop (see Done). It is called when the application shuts down, and by
+// tests when they are done with an application instance. Calling Close more than once has no effect.
+//
+// Available since template-r5
+func (r *Registry) Close() {
+	r.closeOnce.Do(func() { close(r.done) })
+}
+
+// Set stores an application-specific component, identified by name, in the registry.
+//
+// A nil component removes the existing one.
+func (r *Registry) Set(name string, component interface{}) *Registry {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if component == nil {
+		delete(r.components, name)
+	} else {
+		r.components[name] = component
+	}
+	return r
+}
+
+// Get returns an application-specific component stored in the registry, nil is returned if not found.
+func (r *Registry) Get(name string) interface{} {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.components[name]
+}
+
+// Middleware is an Echo middleware that makes the registry available to handlers via GetRegistry.
+func (r *Registry) Middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		c.Set(ctxRegistry, r)
+		return next(c)
+	}
+}
+
+// GetRegistry returns the Registry associated with the current request (see Registry.Middleware).
+func GetRegistry(c echo.Context) *Registry {
+	if r, ok := c.Get(ctxRegistry).(*Registry); ok {
+		return r
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package goadmin
+
+import (
+	"testing"
+
+	hocon "github.com/go-akka/configuration"
+)
+
+func TestRegistry_Close(t *testing.T) {
+	testName := "TestRegistry_Close"
+	r := NewRegistry(hocon.ParseString(""))
+	select {
+	case <-r.Done():
+		t.Fatalf("%s failed: channel must not be closed before Close", testName)
+	default:
+	}
+	r.Close()
+	r.Close()
+	select {
+	case <-r.Done():
+	default:
+		t.Fatalf("%s failed: channel must be closed after Close", testName)
+	}
+}
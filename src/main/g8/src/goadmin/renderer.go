@@ -2,34 +2,42 @@ package goadmin
 
 import (
 	"encoding/json"
+	"html/template"
 	"io"
 	"log"
 	"reflect"
 	"strings"
 
 	"github.com/labstack/echo/v4"
-	"main/src/utils"
 )
 
 var (
+	// TemplateRenderer is the application's template renderer.
+	//
+	// Deprecated: use Registry.Renderer instead.
 	TemplateRenderer *GoadminRenderer
 )
 
 // EchoSetDefaultRenderer registers the default template renderer instance for the whole application
+//
+// Deprecated: use Registry.Renderer.SetDefaultRenderer instead.
 func EchoSetDefaultRenderer(renderer echo.Renderer) {
-	TemplateRenderer.defaultRenderer = renderer
+	TemplateRenderer.SetDefaultRenderer(renderer)
 }
 
 // EchoRegisterRenderer registers a namespace-scope renderers
+//
+// Deprecated: use Registry.Renderer.RegisterRenderer instead.
 func EchoRegisterRenderer(namespace string, renderer echo.Renderer) {
-	if TemplateRenderer.renderers == nil {
-		TemplateRenderer.renderers = make(map[string]echo.Renderer)
-	}
-	TemplateRenderer.renderers[namespace] = renderer
+	TemplateRenderer.RegisterRenderer(namespace, renderer)
 }
 
 func newGoadminRenderer() *GoadminRenderer {
-	return &GoadminRenderer{defaultRenderer: &jsonRenderer{}, renderers: make(map[string]echo.Renderer)}
+	return &GoadminRenderer{
+		defaultRenderer: &jsonRenderer{},
+		renderers:       make(map[string]echo.Renderer),
+		funcs:           template.FuncMap{"markdown": RenderMarkdown},
+	}
 }
 
 // Ref: https://echo.labstack.com/guide/templates
@@ -38,6 +46,42 @@ func newGoadminRenderer() *GoadminRenderer {
 type GoadminRenderer struct {
 	defaultRenderer echo.Renderer
 	renderers       map[string]echo.Renderer
+	funcs           template.FuncMap
+}
+
+// AddFunc registers a template function shared by all namespaces, see Funcs.
+//
+// Available since template-r5
+func (r *GoadminRenderer) AddFunc(name string, fn interface{}) {
+	if r.funcs == nil {
+		r.funcs = make(template.FuncMap)
+	}
+	r.funcs[name] = fn
+}
+
+// Funcs returns template functions shared by all namespaces (e.g. "markdown", see RenderMarkdown). Namespace-scope
+// renderers should register them to their templates before parsing, e.g. template.New(name).Funcs(r.Funcs()).
+//
+// Available since template-r5
+func (r *GoadminRenderer) Funcs() template.FuncMap {
+	result := make(template.FuncMap, len(r.funcs))
+	for name, fn := range r.funcs {
+		result[name] = fn
+	}
+	return result
+}
+
+// SetDefaultRenderer registers the default template renderer instance.
+func (r *GoadminRenderer) SetDefaultRenderer(renderer echo.Renderer) {
+	r.defaultRenderer = renderer
+}
+
+// RegisterRenderer registers a namespace-scope renderer.
+func (r *GoadminRenderer) RegisterRenderer(namespace string, renderer echo.Renderer) {
+	if r.renderers == nil {
+		r.renderers = make(map[string]echo.Renderer)
+	}
+	r.renderers[namespace] = renderer
 }
 
 // Render implements Renderer.Render.
@@ -45,12 +89,21 @@ type GoadminRenderer struct {
 // 'name' must follow the format <namespace>:<template-name>. If there is a renderer associated with the namespace,
 // it will be used for rendering (template-name is passed to the renderer via the 'name' parameter); otherwise, the
 // default renderer is used.
+//
+// If data is a map without key ViewDataI18n, the i18n helper of the namespace is added under that key, so that
+// templates resolve messages in their own namespace first.
 func (r *GoadminRenderer) Render(w io.Writer, name string, data interface{}, c echo.Context) error {
 	namespaceAndTplname := strings.SplitN(name, ":", 2)
 	if renderer, ok := r.renderers[namespaceAndTplname[0]]; ok {
-		if utils.DevMode {
+		if IsDebugEnabled() {
 			log.Printf("[DEBUG] rendering [%s]...", name)
 		}
+		// templates resolve i18n messages in their own namespace first
+		if m, ok := data.(map[string]interface{}); ok && m[ViewDataI18n] == nil {
+			if registry := GetRegistry(c); registry != nil {
+				m[ViewDataI18n] = registry.I18n.Namespace(namespaceAndTplname[0])
+			}
+		}
 		return renderer.Render(w, namespaceAndTplname[1], data, c)
 	}
 	return r.defaultRenderer.Render(w, name, data, c)
@@ -61,7 +114,7 @@ type jsonRenderer struct {
 
 // Render implements Renderer.Render
 func (r *jsonRenderer) Render(w io.Writer, name string, data interface{}, c echo.Context) error {
-	if utils.DevMode {
+	if IsDebugEnabled() {
 		log.Printf("[DEBUG] default renderer has been invoked for [%s]", name)
 	}
 	c.Response().Header().Set(echo.HeaderContentType, "application/json")
@@ -72,7 +125,9 @@ func (r *jsonRenderer) Render(w io.Writer, name string, data interface{}, c echo
 	if m, ok := data.(map[string]interface{}); ok {
 		m["_name_"] = name
 		m["_desc_"] = "generated by default 'jsonRenderer'"
-		m["_app_"] = AppConfig.GetString("app.name") + " v" + AppConfig.GetString("app.version")
+		if registry := GetRegistry(c); registry != nil {
+			m["_app_"] = registry.AppConfig.GetString("app.name") + " v" + registry.AppConfig.GetString("app.version")
+		}
 	}
 	js, err := json.Marshal(data)
 	if err != nil {
@@ -0,0 +1,235 @@
+package goadmin
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	hocon "github.com/go-akka/configuration"
+)
+
+const rotatedFileTimeFormat = "20060102-150405.000000"
+
+// RotatingFileOptions holds rotation settings of a RotatingFile, 0 means no limit.
+//
+// Available since template-r5
+type RotatingFileOptions struct {
+	MaxSize    int64         // the file is rotated once it exceeds this size (bytes)
+	Interval   time.Duration // the file is rotated at every multiple of this interval (e.g. 24h: daily, at 00:00 UTC)
+	MaxBackups int           // maximum number of rotated files kept
+	MaxAge     time.Duration // rotated files older than this are removed
+	Compress   bool          // rotated files are compressed with gzip
+}
+
+// rotatingFileOptions reads rotation settings from a configuration block: max_size, rotate_interval, max_backups,
+// max_age and compress.
+func rotatingFileOptions(conf *hocon.Config, confPath string) (RotatingFileOptions, error) {
+	opts := RotatingFileOptions{
+		MaxSize:    100 * 1024 * 1024,
+		Interval:   conf.GetTimeDuration(confPath+".rotate_interval", 0),
+		MaxBackups: int(conf.GetInt32(confPath+".max_backups", 7)),
+		MaxAge:     conf.GetTimeDuration(confPath+".max_age", 0),
+		Compress:   conf.GetBoolean(confPath+".compress", false),
+	}
+	if conf.HasPath(confPath + ".max_size") {
+		var err error
+		if opts.MaxSize, err = byteSizeOf(conf.GetValue(confPath + ".max_size").GetByteSize); err != nil {
+			return opts, fmt.Errorf("invalid [%s.max_size]: %s", confPath, err)
+		}
+	}
+	return opts, nil
+}
+
+// NewRotatingFile opens (or creates) the file at path for appending, rotating it by size and/or time: the file is
+// renamed to "<path>.<yyyyMMdd-HHmmss.ffffff>" (plus ".gz" if compressed) and a new one is started. Rotation happens
+// on the first write exceeding the size or following the end of the interval. Disable both to leave rotation to an
+// external tool (e.g. logrotate).
+//
+// Available since template-r5
+func NewRotatingFile(path string, opts RotatingFileOptions) (*RotatingFile, error) {
+	f := &RotatingFile{path: path, opts: opts}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	return f, f.open()
+}
+
+// RotatingFile is an io.WriteCloser appending to a file that is rotated by size and/or time, see NewRotatingFile.
+//
+// Available since template-r5
+type RotatingFile struct {
+	path     string
+	opts     RotatingFileOptions
+	lock     sync.Mutex
+	file     *os.File
+	size     int64
+	rotateAt time.Time // end of the current interval, zero if not rotated by time
+
+	// rotated files are compressed in background, one rotation at a time
+	compressLock sync.Mutex
+	compressions sync.WaitGroup
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.size = file, info.Size()
+	if f.opts.Interval > 0 {
+		// an existing file written in a previous interval is rotated on the first write
+		since := time.Now()
+		if f.size > 0 {
+			since = info.ModTime()
+		}
+		f.rotateAt = since.Truncate(f.opts.Interval).Add(f.opts.Interval)
+	}
+	return nil
+}
+
+// Write implements io.Writer.Write
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	bySize := f.opts.MaxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.opts.MaxSize
+	byTime := !f.rotateAt.IsZero() && !time.Now().Before(f.rotateAt)
+	if bySize || byTime {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Close implements io.Closer.Close, it waits for rotated files to be compressed.
+func (f *RotatingFile) Close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	defer f.compressions.Wait()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+	// names are fixed-width, so that they sort in chronological order
+	var backup string
+	for t := time.Now(); ; t = t.Add(time.Microsecond) {
+		backup = f.path + "." + t.Format(rotatedFileTimeFormat)
+		if !fileExists(backup) && !fileExists(backup+".gz") {
+			break
+		}
+	}
+	if err := os.Rename(f.path, backup); err != nil {
+		// keep appending to the current file rather than losing lines
+		log.Printf("[ERROR] cannot rotate [%s]: %s", f.path, err)
+		return f.open()
+	}
+	// old rotated files are removed right away, so that there are never more than MaxBackups of them
+	f.removeBackups()
+	if f.opts.Compress {
+		f.compressions.Add(1)
+		go f.compress(backup)
+	}
+	return f.open()
+}
+
+// compress compresses a rotated file, then removes rotated files again: a file removed while being compressed leaves
+// its compressed copy behind.
+func (f *RotatingFile) compress(backup string) {
+	defer f.compressions.Done()
+	f.compressLock.Lock()
+	defer f.compressLock.Unlock()
+	if err := gzipFile(backup); err != nil && fileExists(backup) {
+		// errors are written to the console, the file may be the log output itself
+		fmt.Fprintf(os.Stderr, "[ERROR] cannot compress [%s]: %s\n", backup, err)
+	}
+	f.removeBackups()
+}
+
+// removeBackups removes rotated files exceeding MaxBackups or older than MaxAge. A rotated file and its compressed
+// copy (while it is being compressed) count as one.
+func (f *RotatingFile) removeBackups() {
+	matches, err := filepath.Glob(f.path + ".*")
+	if err != nil {
+		return
+	}
+	// timestamps in names sort in chronological order, newest first
+	sort.Sort(sort.Reverse(sort.StringSlice(matches)))
+	backups := make(map[string]bool)
+	for _, m := range matches {
+		if strings.HasSuffix(m, ".gz.tmp") {
+			continue
+		}
+		backups[strings.TrimSuffix(m, ".gz")] = true
+		remove := f.opts.MaxBackups > 0 && len(backups) > f.opts.MaxBackups
+		if !remove && f.opts.MaxAge > 0 {
+			if info, err := os.Stat(m); err == nil && time.Since(info.ModTime()) > f.opts.MaxAge {
+				remove = true
+			}
+		}
+		if remove {
+			os.Remove(m)
+		}
+	}
+}
+
+// gzipFile compresses path to path.gz, then removes path.
+func gzipFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	tmp := path + ".gz.tmp"
+	dst, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if e := zw.Close(); err == nil {
+		err = e
+	}
+	if e := dst.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Rename(tmp, path+".gz")
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	src.Close()
+	return os.Remove(path)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,44 @@
+package goadmin
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingFile(t *testing.T) {
+	testName := "TestRotatingFile"
+	path := filepath.Join(t.TempDir(), "app.log")
+	f, err := NewRotatingFile(path, RotatingFileOptions{MaxSize: 100, MaxBackups: 2, Compress: true})
+	if err != nil {
+		t.Fatalf("%s failed: %s", testName, err)
+	}
+	line := strings.Repeat("x", 59) + "\n"
+	for i := 0; i < 10; i++ {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatalf("%s failed: %s", testName, err)
+		}
+	}
+	f.Close()
+	backups, _ := filepath.Glob(path + ".*")
+	if len(backups) != 2 {
+		t.Fatalf("%s failed: expected 2 rotated files, found %v", testName, backups)
+	}
+	for _, backup := range backups {
+		if !strings.HasSuffix(backup, ".gz") {
+			t.Fatalf("%s failed: rotated file %s is not compressed", testName, backup)
+		}
+		gz, _ := os.Open(backup)
+		zr, err := gzip.NewReader(gz)
+		if err != nil {
+			t.Fatalf("%s failed: %s", testName, err)
+		}
+		if content, _ := ioutil.ReadAll(zr); string(content) != line {
+			t.Fatalf("%s failed: unexpected content of %s: %q", testName, backup, content)
+		}
+		gz.Close()
+	}
+}
@@ -0,0 +1,146 @@
+package goadmin
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+const (
+	// MethodOverrideParam is the form field HTML forms use to override the request method (e.g. a form with
+	// method="post" and a hidden field _method=DELETE is routed as a DELETE request).
+	//
+	// Available since template-r5
+	MethodOverrideParam = "_method"
+
+	// MethodOverrideHeader is the request header clients use to override the request method.
+	//
+	// Available since template-r5
+	MethodOverrideHeader = echo.HeaderXHTTPMethodOverride
+)
+
+// methodOverride returns a pre-routing middleware that overrides method of POST requests using either header
+// MethodOverrideHeader or form field MethodOverrideParam.
+func methodOverride() echo.MiddlewareFunc {
+	fromHeader := middleware.MethodFromHeader(MethodOverrideHeader)
+	fromForm := middleware.MethodFromForm(MethodOverrideParam)
+	return middleware.MethodOverrideWithConfig(middleware.MethodOverrideConfig{
+		Getter: func(c echo.Context) string {
+			if m := fromHeader(c); m != "" {
+				return m
+			}
+			return fromForm(c)
+		},
+	})
+}
+
+// Mutation describes a state-changing action: an optional confirmation page served on GET, which must not change any
+// state, and the actual mutation which is only performed on POST or DELETE.
+//
+// Available since template-r5
+type Mutation struct {
+	Path        string           // URI path of the action
+	Confirm     echo.HandlerFunc // (optional) renders the confirmation page, served on GET
+	ConfirmName string           // (optional) name of the confirmation route
+	Submit      echo.HandlerFunc // performs the mutation, served on POST and DELETE
+	SubmitName  string           // (optional) name of the mutation route
+}
+
+// RouteRegistrar is implemented by both *echo.Echo and *echo.Group, so that route helpers work with either.
+//
+// Available since template-r5
+type RouteRegistrar interface {
+	GET(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+	Match(methods []string, path string, handler echo.HandlerFunc, middleware ...echo.MiddlewareFunc) []*echo.Route
+}
+
+// RegisterMutation registers routes of a state-changing action so that the mutation can never be triggered by a GET
+// request (e.g. a link or a prefetch). HTML forms can submit DELETE requests via field MethodOverrideParam.
+//
+// Middlewares apply to all routes of the action.
+//
+// Available since template-r5
+func RegisterMutation(e RouteRegistrar, m Mutation, middlewares ...echo.MiddlewareFunc) {
+	if m.Confirm != nil {
+		e.GET(m.Path, m.Confirm, middlewares...).Name = m.ConfirmName
+	}
+	for _, route := range e.Match([]string{http.MethodPost, http.MethodDelete}, m.Path, m.Submit, middlewares...) {
+		route.Name = m.SubmitName
+	}
+}
+
+/*----------------------------------------------------------------------*/
+
+// WrapRoute appends middlewares to the routes registered with a name (e.g. "cp_users"), so that downstream
+// bootstrappers can customize routes registered by other modules (e.g. add an extra permission check) without editing
+// their code. Routes registered for several methods under the same name (see RegisterMutation) are all wrapped.
+//
+// Middlewares of routes in control panel route groups (see CPGroup) run after the group's middlewares, i.e. the user
+// has been authenticated. Middlewares of other routes run after middlewares registered with Echo.Use, and before
+// per-route middlewares passed at registration time. Routes under control panel prefixes must be registered on a route
+// group created by CPGroup to be wrapped.
+//
+// Available since template-r5
+func (r *Registry) WrapRoute(name string, middlewares ...echo.MiddlewareFunc) *Registry {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.routeHooks[name] = append(r.routeHooks[name], middlewares...)
+	return r
+}
+
+// ReplaceRouteMiddlewares replaces middlewares previously added to a route by WrapRoute, no middleware removes them
+// all. Middlewares passed when the route was registered are not affected.
+//
+// Available since template-r5
+func (r *Registry) ReplaceRouteMiddlewares(name string, middlewares ...echo.MiddlewareFunc) *Registry {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if len(middlewares) == 0 {
+		delete(r.routeHooks, name)
+	} else {
+		r.routeHooks[name] = append([]echo.MiddlewareFunc{}, middlewares...)
+	}
+	return r
+}
+
+// RouteName returns the name of the route matched by the current request, empty string if the route has no name.
+//
+// Available since template-r5
+func (r *Registry) RouteName(c echo.Context) string {
+	method, path := c.Request().Method, c.Path()
+	key := method + " " + path
+	if name, ok := r.routeNames.Load(key); ok {
+		return name.(string)
+	}
+	name := ""
+	for _, route := range r.EchoServer.Routes() {
+		if route.Method == method && route.Path == path {
+			name = route.Name
+			break
+		}
+	}
+	r.routeNames.Store(key, name)
+	return name
+}
+
+// routeHooksMiddleware executes middlewares added by WrapRoute to the matched route. The instance attached to control
+// panel route groups (cp = true) handles routes under control panel prefixes, the global one handles the others.
+func (r *Registry) routeHooksMiddleware(cp bool) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if (r.SiteOf(c.Path()) == SiteCP) != cp {
+				return next(c)
+			}
+			name := r.RouteName(c)
+			r.lock.RLock()
+			middlewares := r.routeHooks[name]
+			r.lock.RUnlock()
+			h := next
+			for i := len(middlewares) - 1; i >= 0; i-- {
+				h = middlewares[i](h)
+			}
+			return h(c)
+		}
+	}
+}
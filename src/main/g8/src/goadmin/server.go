@@ -0,0 +1,157 @@
+package goadmin
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	hocon "github.com/go-akka/configuration"
+)
+
+const (
+	// MaxProcsAuto sizes GOMAXPROCS after the CPU quota of the container (cgroup), if any; see setting server.maxprocs.
+	//
+	// Available since template-r5
+	MaxProcsAuto = "auto"
+
+	// MaxProcsOff leaves GOMAXPROCS to the Go runtime: the number of CPUs of the host, whatever the CPU quota.
+	//
+	// Available since template-r5
+	MaxProcsOff = "off"
+)
+
+// serverTuning holds the tuning of HTTP servers, configured by the block "server".
+type serverTuning struct {
+	readTimeout       time.Duration
+	readHeaderTimeout time.Duration
+	writeTimeout      time.Duration
+	idleTimeout       time.Duration
+	maxHeaderBytes    int
+	keepAlives        bool
+	maxProcs          string // MaxProcsAuto, MaxProcsOff or a number
+}
+
+// serverTuningFromConfig builds the tuning of HTTP servers from the configuration block "server". The read timeout
+// falls back to the legacy setting http.request_timeout.
+func serverTuningFromConfig(appConfig *hocon.Config) (*serverTuning, error) {
+	t := &serverTuning{
+		readTimeout:       appConfig.GetTimeDuration("server.read_timeout", appConfig.GetTimeDuration("http.request_timeout", 0)),
+		readHeaderTimeout: appConfig.GetTimeDuration("server.read_header_timeout", 0),
+		writeTimeout:      appConfig.GetTimeDuration("server.write_timeout", 0),
+		idleTimeout:       appConfig.GetTimeDuration("server.idle_timeout", 0),
+		maxHeaderBytes:    http.DefaultMaxHeaderBytes,
+		keepAlives:        appConfig.GetBoolean("server.keep_alives", true),
+		maxProcs:          strings.ToLower(strings.TrimSpace(appConfig.GetString("server.maxprocs", MaxProcsAuto))),
+	}
+	// GetByteSize returns -1 for a missing key: only a configured value overrides the default
+	if appConfig.HasPath("server.max_header_bytes") {
+		size, err := byteSizeOf(appConfig.GetValue("server.max_header_bytes").GetByteSize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid [server.max_header_bytes]: %s", err)
+		}
+		if t.maxHeaderBytes = int(size); t.maxHeaderBytes <= 0 {
+			return nil, fmt.Errorf("[server.max_header_bytes] must be positive, received %d", t.maxHeaderBytes)
+		}
+	}
+	for path, d := range map[string]time.Duration{"server.read_timeout": t.readTimeout, "server.read_header_timeout": t.readHeaderTimeout,
+		"server.write_timeout": t.writeTimeout, "server.idle_timeout": t.idleTimeout} {
+		if d < 0 {
+			return nil, fmt.Errorf("[%s] must not be negative, received %s", path, d)
+		}
+	}
+	if t.maxProcs == "" {
+		t.maxProcs = MaxProcsAuto
+	}
+	if t.maxProcs != MaxProcsAuto && t.maxProcs != MaxProcsOff {
+		if n, err := strconv.Atoi(t.maxProcs); err != nil || n <= 0 {
+			return nil, fmt.Errorf("[server.maxprocs] must be \"%s\", \"%s\" or a positive number, received \"%s\"", MaxProcsAuto, MaxProcsOff, t.maxProcs)
+		}
+	}
+	return t, nil
+}
+
+// apply sets the timeouts, header size limit and keep-alive setting of an HTTP server.
+func (t *serverTuning) apply(server *http.Server) {
+	server.ReadTimeout = t.readTimeout
+	server.ReadHeaderTimeout = t.readHeaderTimeout
+	server.WriteTimeout = t.writeTimeout
+	server.IdleTimeout = t.idleTimeout
+	server.MaxHeaderBytes = t.maxHeaderBytes
+	server.SetKeepAlivesEnabled(t.keepAlives)
+}
+
+// applyMaxProcs sets GOMAXPROCS according to setting server.maxprocs. Env GOMAXPROCS, if set, takes precedence as
+// for any Go program.
+func (t *serverTuning) applyMaxProcs() {
+	if os.Getenv("GOMAXPROCS") != "" || t.maxProcs == MaxProcsOff {
+		log.Printf("GOMAXPROCS: %d", runtime.GOMAXPROCS(0))
+		return
+	}
+	if n, err := strconv.Atoi(t.maxProcs); err == nil {
+		runtime.GOMAXPROCS(n)
+		log.Printf("GOMAXPROCS: %d (setting server.maxprocs)", n)
+		return
+	}
+	quota, ok := cgroupCpuQuota()
+	if !ok {
+		log.Printf("GOMAXPROCS: %d (no CPU quota)", runtime.GOMAXPROCS(0))
+		return
+	}
+	// a fractional quota (e.g. 1.5 CPU) is rounded down: more threads than the quota allows get throttled
+	n := int(quota)
+	if n < 1 {
+		n = 1
+	}
+	if cpus := runtime.NumCPU(); n > cpus {
+		n = cpus
+	}
+	runtime.GOMAXPROCS(n)
+	log.Printf("GOMAXPROCS: %d (CPU quota %.2f)", n, quota)
+}
+
+// cgroupCpuQuota returns the CPU quota (in number of CPUs) of the cgroup of the process, cgroup v2 or v1. False is
+// returned if there is no quota or the platform has no cgroup.
+func cgroupCpuQuota() (float64, bool) {
+	// cgroup v2: "<quota> <period>" or "max <period>", in the process' cgroup (nested hierarchies) or at the root
+	// (container with a private cgroup namespace)
+	candidates := []string{"/sys/fs/cgroup/cpu.max"}
+	if data, err := ioutil.ReadFile("/proc/self/cgroup"); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if strings.HasPrefix(line, "0::/") && line != "0::/" {
+				candidates = append([]string{"/sys/fs/cgroup" + strings.TrimPrefix(line, "0::") + "/cpu.max"}, candidates...)
+			}
+		}
+	}
+	for _, file := range candidates {
+		if data, err := ioutil.ReadFile(file); err == nil {
+			fields := strings.Fields(string(data))
+			if len(fields) != 2 || fields[0] == "max" {
+				return 0, false
+			}
+			return cpuQuota(fields[0], fields[1])
+		}
+	}
+
+	// cgroup v1: quota is -1 if unlimited
+	quota, errQuota := ioutil.ReadFile("/sys/fs/cgroup/cpu/cpu.cfs_quota_us")
+	period, errPeriod := ioutil.ReadFile("/sys/fs/cgroup/cpu/cpu.cfs_period_us")
+	if errQuota != nil || errPeriod != nil {
+		return 0, false
+	}
+	return cpuQuota(strings.TrimSpace(string(quota)), strings.TrimSpace(string(period)))
+}
+
+func cpuQuota(quotaStr, periodStr string) (float64, bool) {
+	quota, errQuota := strconv.ParseFloat(quotaStr, 64)
+	period, errPeriod := strconv.ParseFloat(periodStr, 64)
+	if errQuota != nil || errPeriod != nil || quota <= 0 || period <= 0 {
+		return 0, false
+	}
+	return quota / period, true
+}
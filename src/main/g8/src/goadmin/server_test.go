@@ -0,0 +1,23 @@
+package goadmin
+
+import (
+	"net/http"
+	"testing"
+
+	hocon "github.com/go-akka/configuration"
+)
+
+func TestServerTuningFromConfig_MaxHeaderBytes(t *testing.T) {
+	testName := "TestServerTuningFromConfig_MaxHeaderBytes"
+	tuning, err := serverTuningFromConfig(hocon.ParseString("server.keep_alives = true"))
+	if err != nil || tuning.maxHeaderBytes != http.DefaultMaxHeaderBytes {
+		t.Fatalf("%s failed: a missing key must keep the default, received %#v / %v", testName, tuning, err)
+	}
+	tuning, err = serverTuningFromConfig(hocon.ParseString("server.max_header_bytes = 64KiB"))
+	if err != nil || tuning.maxHeaderBytes != 64*1024 {
+		t.Fatalf("%s failed: expected 65536 but received %#v / %v", testName, tuning, err)
+	}
+	if _, err = serverTuningFromConfig(hocon.ParseString("server.max_header_bytes = 0B")); err == nil {
+		t.Fatalf("%s failed: an explicit value of 0 must be rejected", testName)
+	}
+}
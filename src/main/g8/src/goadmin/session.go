@@ -0,0 +1,76 @@
+package goadmin
+
+import (
+	"fmt"
+	"strings"
+
+	hocon "github.com/go-akka/configuration"
+)
+
+// defaultSessionKeys are well-known session keys (goadmin's fallback and the one shipped with the template's
+// configuration file). They must not be used in production.
+var defaultSessionKeys = map[string]bool{
+	"s3cr3t_s3ssion_2uth3ntic2tion_k3y": true,
+	"R7thA8b2bmJb6Y3RfsZvJWZKZmdqvtrg":  true,
+}
+
+// sessionKeyPairs builds key pairs (authentication key followed by encryption key) for the session store from
+// configurations:
+//
+//   - goadmin.session_key and goadmin.session_encryption_key form the current pair, which is used to encode sessions.
+//   - goadmin.session_previous_keys lists retired pairs that are still accepted when decoding sessions, so keys can be
+//     rotated without logging out every user.
+//
+// In production mode (dev_mode=false), an empty or default session key is refused.
+func sessionKeyPairs(appConfig *hocon.Config, devMode bool) ([][]byte, error) {
+	authKey := appConfig.GetString("goadmin.session_key", "")
+	encKey := appConfig.GetString("goadmin.session_encryption_key", "")
+	if authKey == "" || defaultSessionKeys[authKey] {
+		if !devMode {
+			return nil, fmt.Errorf("[goadmin.session_key] is empty or a well-known default value, set a random secret key (e.g. via env GA_SESSION_KEY) before running in production mode")
+		}
+		if authKey == "" {
+			authKey = "s3cr3t_s3ssion_2uth3ntic2tion_k3y"
+		}
+	}
+	keyPairs, err := appendSessionKeyPair(nil, "goadmin.session_key", authKey, encKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if v := appConfig.GetValue("goadmin.session_previous_keys"); v != nil && v.IsArray() {
+		for i, item := range v.GetArray() {
+			path := fmt.Sprintf("goadmin.session_previous_keys[%d]", i)
+			authKey, encKey = "", ""
+			if item.IsObject() {
+				if k := item.GetChildObject("key"); k != nil {
+					authKey = k.GetString()
+				}
+				if k := item.GetChildObject("encryption_key"); k != nil {
+					encKey = k.GetString()
+				}
+			} else if item.IsString() {
+				authKey = item.GetString()
+			}
+			if keyPairs, err = appendSessionKeyPair(keyPairs, path, authKey, encKey); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return keyPairs, nil
+}
+
+func appendSessionKeyPair(keyPairs [][]byte, path, authKey, encKey string) ([][]byte, error) {
+	authKey, encKey = strings.TrimSpace(authKey), strings.TrimSpace(encKey)
+	if authKey == "" {
+		return nil, fmt.Errorf("[%s]: authentication key must not be empty", path)
+	}
+	var enc []byte
+	if encKey != "" {
+		if l := len(encKey); l != 16 && l != 24 && l != 32 {
+			return nil, fmt.Errorf("[%s]: encryption key must be 16, 24 or 32 bytes long (AES-128, AES-192 or AES-256), received %d bytes", path, l)
+		}
+		enc = []byte(encKey)
+	}
+	return append(keyPairs, []byte(authKey), enc), nil
+}
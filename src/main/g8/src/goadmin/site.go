@@ -0,0 +1,114 @@
+package goadmin
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// SitePublic identifies the public site: all routes that are not part of the control panel.
+	//
+	// Available since template-r5
+	SitePublic = "public"
+
+	// SiteCP identifies the control panel: routes under the prefixes of control panel route groups (see
+	// Registry.CPGroup), e.g. "/cp".
+	//
+	// Available since template-r5
+	SiteCP = "cp"
+
+	// CtxSite is the key under which the site (SitePublic or SiteCP) of the current request is stored in
+	// echo.Context.
+	//
+	// Available since template-r5
+	CtxSite = "site"
+)
+
+type ctxKeyListenerSite struct{}
+
+// SiteOf returns the site a request path (relative to the base path) belongs to: SiteCP for paths under the
+// prefixes of control panel route groups, empty string for shared paths (see Registry.SharePath) and SitePublic
+// otherwise.
+//
+// Available since template-r5
+func (r *Registry) SiteOf(path string) string {
+	for _, prefix := range r.cpPrefixes {
+		if hasBasePath(path, prefix) {
+			return SiteCP
+		}
+	}
+	for _, prefix := range r.sharedPrefixes {
+		if hasBasePath(path, prefix) {
+			return ""
+		}
+	}
+	return SitePublic
+}
+
+// SharePath marks routes under a path prefix (e.g. static resources) as shared by the public site and the control
+// panel, so that they are served on both when the control panel runs on separate listeners (setting
+// http.cp_listeners).
+//
+// Available since template-r5
+func (r *Registry) SharePath(prefix string) *Registry {
+	if prefix = "/" + strings.Trim(prefix, "/"); prefix != "/" {
+		r.sharedPrefixes = append(r.sharedPrefixes, prefix)
+	}
+	return r
+}
+
+// UseSite appends middlewares to the chain of a site (SitePublic or SiteCP); they are executed, after the
+// middlewares registered via echo.Echo.Use, for requests of that site only.
+//
+// Available since template-r5
+func (r *Registry) UseSite(site string, middlewares ...echo.MiddlewareFunc) *Registry {
+	r.siteMiddlewares[site] = append(r.siteMiddlewares[site], middlewares...)
+	return r
+}
+
+// siteFilter returns a pre-routing middleware that records the site of the current request and, when the request
+// was received on a listener dedicated to one site, answers requests of the other site with 404. Requests for the
+// root path on a control panel listener are redirected to the control panel.
+func (r *Registry) siteFilter() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			site := r.SiteOf(req.URL.Path)
+			c.Set(CtxSite, site)
+			if listenerSite, _ := req.Context().Value(ctxKeyListenerSite{}).(string); listenerSite != "" && site != "" && site != listenerSite {
+				if listenerSite == SiteCP && req.URL.Path == "/" && len(r.cpPrefixes) > 0 {
+					return c.Redirect(http.StatusFound, r.cpPrefixes[0])
+				}
+				return echo.ErrNotFound
+			}
+			return next(c)
+		}
+	}
+}
+
+// siteMiddleware executes the middleware chain of the current request's site (see Registry.UseSite).
+func (r *Registry) siteMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		site, _ := c.Get(CtxSite).(string)
+		h := next
+		middlewares := r.siteMiddlewares[site]
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			h = middlewares[i](h)
+		}
+		return h(c)
+	}
+}
+
+// siteHandler returns the HTTP handler for listeners dedicated to a site, empty site means all sites.
+func (r *Registry) siteHandler(site string) http.Handler {
+	if site == "" {
+		return r.EchoServer
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := context.WithValue(req.Context(), ctxKeyListenerSite{}, site)
+		r.EchoServer.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
@@ -0,0 +1,192 @@
+package goadmin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	awssession "github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sns"
+)
+
+// Providers of SMS, see SmsOptions.Provider.
+//
+// Available since template-r5
+const (
+	SmsProviderTwilio = "twilio"
+	SmsProviderSns    = "sns"
+	SmsProviderHttp   = "http"
+)
+
+// SmsPayload is the payload of OutboxChannelSms messages.
+//
+// Available since template-r5
+type SmsPayload struct {
+	To   string `json:"to"` // phone number in E.164 format, e.g. "+84901234567"
+	Body string `json:"body"`
+}
+
+// SmsOptions holds settings of the provider SMS are sent through (configuration block goadmin.sms).
+//
+// Available since template-r5
+type SmsOptions struct {
+	Provider string        // one of SmsProviderTwilio, SmsProviderSns or SmsProviderHttp
+	From     string        // sender number or alphanumeric sender id, not used by Amazon SNS
+	Timeout  time.Duration // timeout of requests to the provider
+
+	TwilioAccountSid string
+	TwilioAuthToken  string
+	TwilioApiUrl     string // base URL of the Twilio API, default "https://api.twilio.com"
+
+	SnsRegion          string
+	SnsAccessKeyId     string // credentials are read from env AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY if empty
+	SnsSecretAccessKey string
+
+	HttpUrl           string // URL the payload {"to", "from", "body"} is POSTed to as JSON
+	HttpAuthorization string // value of header Authorization, empty if none
+}
+
+// NewSmsSender creates the OutboxSender of SMS, delivering SmsPayload messages through the configured provider.
+//
+// Available since template-r5
+func NewSmsSender(opts SmsOptions) (OutboxSender, error) {
+	var send func(sms *SmsPayload) error
+	switch opts.Provider {
+	case SmsProviderTwilio:
+		if opts.TwilioAccountSid == "" || opts.TwilioAuthToken == "" {
+			return nil, fmt.Errorf("account SID and auth token of Twilio are required")
+		}
+		send = newTwilioSms(opts)
+	case SmsProviderSns:
+		sender, err := newSnsSms(opts)
+		if err != nil {
+			return nil, err
+		}
+		send = sender
+	case SmsProviderHttp:
+		if opts.HttpUrl == "" {
+			return nil, fmt.Errorf("URL of the SMS gateway is required")
+		}
+		send = newHttpSms(opts)
+	default:
+		return nil, fmt.Errorf("unknown SMS provider [%s], valid values are %s, %s, %s", opts.Provider, SmsProviderTwilio, SmsProviderSns, SmsProviderHttp)
+	}
+	return func(payload json.RawMessage) error {
+		sms := &SmsPayload{}
+		if err := json.Unmarshal(payload, sms); err != nil {
+			return err
+		}
+		return send(sms)
+	}, nil
+}
+
+// smsResponseError checks the status of a provider's response, other than 2xx means the delivery failed.
+func smsResponseError(provider string, resp *http.Response) error {
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("SMS provider [%s] responded with status %d: %s", provider, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// newTwilioSms sends SMS with Twilio's Messages API.
+func newTwilioSms(opts SmsOptions) func(sms *SmsPayload) error {
+	client := &http.Client{Timeout: opts.Timeout}
+	apiUrl := strings.TrimSuffix(opts.TwilioApiUrl, "/")
+	if apiUrl == "" {
+		apiUrl = "https://api.twilio.com"
+	}
+	endpoint := apiUrl + "/2010-04-01/Accounts/" + url.PathEscape(opts.TwilioAccountSid) + "/Messages.json"
+	return func(sms *SmsPayload) error {
+		form := url.Values{"To": {sms.To}, "From": {opts.From}, "Body": {sms.Body}}
+		req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(opts.TwilioAccountSid, opts.TwilioAuthToken)
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		return smsResponseError(SmsProviderTwilio, resp)
+	}
+}
+
+// newSnsSms sends SMS with Amazon SNS, publishing messages to phone numbers directly.
+func newSnsSms(opts SmsOptions) (func(sms *SmsPayload) error, error) {
+	creds := credentials.NewEnvCredentials()
+	if opts.SnsAccessKeyId != "" {
+		creds = credentials.NewStaticCredentials(opts.SnsAccessKeyId, opts.SnsSecretAccessKey, "")
+	}
+	sess, err := awssession.NewSession(&aws.Config{
+		Region:      aws.String(opts.SnsRegion),
+		Credentials: creds,
+		HTTPClient:  &http.Client{Timeout: opts.Timeout},
+	})
+	if err != nil {
+		return nil, err
+	}
+	client := sns.New(sess)
+	return func(sms *SmsPayload) error {
+		_, err := client.Publish(&sns.PublishInput{PhoneNumber: aws.String(sms.To), Message: aws.String(sms.Body)})
+		return err
+	}, nil
+}
+
+// newHttpSms sends SMS through a generic HTTP gateway.
+func newHttpSms(opts SmsOptions) func(sms *SmsPayload) error {
+	client := &http.Client{Timeout: opts.Timeout}
+	return func(sms *SmsPayload) error {
+		js, _ := json.Marshal(map[string]string{"to": sms.To, "from": opts.From, "body": sms.Body})
+		req, err := http.NewRequest(http.MethodPost, opts.HttpUrl, bytes.NewReader(js))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if opts.HttpAuthorization != "" {
+			req.Header.Set("Authorization", opts.HttpAuthorization)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		return smsResponseError(SmsProviderHttp, resp)
+	}
+}
+
+// initSms registers the SMS sender of the outbox if a provider is configured (setting goadmin.sms.provider).
+func initSms(registry *Registry) error {
+	conf := registry.AppConfig
+	provider := conf.GetString("goadmin.sms.provider", "")
+	if provider == "" {
+		return nil
+	}
+	sender, err := NewSmsSender(SmsOptions{
+		Provider:           provider,
+		From:               conf.GetString("goadmin.sms.from", ""),
+		Timeout:            conf.GetTimeDuration("goadmin.sms.timeout", 10*time.Second),
+		TwilioAccountSid:   conf.GetString("goadmin.sms.twilio.account_sid", ""),
+		TwilioAuthToken:    conf.GetString("goadmin.sms.twilio.auth_token", ""),
+		TwilioApiUrl:       conf.GetString("goadmin.sms.twilio.api_url", ""),
+		SnsRegion:          conf.GetString("goadmin.sms.sns.region", ""),
+		SnsAccessKeyId:     conf.GetString("goadmin.sms.sns.access_key_id", ""),
+		SnsSecretAccessKey: conf.GetString("goadmin.sms.sns.secret_access_key", ""),
+		HttpUrl:            conf.GetString("goadmin.sms.http.url", ""),
+		HttpAuthorization:  conf.GetString("goadmin.sms.http.authorization", ""),
+	})
+	if err != nil {
+		return err
+	}
+	registry.Outbox.RegisterSender(OutboxChannelSms, sender)
+	return nil
+}
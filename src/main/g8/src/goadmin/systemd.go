@@ -0,0 +1,154 @@
+package goadmin
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SystemdAuto enables systemd integration only when the application is started by systemd (default).
+	//
+	// Available since template-r5
+	SystemdAuto = "auto"
+
+	// SystemdOn always enables systemd integration; a warning is logged if the application is not started by systemd.
+	//
+	// Available since template-r5
+	SystemdOn = "on"
+
+	// SystemdOff disables systemd integration.
+	//
+	// Available since template-r5
+	SystemdOff = "off"
+
+	// sdListenFdsStart is the first file descriptor passed by systemd socket activation (see sd_listen_fds(3)).
+	sdListenFdsStart = 3
+)
+
+// systemdMode normalizes the http.systemd setting to one of SystemdAuto, SystemdOn or SystemdOff.
+func systemdMode(mode string) string {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case SystemdOn, "true", "yes", "1":
+		return SystemdOn
+	case SystemdOff, "false", "no", "0":
+		return SystemdOff
+	}
+	return SystemdAuto
+}
+
+// systemdListeners returns the sockets passed by systemd socket activation (environment variables LISTEN_PID,
+// LISTEN_FDS and LISTEN_FDNAMES, see sd_listen_fds(3)) together with their names. Nil is returned if the application
+// was not socket-activated.
+//
+// The environment variables are unset so that they are not inherited by child processes.
+func systemdListeners() ([]net.Listener, []string, error) {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil, nil
+	}
+	numFds, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || numFds <= 0 {
+		return nil, nil, nil
+	}
+	fdNames := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	listeners := make([]net.Listener, 0, numFds)
+	names := make([]string, 0, numFds)
+	for i := 0; i < numFds; i++ {
+		name := "LISTEN_FD_" + strconv.Itoa(sdListenFdsStart+i)
+		if i < len(fdNames) && fdNames[i] != "" {
+			name = fdNames[i]
+		}
+		f := os.NewFile(uintptr(sdListenFdsStart+i), name)
+		l, err := net.FileListener(f)
+		// net.FileListener duplicates the descriptor, the original one is no longer needed
+		f.Close()
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, nil, fmt.Errorf("invalid socket [%s] passed by systemd: %s", name, err)
+		}
+		listeners = append(listeners, l)
+		names = append(names, name)
+	}
+	return listeners, names, nil
+}
+
+// sdNotify sends a state notification (e.g. "READY=1") to the service manager via the socket specified by
+// environment variable NOTIFY_SOCKET (see sd_notify(3)). It returns false if the notification socket is not available.
+func sdNotify(state string) (bool, error) {
+	socketAddr := os.Getenv("NOTIFY_SOCKET")
+	if socketAddr == "" {
+		return false, nil
+	}
+	if strings.HasPrefix(socketAddr, "@") {
+		// abstract socket
+		socketAddr = "\x00" + socketAddr[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socketAddr, Net: "unixgram"})
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	if _, err = conn.Write([]byte(state)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// sdWatchdogInterval returns the interval at which the service manager expects keep-alive pings (environment
+// variables WATCHDOG_USEC and WATCHDOG_PID, see sd_watchdog_enabled(3)), 0 if the watchdog is not enabled.
+func sdWatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pidStr := os.Getenv("WATCHDOG_PID"); pidStr != "" {
+		if pid, err := strconv.Atoi(pidStr); err != nil || pid != os.Getpid() {
+			return 0
+		}
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// notifySystemdReady tells the service manager that the application has finished starting up and, if the watchdog
+// is enabled, starts sending keep-alive pings at half of the watchdog interval.
+func notifySystemdReady() {
+	ok, err := sdNotify("READY=1")
+	if err != nil {
+		log.Printf("[WARN] cannot notify systemd: %s", err)
+		return
+	}
+	if !ok {
+		return
+	}
+	log.Printf("Notified systemd that the application is ready")
+	if interval := sdWatchdogInterval(); interval > 0 {
+		go func() {
+			ticker := time.NewTicker(interval / 2)
+			defer ticker.Stop()
+			for range ticker.C {
+				if _, err := sdNotify("WATCHDOG=1"); err != nil {
+					log.Printf("[WARN] cannot send watchdog ping to systemd: %s", err)
+				}
+			}
+		}()
+	}
+}
+
+// notifySystemdStopping tells the service manager that the application is shutting down.
+func notifySystemdStopping() {
+	if _, err := sdNotify("STOPPING=1"); err != nil {
+		log.Printf("[WARN] cannot notify systemd: %s", err)
+	}
+}
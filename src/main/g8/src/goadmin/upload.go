@@ -0,0 +1,99 @@
+package goadmin
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/url"
+	"sort"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxUploadFieldSize is the maximum size of a non-file field of a streamed multipart form.
+const maxUploadFieldSize = 64 * 1024
+
+// ErrNotMultipart is returned by StreamUpload if the request is not a multipart/form-data request.
+//
+// Available since template-r5
+var ErrNotMultipart = errors.New("request is not multipart/form-data")
+
+// UploadHandler consumes the content of an uploaded file, e.g. copies it to a file store. The reader must be consumed
+// before UploadHandler returns.
+//
+// Available since template-r5
+type UploadHandler func(field, filename string, content io.Reader) error
+
+// StreamUpload reads a multipart/form-data request part by part: uploaded files are passed to handler as streams, so
+// that large uploads are not buffered in memory or temporary files (as c.FormFile does), and values of non-file fields
+// are returned.
+//
+// The request body is still subject to the body limit of the route (see Registry.SetBodyLimit). Non-file fields
+// must precede file fields in the form if the handler needs them. If a middleware has already parsed the form (e.g.
+// to read the method override or CSRF form field), uploaded files are read from the parsed form instead.
+//
+// Available since template-r5
+func StreamUpload(c echo.Context, handler UploadHandler) (url.Values, error) {
+	if form := c.Request().MultipartForm; form != nil {
+		return parsedUpload(form, handler)
+	}
+	reader, err := c.Request().MultipartReader()
+	if err != nil {
+		return nil, ErrNotMultipart
+	}
+	values := url.Values{}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return values, nil
+		}
+		if err != nil {
+			return values, err
+		}
+		field := part.FormName()
+		if filename := part.FileName(); filename != "" {
+			err = handler(field, filename, part)
+		} else {
+			var data []byte
+			if data, err = ioutil.ReadAll(io.LimitReader(part, maxUploadFieldSize+1)); err == nil {
+				if len(data) > maxUploadFieldSize {
+					err = fmt.Errorf("field [%s] exceeds %d bytes", field, maxUploadFieldSize)
+				} else {
+					values.Add(field, string(data))
+				}
+			}
+		}
+		part.Close()
+		if err != nil {
+			return values, err
+		}
+	}
+}
+
+// parsedUpload passes files of an already parsed multipart form to handler, field by field in alphabetical order.
+func parsedUpload(form *multipart.Form, handler UploadHandler) (url.Values, error) {
+	values := url.Values{}
+	for field, v := range form.Value {
+		values[field] = append([]string{}, v...)
+	}
+	fields := make([]string, 0, len(form.File))
+	for field := range form.File {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		for _, fh := range form.File[field] {
+			f, err := fh.Open()
+			if err == nil {
+				err = handler(field, fh.Filename, f)
+				f.Close()
+			}
+			if err != nil {
+				return values, err
+			}
+		}
+	}
+	return values, nil
+}
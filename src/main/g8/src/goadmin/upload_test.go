@@ -0,0 +1,45 @@
+package goadmin
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func _newUploadContext() echo.Context {
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	w.WriteField("dry_run", "1")
+	fw, _ := w.CreateFormFile("bundle", "bundle.json")
+	fw.Write([]byte(`{"version":1}`))
+	w.Close()
+	req := httptest.NewRequest(http.MethodPost, "/", body)
+	req.Header.Set(echo.HeaderContentType, w.FormDataContentType())
+	return echo.New().NewContext(req, httptest.NewRecorder())
+}
+
+func TestStreamUpload(t *testing.T) {
+	testName := "TestStreamUpload"
+	for _, parsed := range []bool{false, true} {
+		c := _newUploadContext()
+		if parsed {
+			// e.g. the method override middleware reads form field _method
+			c.FormValue(MethodOverrideParam)
+		}
+		var uploaded string
+		values, err := StreamUpload(c, func(field, filename string, content io.Reader) error {
+			data, err := ioutil.ReadAll(content)
+			uploaded = field + ":" + filename + ":" + string(data)
+			return err
+		})
+		if err != nil || values.Get("dry_run") != "1" || uploaded != `bundle:bundle.json:{"version":1}` {
+			t.Fatalf("%s failed (parsed form: %v): received %v / %s / %v", testName, parsed, values, uploaded, err)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package goadmin
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// ActionNameWebhookReceive is the name of the route receiving inbound webhooks (setting goadmin.webhook.receivers).
+	//
+	// Available since template-r5
+	ActionNameWebhookReceive = "goadmin_webhook_receive"
+
+	// Types of fields of webhook payloads, see WebhookField.
+	//
+	// Available since template-r5
+	WebhookFieldString = "string"
+	WebhookFieldNumber = "number"
+	WebhookFieldBool   = "boolean"
+	WebhookFieldObject = "object"
+	WebhookFieldArray  = "array"
+
+	maxWebhookPayloadSize = 1 << 20
+	// webhookDedupTtl is how long ids of processed deliveries (header X-Webhook-Id) are remembered.
+	webhookDedupTtl = 24 * time.Hour
+)
+
+// WebhookField describes a field of the JSON payload expected by a WebhookHandler.
+//
+// Available since template-r5
+type WebhookField struct {
+	Name     string
+	Type     string // one of WebhookFieldString, WebhookFieldNumber, WebhookFieldBool, WebhookFieldObject, WebhookFieldArray
+	Required bool
+}
+
+// WebhookHandler processes payloads of inbound webhooks. Payloads are validated against Schema before Handle is
+// called; fields not in Schema are passed as is.
+//
+// Handle returns an *echo.HTTPError to reject a payload (e.g. with status 422 if it refers to an unknown record), so
+// that the sender does not retry; other errors are responded with status 500.
+//
+// Available since template-r5
+type WebhookHandler struct {
+	Schema []WebhookField
+	Handle func(c echo.Context, payload map[string]interface{}) error
+}
+
+// ValidateWebhookPayload checks a payload against a schema.
+//
+// Available since template-r5
+func ValidateWebhookPayload(schema []WebhookField, payload map[string]interface{}) error {
+	for _, field := range schema {
+		v, ok := payload[field.Name]
+		if !ok || v == nil {
+			if field.Required {
+				return fmt.Errorf("field [%s] is required", field.Name)
+			}
+			continue
+		}
+		valid := false
+		switch field.Type {
+		case WebhookFieldString:
+			_, valid = v.(string)
+		case WebhookFieldNumber:
+			_, valid = v.(float64)
+		case WebhookFieldBool:
+			_, valid = v.(bool)
+		case WebhookFieldObject:
+			_, valid = v.(map[string]interface{})
+		case WebhookFieldArray:
+			_, valid = v.([]interface{})
+		default:
+			return fmt.Errorf("field [%s] has unknown type [%s]", field.Name, field.Type)
+		}
+		if !valid {
+			return fmt.Errorf("field [%s] must be of type %s", field.Name, field.Type)
+		}
+	}
+	return nil
+}
+
+// webhookReceiver is an endpoint receiving webhooks, configured at goadmin.webhook.receivers.<name>.
+type webhookReceiver struct {
+	name            string
+	secret          string
+	signatureHeader string
+	handler         string // name of the WebhookHandler payloads are dispatched to
+}
+
+// WebhookReceivers dispatches inbound webhooks to handlers registered by modules (see RegisterHandler). Receivers are
+// configured at goadmin.webhook.receivers: each is served at POST <goadmin.webhook.receive_path>/<name>, verifies the
+// HMAC-SHA256 signature of payloads with its own secret and dispatches them to a handler. Signatures have the same
+// format as the ones of outgoing webhooks (header X-Webhook-Signature: "sha256=<hex>"), and deliveries carrying header
+// X-Webhook-Id are processed once.
+//
+// Available since template-r5
+type WebhookReceivers struct {
+	lock      sync.RWMutex
+	handlers  map[string]*WebhookHandler
+	receivers map[string]*webhookReceiver
+}
+
+// NewWebhookReceivers creates an empty WebhookReceivers.
+//
+// Available since template-r5
+func NewWebhookReceivers() *WebhookReceivers {
+	return &WebhookReceivers{handlers: make(map[string]*WebhookHandler), receivers: make(map[string]*webhookReceiver)}
+}
+
+// RegisterHandler registers a handler receivers can dispatch payloads to, by name. Modules should prefix names with
+// their namespace (e.g. "myapp.assign_group").
+func (w *WebhookReceivers) RegisterHandler(name string, handler *WebhookHandler) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.handlers[name] = handler
+}
+
+// HandlerNames returns names of registered handlers, sorted.
+func (w *WebhookReceivers) HandlerNames() []string {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	result := make([]string, 0, len(w.handlers))
+	for name := range w.handlers {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Count returns the number of configured receivers.
+func (w *WebhookReceivers) Count() int {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	return len(w.receivers)
+}
+
+// load reads receivers from the configuration block goadmin.webhook.receivers, handlers must have been registered.
+func (w *WebhookReceivers) load(registry *Registry) error {
+	conf := registry.AppConfig
+	confPath := "goadmin.webhook.receivers"
+	v := conf.GetValue(confPath)
+	if v == nil || !v.IsObject() {
+		return nil
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for name := range v.GetObject().Items() {
+		rc := &webhookReceiver{
+			name:            name,
+			secret:          conf.GetString(confPath+"."+name+".secret", ""),
+			signatureHeader: conf.GetString(confPath+"."+name+".signature_header", "X-Webhook-Signature"),
+			handler:         conf.GetString(confPath+"."+name+".handler", ""),
+		}
+		if rc.secret == "" {
+			return fmt.Errorf("webhook receiver [%s] has no secret", name)
+		}
+		if w.handlers[rc.handler] == nil {
+			names := make([]string, 0, len(w.handlers))
+			for h := range w.handlers {
+				names = append(names, h)
+			}
+			sort.Strings(names)
+			return fmt.Errorf("webhook receiver [%s] refers to unknown handler [%s], valid values are %s", name, rc.handler, strings.Join(names, ", "))
+		}
+		w.receivers[name] = rc
+	}
+	return nil
+}
+
+func (w *WebhookReceivers) receiver(name string) (*webhookReceiver, *WebhookHandler) {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	rc := w.receivers[name]
+	if rc == nil {
+		return nil, nil
+	}
+	return rc, w.handlers[rc.handler]
+}
+
+// verifySignature checks the signature ("sha256=<hex HMAC-SHA256 of the body>") of a payload.
+func (rc *webhookReceiver) verifySignature(body []byte, signature string) bool {
+	if !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+	expected, err := hex.DecodeString(signature[len("sha256="):])
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(rc.secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// initWebhookReceivers loads receivers once modules have registered their handlers, and serves them if any.
+func initWebhookReceivers(registry *Registry) error {
+	if err := registry.Webhooks.load(registry); err != nil {
+		return err
+	}
+	if registry.Webhooks.Count() == 0 {
+		return nil
+	}
+	path := "/" + strings.Trim(registry.AppConfig.GetString("goadmin.webhook.receive_path", "/hooks"), "/")
+	registry.EchoServer.POST(path+"/:name", registry.actionWebhookReceive).Name = ActionNameWebhookReceive
+	registry.SharePath(path)
+	return nil
+}
+
+func webhookError(c echo.Context, status int, msg string) error {
+	return c.JSON(status, map[string]interface{}{"error": msg})
+}
+
+// actionWebhookReceive verifies an inbound webhook and dispatches its payload (a JSON object) to the receiver's
+// handler. Processed deliveries are responded with status 204.
+func (r *Registry) actionWebhookReceive(c echo.Context) error {
+	name := c.Param("name")
+	rc, handler := r.Webhooks.receiver(name)
+	if rc == nil || handler == nil {
+		return webhookError(c, http.StatusNotFound, "unknown webhook receiver")
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(c.Request().Body, maxWebhookPayloadSize+1))
+	if err != nil {
+		return webhookError(c, http.StatusBadRequest, err.Error())
+	}
+	if len(body) > maxWebhookPayloadSize {
+		return webhookError(c, http.StatusRequestEntityTooLarge, "payload too large")
+	}
+	if !rc.verifySignature(body, c.Request().Header.Get(rc.signatureHeader)) {
+		log.Printf("[WARN] webhook to receiver [%s] rejected: invalid signature", name)
+		return webhookError(c, http.StatusUnauthorized, "invalid signature")
+	}
+	dedupKey := ""
+	if id := c.Request().Header.Get("X-Webhook-Id"); id != "" {
+		dedupKey = "goadmin:webhook_received:" + name + ":" + id
+		if v, err := r.Cache.Get(dedupKey); err == nil && v != nil {
+			return c.NoContent(http.StatusNoContent)
+		}
+	}
+	payload := make(map[string]interface{})
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return webhookError(c, http.StatusBadRequest, "payload must be a JSON object")
+	}
+	if err := ValidateWebhookPayload(handler.Schema, payload); err != nil {
+		return webhookError(c, http.StatusUnprocessableEntity, err.Error())
+	}
+	if err := handler.Handle(c, payload); err != nil {
+		var he *echo.HTTPError
+		if errors.As(err, &he) {
+			return webhookError(c, he.Code, fmt.Sprint(he.Message))
+		}
+		log.Printf("[ERROR] webhook to receiver [%s] failed: %s", name, err)
+		return webhookError(c, http.StatusInternalServerError, err.Error())
+	}
+	if dedupKey != "" {
+		r.Cache.Set(dedupKey, []byte("1"), webhookDedupTtl)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
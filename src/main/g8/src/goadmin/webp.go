@@ -0,0 +1,286 @@
+package goadmin
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"sort"
+)
+
+// WebP images are encoded losslessly (VP8L bitstream, see RFC 9649): pixels go through the "subtract green" transform
+// and are entropy-coded with one set of prefix (Huffman) codes for the whole image. Backward references and the color
+// cache are not used, which keeps the encoder small at the cost of larger files than libwebp produces.
+
+const (
+	webpMaxDimension     = 1 << 14 // width and height are stored in 14 bits
+	webpMaxCodeLength    = 15      // maximum length of prefix codes of pixel components
+	webpMaxCLCodeLength  = 7       // maximum length of the prefix code of code lengths
+	webpNumLengthCodes   = 24      // length prefix codes, appended to the green alphabet
+	webpNumDistanceCodes = 40
+	webpTransformSubGrn  = 2
+)
+
+// webpCodeLengthOrder is the order code lengths of the code length code are stored in.
+var webpCodeLengthOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// encodeWebp writes img as a lossless WebP image.
+func encodeWebp(w io.Writer, img *image.NRGBA) error {
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	if width < 1 || height < 1 || width > webpMaxDimension || height > webpMaxDimension {
+		return fmt.Errorf("image of %dx%d pixels can not be encoded as WebP", width, height)
+	}
+
+	// ARGB components after the subtract green transform, and their histograms
+	pixels := make([][4]uint8, 0, width*height)
+	histGreen := make([]uint32, 256+webpNumLengthCodes)
+	histRed, histBlue, histAlpha := make([]uint32, 256), make([]uint32, 256), make([]uint32, 256)
+	alphaUsed := false
+	for y := 0; y < height; y++ {
+		i := img.PixOffset(img.Bounds().Min.X, img.Bounds().Min.Y+y)
+		for x := 0; x < width; x, i = x+1, i+4 {
+			r, g, b, a := img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3]
+			p := [4]uint8{g, r - g, b - g, a}
+			pixels = append(pixels, p)
+			histGreen[p[0]]++
+			histRed[p[1]]++
+			histBlue[p[2]]++
+			histAlpha[p[3]]++
+			alphaUsed = alphaUsed || a != 0xff
+		}
+	}
+
+	bw := &webpBitWriter{}
+	bw.write(0x2f, 8) // signature
+	bw.write(uint32(width-1), 14)
+	bw.write(uint32(height-1), 14)
+	if alphaUsed {
+		bw.write(1, 1)
+	} else {
+		bw.write(0, 1)
+	}
+	bw.write(0, 3) // version
+	bw.write(1, 1) // a transform follows...
+	bw.write(webpTransformSubGrn, 2)
+	bw.write(0, 1) // ...and no more
+	bw.write(0, 1) // no color cache
+	bw.write(0, 1) // no meta prefix codes: one set of codes for the whole image
+
+	var codes [4]*webpPrefixCode
+	for i, hist := range [][]uint32{histGreen, histRed, histBlue, histAlpha} {
+		codes[i] = bw.writePrefixCode(hist)
+	}
+	bw.writePrefixCode(make([]uint32, webpNumDistanceCodes)) // distance codes are not used
+	for _, p := range pixels {
+		for i, v := range p {
+			codes[i].write(bw, int(v))
+		}
+	}
+	data := bw.bytes()
+
+	header := make([]byte, 20)
+	padding := len(data) % 2
+	copy(header, "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(4+8+len(data)+padding))
+	copy(header[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(header[16:], uint32(len(data)))
+	if padding != 0 {
+		data = append(data, 0)
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+/*----------------------------------------------------------------------*/
+
+// webpBitWriter packs values into bytes, least significant bit first.
+type webpBitWriter struct {
+	buf   []byte
+	acc   uint64
+	nbits uint
+}
+
+func (bw *webpBitWriter) write(v uint32, nbits uint) {
+	bw.acc |= uint64(v) << bw.nbits
+	for bw.nbits += nbits; bw.nbits >= 8; bw.nbits -= 8 {
+		bw.buf = append(bw.buf, byte(bw.acc))
+		bw.acc >>= 8
+	}
+}
+
+func (bw *webpBitWriter) bytes() []byte {
+	if bw.nbits > 0 {
+		return append(bw.buf, byte(bw.acc))
+	}
+	return bw.buf
+}
+
+// webpPrefixCode is a canonical prefix code, codes are bit-reversed so that they are written least significant bit
+// first. Symbols with a zero length are written with no bits: they are either unused or the only symbol of the code.
+type webpPrefixCode struct {
+	lengths []uint8
+	codes   []uint32
+}
+
+func (pc *webpPrefixCode) write(bw *webpBitWriter, symbol int) {
+	if n := pc.lengths[symbol]; n > 0 {
+		bw.write(pc.codes[symbol], uint(n))
+	}
+}
+
+// writePrefixCode builds the prefix code of a histogram and writes it, as a "simple" code if at most 2 symbols (below
+// 256) are used, as a "normal" code otherwise.
+func (bw *webpBitWriter) writePrefixCode(hist []uint32) *webpPrefixCode {
+	used := make([]int, 0, 2)
+	for s, n := range hist {
+		if n > 0 {
+			used = append(used, s)
+		}
+	}
+	pc := &webpPrefixCode{lengths: make([]uint8, len(hist))}
+	if len(used) <= 2 && (len(used) == 0 || used[len(used)-1] < 256) {
+		if len(used) == 0 {
+			used = append(used, 0)
+		}
+		bw.write(1, 1) // simple code
+		bw.write(uint32(len(used)-1), 1)
+		if used[0] < 2 {
+			bw.write(0, 1)
+			bw.write(uint32(used[0]), 1)
+		} else {
+			bw.write(1, 1)
+			bw.write(uint32(used[0]), 8)
+		}
+		if len(used) == 2 {
+			bw.write(uint32(used[1]), 8)
+			pc.lengths[used[0]], pc.lengths[used[1]] = 1, 1
+		}
+		pc.codes = webpCanonicalCodes(pc.lengths)
+		return pc
+	}
+
+	// normal code: code lengths are themselves prefix-coded, with the code length code
+	pc.lengths = webpCodeLengths(hist, webpMaxCodeLength)
+	pc.codes = webpCanonicalCodes(pc.lengths)
+	clHist := make([]uint32, len(webpCodeLengthOrder))
+	for _, n := range pc.lengths {
+		clHist[n]++
+	}
+	cl := &webpPrefixCode{lengths: webpCodeLengths(clHist, webpMaxCLCodeLength)}
+	clHeaderLengths := append([]uint8{}, cl.lengths...)
+	if webpNoLengths(cl.lengths) {
+		// all symbols have the same length: the code length code has one symbol, read with no bits, whose length
+		// must still be declared
+		clHeaderLengths[pc.lengths[0]] = 1
+	}
+	numCL := len(webpCodeLengthOrder)
+	for numCL > 4 && clHeaderLengths[webpCodeLengthOrder[numCL-1]] == 0 {
+		numCL--
+	}
+	cl.codes = webpCanonicalCodes(cl.lengths)
+	bw.write(0, 1) // normal code
+	bw.write(uint32(numCL-4), 4)
+	for _, n := range webpCodeLengthOrder[:numCL] {
+		bw.write(uint32(clHeaderLengths[n]), 3)
+	}
+	bw.write(0, 1) // code lengths of all symbols of the alphabet follow
+	for _, n := range pc.lengths {
+		cl.write(bw, int(n))
+	}
+	return pc
+}
+
+// webpNoLengths checks if a code has no symbol with a non-zero length, which webpCodeLengths returns for histograms
+// with a single used symbol.
+func webpNoLengths(lengths []uint8) bool {
+	for _, n := range lengths {
+		if n > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// webpCodeLengths computes lengths of a prefix code for a histogram, limited to maxLength bits. As libwebp does, counts
+// are flattened (raised to a minimum count, doubled at each attempt) until the tree fits the limit. A single used
+// symbol gets a zero length: it is coded with no bits.
+func webpCodeLengths(hist []uint32, maxLength int) []uint8 {
+	type node struct {
+		weight      uint64
+		symbol      int // -1 for internal nodes
+		left, right int
+	}
+	lengths := make([]uint8, len(hist))
+	for countMin := uint64(1); ; countMin *= 2 {
+		nodes := make([]node, 0, 2*len(hist))
+		for s, n := range hist {
+			if n > 0 {
+				w := uint64(n)
+				if w < countMin {
+					w = countMin
+				}
+				nodes = append(nodes, node{weight: w, symbol: s, left: -1, right: -1})
+			}
+		}
+		if len(nodes) < 2 {
+			return lengths
+		}
+		// repeatedly merge the two lightest roots, the stable sort keeps the output deterministic
+		roots := make([]int, len(nodes))
+		for i := range roots {
+			roots[i] = i
+		}
+		for len(roots) > 1 {
+			sort.SliceStable(roots, func(i, j int) bool { return nodes[roots[i]].weight < nodes[roots[j]].weight })
+			a, b := roots[0], roots[1]
+			nodes = append(nodes, node{weight: nodes[a].weight + nodes[b].weight, symbol: -1, left: a, right: b})
+			roots = append(roots[2:], len(nodes)-1)
+		}
+		maxDepth := 0
+		var walk func(i, depth int)
+		walk = func(i, depth int) {
+			if nodes[i].symbol >= 0 {
+				lengths[nodes[i].symbol] = uint8(depth)
+				if depth > maxDepth {
+					maxDepth = depth
+				}
+				return
+			}
+			walk(nodes[i].left, depth+1)
+			walk(nodes[i].right, depth+1)
+		}
+		walk(roots[0], 0)
+		if maxDepth <= maxLength {
+			return lengths
+		}
+	}
+}
+
+// webpCanonicalCodes assigns canonical codes to code lengths (shorter codes first, then by symbol), bit-reversed.
+func webpCanonicalCodes(lengths []uint8) []uint32 {
+	var count [webpMaxCodeLength + 1]uint32
+	for _, n := range lengths {
+		count[n]++
+	}
+	count[0] = 0
+	var next [webpMaxCodeLength + 1]uint32
+	for code, n := uint32(0), 1; n <= webpMaxCodeLength; n++ {
+		code = (code + count[n-1]) << 1
+		next[n] = code
+	}
+	codes := make([]uint32, len(lengths))
+	for s, n := range lengths {
+		if n == 0 {
+			continue
+		}
+		code := next[n]
+		next[n]++
+		for i := uint8(0); i < n; i++ {
+			codes[s] = codes[s]<<1 | (code>>i)&1
+		}
+	}
+	return codes
+}
@@ -0,0 +1,258 @@
+package goadmin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+)
+
+// _webpBitReader reads values packed least significant bit first.
+type _webpBitReader struct {
+	data []byte
+	pos  int // in bits
+}
+
+func (br *_webpBitReader) read(nbits int) (uint32, error) {
+	var v uint32
+	for i := 0; i < nbits; i++ {
+		if br.pos/8 >= len(br.data) {
+			return 0, errors.New("unexpected end of data")
+		}
+		v |= uint32(br.data[br.pos/8]>>(br.pos%8)&1) << i
+		br.pos++
+	}
+	return v, nil
+}
+
+// _webpCode decodes symbols of a canonical prefix code, bit by bit from the most significant bit of codes.
+type _webpCode struct {
+	symbols map[[2]uint32]int // (length, code) => symbol
+	single  int               // the only symbol, read with no bits, -1 if the code has more symbols
+}
+
+func _newWebpCode(lengths []int) (*_webpCode, error) {
+	code := &_webpCode{symbols: make(map[[2]uint32]int), single: -1}
+	var used []int
+	for s, n := range lengths {
+		if n > 0 {
+			used = append(used, s)
+		}
+	}
+	if len(used) == 1 {
+		code.single = used[0]
+		return code, nil
+	}
+	next := uint32(0)
+	kraft := 0.0
+	for n := 1; n <= 15; n++ {
+		for s, length := range lengths {
+			if length == n {
+				code.symbols[[2]uint32{uint32(n), next}] = s
+				next++
+				kraft += 1 / float64(uint32(1)<<n)
+			}
+		}
+		next <<= 1
+	}
+	if kraft != 1 {
+		return nil, fmt.Errorf("incomplete prefix code %v", lengths)
+	}
+	return code, nil
+}
+
+func (code *_webpCode) read(br *_webpBitReader) (int, error) {
+	if code.single >= 0 {
+		return code.single, nil
+	}
+	var v uint32
+	for n := uint32(1); n <= 15; n++ {
+		bit, err := br.read(1)
+		if err != nil {
+			return 0, err
+		}
+		v = v<<1 | bit
+		if s, ok := code.symbols[[2]uint32{n, v}]; ok {
+			return s, nil
+		}
+	}
+	return 0, errors.New("invalid prefix code")
+}
+
+func _readWebpCode(br *_webpBitReader, alphabetSize int) (*_webpCode, error) {
+	lengths := make([]int, alphabetSize)
+	if simple, _ := br.read(1); simple == 1 {
+		numSymbols, _ := br.read(1)
+		first8Bits, _ := br.read(1)
+		s0, _ := br.read(1 + 7*int(first8Bits))
+		lengths[s0] = 1
+		if numSymbols == 1 {
+			s1, _ := br.read(8)
+			lengths[s1] = 1
+		}
+		return _newWebpCode(lengths)
+	}
+	order := []int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+	numCL, _ := br.read(4)
+	clLengths := make([]int, 19)
+	for i := 0; i < int(numCL)+4; i++ {
+		n, _ := br.read(3)
+		clLengths[order[i]] = int(n)
+	}
+	clCode, err := _newWebpCode(clLengths)
+	if err != nil {
+		return nil, err
+	}
+	if useMaxSymbol, _ := br.read(1); useMaxSymbol != 0 {
+		return nil, errors.New("max_symbol is not produced by the encoder")
+	}
+	prev := 8
+	for s := 0; s < alphabetSize; {
+		n, err := clCode.read(br)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case n < 16:
+			lengths[s] = n
+			if n != 0 {
+				prev = n
+			}
+			s++
+		case n == 16:
+			extra, _ := br.read(2)
+			for i := 0; i < 3+int(extra); i, s = i+1, s+1 {
+				lengths[s] = prev
+			}
+		default:
+			extra, _ := br.read(3 + 4*(n-17))
+			s += 3 + 8*(n-17) + int(extra)
+		}
+	}
+	return _newWebpCode(lengths)
+}
+
+// _decodeWebp decodes lossless WebP images using the subset of the format produced by encodeWebp.
+func _decodeWebp(data []byte) (*image.NRGBA, error) {
+	if len(data) < 21 || string(data[:4]) != "RIFF" || string(data[8:16]) != "WEBPVP8L" {
+		return nil, errors.New("not a lossless WebP image")
+	}
+	if size := int(binary.LittleEndian.Uint32(data[4:])); size != len(data)-8 {
+		return nil, fmt.Errorf("RIFF size %d does not match file size %d", size, len(data))
+	}
+	br := &_webpBitReader{data: data[20 : 20+binary.LittleEndian.Uint32(data[16:])]}
+	if sig, _ := br.read(8); sig != 0x2f {
+		return nil, errors.New("invalid signature")
+	}
+	w, _ := br.read(14)
+	h, _ := br.read(14)
+	br.read(1) // alpha hint
+	if version, _ := br.read(3); version != 0 {
+		return nil, errors.New("invalid version")
+	}
+	subtractGreen := false
+	for {
+		if more, _ := br.read(1); more == 0 {
+			break
+		}
+		if transform, _ := br.read(2); transform != 2 {
+			return nil, fmt.Errorf("transform %d is not produced by the encoder", transform)
+		}
+		subtractGreen = true
+	}
+	if colorCache, _ := br.read(1); colorCache != 0 {
+		return nil, errors.New("color cache is not produced by the encoder")
+	}
+	if metaCodes, _ := br.read(1); metaCodes != 0 {
+		return nil, errors.New("meta prefix codes are not produced by the encoder")
+	}
+	codes := make([]*_webpCode, 5)
+	for i, size := range []int{256 + 24, 256, 256, 256, 40} {
+		var err error
+		if codes[i], err = _readWebpCode(br, size); err != nil {
+			return nil, err
+		}
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, int(w)+1, int(h)+1))
+	for i := 0; i < len(img.Pix); i += 4 {
+		var argb [4]int
+		for c := 0; c < 4; c++ {
+			v, err := codes[c].read(br)
+			if err != nil {
+				return nil, err
+			}
+			if c == 0 && v >= 256 {
+				return nil, errors.New("backward references are not produced by the encoder")
+			}
+			argb[c] = v
+		}
+		g, r, b, a := uint8(argb[0]), uint8(argb[1]), uint8(argb[2]), uint8(argb[3])
+		if subtractGreen {
+			r, b = r+g, b+g
+		}
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = r, g, b, a
+	}
+	return img, nil
+}
+
+func TestEncodeWebp(t *testing.T) {
+	testName := "TestEncodeWebp"
+	random := rand.New(rand.NewSource(1))
+	gradient := image.NewNRGBA(image.Rect(0, 0, 37, 23))
+	noise := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			gradient.SetNRGBA(x, y, color.NRGBA{R: uint8(x * 7), G: uint8(y * 11), B: uint8(x + y), A: 0xff})
+			noise.SetNRGBA(x, y, color.NRGBA{R: uint8(random.Intn(256)), G: uint8(random.Intn(256)), B: uint8(random.Intn(3)), A: uint8(random.Intn(256))})
+		}
+	}
+	// every green value once: all green codes have the same length
+	uniform := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	for i := 0; i < 256; i++ {
+		uniform.SetNRGBA(i%16, i/16, color.NRGBA{R: uint8(i), G: uint8(i), B: uint8(i), A: 0xff})
+	}
+	solid := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	solid.SetNRGBA(0, 0, color.NRGBA{R: 200, G: 10, B: 30, A: 128})
+	for name, img := range map[string]*image.NRGBA{"gradient": gradient, "noise": noise, "uniform": uniform, "solid": solid} {
+		buf := &bytes.Buffer{}
+		if err := encodeWebp(buf, img); err != nil {
+			t.Fatalf("%s failed (%s): %s", testName, name, err)
+		}
+		decoded, err := _decodeWebp(buf.Bytes())
+		if err != nil {
+			t.Fatalf("%s failed (%s): %s", testName, name, err)
+		}
+		if !decoded.Bounds().Eq(img.Bounds()) || !bytes.Equal(decoded.Pix, img.Pix) {
+			t.Fatalf("%s failed (%s): decoded image differs from the original", testName, name)
+		}
+	}
+	if err := encodeWebp(&bytes.Buffer{}, image.NewNRGBA(image.Rect(0, 0, webpMaxDimension+1, 1))); err == nil {
+		t.Fatalf("%s failed: error expected for images wider than %d pixels", testName, webpMaxDimension)
+	}
+}
+
+func TestWebpCodeLengths_Limited(t *testing.T) {
+	testName := "TestWebpCodeLengths_Limited"
+	// Fibonacci counts give the deepest Huffman trees
+	hist := make([]uint32, 30)
+	a, b := uint32(1), uint32(1)
+	for i := range hist {
+		hist[i] = a
+		a, b = b, a+b
+	}
+	lengths := webpCodeLengths(hist, webpMaxCodeLength)
+	kraft := 0.0
+	for _, n := range lengths {
+		if n == 0 || n > webpMaxCodeLength {
+			t.Fatalf("%s failed: invalid code lengths %v", testName, lengths)
+		}
+		kraft += 1 / float64(uint32(1)<<n)
+	}
+	if kraft != 1 {
+		t.Fatalf("%s failed: code lengths %v do not form a complete code", testName, lengths)
+	}
+}
@@ -0,0 +1,128 @@
+package goadmin
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	hocon "github.com/go-akka/configuration"
+	"github.com/labstack/echo/v4"
+	"main/src/utils"
+)
+
+const (
+	// ActionNameRobotsTxt is the name of the route serving /robots.txt (setting goadmin.robots_txt).
+	//
+	// Available since template-r5
+	ActionNameRobotsTxt = "goadmin_robots_txt"
+
+	// ActionNameSecurityTxt is the name of the route serving /.well-known/security.txt (setting goadmin.security_txt).
+	//
+	// Available since template-r5
+	ActionNameSecurityTxt = "goadmin_security_txt"
+
+	wellKnownCacheControl = "public, max-age=3600"
+)
+
+// initWellKnown registers the built-in /robots.txt and /.well-known/security.txt handlers, served on both the public
+// site and the control panel. Disable them to serve static files instead (setting static_resources).
+func initWellKnown(registry *Registry) {
+	conf := registry.AppConfig
+	if conf.GetBoolean("goadmin.robots_txt.enabled", true) {
+		registry.EchoServer.GET("/robots.txt", registry.actionRobotsTxt).Name = ActionNameRobotsTxt
+		registry.SharePath("/robots.txt")
+	}
+	if len(configCsv(conf, "goadmin.security_txt.contact")) > 0 {
+		registry.EchoServer.GET("/.well-known/security.txt", registry.actionSecurityTxt).Name = ActionNameSecurityTxt
+		registry.SharePath("/.well-known/security.txt")
+	}
+}
+
+// configCsv returns the values of a comma-separated setting, ignoring empty ones.
+func configCsv(conf *hocon.Config, path string) []string {
+	return nonEmptyStrings(strings.Split(conf.GetString(path, ""), ","))
+}
+
+// configStringList returns the values of a list setting, ignoring empty ones.
+func configStringList(conf *hocon.Config, path string) []string {
+	if v := conf.GetValue(path); v == nil || !v.IsArray() {
+		return nil
+	}
+	return nonEmptyStrings(conf.GetStringList(path))
+}
+
+func nonEmptyStrings(values []string) []string {
+	result := make([]string, 0, len(values))
+	for _, s := range values {
+		if s = strings.TrimSpace(s); s != "" {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// actionRobotsTxt generates robots.txt: control panel route groups (see Registry.CPGroup) are disallowed unless
+// setting goadmin.robots_txt.disallow_cp is false, and everything is disallowed on control panel listeners.
+func (r *Registry) actionRobotsTxt(c echo.Context) error {
+	conf := r.AppConfig
+	var sb strings.Builder
+	sb.WriteString("User-agent: *\n")
+	if site, _ := c.Request().Context().Value(ctxKeyListenerSite{}).(string); site == SiteCP {
+		sb.WriteString("Disallow: /\n")
+	} else {
+		if conf.GetBoolean("goadmin.robots_txt.disallow_cp", true) {
+			for _, prefix := range r.cpPrefixes {
+				sb.WriteString("Disallow: " + r.BasePath + prefix + "\n")
+			}
+		}
+		for _, path := range configStringList(conf, "goadmin.robots_txt.disallow") {
+			sb.WriteString("Disallow: " + path + "\n")
+		}
+		for _, path := range configStringList(conf, "goadmin.robots_txt.allow") {
+			sb.WriteString("Allow: " + path + "\n")
+		}
+	}
+	for _, url := range configCsv(conf, "goadmin.robots_txt.sitemap") {
+		sb.WriteString("\nSitemap: " + url + "\n")
+	}
+	c.Response().Header().Set(echo.HeaderCacheControl, wellKnownCacheControl)
+	return c.String(http.StatusOK, sb.String())
+}
+
+// securityTxtFields maps settings of block goadmin.security_txt to fields of security.txt (RFC 9116), in the order
+// they are written. Fields that may appear several times are comma-separated settings.
+var securityTxtFields = []struct {
+	setting, field string
+	multi          bool
+}{
+	{"contact", "Contact", true},
+	{"encryption", "Encryption", true},
+	{"acknowledgments", "Acknowledgments", true},
+	{"preferred_languages", "Preferred-Languages", false},
+	{"canonical", "Canonical", true},
+	{"policy", "Policy", true},
+	{"hiring", "Hiring", true},
+}
+
+// actionSecurityTxt generates security.txt (RFC 9116). The mandatory "Expires" field is computed from setting
+// goadmin.security_txt.expires_after, so that the file never goes stale as long as the application is deployed.
+func (r *Registry) actionSecurityTxt(c echo.Context) error {
+	conf := r.AppConfig
+	var sb strings.Builder
+	for _, f := range securityTxtFields {
+		path := "goadmin.security_txt." + f.setting
+		if !f.multi {
+			if v := strings.TrimSpace(conf.GetString(path, "")); v != "" {
+				sb.WriteString(f.field + ": " + v + "\n")
+			}
+			continue
+		}
+		for _, v := range configCsv(conf, path) {
+			sb.WriteString(f.field + ": " + v + "\n")
+		}
+	}
+	expiresAfter := conf.GetTimeDuration("goadmin.security_txt.expires_after", 180*24*time.Hour)
+	sb.WriteString("Expires: " + utils.Now().UTC().Add(expiresAfter).Truncate(24*time.Hour).Format(time.RFC3339) + "\n")
+	c.Response().Header().Set(echo.HeaderCacheControl, wellKnownCacheControl)
+	return c.String(http.StatusOK, sb.String())
+}
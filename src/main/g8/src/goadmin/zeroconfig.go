@@ -0,0 +1,145 @@
+package goadmin
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultZeroConfigDataDir  = "./data"
+	zeroConfigSecretsFile     = "zero-config-secrets.json"
+	zeroConfigCredentialsFile = "admin-credentials.txt"
+)
+
+// ZeroConfig is the state of zero-config mode, passed to ZeroConfigProviders. In zero-config mode (env GA_ZERO_CONFIG,
+// or no configuration file found), the application boots without any configuration: data is stored under a data
+// directory (env GA_DATA_DIR, default "./data", e.g. a Docker volume) and secrets (session keys, admin password...)
+// are generated on the first boot then kept in the data directory.
+//
+// Available since template-r5
+type ZeroConfig struct {
+	DataDir string // absolute path of the data directory
+
+	secrets     map[string]string
+	generated   bool     // some secrets have been generated on this boot
+	credentials []string // shown once, on the boot the secrets are generated
+}
+
+// Secret returns the secret of the given name, generating a random one of size bytes (hex-encoded) on the first
+// boot.
+func (z *ZeroConfig) Secret(name string, size int) string {
+	if v, ok := z.secrets[name]; ok {
+		return v
+	}
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	z.secrets[name] = hex.EncodeToString(buf)
+	z.generated = true
+	return z.secrets[name]
+}
+
+// Credential records a line describing generated credentials (e.g. "admin: admin@localhost / <password>"). Lines are
+// printed and written to file "admin-credentials.txt" of the data directory once, on the boot the secrets are generated.
+func (z *ZeroConfig) Credential(line string) {
+	z.credentials = append(z.credentials, line)
+}
+
+// ZeroConfigProvider returns the configuration (HOCON) of a module in zero-config mode, see RegisterZeroConfig.
+//
+// Available since template-r5
+type ZeroConfigProvider func(z *ZeroConfig) string
+
+var (
+	zeroConfigLock      sync.Mutex
+	zeroConfigProviders []ZeroConfigProvider
+)
+
+// RegisterZeroConfig registers the provider of a module's configuration in zero-config mode (e.g. the database to use),
+// typically in an init() function so that commands loading the configuration (see LoadAppConfig) get it too.
+//
+// Available since template-r5
+func RegisterZeroConfig(provider ZeroConfigProvider) {
+	zeroConfigLock.Lock()
+	defer zeroConfigLock.Unlock()
+	zeroConfigProviders = append(zeroConfigProviders, provider)
+}
+
+// zeroConfigMode tells if the application boots in zero-config mode: env GA_ZERO_CONFIG is "true" ("false" disables
+// the mode), or the configuration file does not exist.
+func zeroConfigMode(configFile string) bool {
+	switch strings.ToLower(os.Getenv("GA_ZERO_CONFIG")) {
+	case "true", "1":
+		return true
+	case "false", "0":
+		return false
+	}
+	_, err := os.Stat(configFile)
+	return os.IsNotExist(err)
+}
+
+// buildZeroConfig generates the configuration of zero-config mode. Generated settings take precedence over the
+// configuration file (if any), environment variables over both.
+func buildZeroConfig() (string, error) {
+	dataDir := os.Getenv("GA_DATA_DIR")
+	if dataDir == "" {
+		dataDir = defaultZeroConfigDataDir
+	}
+	dataDir, err := filepath.Abs(dataDir)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dataDir, 0750); err != nil {
+		return "", err
+	}
+	z := &ZeroConfig{DataDir: dataDir, secrets: make(map[string]string)}
+	secretsFile := filepath.Join(dataDir, zeroConfigSecretsFile)
+	if data, err := ioutil.ReadFile(secretsFile); err == nil {
+		if err := json.Unmarshal(data, &z.secrets); err != nil {
+			return "", fmt.Errorf("invalid secrets file [%s]: %s", secretsFile, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	conf := &strings.Builder{}
+	fmt.Fprintf(conf, "goadmin {\n")
+	fmt.Fprintf(conf, "  session_key: %q\n  session_key: ${?GA_SESSION_KEY}\n", z.Secret("session_key", 16))
+	fmt.Fprintf(conf, "  session_encryption_key: %q\n  session_encryption_key: ${?GA_SESSION_ENCRYPTION_KEY}\n", z.Secret("session_encryption_key", 16))
+	fmt.Fprintf(conf, "  url_signing_key: %q\n  url_signing_key: ${?GA_URL_SIGNING_KEY}\n", z.Secret("url_signing_key", 32))
+	fmt.Fprintf(conf, "  file_store_dir: %q\n}\n", filepath.Join(dataDir, "files"))
+	fmt.Fprintf(conf, "http {\n  listen_addr: \"0.0.0.0\"\n  listen_addr: ${?HTTP_LISTEN_ADDR}\n")
+	fmt.Fprintf(conf, "  listen_port: 8080\n  listen_port: ${?HTTP_LISTEN_PORT}\n  listen_port: ${?PORT}\n}\n")
+	zeroConfigLock.Lock()
+	for _, provider := range zeroConfigProviders {
+		conf.WriteString(provider(z))
+		conf.WriteString("\n")
+	}
+	zeroConfigLock.Unlock()
+
+	if z.generated {
+		js, _ := json.MarshalIndent(z.secrets, "", "  ")
+		if err := ioutil.WriteFile(secretsFile, js, 0600); err != nil {
+			return "", err
+		}
+		if len(z.credentials) > 0 {
+			credentialsFile := filepath.Join(dataDir, zeroConfigCredentialsFile)
+			content := strings.Join(z.credentials, "\n") + "\n"
+			if err := ioutil.WriteFile(credentialsFile, []byte(content), 0600); err != nil {
+				return "", err
+			}
+			log.Printf("[WARN] Zero-config mode: credentials generated on this first boot are shown once (also written to [%s]):\n%s", credentialsFile, content)
+		}
+	}
+	log.Printf("Zero-config mode: data is stored under [%s]", dataDir)
+	return conf.String(), nil
+}
@@ -0,0 +1,342 @@
+package myapp
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/btnguyen2k/goyai"
+	"github.com/labstack/echo/v4"
+	"main/src/utils"
+)
+
+const (
+	// settingPrefixUsageStats prefixes ids of settings that store daily page views of the control panel, e.g.
+	// "usage:2006-01-02".
+	settingPrefixUsageStats = "usage:"
+
+	defaultAnalyticsDays = 30
+)
+
+// UsageDay holds page views of the control panel of a day (in the application's timezone).
+//
+// available since template-r5
+type UsageDay struct {
+	Date   string         `json:"date"`
+	Routes map[string]int `json:"routes"`          // page views per route name
+	Users  map[string]int `json:"users,omitempty"` // page views per username, empty if users are not tracked
+	Hours  [24]int        `json:"hours"`           // page views per hour of the day
+}
+
+func newUsageDay(date string) *UsageDay {
+	return &UsageDay{Date: date, Routes: make(map[string]int), Users: make(map[string]int)}
+}
+
+func (d *UsageDay) merge(other *UsageDay) {
+	for k, n := range other.Routes {
+		d.Routes[k] += n
+	}
+	for k, n := range other.Users {
+		d.Users[k] += n
+	}
+	for h, n := range other.Hours {
+		d.Hours[h] += n
+	}
+}
+
+// usageTracker counts page views of the control panel (configuration block myapp.analytics). Only route names,
+// usernames and hours are recorded, no URLs, query strings nor client addresses; counters stay in the application's
+// database. Page views are buffered in memory and written periodically, so that browsing does not write to database.
+//
+// available since template-r5
+type usageTracker struct {
+	r             *myRegistry
+	trackUsers    bool
+	excluded      map[string]bool // names of routes that are not counted
+	flushInterval time.Duration
+
+	routesOnce sync.Once
+	routeNames map[string]string // route name per "path"
+
+	lock    sync.Mutex
+	pending map[string]*UsageDay // buffered page views, per date
+}
+
+// newUsageTracker creates a usageTracker from the configuration block myapp.analytics and starts flushing page views
+// periodically, nil is returned if analytics are disabled.
+func newUsageTracker(r *myRegistry) *usageTracker {
+	conf := r.AppConfig
+	if !conf.GetBoolean(namespace+".analytics.enabled", true) {
+		return nil
+	}
+	t := &usageTracker{
+		r:             r,
+		trackUsers:    conf.GetBoolean(namespace+".analytics.track_users", true),
+		excluded:      make(map[string]bool),
+		flushInterval: conf.GetTimeDuration(namespace+".analytics.flush_interval", time.Minute),
+		pending:       make(map[string]*UsageDay),
+	}
+	for _, name := range strings.Split(conf.GetString(namespace+".analytics.exclude_routes", actionNameCpFragment), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			t.excluded[name] = true
+		}
+	}
+	if t.flushInterval > 0 {
+		go func() {
+			ticker := time.NewTicker(t.flushInterval)
+			defer ticker.Stop()
+			for range ticker.C {
+				t.flush()
+			}
+		}()
+	}
+	return t
+}
+
+// routeName returns the name of the GET route matched by a request, empty if the route has no name.
+func (t *usageTracker) routeName(c echo.Context) string {
+	t.routesOnce.Do(func() {
+		t.routeNames = make(map[string]string)
+		for _, route := range c.Echo().Routes() {
+			if route.Method == http.MethodGet && route.Name != "" {
+				t.routeNames[route.Path] = route.Name
+			}
+		}
+	})
+	return t.routeNames[c.Path()]
+}
+
+// middleware counts HTML pages successfully served to signed-in users.
+func (t *usageTracker) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		err := next(c)
+		if err != nil || c.Request().Method != http.MethodGet || c.Response().Status != http.StatusOK {
+			return err
+		}
+		if !strings.HasPrefix(c.Response().Header().Get(echo.HeaderContentType), echo.MIMETextHTML) {
+			return err
+		}
+		user, _ := c.Get(ctxCurrentUser).(*User)
+		if route := t.routeName(c); user != nil && route != "" && !t.excluded[route] {
+			t.record(route, user.Username, utils.Now())
+		}
+		return err
+	}
+}
+
+// record counts a page view.
+func (t *usageTracker) record(route, username string, at time.Time) {
+	at = at.In(utils.Location)
+	date := at.Format(statsDateLayout)
+	t.lock.Lock()
+	day := t.pending[date]
+	if day == nil {
+		day = newUsageDay(date)
+		t.pending[date] = day
+	}
+	day.Routes[route]++
+	if t.trackUsers {
+		day.Users[username]++
+	}
+	day.Hours[at.Hour()]++
+	t.lock.Unlock()
+	if t.flushInterval <= 0 {
+		t.flush()
+	}
+}
+
+// flush writes buffered page views to database. Failures are logged but not returned: analytics must not break the
+// control panel.
+func (t *usageTracker) flush() {
+	t.lock.Lock()
+	pending := t.pending
+	t.pending = make(map[string]*UsageDay)
+	t.lock.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+	for date, delta := range pending {
+		err := t.r.withSettingLock(settingPrefixUsageStats+date, func() error {
+			day := newUsageDay(date)
+			if _, err := t.r.loadSetting(settingPrefixUsageStats+date, day); err != nil {
+				return err
+			}
+			day.merge(delta)
+			return t.r.saveSetting(settingPrefixUsageStats+date, day)
+		})
+		if err != nil {
+			log.Printf("[ERROR] cannot save usage analytics [%s]: %s", date, err)
+		}
+	}
+}
+
+/*----------------------------------------------------------------------*/
+
+// FeatureUsage is the number of page views of a page of the control panel.
+//
+// available since template-r5
+type FeatureUsage struct {
+	Route   string
+	I18nKey string // title of the page's section, empty if the page is not the root of a section
+	Views   int
+	Percent int // share of all page views
+}
+
+// AdminActivity summarizes page views of a user.
+//
+// available since template-r5
+type AdminActivity struct {
+	Username   string
+	Views      int
+	DaysActive int
+	LastActive string // date of the last page view
+}
+
+// HeatmapRow holds page views of a day of the week, per hour.
+//
+// available since template-r5
+type HeatmapRow struct {
+	Weekday string // i18n key of the day of the week
+	Cells   []*HeatmapCell
+}
+
+// HeatmapCell holds page views of an hour of a day of the week.
+//
+// available since template-r5
+type HeatmapCell struct {
+	Hour    int
+	Views   int
+	Opacity float64 // views relative to the busiest hour, between 0 and 1
+}
+
+// UsageReport aggregates page views of the control panel over a period, see usageReport.
+//
+// available since template-r5
+type UsageReport struct {
+	Days     int
+	Total    int
+	Features []*FeatureUsage  // most viewed first
+	Admins   []*AdminActivity // most active first, empty if users are not tracked
+	Heatmap  []*HeatmapRow    // Monday first
+}
+
+var heatmapWeekdays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday}
+
+// usageReport aggregates page views of the last n days, ending today.
+func (r *myRegistry) usageReport(n int) (*UsageReport, error) {
+	if r.usageTracker != nil {
+		r.usageTracker.flush()
+	}
+	report := &UsageReport{Days: n}
+	routes := make(map[string]int)
+	admins := make(map[string]*AdminActivity)
+	var hours [7][24]int
+	today := utils.Now().In(utils.Location)
+	for i := n - 1; i >= 0; i-- {
+		date := today.AddDate(0, 0, -i)
+		day := newUsageDay(date.Format(statsDateLayout))
+		if found, err := r.loadSetting(settingPrefixUsageStats+day.Date, day); err != nil {
+			return nil, err
+		} else if !found {
+			continue
+		}
+		for route, views := range day.Routes {
+			routes[route] += views
+			report.Total += views
+		}
+		for username, views := range day.Users {
+			a := admins[username]
+			if a == nil {
+				a = &AdminActivity{Username: username}
+				admins[username] = a
+			}
+			a.Views += views
+			a.DaysActive++
+			a.LastActive = day.Date
+		}
+		for h, views := range day.Hours {
+			hours[(int(date.Weekday())+6)%7][h] += views
+		}
+	}
+
+	for route, views := range routes {
+		f := &FeatureUsage{Route: route, Views: views, Percent: views * 100 / report.Total}
+		for _, s := range cpSections {
+			if s.actionName == route {
+				f.I18nKey = s.i18nKey
+			}
+		}
+		report.Features = append(report.Features, f)
+	}
+	sort.Slice(report.Features, func(i, j int) bool {
+		fi, fj := report.Features[i], report.Features[j]
+		return fi.Views > fj.Views || (fi.Views == fj.Views && fi.Route < fj.Route)
+	})
+	for _, a := range admins {
+		report.Admins = append(report.Admins, a)
+	}
+	sort.Slice(report.Admins, func(i, j int) bool {
+		ai, aj := report.Admins[i], report.Admins[j]
+		return ai.Views > aj.Views || (ai.Views == aj.Views && ai.Username < aj.Username)
+	})
+
+	busiest := 0
+	for _, row := range hours {
+		for _, views := range row {
+			if views > busiest {
+				busiest = views
+			}
+		}
+	}
+	for i, weekday := range heatmapWeekdays {
+		row := &HeatmapRow{Weekday: "weekday_" + strings.ToLower(weekday.String()[:3])}
+		for h, views := range hours[i] {
+			cell := &HeatmapCell{Hour: h, Views: views}
+			if busiest > 0 {
+				cell.Opacity = float64(views) / float64(busiest)
+			}
+			row.Cells = append(row.Cells, cell)
+		}
+		report.Heatmap = append(report.Heatmap, row)
+	}
+	return report, nil
+}
+
+// actionCpAnalytics shows the most used pages of the control panel, activity per user and an hour-of-week heatmap of
+// the last "days" days (query parameter, default 30, at most 365).
+//
+// available since template-r5
+func actionCpAnalytics(c echo.Context) error {
+	if err := checkPermission(c, permAnalyticsView); err != nil {
+		AddFlashText(c, FlashWarning, err.Error())
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpDashboard))
+	}
+	days, err := strconv.Atoi(c.QueryParam("days"))
+	if err != nil || days <= 0 {
+		days = defaultAnalyticsDays
+	}
+	if days > maxStatsDays {
+		days = maxStatsDays
+	}
+	myReg := getRegistry(c)
+	data := map[string]interface{}{
+		"active":     "analytics",
+		"days":       days,
+		"periods":    []int{7, defaultAnalyticsDays, 90, maxStatsDays},
+		"enabled":    myReg.usageTracker != nil,
+		"trackUsers": myReg.usageTracker != nil && myReg.usageTracker.trackUsers,
+		"message": myReg.i18n.Localize(getContextString(c, ctxLocale), "analytics_msg", &goyai.LocalizeConfig{
+			TemplateData: map[string]interface{}{"days": days},
+		}),
+	}
+	if report, err := myReg.usageReport(days); err != nil {
+		data["error"] = err.Error()
+	} else {
+		data["report"] = report
+	}
+	return c.Render(http.StatusOK, namespace+":layout:cp_analytics", data)
+}
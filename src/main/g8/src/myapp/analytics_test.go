@@ -0,0 +1,42 @@
+package myapp
+
+import (
+	"net/http"
+	"testing"
+
+	"main/src/apptest"
+)
+
+func TestUsageAnalytics(t *testing.T) {
+	testName := "TestUsageAnalytics"
+	h := apptest.New(t, apptest.SqliteInMemoryConfig+"\nmyapp.analytics.flush_interval = 0\n", NewBootstrapper(nil, nil))
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpUsers)), http.StatusOK)
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpUsers)), http.StatusOK)
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpGroups)), http.StatusOK)
+	// fragments and JSON responses are not page views
+	h.Get(h.Reverse(actionNameCpFragment, "widget_users"))
+	h.Get(h.Reverse(actionNameCpCommands))
+
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpAnalytics)+"?days=7"), http.StatusOK)
+	h.AssertTemplate(namespace + ":layout:cp_analytics")
+	report, _ := h.LastData()["report"].(*UsageReport)
+	if report == nil || report.Total != 3 || len(report.Features) != 2 {
+		t.Fatalf("%s failed: unexpected report %#v", testName, report)
+	}
+	if f := report.Features[0]; f.Route != actionNameCpUsers || f.Views != 2 || f.I18nKey != "users" {
+		t.Fatalf("%s failed: unexpected most used page %#v", testName, f)
+	}
+	if len(report.Admins) != 1 || report.Admins[0].Username != testAdminUsername || report.Admins[0].Views != 3 {
+		t.Fatalf("%s failed: unexpected activity per user %#v", testName, report.Admins)
+	}
+	views := 0
+	for _, row := range report.Heatmap {
+		for _, cell := range row.Cells {
+			views += cell.Views
+		}
+	}
+	if len(report.Heatmap) != 7 || views != 3 {
+		t.Fatalf("%s failed: unexpected heatmap", testName)
+	}
+}
@@ -0,0 +1,329 @@
+package myapp
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"main/src/utils"
+)
+
+const (
+	apiVersion1 = "v1"
+	apiVersion2 = "v2"
+
+	// apiLatestVersion is the version endpoints are implemented in, older versions are served through shims.
+	apiLatestVersion = apiVersion2
+
+	// apiMaxPageSize caps query parameter "limit" of list endpoints.
+	apiMaxPageSize = 1000
+)
+
+// apiEndpoint is an endpoint of the JSON API. Endpoints are implemented once, returning the document of the latest
+// version; older versions adapt it with their shim.
+//
+// Endpoints also describe themselves for the OpenAPI spec (see apiSpec), which generated clients are built from.
+type apiEndpoint struct {
+	name    string // name of the unversioned route, versioned routes are named "<name>_<version>"
+	path    string
+	handler func(c echo.Context) (interface{}, error)
+
+	operation string // operation id in the OpenAPI spec, name of the method of generated clients
+	summary   string
+	schema    string // schema of the returned resource(s), see apiSchemas
+	list      bool   // true if the endpoint returns a list, paged with query parameters "limit" and "cursor"
+}
+
+// apiEndpoints lists endpoints of the JSON API, all served with method GET.
+var apiEndpoints = []*apiEndpoint{
+	{name: actionNameApiMe, path: "/me", handler: apiMe, operation: "getMe", summary: "Returns the user the API token belongs to", schema: "User"},
+	{name: actionNameApiUsers, path: "/users", handler: apiUsers, operation: "listUsers", summary: "Returns users, sorted by username", schema: "User", list: true},
+	{name: actionNameApiGroups, path: "/groups", handler: apiGroups, operation: "listGroups", summary: "Returns groups, sorted by id", schema: "Group", list: true},
+}
+
+// apiVersion is a version of the JSON API, served under /api/<name>. Deprecation and sunset dates are configured per
+// version (configuration block myapp.api.versions.<name>) and announced with headers Deprecation, Sunset and Link.
+type apiVersion struct {
+	name string
+	// shim adapts the document of an endpoint returned in the latest version to this version, nil for the latest
+	// version
+	shim       func(endpoint *apiEndpoint, doc interface{}) interface{}
+	deprecated time.Time // zero if the version is not deprecated
+	sunset     time.Time // date the version stops being served, zero if none
+	link       string    // URL of the migration guide, empty if none
+}
+
+// newApiVersions returns the versions of the JSON API, oldest first.
+func newApiVersions() []*apiVersion {
+	return []*apiVersion{
+		{name: apiVersion1, shim: apiShimV1},
+		{name: apiVersion2},
+	}
+}
+
+// apiShimV1 adapts documents to version 1: resources are returned as is rather than wrapped in field "data", and lists
+// are wrapped in a field named after the endpoint (e.g. {"users": [...]}), like /cp/users?format=json. The cursor of
+// the next page of paged lists is kept.
+func apiShimV1(endpoint *apiEndpoint, doc interface{}) interface{} {
+	m := doc.(map[string]interface{})
+	var list map[string]interface{}
+	switch endpoint.name {
+	case actionNameApiUsers:
+		list = map[string]interface{}{"users": m["data"]}
+	case actionNameApiGroups:
+		list = map[string]interface{}{"groups": m["data"]}
+	default:
+		return m["data"]
+	}
+	if cursor, ok := m["next_cursor"]; ok {
+		list["next_cursor"] = cursor
+	}
+	return list
+}
+
+// parseApiDate parses a date of the configuration: a day (2006-01-02, in the application's timezone) or a RFC 3339
+// timestamp.
+func parseApiDate(value string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, utils.Location); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// loadApiVersions reads deprecation settings of API versions (configuration block myapp.api.versions) and the version
+// served at unversioned paths (setting myapp.api.default_version).
+func (r *myRegistry) loadApiVersions() error {
+	versions := newApiVersions()
+	byName := make(map[string]*apiVersion, len(versions))
+	names := make([]string, 0, len(versions))
+	for _, v := range versions {
+		byName[v.name] = v
+		names = append(names, v.name)
+	}
+	confPath := namespace + ".api.versions"
+	if node := r.AppConfig.GetValue(confPath); node != nil && node.IsObject() {
+		for name := range node.GetObject().Items() {
+			v := byName[name]
+			if v == nil {
+				sort.Strings(names)
+				return fmt.Errorf("unknown API version [%s], valid values are %s", name, strings.Join(names, ", "))
+			}
+			var err error
+			if value := r.AppConfig.GetString(confPath+"."+name+".deprecated", ""); value != "" {
+				if v.deprecated, err = parseApiDate(value); err != nil {
+					return fmt.Errorf("invalid deprecation date of API version [%s]: %s", name, err)
+				}
+			}
+			if value := r.AppConfig.GetString(confPath+"."+name+".sunset", ""); value != "" {
+				if v.sunset, err = parseApiDate(value); err != nil {
+					return fmt.Errorf("invalid sunset date of API version [%s]: %s", name, err)
+				}
+			}
+			v.link = r.AppConfig.GetString(confPath+"."+name+".link", "")
+		}
+	}
+	name := r.AppConfig.GetString(namespace+".api.default_version", apiVersion1)
+	if byName[name] == nil {
+		sort.Strings(names)
+		return fmt.Errorf("unknown default API version [%s], valid values are %s", name, strings.Join(names, ", "))
+	}
+	r.apiVersions, r.apiDefaultVersion = versions, byName[name]
+	return nil
+}
+
+// middleware announces deprecation of the version (headers Deprecation, Sunset and Link) and rejects requests with
+// status 410 once the version is past its sunset date.
+func (v *apiVersion) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		header := c.Response().Header()
+		header.Set("API-Version", v.name)
+		if !v.deprecated.IsZero() {
+			// structured date of the Deprecation header (RFC 9745)
+			header.Set("Deprecation", "@"+strconv.FormatInt(v.deprecated.Unix(), 10))
+			if v.link != "" {
+				header.Add("Link", fmt.Sprintf(`<%s>; rel="deprecation"`, v.link))
+			}
+		}
+		if !v.sunset.IsZero() {
+			if !utils.Now().Before(v.sunset) {
+				return apiError(c, http.StatusGone, "error_api_version_sunset", "version", v.name)
+			}
+			header.Set("Sunset", v.sunset.UTC().Format(http.TimeFormat))
+			if v.link != "" {
+				header.Add("Link", fmt.Sprintf(`<%s>; rel="sunset"`, v.link))
+			}
+		}
+		return next(c)
+	}
+}
+
+// handler serves an endpoint in the version.
+func (v *apiVersion) handler(endpoint *apiEndpoint) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		doc, err := endpoint.handler(c)
+		if reqErr, ok := err.(*apiRequestError); ok {
+			return apiError(c, http.StatusBadRequest, reqErr.i18nKey)
+		}
+		if err != nil {
+			log.Printf("[ERROR] API request %s failed: %s", c.Request().URL.Path, err)
+			return apiError(c, http.StatusInternalServerError, "error_db_101", "err", endpoint.name+"/"+err.Error())
+		}
+		if v.shim != nil {
+			doc = v.shim(endpoint, doc)
+		}
+		return c.JSON(http.StatusOK, doc)
+	}
+}
+
+// registerApiRoutes serves endpoints of all versions under /api/<version>, and endpoints of the default version
+// under /api for clients written before the API was versioned. The OpenAPI spec of the latest version is served at
+// /api/openapi.json.
+func (r *myRegistry) registerApiRoutes(e *echo.Echo) {
+	// the spec describes the API, it needs no token
+	e.GET("/api/openapi.json", actionApiSpec).Name = actionNameApiSpec
+	for _, v := range r.apiVersions {
+		g := e.Group("/api/"+v.name, v.middleware, r.api.middleware)
+		for _, endpoint := range apiEndpoints {
+			g.GET(endpoint.path, v.handler(endpoint)).Name = endpoint.name + "_" + v.name
+		}
+	}
+	g := e.Group("/api", r.apiDefaultVersion.middleware, r.api.middleware)
+	for _, endpoint := range apiEndpoints {
+		g.GET(endpoint.path, r.apiDefaultVersion.handler(endpoint)).Name = endpoint.name
+	}
+}
+
+/*----------------------------------------------------------------------*/
+
+// apiMe returns the user the API token belongs to.
+func apiMe(c echo.Context) (interface{}, error) {
+	user, _ := c.Get(ctxCurrentUser).(*User)
+	return map[string]interface{}{"data": toUserModel(c, user)}, nil
+}
+
+// apiUsers returns users, all of them unless a page is requested (see parseApiPage), sorted by username.
+func apiUsers(c echo.Context) (interface{}, error) {
+	page, err := parseApiPage(c)
+	if err != nil {
+		return nil, err
+	}
+	if page == nil {
+		users, err := getUserDao(c).GetAll()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"data": toUserModelList(c, users), "count": len(users)}, nil
+	}
+	// one more row than requested tells whether there is a next page
+	users, err := getNUsersAfter(getUserDao(c), page.after, page.limit+1)
+	if err != nil {
+		return nil, err
+	}
+	doc := map[string]interface{}{"next_cursor": nil}
+	if len(users) > page.limit {
+		users = users[:page.limit]
+		doc["next_cursor"] = encodeApiCursor(users[len(users)-1].Username)
+	}
+	doc["data"], doc["count"] = toUserModelList(c, users), len(users)
+	return doc, nil
+}
+
+// apiGroups returns groups, all of them unless a page is requested (see parseApiPage), sorted by id.
+func apiGroups(c echo.Context) (interface{}, error) {
+	page, err := parseApiPage(c)
+	if err != nil {
+		return nil, err
+	}
+	if page == nil {
+		groups, err := getGroupDao(c).GetAll()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"data": toGroupModelList(c, groups), "count": len(groups)}, nil
+	}
+	groups, err := getNGroupsAfter(getGroupDao(c), page.after, page.limit+1)
+	if err != nil {
+		return nil, err
+	}
+	doc := map[string]interface{}{"next_cursor": nil}
+	if len(groups) > page.limit {
+		groups = groups[:page.limit]
+		doc["next_cursor"] = encodeApiCursor(groups[len(groups)-1].Id)
+	}
+	doc["data"], doc["count"] = toGroupModelList(c, groups), len(groups)
+	return doc, nil
+}
+
+/*----------------------------------------------------------------------*/
+
+// apiRequestError is returned by endpoints to reject invalid requests with status 400.
+type apiRequestError struct {
+	i18nKey string
+}
+
+func (e *apiRequestError) Error() string {
+	return e.i18nKey
+}
+
+// apiPage is a page of a list endpoint: rows whose key sorts after "after" (keyset pagination), so that iterating
+// over large tables stays fast and is not shifted by rows created or deleted meanwhile, as it would be with offsets.
+type apiPage struct {
+	after string
+	limit int
+}
+
+// apiCursor is the content of the opaque cursors returned in field "next_cursor".
+type apiCursor struct {
+	After string `json:"a"`
+}
+
+func encodeApiCursor(after string) string {
+	js, _ := json.Marshal(&apiCursor{After: after})
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+func decodeApiCursor(cursor string) (string, error) {
+	js, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", err
+	}
+	c := &apiCursor{}
+	if err := json.Unmarshal(js, c); err != nil {
+		return "", err
+	}
+	return c.After, nil
+}
+
+// parseApiPage reads query parameters "limit" (page size, at most apiMaxPageSize) and "cursor" (field "next_cursor"
+// of the previous page) of list endpoints. Nil is returned if neither is supplied: the whole list is requested.
+func parseApiPage(c echo.Context) (*apiPage, error) {
+	limitStr, cursor := c.QueryParam("limit"), c.QueryParam("cursor")
+	if limitStr == "" && cursor == "" {
+		return nil, nil
+	}
+	page := &apiPage{limit: apiMaxPageSize}
+	if limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			return nil, &apiRequestError{i18nKey: "error_api_invalid_limit"}
+		}
+		if limit < apiMaxPageSize {
+			page.limit = limit
+		}
+	}
+	if cursor != "" {
+		after, err := decodeApiCursor(cursor)
+		if err != nil {
+			return nil, &apiRequestError{i18nKey: "error_api_invalid_cursor"}
+		}
+		page.after = after
+	}
+	return page, nil
+}
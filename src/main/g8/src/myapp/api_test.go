@@ -0,0 +1,118 @@
+package myapp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"main/src/apptest"
+)
+
+func TestApiVersions(t *testing.T) {
+	testName := "TestApiVersions"
+	conf := apptest.SqliteInMemoryConfig + `
+myapp.api.versions.v1 { deprecated = "2020-01-01T00:00:00Z", sunset = "2999-01-01T00:00:00Z", link = "https://example.com/migrate" }
+`
+	h := apptest.New(t, conf, NewBootstrapper(nil, nil))
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	_, token, _ := myReg.api.createToken(testAdminUsername, "ci")
+	callApi := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+		return h.Do(req)
+	}
+
+	// v1 (also served at unversioned paths) returns lists wrapped in a field named after the endpoint
+	for _, path := range []string{h.Reverse(actionNameApiUsers), h.Reverse(actionNameApiUsers + "_" + apiVersion1)} {
+		resp := callApi(path)
+		h.AssertStatus(resp, http.StatusOK)
+		doc := map[string]interface{}{}
+		json.Unmarshal(resp.Body.Bytes(), &doc)
+		if _, ok := doc["users"].([]interface{}); !ok {
+			t.Fatalf("%s failed: unexpected v1 document %s", testName, resp.Body.String())
+		}
+		if resp.Header().Get("Deprecation") != "@1577836800" || resp.Header().Get("Sunset") != "Tue, 01 Jan 2999 00:00:00 GMT" {
+			t.Fatalf("%s failed: unexpected headers %#v", testName, resp.Header())
+		}
+		if !strings.Contains(strings.Join(resp.Header().Values("Link"), ","), `<https://example.com/migrate>; rel="deprecation"`) {
+			t.Fatalf("%s failed: unexpected Link header %#v", testName, resp.Header())
+		}
+	}
+	// v2 wraps documents in field "data", it is not deprecated
+	resp := callApi(h.Reverse(actionNameApiMe + "_" + apiVersion2))
+	h.AssertStatus(resp, http.StatusOK)
+	doc := map[string]map[string]interface{}{}
+	json.Unmarshal(resp.Body.Bytes(), &doc)
+	if doc["data"]["username"] != testAdminUsername || resp.Header().Get("Deprecation") != "" || resp.Header().Get("API-Version") != apiVersion2 {
+		t.Fatalf("%s failed: unexpected v2 response %#v %s", testName, resp.Header(), resp.Body.String())
+	}
+
+	// versions past their sunset date are gone
+	conf = apptest.SqliteInMemoryConfig + `
+myapp.api.default_version = "v2"
+myapp.api.versions.v1.sunset = "2020-01-01T00:00:00Z"
+`
+	h = apptest.New(t, conf, NewBootstrapper(nil, nil))
+	myReg = h.Registry.Get(namespace).(*myRegistry)
+	_, token, _ = myReg.api.createToken(testAdminUsername, "ci")
+	h.AssertStatus(callApi(h.Reverse(actionNameApiMe+"_"+apiVersion1)), http.StatusGone)
+	resp = callApi(h.Reverse(actionNameApiMe))
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertBodyContains(resp, `"data"`)
+}
+
+func TestApiCursorPagination(t *testing.T) {
+	testName := "TestApiCursorPagination"
+	h := _newHarness(t)
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	for i := 0; i < 5; i++ {
+		myReg.userDao.Create(fmt.Sprintf("user%d@local", i), "", fmt.Sprintf("User %d", i), systemGroupId)
+	}
+	_, token, _ := myReg.api.createToken(testAdminUsername, "ci")
+	callApi := func(query string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		req := httptest.NewRequest(http.MethodGet, h.Reverse(actionNameApiUsers+"_"+apiVersion2)+query, nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+		resp := h.Do(req)
+		doc := map[string]interface{}{}
+		json.Unmarshal(resp.Body.Bytes(), &doc)
+		return resp, doc
+	}
+
+	// lists are whole without paging parameters
+	if _, doc := callApi(""); doc["count"] != float64(6) {
+		t.Fatalf("%s failed: expected all users but received %#v", testName, doc)
+	}
+	received, query := make([]string, 0), "?limit=4"
+	for i := 0; i < 3; i++ {
+		resp, doc := callApi(query)
+		h.AssertStatus(resp, http.StatusOK)
+		for _, u := range doc["data"].([]interface{}) {
+			received = append(received, u.(map[string]interface{})["username"].(string))
+		}
+		cursor, _ := doc["next_cursor"].(string)
+		if cursor == "" {
+			break
+		}
+		query = "?limit=4&cursor=" + cursor
+	}
+	if len(received) != 6 || received[0] != testAdminUsername || received[5] != "user4@local" {
+		t.Fatalf("%s failed: unexpected users %v", testName, received)
+	}
+
+	// v1 keeps the cursor of paged lists
+	req := httptest.NewRequest(http.MethodGet, h.Reverse(actionNameApiGroups+"_"+apiVersion1)+"?limit=1", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	resp := h.Do(req)
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertBodyContains(resp, `"next_cursor":null`)
+	if resp, _ := callApi("?cursor=not-a-cursor"); resp.Code != http.StatusBadRequest {
+		t.Fatalf("%s failed: invalid cursors must be rejected, received status %d", testName, resp.Code)
+	}
+	if resp, _ := callApi("?limit=-1"); resp.Code != http.StatusBadRequest {
+		t.Fatalf("%s failed: invalid limits must be rejected, received status %d", testName, resp.Code)
+	}
+}
@@ -0,0 +1,441 @@
+package myapp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ApiClientOptions tells GenerateApiClients which clients to generate and where.
+//
+// available since template-r5
+type ApiClientOptions struct {
+	OutDir    string   // directory generated files are written to
+	Langs     []string // languages of the clients, see apiClientLangs
+	GoPackage string   // package name of the Go client
+	Spec      string   // JSON file of the OpenAPI spec to generate clients from (e.g. downloaded from /api/openapi.json), empty for the spec of this build
+}
+
+// apiClientLangs lists the languages clients are generated in.
+var apiClientLangs = []string{"go", "ts"}
+
+var reGoPackage = regexp.MustCompile(`^[a-z][a-z0-9]*$`)
+
+// GenerateApiClients generates client packages of the JSON API from its OpenAPI spec (command "gen-api-client"), so
+// that integrators do not hand-roll HTTP calls: the spec is written to <OutDir>/openapi.json, the Go client to
+// <OutDir>/go/<GoPackage>/client.go and the TypeScript client to <OutDir>/ts/client.ts. The spec is built from the
+// endpoints of the API, hence clients generated again after endpoints change follow them. Paths of written files are
+// returned.
+//
+// available since template-r5
+func GenerateApiClients(opts *ApiClientOptions) ([]string, error) {
+	if opts.OutDir == "" {
+		return nil, errors.New("output directory must not be empty")
+	}
+	for _, lang := range opts.Langs {
+		if !containsString(apiClientLangs, lang) {
+			return nil, fmt.Errorf("unknown language [%s], valid values are %s", lang, strings.Join(apiClientLangs, ", "))
+		}
+	}
+	if containsString(opts.Langs, "go") && !reGoPackage.MatchString(opts.GoPackage) {
+		return nil, fmt.Errorf("invalid Go package name [%s]", opts.GoPackage)
+	}
+	spec := apiSpec(defaultApiSpecTitle)
+	if opts.Spec != "" {
+		js, err := ioutil.ReadFile(opts.Spec)
+		if err != nil {
+			return nil, err
+		}
+		spec = &openApiDoc{}
+		if err := json.Unmarshal(js, spec); err != nil {
+			return nil, fmt.Errorf("cannot parse OpenAPI spec [%s]: %s", opts.Spec, err)
+		}
+		if spec.Info == nil || spec.Components == nil {
+			return nil, fmt.Errorf("OpenAPI spec [%s] has no info or components", opts.Spec)
+		}
+	}
+	js, _ := json.MarshalIndent(spec, "", "  ")
+	files := map[string][]byte{"openapi.json": append(js, '\n')}
+	for _, lang := range opts.Langs {
+		var err error
+		switch lang {
+		case "go":
+			files[filepath.Join("go", opts.GoPackage, "client.go")], err = generateGoApiClient(spec, opts.GoPackage)
+		case "ts":
+			files[filepath.Join("ts", "client.ts")], err = generateTsApiClient(spec)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	result := make([]string, 0, len(files))
+	for name, content := range files {
+		path := filepath.Join(opts.OutDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return result, err
+		}
+		if err := ioutil.WriteFile(path, content, 0644); err != nil {
+			return result, err
+		}
+		result = append(result, path)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+/*----------------------------------------------------------------------*/
+
+// apiClientOperation is an operation of the spec, as clients call it.
+type apiClientOperation struct {
+	path   string
+	method string
+	*openApiOperation
+	result string // name of the schema of the response
+}
+
+// apiClientOperations returns operations of the spec sorted by path, only operations with method GET returning a
+// JSON object are supported.
+func apiClientOperations(spec *openApiDoc) ([]*apiClientOperation, error) {
+	paths := make([]string, 0, len(spec.Paths))
+	for path := range spec.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	result := make([]*apiClientOperation, 0, len(paths))
+	for _, path := range paths {
+		for method, op := range spec.Paths[path] {
+			if method != "get" {
+				return nil, fmt.Errorf("unsupported method [%s] of path [%s]", method, path)
+			}
+			if op.OperationId == "" {
+				return nil, fmt.Errorf("operation of path [%s] has no id", path)
+			}
+			resp := op.Responses["200"]
+			if resp == nil || resp.Content["application/json"] == nil || resp.Content["application/json"].Schema.Ref == "" {
+				return nil, fmt.Errorf("operation [%s] has no JSON response", op.OperationId)
+			}
+			result = append(result, &apiClientOperation{path: path, method: method, openApiOperation: op,
+				result: resp.Content["application/json"].Schema.refName()})
+		}
+	}
+	return result, nil
+}
+
+// apiClientSchemaNames returns names of the schemas of the spec, sorted; ApiError is left out, clients declare it.
+func apiClientSchemaNames(spec *openApiDoc) []string {
+	names := make([]string, 0, len(spec.Components.Schemas))
+	for name := range spec.Components.Schemas {
+		if name != "ApiError" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// goIdentifier turns a name of the spec into an exported Go identifier, e.g. "group_id" into "GroupId".
+func goIdentifier(name string) string {
+	result := ""
+	for _, part := range strings.Split(name, "_") {
+		if part != "" {
+			result += strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+	return result
+}
+
+// goComment returns a Go comment of a text of the spec, empty if there is no text.
+func goComment(indent, text string) string {
+	if text == "" {
+		return ""
+	}
+	return indent + "// " + strings.ReplaceAll(text, "\n", "\n"+indent+"// ") + "\n"
+}
+
+func goType(schema *openApiSchema) string {
+	if schema.Ref != "" {
+		return "*" + schema.refName()
+	}
+	switch schema.Type {
+	case "string":
+		if schema.Nullable {
+			return "*string"
+		}
+		return "string"
+	case "integer":
+		return "int"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]" + goType(schema.Items)
+	}
+	return "map[string]interface{}"
+}
+
+// generateGoApiClient generates the Go client of the spec: a struct per schema and a method of Client per operation.
+func generateGoApiClient(spec *openApiDoc, pkg string) ([]byte, error) {
+	ops, err := apiClientOperations(spec)
+	if err != nil {
+		return nil, err
+	}
+	usesStrconv := false
+	for _, op := range ops {
+		for _, p := range op.Parameters {
+			usesStrconv = usesStrconv || p.Schema.Type != "string"
+		}
+	}
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "// Code generated by gen-api-client from the OpenAPI spec of %s (version %s). DO NOT EDIT.\n\n", spec.Info.Title, spec.Info.Version)
+	fmt.Fprintf(buf, "// Package %s is a client of %s.\n", pkg, spec.Info.Title)
+	buf.WriteString(goComment("", spec.Info.Description))
+	fmt.Fprintf(buf, "package %s\n\nimport (\n\t\"context\"\n\t\"encoding/json\"\n\t\"fmt\"\n\t\"net/http\"\n\t\"net/url\"\n", pkg)
+	if usesStrconv {
+		buf.WriteString("\t\"strconv\"\n")
+	}
+	buf.WriteString(`	"strings"
+)
+
+// ApiError is an error responded by the API.
+type ApiError struct {
+	Status  int    ` + "`json:\"-\"`" + `
+	Message string ` + "`json:\"error\"`" + `
+}
+
+func (e *ApiError) Error() string {
+	return fmt.Sprintf("%d: %s", e.Status, e.Message)
+}
+
+// Client calls the API with an API token.
+type Client struct {
+	BaseUrl    string       // base URL of the application, e.g. https://admin.example.com
+	Token      string       // API token, created on page /cp/tokens
+	HttpClient *http.Client // http.DefaultClient if nil
+}
+
+// NewClient returns a client of the application at baseUrl.
+func NewClient(baseUrl, token string) *Client {
+	return &Client{BaseUrl: baseUrl, Token: token}
+}
+
+func (c *Client) get(ctx context.Context, path string, query url.Values, result interface{}) error {
+	u := strings.TrimSuffix(c.BaseUrl, "/") + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("Accept", "application/json")
+	httpClient := c.HttpClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &ApiError{Status: resp.StatusCode}
+		if json.NewDecoder(resp.Body).Decode(apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = resp.Status
+		}
+		return apiErr
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+`)
+	for _, name := range apiClientSchemaNames(spec) {
+		schema := spec.Components.Schemas[name]
+		if schema.Description != "" {
+			fmt.Fprintf(buf, "\n// %s: %s\n", name, schema.Description)
+		} else {
+			fmt.Fprintf(buf, "\n// %s is schema %s of the spec.\n", name, name)
+		}
+		fmt.Fprintf(buf, "type %s struct {\n", name)
+		for _, prop := range schema.propertyNames() {
+			tag := prop
+			if !schema.isRequired(prop) {
+				tag += ",omitempty"
+			}
+			buf.WriteString(goComment("\t", schema.Properties[prop].Description))
+			fmt.Fprintf(buf, "\t%s %s `json:\"%s\"`\n", goIdentifier(prop), goType(schema.Properties[prop]), tag)
+		}
+		buf.WriteString("}\n")
+	}
+	for _, op := range ops {
+		method := goIdentifier(op.OperationId)
+		if len(op.Parameters) > 0 {
+			fmt.Fprintf(buf, "\n// %sParams are the query parameters of %s.\ntype %sParams struct {\n", method, method, method)
+			for _, p := range op.Parameters {
+				buf.WriteString(goComment("\t", p.Description))
+				fmt.Fprintf(buf, "\t%s %s\n", goIdentifier(p.Name), goType(p.Schema))
+			}
+			buf.WriteString("}\n")
+		}
+		fmt.Fprintf(buf, "\n// %s calls %s %s.\n", method, strings.ToUpper(op.method), op.path)
+		buf.WriteString(goComment("", op.Summary))
+		query := "nil"
+		if len(op.Parameters) > 0 {
+			query = "query"
+			fmt.Fprintf(buf, "func (c *Client) %s(ctx context.Context, params *%sParams) (*%s, error) {\n\tquery := url.Values{}\n\tif params != nil {\n", method, method, op.result)
+			for _, p := range op.Parameters {
+				field := "params." + goIdentifier(p.Name)
+				switch goType(p.Schema) {
+				case "string":
+					fmt.Fprintf(buf, "\t\tif %s != \"\" {\n\t\t\tquery.Set(%q, %s)\n\t\t}\n", field, p.Name, field)
+				case "int":
+					fmt.Fprintf(buf, "\t\tif %s != 0 {\n\t\t\tquery.Set(%q, strconv.Itoa(%s))\n\t\t}\n", field, p.Name, field)
+				case "bool":
+					fmt.Fprintf(buf, "\t\tif %s {\n\t\t\tquery.Set(%q, strconv.FormatBool(%s))\n\t\t}\n", field, p.Name, field)
+				default:
+					return nil, fmt.Errorf("unsupported type of parameter [%s] of operation [%s]", p.Name, op.OperationId)
+				}
+			}
+			buf.WriteString("\t}\n")
+		} else {
+			fmt.Fprintf(buf, "func (c *Client) %s(ctx context.Context) (*%s, error) {\n", method, op.result)
+		}
+		fmt.Fprintf(buf, "\tresult := &%s{}\n\tif err := c.get(ctx, %q, %s, result); err != nil {\n\t\treturn nil, err\n\t}\n\treturn result, nil\n}\n", op.result, op.path, query)
+	}
+	return format.Source(buf.Bytes())
+}
+
+/*----------------------------------------------------------------------*/
+
+// tsComment returns a TSDoc comment of a text of the spec, empty if there is no text.
+func tsComment(indent, text string) string {
+	if text == "" {
+		return ""
+	}
+	return indent + "/** " + strings.ReplaceAll(text, "*/", "*\\/") + " */\n"
+}
+
+func tsType(schema *openApiSchema) string {
+	if schema.Ref != "" {
+		return schema.refName()
+	}
+	switch schema.Type {
+	case "string":
+		if schema.Nullable {
+			return "string | null"
+		}
+		return "string"
+	case "integer":
+		return "number"
+	case "boolean":
+		return "boolean"
+	case "array":
+		return tsType(schema.Items) + "[]"
+	}
+	return "Record<string, unknown>"
+}
+
+// tsMethodName turns an operation id into a TypeScript method name, e.g. "listUsers".
+func tsMethodName(operationId string) string {
+	name := goIdentifier(operationId)
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+// generateTsApiClient generates the TypeScript client of the spec: an interface per schema and a method of Client per
+// operation. The client relies on fetch, available in browsers and Node.js 18+.
+func generateTsApiClient(spec *openApiDoc) ([]byte, error) {
+	ops, err := apiClientOperations(spec)
+	if err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "// Code generated by gen-api-client from the OpenAPI spec of %s (version %s). DO NOT EDIT.\n", spec.Info.Title, spec.Info.Version)
+	buf.WriteString(`
+/** Error responded by the API. */
+export class ApiError extends Error {
+  constructor(public status: number, message: string) {
+    super(message);
+    this.name = "ApiError";
+  }
+}
+`)
+	for _, name := range apiClientSchemaNames(spec) {
+		schema := spec.Components.Schemas[name]
+		buf.WriteString("\n" + tsComment("", schema.Description))
+		fmt.Fprintf(buf, "export interface %s {\n", name)
+		for _, prop := range schema.propertyNames() {
+			optional := "?"
+			if schema.isRequired(prop) {
+				optional = ""
+			}
+			buf.WriteString(tsComment("  ", schema.Properties[prop].Description))
+			fmt.Fprintf(buf, "  %s%s: %s;\n", prop, optional, tsType(schema.Properties[prop]))
+		}
+		buf.WriteString("}\n")
+	}
+	for _, op := range ops {
+		if len(op.Parameters) > 0 {
+			fmt.Fprintf(buf, "\n/** Query parameters of %s. */\nexport interface %sParams {\n", tsMethodName(op.OperationId), goIdentifier(op.OperationId))
+			for _, p := range op.Parameters {
+				buf.WriteString(tsComment("  ", p.Description))
+				fmt.Fprintf(buf, "  %s?: %s;\n", p.Name, tsType(p.Schema))
+			}
+			buf.WriteString("}\n")
+		}
+	}
+	fmt.Fprintf(buf, `
+/** Client of %s, calls the API with an API token created on page /cp/tokens. */
+export class Client {
+  private readonly baseUrl: string;
+
+  constructor(baseUrl: string, private readonly token: string,
+              private readonly fetchFn: typeof fetch = (input, init) => fetch(input, init)) {
+    this.baseUrl = baseUrl.replace(/\/+$/, "");
+  }
+
+  private async get<T>(path: string, query: Record<string, string | number | boolean | undefined> = {}): Promise<T> {
+    const params = new URLSearchParams();
+    for (const [name, value] of Object.entries(query)) {
+      if (value !== undefined && value !== "") {
+        params.set(name, String(value));
+      }
+    }
+    const qs = params.toString();
+    const resp = await this.fetchFn(this.baseUrl + path + (qs ? "?" + qs : ""), {
+      headers: {Authorization: "Bearer " + this.token, Accept: "application/json"},
+    });
+    if (!resp.ok) {
+      let message = resp.statusText;
+      try {
+        message = (await resp.json()).error || message;
+      } catch (e) {
+        // the response is not JSON
+      }
+      throw new ApiError(resp.status, message);
+    }
+    return (await resp.json()) as T;
+  }
+`, spec.Info.Title)
+	for _, op := range ops {
+		buf.WriteString("\n" + tsComment("  ", fmt.Sprintf("%s %s: %s", strings.ToUpper(op.method), op.path, op.Summary)))
+		if len(op.Parameters) > 0 {
+			names := make([]string, 0, len(op.Parameters))
+			for _, p := range op.Parameters {
+				names = append(names, fmt.Sprintf("%s: params.%s", p.Name, p.Name))
+			}
+			fmt.Fprintf(buf, "  %s(params: %sParams = {}): Promise<%s> {\n    return this.get<%s>(%q, {%s});\n  }\n",
+				tsMethodName(op.OperationId), goIdentifier(op.OperationId), op.result, op.result, op.path, strings.Join(names, ", "))
+		} else {
+			fmt.Fprintf(buf, "  %s(): Promise<%s> {\n    return this.get<%s>(%q);\n  }\n",
+				tsMethodName(op.OperationId), op.result, op.result, op.path)
+		}
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,100 @@
+package myapp
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestApiClients(t *testing.T) {
+	testName := "TestApiClients"
+	h := _newHarness(t)
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	myReg.groupDao.Create("staff", "Staff")
+	myReg.userDao.Update(&User{Username: testAdminUsername, Password: encryptPassword(testAdminUsername, testAdminPassword), Name: "Admin", GroupId: systemGroupId, Tags: "vip", Notes: "owner"})
+
+	// the spec needs no token and describes every endpoint
+	resp := h.Get(h.Reverse(actionNameApiSpec))
+	h.AssertStatus(resp, http.StatusOK)
+	spec := &openApiDoc{}
+	if err := json.Unmarshal(resp.Body.Bytes(), spec); err != nil {
+		t.Fatalf("%s failed: cannot parse spec: %s", testName, err)
+	}
+	for _, endpoint := range apiEndpoints {
+		op := spec.Paths[h.Reverse(endpoint.name+"_"+apiLatestVersion)]["get"]
+		if op == nil || op.OperationId != endpoint.operation {
+			t.Fatalf("%s failed: endpoint [%s] missing from the spec", testName, endpoint.name)
+		}
+	}
+
+	// documents returned by endpoints match the spec
+	_, apiToken, _ := myReg.api.createToken(testAdminUsername, "ci")
+	for _, endpoint := range apiEndpoints {
+		req := httptest.NewRequest(http.MethodGet, h.Reverse(endpoint.name+"_"+apiLatestVersion), nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+apiToken)
+		resp := h.Do(req)
+		h.AssertStatus(resp, http.StatusOK)
+		doc := map[string]interface{}{}
+		json.Unmarshal(resp.Body.Bytes(), &doc)
+		items := []interface{}{doc["data"]}
+		if endpoint.list {
+			items = doc["data"].([]interface{})
+		}
+		schema := spec.Components.Schemas[endpoint.schema]
+		for _, item := range items {
+			for k := range item.(map[string]interface{}) {
+				if schema.Properties[k] == nil {
+					t.Fatalf("%s failed: property [%s] returned by endpoint [%s] is missing from schema [%s]", testName, k, endpoint.name, endpoint.schema)
+				}
+			}
+			for _, k := range schema.Required {
+				if _, ok := item.(map[string]interface{})[k]; !ok {
+					t.Fatalf("%s failed: required property [%s] not returned by endpoint [%s]", testName, k, endpoint.name)
+				}
+			}
+		}
+	}
+
+	// generated clients
+	if _, err := GenerateApiClients(&ApiClientOptions{OutDir: t.TempDir(), Langs: []string{"java"}}); err == nil {
+		t.Fatalf("%s failed: unknown languages must be rejected", testName)
+	}
+	dir := t.TempDir()
+	files, err := GenerateApiClients(&ApiClientOptions{OutDir: dir, Langs: []string{"go", "ts"}, GoPackage: "adminapi"})
+	if err != nil || len(files) != 3 {
+		t.Fatalf("%s failed: %#v / %s", testName, files, err)
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filepath.Join(dir, "go", "adminapi", "client.go"), nil, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("%s failed: generated Go client does not parse: %s", testName, err)
+	}
+	conf := &types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	pkg, err := conf.Check("adminapi", fset, []*ast.File{file}, nil)
+	if err != nil {
+		t.Fatalf("%s failed: generated Go client does not compile: %s", testName, err)
+	}
+	for _, name := range []string{"Client", "User", "Group", "UserListResponse", "ListUsersParams", "ApiError"} {
+		if pkg.Scope().Lookup(name) == nil {
+			t.Fatalf("%s failed: type [%s] missing from generated Go client", testName, name)
+		}
+	}
+	ts, _ := ioutil.ReadFile(filepath.Join(dir, "ts", "client.ts"))
+	for _, expected := range []string{"export interface User {", "group_id?: string;", "next_cursor?: string | null;",
+		"listUsers(params: ListUsersParams = {}): Promise<UserListResponse>", "getMe(): Promise<UserResponse>"} {
+		if !strings.Contains(string(ts), expected) {
+			t.Fatalf("%s failed: expected [%s] in generated TypeScript client\n%s", testName, expected, ts)
+		}
+	}
+}
@@ -0,0 +1,546 @@
+package myapp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/btnguyen2k/goyai"
+	"github.com/labstack/echo/v4"
+	"main/src/goadmin"
+	"main/src/utils"
+)
+
+const (
+	// settingPrefixApiToken prefixes ids of settings that store API tokens, e.g. "api_token:<token-id>".
+	settingPrefixApiToken = "api_token:"
+	// settingPrefixApiUsage prefixes ids of settings that store monthly usage of API tokens, e.g.
+	// "api_usage:<token-id>:2006-01".
+	settingPrefixApiUsage = "api_usage:"
+
+	apiUsageMonthLayout = "2006-01"
+
+	ctxApiToken = "api_token"
+)
+
+// ApiToken is a personal token authenticating requests to the JSON API (see apiEndpoints) on behalf of its owner. Only
+// the hash of the token's secret is stored: the token is shown once, when created.
+//
+// available since template-r5
+type ApiToken struct {
+	Id           string    `json:"id"`
+	Owner        string    `json:"owner"`
+	Name         string    `json:"name"`
+	Hash         string    `json:"hash"`          // SHA-256 of the token's secret
+	RateLimit    int       `json:"rate_limit"`    // max number of requests per rate window, 0 for no limit
+	MonthlyQuota int       `json:"monthly_quota"` // max number of requests per calendar month, 0 for no limit
+	Created      time.Time `json:"created"`
+}
+
+// ApiUsage counts requests made with an API token during a calendar month (in the application's timezone).
+//
+// available since template-r5
+type ApiUsage struct {
+	Month    string    `json:"month"`
+	Requests int       `json:"requests"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+func hashApiSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// apiUsageMonth returns the calendar month of t and the start of the next month, in the application's timezone.
+func apiUsageMonth(t time.Time) (string, time.Time) {
+	t = t.In(utils.Location)
+	return t.Format(apiUsageMonthLayout), time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, utils.Location)
+}
+
+// apiPolicy is the state of a limit applied to a request, reported with RateLimit-* headers.
+type apiPolicy struct {
+	limit     int
+	remaining int
+	reset     time.Time
+	window    time.Duration
+}
+
+// apiWindow counts requests of a token during the current rate window.
+type apiWindow struct {
+	start time.Time
+	count int
+}
+
+// apiAccess authenticates requests to the JSON API with API tokens and enforces their rate limits and monthly quotas
+// (configuration block myapp.api).
+//
+// Requests are counted per fixed rate window in memory, hence rate limits apply per instance. Monthly usage is stored
+// in database and updated while holding a lock of the token (see Registry.WithLock), so that quotas hold across
+// restarts, and across instances if locks are shared by them (Redis cache or SQL database).
+//
+// available since template-r5
+type apiAccess struct {
+	r            *myRegistry
+	window       time.Duration
+	rateLimit    int // rate limit of new tokens
+	monthlyQuota int // monthly quota of new tokens
+
+	lock    sync.Mutex
+	windows map[string]*apiWindow // by token id
+}
+
+// newApiAccess creates an apiAccess from the configuration block myapp.api, nil is returned if the API is disabled.
+func newApiAccess(r *myRegistry) *apiAccess {
+	conf := r.AppConfig
+	if !conf.GetBoolean(namespace+".api.enabled", true) {
+		return nil
+	}
+	a := &apiAccess{
+		r:            r,
+		window:       conf.GetTimeDuration(namespace+".api.rate_window", time.Minute),
+		rateLimit:    int(conf.GetInt32(namespace+".api.rate_limit", 60)),
+		monthlyQuota: int(conf.GetInt32(namespace+".api.monthly_quota", 0)),
+		windows:      make(map[string]*apiWindow),
+	}
+	if a.window <= 0 {
+		a.window = time.Minute
+	}
+	return a
+}
+
+// createToken creates a token for the user and returns it along with the string clients authenticate with.
+func (a *apiAccess) createToken(owner, name string) (*ApiToken, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, "", err
+	}
+	secret := hex.EncodeToString(buf)
+	token := &ApiToken{
+		Id:           utils.UniqueId(),
+		Owner:        owner,
+		Name:         name,
+		Hash:         hashApiSecret(secret),
+		RateLimit:    a.rateLimit,
+		MonthlyQuota: a.monthlyQuota,
+		Created:      utils.Now(),
+	}
+	if err := a.r.saveSetting(settingPrefixApiToken+token.Id, token); err != nil {
+		return nil, "", err
+	}
+	return token, token.Id + "." + secret, nil
+}
+
+// getToken returns a stored token, nil if not found.
+func (a *apiAccess) getToken(id string) (*ApiToken, error) {
+	token := &ApiToken{}
+	if found, err := a.r.loadSetting(settingPrefixApiToken+id, token); err != nil || !found {
+		return nil, err
+	}
+	return token, nil
+}
+
+// listTokens returns stored tokens of the owner (all tokens if owner is empty), oldest first.
+func (a *apiAccess) listTokens(owner string) ([]*ApiToken, error) {
+	list, err := a.r.settingsWithPrefix(settingPrefixApiToken)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*ApiToken, 0, len(list))
+	for _, s := range list {
+		token := &ApiToken{}
+		if _, err := a.r.loadSetting(s.Id, token); err != nil {
+			return nil, err
+		}
+		if owner == "" || token.Owner == owner {
+			result = append(result, token)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Created.Before(result[j].Created) })
+	return result, nil
+}
+
+// saveToken stores changes of a token (e.g. its limits).
+func (a *apiAccess) saveToken(token *ApiToken) error {
+	return a.r.saveSetting(settingPrefixApiToken+token.Id, token)
+}
+
+// revokeToken deletes a token and its usage.
+func (a *apiAccess) revokeToken(token *ApiToken) error {
+	usages, err := a.r.settingsWithPrefix(settingPrefixApiUsage + token.Id + ":")
+	if err != nil {
+		return err
+	}
+	for _, s := range usages {
+		if _, err := a.r.settingDao.Delete(s); err != nil {
+			return err
+		}
+	}
+	_, err = a.r.settingDao.Delete(&Setting{Id: settingPrefixApiToken + token.Id})
+	a.lock.Lock()
+	delete(a.windows, token.Id)
+	a.lock.Unlock()
+	return err
+}
+
+// usage returns the usage of a token during the month of t.
+func (a *apiAccess) usage(tokenId string, t time.Time) (*ApiUsage, error) {
+	month, _ := apiUsageMonth(t)
+	usage := &ApiUsage{Month: month}
+	_, err := a.r.loadSetting(settingPrefixApiUsage+tokenId+":"+month, usage)
+	return usage, err
+}
+
+// authenticate returns the token of header "Authorization: Bearer <token>" and its owner, nils if the token is
+// missing, unknown or its owner does not exist anymore.
+func (a *apiAccess) authenticate(c echo.Context) (*ApiToken, *User, error) {
+	auth := c.Request().Header.Get(echo.HeaderAuthorization)
+	if len(auth) < 7 || !strings.EqualFold(auth[:7], "bearer ") {
+		return nil, nil, nil
+	}
+	tokens := strings.SplitN(strings.TrimSpace(auth[7:]), ".", 2)
+	if len(tokens) != 2 || tokens[0] == "" {
+		return nil, nil, nil
+	}
+	token, err := a.getToken(tokens[0])
+	if err != nil || token == nil || !hmac.Equal([]byte(token.Hash), []byte(hashApiSecret(tokens[1]))) {
+		return nil, nil, err
+	}
+	user, err := getUserDao(c).Get(token.Owner)
+	if err != nil || user == nil {
+		return nil, nil, err
+	}
+	return token, user, nil
+}
+
+// takeRate counts a request of the token in the current rate window, false is returned if the rate limit is reached.
+func (a *apiAccess) takeRate(token *ApiToken, now time.Time) (*apiPolicy, bool) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	w := a.windows[token.Id]
+	if w == nil || !now.Before(w.start.Add(a.window)) {
+		if w == nil && len(a.windows) > 10000 {
+			// drop windows of tokens that have been idle, so that the map does not grow forever
+			for id, other := range a.windows {
+				if !now.Before(other.start.Add(a.window)) {
+					delete(a.windows, id)
+				}
+			}
+		}
+		w = &apiWindow{start: now}
+		a.windows[token.Id] = w
+	}
+	policy := &apiPolicy{limit: token.RateLimit, reset: w.start.Add(a.window), window: a.window}
+	if w.count >= token.RateLimit {
+		return policy, false
+	}
+	w.count++
+	policy.remaining = token.RateLimit - w.count
+	return policy, true
+}
+
+// takeQuota counts a request of the token in its monthly usage, false is returned if the monthly quota is reached.
+func (a *apiAccess) takeQuota(token *ApiToken, now time.Time) (*apiPolicy, bool, error) {
+	month, nextMonth := apiUsageMonth(now)
+	policy := &apiPolicy{limit: token.MonthlyQuota, reset: nextMonth, window: nextMonth.Sub(nextMonth.AddDate(0, -1, 0))}
+	taken := false
+	err := a.r.WithLock(namespace+":"+settingPrefixApiUsage+token.Id, 10*time.Second, func() error {
+		usage, err := a.usage(token.Id, now)
+		if err != nil || (token.MonthlyQuota > 0 && usage.Requests >= token.MonthlyQuota) {
+			return err
+		}
+		usage.Requests++
+		usage.LastUsed = now
+		if err := a.r.saveSetting(settingPrefixApiUsage+token.Id+":"+month, usage); err != nil {
+			return err
+		}
+		policy.remaining, taken = token.MonthlyQuota-usage.Requests, true
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return policy, taken, nil
+}
+
+// setRateLimitHeaders reports the applied limits with headers RateLimit-Policy (all limits) and RateLimit-Limit,
+// RateLimit-Remaining, RateLimit-Reset (the limit closest to be reached), as drafted by the IETF.
+func setRateLimitHeaders(c echo.Context, now time.Time, policies ...*apiPolicy) {
+	var closest *apiPolicy
+	descs := make([]string, 0, len(policies))
+	for _, p := range policies {
+		descs = append(descs, fmt.Sprintf("%d;w=%d", p.limit, int(p.window.Seconds())))
+		if closest == nil || p.remaining < closest.remaining {
+			closest = p
+		}
+	}
+	if closest == nil {
+		return
+	}
+	reset := int(closest.reset.Sub(now).Seconds() + 0.999)
+	header := c.Response().Header()
+	header.Set("RateLimit-Policy", strings.Join(descs, ", "))
+	header.Set("RateLimit-Limit", strconv.Itoa(closest.limit))
+	header.Set("RateLimit-Remaining", strconv.Itoa(closest.remaining))
+	header.Set("RateLimit-Reset", strconv.Itoa(reset))
+	if closest.remaining <= 0 && closest.limit > 0 {
+		header.Set(echo.HeaderRetryAfter, strconv.Itoa(reset))
+	}
+}
+
+// apiError responds with a JSON error, the message is localized.
+func apiError(c echo.Context, status int, i18nKey string, args ...interface{}) error {
+	msg := getI18n(c).Localize(getContextString(c, ctxLocale), i18nKey, &goyai.LocalizeConfig{TemplateData: templateDataOf(args...)})
+	return c.JSON(status, map[string]interface{}{"error": msg})
+}
+
+// middleware authenticates requests with API tokens and enforces their limits: requests beyond the rate limit or the
+// monthly quota are rejected with status 429.
+func (a *apiAccess) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+		token, user, err := a.authenticate(c)
+		if err != nil {
+			log.Printf("[ERROR] cannot authenticate API request: %s", err)
+			return apiError(c, http.StatusInternalServerError, "error_db_101", "err", "api_token/"+err.Error())
+		}
+		if token == nil {
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer realm="api"`)
+			return apiError(c, http.StatusUnauthorized, "error_api_token_invalid")
+		}
+		now := utils.Now()
+		policies := make([]*apiPolicy, 0, 2)
+		if token.RateLimit > 0 {
+			policy, ok := a.takeRate(token, now)
+			policies = append(policies, policy)
+			if !ok {
+				setRateLimitHeaders(c, now, policies...)
+				return apiError(c, http.StatusTooManyRequests, "error_api_rate_limited")
+			}
+		}
+		policy, ok, err := a.takeQuota(token, now)
+		if err != nil {
+			log.Printf("[ERROR] cannot record usage of API token [%s]: %s", token.Id, err)
+			return apiError(c, http.StatusInternalServerError, "error_db_101", "err", "api_usage/"+err.Error())
+		}
+		if token.MonthlyQuota > 0 {
+			policies = append(policies, policy)
+		}
+		setRateLimitHeaders(c, now, policies...)
+		if !ok {
+			return apiError(c, http.StatusTooManyRequests, "error_api_quota_exceeded")
+		}
+		c.Set(ctxCurrentUser, user)
+		c.Set(ctxApiToken, token)
+		c.Set(goadmin.CtxAccessLogUser, user.Username)
+		return next(c)
+	}
+}
+
+// countApiUsages counts stored monthly usages of API tokens.
+func countApiUsages(r *myRegistry) (int, error) {
+	list, err := r.settingsWithPrefix(settingPrefixApiUsage)
+	return len(list), err
+}
+
+// purgeApiUsages deletes monthly usages of API tokens of months that ended before cutoff.
+func purgeApiUsages(r *myRegistry, cutoff time.Time) (int, error) {
+	list, err := r.settingsWithPrefix(settingPrefixApiUsage)
+	if err != nil {
+		return 0, err
+	}
+	numDeleted := 0
+	for _, s := range list {
+		i := strings.LastIndex(s.Id, ":")
+		month, err := time.ParseInLocation(apiUsageMonthLayout, s.Id[i+1:], utils.Location)
+		if err != nil || !month.AddDate(0, 1, 0).Before(cutoff) {
+			continue
+		}
+		if _, err := r.settingDao.Delete(s); err != nil {
+			return numDeleted, err
+		}
+		numDeleted++
+	}
+	return numDeleted, nil
+}
+
+/*----------------------------------------------------------------------*/
+
+// ApiTokenModel represents an API token and its usage of the current month in views.
+//
+// available since template-r5
+type ApiTokenModel struct {
+	c echo.Context
+	*ApiToken
+	Usage *ApiUsage
+}
+
+// CreatedStr returns the creation time of the token, in the application's timezone.
+func (m *ApiTokenModel) CreatedStr() string {
+	return m.Created.In(utils.Location).Format("2006-01-02 15:04:05")
+}
+
+// LastUsedStr returns the time the token was last used this month, empty if not used.
+func (m *ApiTokenModel) LastUsedStr() string {
+	if m.Usage.LastUsed.IsZero() {
+		return ""
+	}
+	return m.Usage.LastUsed.In(utils.Location).Format("2006-01-02 15:04:05")
+}
+
+// QuotaPercent returns the share of the monthly quota used, -1 if the token has no quota.
+func (m *ApiTokenModel) QuotaPercent() int {
+	if m.MonthlyQuota <= 0 {
+		return -1
+	}
+	if m.Usage.Requests >= m.MonthlyQuota {
+		return 100
+	}
+	return m.Usage.Requests * 100 / m.MonthlyQuota
+}
+
+// UrlRevoke returns the URL to revoke the token.
+func (m *ApiTokenModel) UrlRevoke() string {
+	return m.c.Echo().Reverse(actionNameCpRevokeTokenSubmit) + "?id=" + url.QueryEscape(m.Id)
+}
+
+// UrlLimits returns the URL to change limits of the token.
+func (m *ApiTokenModel) UrlLimits() string {
+	return m.c.Echo().Reverse(actionNameCpTokenLimitsSubmit) + "?id=" + url.QueryEscape(m.Id)
+}
+
+// renderCpTokens renders the list of API tokens: tokens of the current user, or all tokens for users allowed to manage
+// the API.
+func renderCpTokens(c echo.Context, data map[string]interface{}) error {
+	data["active"] = "tokens"
+	api := getRegistry(c).api
+	data["enabled"] = api != nil
+	if api == nil {
+		return c.Render(http.StatusOK, namespace+":layout:cp_tokens", data)
+	}
+	owner := ""
+	if !can(c, permApiManage) {
+		currentUser, _ := c.Get(ctxCurrentUser).(*User)
+		owner = currentUser.Username
+	}
+	tokens, err := api.listTokens(owner)
+	result := make([]*ApiTokenModel, 0, len(tokens))
+	now := utils.Now()
+	for _, t := range tokens {
+		usage, e := api.usage(t.Id, now)
+		if e != nil && err == nil {
+			err = e
+		}
+		result = append(result, &ApiTokenModel{c: c, ApiToken: t, Usage: usage})
+	}
+	if err != nil {
+		data["error"] = err.Error()
+	}
+	data["tokens"] = result
+	data["manage"] = owner == ""
+	data["apiUrl"] = goadmin.GetRegistry(c).Url("/api/" + apiLatestVersion)
+	return c.Render(http.StatusOK, namespace+":layout:cp_tokens", data)
+}
+
+// actionCpTokens lists API tokens with their limits and usage of the current month.
+//
+// available since template-r5
+func actionCpTokens(c echo.Context) error {
+	return renderCpTokens(c, map[string]interface{}{})
+}
+
+// actionCpCreateTokenSubmit creates an API token named after form field "name". The token is shown once, right after
+// it is created.
+//
+// available since template-r5
+func actionCpCreateTokenSubmit(c echo.Context) error {
+	api := getRegistry(c).api
+	if api == nil {
+		return echo.ErrNotFound
+	}
+	currentUser, _ := c.Get(ctxCurrentUser).(*User)
+	name := strings.TrimSpace(c.FormValue("name"))
+	if name == "" {
+		return renderCpTokens(c, map[string]interface{}{
+			"error": getI18n(c).Localize(getContextString(c, ctxLocale), "error_empty_token_name"),
+		})
+	}
+	token, secret, err := api.createToken(currentUser.Username, name)
+	if err != nil {
+		AddFlash(c, FlashError, "error_db_001", "err", err.Error())
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpTokens)+"?r="+utils.RandomString(4))
+	}
+	getRegistry(c).auditf("user [%s] created API token [%s] (%s)", currentUser.Username, token.Id, token.Name)
+	return renderCpTokens(c, map[string]interface{}{"newToken": secret, "newTokenName": token.Name})
+}
+
+// checkCpToken returns the API token of query parameter "id" if the current user owns it or can manage the API.
+func checkCpToken(c echo.Context) (*ApiToken, error) {
+	api := getRegistry(c).api
+	if api == nil {
+		return nil, echo.ErrNotFound
+	}
+	token, err := api.getToken(c.QueryParam("id"))
+	if err != nil {
+		return nil, err
+	}
+	currentUser, _ := c.Get(ctxCurrentUser).(*User)
+	if token == nil || (token.Owner != currentUser.Username && !can(c, permApiManage)) {
+		return nil, echo.ErrNotFound
+	}
+	return token, nil
+}
+
+// actionCpRevokeTokenSubmit revokes the API token of query parameter "id".
+//
+// available since template-r5
+func actionCpRevokeTokenSubmit(c echo.Context) error {
+	token, err := checkCpToken(c)
+	if err != nil {
+		return err
+	}
+	if err := getRegistry(c).api.revokeToken(token); err != nil {
+		AddFlash(c, FlashError, "error_db_001", "err", err.Error())
+	} else {
+		AddFlash(c, FlashInfo, "token_revoked", "name", token.Name)
+	}
+	return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpTokens)+"?r="+utils.RandomString(4))
+}
+
+// actionCpTokenLimitsSubmit changes the rate limit and monthly quota (form fields "rate_limit" and "monthly_quota", 0
+// for no limit) of the API token of query parameter "id".
+//
+// available since template-r5
+func actionCpTokenLimitsSubmit(c echo.Context) error {
+	redirectUrl := c.Echo().Reverse(actionNameCpTokens) + "?r=" + utils.RandomString(4)
+	if err := checkPermission(c, permApiManage); err != nil {
+		AddFlashText(c, FlashWarning, err.Error())
+		return c.Redirect(http.StatusFound, redirectUrl)
+	}
+	token, err := checkCpToken(c)
+	if err != nil {
+		return err
+	}
+	rateLimit, err1 := strconv.Atoi(strings.TrimSpace(c.FormValue("rate_limit")))
+	monthlyQuota, err2 := strconv.Atoi(strings.TrimSpace(c.FormValue("monthly_quota")))
+	if err1 != nil || err2 != nil || rateLimit < 0 || monthlyQuota < 0 {
+		AddFlash(c, FlashWarning, "error_invalid_token_limits")
+		return c.Redirect(http.StatusFound, redirectUrl)
+	}
+	token.RateLimit, token.MonthlyQuota = rateLimit, monthlyQuota
+	if err := getRegistry(c).api.saveToken(token); err != nil {
+		AddFlash(c, FlashError, "error_db_001", "err", err.Error())
+	} else {
+		AddFlash(c, FlashInfo, "token_limits_saved", "name", token.Name)
+	}
+	return c.Redirect(http.StatusFound, redirectUrl)
+}
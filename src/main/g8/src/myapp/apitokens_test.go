@@ -0,0 +1,93 @@
+package myapp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"main/src/apptest"
+)
+
+func TestApiTokens(t *testing.T) {
+	testName := "TestApiTokens"
+	h := apptest.New(t, apptest.SqliteInMemoryConfig+"\nmyapp.api.rate_window = 1h\n", NewBootstrapper(nil, nil))
+	callApi := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, h.Reverse(actionNameApiMe), nil)
+		if token != "" {
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+		}
+		return h.Do(req)
+	}
+	h.AssertStatus(callApi(""), http.StatusUnauthorized)
+
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	h.AssertStatus(h.PostForm(h.Reverse(actionNameCpCreateTokenSubmit), url.Values{"name": {"ci"}}), http.StatusOK)
+	h.AssertTemplate(namespace + ":layout:cp_tokens")
+	token, _ := h.LastData()["newToken"].(string)
+	tokens, _ := h.LastData()["tokens"].([]*ApiTokenModel)
+	if token == "" || len(tokens) != 1 || strings.Contains(tokens[0].Hash, strings.SplitN(token, ".", 2)[1]) {
+		t.Fatalf("%s failed: unexpected token %q %#v", testName, token, tokens)
+	}
+	h.AssertStatus(callApi(token+"x"), http.StatusUnauthorized)
+
+	h.AssertRedirect(h.PostForm(tokens[0].UrlLimits(), url.Values{"rate_limit": {"2"}, "monthly_quota": {"3"}}), h.Reverse(actionNameCpTokens))
+	resp := callApi(token)
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertBodyContains(resp, testAdminUsername)
+	if resp.Header().Get("RateLimit-Limit") != "2" || resp.Header().Get("RateLimit-Remaining") != "1" {
+		t.Fatalf("%s failed: unexpected headers %#v", testName, resp.Header())
+	}
+	h.AssertStatus(callApi(token), http.StatusOK)
+	// beyond the rate limit
+	resp = callApi(token)
+	h.AssertStatus(resp, http.StatusTooManyRequests)
+	if resp.Header().Get(echo.HeaderRetryAfter) == "" {
+		t.Fatalf("%s failed: no Retry-After header %#v", testName, resp.Header())
+	}
+	// beyond the monthly quota
+	h.AssertRedirect(h.PostForm(tokens[0].UrlLimits(), url.Values{"rate_limit": {"0"}, "monthly_quota": {"3"}}), h.Reverse(actionNameCpTokens))
+	h.AssertStatus(callApi(token), http.StatusOK)
+	resp = callApi(token)
+	h.AssertStatus(resp, http.StatusTooManyRequests)
+	if resp.Header().Get("RateLimit-Limit") != "3" || resp.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("%s failed: unexpected headers %#v", testName, resp.Header())
+	}
+
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpTokens)), http.StatusOK)
+	tokens, _ = h.LastData()["tokens"].([]*ApiTokenModel)
+	if len(tokens) != 1 || tokens[0].Usage.Requests != 3 || tokens[0].QuotaPercent() != 100 {
+		t.Fatalf("%s failed: unexpected usage %#v", testName, tokens)
+	}
+
+	// concurrent requests do not exceed the quota: usage is updated while holding the lock of the token
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	quotaToken := *tokens[0].ApiToken
+	quotaToken.MonthlyQuota = 7
+	var wg sync.WaitGroup
+	var taken int32
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, ok, err := myReg.api.takeQuota(&quotaToken, time.Now()); ok && err == nil {
+				atomic.AddInt32(&taken, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if taken != 4 {
+		t.Fatalf("%s failed: expected 4 requests within quota but received %d", testName, taken)
+	}
+
+	h.AssertRedirect(h.PostForm(tokens[0].UrlRevoke(), nil), h.Reverse(actionNameCpTokens))
+	h.AssertStatus(callApi(token), http.StatusUnauthorized)
+	if list, _ := h.Registry.Get(namespace).(*myRegistry).settingsWithPrefix(settingPrefixApiUsage); len(list) != 0 {
+		t.Fatalf("%s failed: usage of revoked token not deleted %#v", testName, list)
+	}
+}
@@ -0,0 +1,61 @@
+package myapp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	awssession "github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	hocon "github.com/go-akka/configuration"
+	"main/src/goadmin"
+)
+
+// newS3AssetUploader creates a goadmin.AssetUploader that uploads static resources to an AWS S3 bucket, under the
+// supplied key prefix.
+//
+// AWS credentials are read from env AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, region from env AWS_REGION.
+func newS3AssetUploader(bucket, prefix string) (goadmin.AssetUploader, error) {
+	sess, err := awssession.NewSession(&aws.Config{Credentials: credentials.NewEnvCredentials()})
+	if err != nil {
+		return nil, err
+	}
+	client := s3.New(sess)
+	return func(key, contentType string, content io.Reader) error {
+		body, ok := content.(io.ReadSeeker)
+		if !ok {
+			return fmt.Errorf("content of [%s] is not seekable", key)
+		}
+		_, err := client.PutObject(&s3.PutObjectInput{
+			Bucket:       aws.String(bucket),
+			Key:          aws.String(path.Join(prefix, key)),
+			Body:         body,
+			ContentType:  aws.String(contentType),
+			CacheControl: aws.String("public, max-age=31536000"),
+		})
+		return err
+	}, nil
+}
+
+// UploadAssets uploads static resources (the "public" directory) to the S3 bucket configured at myapp.assets, so that
+// they can be served from the CDN origin. It is meant to be run on deploy (see command "upload-assets" in main.go).
+//
+// Available since template-r5
+func UploadAssets(appConfig *hocon.Config) error {
+	bucket := appConfig.GetString(namespace+".assets.s3_bucket", "")
+	if bucket == "" {
+		return errors.New("no S3 bucket configured at [" + namespace + ".assets.s3_bucket]")
+	}
+	prefix := appConfig.GetString(namespace+".assets.s3_prefix", "")
+	uploader, err := newS3AssetUploader(bucket, prefix)
+	if err != nil {
+		return err
+	}
+	count, err := goadmin.UploadAssets("public", uploader)
+	log.Printf("Uploaded %d static resource(s) to [s3://%s/%s]", count, bucket, prefix)
+	return err
+}
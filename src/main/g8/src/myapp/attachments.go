@@ -0,0 +1,436 @@
+package myapp
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/btnguyen2k/goyai"
+	"github.com/labstack/echo/v4"
+	"main/src/goadmin"
+	"main/src/utils"
+)
+
+const (
+	// settingPrefixAttachments is the prefix of settings listing attachments of a user (see userAttachments).
+	settingPrefixAttachments = "attachments:"
+
+	// attachmentFormOverhead is added to setting myapp.attachments.max_size to get the body limit of the upload route,
+	// to leave room for the other fields and the multipart framing.
+	attachmentFormOverhead = 64 * 1024
+)
+
+var (
+	errAttachmentTooLarge  = errors.New("file is too large")
+	errAttachmentType      = errors.New("type of file is not allowed")
+	errAttachmentTooMany   = errors.New("user has too many attachments")
+	errAttachmentNoFile    = errors.New("no file uploaded")
+	errAttachmentEmptyFile = errors.New("file is empty")
+)
+
+// UserAttachment is a file admins attached to a user account, e.g. a scan of an ID or a contract.
+//
+// available since template-r5
+type UserAttachment struct {
+	Id          string    `json:"id"`
+	Name        string    `json:"name"` // name of the uploaded file
+	ContentType string    `json:"type"`
+	Size        int64     `json:"size"`
+	UploadedBy  string    `json:"by"`
+	UploadedAt  time.Time `json:"at"`
+}
+
+// UploadedAtStr returns the upload time in the application's timezone.
+func (a *UserAttachment) UploadedAtStr() string {
+	return a.UploadedAt.In(utils.Location).Format("2006-01-02 15:04:05")
+}
+
+// SizeStr returns the size of the file in a human-readable form, e.g. "1.2 MiB".
+func (a *UserAttachment) SizeStr() string {
+	return formatByteSize(a.Size)
+}
+
+// formatByteSize formats a number of bytes with binary units, e.g. "1.2 MiB".
+func formatByteSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// userAttachments manages files attached to user accounts (configuration block myapp.attachments). Files are kept in
+// the application's file store (setting goadmin.file_store_dir), the list of attachments of a user in the setting
+// table; both are removed when the user is deleted.
+//
+// available since template-r5
+type userAttachments struct {
+	r          *myRegistry
+	maxSize    int64
+	maxFiles   int
+	extensions []string // allowed extensions of files, lower-cased and including the dot
+}
+
+// newUserAttachments creates a userAttachments from the configuration block myapp.attachments, nil is returned if
+// attachments are disabled.
+func newUserAttachments(r *myRegistry) *userAttachments {
+	conf := r.AppConfig
+	confPath := namespace + ".attachments"
+	if !conf.GetBoolean(confPath+".enabled", true) {
+		return nil
+	}
+	a := &userAttachments{
+		r:          r,
+		maxSize:    10 * 1024 * 1024,
+		maxFiles:   int(conf.GetInt32(confPath+".max_files", 20)),
+		extensions: make([]string, 0),
+	}
+	if size := conf.GetByteSize(confPath + ".max_size"); size != nil && size.Int64() > 0 {
+		a.maxSize = size.Int64()
+	}
+	for _, ext := range conf.GetStringList(confPath + ".allowed_extensions") {
+		if ext = strings.ToLower(strings.TrimSpace(ext)); ext != "" {
+			a.extensions = append(a.extensions, "."+strings.TrimPrefix(ext, "."))
+		}
+	}
+	// attachments of deleted users are removed, whatever the handler deleting them
+	r.onEvent(func(event *Event) {
+		if username, _ := event.Data["username"].(string); username != "" {
+			if err := a.removeAll(username); err != nil {
+				log.Printf("[WARN] cannot remove attachments of deleted user [%s]: %s", username, err)
+			}
+		}
+	}, eventUserDeleted)
+	return a
+}
+
+// fileName returns the name of an attachment's file in the file store. Usernames are hashed so that they do not
+// appear in file names.
+func (a *userAttachments) fileName(username, id string) string {
+	sum := sha1.Sum([]byte(strings.ToLower(username)))
+	return "attachments/" + hex.EncodeToString(sum[:]) + "/" + id
+}
+
+// allowed checks if files with the name's extension may be attached, all are if no extension is configured.
+func (a *userAttachments) allowed(filename string) bool {
+	if len(a.extensions) == 0 {
+		return true
+	}
+	return containsString(a.extensions, strings.ToLower(path.Ext(filename)))
+}
+
+// list returns attachments of a user, oldest first.
+func (a *userAttachments) list(username string) ([]*UserAttachment, error) {
+	result := make([]*UserAttachment, 0)
+	_, err := a.r.loadSetting(settingPrefixAttachments+username, &result)
+	return result, err
+}
+
+// get returns an attachment of a user, nil if not found.
+func (a *userAttachments) get(username, id string) (*UserAttachment, error) {
+	list, err := a.list(username)
+	if err != nil {
+		return nil, err
+	}
+	for _, att := range list {
+		if att.Id == id {
+			return att, nil
+		}
+	}
+	return nil, nil
+}
+
+// update modifies the list of attachments of a user while holding a lock, so that concurrent uploads are not lost.
+func (a *userAttachments) update(username string, modify func(list []*UserAttachment) ([]*UserAttachment, error)) error {
+	return a.r.WithLock(settingPrefixAttachments+username, 10*time.Second, func() error {
+		list, err := a.list(username)
+		if err != nil {
+			return err
+		}
+		if list, err = modify(list); err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			_, err = a.r.settingDao.Delete(&Setting{Id: settingPrefixAttachments + username})
+			return err
+		}
+		return a.r.saveSetting(settingPrefixAttachments+username, list)
+	})
+}
+
+// add stores an uploaded file as an attachment of a user.
+func (a *userAttachments) add(username, filename string, content io.Reader, uploadedBy string) (*UserAttachment, error) {
+	filename = path.Base(strings.ReplaceAll(filename, `\`, "/"))
+	if !a.allowed(filename) {
+		return nil, errAttachmentType
+	}
+	if list, err := a.list(username); err != nil {
+		return nil, err
+	} else if a.maxFiles > 0 && len(list) >= a.maxFiles {
+		return nil, errAttachmentTooMany
+	}
+	att := &UserAttachment{
+		Id:          utils.UniqueId(),
+		Name:        filename,
+		ContentType: mime.TypeByExtension(strings.ToLower(path.Ext(filename))),
+		UploadedBy:  uploadedBy,
+		UploadedAt:  utils.Now(),
+	}
+	if att.ContentType == "" {
+		att.ContentType = echo.MIMEOctetStream
+	}
+	counter := &countingReader{r: io.LimitReader(content, a.maxSize+1)}
+	name := a.fileName(username, att.Id)
+	if err := a.r.FileStore.Put(name, counter); err != nil {
+		return nil, err
+	}
+	att.Size = counter.n
+	err := errAttachmentTooLarge
+	if att.Size == 0 {
+		err = errAttachmentEmptyFile
+	} else if att.Size <= a.maxSize {
+		err = a.update(username, func(list []*UserAttachment) ([]*UserAttachment, error) {
+			if a.maxFiles > 0 && len(list) >= a.maxFiles {
+				return nil, errAttachmentTooMany
+			}
+			return append(list, att), nil
+		})
+	}
+	if err != nil {
+		if errDelete := a.r.FileStore.Delete(name); errDelete != nil {
+			log.Printf("[WARN] cannot delete file [%s]: %s", name, errDelete)
+		}
+		return nil, err
+	}
+	return att, nil
+}
+
+// remove deletes an attachment of a user, false is returned if not found.
+func (a *userAttachments) remove(username, id string) (bool, error) {
+	found := false
+	err := a.update(username, func(list []*UserAttachment) ([]*UserAttachment, error) {
+		result := make([]*UserAttachment, 0, len(list))
+		for _, att := range list {
+			if att.Id == id {
+				found = true
+			} else {
+				result = append(result, att)
+			}
+		}
+		return result, nil
+	})
+	if err != nil || !found {
+		return false, err
+	}
+	return true, a.r.FileStore.Delete(a.fileName(username, id))
+}
+
+// removeAll deletes all attachments of a user.
+func (a *userAttachments) removeAll(username string) error {
+	var removed []*UserAttachment
+	err := a.update(username, func(list []*UserAttachment) ([]*UserAttachment, error) {
+		removed = list
+		return nil, nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, att := range removed {
+		if err := a.r.FileStore.Delete(a.fileName(username, att.Id)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// countingReader counts bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
+/*----------------------------------------------------------------------*/
+
+// AttachmentModel represents an attachment of a user account to be used in view.
+//
+// available since template-r5
+type AttachmentModel struct {
+	c        echo.Context
+	username string
+	*UserAttachment
+}
+
+// CanDownload checks if the viewer is allowed to download the attachment.
+func (m *AttachmentModel) CanDownload() bool {
+	return can(m.c, permUserAttachment)
+}
+
+func (m *AttachmentModel) UrlDownload() string {
+	return signUrl(m.c, m.c.Echo().Reverse(actionNameCpUserAttachment)+"?u="+url.QueryEscape(m.username)+"&id="+url.QueryEscape(m.Id))
+}
+
+// userAttachmentModels returns attachments of a user to be listed on the form editing the user account, nil if
+// attachments are disabled.
+func userAttachmentModels(c echo.Context, username string) []*AttachmentModel {
+	a := getRegistry(c).attachments
+	if a == nil {
+		return nil
+	}
+	list, err := a.list(username)
+	if err != nil {
+		log.Printf("[WARN] cannot list attachments of user [%s]: %s", username, err)
+	}
+	result := make([]*AttachmentModel, 0, len(list))
+	for _, att := range list {
+		result = append(result, &AttachmentModel{c: c, username: username, UserAttachment: att})
+	}
+	return result
+}
+
+// attachmentRules returns the limits of attachments (see userAttachments), localized to be displayed on the upload
+// form.
+func attachmentRules(c echo.Context) string {
+	a := getRegistry(c).attachments
+	if a == nil {
+		return ""
+	}
+	key, exts := "attachments_rules", make([]string, 0, len(a.extensions))
+	for _, ext := range a.extensions {
+		exts = append(exts, strings.TrimPrefix(ext, "."))
+	}
+	if len(exts) == 0 {
+		key = "attachments_rules_any"
+	}
+	return getI18n(c).Localize(getContextString(c, ctxLocale), key, &goyai.LocalizeConfig{
+		TemplateData: map[string]interface{}{"max": formatByteSize(a.maxSize), "types": strings.Join(exts, ", ")},
+	})
+}
+
+// redirectToEditUser redirects to the form editing the user account.
+func redirectToEditUser(c echo.Context, user *User) error {
+	return c.Redirect(http.StatusFound, toUserModel(c, user).UrlEdit())
+}
+
+// actionCpUserAttachmentSubmit attaches the uploaded file (field "file") to the user of query parameter "u". The file
+// is streamed to the file store, it must not be larger than setting myapp.attachments.max_size.
+//
+// available since template-r5
+func actionCpUserAttachmentSubmit(c echo.Context) error {
+	a := getRegistry(c).attachments
+	user, err := checkCpEditUser(c)
+	if err != nil || a == nil {
+		if err != nil {
+			AddFlashText(c, FlashWarning, err.Error())
+		}
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpUsers)+"?r="+utils.RandomString(4))
+	}
+	currentUser, _ := getCurrentUser(c)
+	var att *UserAttachment
+	_, err = goadmin.StreamUpload(c, func(field, filename string, content io.Reader) error {
+		if field != "file" || att != nil {
+			_, err := io.Copy(ioutil.Discard, content)
+			return err
+		}
+		var err error
+		att, err = a.add(user.Username, filename, content, currentUser.Username)
+		return err
+	})
+	if err == nil && att == nil {
+		err = errAttachmentNoFile
+	}
+	if err != nil {
+		AddFlashText(c, FlashError, getI18n(c).Localize(getContextString(c, ctxLocale), "error_attachment_upload", &goyai.LocalizeConfig{
+			TemplateData: map[string]interface{}{"err": err.Error(), "max": formatByteSize(a.maxSize)},
+		}))
+		return redirectToEditUser(c, user)
+	}
+	getRegistry(c).auditf("user [%s] attached file [%s] (%d bytes) to user [%s]", currentUser.Username, att.Name, att.Size, user.Username)
+	AddFlash(c, FlashInfo, "attachment_upload_successful", "file", att.Name)
+	return redirectToEditUser(c, user)
+}
+
+// actionCpUserAttachment downloads the attachment of query parameter "id" of the user of query parameter "u". Files
+// are always downloaded, never displayed inline, so that uploaded HTML or scripts do not run in the control panel.
+//
+// available since template-r5
+func actionCpUserAttachment(c echo.Context) error {
+	a := getRegistry(c).attachments
+	if a == nil {
+		return echo.ErrNotFound
+	}
+	if err := checkPermission(c, permUserAttachment); err != nil {
+		return echo.NewHTTPError(http.StatusForbidden, err.Error())
+	}
+	username := c.QueryParam("u")
+	att, err := a.get(username, c.QueryParam("id"))
+	if err != nil {
+		return err
+	}
+	if att == nil {
+		return echo.ErrNotFound
+	}
+	f, err := getRegistry(c).FileStore.Open(a.fileName(username, att.Id))
+	if os.IsNotExist(err) {
+		return echo.ErrNotFound
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+	if currentUser, _ := getCurrentUser(c); currentUser != nil {
+		getRegistry(c).auditf("user [%s] downloaded file [%s] attached to user [%s]", currentUser.Username, att.Name, username)
+	}
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": att.Name}))
+	header.Set(echo.HeaderCacheControl, "no-store")
+	header.Set(echo.HeaderXContentTypeOptions, "nosniff")
+	return c.Stream(http.StatusOK, att.ContentType, f)
+}
+
+// actionCpUserAttachmentDeleteSubmit deletes the attachment of form field "id" of the user of query parameter "u".
+//
+// available since template-r5
+func actionCpUserAttachmentDeleteSubmit(c echo.Context) error {
+	a := getRegistry(c).attachments
+	user, err := checkCpEditUser(c)
+	if err != nil || a == nil {
+		if err != nil {
+			AddFlashText(c, FlashWarning, err.Error())
+		}
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpUsers)+"?r="+utils.RandomString(4))
+	}
+	att, err := a.get(user.Username, c.FormValue("id"))
+	if err == nil && att != nil {
+		_, err = a.remove(user.Username, att.Id)
+	}
+	if err != nil {
+		AddFlash(c, FlashError, "error_db_001", "err", "attachment/"+err.Error())
+	} else if att != nil {
+		if currentUser, _ := getCurrentUser(c); currentUser != nil {
+			getRegistry(c).auditf("user [%s] deleted file [%s] attached to user [%s]", currentUser.Username, att.Name, user.Username)
+		}
+		AddFlash(c, FlashInfo, "attachment_delete_successful", "file", att.Name)
+	}
+	return redirectToEditUser(c, user)
+}
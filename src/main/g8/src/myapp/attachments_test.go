@@ -0,0 +1,79 @@
+package myapp
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"main/src/apptest"
+)
+
+func TestUserAttachments(t *testing.T) {
+	testName := "TestUserAttachments"
+	conf := apptest.SqliteInMemoryConfig + "\ngoadmin.file_store_dir = \"" + t.TempDir() + "\"\n" +
+		"myapp.attachments { max_size = 1KiB, allowed_extensions = [\"pdf\"] }\n" +
+		"myapp.permissions { editors = [\"user.edit\"] }\n"
+	h := apptest.New(t, conf, NewBootstrapper(nil, nil))
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	myReg.userDao.Create("bob@local", "", "Bob", systemGroupId)
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	upload := func(filename string, content []byte) *httptest.ResponseRecorder {
+		body := &bytes.Buffer{}
+		w := multipart.NewWriter(body)
+		fw, _ := w.CreateFormFile("file", filename)
+		fw.Write(content)
+		w.Close()
+		req := httptest.NewRequest(http.MethodPost, h.Reverse(actionNameCpUserAttachmentSubmit)+"?u=bob@local", body)
+		req.Header.Set(echo.HeaderContentType, w.FormDataContentType())
+		return h.Do(req)
+	}
+	h.AssertRedirect(upload("contract.pdf", []byte("%PDF-1.4 contract")), h.Reverse(actionNameCpEditUser))
+	h.AssertRedirect(upload("page.html", []byte("<script>alert(1)</script>")), h.Reverse(actionNameCpEditUser))
+	h.AssertRedirect(upload("large.pdf", bytes.Repeat([]byte("x"), 1025)), h.Reverse(actionNameCpEditUser))
+	list, err := myReg.attachments.list("bob@local")
+	if err != nil || len(list) != 1 || list[0].Name != "contract.pdf" || list[0].Size != 17 || list[0].UploadedBy != testAdminUsername {
+		t.Fatalf("%s failed: expected only contract.pdf to be attached but received %#v (%v)", testName, list, err)
+	}
+
+	// attachments are listed on the edit page and downloaded with a signed URL
+	h.AssertStatus(h.Get(h.Registry.UrlSigner.Sign(h.Reverse(actionNameCpEditUser)+"?u=bob@local")), http.StatusOK)
+	models, _ := h.LastData()["attachments"].([]*AttachmentModel)
+	if len(models) != 1 || !models[0].CanDownload() {
+		t.Fatalf("%s failed: expected 1 downloadable attachment but received %#v", testName, models)
+	}
+	downloadUrl := models[0].UrlDownload()
+	resp := h.Get(downloadUrl)
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertBodyContains(resp, "%PDF-1.4 contract")
+	if disposition := resp.Header().Get(echo.HeaderContentDisposition); !strings.HasPrefix(disposition, "attachment") || !strings.Contains(disposition, "contract.pdf") {
+		t.Fatalf("%s failed: file must be downloaded as attachment, received [%s]", testName, disposition)
+	}
+
+	// downloads require permission user.attachment
+	myReg.groupDao.Create("editors", "Editors")
+	myReg.userDao.Create("editor@local", encryptPassword("editor@local", "Ed1t0r"), "Editor", "editors")
+	h.PostForm(h.Reverse(actionNameCpLogout), url.Values{})
+	h.Login(h.Reverse(actionNameCpLoginSubmit), "editor@local", "Ed1t0r")
+	h.AssertStatus(h.Get(downloadUrl), http.StatusForbidden)
+	h.AssertRedirect(upload("id.pdf", []byte("%PDF-1.4 id")), h.Reverse(actionNameCpEditUser))
+	if list, _ = myReg.attachments.list("bob@local"); len(list) != 2 {
+		t.Fatalf("%s failed: editors must be able to attach files, received %#v", testName, list)
+	}
+
+	// attachments are removed along with the user
+	myReg.userDao.Delete(&User{Username: "bob@local"})
+	if list, _ = myReg.attachments.list("bob@local"); len(list) != 0 {
+		t.Fatalf("%s failed: attachments of deleted user must be removed, received %#v", testName, list)
+	}
+	for _, att := range models {
+		if _, err := myReg.FileStore.Open(myReg.attachments.fileName("bob@local", att.Id)); !os.IsNotExist(err) {
+			t.Fatalf("%s failed: file of attachment [%s] must be deleted, received %v", testName, att.Name, err)
+		}
+	}
+}
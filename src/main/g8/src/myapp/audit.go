@@ -0,0 +1,351 @@
+package myapp
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	hocon "github.com/go-akka/configuration"
+	"main/src/goadmin"
+	"main/src/utils"
+)
+
+// Audit entries are written to the log output with tag [AUDIT]. If setting myapp.audit.signing_key is set, entries
+// are hash-chained: each one carries the id of its chain, a sequence number and the HMAC-SHA256 of the previous
+// entry's signature, its sequence number and its message, e.g.
+//
+//	[AUDIT] user [admin] from 10.0.0.1: POST /cp/users/create -> 302 [chain=1a2b3c seq=42 sig=5f0c...]
+//
+// so that deleted, modified, reordered or forged entries are detected by command "verify-audit" (see
+// VerifyAuditLog). Every instance signs its entries in its own chain, identified by the instance id, so that
+// instances do not coordinate on every entry. The head of the chain (last sequence number and signature) is stored
+// periodically as setting "audit_chain:<chain id>" and compared with the last entry found in logs, so that truncated
+// logs are detected as well.
+
+const (
+	settingIdAuditChain = "audit_chain"
+	auditTag            = "[AUDIT] "
+	// auditChainFlushInterval is the interval the head of the audit chain is stored at
+	auditChainFlushInterval = 10 * time.Second
+)
+
+// reAuditSignature matches signed entries; entries signed before chains were per-instance have no chain id.
+var reAuditSignature = regexp.MustCompile(`^(.*) \[(?:chain=(\S+) )?seq=(\d+) sig=([0-9a-f]{64})\]$`)
+
+// auditChainHead is the last signed audit entry of a chain.
+type auditChainHead struct {
+	Seq  int64  `json:"seq"`
+	Sig  string `json:"sig"`
+	Time string `json:"time"`
+}
+
+// auditSignature computes the signature of the entry following prevSig; the first entry of a chain follows the id of
+// the chain, so that entries can not be moved to another chain.
+func auditSignature(key []byte, prevSig string, seq int64, msg string) string {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%s\n%d\n%s", prevSig, seq, msg)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// auditSigningKey returns the key audit entries are signed with, empty if signing is disabled.
+func auditSigningKey(conf *hocon.Config) []byte {
+	return []byte(conf.GetString(namespace+".audit.signing_key", ""))
+}
+
+// auditChain signs audit entries of the running instance. Entries are signed in memory; the head of the chain is
+// stored in the background every auditChainFlushInterval, except in read-only mode (see flush).
+type auditChain struct {
+	r       *myRegistry
+	key     []byte
+	id      string
+	lock    sync.Mutex
+	head    auditChainHead
+	flushed int64 // sequence number of the last stored head
+}
+
+// newAuditChain creates the audit chain of the running instance and starts storing its head periodically, nil is
+// returned if signing is disabled.
+func newAuditChain(r *myRegistry) *auditChain {
+	key := auditSigningKey(r.AppConfig)
+	if len(key) == 0 {
+		return nil
+	}
+	chain := &auditChain{r: r, key: key, id: r.instanceId, head: auditChainHead{Sig: r.instanceId}}
+	go func() {
+		ticker := time.NewTicker(auditChainFlushInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := chain.flush(); err != nil {
+				log.Printf("[ERROR] cannot store head of audit chain [%s]: %s", chain.id, err)
+			}
+		}
+	}()
+	return chain
+}
+
+// sign appends msg to the chain and returns it with its signature.
+func (a *auditChain) sign(msg string) string {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	seq := a.head.Seq + 1
+	a.head = auditChainHead{Seq: seq, Sig: auditSignature(a.key, a.head.Sig, seq, msg), Time: utils.Now().Format(time.RFC3339)}
+	return fmt.Sprintf("%s [chain=%s seq=%d sig=%s]", msg, a.id, a.head.Seq, a.head.Sig)
+}
+
+// flush stores the head of the chain if entries were signed since the last call. Nothing is written in read-only
+// mode (e.g. when pointing at a read replica): the stored head lags behind, which verification tolerates.
+func (a *auditChain) flush() error {
+	a.lock.Lock()
+	head, flushed := a.head, a.flushed
+	a.lock.Unlock()
+	if head.Seq == flushed || a.r.isReadOnly() {
+		return nil
+	}
+	if err := a.r.saveSetting(settingIdAuditChain+":"+a.id, head); err != nil {
+		return err
+	}
+	a.lock.Lock()
+	if head.Seq > a.flushed {
+		a.flushed = head.Seq
+	}
+	a.lock.Unlock()
+	return nil
+}
+
+// auditf writes an audit entry to the log output, chained to the previous one if signing is enabled.
+func (r *myRegistry) auditf(format string, args ...interface{}) {
+	// one entry per line, so that entries can be verified line by line; entries are signed as written to the log
+	msg := strings.NewReplacer("\r", `\r`, "\n", `\n`).Replace(goadmin.SanitizeLog(fmt.Sprintf(format, args...)))
+	if r.auditChain == nil {
+		log.Print(auditTag + msg)
+		return
+	}
+	log.Print(auditTag + r.auditChain.sign(msg))
+}
+
+// auditChainHeads returns the stored heads of audit chains, per chain id.
+func (r *myRegistry) auditChainHeads() (map[string]*auditChainHead, error) {
+	settings, err := getSettingsByPrefix(r.settingDao, settingIdAuditChain+":")
+	if err != nil {
+		return nil, err
+	}
+	heads := make(map[string]*auditChainHead)
+	// head of the chain shared by all instances, before chains were per instance
+	legacy := &auditChainHead{}
+	if ok, err := r.loadSetting(settingIdAuditChain, legacy); err != nil {
+		return nil, err
+	} else if ok {
+		heads[""] = legacy
+	}
+	for _, setting := range settings {
+		head := &auditChainHead{}
+		if err := json.Unmarshal([]byte(setting.Value), head); err != nil {
+			return nil, fmt.Errorf("invalid setting [%s]: %s", setting.Id, err)
+		}
+		heads[strings.TrimPrefix(setting.Id, settingIdAuditChain+":")] = head
+	}
+	return heads, nil
+}
+
+/*----------------------------------------------------------------------*/
+
+// AuditProblem is an inconsistency found in audit logs by VerifyAuditLog.
+//
+// available since template-r5
+type AuditProblem struct {
+	File    string
+	Line    int
+	Message string
+}
+
+// String implements fmt.Stringer.String
+func (p AuditProblem) String() string {
+	return fmt.Sprintf("%s:%d: %s", p.File, p.Line, p.Message)
+}
+
+// AuditChainVerification is the verification of the entries of an audit chain, see AuditVerification.
+//
+// available since template-r5
+type AuditChainVerification struct {
+	Id       string // id of the chain, empty for entries signed before chains were per instance
+	Entries  int    // number of signed entries found
+	FirstSeq int64  // sequence number of the first signed entry, entries before it (e.g. rotated away) are not verified
+	LastSeq  int64  // sequence number of the last signed entry
+	LastSig  string // signature of the last signed entry
+	file     string // location of the last signed entry
+	line     int
+}
+
+// AuditVerification is the result of VerifyAuditLog.
+//
+// available since template-r5
+type AuditVerification struct {
+	Entries  int                       // number of signed entries found
+	Chains   []*AuditChainVerification // chains entries belong to, in order of appearance
+	Problems []AuditProblem
+}
+
+// VerifyAuditLog checks the chains of signed audit entries found in log files (text or JSON lines, see setting
+// goadmin.log_sinks; gzip-compressed if named *.gz), which must be supplied in chronological order. It reports entries
+// that were deleted (gaps in sequence numbers, or entries missing after the last one found while the stored head of
+// the chain is further), modified or forged (invalid signatures), reordered, or written unsigned.
+//
+// Heads of chains are read from the application's database. They are stored periodically, so that deletion of the
+// latest entries of an instance (signed within the last few seconds before it stopped) is not detected; neither are
+// chains without any entry in the supplied files.
+//
+// available since template-r5
+func VerifyAuditLog(appConfig *hocon.Config, files []string) (*AuditVerification, error) {
+	myReg := &myRegistry{Registry: goadmin.NewRegistry(appConfig)}
+	if len(auditSigningKey(appConfig)) == 0 {
+		return nil, errors.New("audit signing is disabled, setting [" + namespace + ".audit.signing_key] is empty")
+	}
+	if err := initDaos(myReg); err != nil {
+		return nil, err
+	}
+	return myReg.verifyAuditLog(files)
+}
+
+// verifyAuditLog checks audit entries of log files against heads of chains stored in database, see VerifyAuditLog.
+func (r *myRegistry) verifyAuditLog(files []string) (*AuditVerification, error) {
+	key := auditSigningKey(r.AppConfig)
+	if len(key) == 0 {
+		return nil, errors.New("audit signing is disabled, setting [" + namespace + ".audit.signing_key] is empty")
+	}
+	result := &AuditVerification{Chains: make([]*AuditChainVerification, 0), Problems: make([]AuditProblem, 0)}
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		var in io.Reader = f
+		if strings.HasSuffix(file, ".gz") {
+			// rotated log files may be compressed
+			if in, err = gzip.NewReader(f); err != nil {
+				f.Close()
+				return nil, fmt.Errorf("cannot read [%s]: %s", file, err)
+			}
+		}
+		err = result.verify(key, file, in)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("cannot read [%s]: %s", file, err)
+		}
+	}
+	heads, err := r.auditChainHeads()
+	if err != nil {
+		return nil, err
+	}
+	for _, chain := range result.Chains {
+		head := heads[chain.Id]
+		problem := func(format string, args ...interface{}) {
+			result.Problems = append(result.Problems, AuditProblem{File: chain.file, Line: chain.line, Message: fmt.Sprintf(format, args...)})
+		}
+		switch {
+		case head == nil:
+		case head.Seq > chain.LastSeq:
+			problem("entries #%d to #%d were deleted after the last entry found%s", chain.LastSeq+1, head.Seq, chain.label())
+		case head.Seq == chain.LastSeq && head.Sig != chain.LastSig:
+			problem("entry #%d does not match the stored head of the chain%s", chain.LastSeq, chain.label())
+		}
+	}
+	return result, nil
+}
+
+// label identifies the chain in problems, empty for entries signed before chains were per instance.
+func (c *AuditChainVerification) label() string {
+	if c.Id == "" {
+		return ""
+	}
+	return " (chain " + c.Id + ")"
+}
+
+func (v *AuditVerification) verify(key []byte, file string, in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	chains := make(map[string]*AuditChainVerification)
+	for _, chain := range v.Chains {
+		chains[chain.Id] = chain
+	}
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		msg := auditMessageOf(scanner.Text())
+		if msg == "" {
+			continue
+		}
+		problem := func(format string, args ...interface{}) {
+			v.Problems = append(v.Problems, AuditProblem{File: file, Line: lineNo, Message: fmt.Sprintf(format, args...)})
+		}
+		m := reAuditSignature.FindStringSubmatch(msg)
+		if m == nil {
+			problem("unsigned entry: %s", msg)
+			continue
+		}
+		seq, _ := strconv.ParseInt(m[3], 10, 64)
+		chain := chains[m[2]]
+		if chain == nil {
+			chain = &AuditChainVerification{Id: m[2]}
+			chains[chain.Id] = chain
+			v.Chains = append(v.Chains, chain)
+		}
+		switch {
+		case chain.Entries == 0 && seq == 1:
+			// the first entry of a chain follows the id of the chain
+			if m[4] != auditSignature(key, chain.Id, seq, m[1]) {
+				problem("entry #%d was modified or forged%s", seq, chain.label())
+			}
+		case chain.Entries == 0:
+			// chain starts in the middle (e.g. older files were rotated away): the entry is trusted as anchor
+		case seq <= chain.LastSeq:
+			problem("entry #%d is duplicated or out of order (after #%d)%s", seq, chain.LastSeq, chain.label())
+			continue
+		case seq > chain.LastSeq+1:
+			// the previous signature is unknown, the chain restarts from this entry
+			problem("entries #%d to #%d were deleted%s", chain.LastSeq+1, seq-1, chain.label())
+		default:
+			if m[4] != auditSignature(key, chain.LastSig, seq, m[1]) {
+				problem("entry #%d was modified or forged%s", seq, chain.label())
+			}
+		}
+		if chain.Entries == 0 {
+			chain.FirstSeq = seq
+		}
+		chain.Entries++
+		chain.LastSeq, chain.LastSig = seq, m[4]
+		chain.file, chain.line = file, lineNo
+		v.Entries++
+	}
+	return scanner.Err()
+}
+
+// auditMessageOf extracts the audit message of a log line (text, or JSON document with field "message"), empty if
+// the line is not an audit entry.
+func auditMessageOf(line string) string {
+	if strings.HasPrefix(line, "{") {
+		doc := struct {
+			Message string `json:"message"`
+		}{}
+		if json.Unmarshal([]byte(line), &doc) == nil {
+			line = doc.Message
+		}
+	}
+	i := strings.Index(line, auditTag)
+	if i < 0 {
+		return ""
+	}
+	return strings.TrimRight(line[i+len(auditTag):], "\r")
+}
@@ -0,0 +1,123 @@
+package myapp
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"main/src/apptest"
+)
+
+func TestAuditChain(t *testing.T) {
+	testName := "TestAuditChain"
+	conf := apptest.SqliteInMemoryConfig + "\nmyapp.audit.signing_key = \"audit-secret\"\n"
+	h := apptest.New(t, conf, NewBootstrapper(nil, nil))
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	buf := &bytes.Buffer{}
+	log.SetOutput(buf)
+	for i := 0; i < 3; i++ {
+		h.PostForm(h.Reverse(actionNameCpCreateGroupSubmit), url.Values{})
+	}
+	log.SetOutput(os.Stderr)
+
+	verify := func(content string) *AuditVerification {
+		file := filepath.Join(t.TempDir(), "app.log")
+		ioutil.WriteFile(file, []byte(content), 0644)
+		result, err := myReg.verifyAuditLog([]string{file})
+		if err != nil {
+			t.Fatalf("%s failed: %s", testName, err)
+		}
+		return result
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	audit := make([]string, 0)
+	for _, line := range lines {
+		if strings.Contains(line, "[AUDIT]") {
+			audit = append(audit, line)
+		}
+	}
+	if result := verify(strings.Join(audit, "\n")); len(audit) != 3 || result.Entries != 3 || len(result.Chains) != 1 || result.Chains[0].Id != myReg.instanceId || result.Chains[0].LastSeq != 3 || len(result.Problems) != 0 {
+		t.Fatalf("%s failed: unexpected verification of %v: %#v", testName, audit, result)
+	}
+
+	// modified entry
+	modified := strings.Replace(audit[1], testAdminUsername, "someone", 1)
+	if result := verify(audit[0] + "\n" + modified + "\n" + audit[2]); len(result.Problems) != 1 || !strings.Contains(result.Problems[0].Message, "#2 was modified") {
+		t.Fatalf("%s failed: modification not detected: %v", testName, result.Problems)
+	}
+	// deleted entry
+	if result := verify(audit[0] + "\n" + audit[2]); len(result.Problems) != 1 || !strings.Contains(result.Problems[0].Message, "#2 to #2 were deleted") {
+		t.Fatalf("%s failed: deletion not detected: %v", testName, result.Problems)
+	}
+
+	// head of the chain is stored in database periodically, so that entries deleted at the end are detected
+	if err := myReg.auditChain.flush(); err != nil {
+		t.Fatalf("%s failed: %s", testName, err)
+	}
+	head := &auditChainHead{}
+	if ok, err := myReg.loadSetting(settingIdAuditChain+":"+myReg.instanceId, head); !ok || err != nil || head.Seq != 3 {
+		t.Fatalf("%s failed: unexpected chain head %#v (%v)", testName, head, err)
+	}
+	if result := verify(audit[0] + "\n" + audit[1]); len(result.Problems) != 1 || !strings.Contains(result.Problems[0].Message, "#3 to #3 were deleted") {
+		t.Fatalf("%s failed: deletion of the last entry not detected: %v", testName, result.Problems)
+	}
+	if result := verify(strings.Join(audit, "\n")); len(result.Problems) != 0 {
+		t.Fatalf("%s failed: unexpected problems %v", testName, result.Problems)
+	}
+
+	// entries of another instance are chained separately
+	other := &auditChain{r: myReg, key: myReg.auditChain.key, id: "other", head: auditChainHead{Sig: "other"}}
+	mixed := audit[0] + "\n" + auditTag + other.sign("entry of another instance") + "\n" + audit[1] + "\n" + audit[2]
+	if result := verify(mixed); result.Entries != 4 || len(result.Chains) != 2 || len(result.Problems) != 0 {
+		t.Fatalf("%s failed: unexpected verification of chains: %#v", testName, result)
+	}
+
+	// the head is not written in read-only mode
+	myReg.readOnly.set(true)
+	myReg.auditf("entry in read-only mode")
+	if err := myReg.auditChain.flush(); err != nil {
+		t.Fatalf("%s failed: %s", testName, err)
+	}
+	if myReg.loadSetting(settingIdAuditChain+":"+myReg.instanceId, head); head.Seq != 3 {
+		t.Fatalf("%s failed: head of the chain must not be written in read-only mode %#v", testName, head)
+	}
+	myReg.readOnly.set(false)
+}
+
+func TestActionCpAuditExport(t *testing.T) {
+	testName := "TestActionCpAuditExport"
+	file := filepath.Join(t.TempDir(), "app.log")
+	lines := &bytes.Buffer{}
+	numEntries := 3000
+	for i := 0; i < numEntries; i++ {
+		fmt.Fprintf(lines, "2021/06/01 10:00:00 [AUDIT] user [someone] from 10.0.0.1: entry #%d\n", i)
+	}
+	ioutil.WriteFile(file, lines.Bytes(), 0644)
+	conf := apptest.SqliteInMemoryConfig + "\ngoadmin.log_sinks = \"file\"\ngoadmin.log_file { path = \"" + file + "\", format = \"text\" }\n"
+	h := apptest.New(t, conf, NewBootstrapper(nil, nil))
+	defer log.SetOutput(os.Stderr)
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+
+	resp := h.Get(h.Reverse(actionNameCpAuditExport))
+	h.AssertStatus(resp, http.StatusOK)
+	if !strings.HasPrefix(resp.Header().Get(echo.HeaderContentType), "text/csv") || !resp.Flushed {
+		t.Fatalf("%s failed: expected a streamed CSV document, got %s / flushed %v", testName, resp.Header().Get(echo.HeaderContentType), resp.Flushed)
+	}
+	rows, err := csv.NewReader(resp.Body).ReadAll()
+	if err != nil || len(rows) < numEntries+1 {
+		t.Fatalf("%s failed: expected at least %d rows, got %d / %v", testName, numEntries+1, len(rows), err)
+	}
+	if rows[0][0] != "Time" || rows[1][0] != "2021/06/01 10:00:00" || rows[numEntries][1] != fmt.Sprintf("user [someone] from 10.0.0.1: entry #%d", numEntries-1) {
+		t.Fatalf("%s failed: unexpected rows %v %v %v", testName, rows[0], rows[1], rows[numEntries])
+	}
+}
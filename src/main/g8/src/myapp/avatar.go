@@ -0,0 +1,148 @@
+package myapp
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/btnguyen2k/goyai"
+	"github.com/labstack/echo/v4"
+	"main/src/goadmin"
+	"main/src/utils"
+)
+
+const (
+	avatarSizeSmall = "small" // used by the sidebar
+	avatarSizeLarge = "large" // used by the profile page
+)
+
+// avatarSizes returns the sizes avatars are generated in (setting myapp.avatar.sizes, a map of size names to
+// pixels), avatars are square thumbnails.
+func (r *myRegistry) avatarSizes() []goadmin.ImageSize {
+	sizes := map[string]int{avatarSizeSmall: 64, avatarSizeLarge: 256}
+	if v := r.AppConfig.GetValue(namespace + ".avatar.sizes"); v != nil && v.IsObject() {
+		for name := range v.GetObject().Items() {
+			sizes[name] = int(r.AppConfig.GetInt32(namespace+".avatar.sizes."+name, 0))
+		}
+	}
+	result := make([]goadmin.ImageSize, 0, len(sizes))
+	for name, px := range sizes {
+		if px > 0 {
+			result = append(result, goadmin.ImageSize{Name: name, Width: px, Height: px, Crop: true})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Width < result[j].Width })
+	return result
+}
+
+// avatarFormat returns the format avatars are stored in (setting myapp.avatar.format).
+func (r *myRegistry) avatarFormat() string {
+	switch format := strings.ToLower(r.AppConfig.GetString(namespace+".avatar.format", goadmin.ImageFormatJpeg)); format {
+	case goadmin.ImageFormatPng, goadmin.ImageFormatWebp:
+		return format
+	}
+	return goadmin.ImageFormatJpeg
+}
+
+// avatarBaseName returns the base name of a user's avatar files in the file store. Usernames are hashed so that they
+// do not appear in file names.
+func avatarBaseName(username string) string {
+	sum := sha1.Sum([]byte(strings.ToLower(username)))
+	return "avatars/" + hex.EncodeToString(sum[:])
+}
+
+// gravatarUrl returns the Gravatar URL of a user, used when the user has not uploaded an avatar.
+func gravatarUrl(username string, px int) string {
+	sum := md5.Sum([]byte(strings.ToLower(strings.TrimSpace(username))))
+	return fmt.Sprintf("https://www.gravatar.com/avatar/%s?s=%d", hex.EncodeToString(sum[:]), px)
+}
+
+// actionCpAvatar serves a user's avatar in the requested size, redirecting to Gravatar if the user has not uploaded
+// one.
+//
+// available since template-r5
+func actionCpAvatar(c echo.Context) error {
+	myReg := getRegistry(c)
+	username, sizeName := c.Param("username"), c.Param("size")
+	var size *goadmin.ImageSize
+	for _, s := range myReg.avatarSizes() {
+		if s.Name == sizeName {
+			size = &s
+			break
+		}
+	}
+	if size == nil {
+		return echo.ErrNotFound
+	}
+	format := myReg.avatarFormat()
+	f, err := myReg.FileStore.Open(goadmin.ImageFileName(avatarBaseName(username), size.Name, format))
+	if os.IsNotExist(err) {
+		return c.Redirect(http.StatusFound, gravatarUrl(username, size.Width))
+	}
+	if err != nil {
+		log.Printf("[ERROR] cannot open avatar of user [%s]: %s", username, err)
+		return echo.ErrInternalServerError
+	}
+	defer f.Close()
+	c.Response().Header().Set(echo.HeaderCacheControl, "private, max-age=300")
+	return c.Stream(http.StatusOK, "image/"+format, f)
+}
+
+// actionCpProfileAvatarSubmit processes the avatar uploaded by the current user (field "avatar"): the image is
+// resized to all avatar sizes, re-encoded (which strips EXIF metadata) and stored in the file store.
+//
+// available since template-r5
+func actionCpProfileAvatarSubmit(c echo.Context) error {
+	myReg := getRegistry(c)
+	currentUser, err := getCurrentUser(c)
+	if err != nil || currentUser == nil {
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpLogin))
+	}
+	uploaded := false
+	_, err = goadmin.StreamUpload(c, func(field, filename string, content io.Reader) error {
+		if field != "avatar" {
+			_, err := io.Copy(ioutil.Discard, content)
+			return err
+		}
+		uploaded = true
+		_, err := goadmin.ProcessImage(myReg.FileStore, avatarBaseName(currentUser.Username), content, myReg.avatarSizes(), myReg.avatarFormat())
+		return err
+	})
+	if err == nil && !uploaded {
+		err = fmt.Errorf("no image uploaded")
+	}
+	if err != nil {
+		AddFlashText(c, FlashError, getI18n(c).Localize(getContextString(c, ctxLocale), "error_invalid_avatar", &goyai.LocalizeConfig{
+			TemplateData: map[string]interface{}{"err": err.Error()},
+		}))
+	} else {
+		AddFlash(c, FlashInfo, "update_avatar_successful")
+	}
+	return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpProfile)+"?r="+utils.RandomString(4))
+}
+
+// actionCpProfileAvatarDeleteSubmit removes the current user's avatar, Gravatar is used again.
+//
+// available since template-r5
+func actionCpProfileAvatarDeleteSubmit(c echo.Context) error {
+	myReg := getRegistry(c)
+	if currentUser, _ := getCurrentUser(c); currentUser != nil {
+		base, format := avatarBaseName(currentUser.Username), myReg.avatarFormat()
+		for _, size := range myReg.avatarSizes() {
+			if err := myReg.FileStore.Delete(goadmin.ImageFileName(base, size.Name, format)); err != nil {
+				AddFlash(c, FlashError, "error_db_001", "err", "avatar/"+err.Error())
+				return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpProfile)+"?r="+utils.RandomString(4))
+			}
+		}
+		AddFlash(c, FlashInfo, "delete_avatar_successful")
+	}
+	return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpProfile)+"?r="+utils.RandomString(4))
+}
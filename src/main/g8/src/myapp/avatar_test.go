@@ -0,0 +1,70 @@
+package myapp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"main/src/apptest"
+)
+
+func TestActionCpProfileAvatar(t *testing.T) {
+	testName := "TestActionCpProfileAvatar"
+	conf := apptest.SqliteInMemoryConfig + "\ngoadmin.file_store_dir = \"" + t.TempDir() + "\"\n"
+	h := apptest.New(t, conf, NewBootstrapper(nil, nil))
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	avatarUrl := h.Reverse(actionNameCpAvatar, testAdminUsername, avatarSizeSmall)
+	h.AssertStatus(h.Get(avatarUrl), http.StatusFound)
+
+	img := image.NewNRGBA(image.Rect(0, 0, 300, 200))
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	fw, _ := w.CreateFormFile("avatar", "avatar.png")
+	png.Encode(fw, img)
+	w.Close()
+	req := httptest.NewRequest(http.MethodPost, h.Reverse(actionNameCpProfileAvatarSubmit), body)
+	req.Header.Set(echo.HeaderContentType, w.FormDataContentType())
+	h.AssertRedirect(h.Do(req), h.Reverse(actionNameCpProfile))
+
+	resp := h.Get(avatarUrl)
+	h.AssertStatus(resp, http.StatusOK)
+	if cfg, _, err := image.DecodeConfig(resp.Body); err != nil || cfg.Width != 64 || cfg.Height != 64 {
+		t.Fatalf("%s failed: expected 64x64 avatar but received %#v / %v", testName, cfg, err)
+	}
+
+	h.PostForm(h.Reverse(actionNameCpProfileAvatarDeleteSubmit), url.Values{})
+	h.AssertStatus(h.Get(avatarUrl), http.StatusFound)
+}
+
+func TestActionCpProfileAvatar_Webp(t *testing.T) {
+	testName := "TestActionCpProfileAvatar_Webp"
+	conf := apptest.SqliteInMemoryConfig + "\ngoadmin.file_store_dir = \"" + t.TempDir() + "\"\nmyapp.avatar.format = webp\n"
+	h := apptest.New(t, conf, NewBootstrapper(nil, nil))
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	fw, _ := w.CreateFormFile("avatar", "avatar.png")
+	png.Encode(fw, image.NewNRGBA(image.Rect(0, 0, 300, 200)))
+	w.Close()
+	req := httptest.NewRequest(http.MethodPost, h.Reverse(actionNameCpProfileAvatarSubmit), body)
+	req.Header.Set(echo.HeaderContentType, w.FormDataContentType())
+	h.AssertRedirect(h.Do(req), h.Reverse(actionNameCpProfile))
+
+	resp := h.Get(h.Reverse(actionNameCpAvatar, testAdminUsername, avatarSizeSmall))
+	h.AssertStatus(resp, http.StatusOK)
+	data := resp.Body.Bytes()
+	if resp.Header().Get(echo.HeaderContentType) != "image/webp" || len(data) < 25 || string(data[8:16]) != "WEBPVP8L" {
+		t.Fatalf("%s failed: expected a lossless WebP image but received %s / %q", testName, resp.Header().Get(echo.HeaderContentType), data)
+	}
+	// VP8L header: signature byte, then width-1 and height-1 on 14 bits each
+	if bits := binary.LittleEndian.Uint32(data[21:]); bits&0x3fff != 63 || (bits>>14)&0x3fff != 63 {
+		t.Fatalf("%s failed: expected 64x64 avatar", testName)
+	}
+}
@@ -0,0 +1,150 @@
+package myapp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+	"main/src/apptest"
+)
+
+// Benchmarks of the hot paths of the control panel, run against generated fixtures (see generateFixtures):
+//
+//	go test -run=NONE -bench=. -benchmem ./src/myapp/
+//
+// Compare results before and after a change (e.g. with benchstat) to catch performance regressions.
+
+const (
+	benchNumGroups = 50
+	benchNumUsers  = 5000
+)
+
+// _newBenchHarness bootstraps the application on an in-memory SQLite database seeded with fixtures, and signs in as
+// the admin user.
+func _newBenchHarness(b *testing.B) *apptest.Harness {
+	h := apptest.New(b, apptest.SqliteInMemoryConfig, NewBootstrapper(nil, nil))
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	if _, err := generateFixtures(myReg.groupDao, myReg.userDao, &FixtureOptions{Groups: benchNumGroups, Users: benchNumUsers, Password: "fixture"}); err != nil {
+		b.Fatal(err)
+	}
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	return h
+}
+
+func BenchmarkRendererLookup(b *testing.B) {
+	apptest.ChdirProjectRoot(b)
+	renderer := newTemplateRenderer("./views/myapp", "", ".html", nil)
+	if err := renderer.preload([]string{"layout:cp_users:cp_fragments"}); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := renderer.lookup("layout:cp_users:cp_fragments"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func _benchmarkPage(b *testing.B, path string) {
+	h := _newBenchHarness(b)
+	h.AssertStatus(h.Get(path), http.StatusOK)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if resp := h.Get(path); resp.Code != http.StatusOK {
+			b.Fatalf("expected status %d but received %d", http.StatusOK, resp.Code)
+		}
+	}
+}
+
+func BenchmarkRender_CpDashboard(b *testing.B) {
+	_benchmarkPage(b, "/cp")
+}
+
+func BenchmarkRender_CpUsers(b *testing.B) {
+	_benchmarkPage(b, "/cp/users")
+}
+
+func BenchmarkRender_CpGroups(b *testing.B) {
+	_benchmarkPage(b, "/cp/groups")
+}
+
+func BenchmarkRender_Landing(b *testing.B) {
+	_benchmarkPage(b, "/")
+}
+
+// _benchmarkDaos runs fn against the DAOs of the application (SQLite) and in-memory DAOs, both seeded with fixtures.
+func _benchmarkDaos(b *testing.B, fn func(b *testing.B, groupDao GroupDao, userDao UserDao)) {
+	h := _newBenchHarness(b)
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	memGroupDao, memUserDao := newGroupDaoMemory(), newUserDaoMemory()
+	if _, err := generateFixtures(memGroupDao, memUserDao, &FixtureOptions{Groups: benchNumGroups, Users: benchNumUsers, Password: "fixture"}); err != nil {
+		b.Fatal(err)
+	}
+	b.Run("sqlite", func(b *testing.B) { fn(b, myReg.groupDao, myReg.userDao) })
+	b.Run("memory", func(b *testing.B) { fn(b, memGroupDao, memUserDao) })
+}
+
+func BenchmarkUserDao_Get(b *testing.B) {
+	_benchmarkDaos(b, func(b *testing.B, _ GroupDao, userDao UserDao) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if user, err := userDao.Get(fixtureUsername(1 + i%benchNumUsers)); err != nil || user == nil {
+				b.Fatalf("expected user but received %v / %v", user, err)
+			}
+		}
+	})
+}
+
+func BenchmarkUserDao_GetNByGroup(b *testing.B) {
+	_benchmarkDaos(b, func(b *testing.B, _ GroupDao, userDao UserDao) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := getNUsersByGroup(userDao, fixtureGroupId(1+i%benchNumGroups), 0, 20); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkGroupDao_GetByIds(b *testing.B) {
+	ids := make([]string, 0, 20)
+	for i := 1; i <= 20; i++ {
+		ids = append(ids, fixtureGroupId(i))
+	}
+	_benchmarkDaos(b, func(b *testing.B, groupDao GroupDao, _ UserDao) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if groups, err := getGroupsByIds(groupDao, ids); err != nil || len(groups) != len(ids) {
+				b.Fatalf("expected %d groups but received %d / %v", len(ids), len(groups), err)
+			}
+		}
+	})
+}
+
+func BenchmarkMiddlewareRequiredAuth(b *testing.B) {
+	h := _newBenchHarness(b)
+	resp := h.PostForm(h.Reverse(actionNameCpLoginSubmit), url.Values{"username": {testAdminUsername}, "password": {testAdminPassword}})
+	cookies := resp.Result().Cookies()
+	// the middleware reads the session and the current user, as it does in front of every page of the control panel
+	handler := h.Registry.Middleware(session.Middleware(h.Registry.SessionStore)(middlewareRequiredAuth(func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	})))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/cp", nil)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		if err := handler(h.Echo.NewContext(req, rec)); err != nil || rec.Code != http.StatusNoContent {
+			b.Fatalf("expected status %d but received %d / %v", http.StatusNoContent, rec.Code, err)
+		}
+	}
+}
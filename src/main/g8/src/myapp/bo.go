@@ -1,14 +1,22 @@
 package myapp
 
+import (
+	"strings"
+
+	"main/src/goadmin"
+)
+
 const (
-	fieldGroupId   = "id"
-	fieldGroupName = "name"
+	fieldGroupId    = "id"
+	fieldGroupName  = "name"
+	fieldGroupAttrs = "attrs"
 )
 
 // Group represents a user group
 type Group struct {
-	Id   string `json:"id"`
-	Name string `json:"name"`
+	Id    string            `json:"id"`
+	Name  string            `json:"name"`
+	Attrs map[string]string `json:"attrs,omitempty"` // values of custom fields, keyed by field name (available since template-r5)
 }
 
 // GroupDao defines API to access user group storage
@@ -21,19 +29,107 @@ type GroupDao interface {
 	Update(bo *Group) (bool, error)
 }
 
+// GroupBatchGetter is implemented by GroupDaos that look up several groups at once natively (e.g. SQL "WHERE ... OR").
+// Groups of other DAOs are looked up by fetching all of them, see getGroupsByIds.
+//
+// available since template-r5
+type GroupBatchGetter interface {
+	// GetByIds returns the groups of the supplied ids, keyed by group id. Ids of groups that do not exist are absent
+	// from the result.
+	GetByIds(ids []string) (map[string]*Group, error)
+}
+
+// GroupKeysetPager is implemented by GroupDaos that page through groups by key natively (e.g. SQL "WHERE id>?"), so
+// that paging stays fast on large tables and is not shifted by concurrent writes. Groups of other DAOs are paged by
+// scanning all groups, see getNGroupsAfter.
+//
+// available since template-r5
+type GroupKeysetPager interface {
+	// GetNAfter returns at most maxNumRows groups (all if maxNumRows <= 0) whose id sorts after afterId (from the
+	// first group if afterId is empty), sorted by id.
+	GetNAfter(afterId string, maxNumRows int) ([]*Group, error)
+}
+
 const (
 	fieldUserUsername = "uname"
 	fieldUserPassword = "pwd"
 	fieldUserName     = "name"
 	fieldUserGroupId  = "gid"
+	fieldUserFlags    = "flags"
+	fieldUserNotes    = "notes"
+	fieldUserTags     = "tags"
+)
+
+// Flags admins set on individual user accounts (see User.Flags), stored as a comma-separated list.
+const (
+	userFlagMustChangePassword = "must_change_password" // the user must change their password before using the control panel
+	userFlagCannotChangeEmail  = "cannot_change_email"  // the user can not change their email address
+	userFlagReadOnly           = "read_only"            // the user can browse but not change anything
 )
 
+// userFlags lists the supported user flags, in the order they are displayed.
+var userFlags = []string{userFlagMustChangePassword, userFlagCannotChangeEmail, userFlagReadOnly}
+
 // User represents a user account
 type User struct {
 	Username string `json:"uname"`
 	Password string `json:"pwd"`
 	Name     string `json:"name"`
 	GroupId  string `json:"gid"`
+	Flags    string `json:"flags,omitempty"` // comma-separated user flags, e.g. "must_change_password,read_only" (available since template-r5)
+	Notes    string `json:"notes,omitempty"` // free-form notes of admins, e.g. support history (available since template-r5)
+	Tags     string `json:"tags,omitempty"`  // comma-separated tags, e.g. "beta,vip", see normalizeUserTags (available since template-r5)
+}
+
+// HasFlag checks if the flag is set on the user account.
+//
+// available since template-r5
+func (u *User) HasFlag(flag string) bool {
+	for _, f := range strings.Split(u.Flags, ",") {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// SetFlag sets or clears the flag on the user account, unknown flags are ignored.
+//
+// available since template-r5
+func (u *User) SetFlag(flag string, on bool) {
+	result := make([]string, 0, len(userFlags))
+	for _, f := range userFlags {
+		if (f == flag && on) || (f != flag && u.HasFlag(f)) {
+			result = append(result, f)
+		}
+	}
+	u.Flags = strings.Join(result, ",")
+}
+
+// TagList returns the tags of the user account.
+//
+// available since template-r5
+func (u *User) TagList() []string {
+	result := make([]string, 0)
+	for _, tag := range strings.Split(u.Tags, ",") {
+		if tag != "" {
+			result = append(result, tag)
+		}
+	}
+	return result
+}
+
+// HasTag checks if the user account is tagged with tag (case-insensitive).
+//
+// available since template-r5
+func (u *User) HasTag(tag string) bool {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	for _, t := range u.TagList() {
+		if t == tag {
+			return true
+		}
+	}
+	return false
 }
 
 // UserDao defines API to access user account storage
@@ -45,3 +141,110 @@ type UserDao interface {
 	GetAll() ([]*User, error)
 	Update(bo *User) (bool, error)
 }
+
+// UserCounter is implemented by UserDaos that aggregate users natively (e.g. SQL "GROUP BY"). Users of other DAOs are
+// counted by fetching all of them, see countUsersByGroup.
+//
+// available since template-r5
+type UserCounter interface {
+	// CountByGroup returns number of users of each group, keyed by group id.
+	CountByGroup() (map[string]int, error)
+}
+
+// UserGroupPager is implemented by UserDaos that page through users of a group natively (e.g. SQL "WHERE ... LIMIT").
+// Users of other DAOs are paged by scanning all users, see getNUsersByGroup.
+//
+// available since template-r5
+type UserGroupPager interface {
+	// GetNByGroup returns at most maxNumRows users of a group (all if maxNumRows <= 0) starting from fromOffset,
+	// sorted by username.
+	GetNByGroup(groupId string, fromOffset, maxNumRows int) ([]*User, error)
+}
+
+// UserKeysetPager is implemented by UserDaos that page through users by key natively (e.g. SQL "WHERE username>?"),
+// so that paging stays fast on large tables and is not shifted by concurrent writes. Users of other DAOs are paged by
+// scanning all users, see getNUsersAfter.
+//
+// available since template-r5
+type UserKeysetPager interface {
+	// GetNAfter returns at most maxNumRows users (all if maxNumRows <= 0) whose username sorts after afterUsername
+	// (from the first user if afterUsername is empty), sorted by username.
+	GetNAfter(afterUsername string, maxNumRows int) ([]*User, error)
+}
+
+const (
+	fieldMessageId     = "id"
+	fieldMessageLocale = "locale"
+	fieldMessageKey    = "key"
+	fieldMessageText   = "text"
+)
+
+// Message is a translation that overrides the one of the file-based i18n bundles
+//
+// available since template-r5
+type Message struct {
+	Locale string `json:"locale"`
+	Key    string `json:"key"`
+	Text   string `json:"text"`
+}
+
+// Id returns the unique id of the message, in format <locale>:<key>
+func (m *Message) Id() string {
+	return m.Locale + ":" + m.Key
+}
+
+// MessageDao defines API to access translation override storage
+//
+// available since template-r5
+type MessageDao interface {
+	Delete(bo *Message) (bool, error)
+	Get(locale, key string) (*Message, error)
+	GetAll() ([]*Message, error)
+	Save(bo *Message) (bool, error)
+}
+
+const (
+	fieldSettingId    = "id"
+	fieldSettingValue = "value"
+)
+
+// Setting is a named piece of application data stored as a JSON document, e.g. security settings or a user's login
+// profile
+//
+// available since template-r5
+type Setting struct {
+	Id    string `json:"id"`
+	Value string `json:"value"`
+}
+
+// SettingDao defines API to access setting storage
+//
+// available since template-r5
+type SettingDao interface {
+	Delete(bo *Setting) (bool, error)
+	Get(id string) (*Setting, error)
+	GetAll() ([]*Setting, error)
+	Save(bo *Setting) (bool, error)
+}
+
+// SettingPrefixGetter is implemented by SettingDaos that look settings up by id prefix natively (e.g. SQL range on the
+// primary key). Settings of other DAOs are looked up by fetching all of them, see getSettingsByPrefix.
+//
+// available since template-r5
+type SettingPrefixGetter interface {
+	// GetByPrefix returns settings whose id starts with prefix, sorted by id.
+	GetByPrefix(prefix string) ([]*Setting, error)
+}
+
+// Daos is the set of DAOs used by myapp.
+//
+// Storage backends registered via goadmin.RegisterDbBackend for myapp must return a *Daos. MessageDao and SettingDao
+// are optional: if not provided, translation overrides and settings are kept in memory. Locker is optional too: if
+// provided, locks (see goadmin.Registry.WithLock) are kept in the database unless the Redis cache is used.
+type Daos struct {
+	GroupDao   GroupDao
+	UserDao    UserDao
+	MessageDao MessageDao
+	SettingDao SettingDao
+	Locker     goadmin.Locker
+}
@@ -2,6 +2,7 @@ package myapp
 
 import (
 	"fmt"
+	"reflect"
 	"strconv"
 	"testing"
 
@@ -11,6 +12,8 @@ import (
 var (
 	testSqlTableNameGroup        = "test_group"
 	testMongoCollectionNameGroup = "test_group"
+	testDynamodbTableNameGroup   = "test_group"
+	testCassandraTableNameGroup  = "test_group"
 )
 
 func _initGroupDaoMongo(url, db, collectionName string) GroupDao {
@@ -26,6 +29,26 @@ func _initGroupDaoMongo(url, db, collectionName string) GroupDao {
 	return newGroupDaoMongo(mc, collectionName)
 }
 
+func _initGroupDaoDynamodb(region, endpoint, tableName string) GroupDao {
+	adc := _newAwsDynamodbConnect(region, endpoint)
+	if adc == nil {
+		return nil
+	}
+	_dropDynamodbTable(adc, tableName)
+	dynamodbInitTableGroup(adc, tableName)
+	return newGroupDaoDynamodb(adc, tableName)
+}
+
+func _initGroupDaoCassandra(hosts, keyspace, tableName string) GroupDao {
+	session := _newCassandraSession(hosts, keyspace)
+	if session == nil {
+		return nil
+	}
+	_dropCassandraTable(session, tableName)
+	cassandraInitTableGroup(session, tableName)
+	return newGroupDaoCassandra(session, tableName)
+}
+
 func _initGroupDaoSql(driver, url, tableName string, flavor sql.DbFlavor) GroupDao {
 	sqlc, err := _newSqlConnect(driver, url, testTimeZone, flavor)
 	if err != nil {
@@ -197,3 +220,107 @@ func testGroupDaoGetAll(t *testing.T, testName string, dao GroupDao) {
 		}
 	}
 }
+
+func testGroupDaoGetByIds(t *testing.T, testName string, dao GroupDao) {
+	numRows := 150
+	for i := 0; i < numRows; i++ {
+		groupId := fmt.Sprintf("%03d", i)
+		groupName := "group-name-" + strconv.Itoa(i)
+		result, err := dao.Create(groupId, groupName)
+		if !result || err != nil {
+			t.Fatalf("%s failed: {result %#v / error %s}", testName, result, err)
+		}
+	}
+
+	getter, ok := dao.(GroupBatchGetter)
+	if !ok {
+		t.Fatalf("%s failed: DAO does not implement GroupBatchGetter", testName)
+	}
+	// more ids than a single SQL query looks up, plus one that does not exist
+	ids := []string{"not-exists"}
+	for i := 0; i < numRows; i++ {
+		if i%10 != 5 {
+			ids = append(ids, fmt.Sprintf("%03d", i))
+		}
+	}
+	result, err := getter.GetByIds(ids)
+	if err != nil {
+		t.Fatalf("%s failed: %s", testName, err)
+	}
+	if len(result) != len(ids)-1 {
+		t.Fatalf("%s failed: expected %d groups but received %d", testName, len(ids)-1, len(result))
+	}
+	for _, id := range ids[1:] {
+		i, _ := strconv.Atoi(id)
+		if group := result[id]; group == nil || group.Name != "group-name-"+strconv.Itoa(i) {
+			t.Fatalf("%s failed: expected group %s but received %#v", testName, id, group)
+		}
+	}
+	if result, err := getter.GetByIds([]string{}); err != nil || len(result) != 0 {
+		t.Fatalf("%s failed: expected no groups but received %#v / %s", testName, result, err)
+	}
+}
+
+func testGroupDaoGetNAfter(t *testing.T, testName string, dao GroupDao) {
+	numRows := 50
+	for i := 0; i < numRows; i++ {
+		result, err := dao.Create(fmt.Sprintf("%03d", i), "group-name-"+strconv.Itoa(i))
+		if !result || err != nil {
+			t.Fatalf("%s failed: {result %#v / error %s}", testName, result, err)
+		}
+	}
+
+	pager, ok := dao.(GroupKeysetPager)
+	if !ok {
+		t.Fatalf("%s failed: %T does not implement GroupKeysetPager", testName, dao)
+	}
+	received := make([]string, 0)
+	for after, pageSize := "", 7; ; {
+		groups, err := pager.GetNAfter(after, pageSize)
+		if err != nil {
+			t.Fatalf("%s failed: %s", testName, err)
+		}
+		for _, group := range groups {
+			received = append(received, group.Id)
+		}
+		if len(groups) < pageSize {
+			break
+		}
+		after = groups[len(groups)-1].Id
+		// rows deleted before the cursor do not shift the next pages
+		dao.Delete(&Group{Id: fmt.Sprintf("%03d", 0)})
+	}
+	if len(received) != numRows {
+		t.Fatalf("%s failed: expected %d groups but received %d", testName, numRows, len(received))
+	}
+	for i, id := range received {
+		if id != fmt.Sprintf("%03d", i) {
+			t.Fatalf("%s failed: expected row #%d is %03d but received %s", testName, i, i, id)
+		}
+	}
+	if groups, err := pager.GetNAfter("025", 0); err != nil || len(groups) != 24 {
+		t.Fatalf("%s failed: expected 24 groups but received %d / %v", testName, len(groups), err)
+	}
+}
+
+func testGroupDaoAttrs(t *testing.T, testName string, dao GroupDao) {
+	groupId := "group-id"
+	if ok, err := dao.Create(groupId, "group-name"); !ok || err != nil {
+		t.Fatalf("%s failed: {result %#v / error %s}", testName, ok, err)
+	}
+	if group, err := dao.Get(groupId); err != nil || group == nil || len(group.Attrs) != 0 {
+		t.Fatalf("%s failed: new groups must have no attributes, received %#v (%v)", testName, group, err)
+	}
+	group := &Group{Id: groupId, Name: "group-name", Attrs: map[string]string{"cost_center": "CC-42", "email": "ops@local"}}
+	if ok, err := dao.Update(group); !ok || err != nil {
+		t.Fatalf("%s failed: {result %#v / error %s}", testName, ok, err)
+	}
+	group.Attrs["email"] = "changed@local"
+	groups, err := dao.GetAll()
+	if err != nil || len(groups) != 1 {
+		t.Fatalf("%s failed: expected 1 group but received %#v (%v)", testName, groups, err)
+	}
+	if expected := map[string]string{"cost_center": "CC-42", "email": "ops@local"}; !reflect.DeepEqual(groups[0].Attrs, expected) {
+		t.Fatalf("%s failed: expected attributes %#v but received %#v", testName, expected, groups[0].Attrs)
+	}
+}
@@ -0,0 +1,121 @@
+package myapp
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/btnguyen2k/prom/sql"
+)
+
+var (
+	testSqlTableNameMessage        = "test_message"
+	testMongoCollectionNameMessage = "test_message"
+	testDynamodbTableNameMessage   = "test_message"
+	testCassandraTableNameMessage  = "test_message"
+)
+
+func _initMessageDaoMongo(url, db, collectionName string) MessageDao {
+	mc, err := _newMongoConnect(url, db)
+	if err != nil {
+		panic(err)
+	}
+	if mc == nil {
+		return nil
+	}
+	mc.DropCollection(collectionName)
+	mongoInitCollectionMessage(mc, collectionName)
+	return newMessageDaoMongo(mc, collectionName)
+}
+
+func _initMessageDaoDynamodb(region, endpoint, tableName string) MessageDao {
+	adc := _newAwsDynamodbConnect(region, endpoint)
+	if adc == nil {
+		return nil
+	}
+	_dropDynamodbTable(adc, tableName)
+	dynamodbInitTableMessage(adc, tableName)
+	return newMessageDaoDynamodb(adc, tableName)
+}
+
+func _initMessageDaoCassandra(hosts, keyspace, tableName string) MessageDao {
+	session := _newCassandraSession(hosts, keyspace)
+	if session == nil {
+		return nil
+	}
+	_dropCassandraTable(session, tableName)
+	cassandraInitTableMessage(session, tableName)
+	return newMessageDaoCassandra(session, tableName)
+}
+
+func _initMessageDaoSql(driver, url, tableName string, flavor sql.DbFlavor) MessageDao {
+	sqlc, err := _newSqlConnect(driver, url, testTimeZone, flavor)
+	if err != nil {
+		panic(err)
+	}
+	if sqlc == nil {
+		return nil
+	}
+	switch flavor {
+	case sql.FlavorSqlite:
+		sqlc.GetDB().Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", tableName))
+		sqliteInitTableMessage(sqlc, tableName)
+		return newMessageDaoSqlite(sqlc, tableName)
+	case sql.FlavorMySql:
+		sqlc.GetDB().Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", tableName))
+		mysqlInitTableMessage(sqlc, tableName)
+		return newMessageDaoMysql(sqlc, tableName)
+	case sql.FlavorPgSql:
+		sqlc.GetDB().Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", tableName))
+		pgsqlInitTableMessage(sqlc, tableName)
+		return newMessageDaoPgsql(sqlc, tableName)
+	}
+	sqlc.Close()
+	return nil
+}
+
+func testMessageDaoSaveGetDelete(t *testing.T, testName string, dao MessageDao) {
+	if msg, err := dao.Get("en", "home"); err != nil || msg != nil {
+		t.Fatalf("%s failed: expected nil but received %#v/%s", testName, msg, err)
+	}
+	for _, text := range []string{"Start", "Start page"} {
+		if result, err := dao.Save(&Message{Locale: "en", Key: "home", Text: text}); !result || err != nil {
+			t.Fatalf("%s failed: %#v/%s", testName, result, err)
+		}
+		msg, err := dao.Get("en", "home")
+		if err != nil || msg == nil {
+			t.Fatalf("%s failed: %#v/%s", testName, msg, err)
+		}
+		if msg.Locale != "en" || msg.Key != "home" || msg.Text != text {
+			t.Fatalf("%s failed: expected text [%s] but received %#v", testName, text, msg)
+		}
+	}
+	if result, err := dao.Delete(&Message{Locale: "en", Key: "home"}); !result || err != nil {
+		t.Fatalf("%s failed: %#v/%s", testName, result, err)
+	}
+	if msg, err := dao.Get("en", "home"); err != nil || msg != nil {
+		t.Fatalf("%s failed: expected nil but received %#v/%s", testName, msg, err)
+	}
+}
+
+func testMessageDaoGetAll(t *testing.T, testName string, dao MessageDao) {
+	for _, locale := range []string{"vi", "en"} {
+		for _, key := range []string{"signin", "home"} {
+			if _, err := dao.Save(&Message{Locale: locale, Key: key, Text: locale + "/" + key}); err != nil {
+				t.Fatalf("%s failed: %s", testName, err)
+			}
+		}
+	}
+	msgList, err := dao.GetAll()
+	if err != nil {
+		t.Fatalf("%s failed: %s", testName, err)
+	}
+	expected := []string{"en:home", "en:signin", "vi:home", "vi:signin"}
+	if len(msgList) != len(expected) {
+		t.Fatalf("%s failed: expected %d messages but received %d", testName, len(expected), len(msgList))
+	}
+	for i, msg := range msgList {
+		if msg.Id() != expected[i] || msg.Text != msg.Locale+"/"+msg.Key {
+			t.Fatalf("%s failed: expected message [%s] at position %d but received %#v", testName, expected[i], i, msg)
+		}
+	}
+}
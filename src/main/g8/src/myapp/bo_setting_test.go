@@ -0,0 +1,119 @@
+package myapp
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/btnguyen2k/prom/sql"
+)
+
+var (
+	testSqlTableNameSetting        = "test_setting"
+	testMongoCollectionNameSetting = "test_setting"
+	testDynamodbTableNameSetting   = "test_setting"
+	testCassandraTableNameSetting  = "test_setting"
+)
+
+func _initSettingDaoMongo(url, db, collectionName string) SettingDao {
+	mc, err := _newMongoConnect(url, db)
+	if err != nil {
+		panic(err)
+	}
+	if mc == nil {
+		return nil
+	}
+	mc.DropCollection(collectionName)
+	mongoInitCollectionSetting(mc, collectionName)
+	return newSettingDaoMongo(mc, collectionName)
+}
+
+func _initSettingDaoDynamodb(region, endpoint, tableName string) SettingDao {
+	adc := _newAwsDynamodbConnect(region, endpoint)
+	if adc == nil {
+		return nil
+	}
+	_dropDynamodbTable(adc, tableName)
+	dynamodbInitTableSetting(adc, tableName)
+	return newSettingDaoDynamodb(adc, tableName)
+}
+
+func _initSettingDaoCassandra(hosts, keyspace, tableName string) SettingDao {
+	session := _newCassandraSession(hosts, keyspace)
+	if session == nil {
+		return nil
+	}
+	_dropCassandraTable(session, tableName)
+	cassandraInitTableSetting(session, tableName)
+	return newSettingDaoCassandra(session, tableName)
+}
+
+func _initSettingDaoSql(driver, url, tableName string, flavor sql.DbFlavor) SettingDao {
+	sqlc, err := _newSqlConnect(driver, url, testTimeZone, flavor)
+	if err != nil {
+		panic(err)
+	}
+	if sqlc == nil {
+		return nil
+	}
+	switch flavor {
+	case sql.FlavorSqlite:
+		sqlc.GetDB().Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", tableName))
+		sqliteInitTableSetting(sqlc, tableName)
+		return newSettingDaoSqlite(sqlc, tableName)
+	case sql.FlavorMySql:
+		sqlc.GetDB().Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", tableName))
+		mysqlInitTableSetting(sqlc, tableName)
+		return newSettingDaoMysql(sqlc, tableName)
+	case sql.FlavorPgSql:
+		sqlc.GetDB().Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", tableName))
+		pgsqlInitTableSetting(sqlc, tableName)
+		return newSettingDaoPgsql(sqlc, tableName)
+	}
+	sqlc.Close()
+	return nil
+}
+
+func testSettingDaoSaveGetDelete(t *testing.T, testName string, dao SettingDao) {
+	if bo, err := dao.Get("security"); err != nil || bo != nil {
+		t.Fatalf("%s failed: expected nil but received %#v/%s", testName, bo, err)
+	}
+	for _, value := range []string{`{"notify_admins":true}`, `{"notify_admins":false}`} {
+		if result, err := dao.Save(&Setting{Id: "security", Value: value}); !result || err != nil {
+			t.Fatalf("%s failed: %#v/%s", testName, result, err)
+		}
+		bo, err := dao.Get("security")
+		if err != nil || bo == nil {
+			t.Fatalf("%s failed: %#v/%s", testName, bo, err)
+		}
+		if bo.Id != "security" || bo.Value != value {
+			t.Fatalf("%s failed: expected value [%s] but received %#v", testName, value, bo)
+		}
+	}
+	if result, err := dao.Delete(&Setting{Id: "security"}); !result || err != nil {
+		t.Fatalf("%s failed: %#v/%s", testName, result, err)
+	}
+	if bo, err := dao.Get("security"); err != nil || bo != nil {
+		t.Fatalf("%s failed: expected nil but received %#v/%s", testName, bo, err)
+	}
+}
+
+func testSettingDaoGetAll(t *testing.T, testName string, dao SettingDao) {
+	for _, id := range []string{"security", "login_profile:admin", "notifications:admin"} {
+		if _, err := dao.Save(&Setting{Id: id, Value: `"` + id + `"`}); err != nil {
+			t.Fatalf("%s failed: %s", testName, err)
+		}
+	}
+	boList, err := dao.GetAll()
+	if err != nil {
+		t.Fatalf("%s failed: %s", testName, err)
+	}
+	expected := []string{"login_profile:admin", "notifications:admin", "security"}
+	if len(boList) != len(expected) {
+		t.Fatalf("%s failed: expected %d settings but received %d", testName, len(expected), len(boList))
+	}
+	for i, bo := range boList {
+		if bo.Id != expected[i] || bo.Value != `"`+bo.Id+`"` {
+			t.Fatalf("%s failed: expected setting [%s] at position %d but received %#v", testName, expected[i], i, bo)
+		}
+	}
+}
@@ -1,11 +1,15 @@
 package myapp
 
 import (
+	"os"
 	"strings"
 	"time"
 
+	awsdynamodb "github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/btnguyen2k/prom/dynamodb"
 	"github.com/btnguyen2k/prom/mongo"
 	"github.com/btnguyen2k/prom/sql"
+	"github.com/gocql/gocql"
 )
 
 const (
@@ -19,6 +23,12 @@ const (
 	envMongoUrl     = "MONGO_URL"
 	envCosmosDriver = "COSMOSDB_DRIVER"
 	envCosmosUrl    = "COSMOSDB_URL"
+
+	envAwsRegion           = "AWS_REGION"
+	envAwsDynamodbEndpoint = "AWS_DYNAMODB_ENDPOINT"
+
+	envCassandraHosts    = "CASSANDRA_HOSTS"
+	envCassandraKeyspace = "CASSANDRA_KEYSPACE"
 )
 
 var (
@@ -56,3 +66,49 @@ func _newMongoConnect(url, db string) (*mongo.MongoConnect, error) {
 	mc, err := mongo.NewMongoConnect(url, db, 10000)
 	return mc, err
 }
+
+func _newAwsDynamodbConnect(region, endpoint string) *dynamodb.AwsDynamodbConnect {
+	region = strings.Trim(region, "\"")
+	endpoint = strings.Trim(endpoint, "\"")
+	if region == "" || os.Getenv("AWS_ACCESS_KEY_ID") == "" || os.Getenv("AWS_SECRET_ACCESS_KEY") == "" {
+		return nil
+	}
+	return newAwsDynamodbConnection(region, endpoint)
+}
+
+func _dropDynamodbTable(adc *dynamodb.AwsDynamodbConnect, tableName string) {
+	err := adc.DeleteTable(nil, tableName)
+	if dynamodb.AwsIgnoreErrorIfMatched(err, awsdynamodb.ErrCodeResourceNotFoundException) != nil {
+		panic(err)
+	}
+	dynamodb.AwsDynamodbWaitForTableStatus(adc, tableName, []string{}, 1*time.Second, 30*time.Second)
+}
+
+func _newCassandraSession(hosts, keyspace string) *gocql.Session {
+	hosts = strings.Trim(hosts, "\"")
+	keyspace = strings.Trim(keyspace, "\"")
+	if hosts == "" || keyspace == "" {
+		return nil
+	}
+	session, err := newCassandraSession(hosts, "", "", "")
+	if err != nil {
+		panic(err)
+	}
+	cql := "CREATE KEYSPACE IF NOT EXISTS " + keyspace + " WITH replication = {'class': 'SimpleStrategy', 'replication_factor': 1}"
+	err = session.Query(cql).Exec()
+	session.Close()
+	if err != nil {
+		panic(err)
+	}
+	session, err = newCassandraSession(hosts, keyspace, "", "")
+	if err != nil {
+		panic(err)
+	}
+	return session
+}
+
+func _dropCassandraTable(session *gocql.Session, tableName string) {
+	if err := session.Query("DROP TABLE IF EXISTS " + tableName).Exec(); err != nil {
+		panic(err)
+	}
+}
@@ -13,6 +13,8 @@ import (
 var (
 	testSqlTableNameUser        = "test_user"
 	testMongoCollectionNameUser = "test_user"
+	testDynamodbTableNameUser   = "test_user"
+	testCassandraTableNameUser  = "test_user"
 )
 
 func _initUserDaoMongo(url, db, collectionName string) UserDao {
@@ -28,6 +30,26 @@ func _initUserDaoMongo(url, db, collectionName string) UserDao {
 	return newUserDaoMongo(mc, collectionName)
 }
 
+func _initUserDaoDynamodb(region, endpoint, tableName string) UserDao {
+	adc := _newAwsDynamodbConnect(region, endpoint)
+	if adc == nil {
+		return nil
+	}
+	_dropDynamodbTable(adc, tableName)
+	dynamodbInitTableUser(adc, tableName)
+	return newUserDaoDynamodb(adc, tableName)
+}
+
+func _initUserDaoCassandra(hosts, keyspace, tableName string) UserDao {
+	session := _newCassandraSession(hosts, keyspace)
+	if session == nil {
+		return nil
+	}
+	_dropCassandraTable(session, tableName)
+	cassandraInitTableUser(session, tableName)
+	return newUserDaoCassandra(session, tableName)
+}
+
 func _initUserDaoSql(driver, url, tableName string, flavor sql.DbFlavor) UserDao {
 	sqlc, err := _newSqlConnect(driver, url, testTimeZone, flavor)
 	if err != nil || sqlc == nil {
@@ -147,6 +169,27 @@ func testUserDaoCreateUpdate(t *testing.T, testName string, dao UserDao) {
 	}
 }
 
+func testUserDaoNotesTags(t *testing.T, testName string, dao UserDao) {
+	username := "username"
+	if ok, err := dao.Create(username, encryptPassword("salt", "S3cr3t"), "User 1", "group-id"); !ok || err != nil {
+		t.Fatalf("%s failed: {result %#v / error %s}", testName, ok, err)
+	}
+	if user, err := dao.Get(username); err != nil || user == nil || user.Notes != "" || user.Tags != "" {
+		t.Fatalf("%s failed: new users must have no notes nor tags, received %#v (%v)", testName, user, err)
+	}
+	user := &User{Username: username, Name: "User 1", GroupId: "group-id", Notes: "Called support\non 2021-01-01", Tags: "beta,vip"}
+	if ok, err := dao.Update(user); !ok || err != nil {
+		t.Fatalf("%s failed: {result %#v / error %s}", testName, ok, err)
+	}
+	users, err := dao.GetAll()
+	if err != nil || len(users) != 1 {
+		t.Fatalf("%s failed: expected 1 user but received %#v (%v)", testName, users, err)
+	}
+	if users[0].Notes != user.Notes || users[0].Tags != user.Tags || !users[0].HasTag("VIP") || users[0].HasTag("alpha") {
+		t.Fatalf("%s failed: expected notes and tags of %#v but received %#v", testName, user, users[0])
+	}
+}
+
 func testUserDaoGetN(t *testing.T, testName string, dao UserDao) {
 	numRows := 100
 	usernameList := make([]string, numRows)
@@ -200,3 +243,103 @@ func testUserDaoGetAll(t *testing.T, testName string, dao UserDao) {
 		}
 	}
 }
+
+func testUserDaoCountByGroup(t *testing.T, testName string, dao UserDao) {
+	numRows := 100
+	expected := make(map[string]int)
+	for i := 0; i < numRows; i++ {
+		username, encpwd, name, groupId := fmt.Sprintf("%03d", i), encryptPassword("salt", "S3cr3t"), "User "+strconv.Itoa(i), fmt.Sprintf("group-%03d", rand.Intn(10))
+		expected[groupId]++
+		result, err := dao.Create(username, encpwd, name, groupId)
+		if !result || err != nil {
+			t.Fatalf("%s failed: {result %#v / error %s}", testName, result, err)
+		}
+	}
+
+	counter, ok := dao.(UserCounter)
+	if !ok {
+		t.Fatalf("%s failed: %T does not implement UserCounter", testName, dao)
+	}
+	counts, err := counter.CountByGroup()
+	if err != nil {
+		t.Fatalf("%s failed: %s", testName, err)
+	}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Fatalf("%s failed: expected %#v but received %#v", testName, expected, counts)
+	}
+}
+
+func testUserDaoGetNByGroup(t *testing.T, testName string, dao UserDao) {
+	numRows := 100
+	expected := make([]string, 0)
+	for i := 0; i < numRows; i++ {
+		username, encpwd, name, groupId := fmt.Sprintf("%03d", i), encryptPassword("salt", "S3cr3t"), "User "+strconv.Itoa(i), fmt.Sprintf("group-%03d", i%3)
+		if groupId == "group-001" {
+			expected = append(expected, username)
+		}
+		result, err := dao.Create(username, encpwd, name, groupId)
+		if !result || err != nil {
+			t.Fatalf("%s failed: {result %#v / error %s}", testName, result, err)
+		}
+	}
+
+	pager, ok := dao.(UserGroupPager)
+	if !ok {
+		t.Fatalf("%s failed: %T does not implement UserGroupPager", testName, dao)
+	}
+	received := make([]string, 0)
+	for offset, pageSize := 0, 7; ; offset += pageSize {
+		users, err := pager.GetNByGroup("group-001", offset, pageSize)
+		if err != nil {
+			t.Fatalf("%s failed: %s", testName, err)
+		}
+		for _, user := range users {
+			received = append(received, user.Username)
+		}
+		if len(users) < pageSize {
+			break
+		}
+	}
+	if !reflect.DeepEqual(received, expected) {
+		t.Fatalf("%s failed: expected %#v but received %#v", testName, expected, received)
+	}
+}
+
+func testUserDaoGetNAfter(t *testing.T, testName string, dao UserDao) {
+	numRows := 50
+	for i := 0; i < numRows; i++ {
+		username, encpwd, name, groupId := fmt.Sprintf("%03d", i), encryptPassword("salt", "S3cr3t"), "User "+strconv.Itoa(i), fmt.Sprintf("group-%03d", i%3)
+		result, err := dao.Create(username, encpwd, name, groupId)
+		if !result || err != nil {
+			t.Fatalf("%s failed: {result %#v / error %s}", testName, result, err)
+		}
+	}
+
+	pager, ok := dao.(UserKeysetPager)
+	if !ok {
+		t.Fatalf("%s failed: %T does not implement UserKeysetPager", testName, dao)
+	}
+	received := make([]string, 0)
+	for after, pageSize := "", 7; ; {
+		users, err := pager.GetNAfter(after, pageSize)
+		if err != nil {
+			t.Fatalf("%s failed: %s", testName, err)
+		}
+		for _, user := range users {
+			received = append(received, user.Username)
+		}
+		if len(users) < pageSize {
+			break
+		}
+		after = users[len(users)-1].Username
+		// rows created before the cursor do not shift the next pages
+		dao.Create("", encryptPassword("salt", "S3cr3t"), "Before all", "group-000")
+	}
+	expected := make([]string, numRows)
+	for i := range expected {
+		expected[i] = fmt.Sprintf("%03d", i)
+	}
+	if !reflect.DeepEqual(received, expected) {
+		t.Fatalf("%s failed: expected %#v but received %#v", testName, expected, received)
+	}
+}
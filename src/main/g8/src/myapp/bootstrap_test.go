@@ -0,0 +1,735 @@
+package myapp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"main/src/apptest"
+	"main/src/goadmin"
+	"main/src/utils"
+)
+
+const (
+	testAdminUsername = "admin@local"
+	testAdminPassword = "S3cr3t"
+)
+
+func _newHarness(t *testing.T) *apptest.Harness {
+	return apptest.New(t, apptest.SqliteInMemoryConfig, NewBootstrapper(nil, nil))
+}
+
+func TestActionCp_RequiredAuth(t *testing.T) {
+	h := _newHarness(t)
+	resp := h.Get(h.Reverse(actionNameCpGroups))
+	h.AssertRedirect(resp, h.Reverse(actionNameCpLogin))
+}
+
+func TestActionCpLoginSubmit_Failed(t *testing.T) {
+	h := _newHarness(t)
+	resp := h.PostForm(h.Reverse(actionNameCpLoginSubmit), url.Values{"username": {testAdminUsername}, "password": {"wrong"}})
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertTemplate(namespace + ":login")
+	h.AssertData("error", "password does not match")
+}
+
+func TestActionCpLoginSubmit_Successful(t *testing.T) {
+	h := _newHarness(t)
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	resp := h.Get(h.Reverse(actionNameCpDashboard))
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertTemplate(namespace + ":layout:cp_dashboard:cp_fragments")
+}
+
+func TestActionCpCreateGroupSubmit(t *testing.T) {
+	h := _newHarness(t)
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	resp := h.PostForm(h.Reverse(actionNameCpCreateGroupSubmit), url.Values{"id": {"testers"}, "name": {"Testers"}})
+	h.AssertRedirect(resp, h.Reverse(actionNameCpGroups))
+	resp = h.Get(h.Reverse(actionNameCpGroups))
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertData("flashInfo", "testers")
+	h.AssertBodyContains(resp, "Testers")
+}
+
+func TestInitDaos_UnknownType(t *testing.T) {
+	conf := goadmin.ParseAppConfig(namespace + `.db.type = "unknown"`)
+	err := initDaos(&myRegistry{Registry: goadmin.NewRegistry(conf)})
+	if err == nil {
+		t.Fatalf("expected error for unknown database type")
+	}
+	if !strings.Contains(err.Error(), "sqlite") || !strings.Contains(err.Error(), "memory") {
+		t.Fatalf("expected error to list registered backends but received: %s", err)
+	}
+}
+
+func TestBootstrap_Diagnostics(t *testing.T) {
+	h := _newHarness(t)
+	names := make(map[string]bool)
+	for _, r := range h.Registry.Diagnostics.Results() {
+		if !r.Ok {
+			t.Fatalf("self-check [%s] failed: %s", r.Name, r.Message)
+		}
+		names[r.Name] = true
+	}
+	for _, name := range []string{namespace + ".views", namespace + ".views_preload", namespace + ".i18n", namespace + ".db"} {
+		if !names[name] {
+			t.Fatalf("expected self-check [%s] to be performed", name)
+		}
+	}
+}
+
+func TestDeclareConfigKeys_Typo(t *testing.T) {
+	conf := goadmin.ParseAppConfig(apptest.SqliteInMemoryConfig + "\n" + namespace + `.db.tpye = "mysql"`)
+	registry := goadmin.NewRegistry(conf)
+	declareConfigKeys(registry.ConfigSchema)
+	errs := registry.ConfigSchema.Validate(conf)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "did you mean ["+namespace+".db.type]") {
+		t.Fatalf("expected typo of [%s] to be reported but received %v", namespace+".db.type", errs)
+	}
+}
+
+func TestActionCpEditGroup_SignedUrl(t *testing.T) {
+	h := _newHarness(t)
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	unsignedUrl := h.Reverse(actionNameCpEditGroup) + "?id=" + systemGroupId
+	h.AssertStatus(h.Get(unsignedUrl), http.StatusForbidden)
+	h.AssertStatus(h.Get(unsignedUrl+"&_exp=9999999999&_sig=forged"), http.StatusForbidden)
+
+	resp := h.Get(h.Registry.UrlSigner.Sign(unsignedUrl))
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertTemplate(namespace + ":layout:cp_create_edit_group")
+}
+
+func TestActionCpLogout_PostOnly(t *testing.T) {
+	h := _newHarness(t)
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpLogout)), http.StatusMethodNotAllowed)
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpDashboard)), http.StatusOK)
+
+	h.AssertRedirect(h.PostForm(h.Reverse(actionNameCpLogout), url.Values{}), h.Reverse(actionNameCpDashboard))
+	h.AssertRedirect(h.Get(h.Reverse(actionNameCpDashboard)), h.Reverse(actionNameCpLogin))
+}
+
+func TestBootstrap_BasePath(t *testing.T) {
+	h := apptest.New(t, apptest.SqliteInMemoryConfig+"\nhttp.base_path = \"/admin/\"\n", NewBootstrapper(nil, nil))
+	if h.Registry.BasePath != "/admin" {
+		t.Fatalf("expected base path [/admin] but received [%s]", h.Registry.BasePath)
+	}
+	if loginUrl := h.Reverse(actionNameCpLogin); loginUrl != "/admin/cp/login" {
+		t.Fatalf("expected login URL [/admin/cp/login] but received [%s]", loginUrl)
+	}
+	h.AssertStatus(h.Get("/cp/login"), http.StatusNotFound)
+	h.AssertRedirect(h.Get("/admin/cp/groups"), "/admin/cp/login")
+	h.AssertStatus(h.Get("/admin/cp/login"), http.StatusOK)
+	h.AssertData("static", "/admin/static_v0.0.0")
+
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	h.AssertStatus(h.Get("/admin/cp/groups"), http.StatusOK)
+	h.AssertTemplate(namespace + ":layout:cp_groups:cp_fragments")
+}
+
+func TestBootstrap_AssetOrigin(t *testing.T) {
+	h := _newHarness(t)
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpLogin)), http.StatusOK)
+	h.AssertData("static", "/static_v0.0.0")
+
+	cdnConfig := apptest.SqliteInMemoryConfig + `
+myapp.assets {
+  env = "production"
+  cdn_base_urls.production = "https://cdn.example.com/myapp/"
+  cache_bust_param = "v"
+}
+`
+	h = apptest.New(t, cdnConfig, NewBootstrapper(nil, nil))
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpLogin)), http.StatusOK)
+	h.AssertData("static", "https://cdn.example.com/myapp")
+	assetUrl, _ := h.LastData()["asset"].(func(string) string)
+	if assetUrl == nil {
+		t.Fatalf("function [asset] must be passed to templates")
+	}
+	if u := assetUrl("css/app.css"); u != "https://cdn.example.com/myapp/css/app.css?v=0.0.0" {
+		t.Fatalf("unexpected asset URL [%s]", u)
+	}
+}
+
+func TestHttpErrorHandler_Localized(t *testing.T) {
+	h := _newHarness(t)
+	resp := h.Get("/not-found")
+	h.AssertStatus(resp, http.StatusNotFound)
+	h.AssertBodyContains(resp, "Page not found")
+
+	req := httptest.NewRequest(http.MethodGet, "/not-found", nil)
+	req.Header.Set("Accept-Language", "vi-VN,vi;q=0.9,en;q=0.8")
+	resp = h.Do(req)
+	h.AssertStatus(resp, http.StatusNotFound)
+	h.AssertBodyContains(resp, "Không tìm thấy trang")
+
+	resp = h.Get(h.Reverse(actionNameCpLogout) + "?_l=vi")
+	h.AssertStatus(resp, http.StatusMethodNotAllowed)
+	h.AssertBodyContains(resp, "Phương thức không được hỗ trợ")
+}
+
+func TestRender_LocaleMeta(t *testing.T) {
+	h := _newHarness(t)
+	for locale, expectedDir := range map[string]string{"en": "ltr", "vi": "ltr", "ar": "rtl"} {
+		h.AssertStatus(h.Get(h.Reverse(actionNameCpLogin)+"?_l="+locale), http.StatusOK)
+		meta, _ := h.LastData()["localeMeta"].(*LocaleMeta)
+		if meta == nil || meta.Id != locale || meta.Dir != expectedDir {
+			t.Fatalf("expected locale [%s] with direction [%s] but received %#v", locale, expectedDir, meta)
+		}
+	}
+	h.AssertBodyContains(h.Get(h.Reverse(actionNameCpLogin)+"?_l=ar"), `dir="rtl"`)
+}
+
+func TestRenderer_Markdown(t *testing.T) {
+	h := _newHarness(t)
+	markdown, ok := h.Registry.Renderer.Funcs()["markdown"].(func(string) template.HTML)
+	if !ok {
+		t.Fatalf("template function [markdown] is not registered")
+	}
+	testCases := []struct{ src, expected string }{
+		{"# Title", "<h1>Title</h1>\n"},
+		{"**bold** and *italic*", "<p><strong>bold</strong> and <em>italic</em></p>\n"},
+		{"- a\n- b", "<ul>\n<li>a</li>\n<li>b</li>\n</ul>\n"},
+		{"<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n"},
+		{"[x](javascript:alert)", `<p><a href="#" rel="nofollow noopener">x</a></p>` + "\n"},
+		{"[x](/cp/help)", `<p><a href="/cp/help" rel="nofollow noopener">x</a></p>` + "\n"},
+	}
+	for _, tc := range testCases {
+		if html := string(markdown(tc.src)); html != tc.expected {
+			t.Fatalf("%q: expected %q but received %q", tc.src, tc.expected, html)
+		}
+	}
+}
+
+func TestCPGroup_Csrf(t *testing.T) {
+	h := _newHarness(t)
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpCreateGroup)), http.StatusOK)
+	if token, _ := h.LastData()["csrf"].(string); token == "" {
+		t.Fatalf("CSRF token must be passed to templates")
+	}
+
+	req := httptest.NewRequest(http.MethodPost, h.Reverse(actionNameCpCreateGroupSubmit), strings.NewReader(url.Values{"id": {"testers"}, "name": {"Testers"}}.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	req.Header.Set(echo.HeaderXCSRFToken, "invalid")
+	h.AssertStatus(h.Do(req), http.StatusForbidden)
+	if group, _ := h.Registry.Get(namespace).(*myRegistry).groupDao.Get("testers"); group != nil {
+		t.Fatalf("group [testers] must not be created without a valid CSRF token")
+	}
+
+	h.AssertRedirect(h.PostForm(h.Reverse(actionNameCpCreateGroupSubmit), url.Values{"id": {"testers"}, "name": {"Testers"}}), h.Reverse(actionNameCpGroups))
+}
+
+func TestActionCpDeleteGroupSubmit_MethodOverride(t *testing.T) {
+	h := _newHarness(t)
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	h.PostForm(h.Reverse(actionNameCpCreateGroupSubmit), url.Values{"id": {"testers"}, "name": {"Testers"}})
+
+	deleteUrl := h.Registry.UrlSigner.Sign(h.Reverse(actionNameCpDeleteGroup) + "?id=testers")
+	h.AssertStatus(h.Get(deleteUrl), http.StatusOK)
+	h.AssertTemplate(namespace + ":layout:cp_delete_group")
+	if group, _ := h.Registry.Get(namespace).(*myRegistry).groupDao.Get("testers"); group == nil {
+		t.Fatalf("group [testers] must not be deleted by a GET request")
+	}
+
+	resp := h.PostForm(deleteUrl, url.Values{goadmin.MethodOverrideParam: {http.MethodDelete}})
+	h.AssertRedirect(resp, h.Reverse(actionNameCpGroups))
+	if group, _ := h.Registry.Get(namespace).(*myRegistry).groupDao.Get("testers"); group != nil {
+		t.Fatalf("group [testers] should have been deleted")
+	}
+}
+
+func TestBootstrap_Sites(t *testing.T) {
+	h := _newHarness(t)
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	testCases := map[string]string{
+		"/":                                 goadmin.SitePublic,
+		h.Reverse(actionNameCpLogin):        goadmin.SiteCP,
+		h.Reverse(actionNameCpUsers):        goadmin.SiteCP,
+		myReg.staticPath + "/css/style.css": "",
+	}
+	for path, expected := range testCases {
+		if site := h.Registry.SiteOf(path); site != expected {
+			t.Fatalf("TestBootstrap_Sites failed: expected site [%s] for [%s] but received [%s]", expected, path, site)
+		}
+	}
+
+	// middlewares of a site are executed for requests of that site only
+	h.Registry.UseSite(goadmin.SitePublic, func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Response().Header().Set("X-Site", goadmin.SitePublic)
+			return next(c)
+		}
+	})
+	if resp := h.Get("/"); resp.Header().Get("X-Site") != goadmin.SitePublic {
+		t.Fatalf("TestBootstrap_Sites failed: expected public site middleware to be executed for [/]")
+	}
+	if resp := h.Get(h.Reverse(actionNameCpLogin)); resp.Header().Get("X-Site") != "" {
+		t.Fatalf("TestBootstrap_Sites failed: expected public site middleware not to be executed for control panel")
+	}
+}
+
+func TestActionCpLoggingSettingsSubmit(t *testing.T) {
+	h := _newHarness(t)
+	t.Cleanup(func() { goadmin.SetLogLevel(h.Registry.DefaultLogLevel) })
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	resp := h.PostForm(h.Reverse(actionNameCpLoggingSettingsSubmit), url.Values{"level": {"verbose"}})
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertData("error", "Invalid log level")
+
+	resp = h.PostForm(h.Reverse(actionNameCpLoggingSettingsSubmit), url.Values{"level": {"debug"}})
+	h.AssertRedirect(resp, h.Reverse(actionNameCpLoggingSettings))
+	if !goadmin.IsDebugEnabled() {
+		t.Fatalf("TestActionCpLoggingSettingsSubmit failed: expected debug logging to be enabled")
+	}
+	resp = h.PostForm(h.Reverse(actionNameCpLoggingSettingsSubmit), url.Values{"level": {"warn"}})
+	h.AssertRedirect(resp, h.Reverse(actionNameCpLoggingSettings))
+	if level := goadmin.GetLogLevel(); level != goadmin.LogLevelWarn {
+		t.Fatalf("TestActionCpLoggingSettingsSubmit failed: expected log level [warn] but received [%s]", level)
+	}
+	resp = h.Get(h.Reverse(actionNameCpLoggingSettings))
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertData("flashInfo", "[warn]")
+}
+
+func TestBootstrap_Version(t *testing.T) {
+	h := _newHarness(t)
+	resp := h.Get(h.Reverse(goadmin.ActionNameVersion))
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertBodyContains(resp, `"version":"0.0.0"`)
+	h.AssertBodyContains(resp, `"commit":"unknown"`)
+
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	resp = h.Get(h.Reverse(actionNameCpDashboard))
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertBodyContains(resp, "build unknown")
+}
+
+func TestBootstrap_BodyLimit(t *testing.T) {
+	h := _newHarness(t)
+	path := h.Reverse(actionNameCpLoginSubmit)
+	h.Registry.SetBodyLimit(path, 16)
+	if limit := h.Registry.BodyLimit(path); limit != 16 {
+		t.Fatalf("TestBootstrap_BodyLimit failed: expected limit 16 but received %d", limit)
+	}
+	resp := h.PostForm(path, url.Values{"username": {testAdminUsername}, "password": {testAdminPassword}})
+	h.AssertStatus(resp, http.StatusRequestEntityTooLarge)
+	h.Registry.SetBodyLimit(path, 0)
+	resp = h.PostForm(path, url.Values{"username": {testAdminUsername}, "password": {testAdminPassword}})
+	h.AssertRedirect(resp, h.Reverse(actionNameCpDashboard))
+}
+
+func TestActionCpList_FormatJson(t *testing.T) {
+	h := _newHarness(t)
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+
+	resp := h.Get(h.Reverse(actionNameCpGroups) + "?format=json")
+	h.AssertStatus(resp, http.StatusOK)
+	var groups map[string][]map[string]interface{}
+	if err := json.Unmarshal(resp.Body.Bytes(), &groups); err != nil || len(groups["groups"]) != 1 {
+		t.Fatalf("TestActionCpList_FormatJson failed: unexpected response %s / %v", resp.Body.String(), err)
+	}
+	if g := groups["groups"][0]; g["id"] != systemGroupId || g["url_edit"] == nil || g["url_delete"] != nil {
+		t.Fatalf("TestActionCpList_FormatJson failed: unexpected group %v", g)
+	}
+
+	resp = h.Get(h.Reverse(actionNameCpUsers) + "?format=json")
+	h.AssertStatus(resp, http.StatusOK)
+	var users map[string][]map[string]interface{}
+	if err := json.Unmarshal(resp.Body.Bytes(), &users); err != nil || len(users["users"]) != 1 {
+		t.Fatalf("TestActionCpList_FormatJson failed: unexpected response %s / %v", resp.Body.String(), err)
+	}
+	if u := users["users"][0]; u["username"] != testAdminUsername || u["group_id"] != systemGroupId || u["url_edit"] != nil {
+		t.Fatalf("TestActionCpList_FormatJson failed: unexpected user %v", u)
+	}
+	if strings.Contains(resp.Body.String(), "pwd") || strings.Contains(resp.Body.String(), "password") {
+		t.Fatalf("TestActionCpList_FormatJson failed: password is serialized")
+	}
+}
+
+func TestUserModel_ToMap_NonSystemViewer(t *testing.T) {
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	c.Set(ctxCurrentUser, &User{Username: "viewer", GroupId: "staff"})
+	m := toUserModel(c, &User{Username: "someone", Password: "secret", Name: "Someone", GroupId: "staff"}).ToMap()
+	if len(m) != 2 || m["username"] != "someone" || m["name"] != "Someone" {
+		t.Fatalf("TestUserModel_ToMap_NonSystemViewer failed: expected only username and name but received %v", m)
+	}
+}
+
+func TestWrapRoute(t *testing.T) {
+	h := _newHarness(t)
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	var currentUser *User
+	h.Registry.WrapRoute(actionNameCpUsers, func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			// middlewares of control panel routes run after authentication
+			currentUser, _ = c.Get(ctxCurrentUser).(*User)
+			return c.NoContent(http.StatusTeapot)
+		}
+	})
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpUsers)), http.StatusTeapot)
+	if currentUser == nil || currentUser.Username != testAdminUsername {
+		t.Fatalf("TestWrapRoute failed: current user is not available to middlewares of control panel routes")
+	}
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpGroups)), http.StatusOK)
+
+	h.Registry.WrapRoute(actionNameHome, func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			return c.NoContent(http.StatusTeapot)
+		}
+	})
+	h.AssertStatus(h.Get(h.Reverse(actionNameHome)), http.StatusTeapot)
+
+	h.Registry.ReplaceRouteMiddlewares(actionNameCpUsers)
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpUsers)), http.StatusOK)
+}
+
+func TestLogSinkLoki(t *testing.T) {
+	testName := "TestLogSinkLoki"
+	pushed := make(chan string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		pushed <- r.URL.Path + " " + string(body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	conf := apptest.SqliteInMemoryConfig + "\ngoadmin.log_sinks = \"console,loki\"\ngoadmin.log_loki { url = \"" + srv.URL + "\", labels { app = \"test\" } }\n"
+	apptest.New(t, conf, NewBootstrapper(nil, nil))
+	defer goadmin.FlushLogSinks()
+	log.Printf("[WARN] %s shipped message", testName)
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-pushed:
+			if !strings.HasPrefix(msg, "/loki/api/v1/push ") {
+				t.Fatalf("%s failed: unexpected request %s", testName, msg)
+			}
+			if strings.Contains(msg, testName+" shipped message") {
+				if !strings.Contains(msg, `"level":"warn"`) || !strings.Contains(msg, `"app":"test"`) {
+					t.Fatalf("%s failed: unexpected labels %s", testName, msg)
+				}
+				return
+			}
+		case <-timeout:
+			t.Fatalf("%s failed: message was not shipped", testName)
+		}
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	testName := "TestAccessLog"
+	file := filepath.Join(t.TempDir(), "access.log")
+	conf := apptest.SqliteInMemoryConfig + "\nhttp.access_log { file = \"" + file + "\", max_size = 1kB, max_backups = 1 }\n"
+	h := apptest.New(t, conf, NewBootstrapper(nil, nil))
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	req := httptest.NewRequest(http.MethodGet, h.Reverse(actionNameCpDashboard)+"?q=\"x\"", nil)
+	req.Header.Set("Referer", "http://localhost/cp")
+	req.Header.Set("User-Agent", "test-agent")
+	h.AssertStatus(h.Do(req), http.StatusOK)
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatalf("%s failed: %s", testName, err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	expected := regexp.MustCompile(`^\S+ - ` + testAdminUsername + ` \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET ` +
+		regexp.QuoteMeta(h.Reverse(actionNameCpDashboard)+`?q=\"x\" HTTP/1.1`) + `" 200 \d+ "http://localhost/cp" "test-agent"$`)
+	if last := lines[len(lines)-1]; !expected.MatchString(last) {
+		t.Fatalf("%s failed: unexpected line %s", testName, last)
+	}
+
+	// file is rotated once it exceeds max_size, at most max_backups rotated files are kept
+	for i := 0; i < 20; i++ {
+		h.Get(h.Reverse(actionNameCpDashboard))
+	}
+	if backups, _ := filepath.Glob(file + ".*"); len(backups) != 1 {
+		t.Fatalf("%s failed: expected 1 rotated file, found %v", testName, backups)
+	}
+}
+
+func TestLogMasking(t *testing.T) {
+	testName := "TestLogMasking"
+	// panics are logged with the stack trace and masked form data, error pages are redacted
+	h := apptest.New(t, apptest.SqliteInMemoryConfig, NewBootstrapper(nil, nil))
+	if goadmin.GetLogSanitizer() == nil {
+		t.Fatalf("%s failed: log masking should be enabled by default", testName)
+	}
+	h.Registry.EchoServer.POST("/test-panic", func(c echo.Context) error {
+		c.FormParams()
+		panic("boom")
+	})
+	h.Registry.EchoServer.GET("/test-error", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid address john.doe@example.com")
+	})
+	buf := &bytes.Buffer{}
+	log.SetOutput(buf)
+	resp := h.PostForm("/test-panic", url.Values{"username": {"admin"}, "password": {"s3cret"}})
+	log.SetOutput(os.Stderr)
+	h.AssertStatus(resp, http.StatusInternalServerError)
+	logged := buf.String()
+	if !strings.Contains(logged, "[ERROR] POST /test-panic: panic: boom") || !strings.Contains(logged, "username=admin") ||
+		!strings.Contains(logged, "password=***") || strings.Contains(logged, "s3cret") || !strings.Contains(logged, "goroutine") {
+		t.Fatalf("%s failed: unexpected log %s", testName, logged)
+	}
+	resp = h.Get("/test-error")
+	h.AssertStatus(resp, http.StatusBadRequest)
+	if body := resp.Body.String(); strings.Contains(body, "john.doe@") || !strings.Contains(body, "***@example.com") {
+		t.Fatalf("%s failed: email not masked in error page %s", testName, body)
+	}
+}
+
+func TestWellKnownFiles(t *testing.T) {
+	testName := "TestWellKnownFiles"
+	h := apptest.New(t, apptest.SqliteInMemoryConfig, NewBootstrapper(nil, nil))
+	resp := h.Get("/robots.txt")
+	h.AssertStatus(resp, http.StatusOK)
+	if body := resp.Body.String(); !strings.Contains(body, "User-agent: *\n") || !strings.Contains(body, "Disallow: /cp\n") {
+		t.Fatalf("%s failed: unexpected robots.txt %s", testName, body)
+	}
+	// security.txt is served only if a contact is configured
+	h.AssertStatus(h.Get("/.well-known/security.txt"), http.StatusNotFound)
+
+	conf := apptest.SqliteInMemoryConfig + `
+goadmin.robots_txt { disallow_cp = false, disallow = ["/private"], sitemap = "https://example.com/sitemap.xml" }
+goadmin.security_txt { contact = "mailto:security@example.com, https://example.com/report", preferred_languages = "en, vi", expires_after = 30d }
+`
+	h = apptest.New(t, conf, NewBootstrapper(nil, nil))
+	body := h.Get("/robots.txt").Body.String()
+	if strings.Contains(body, "/cp") || !strings.Contains(body, "Disallow: /private\n") || !strings.Contains(body, "Sitemap: https://example.com/sitemap.xml\n") {
+		t.Fatalf("%s failed: unexpected robots.txt %s", testName, body)
+	}
+	resp = h.Get("/.well-known/security.txt")
+	h.AssertStatus(resp, http.StatusOK)
+	body = resp.Body.String()
+	expires := time.Now().UTC().Add(30 * 24 * time.Hour).Truncate(24 * time.Hour).Format(time.RFC3339)
+	for _, line := range []string{"Contact: mailto:security@example.com\n", "Contact: https://example.com/report\n", "Preferred-Languages: en, vi\n", "Expires: " + expires + "\n"} {
+		if !strings.Contains(body, line) {
+			t.Fatalf("%s failed: expected [%s] in security.txt %s", testName, strings.TrimSpace(line), body)
+		}
+	}
+}
+
+func TestZeroConfig(t *testing.T) {
+	testName := "TestZeroConfig"
+	dataDir := t.TempDir()
+	for k, v := range map[string]string{"APP_CONFIG": filepath.Join(dataDir, "missing.conf"), "GA_DATA_DIR": dataDir} {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+	conf := goadmin.LoadAppConfig()
+	if conf.GetString("myapp.db.type") != "sqlite" || conf.GetString("myapp.db.sqlite.root") != filepath.Join(dataDir, "sqlite") || conf.GetInt32("http.listen_port") != 8080 {
+		t.Fatalf("%s failed: unexpected configuration %s", testName, conf.String())
+	}
+	sessionKey, pwd := conf.GetString("goadmin.session_key"), conf.GetString("myapp.init.admin_password")
+	if len(sessionKey) != 32 || pwd == "" {
+		t.Fatalf("%s failed: secrets were not generated: [%s] [%s]", testName, sessionKey, pwd)
+	}
+	credentialsFile := filepath.Join(dataDir, "admin-credentials.txt")
+	if data, err := ioutil.ReadFile(credentialsFile); err != nil || !strings.Contains(string(data), pwd) {
+		t.Fatalf("%s failed: credentials were not written: %s/%s", testName, data, err)
+	}
+
+	// secrets are kept across restarts, credentials are shown once
+	os.Remove(credentialsFile)
+	conf = goadmin.LoadAppConfig()
+	if conf.GetString("goadmin.session_key") != sessionKey || conf.GetString("myapp.init.admin_password") != pwd {
+		t.Fatalf("%s failed: secrets were generated again", testName)
+	}
+	if _, err := os.Stat(credentialsFile); !os.IsNotExist(err) {
+		t.Fatalf("%s failed: credentials must be written once", testName)
+	}
+}
+
+func TestActionCpEffectiveConfig(t *testing.T) {
+	h := _newHarness(t)
+	h.AssertRedirect(_loginWithUserAgent(h, testUserAgentChrome), h.Reverse(actionNameCpDashboard))
+	resp := h.Get(h.Reverse(actionNameCpEffectiveConfig))
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertBodyContains(resp, `myapp.db.type = "sqlite"`)
+	h.AssertBodyContains(resp, "goadmin.session_key = ***")
+	if strings.Contains(resp.Body.String(), "apptest_s3ss10n_k3y") {
+		t.Fatalf("TestActionCpEffectiveConfig failed: session key is not masked")
+	}
+}
+
+func TestTemplateOverrides(t *testing.T) {
+	testName := "TestTemplateOverrides"
+	overrideDir := t.TempDir()
+	ioutil.WriteFile(filepath.Join(overrideDir, "cp_config_bundle.html"), []byte(`{{define "page_css"}}{{end}}
+{{define "page_js"}}{{end}}
+{{define "page_content"}}<p>overridden-config-bundle-page</p>{{end}}`), 0600)
+	h := apptest.New(t, apptest.SqliteInMemoryConfig+"\nmyapp.views_override_dir = \""+overrideDir+"\"\n", NewBootstrapper(nil, nil))
+	h.AssertRedirect(_loginWithUserAgent(h, testUserAgentChrome), h.Reverse(actionNameCpDashboard))
+	resp := h.Get(h.Reverse(actionNameCpConfigBundle))
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertBodyContains(resp, "overridden-config-bundle-page")
+	// other templates, including the layout, are the packaged ones
+	resp = h.Get(h.Reverse(actionNameCpLoggingSettings))
+	h.AssertStatus(resp, http.StatusOK)
+	if strings.Contains(resp.Body.String(), "overridden-config-bundle-page") {
+		t.Fatalf("%s failed: override must only replace its own template", testName)
+	}
+
+	// overrides of templates that do not exist (e.g. renamed by an upgrade) are reported
+	ioutil.WriteFile(filepath.Join(overrideDir, "cp_no_such_page.html"), []byte(`{{define "page_content"}}{{end}}`), 0600)
+	renderer := newTemplateRenderer("./views/myapp", overrideDir, ".html", nil)
+	if err := renderer.checkOverrides(); err == nil {
+		t.Fatalf("%s failed: unknown override must be reported", testName)
+	}
+}
+
+// countingGroupDao counts lookups of single groups.
+type countingGroupDao struct {
+	GroupDao
+	gets int
+}
+
+func (dao *countingGroupDao) Get(id string) (*Group, error) {
+	dao.gets++
+	return dao.GroupDao.Get(id)
+}
+
+func TestActionCpUserList_GroupNames(t *testing.T) {
+	testName := "TestActionCpUserList_GroupNames"
+	h := _newHarness(t)
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	for i := 0; i < 3; i++ {
+		myReg.groupDao.Create(fmt.Sprintf("group%d", i), fmt.Sprintf("Group #%d", i))
+	}
+	for i := 0; i < 12; i++ {
+		myReg.userDao.Create(fmt.Sprintf("user%d@local", i), "", fmt.Sprintf("User %d", i), fmt.Sprintf("group%d", i%3))
+	}
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	dao := &countingGroupDao{GroupDao: myReg.groupDao}
+	myReg.groupDao = dao
+
+	resp := h.Get(h.Reverse(actionNameCpUsers))
+	h.AssertStatus(resp, http.StatusOK)
+	for i := 0; i < 3; i++ {
+		h.AssertBodyContains(resp, fmt.Sprintf("Group #%d", i))
+	}
+	resp = h.Get(h.Reverse(actionNameCpUsers) + "?format=json")
+	h.AssertBodyContains(resp, `"group_name":"Group #1"`)
+	if dao.gets != 0 {
+		t.Fatalf("%s failed: groups must be looked up once per list, got %d lookups of single groups", testName, dao.gets)
+	}
+}
+
+func TestRendererLookup_Concurrent(t *testing.T) {
+	testName := "TestRendererLookup_Concurrent"
+	h := _newHarness(t)
+	renderer := newTemplateRenderer("./views/myapp", "", ".html", h.Registry.Renderer.Funcs())
+	if err := renderer.preload(preloadedTemplateSets[:3]); err != nil {
+		t.Fatalf("%s failed: %s", testName, err)
+	}
+
+	// run with -race: template sets are looked up (and parsed, for those not preloaded) by concurrent requests
+	var wg sync.WaitGroup
+	errs := make(chan error, 16*len(preloadedTemplateSets))
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, tplNames := range preloadedTemplateSets {
+				if _, err := renderer.lookup(tplNames); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("%s failed: %s", testName, err)
+	}
+
+	first, _ := renderer.lookup("landing")
+	if second, _ := renderer.lookup("landing"); first != second {
+		t.Fatalf("%s failed: template sets must be parsed once", testName)
+	}
+	if _, err := renderer.lookup("no_such_template"); err == nil {
+		t.Fatalf("%s failed: error expected for unknown template", testName)
+	}
+}
+
+func TestBootstrap_ServerTuning(t *testing.T) {
+	testName := "TestBootstrap_ServerTuning"
+	conf := apptest.SqliteInMemoryConfig + "\nhttp.request_timeout = 7s\nserver.read_header_timeout = 3s\nserver.max_header_bytes = 64KiB\nserver.keep_alives = false\n"
+	h := apptest.New(t, conf, NewBootstrapper(nil, nil))
+	server := h.Echo.Server
+	if server.ReadTimeout != 7*time.Second || server.ReadHeaderTimeout != 3*time.Second || server.MaxHeaderBytes != 64*1024 {
+		t.Fatalf("%s failed: server is not tuned as configured (read %s, read header %s, max header bytes %d)", testName,
+			server.ReadTimeout, server.ReadHeaderTimeout, server.MaxHeaderBytes)
+	}
+}
+
+func TestBootstrap_ServerTuningDefaults(t *testing.T) {
+	testName := "TestBootstrap_ServerTuningDefaults"
+	h := apptest.New(t, apptest.SqliteInMemoryConfig, NewBootstrapper(nil, nil))
+	if server := h.Echo.Server; server.MaxHeaderBytes != http.DefaultMaxHeaderBytes {
+		t.Fatalf("%s failed: expected default max header bytes %d but received %d", testName, http.DefaultMaxHeaderBytes, server.MaxHeaderBytes)
+	}
+}
+
+func TestE2eMode(t *testing.T) {
+	testName := "TestE2eMode"
+	t.Cleanup(func() {
+		utils.FreezeClock(time.Time{})
+		utils.RestoreRandom()
+	})
+	conf := apptest.SqliteInMemoryConfig + "\ngoadmin.e2e { enabled = true, time = \"2022-03-04T05:06:07Z\", seed = 42 }\n"
+	frozen := time.Date(2022, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	// refused outside of dev mode and of environment "e2e"
+	t.Setenv("APP_ENV", "")
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("%s failed: end-to-end test mode must be refused outside of dev mode and of APP_ENV=e2e", testName)
+			}
+		}()
+		apptest.New(t, conf, NewBootstrapper(nil, nil))
+	}()
+	t.Setenv("APP_ENV", "e2e")
+
+	// every run of the same scenario renders the same redirects and records the same events
+	run := func() (string, *Event) {
+		h := apptest.New(t, conf, NewBootstrapper(nil, nil))
+		myReg := h.Registry.Get(namespace).(*myRegistry)
+		var created *Event
+		myReg.onEvent(func(event *Event) { created = event }, eventGroupCreated)
+		h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+		resp := h.PostForm(h.Reverse(actionNameCpCreateGroupSubmit), url.Values{"id": {"testers"}, "name": {"Testers"}})
+		h.AssertRedirect(resp, h.Reverse(actionNameCpGroups))
+		if created == nil {
+			t.Fatalf("%s failed: event [%s] not emitted", testName, eventGroupCreated)
+		}
+		return resp.Header().Get(echo.HeaderLocation), created
+	}
+	location1, event1 := run()
+	location2, event2 := run()
+	if location1 != location2 {
+		t.Fatalf("%s failed: expected same redirect on every run but received [%s] and [%s]", testName, location1, location2)
+	}
+	if event1.Id != event2.Id || !event1.Time.Equal(event2.Time) {
+		t.Fatalf("%s failed: expected same event on every run but received %#v and %#v", testName, event1, event2)
+	}
+	if !event1.Time.Equal(frozen) || !utils.Now().Equal(frozen) {
+		t.Fatalf("%s failed: expected clock frozen at %s but received %s", testName, frozen, event1.Time)
+	}
+}
@@ -0,0 +1,165 @@
+package myapp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// botGuardTokenField is the hidden form field carrying the time the form was rendered, signed.
+	botGuardTokenField = "_ft"
+
+	defaultBotGuardHoneypotField = "website"
+)
+
+// botGuard detects bots submitting public forms (e.g. the login form), which are rendered with two hidden fields:
+//   - a honeypot field, invisible to humans, that bots filling in every field fill in;
+//   - a signed timestamp, so that forms submitted too fast (scripted) or too late (replayed), or without the
+//     timestamp (posted without rendering the form), are detected.
+//
+// Clients detected as bots ban_threshold times within ban_duration are banned for ban_duration: their submissions are
+// answered with 429. Detections and bans are stored in the shared cache (setting goadmin.cache), so that they are
+// shared by all instances.
+//
+// available since template-r5
+type botGuard struct {
+	r             *myRegistry
+	key           []byte
+	honeypotField string
+	minFillTime   time.Duration
+	maxFillTime   time.Duration
+	banThreshold  int
+	banDuration   time.Duration
+}
+
+// BotGuardForm holds the hidden fields rendered in forms protected by botGuard.
+//
+// available since template-r5
+type BotGuardForm struct {
+	HoneypotField string
+	TokenField    string
+	Token         string
+}
+
+// newBotGuard creates a botGuard from the configuration block myapp.bot_guard, nil is returned if bot detection is
+// disabled.
+func newBotGuard(r *myRegistry) *botGuard {
+	conf := r.AppConfig
+	if !conf.GetBoolean(namespace+".bot_guard.enabled", true) {
+		return nil
+	}
+	// timestamps are signed with a key derived from the session key, they do not need to survive a key change
+	mac := hmac.New(sha256.New, []byte(conf.GetString("goadmin.session_key", "")))
+	mac.Write([]byte(namespace + ":bot_guard"))
+	return &botGuard{
+		r:             r,
+		key:           mac.Sum(nil),
+		honeypotField: conf.GetString(namespace+".bot_guard.honeypot_field", defaultBotGuardHoneypotField),
+		minFillTime:   conf.GetTimeDuration(namespace+".bot_guard.min_fill_time", 2*time.Second),
+		maxFillTime:   conf.GetTimeDuration(namespace+".bot_guard.max_fill_time", 2*time.Hour),
+		banThreshold:  int(conf.GetInt32(namespace+".bot_guard.ban_threshold", 3)),
+		banDuration:   conf.GetTimeDuration(namespace+".bot_guard.ban_duration", 15*time.Minute),
+	}
+}
+
+func (g *botGuard) sign(ts string) string {
+	mac := hmac.New(sha256.New, g.key)
+	mac.Write([]byte(ts))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// form returns the hidden fields of a newly rendered form.
+func (g *botGuard) form() *BotGuardForm {
+	ts := strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 36)
+	return &BotGuardForm{HoneypotField: g.honeypotField, TokenField: botGuardTokenField, Token: ts + "." + g.sign(ts)}
+}
+
+// check returns the reason why a submitted form looks submitted by a bot, empty if it does not.
+func (g *botGuard) check(c echo.Context) string {
+	if c.FormValue(g.honeypotField) != "" {
+		return "honeypot field filled in"
+	}
+	tokens := strings.SplitN(c.FormValue(botGuardTokenField), ".", 2)
+	if len(tokens) != 2 || !hmac.Equal([]byte(tokens[1]), []byte(g.sign(tokens[0]))) {
+		return "missing or invalid form timestamp"
+	}
+	ms, err := strconv.ParseInt(tokens[0], 36, 64)
+	if err != nil {
+		return "missing or invalid form timestamp"
+	}
+	elapsed := time.Since(time.Unix(0, ms*int64(time.Millisecond)))
+	if elapsed < g.minFillTime {
+		return "form submitted after " + elapsed.Round(time.Millisecond).String()
+	}
+	if g.maxFillTime > 0 && elapsed > g.maxFillTime {
+		return "form submitted after " + elapsed.Round(time.Second).String()
+	}
+	return ""
+}
+
+func (g *botGuard) cacheKey(kind, ip string) string {
+	return namespace + ":bot_guard:" + kind + ":" + ip
+}
+
+// isBanned checks if a client IP is temporarily banned.
+func (g *botGuard) isBanned(ip string) bool {
+	v, err := g.r.Cache.Get(g.cacheKey("ban", ip))
+	return err == nil && v != nil
+}
+
+// recordDetection counts a bot detection and bans the client IP once the threshold is reached. The count is not
+// atomic across instances: a few extra detections before a ban are harmless.
+func (g *botGuard) recordDetection(ip string) {
+	key := g.cacheKey("hits", ip)
+	hits := 0
+	if v, err := g.r.Cache.Get(key); err == nil && v != nil {
+		hits, _ = strconv.Atoi(string(v))
+	}
+	hits++
+	if hits < g.banThreshold {
+		g.r.Cache.Set(key, []byte(strconv.Itoa(hits)), g.banDuration)
+		return
+	}
+	g.r.Cache.Delete(key)
+	if err := g.r.Cache.Set(g.cacheKey("ban", ip), []byte(time.Now().Format(time.RFC3339)), g.banDuration); err != nil {
+		log.Printf("[ERROR] cannot ban IP %s: %s", ip, err)
+		return
+	}
+	log.Printf("[WARN] IP %s banned for %s after %d bot detection(s)", ip, g.banDuration, hits)
+}
+
+// middleware protects the submission of a form rendered with botGuard.form: bots are answered with 400, banned
+// clients with 429.
+func (g *botGuard) middleware(formName string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ip := c.RealIP()
+			if g.isBanned(ip) {
+				c.Response().Header().Set("Retry-After", strconv.Itoa(int(g.banDuration.Seconds())))
+				return echo.NewHTTPError(http.StatusTooManyRequests)
+			}
+			if reason := g.check(c); reason != "" {
+				log.Printf("[WARN] bot detected on form [%s] from %s: %s", formName, clientOrigin(c), reason)
+				g.recordDetection(ip)
+				return echo.NewHTTPError(http.StatusBadRequest)
+			}
+			return next(c)
+		}
+	}
+}
+
+// botGuardForm returns the hidden fields of a form protected against bots, nil if bot detection is disabled.
+func (r *myRegistry) botGuardForm() *BotGuardForm {
+	if r.botGuard == nil {
+		return nil
+	}
+	return r.botGuard.form()
+}
@@ -0,0 +1,67 @@
+package myapp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"main/src/apptest"
+)
+
+func TestBotGuard(t *testing.T) {
+	testName := "TestBotGuard"
+	conf := apptest.SqliteInMemoryConfig + "\nmyapp.bot_guard { enabled = true, min_fill_time = 0s, ban_threshold = 2 }\n"
+	h := apptest.New(t, conf, NewBootstrapper(nil, nil))
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpLogin)), http.StatusOK)
+	form, ok := h.LastData()["botGuard"].(*BotGuardForm)
+	if !ok || form == nil || form.Token == "" {
+		t.Fatalf("%s failed: login form rendered without bot guard fields", testName)
+	}
+	submit := func(extra url.Values) *httptest.ResponseRecorder {
+		values := url.Values{"username": {testAdminUsername}, "password": {testAdminPassword}}
+		for k, v := range extra {
+			values[k] = v
+		}
+		return h.PostForm(h.Reverse(actionNameCpLoginSubmit), values)
+	}
+	h.AssertStatus(submit(url.Values{form.TokenField: {form.Token}}), http.StatusFound)
+
+	// forms submitted too fast
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	myReg.botGuard.minFillTime = time.Hour
+	h.AssertStatus(submit(url.Values{form.TokenField: {form.Token}}), http.StatusBadRequest)
+	myReg.botGuard.minFillTime = 0
+
+	// honeypot filled in, then the client is banned after the second detection
+	h.AssertStatus(submit(url.Values{form.TokenField: {form.Token}, form.HoneypotField: {"http://spam.example"}}), http.StatusBadRequest)
+	resp := submit(url.Values{form.TokenField: {form.Token}})
+	h.AssertStatus(resp, http.StatusTooManyRequests)
+	if resp.Header().Get("Retry-After") == "" {
+		t.Fatalf("%s failed: Retry-After header expected", testName)
+	}
+
+	// forged or missing timestamps
+	h.Registry.Cache.Delete(myReg.botGuard.cacheKey("ban", "192.0.2.1"))
+	h.AssertStatus(submit(url.Values{form.TokenField: {"abc." + strings.Repeat("0", 32)}}), http.StatusBadRequest)
+	h.AssertStatus(submit(nil), http.StatusBadRequest)
+	h.AssertStatus(submit(url.Values{form.TokenField: {form.Token}}), http.StatusTooManyRequests)
+
+	// banned clients cannot pretend to be someone else with X-Forwarded-For, unless they are trusted proxies
+	submitFrom := func(ip string) *httptest.ResponseRecorder {
+		values := url.Values{"username": {testAdminUsername}, "password": {testAdminPassword}, form.TokenField: {form.Token}}
+		req := httptest.NewRequest(http.MethodPost, h.Reverse(actionNameCpLoginSubmit), strings.NewReader(values.Encode()))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		req.Header.Set(echo.HeaderXForwardedFor, ip)
+		req.Header.Set(echo.HeaderXRealIP, ip)
+		return h.Do(req)
+	}
+	h.AssertStatus(submitFrom("198.51.100.7"), http.StatusTooManyRequests)
+	h = apptest.New(t, conf+"\nhttp.trusted_proxies = [\"192.0.2.0/24\"]\n", NewBootstrapper(nil, nil))
+	myReg = h.Registry.Get(namespace).(*myRegistry)
+	h.Registry.Cache.Set(myReg.botGuard.cacheKey("ban", "192.0.2.1"), []byte("banned"), time.Hour)
+	h.AssertStatus(submitFrom("198.51.100.7"), http.StatusFound)
+}
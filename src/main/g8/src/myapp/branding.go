@@ -0,0 +1,360 @@
+package myapp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/btnguyen2k/goyai"
+	"github.com/labstack/echo/v4"
+	"main/src/goadmin"
+	"main/src/utils"
+)
+
+const (
+	// settingIdBranding is the id of the setting that stores the branding of the control panel.
+	settingIdBranding = "branding"
+	// settingIdLoginPage is the id of the setting that stores the content of the login page.
+	settingIdLoginPage = "login_page"
+
+	brandingAssetLogo            = "logo"
+	brandingAssetFavicon         = "favicon"
+	brandingAssetLoginBackground = "login_background"
+
+	maxSiteNameLength = 64
+)
+
+// brandingAsset describes how an uploaded branding image is stored, see brandingAssetFile.
+type brandingAsset struct {
+	size   goadmin.ImageSize
+	format string
+}
+
+// brandingAssets lists the branding images that can be uploaded.
+var brandingAssets = map[string]brandingAsset{
+	brandingAssetLogo:            {size: goadmin.ImageSize{Name: "default", Width: 256, Height: 256}, format: goadmin.ImageFormatPng},
+	brandingAssetFavicon:         {size: goadmin.ImageSize{Name: "default", Width: 64, Height: 64, Crop: true}, format: goadmin.ImageFormatPng},
+	brandingAssetLoginBackground: {size: goadmin.ImageSize{Name: "default", Width: 1920, Height: 1080}, format: goadmin.ImageFormatJpeg},
+}
+
+// reAccentColor matches accent colors, in hex notation.
+var reAccentColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// BrandingSettings customizes the control panel of a deployment (site name, logo, favicon and accent color), managed
+// at /cp/settings/branding. Empty values fall back to the application's defaults.
+//
+// available since template-r5
+type BrandingSettings struct {
+	SiteName    string `json:"site_name"`    // replaces app.name and app.shortname in pages
+	AccentColor string `json:"accent_color"` // e.g. "#0d6efd", empty to keep the theme's color
+	HasLogo     bool   `json:"has_logo"`
+	HasFavicon  bool   `json:"has_favicon"`
+	Version     int64  `json:"version"` // changed whenever images are uploaded, so that browsers do not keep stale ones
+}
+
+// validate normalizes the settings.
+func (s *BrandingSettings) validate() {
+	s.SiteName = strings.TrimSpace(s.SiteName)
+	if len([]rune(s.SiteName)) > maxSiteNameLength {
+		s.SiteName = string([]rune(s.SiteName)[:maxSiteNameLength])
+	}
+	if s.AccentColor = strings.ToLower(strings.TrimSpace(s.AccentColor)); !reAccentColor.MatchString(s.AccentColor) {
+		s.AccentColor = ""
+	}
+}
+
+// brandingModel is the branding passed to templates as view data "branding".
+type brandingModel struct {
+	Name        string // site name, or app.name
+	ShortName   string // site name, or app.shortname
+	LogoUrl     string // empty if no logo is uploaded
+	FaviconUrl  string // empty if no favicon is uploaded
+	AccentColor string
+}
+
+// brandingSettings returns the branding saved by admins, the application's defaults if none.
+func (r *myRegistry) brandingSettings() (*BrandingSettings, error) {
+	settings := &BrandingSettings{}
+	if _, err := r.loadSetting(settingIdBranding, settings); err != nil {
+		return &BrandingSettings{}, err
+	}
+	settings.validate()
+	return settings, nil
+}
+
+// branding builds view data "branding", injected into all pages by the renderer.
+func (r *myRegistry) branding() *brandingModel {
+	settings, err := r.brandingSettings()
+	if err != nil {
+		log.Printf("[ERROR] cannot load setting [%s]: %s", settingIdBranding, err)
+	}
+	model := &brandingModel{
+		Name:        r.AppConfig.GetString("app.name", ""),
+		ShortName:   r.AppConfig.GetString("app.shortname", ""),
+		AccentColor: settings.AccentColor,
+	}
+	if settings.SiteName != "" {
+		model.Name, model.ShortName = settings.SiteName, settings.SiteName
+	}
+	if settings.HasLogo {
+		model.LogoUrl = fmt.Sprintf("%s?v=%d", r.Reverse(actionNameBrandingAsset, brandingAssetLogo), settings.Version)
+	}
+	if settings.HasFavicon {
+		model.FaviconUrl = fmt.Sprintf("%s?v=%d", r.Reverse(actionNameBrandingAsset, brandingAssetFavicon), settings.Version)
+	}
+	return model
+}
+
+// brandingAssetFile returns the name of an uploaded branding image in the file store.
+func brandingAssetFile(asset string) string {
+	return goadmin.ImageFileName("branding/"+asset, brandingAssets[asset].size.Name, brandingAssets[asset].format)
+}
+
+// storeBrandingAssets processes the branding images uploaded with a multipart form (fields named after the images,
+// e.g. "logo", only the supplied ones are accepted), values of other fields are returned along with the set of
+// uploaded images.
+func (r *myRegistry) storeBrandingAssets(c echo.Context, fields ...string) (url.Values, map[string]bool, error) {
+	allowed, uploaded := make(map[string]bool), make(map[string]bool)
+	for _, field := range fields {
+		allowed[field] = true
+	}
+	formData, err := goadmin.StreamUpload(c, func(field, filename string, content io.Reader) error {
+		asset, ok := brandingAssets[field]
+		if !ok || filename == "" || !allowed[field] {
+			_, err := io.Copy(ioutil.Discard, content)
+			return err
+		}
+		data, err := ioutil.ReadAll(content)
+		if err != nil || len(data) == 0 {
+			// no file selected
+			return err
+		}
+		uploaded[field] = true
+		_, err = goadmin.ProcessImage(r.FileStore, "branding/"+field, bytes.NewReader(data), []goadmin.ImageSize{asset.size}, asset.format)
+		return err
+	})
+	return formData, uploaded, err
+}
+
+// updateBrandingAssets flags uploaded images as available, and removes the ones requested by form fields
+// "remove_<image>".
+func (r *myRegistry) updateBrandingAssets(formData url.Values, uploaded map[string]bool, flags map[string]*bool) {
+	for asset, has := range flags {
+		if uploaded[asset] {
+			*has = true
+		} else if formData.Get("remove_"+asset) == "1" {
+			*has = false
+			if err := r.FileStore.Delete(brandingAssetFile(asset)); err != nil {
+				log.Printf("[WARN] cannot delete branding image [%s]: %s", asset, err)
+			}
+		}
+	}
+}
+
+// actionBrandingAsset serves an uploaded branding image. It is public, as the login page is branded too.
+//
+// available since template-r5
+func actionBrandingAsset(c echo.Context) error {
+	asset := c.Param("asset")
+	if _, ok := brandingAssets[asset]; !ok {
+		return echo.ErrNotFound
+	}
+	f, err := getRegistry(c).FileStore.Open(brandingAssetFile(asset))
+	if os.IsNotExist(err) {
+		// e.g. settings imported from another environment, images are not part of configuration bundles
+		return echo.ErrNotFound
+	}
+	if err != nil {
+		log.Printf("[ERROR] cannot open branding image [%s]: %s", asset, err)
+		return echo.ErrInternalServerError
+	}
+	defer f.Close()
+	// URLs are versioned, see BrandingSettings.Version
+	c.Response().Header().Set(echo.HeaderCacheControl, "public, max-age=86400")
+	return c.Stream(http.StatusOK, "image/"+brandingAssets[asset].format, f)
+}
+
+// actionCpBrandingSettings renders the branding settings page.
+//
+// available since template-r5
+func actionCpBrandingSettings(c echo.Context) error {
+	if err := checkCpManageSecurity(c); err != nil {
+		AddFlashText(c, FlashWarning, err.Error())
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpDashboard))
+	}
+	var errMsg string
+	myReg := getRegistry(c)
+	settings, err := myReg.brandingSettings()
+	if err != nil {
+		errMsg = getI18n(c).Localize(getContextString(c, ctxLocale), "error_db_001", &goyai.LocalizeConfig{
+			TemplateData: map[string]interface{}{"err": err.Error()},
+		})
+	}
+	loginPage, err := myReg.loginPageSettings()
+	if err != nil && errMsg == "" {
+		errMsg = getI18n(c).Localize(getContextString(c, ctxLocale), "error_db_001", &goyai.LocalizeConfig{
+			TemplateData: map[string]interface{}{"err": err.Error()},
+		})
+	}
+	return c.Render(http.StatusOK, namespace+":layout:cp_branding_settings", map[string]interface{}{
+		"active":    "branding",
+		"settings":  settings,
+		"loginPage": loginPage,
+		"error":     errMsg,
+	})
+}
+
+// actionCpBrandingSettingsSubmit saves the branding settings (multipart form): site name, accent color, and logo and
+// favicon images (fields "logo" and "favicon", kept if not uploaded, removed with "remove_logo"/"remove_favicon").
+//
+// available since template-r5
+func actionCpBrandingSettingsSubmit(c echo.Context) error {
+	if err := checkCpManageSecurity(c); err != nil {
+		AddFlashText(c, FlashWarning, err.Error())
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpDashboard))
+	}
+	myReg := getRegistry(c)
+	settings, err := myReg.brandingSettings()
+	if err != nil {
+		AddFlash(c, FlashError, "error_db_001", "err", settingIdBranding+"/"+err.Error())
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpBrandingSettings)+"?r="+utils.RandomString(4))
+	}
+	formData, uploaded, err := myReg.storeBrandingAssets(c, brandingAssetLogo, brandingAssetFavicon)
+	if err != nil {
+		AddFlashText(c, FlashError, getI18n(c).Localize(getContextString(c, ctxLocale), "error_invalid_branding_image", &goyai.LocalizeConfig{
+			TemplateData: map[string]interface{}{"err": err.Error()},
+		}))
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpBrandingSettings)+"?r="+utils.RandomString(4))
+	}
+	accentColor := strings.TrimSpace(formData.Get("accent_color"))
+	if accentColor != "" && !reAccentColor.MatchString(accentColor) {
+		AddFlash(c, FlashError, "error_invalid_accent_color")
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpBrandingSettings)+"?r="+utils.RandomString(4))
+	}
+	settings.SiteName, settings.AccentColor = formData.Get("site_name"), accentColor
+	myReg.updateBrandingAssets(formData, uploaded, map[string]*bool{brandingAssetLogo: &settings.HasLogo, brandingAssetFavicon: &settings.HasFavicon})
+	if len(uploaded) > 0 {
+		settings.Version = utils.Now().Unix()
+	}
+	settings.validate()
+	if err := myReg.saveSetting(settingIdBranding, settings); err != nil {
+		AddFlash(c, FlashError, "error_db_001", "err", settingIdBranding+"/"+err.Error())
+	} else {
+		myReg.outputCache.invalidate()
+		currentUser, _ := getCurrentUser(c)
+		if currentUser != nil {
+			myReg.auditf("user [%s] updated branding settings", currentUser.Username)
+		}
+		AddFlash(c, FlashInfo, "update_branding_settings_successful")
+	}
+	return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpBrandingSettings)+"?r="+utils.RandomString(4))
+}
+
+/*----------------------------------------------------------------------*/
+
+// LoginPageSettings is the content of the login page, managed at /cp/settings/branding.
+//
+// available since template-r5
+type LoginPageSettings struct {
+	WelcomeText    string `json:"welcome_text"`    // Markdown, shown above the login form
+	NoticeText     string `json:"notice_text"`     // legal/compliance notice (Markdown), shown below the login form
+	NoticeRequired bool   `json:"notice_required"` // users must tick a checkbox accepting the notice to sign in
+	HasBackground  bool   `json:"has_background"`
+	Version        int64  `json:"version"` // changed whenever the background image is uploaded
+}
+
+// validate normalizes the settings: the notice can not be required if there is none.
+func (s *LoginPageSettings) validate() {
+	s.WelcomeText = strings.TrimSpace(s.WelcomeText)
+	s.NoticeText = strings.TrimSpace(s.NoticeText)
+	s.NoticeRequired = s.NoticeRequired && s.NoticeText != ""
+}
+
+// loginPageModel is the content of the login page passed to templates as view data "loginPage".
+type loginPageModel struct {
+	WelcomeText    string
+	NoticeText     string
+	NoticeRequired bool
+	BackgroundUrl  string // empty if no background image is uploaded
+}
+
+// loginPageSettings returns the content of the login page saved by admins, empty if none.
+func (r *myRegistry) loginPageSettings() (*LoginPageSettings, error) {
+	settings := &LoginPageSettings{}
+	if _, err := r.loadSetting(settingIdLoginPage, settings); err != nil {
+		return &LoginPageSettings{}, err
+	}
+	settings.validate()
+	return settings, nil
+}
+
+// loginPage builds view data "loginPage" of the pages users sign in with.
+func (r *myRegistry) loginPage() *loginPageModel {
+	settings, err := r.loginPageSettings()
+	if err != nil {
+		log.Printf("[ERROR] cannot load setting [%s]: %s", settingIdLoginPage, err)
+	}
+	model := &loginPageModel{WelcomeText: settings.WelcomeText, NoticeText: settings.NoticeText, NoticeRequired: settings.NoticeRequired}
+	if settings.HasBackground {
+		model.BackgroundUrl = fmt.Sprintf("%s?v=%d", r.Reverse(actionNameBrandingAsset, brandingAssetLoginBackground), settings.Version)
+	}
+	return model
+}
+
+// checkLoginNotice returns false if the user must accept the notice of the login page to sign in but did not (form
+// field "accept_notice").
+func (r *myRegistry) checkLoginNotice(c echo.Context) bool {
+	settings, err := r.loginPageSettings()
+	if err != nil {
+		log.Printf("[ERROR] cannot load setting [%s]: %s", settingIdLoginPage, err)
+	}
+	return !settings.NoticeRequired || c.FormValue("accept_notice") == "1"
+}
+
+// actionCpLoginPageSubmit saves the content of the login page (multipart form): welcome text, notice, and
+// background image (field "login_background", kept if not uploaded, removed with "remove_login_background").
+//
+// available since template-r5
+func actionCpLoginPageSubmit(c echo.Context) error {
+	if err := checkCpManageSecurity(c); err != nil {
+		AddFlashText(c, FlashWarning, err.Error())
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpDashboard))
+	}
+	myReg := getRegistry(c)
+	settings, err := myReg.loginPageSettings()
+	if err != nil {
+		AddFlash(c, FlashError, "error_db_001", "err", settingIdLoginPage+"/"+err.Error())
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpBrandingSettings)+"?r="+utils.RandomString(4))
+	}
+	formData, uploaded, err := myReg.storeBrandingAssets(c, brandingAssetLoginBackground)
+	if err != nil {
+		AddFlashText(c, FlashError, getI18n(c).Localize(getContextString(c, ctxLocale), "error_invalid_branding_image", &goyai.LocalizeConfig{
+			TemplateData: map[string]interface{}{"err": err.Error()},
+		}))
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpBrandingSettings)+"?r="+utils.RandomString(4))
+	}
+	settings.WelcomeText, settings.NoticeText = formData.Get("welcome_text"), formData.Get("notice_text")
+	settings.NoticeRequired = formData.Get("notice_required") == "1"
+	myReg.updateBrandingAssets(formData, uploaded, map[string]*bool{brandingAssetLoginBackground: &settings.HasBackground})
+	if len(uploaded) > 0 {
+		settings.Version = utils.Now().Unix()
+	}
+	settings.validate()
+	if err := myReg.saveSetting(settingIdLoginPage, settings); err != nil {
+		AddFlash(c, FlashError, "error_db_001", "err", settingIdLoginPage+"/"+err.Error())
+	} else {
+		currentUser, _ := getCurrentUser(c)
+		if currentUser != nil {
+			myReg.auditf("user [%s] updated the login page (notice required: %v)", currentUser.Username, settings.NoticeRequired)
+		}
+		AddFlash(c, FlashInfo, "update_login_page_successful")
+	}
+	return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpBrandingSettings)+"?r="+utils.RandomString(4))
+}
@@ -0,0 +1,103 @@
+package myapp
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"main/src/apptest"
+)
+
+func TestActionCpBrandingSettings(t *testing.T) {
+	testName := "TestActionCpBrandingSettings"
+	conf := apptest.SqliteInMemoryConfig + "\ngoadmin.file_store_dir = \"" + t.TempDir() + "\"\n"
+	h := apptest.New(t, conf, NewBootstrapper(nil, nil))
+	logoUrl := h.Reverse(actionNameBrandingAsset, brandingAssetLogo)
+	h.AssertStatus(h.Get(logoUrl), http.StatusNotFound)
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	w.WriteField("site_name", "Acme Console")
+	w.WriteField("accent_color", "#FF6600")
+	fw, _ := w.CreateFormFile("logo", "logo.png")
+	png.Encode(fw, image.NewNRGBA(image.Rect(0, 0, 512, 128)))
+	w.Close()
+	req := httptest.NewRequest(http.MethodPost, h.Reverse(actionNameCpBrandingSettingsSubmit), body)
+	req.Header.Set(echo.HeaderContentType, w.FormDataContentType())
+	h.AssertRedirect(h.Do(req), h.Reverse(actionNameCpBrandingSettings))
+
+	resp := h.Get(h.Reverse(actionNameCpDashboard))
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertBodyContains(resp, "Acme Console")
+	h.AssertBodyContains(resp, "#ff6600")
+	h.AssertBodyContains(resp, logoUrl+"?v=")
+
+	// the logo is public and fits within 256x256
+	h.PostForm(h.Reverse(actionNameCpLogout), url.Values{})
+	resp = h.Get(logoUrl)
+	h.AssertStatus(resp, http.StatusOK)
+	if cfg, _, err := image.DecodeConfig(resp.Body); err != nil || cfg.Width != 256 || cfg.Height != 64 {
+		t.Fatalf("%s failed: expected 256x64 logo but received %#v / %v", testName, cfg, err)
+	}
+	resp = h.Get(h.Reverse(actionNameCpLogin))
+	h.AssertBodyContains(resp, "Acme Console")
+
+	// invalid colors are rejected
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	body = &bytes.Buffer{}
+	w = multipart.NewWriter(body)
+	w.WriteField("accent_color", "red;}")
+	w.Close()
+	req = httptest.NewRequest(http.MethodPost, h.Reverse(actionNameCpBrandingSettingsSubmit), body)
+	req.Header.Set(echo.HeaderContentType, w.FormDataContentType())
+	h.AssertRedirect(h.Do(req), h.Reverse(actionNameCpBrandingSettings))
+	if settings, _ := h.Registry.Get(namespace).(*myRegistry).brandingSettings(); settings.AccentColor != "#ff6600" {
+		t.Fatalf("%s failed: invalid accent color must not be saved, got [%s]", testName, settings.AccentColor)
+	}
+}
+
+func TestLoginPageSettings(t *testing.T) {
+	testName := "TestLoginPageSettings"
+	conf := apptest.SqliteInMemoryConfig + "\ngoadmin.file_store_dir = \"" + t.TempDir() + "\"\n"
+	h := apptest.New(t, conf, NewBootstrapper(nil, nil))
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	w.WriteField("welcome_text", "Welcome to **Acme** console")
+	w.WriteField("notice_text", "Authorized use only")
+	w.WriteField("notice_required", "1")
+	fw, _ := w.CreateFormFile("login_background", "background.png")
+	png.Encode(fw, image.NewNRGBA(image.Rect(0, 0, 64, 64)))
+	w.Close()
+	req := httptest.NewRequest(http.MethodPost, h.Reverse(actionNameCpLoginPageSubmit), body)
+	req.Header.Set(echo.HeaderContentType, w.FormDataContentType())
+	h.AssertRedirect(h.Do(req), h.Reverse(actionNameCpBrandingSettings))
+
+	h.PostForm(h.Reverse(actionNameCpLogout), url.Values{})
+	resp := h.Get(h.Reverse(actionNameCpLogin))
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertBodyContains(resp, "<strong>Acme</strong>")
+	h.AssertBodyContains(resp, "Authorized use only")
+	h.AssertBodyContains(resp, h.Reverse(actionNameBrandingAsset, brandingAssetLoginBackground)+"?v=")
+	resp = h.Get(h.Reverse(actionNameBrandingAsset, brandingAssetLoginBackground))
+	h.AssertStatus(resp, http.StatusOK)
+	if ct := resp.Header().Get(echo.HeaderContentType); ct != "image/jpeg" {
+		t.Fatalf("%s failed: expected image/jpeg background but received %s", testName, ct)
+	}
+
+	// the notice must be accepted to sign in
+	form := url.Values{"username": {testAdminUsername}, "password": {testAdminPassword}}
+	resp = h.PostForm(h.Reverse(actionNameCpLoginSubmit), form)
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertBodyContains(resp, "Please accept the notice to sign in")
+	form.Set("accept_notice", "1")
+	h.AssertRedirect(h.PostForm(h.Reverse(actionNameCpLoginSubmit), form), h.Reverse(actionNameCpDashboard))
+}
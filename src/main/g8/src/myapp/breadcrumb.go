@@ -0,0 +1,97 @@
+package myapp
+
+import (
+	"github.com/btnguyen2k/goyai"
+	"github.com/labstack/echo/v4"
+)
+
+// ctxBreadcrumbs is the context key of breadcrumb entries added by handlers of the current request.
+const ctxBreadcrumbs = "bc"
+
+// BreadcrumbItem is an entry of the breadcrumb trail of a page.
+//
+// available since template-r5
+type BreadcrumbItem struct {
+	Title string // localized title
+	Url   string // empty for the current page
+}
+
+// cpSection is a section of the control panel, i.e. an item of the sidebar menu.
+type cpSection struct {
+	name       string // value of view data "active", which also highlights the sidebar menu item
+	actionName string
+	i18nKey    string
+	icon       string
+	permission string // permission required to access the section, empty if none
+}
+
+// cpSections lists sections of the control panel, in the order of the sidebar menu. A section is the root of
+// breadcrumb trails of its pages, right after "home".
+var cpSections = []cpSection{
+	{name: "dashboard", actionName: actionNameCpDashboard, i18nKey: "dashboard", icon: "fas fa-tachometer-alt"},
+	{name: "users", actionName: actionNameCpUsers, i18nKey: "users", icon: "fas fa-user-alt"},
+	{name: "groups", actionName: actionNameCpGroups, i18nKey: "groups", icon: "fas fa-users"},
+	{name: "stats", actionName: actionNameCpStats, i18nKey: "statistics", icon: "fas fa-chart-bar"},
+	{name: "analytics", actionName: actionNameCpAnalytics, i18nKey: "analytics", icon: "fas fa-chart-line", permission: permAnalyticsView},
+	{name: "tasks", actionName: actionNameCpTasks, i18nKey: "tasks", icon: "fas fa-tasks"},
+	{name: "emails", actionName: actionNameCpEmails, i18nKey: "emails", icon: "fas fa-envelope", permission: permEmailManage},
+	{name: "tokens", actionName: actionNameCpTokens, i18nKey: "tokens", icon: "fas fa-key"},
+	{name: "pages", actionName: actionNameCpPages, i18nKey: "pages", icon: "fas fa-newspaper", permission: permPageManage},
+	{name: "inbox", actionName: actionNameCpInbox, i18nKey: "inbox", icon: "fas fa-inbox", permission: permInboxManage},
+	{name: "translations", actionName: actionNameCpTranslations, i18nKey: "translations", icon: "fas fa-language", permission: permTranslationManage},
+	{name: "security", actionName: actionNameCpSecuritySettings, i18nKey: "security_settings", icon: "fas fa-shield-alt", permission: permSettingsManage},
+	{name: "branding", actionName: actionNameCpBrandingSettings, i18nKey: "branding_settings", icon: "fas fa-palette", permission: permSettingsManage},
+	{name: "terms", actionName: actionNameCpTermsSettings, i18nKey: "terms_settings", icon: "fas fa-file-contract", permission: permSettingsManage},
+	{name: "logging", actionName: actionNameCpLoggingSettings, i18nKey: "logging_settings", icon: "fas fa-file-alt", permission: permSettingsManage},
+	{name: "config_bundle", actionName: actionNameCpConfigBundle, i18nKey: "config_bundle", icon: "fas fa-file-export", permission: permSettingsManage},
+	{name: "retention", actionName: actionNameCpRetentionSettings, i18nKey: "retention_settings", icon: "fas fa-history", permission: permSettingsManage},
+	{name: "help", actionName: actionNameCpHelp, i18nKey: "help", icon: "fas fa-question-circle"},
+	{name: "profile", actionName: actionNameCpProfile, i18nKey: "profile", icon: "fas fa-user-circle"},
+}
+
+// findCpSection returns the section of the control panel with the supplied name, nil if not found.
+func findCpSection(name string) *cpSection {
+	for i := range cpSections {
+		if cpSections[i].name == name {
+			return &cpSections[i]
+		}
+	}
+	return nil
+}
+
+// AddBreadcrumb appends an entry to the breadcrumb trail of the current page, below the page's section (see
+// cpSections). url is empty for the current page; args are pairs of template data of the i18n message (see AddFlash).
+//
+// The title of the last entry is the page title.
+//
+// available since template-r5
+func AddBreadcrumb(c echo.Context, url, i18nKey string, args ...interface{}) {
+	title := getI18n(c).Localize(getContextString(c, ctxLocale), i18nKey, &goyai.LocalizeConfig{TemplateData: templateDataOf(args...)})
+	AddBreadcrumbText(c, url, title)
+}
+
+// AddBreadcrumbText is like AddBreadcrumb, but the title is already localized (e.g. title of a help page).
+//
+// available since template-r5
+func AddBreadcrumbText(c echo.Context, url, title string) {
+	items, _ := c.Get(ctxBreadcrumbs).([]*BreadcrumbItem)
+	c.Set(ctxBreadcrumbs, append(items, &BreadcrumbItem{Title: title, Url: url}))
+}
+
+// buildBreadcrumbs builds the breadcrumb trail of the current page: "home", the section of the page then entries
+// added by handlers. It also returns the page title, which is the title of the last entry.
+func buildBreadcrumbs(c echo.Context, section string) ([]*BreadcrumbItem, string) {
+	myReg, locale := getRegistry(c), getContextString(c, ctxLocale)
+	items := []*BreadcrumbItem{{Title: myReg.i18n.Localize(locale, "home"), Url: myReg.Reverse(actionNameCpDashboard)}}
+	if s := findCpSection(section); s != nil {
+		items = append(items, &BreadcrumbItem{Title: myReg.i18n.Localize(locale, s.i18nKey), Url: myReg.Reverse(s.actionName)})
+	}
+	added, _ := c.Get(ctxBreadcrumbs).([]*BreadcrumbItem)
+	items = append(items, added...)
+	last := items[len(items)-1]
+	if len(items) > 1 {
+		// the last entry is the current page
+		last.Url = ""
+	}
+	return items, last.Title
+}
@@ -0,0 +1,25 @@
+package myapp
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRender_Breadcrumbs(t *testing.T) {
+	h := _newHarness(t)
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	resp := h.Get(h.Reverse(actionNameCpCreateGroup))
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertData("pageTitle", "Create new group")
+	h.AssertBodyContains(resp, "<title>Create new group |")
+	items, _ := h.LastData()["breadcrumbs"].([]*BreadcrumbItem)
+	if len(items) != 3 || items[1].Title != "Groups" || items[1].Url != h.Reverse(actionNameCpGroups) || items[2].Url != "" {
+		t.Fatalf("TestRender_Breadcrumbs failed: unexpected breadcrumbs %#v", items)
+	}
+
+	h.Get(h.Reverse(actionNameCpDashboard))
+	h.AssertData("pageTitle", "Dashboard")
+	if items, _ := h.LastData()["breadcrumbs"].([]*BreadcrumbItem); len(items) != 2 || items[0].Url == "" {
+		t.Fatalf("TestRender_Breadcrumbs failed: unexpected breadcrumbs %#v", items)
+	}
+}
@@ -0,0 +1,407 @@
+package myapp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/btnguyen2k/goyai"
+	"github.com/labstack/echo/v4"
+	"main/src/goadmin"
+	"main/src/utils"
+)
+
+// configBundleVersion is the format version of configuration bundles, bundles of other versions are rejected.
+const configBundleVersion = 1
+
+// maxConfigBundleSize is the maximum size of an uploaded configuration bundle.
+const maxConfigBundleSize = 8 * 1024 * 1024
+
+// bundleExcludedSettingPrefixes lists prefixes of settings that hold per-user or runtime data rather than
+// configuration, they are never exported.
+var bundleExcludedSettingPrefixes = []string{settingPrefixLoginProfile, settingPrefixNotifications, settingPrefixDailyStats, settingPrefixUsageStats, settingPrefixTask, settingPrefixLoginToken, settingPrefixApiToken, settingPrefixApiUsage, settingPrefixGroupInvite, settingPrefixOutbox, settingPrefixTermsAcceptance, settingPrefixUserPreferences, settingPrefixContactMessage, settingIdAuditChain, settingPrefixAttachments, settingIdReadOnly}
+
+var (
+	errBundleVersion   = errors.New("unsupported bundle version")
+	errBundleSignature = errors.New("invalid bundle signature")
+)
+
+// BundleAssignment assigns a user to a group.
+//
+// available since template-r5
+type BundleAssignment struct {
+	Username string `json:"username"`
+	GroupId  string `json:"group_id"`
+}
+
+// BundleContent is the configuration carried by a bundle: groups, user-group assignments, settings and translation
+// overrides. User accounts themselves (and their passwords) are not part of bundles, assignments are applied to
+// existing users only.
+//
+// available since template-r5
+type BundleContent struct {
+	Groups       []*Group            `json:"groups"`
+	Assignments  []*BundleAssignment `json:"assignments"`
+	Settings     []*Setting          `json:"settings"`
+	Translations []*Message          `json:"translations"`
+}
+
+// ConfigBundle is a signed snapshot of the application's configuration, used to synchronize environments (e.g.
+// staging -> production). The signature is a HMAC-SHA256 of the JSON-encoded content, keyed by setting
+// myapp.config_bundle.signing_key which must be the same on both environments.
+//
+// available since template-r5
+type ConfigBundle struct {
+	Version   int            `json:"version"`
+	Source    string         `json:"source"` // application name and version the bundle was exported from
+	Time      time.Time      `json:"time"`
+	Content   *BundleContent `json:"content"`
+	Signature string         `json:"signature"`
+}
+
+// bundleSigningKey returns the key to sign configuration bundles with, falling back to the URL signing key.
+func (r *myRegistry) bundleSigningKey() []byte {
+	key := r.AppConfig.GetString(namespace+".config_bundle.signing_key", "")
+	if key == "" {
+		key = r.AppConfig.GetString("goadmin.url_signing_key", "")
+	}
+	if key == "" {
+		key = r.AppConfig.GetString("goadmin.session_key", "")
+	}
+	return []byte(key)
+}
+
+// bundleSignature calculates the signature of a bundle's content.
+func (r *myRegistry) bundleSignature(content *BundleContent) (string, error) {
+	js, err := json.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, r.bundleSigningKey())
+	mac.Write(js)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// exportConfigBundle builds a signed bundle of the current configuration, entries are sorted by id so that bundles of
+// identical configurations are identical.
+func (r *myRegistry) exportConfigBundle() (*ConfigBundle, error) {
+	content := &BundleContent{}
+	groups, err := r.groupDao.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Id < groups[j].Id })
+	content.Groups = groups
+
+	content.Assignments = make([]*BundleAssignment, 0)
+	for _, g := range groups {
+		for offset := 0; ; offset += reportPageSize {
+			users, err := getNUsersByGroup(r.userDao, g.Id, offset, reportPageSize)
+			if err != nil {
+				return nil, err
+			}
+			for _, u := range users {
+				content.Assignments = append(content.Assignments, &BundleAssignment{Username: u.Username, GroupId: u.GroupId})
+			}
+			if len(users) < reportPageSize {
+				break
+			}
+		}
+	}
+	sort.Slice(content.Assignments, func(i, j int) bool { return content.Assignments[i].Username < content.Assignments[j].Username })
+
+	settings, err := r.settingDao.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	content.Settings = make([]*Setting, 0, len(settings))
+	for _, s := range settings {
+		if !isBundleExcludedSetting(s.Id) {
+			content.Settings = append(content.Settings, s)
+		}
+	}
+	sort.Slice(content.Settings, func(i, j int) bool { return content.Settings[i].Id < content.Settings[j].Id })
+
+	messages, err := r.messageDao.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].Id() < messages[j].Id() })
+	content.Translations = messages
+
+	bundle := &ConfigBundle{Version: configBundleVersion, Source: r.BuildInfo().String(), Time: utils.Now(), Content: content}
+	bundle.Signature, err = r.bundleSignature(content)
+	return bundle, err
+}
+
+func isBundleExcludedSetting(id string) bool {
+	for _, prefix := range bundleExcludedSettingPrefixes {
+		if strings.HasPrefix(id, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseConfigBundle decodes a bundle and verifies its version and signature.
+func (r *myRegistry) parseConfigBundle(data []byte) (*ConfigBundle, error) {
+	bundle := &ConfigBundle{}
+	if err := json.Unmarshal(data, bundle); err != nil {
+		return nil, err
+	}
+	if bundle.Version != configBundleVersion {
+		return nil, errBundleVersion
+	}
+	if bundle.Content == nil {
+		bundle.Content = &BundleContent{}
+	}
+	signature, err := r.bundleSignature(bundle.Content)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal([]byte(signature), []byte(bundle.Signature)) {
+		return nil, errBundleSignature
+	}
+	return bundle, nil
+}
+
+/*----------------------------------------------------------------------*/
+
+// Actions of BundleChange.
+const (
+	bundleActionCreate = "create"
+	bundleActionUpdate = "update"
+	bundleActionSkip   = "skip"
+)
+
+// BundleChange is a change an import of a bundle makes (or would make, in dry-run mode). Entries that are identical
+// in the bundle and the current configuration produce no change; entries that exist only in the current
+// configuration are kept.
+//
+// available since template-r5
+type BundleChange struct {
+	Kind   string // "group", "assignment", "setting" or "translation"
+	Id     string
+	Action string // "create", "update" or "skip"
+	From   string // current value, empty for creations
+	To     string // value of the bundle
+	Note   string // reason of skipping
+}
+
+// importConfigBundle applies a bundle and returns the changes, nothing is written if dryRun is true. Import stops at
+// the first storage error, changes made so far are kept.
+func (r *myRegistry) importConfigBundle(bundle *ConfigBundle, dryRun bool) ([]*BundleChange, error) {
+	changes := make([]*BundleChange, 0)
+	content := bundle.Content
+
+	bundleGroups := make(map[string]bool)
+	for _, g := range content.Groups {
+		bundleGroups[g.Id] = true
+		current, err := r.groupDao.Get(g.Id)
+		if err != nil {
+			return changes, err
+		}
+		switch {
+		case current == nil:
+			changes = append(changes, &BundleChange{Kind: "group", Id: g.Id, Action: bundleActionCreate, To: g.Name})
+			if !dryRun {
+				_, err = r.groupDao.Create(g.Id, g.Name)
+			}
+			if !dryRun && err == nil && len(g.Attrs) > 0 {
+				_, err = r.groupDao.Update(g)
+			}
+		case current.Name != g.Name || encodeGroupAttrs(current.Attrs) != encodeGroupAttrs(g.Attrs):
+			changes = append(changes, &BundleChange{Kind: "group", Id: g.Id, Action: bundleActionUpdate, From: current.Name, To: g.Name})
+			if !dryRun {
+				_, err = r.groupDao.Update(g)
+			}
+		}
+		if err != nil {
+			return changes, err
+		}
+	}
+
+	for _, a := range content.Assignments {
+		current, err := r.userDao.Get(a.Username)
+		if err != nil {
+			return changes, err
+		}
+		change := &BundleChange{Kind: "assignment", Id: a.Username, Action: bundleActionUpdate, To: a.GroupId}
+		switch {
+		case current == nil:
+			change.Action, change.Note = bundleActionSkip, "user does not exist"
+		case current.GroupId == a.GroupId:
+			continue
+		case current.Username == systemUserUsername:
+			change.Action, change.Note = bundleActionSkip, "system user's group cannot be changed"
+		default:
+			if !bundleGroups[a.GroupId] {
+				if g, err := r.groupDao.Get(a.GroupId); err != nil {
+					return changes, err
+				} else if g == nil {
+					change.Action, change.Note = bundleActionSkip, "group does not exist"
+				}
+			}
+			change.From = current.GroupId
+		}
+		changes = append(changes, change)
+		if !dryRun && change.Action == bundleActionUpdate {
+			current.GroupId = a.GroupId
+			if _, err := r.userDao.Update(current); err != nil {
+				return changes, err
+			}
+		}
+	}
+
+	for _, s := range content.Settings {
+		if isBundleExcludedSetting(s.Id) {
+			changes = append(changes, &BundleChange{Kind: "setting", Id: s.Id, Action: bundleActionSkip, Note: "not a configuration setting"})
+			continue
+		}
+		current, err := r.settingDao.Get(s.Id)
+		if err != nil {
+			return changes, err
+		}
+		switch {
+		case current == nil:
+			changes = append(changes, &BundleChange{Kind: "setting", Id: s.Id, Action: bundleActionCreate, To: s.Value})
+		case current.Value != s.Value:
+			changes = append(changes, &BundleChange{Kind: "setting", Id: s.Id, Action: bundleActionUpdate, From: current.Value, To: s.Value})
+		default:
+			continue
+		}
+		if !dryRun {
+			if _, err := r.settingDao.Save(s); err != nil {
+				return changes, err
+			}
+		}
+	}
+
+	i18n, _ := r.i18n.(*layeredI18n)
+	for _, m := range content.Translations {
+		current, err := r.messageDao.Get(m.Locale, m.Key)
+		if err != nil {
+			return changes, err
+		}
+		switch {
+		case current == nil:
+			changes = append(changes, &BundleChange{Kind: "translation", Id: m.Id(), Action: bundleActionCreate, To: m.Text})
+		case current.Text != m.Text:
+			changes = append(changes, &BundleChange{Kind: "translation", Id: m.Id(), Action: bundleActionUpdate, From: current.Text, To: m.Text})
+		default:
+			continue
+		}
+		if !dryRun {
+			// overrides are made effective immediately, not only stored
+			if i18n != nil {
+				err = i18n.Override(m.Locale, m.Key, m.Text)
+			} else {
+				_, err = r.messageDao.Save(m)
+			}
+			if err != nil {
+				return changes, err
+			}
+		}
+	}
+	return changes, nil
+}
+
+/*----------------------------------------------------------------------*/
+
+// actionCpConfigBundle renders the page to export/import configuration bundles.
+//
+// available since template-r5
+func actionCpConfigBundle(c echo.Context) error {
+	if err := checkCpManageSecurity(c); err != nil {
+		AddFlashText(c, FlashWarning, err.Error())
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpDashboard))
+	}
+	return c.Render(http.StatusOK, namespace+":layout:cp_config_bundle", map[string]interface{}{
+		"active": "config_bundle",
+	})
+}
+
+// actionCpConfigBundleExport downloads the configuration bundle.
+//
+// available since template-r5
+func actionCpConfigBundleExport(c echo.Context) error {
+	if err := checkCpManageSecurity(c); err != nil {
+		AddFlashText(c, FlashWarning, err.Error())
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpDashboard))
+	}
+	bundle, err := getRegistry(c).exportConfigBundle()
+	if err != nil {
+		AddFlash(c, FlashError, "error_db_001", "err", err.Error())
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpConfigBundle)+"?r="+utils.RandomString(4))
+	}
+	filename := fmt.Sprintf("config-bundle-%s.json", utils.Now().In(utils.Location).Format("20060102-150405"))
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	return c.JSONPretty(http.StatusOK, bundle, "  ")
+}
+
+// actionCpConfigBundleImportSubmit imports an uploaded configuration bundle (form field "bundle"). With form field
+// "dry_run" set, changes are only listed.
+//
+// available since template-r5
+func actionCpConfigBundleImportSubmit(c echo.Context) error {
+	if err := checkCpManageSecurity(c); err != nil {
+		AddFlashText(c, FlashWarning, err.Error())
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpDashboard))
+	}
+	var errMsg string
+	var bundle *ConfigBundle
+	var changes []*BundleChange
+	var dryRun bool
+	var data []byte
+	myReg := getRegistry(c)
+	currentUser, _ := getCurrentUser(c)
+	formData, err := goadmin.StreamUpload(c, func(field, filename string, content io.Reader) error {
+		if field != "bundle" {
+			_, err := io.Copy(ioutil.Discard, content)
+			return err
+		}
+		var err error
+		if data, err = ioutil.ReadAll(io.LimitReader(content, maxConfigBundleSize+1)); err == nil && len(data) > maxConfigBundleSize {
+			err = fmt.Errorf("bundle exceeds %d bytes", maxConfigBundleSize)
+		}
+		return err
+	})
+	if err == nil && len(data) == 0 {
+		err = errors.New("no bundle uploaded")
+	}
+	if err == nil {
+		bundle, err = myReg.parseConfigBundle(data)
+	}
+	if err != nil {
+		errMsg = getI18n(c).Localize(getContextString(c, ctxLocale), "error_invalid_config_bundle", &goyai.LocalizeConfig{TemplateData: map[string]interface{}{"err": err.Error()}})
+		goto end
+	}
+	dryRun = formData.Get("dry_run") != ""
+	changes, err = myReg.importConfigBundle(bundle, dryRun)
+	if err != nil {
+		errMsg = getI18n(c).Localize(getContextString(c, ctxLocale), "error_db_001", &goyai.LocalizeConfig{TemplateData: map[string]interface{}{"err": err.Error()}})
+		goto end
+	}
+	if !dryRun {
+		myReg.outputCache.invalidate()
+		myReg.auditf("user [%s] imported configuration bundle from [%s]: %d change(s)", currentUser.Username, bundle.Source, len(changes))
+	}
+end:
+	return c.Render(http.StatusOK, namespace+":layout:cp_config_bundle", map[string]interface{}{
+		"active":  "config_bundle",
+		"error":   errMsg,
+		"bundle":  bundle,
+		"changes": changes,
+		"dryRun":  dryRun,
+	})
+}
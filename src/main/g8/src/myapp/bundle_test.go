@@ -0,0 +1,66 @@
+package myapp
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"main/src/apptest"
+)
+
+func _postConfigBundle(h *apptest.Harness, bundle *ConfigBundle, dryRun bool) *httptest.ResponseRecorder {
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	if dryRun {
+		w.WriteField("dry_run", "1")
+	}
+	fw, _ := w.CreateFormFile("bundle", "bundle.json")
+	json.NewEncoder(fw).Encode(bundle)
+	w.Close()
+	req := httptest.NewRequest(http.MethodPost, h.Reverse(actionNameCpConfigBundleImport), body)
+	req.Header.Set(echo.HeaderContentType, w.FormDataContentType())
+	return h.Do(req)
+}
+
+func TestActionCpConfigBundle_ExportImport(t *testing.T) {
+	testName := "TestActionCpConfigBundle_ExportImport"
+	h := _newHarness(t)
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	myReg.settingDao.Save(&Setting{Id: settingPrefixAttachments + testAdminUsername, Value: "[]"})
+	myReg.settingDao.Save(&Setting{Id: settingIdReadOnly, Value: "{}"})
+	resp := h.Get(h.Reverse(actionNameCpConfigBundleExport))
+	h.AssertStatus(resp, http.StatusOK)
+	bundle := &ConfigBundle{}
+	if err := json.Unmarshal(resp.Body.Bytes(), bundle); err != nil || len(bundle.Content.Groups) != 1 || len(bundle.Content.Assignments) != 1 {
+		t.Fatalf("%s failed: unexpected bundle %s / %v", testName, resp.Body.String(), err)
+	}
+	for _, s := range bundle.Content.Settings {
+		if s.Id == settingPrefixAttachments+testAdminUsername || s.Id == settingIdReadOnly {
+			t.Fatalf("%s failed: runtime setting [%s] exported", testName, s.Id)
+		}
+	}
+
+	groupDao := myReg.groupDao
+	groupDao.Update(&Group{Id: systemGroupId, Name: "Renamed"})
+	h.AssertStatus(_postConfigBundle(h, bundle, true), http.StatusOK)
+	if changes, _ := h.LastData()["changes"].([]*BundleChange); len(changes) != 1 || changes[0].Action != bundleActionUpdate || changes[0].From != "Renamed" {
+		t.Fatalf("%s failed: unexpected changes %#v", testName, h.LastData()["changes"])
+	}
+	if group, _ := groupDao.Get(systemGroupId); group.Name != "Renamed" {
+		t.Fatalf("%s failed: dry run must not change group", testName)
+	}
+
+	h.AssertStatus(_postConfigBundle(h, bundle, false), http.StatusOK)
+	if group, _ := groupDao.Get(systemGroupId); group.Name != bundle.Content.Groups[0].Name {
+		t.Fatalf("%s failed: expected group name %s but received %s", testName, bundle.Content.Groups[0].Name, group.Name)
+	}
+
+	bundle.Content.Groups[0].Name = "Tampered"
+	h.AssertStatus(_postConfigBundle(h, bundle, false), http.StatusOK)
+	h.AssertData("error", errBundleSignature.Error())
+}
@@ -0,0 +1,138 @@
+package myapp
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"main/src/goadmin"
+)
+
+// cachedSettingDao is a SettingDao that caches settings in the application's cache (see goadmin.Registry.Cache):
+// settings are read on most requests (e.g. security settings) but rarely change. Cached entries are invalidated when
+// settings are saved or deleted through the DAO; with the Redis cache driver, invalidation is seen by all instances.
+//
+// available since template-r5
+type cachedSettingDao struct {
+	SettingDao
+	cache goadmin.Cache
+	ttl   time.Duration
+}
+
+// newCachedSettingDao wraps dao so that settings are cached for ttl (setting myapp.cache_ttl), caching is disabled if
+// ttl is not positive.
+func newCachedSettingDao(dao SettingDao, cache goadmin.Cache, ttl time.Duration) SettingDao {
+	if cache == nil || ttl <= 0 {
+		return dao
+	}
+	return &cachedSettingDao{SettingDao: dao, cache: cache, ttl: ttl}
+}
+
+func (dao *cachedSettingDao) cacheKey(id string) string {
+	return namespace + ":setting:" + id
+}
+
+// Get implements SettingDao.Get
+func (dao *cachedSettingDao) Get(id string) (*Setting, error) {
+	// non-existing settings are cached as "null", so that they are not looked up on every request either
+	js, err := dao.cache.Remember(dao.cacheKey(id), dao.ttl, func() ([]byte, error) {
+		bo, err := dao.SettingDao.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(bo)
+	})
+	if err != nil {
+		return nil, err
+	}
+	var bo *Setting
+	return bo, json.Unmarshal(js, &bo)
+}
+
+// Delete implements SettingDao.Delete
+func (dao *cachedSettingDao) Delete(bo *Setting) (bool, error) {
+	defer dao.invalidate(bo.Id)
+	return dao.SettingDao.Delete(bo)
+}
+
+// Save implements SettingDao.Save
+func (dao *cachedSettingDao) Save(bo *Setting) (bool, error) {
+	defer dao.invalidate(bo.Id)
+	return dao.SettingDao.Save(bo)
+}
+
+// GetByPrefix implements SettingPrefixGetter.GetByPrefix
+func (dao *cachedSettingDao) GetByPrefix(prefix string) ([]*Setting, error) {
+	return getSettingsByPrefix(dao.SettingDao, prefix)
+}
+
+func (dao *cachedSettingDao) invalidate(id string) {
+	if err := dao.cache.Delete(dao.cacheKey(id)); err != nil {
+		log.Printf("[WARN] cannot invalidate cached setting [%s]: %s", id, err)
+	}
+}
+
+/*----------------------------------------------------------------------*/
+
+// groupSnapshot keeps the list of groups in memory to populate group selects (e.g. on the create/edit user pages,
+// see MyAppUtils.AllUserGroups), so that forms are rendered without querying the group table. The snapshot is
+// dropped on group events of the running instance (see myRegistry.onEvent) and reloaded after ttl (setting
+// myapp.group_snapshot_ttl), so that changes made by other instances are eventually seen.
+//
+// available since template-r5
+type groupSnapshot struct {
+	r      *myRegistry
+	ttl    time.Duration
+	lock   sync.RWMutex
+	groups []*Group // nil if not loaded
+	loaded time.Time
+}
+
+// newGroupSnapshot creates a groupSnapshot refreshed on group events, nil is returned if ttl is not positive.
+func newGroupSnapshot(r *myRegistry, ttl time.Duration) *groupSnapshot {
+	if ttl <= 0 {
+		return nil
+	}
+	s := &groupSnapshot{r: r, ttl: ttl}
+	r.onEvent(func(*Event) { s.invalidate() }, eventGroupCreated, eventGroupUpdated, eventGroupDeleted)
+	return s
+}
+
+// get returns all groups, from the snapshot if it is fresh. Groups are shared with other callers and must not be
+// modified.
+func (s *groupSnapshot) get() ([]*Group, error) {
+	s.lock.RLock()
+	groups, loaded := s.groups, s.loaded
+	s.lock.RUnlock()
+	if groups != nil && time.Since(loaded) < s.ttl {
+		return groups, nil
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.groups != nil && time.Since(s.loaded) < s.ttl {
+		return s.groups, nil
+	}
+	groups, err := s.r.groupDao.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	s.groups, s.loaded = groups, time.Now()
+	return groups, nil
+}
+
+// invalidate drops the snapshot, it is reloaded on next use.
+func (s *groupSnapshot) invalidate() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.groups = nil
+}
+
+// allGroups returns all groups, from the group snapshot if enabled.
+func (r *myRegistry) allGroups() ([]*Group, error) {
+	if r.groupSnapshot == nil {
+		return r.groupDao.GetAll()
+	}
+	return r.groupSnapshot.get()
+}
@@ -0,0 +1,76 @@
+package myapp
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"main/src/goadmin"
+)
+
+func TestCachedSettingDao(t *testing.T) {
+	testName := "TestCachedSettingDao"
+	backend := newSettingDaoMemory()
+	dao := newCachedSettingDao(backend, goadmin.NewMemoryCache(0), time.Minute)
+	if bo, err := dao.Get("s"); err != nil || bo != nil {
+		t.Fatalf("%s failed: expected no setting but received %#v / %v", testName, bo, err)
+	}
+	backend.Save(&Setting{Id: "s", Value: "bypassed"})
+	if bo, _ := dao.Get("s"); bo != nil {
+		t.Fatalf("%s failed: expected cached miss but received %#v", testName, bo)
+	}
+	dao.Save(&Setting{Id: "s", Value: "saved"})
+	if bo, _ := dao.Get("s"); bo == nil || bo.Value != "saved" {
+		t.Fatalf("%s failed: expected saved setting but received %#v", testName, bo)
+	}
+	dao.Delete(&Setting{Id: "s"})
+	if bo, _ := dao.Get("s"); bo != nil {
+		t.Fatalf("%s failed: expected deleted setting but received %#v", testName, bo)
+	}
+}
+
+type getAllCountingGroupDao struct {
+	GroupDao
+	getAlls int
+}
+
+func (dao *getAllCountingGroupDao) GetAll() ([]*Group, error) {
+	dao.getAlls++
+	return dao.GroupDao.GetAll()
+}
+
+func TestGroupSnapshot(t *testing.T) {
+	testName := "TestGroupSnapshot"
+	h := _newHarness(t)
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	if myReg.groupSnapshot == nil {
+		t.Fatalf("%s failed: group snapshot must be enabled by default", testName)
+	}
+	dao := &getAllCountingGroupDao{GroupDao: myReg.groupDao}
+	myReg.groupDao = dao
+	myReg.groupSnapshot.invalidate()
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	for i := 0; i < 3; i++ {
+		h.AssertStatus(h.Get(h.Reverse(actionNameCpCreateUser)), http.StatusOK)
+	}
+	if dao.getAlls != 1 {
+		t.Fatalf("%s failed: groups must be queried once, got %d queries", testName, dao.getAlls)
+	}
+
+	// group events of the instance refresh the snapshot
+	var events []string
+	myReg.onEvent(func(event *Event) { events = append(events, event.Name) }, eventGroupCreated, eventGroupDeleted)
+	myReg.groupDao.Create("snapshot", "Snapshot group")
+	resp := h.Get(h.Reverse(actionNameCpCreateUser))
+	h.AssertBodyContains(resp, "Snapshot group")
+	if dao.getAlls != 2 {
+		t.Fatalf("%s failed: groups must be queried again after a change, got %d queries", testName, dao.getAlls)
+	}
+	myReg.groupDao.Delete(&Group{Id: "snapshot"})
+	if groups, _ := myReg.allGroups(); len(groups) != 1 {
+		t.Fatalf("%s failed: deleted group must be gone from the snapshot, received %d groups", testName, len(groups))
+	}
+	if len(events) != 2 || events[0] != eventGroupCreated || events[1] != eventGroupDeleted {
+		t.Fatalf("%s failed: expected group events but received %v", testName, events)
+	}
+}
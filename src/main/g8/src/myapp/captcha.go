@@ -0,0 +1,174 @@
+package myapp
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// captchaProvider describes a CAPTCHA service: the widget rendered in forms and the server-side verification API.
+// reCAPTCHA, hCaptcha and Turnstile share the same verification protocol.
+type captchaProvider struct {
+	scriptUrl     string // script rendering the widget
+	widgetClass   string // CSS class of the widget's container
+	responseField string // form field the widget stores its response in
+	verifyUrl     string
+}
+
+var captchaProviders = map[string]*captchaProvider{
+	"recaptcha": {
+		scriptUrl:     "https://www.google.com/recaptcha/api.js",
+		widgetClass:   "g-recaptcha",
+		responseField: "g-recaptcha-response",
+		verifyUrl:     "https://www.google.com/recaptcha/api/siteverify",
+	},
+	"hcaptcha": {
+		scriptUrl:     "https://js.hcaptcha.com/1/api.js",
+		widgetClass:   "h-captcha",
+		responseField: "h-captcha-response",
+		verifyUrl:     "https://api.hcaptcha.com/siteverify",
+	},
+	"turnstile": {
+		scriptUrl:     "https://challenges.cloudflare.com/turnstile/v0/api.js",
+		widgetClass:   "cf-turnstile",
+		responseField: "cf-turnstile-response",
+		verifyUrl:     "https://challenges.cloudflare.com/turnstile/v0/siteverify",
+	},
+}
+
+// loginCaptcha requires a CAPTCHA on the login form once a client IP or a username has failed to sign in
+// after_failures times within failure_window (configuration block myapp.captcha). Failures are counted in the shared
+// cache (setting goadmin.cache), so that they are shared by all instances.
+//
+// available since template-r5
+type loginCaptcha struct {
+	r             *myRegistry
+	provider      *captchaProvider
+	verifyUrl     string
+	siteKey       string
+	secretKey     string
+	afterFailures int
+	window        time.Duration
+	client        *http.Client
+}
+
+// CaptchaWidget holds what the login form needs to render the CAPTCHA widget.
+//
+// available since template-r5
+type CaptchaWidget struct {
+	ScriptUrl   string
+	WidgetClass string
+	SiteKey     string
+}
+
+// newLoginCaptcha creates a loginCaptcha from the configuration block myapp.captcha, nil is returned if no provider
+// is configured.
+func newLoginCaptcha(r *myRegistry) (*loginCaptcha, error) {
+	conf := r.AppConfig
+	name := strings.ToLower(conf.GetString(namespace+".captcha.provider", ""))
+	if name == "" {
+		return nil, nil
+	}
+	provider, ok := captchaProviders[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown CAPTCHA provider [%s], valid values are recaptcha, hcaptcha and turnstile", name)
+	}
+	c := &loginCaptcha{
+		r:             r,
+		provider:      provider,
+		verifyUrl:     conf.GetString(namespace+".captcha.verify_url", ""),
+		siteKey:       conf.GetString(namespace+".captcha.site_key", ""),
+		secretKey:     conf.GetString(namespace+".captcha.secret_key", ""),
+		afterFailures: int(conf.GetInt32(namespace+".captcha.after_failures", 3)),
+		window:        conf.GetTimeDuration(namespace+".captcha.failure_window", 15*time.Minute),
+		client:        &http.Client{Timeout: conf.GetTimeDuration(namespace+".captcha.timeout", 5*time.Second)},
+	}
+	if c.siteKey == "" || c.secretKey == "" {
+		return nil, fmt.Errorf("CAPTCHA provider [%s] requires settings [%s.captcha.site_key] and [%s.captcha.secret_key]", name, namespace, namespace)
+	}
+	if c.verifyUrl == "" {
+		c.verifyUrl = provider.verifyUrl
+	}
+	return c, nil
+}
+
+func (c *loginCaptcha) cacheKey(kind, value string) string {
+	return namespace + ":login_failures:" + kind + ":" + value
+}
+
+func (c *loginCaptcha) failures(kind, value string) int {
+	if value == "" {
+		return 0
+	}
+	v, err := c.r.Cache.Get(c.cacheKey(kind, value))
+	if err != nil || v == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(string(v))
+	return n
+}
+
+// required checks if a CAPTCHA must be solved to sign in from an IP, or as a username (empty if unknown).
+func (c *loginCaptcha) required(ip, username string) bool {
+	return c.failures("ip", ip) >= c.afterFailures || c.failures("user", strings.ToLower(username)) >= c.afterFailures
+}
+
+// recordFailure counts a failed sign-in. Counts are not atomic across instances, they only need to be approximate.
+func (c *loginCaptcha) recordFailure(ip, username string) {
+	for kind, value := range map[string]string{"ip": ip, "user": strings.ToLower(username)} {
+		if value != "" {
+			c.r.Cache.Set(c.cacheKey(kind, value), []byte(strconv.Itoa(c.failures(kind, value)+1)), c.window)
+		}
+	}
+}
+
+// reset clears failures of an IP and a username after a successful sign-in.
+func (c *loginCaptcha) reset(ip, username string) {
+	c.r.Cache.Delete(c.cacheKey("ip", ip))
+	c.r.Cache.Delete(c.cacheKey("user", strings.ToLower(username)))
+}
+
+// widget returns the widget to render in the login form.
+func (c *loginCaptcha) widget() *CaptchaWidget {
+	return &CaptchaWidget{ScriptUrl: c.provider.scriptUrl, WidgetClass: c.provider.widgetClass, SiteKey: c.siteKey}
+}
+
+// verify checks the CAPTCHA response submitted with a form against the provider's API.
+func (c *loginCaptcha) verify(form url.Values, ip string) (bool, error) {
+	response := form.Get(c.provider.responseField)
+	if response == "" {
+		return false, nil
+	}
+	resp, err := c.client.PostForm(c.verifyUrl, url.Values{"secret": {c.secretKey}, "response": {response}, "remoteip": {ip}})
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("CAPTCHA verification returned status %d", resp.StatusCode)
+	}
+	result := struct {
+		Success    bool     `json:"success"`
+		ErrorCodes []string `json:"error-codes"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, err
+	}
+	if !result.Success && len(result.ErrorCodes) > 0 {
+		log.Printf("[WARN] CAPTCHA verification failed for %s: %s", ip, strings.Join(result.ErrorCodes, ", "))
+	}
+	return result.Success, nil
+}
+
+// loginCaptchaWidget returns the CAPTCHA widget to render in the login form, nil if no CAPTCHA is required.
+func (r *myRegistry) loginCaptchaWidget(ip, username string) *CaptchaWidget {
+	if r.captcha == nil || !r.captcha.required(ip, username) {
+		return nil
+	}
+	return r.captcha.widget()
+}
@@ -0,0 +1,57 @@
+package myapp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"main/src/apptest"
+)
+
+func TestLoginCaptcha(t *testing.T) {
+	testName := "TestLoginCaptcha"
+	verifier := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		success := r.PostFormValue("secret") == "test-secret" && r.PostFormValue("response") == "solved"
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": success})
+	}))
+	defer verifier.Close()
+	conf := apptest.SqliteInMemoryConfig + `
+myapp.captcha {
+  provider = "turnstile"
+  site_key = "test-site"
+  secret_key = "test-secret"
+  verify_url = "` + verifier.URL + `"
+  after_failures = 2
+}
+`
+	h := apptest.New(t, conf, NewBootstrapper(nil, nil))
+	captchaOf := func() *CaptchaWidget {
+		h.AssertStatus(h.Get(h.Reverse(actionNameCpLogin)), http.StatusOK)
+		widget, _ := h.LastData()["captcha"].(*CaptchaWidget)
+		return widget
+	}
+	if captchaOf() != nil {
+		t.Fatalf("%s failed: CAPTCHA should not be required before failed sign-ins", testName)
+	}
+	submit := func(password, captchaResponse string) *httptest.ResponseRecorder {
+		return h.PostForm(h.Reverse(actionNameCpLoginSubmit), url.Values{"username": {testAdminUsername},
+			"password": {password}, "cf-turnstile-response": {captchaResponse}})
+	}
+	for i := 0; i < 2; i++ {
+		h.AssertStatus(submit("wrong", ""), http.StatusOK)
+	}
+	if widget := captchaOf(); widget == nil || widget.SiteKey != "test-site" || widget.WidgetClass != "cf-turnstile" {
+		t.Fatalf("%s failed: CAPTCHA should be required after failed sign-ins, got %#v", testName, widget)
+	}
+	submit(testAdminPassword, "")
+	h.AssertData("error", "Please confirm you are not a robot")
+	submit(testAdminPassword, "forged")
+	h.AssertData("error", "Please confirm you are not a robot")
+	h.AssertStatus(submit(testAdminPassword, "solved"), http.StatusFound)
+	// failures are cleared by a successful sign-in
+	if captchaOf() != nil {
+		t.Fatalf("%s failed: CAPTCHA should not be required after a successful sign-in", testName)
+	}
+}
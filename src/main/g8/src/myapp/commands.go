@@ -0,0 +1,81 @@
+package myapp
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxCommands is the maximum number of commands returned by actionCpCommands.
+const maxCommands = 50
+
+// Command is an entry of the command palette: a page of the control panel, an action or an item (e.g. a user) the
+// current user can jump to.
+//
+// available since template-r5
+type Command struct {
+	Title    string `json:"title"`
+	Url      string `json:"url"`
+	Category string `json:"category"`
+	Icon     string `json:"icon,omitempty"`
+}
+
+// cpCommands lists commands available to the current user, respecting the same permissions as the sidebar menu and
+// list pages do.
+func cpCommands(c echo.Context) []*Command {
+	myReg, locale := getRegistry(c), getContextString(c, ctxLocale)
+	result := make([]*Command, 0)
+
+	catPages := myReg.i18n.Localize(locale, "commands_pages")
+	for _, s := range cpSections {
+		if s.name == "inbox" && myReg.contact == nil {
+			continue
+		}
+		if s.permission == "" || can(c, s.permission) {
+			result = append(result, &Command{Title: myReg.i18n.Localize(locale, s.i18nKey), Url: myReg.Reverse(s.actionName), Category: catPages, Icon: s.icon})
+		}
+	}
+
+	catActions := myReg.i18n.Localize(locale, "commands_actions")
+	if can(c, permGroupCreate) {
+		result = append(result, &Command{Title: myReg.i18n.Localize(locale, "create_group"), Url: myReg.Reverse(actionNameCpCreateGroup), Category: catActions, Icon: "fas fa-plus"})
+	}
+	if can(c, permUserCreate) {
+		result = append(result, &Command{Title: myReg.i18n.Localize(locale, "create_user"), Url: myReg.Reverse(actionNameCpCreateUser), Category: catActions, Icon: "fas fa-plus"})
+	}
+
+	u := &MyAppUtils{c: c}
+	catGroups := myReg.i18n.Localize(locale, "groups")
+	for _, g := range u.AllUserGroups() {
+		if g.CanEdit() {
+			result = append(result, &Command{Title: g.Name + " (" + g.Id + ")", Url: g.UrlEdit(), Category: catGroups, Icon: "fas fa-users"})
+		}
+	}
+	catUsers := myReg.i18n.Localize(locale, "users")
+	for _, usr := range u.AllUsers() {
+		if usr.CanEdit() {
+			result = append(result, &Command{Title: usr.Name + " (" + usr.Username + ")", Url: usr.UrlEdit(), Category: catUsers, Icon: "fas fa-user-alt"})
+		}
+	}
+	return result
+}
+
+// actionCpCommands responds with commands of the command palette in JSON format, filtered by query parameter "q"
+// (case-insensitive match against titles).
+//
+// available since template-r5
+func actionCpCommands(c echo.Context) error {
+	query := strings.ToLower(strings.TrimSpace(c.QueryParam("q")))
+	commands := make([]*Command, 0)
+	for _, cmd := range cpCommands(c) {
+		if query == "" || strings.Contains(strings.ToLower(cmd.Title), query) {
+			commands = append(commands, cmd)
+			if len(commands) >= maxCommands {
+				break
+			}
+		}
+	}
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	return c.JSON(http.StatusOK, map[string]interface{}{"commands": commands})
+}
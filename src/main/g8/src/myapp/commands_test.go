@@ -0,0 +1,24 @@
+package myapp
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestActionCpCommands(t *testing.T) {
+	h := _newHarness(t)
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	resp := h.Get(h.Reverse(actionNameCpCommands))
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertBodyContains(resp, h.Reverse(actionNameCpTranslations))
+	h.AssertBodyContains(resp, h.Reverse(actionNameCpCreateGroup))
+	h.AssertBodyContains(resp, "System User Group ("+systemGroupId+")")
+
+	resp = h.Get(h.Reverse(actionNameCpCommands) + "?q=LOGG")
+	h.AssertStatus(resp, http.StatusOK)
+	var result map[string][]*Command
+	if err := json.Unmarshal(resp.Body.Bytes(), &result); err != nil || len(result["commands"]) != 1 || result["commands"][0].Url != h.Reverse(actionNameCpLoggingSettings) {
+		t.Fatalf("TestActionCpCommands failed: unexpected response %s / %v", resp.Body.String(), err)
+	}
+}
@@ -0,0 +1,512 @@
+package myapp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/btnguyen2k/goyai"
+	"github.com/labstack/echo/v4"
+	"main/src/goadmin"
+	"main/src/utils"
+)
+
+// settingPrefixContactMessage prefixes ids of settings that store messages submitted with the contact form, e.g.
+// "contact_message:<id>".
+const settingPrefixContactMessage = "contact_message:"
+
+// reEmail loosely matches email addresses: the address is only used to reply. Domains without a dot are accepted,
+// e.g. local ones such as "support@local".
+var reEmail = regexp.MustCompile(`^[^@\s]+@[^@\s.][^@\s]*$`)
+
+// ContactMessage is a message submitted with the public contact form, read and replied to at /cp/inbox.
+//
+// available since template-r5
+type ContactMessage struct {
+	Id      string          `json:"id"`
+	Name    string          `json:"name"`
+	Email   string          `json:"email"`
+	Subject string          `json:"subject"`
+	Message string          `json:"message"`
+	Ip      string          `json:"ip"`
+	Created time.Time       `json:"created"`
+	Read    bool            `json:"read"`
+	Replies []*ContactReply `json:"replies,omitempty"`
+}
+
+// ContactReply is a reply emailed to the sender of a contact message.
+//
+// available since template-r5
+type ContactReply struct {
+	By   string    `json:"by"`
+	Body string    `json:"body"`
+	Time time.Time `json:"time"`
+}
+
+// validate normalizes the message and returns the i18n key of the error, empty if the message is valid.
+func (m *ContactMessage) validate() string {
+	m.Name = strings.TrimSpace(m.Name)
+	m.Email = strings.TrimSpace(m.Email)
+	m.Subject = strings.TrimSpace(m.Subject)
+	m.Message = strings.TrimSpace(m.Message)
+	if m.Name == "" || m.Message == "" {
+		return "error_contact_empty"
+	}
+	if !reEmail.MatchString(m.Email) {
+		return "error_contact_invalid_email"
+	}
+	if len(m.Name) > 128 || len(m.Email) > 256 || len(m.Subject) > 256 || len(m.Message) > 8192 {
+		return "error_contact_too_long"
+	}
+	return ""
+}
+
+// ContactMessageDao stores messages submitted with the contact form.
+//
+// available since template-r5
+type ContactMessageDao interface {
+	// Get returns the message with the supplied id, nil if not found.
+	Get(id string) (*ContactMessage, error)
+	// GetAll returns all messages, newest first.
+	GetAll() ([]*ContactMessage, error)
+	// Save creates or updates a message.
+	Save(msg *ContactMessage) error
+	// Delete removes a message, returns false if not found.
+	Delete(id string) (bool, error)
+}
+
+// settingContactMessageDao is a ContactMessageDao keeping messages as settings, so that they are persisted in the
+// application's database whatever its type.
+type settingContactMessageDao struct {
+	r *myRegistry
+}
+
+// Get implements ContactMessageDao.Get
+func (dao *settingContactMessageDao) Get(id string) (*ContactMessage, error) {
+	msg := &ContactMessage{}
+	if found, err := dao.r.loadSetting(settingPrefixContactMessage+id, msg); err != nil || !found {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// GetAll implements ContactMessageDao.GetAll
+func (dao *settingContactMessageDao) GetAll() ([]*ContactMessage, error) {
+	list, err := dao.r.settingsWithPrefix(settingPrefixContactMessage)
+	if err != nil {
+		return nil, err
+	}
+	msgs := make([]*ContactMessage, 0, len(list))
+	for _, s := range list {
+		msg := &ContactMessage{}
+		if err := json.Unmarshal([]byte(s.Value), msg); err != nil {
+			log.Printf("[WARN] cannot decode setting [%s]: %s", s.Id, err)
+			continue
+		}
+		msgs = append(msgs, msg)
+	}
+	sort.Slice(msgs, func(i, j int) bool { return msgs[i].Created.After(msgs[j].Created) })
+	return msgs, nil
+}
+
+// Save implements ContactMessageDao.Save
+func (dao *settingContactMessageDao) Save(msg *ContactMessage) error {
+	return dao.r.saveSetting(settingPrefixContactMessage+msg.Id, msg)
+}
+
+// Delete implements ContactMessageDao.Delete
+func (dao *settingContactMessageDao) Delete(id string) (bool, error) {
+	setting, err := dao.r.settingDao.Get(settingPrefixContactMessage + id)
+	if err != nil || setting == nil {
+		return false, err
+	}
+	return dao.r.settingDao.Delete(setting)
+}
+
+/*----------------------------------------------------------------------*/
+
+// contactForm is the public contact form served at /contact (configuration block myapp.contact). Submissions are
+// protected against spam by the bot guard (honeypot and form timestamp, see botGuard), a rate limit per client IP and,
+// optionally, the CAPTCHA configured in myapp.captcha. They are stored with a ContactMessageDao and notified by email
+// to the recipients.
+//
+// available since template-r5
+type contactForm struct {
+	r          *myRegistry
+	dao        ContactMessageDao
+	recipients []string      // email addresses notified of new messages
+	rateLimit  int           // max number of messages per client IP per rate window, 0 for no limit
+	rateWindow time.Duration // period messages are counted over
+	captcha    bool          // require the CAPTCHA of myapp.captcha
+}
+
+// newContactForm creates a contactForm from the configuration block myapp.contact, nil is returned if the contact
+// form is disabled.
+func newContactForm(r *myRegistry) (*contactForm, error) {
+	conf := r.AppConfig
+	confPath := namespace + ".contact"
+	if !conf.GetBoolean(confPath+".enabled", false) {
+		return nil, nil
+	}
+	f := &contactForm{
+		r:          r,
+		dao:        &settingContactMessageDao{r: r},
+		recipients: make([]string, 0),
+		rateLimit:  int(conf.GetInt32(confPath+".rate_limit", 3)),
+		rateWindow: conf.GetTimeDuration(confPath+".rate_window", time.Hour),
+		captcha:    conf.GetBoolean(confPath+".captcha", false),
+	}
+	for _, email := range conf.GetStringList(confPath + ".recipients") {
+		if email = strings.TrimSpace(email); email == "" {
+			continue
+		} else if !reEmail.MatchString(email) {
+			return nil, fmt.Errorf("invalid recipient [%s] in setting [%s.recipients]", email, confPath)
+		}
+		f.recipients = append(f.recipients, email)
+	}
+	if f.rateWindow <= 0 {
+		return nil, fmt.Errorf("setting [%s.rate_window] must be positive", confPath)
+	}
+	if f.captcha && r.captcha == nil {
+		return nil, fmt.Errorf("setting [%s.captcha] requires a CAPTCHA provider (setting [%s.captcha.provider])", confPath, namespace)
+	}
+	return f, nil
+}
+
+// rateKey returns the cache key counting messages of the client IP during the current rate window.
+func (f *contactForm) rateKey(ip string, now time.Time) string {
+	return namespace + ":contact:" + ip + ":" + strconv.FormatInt(now.UnixNano()/int64(f.rateWindow), 36)
+}
+
+// rateExceeded checks if the client IP has reached the rate limit.
+func (f *contactForm) rateExceeded(ip string) bool {
+	if f.rateLimit <= 0 {
+		return false
+	}
+	v, err := f.r.Cache.Get(f.rateKey(ip, time.Now()))
+	if err != nil || v == nil {
+		return false
+	}
+	n, _ := strconv.Atoi(string(v))
+	return n >= f.rateLimit
+}
+
+// recordSubmission counts a message of the client IP. Counts are not atomic across instances, they only need to be
+// approximate.
+func (f *contactForm) recordSubmission(ip string) {
+	if f.rateLimit <= 0 {
+		return
+	}
+	key, n := f.rateKey(ip, time.Now()), 0
+	if v, err := f.r.Cache.Get(key); err == nil && v != nil {
+		n, _ = strconv.Atoi(string(v))
+	}
+	f.r.Cache.Set(key, []byte(strconv.Itoa(n+1)), f.rateWindow)
+}
+
+// widget returns the CAPTCHA widget to render in the contact form, nil if no CAPTCHA is required.
+func (f *contactForm) widget() *CaptchaWidget {
+	if !f.captcha {
+		return nil
+	}
+	return f.r.captcha.widget()
+}
+
+// notify emails the message to the recipients, if emails are enabled.
+func (f *contactForm) notify(c echo.Context, msg *ContactMessage) {
+	if len(f.recipients) == 0 || !f.r.Outbox.HasSender(goadmin.OutboxChannelEmail) {
+		return
+	}
+	link := f.r.seo.absoluteUrl(c, c.Echo().Reverse(actionNameCpInboxMessage)+"?id="+url.QueryEscape(msg.Id))
+	// recipients are staff members rather than the sender, hence the application's default locale
+	locale := f.r.defaultPreferences().Locale
+	tplData := &goyai.LocalizeConfig{TemplateData: map[string]interface{}{
+		"name": msg.Name, "email": msg.Email, "subject": msg.Subject, "message": msg.Message, "link": link,
+	}}
+	email := &goadmin.EmailPayload{
+		To:      f.recipients,
+		Subject: f.r.AppConfig.GetString("app.name") + ": " + f.r.i18n.Localize(locale, "contact_email_subject", tplData),
+		Body:    f.r.i18n.Localize(locale, "contact_email_body", tplData),
+	}
+	if _, err := f.r.Outbox.Enqueue(goadmin.OutboxChannelEmail, email); err != nil {
+		log.Printf("[ERROR] cannot notify contact message [%s]: %s", msg.Id, err)
+	}
+}
+
+/*----------------------------------------------------------------------*/
+
+// renderContact renders the contact form.
+func renderContact(c echo.Context, status int, formData url.Values, errMsg string) error {
+	myReg := getRegistry(c)
+	return c.Render(status, namespace+":contact", map[string]interface{}{
+		"form":     formData,
+		"error":    errMsg,
+		"botGuard": myReg.botGuardForm(),
+		"captcha":  myReg.contact.widget(),
+	})
+}
+
+// actionContact renders the public contact form.
+//
+// available since template-r5
+func actionContact(c echo.Context) error {
+	return renderContact(c, http.StatusOK, url.Values{}, "")
+}
+
+// actionContactSubmit stores a message submitted with the contact form and notifies the recipients. Clients beyond
+// the rate limit are answered with 429.
+//
+// available since template-r5
+func actionContactSubmit(c echo.Context) error {
+	myReg := getRegistry(c)
+	f, ip := myReg.contact, c.RealIP()
+	formData, _ := c.FormParams()
+	if f.rateExceeded(ip) {
+		log.Printf("[WARN] contact message from %s rejected: rate limit reached", clientOrigin(c))
+		c.Response().Header().Set("Retry-After", strconv.Itoa(int(f.rateWindow.Seconds())))
+		return renderContact(c, http.StatusTooManyRequests, formData, getI18n(c).Localize(getContextString(c, ctxLocale), "error_contact_rate_limited"))
+	}
+	if f.captcha {
+		if ok, err := myReg.captcha.verify(formData, ip); err != nil {
+			log.Printf("[ERROR] cannot verify CAPTCHA: %s", err)
+			return renderContact(c, http.StatusOK, formData, getI18n(c).Localize(getContextString(c, ctxLocale), "error_captcha_unavailable"))
+		} else if !ok {
+			return renderContact(c, http.StatusOK, formData, getI18n(c).Localize(getContextString(c, ctxLocale), "error_captcha_failed"))
+		}
+	}
+	msg := &ContactMessage{
+		Id:      utils.UniqueId(),
+		Name:    formData.Get("name"),
+		Email:   formData.Get("email"),
+		Subject: formData.Get("subject"),
+		Message: formData.Get("message"),
+		Ip:      ip,
+		Created: utils.Now(),
+	}
+	if errKey := msg.validate(); errKey != "" {
+		return renderContact(c, http.StatusOK, formData, getI18n(c).Localize(getContextString(c, ctxLocale), errKey))
+	}
+	if err := f.dao.Save(msg); err != nil {
+		log.Printf("[ERROR] cannot save contact message: %s", err)
+		return renderContact(c, http.StatusOK, formData, getI18n(c).Localize(getContextString(c, ctxLocale), "error_contact_failed"))
+	}
+	f.recordSubmission(ip)
+	f.notify(c, msg)
+	return c.Render(http.StatusOK, namespace+":contact", map[string]interface{}{"sent": true})
+}
+
+/*----------------------------------------------------------------------*/
+
+// ContactMessageModel is the view model of a contact message.
+//
+// available since template-r5
+type ContactMessageModel struct {
+	*ContactMessage
+	c echo.Context
+}
+
+func toContactMessageModel(c echo.Context, msg *ContactMessage) *ContactMessageModel {
+	return &ContactMessageModel{ContactMessage: msg, c: c}
+}
+
+// CreatedStr returns the time the message was submitted, in the application's timezone.
+func (m *ContactMessageModel) CreatedStr() string {
+	return m.Created.In(utils.Location).Format("2006-01-02 15:04:05")
+}
+
+// ReplyTimeStr returns the time of a reply, in the application's timezone.
+func (m *ContactMessageModel) ReplyTimeStr(reply *ContactReply) string {
+	return reply.Time.In(utils.Location).Format("2006-01-02 15:04:05")
+}
+
+// UrlView returns the URL to read the message.
+func (m *ContactMessageModel) UrlView() string {
+	return m.c.Echo().Reverse(actionNameCpInboxMessage) + "?id=" + url.QueryEscape(m.Id)
+}
+
+// UrlReply returns the URL to reply to the message.
+func (m *ContactMessageModel) UrlReply() string {
+	return m.c.Echo().Reverse(actionNameCpInboxReplySubmit) + "?id=" + url.QueryEscape(m.Id)
+}
+
+// UrlDelete returns the URL to delete the message.
+func (m *ContactMessageModel) UrlDelete() string {
+	return m.c.Echo().Reverse(actionNameCpInboxDeleteSubmit) + "?id=" + url.QueryEscape(m.Id)
+}
+
+// checkCpInbox checks if the contact form is enabled and the current user can manage its messages.
+func checkCpInbox(c echo.Context) error {
+	if getRegistry(c).contact == nil {
+		return errors.New(getI18n(c).Localize(getContextString(c, ctxLocale), "error_contact_disabled"))
+	}
+	return checkPermission(c, permInboxManage)
+}
+
+// actionCpInbox lists messages submitted with the contact form, newest first.
+//
+// available since template-r5
+func actionCpInbox(c echo.Context) error {
+	if err := checkCpInbox(c); err != nil {
+		AddFlashText(c, FlashWarning, err.Error())
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpDashboard))
+	}
+	myReg := getRegistry(c)
+	data := map[string]interface{}{
+		"active":     "inbox",
+		"canReply":   myReg.Outbox.HasSender(goadmin.OutboxChannelEmail),
+		"recipients": strings.Join(myReg.contact.recipients, ", "),
+	}
+	msgs, err := myReg.contact.dao.GetAll()
+	if err != nil {
+		data["error"] = getI18n(c).Localize(getContextString(c, ctxLocale), "error_db_001", &goyai.LocalizeConfig{
+			TemplateData: map[string]interface{}{"err": settingPrefixContactMessage + "/" + err.Error()},
+		})
+	}
+	models := make([]*ContactMessageModel, 0, len(msgs))
+	for _, msg := range msgs {
+		models = append(models, toContactMessageModel(c, msg))
+	}
+	data["messages"] = models
+	return c.Render(http.StatusOK, namespace+":layout:cp_inbox", data)
+}
+
+// checkCpInboxMessage returns the message of query parameter "id" if the current user can manage contact messages.
+func checkCpInboxMessage(c echo.Context) (*ContactMessage, error) {
+	if err := checkCpInbox(c); err != nil {
+		return nil, err
+	}
+	id := c.QueryParam("id")
+	msg, err := getRegistry(c).contact.dao.Get(id)
+	if err != nil {
+		return nil, errors.New(getI18n(c).Localize(getContextString(c, ctxLocale), "error_db_001", &goyai.LocalizeConfig{
+			TemplateData: map[string]interface{}{"err": settingPrefixContactMessage + id + "/" + err.Error()},
+		}))
+	}
+	if msg == nil {
+		return nil, errors.New(getI18n(c).Localize(getContextString(c, ctxLocale), "error_contact_message_not_found", &goyai.LocalizeConfig{
+			TemplateData: map[string]interface{}{"id": id},
+		}))
+	}
+	return msg, nil
+}
+
+// actionCpInboxMessage shows the message of query parameter "id", its replies and the form to reply. The message is
+// marked as read.
+//
+// available since template-r5
+func actionCpInboxMessage(c echo.Context) error {
+	msg, err := checkCpInboxMessage(c)
+	if err != nil {
+		AddFlashText(c, FlashWarning, err.Error())
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpInbox)+"?r="+utils.RandomString(4))
+	}
+	myReg := getRegistry(c)
+	if !msg.Read {
+		msg.Read = true
+		if err := myReg.contact.dao.Save(msg); err != nil {
+			log.Printf("[WARN] cannot mark contact message [%s] as read: %s", msg.Id, err)
+		}
+	}
+	AddBreadcrumb(c, "", "contact_message")
+	return c.Render(http.StatusOK, namespace+":layout:cp_inbox_message", map[string]interface{}{
+		"active":   "inbox",
+		"message":  toContactMessageModel(c, msg),
+		"canReply": myReg.Outbox.HasSender(goadmin.OutboxChannelEmail),
+	})
+}
+
+// actionCpInboxReplySubmit emails a reply (form field "body") to the sender of the message of query parameter "id"
+// via the outbox, and keeps it with the message.
+//
+// available since template-r5
+func actionCpInboxReplySubmit(c echo.Context) error {
+	msg, err := checkCpInboxMessage(c)
+	if err != nil {
+		AddFlashText(c, FlashWarning, err.Error())
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpInbox)+"?r="+utils.RandomString(4))
+	}
+	myReg := getRegistry(c)
+	redirectUrl := toContactMessageModel(c, msg).UrlView() + "&r=" + utils.RandomString(4)
+	body := strings.TrimSpace(c.FormValue("body"))
+	if body == "" {
+		AddFlash(c, FlashWarning, "error_contact_reply_empty")
+		return c.Redirect(http.StatusFound, redirectUrl)
+	}
+	if !myReg.Outbox.HasSender(goadmin.OutboxChannelEmail) {
+		AddFlash(c, FlashWarning, "error_contact_reply_disabled")
+		return c.Redirect(http.StatusFound, redirectUrl)
+	}
+	subject := msg.Subject
+	if subject == "" {
+		subject = myReg.AppConfig.GetString("app.name")
+	}
+	email := &goadmin.EmailPayload{To: []string{msg.Email}, Subject: "Re: " + subject, Body: body}
+	if _, err := myReg.Outbox.Enqueue(goadmin.OutboxChannelEmail, email); err != nil {
+		AddFlash(c, FlashError, "error_db_001", "err", err.Error())
+		return c.Redirect(http.StatusFound, redirectUrl)
+	}
+	username := ""
+	if currentUser, _ := c.Get(ctxCurrentUser).(*User); currentUser != nil {
+		username = currentUser.Username
+	}
+	msg.Replies = append(msg.Replies, &ContactReply{By: username, Body: body, Time: utils.Now()})
+	if err := myReg.contact.dao.Save(msg); err != nil {
+		log.Printf("[WARN] cannot save reply to contact message [%s]: %s", msg.Id, err)
+	}
+	myReg.auditf("user [%s] replied to contact message [%s]", username, msg.Id)
+	AddFlash(c, FlashInfo, "contact_reply_sent", "email", msg.Email)
+	return c.Redirect(http.StatusFound, redirectUrl)
+}
+
+// actionCpInboxDeleteSubmit deletes the message of query parameter "id".
+//
+// available since template-r5
+func actionCpInboxDeleteSubmit(c echo.Context) error {
+	msg, err := checkCpInboxMessage(c)
+	if err != nil {
+		AddFlashText(c, FlashWarning, err.Error())
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpInbox)+"?r="+utils.RandomString(4))
+	}
+	myReg := getRegistry(c)
+	if _, err := myReg.contact.dao.Delete(msg.Id); err != nil {
+		AddFlash(c, FlashError, "error_db_001", "err", settingPrefixContactMessage+msg.Id+"/"+err.Error())
+	} else {
+		username := ""
+		if currentUser, _ := c.Get(ctxCurrentUser).(*User); currentUser != nil {
+			username = currentUser.Username
+		}
+		myReg.auditf("user [%s] deleted contact message [%s]", username, msg.Id)
+		AddFlash(c, FlashInfo, "delete_contact_message_successful")
+	}
+	return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpInbox)+"?r="+utils.RandomString(4))
+}
+
+// NumUnreadContactMessages returns the number of unread contact messages, -1 if the contact form is disabled.
+//
+// available since template-r5
+func (u *MyAppUtils) NumUnreadContactMessages() int {
+	f := getRegistry(u.c).contact
+	if f == nil {
+		return -1
+	}
+	msgs, err := f.dao.GetAll()
+	if err != nil {
+		log.Printf("[ERROR] cannot load contact messages: %s", err)
+		return 0
+	}
+	count := 0
+	for _, msg := range msgs {
+		if !msg.Read {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,86 @@
+package myapp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"main/src/apptest"
+	"main/src/goadmin"
+)
+
+func TestContact(t *testing.T) {
+	testName := "TestContact"
+	conf := apptest.SqliteInMemoryConfig + "\ngoadmin.smtp.addr = \"127.0.0.1:1\"\nmyapp.contact { enabled = true, recipients = [\"support@local\"], rate_limit = 2 }\n"
+	h := apptest.New(t, conf, NewBootstrapper(nil, nil))
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	emails := func() []*goadmin.EmailPayload {
+		msgs, _ := myReg.Outbox.Messages(goadmin.OutboxChannelEmail)
+		result := make([]*goadmin.EmailPayload, len(msgs))
+		for i, msg := range msgs {
+			result[i] = &goadmin.EmailPayload{}
+			json.Unmarshal(msg.Payload, result[i])
+		}
+		return result
+	}
+
+	h.AssertStatus(h.Get(h.Reverse(actionNameContact)), http.StatusOK)
+	form := url.Values{"name": {"Jane"}, "email": {"jane"}, "subject": {"Hello"}, "message": {"Do you ship abroad?"}}
+	h.AssertBodyContains(h.PostForm(h.Reverse(actionNameContactSubmit), form), "valid email")
+	form.Set("email", "jane@example.com")
+	h.AssertStatus(h.PostForm(h.Reverse(actionNameContactSubmit), form), http.StatusOK)
+	if h.LastData()["sent"] != true {
+		t.Fatalf("%s failed: message not sent", testName)
+	}
+	sent := emails()
+	if len(sent) != 1 || sent[0].To[0] != "support@local" || !strings.Contains(sent[0].Body, "Do you ship abroad?") {
+		t.Fatalf("%s failed: unexpected notifications %#v", testName, sent)
+	}
+	h.AssertStatus(h.PostForm(h.Reverse(actionNameContactSubmit), form), http.StatusOK)
+	// rate limit reached
+	h.AssertStatus(h.PostForm(h.Reverse(actionNameContactSubmit), form), http.StatusTooManyRequests)
+	msgs, _ := myReg.contact.dao.GetAll()
+	if len(msgs) != 2 || msgs[0].Email != "jane@example.com" || msgs[0].Read {
+		t.Fatalf("%s failed: unexpected messages %#v", testName, msgs)
+	}
+	// public forms are rejected in read-only mode too
+	myReg.readOnly.set(true)
+	h.AssertStatus(h.PostForm(h.Reverse(actionNameContactSubmit), form), http.StatusSeeOther)
+	myReg.readOnly.set(false)
+	if list, _ := myReg.contact.dao.GetAll(); len(list) != 2 {
+		t.Fatalf("%s failed: message stored in read-only mode", testName)
+	}
+	h.AssertBodyContains(h.Get("/sitemap.xml"), "/contact</loc>")
+
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpInbox)), http.StatusOK)
+	if models, _ := h.LastData()["messages"].([]*ContactMessageModel); len(models) != 2 {
+		t.Fatalf("%s failed: expected 2 messages in inbox, got %#v", testName, h.LastData()["messages"])
+	}
+	viewUrl := h.Reverse(actionNameCpInboxMessage) + "?id=" + msgs[0].Id
+	h.AssertBodyContains(h.Get(viewUrl), "Do you ship abroad?")
+	if msg, _ := myReg.contact.dao.Get(msgs[0].Id); msg == nil || !msg.Read {
+		t.Fatalf("%s failed: message must be marked as read", testName)
+	}
+
+	h.AssertRedirect(h.PostForm(h.Reverse(actionNameCpInboxReplySubmit)+"?id="+msgs[0].Id, url.Values{"body": {"Yes, we do."}}), h.Reverse(actionNameCpInboxMessage))
+	var reply *goadmin.EmailPayload
+	for _, email := range emails() {
+		if email.To[0] == "jane@example.com" {
+			reply = email
+		}
+	}
+	if reply == nil || reply.Subject != "Re: Hello" || reply.Body != "Yes, we do." {
+		t.Fatalf("%s failed: unexpected reply %#v", testName, reply)
+	}
+	if msg, _ := myReg.contact.dao.Get(msgs[0].Id); msg == nil || len(msg.Replies) != 1 || msg.Replies[0].By != testAdminUsername {
+		t.Fatalf("%s failed: reply must be kept with the message %#v", testName, msg)
+	}
+
+	h.AssertRedirect(h.PostForm(h.Reverse(actionNameCpInboxDeleteSubmit)+"?id="+msgs[0].Id, url.Values{}), h.Reverse(actionNameCpInbox))
+	if msg, _ := myReg.contact.dao.Get(msgs[0].Id); msg != nil {
+		t.Fatalf("%s failed: message must be deleted", testName)
+	}
+}
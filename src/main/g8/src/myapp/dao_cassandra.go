@@ -0,0 +1,368 @@
+package myapp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// newCassandraSession creates a session to an Apache Cassandra cluster.
+//
+// hosts is a comma-separated list of contact points (e.g. "cassandra1:9042,cassandra2:9042"); the keyspace must exist,
+// it is not created because its replication settings depend on the cluster. If username is not empty, password
+// authentication is used.
+func newCassandraSession(hosts, keyspace, username, password string) (*gocql.Session, error) {
+	cluster := gocql.NewCluster(strings.Split(hosts, ",")...)
+	cluster.Keyspace = keyspace
+	cluster.Consistency = gocql.Quorum
+	cluster.Timeout = 10 * time.Second
+	if username != "" {
+		cluster.Authenticator = gocql.PasswordAuthenticator{Username: username, Password: password}
+	}
+	return cluster.CreateSession()
+}
+
+// cassandraInitTable creates a table (if not exists) whose columns are all text, the first one being the primary key.
+func cassandraInitTable(session *gocql.Session, tableName string, columns ...string) {
+	colDefs := make([]string, len(columns))
+	for i, col := range columns {
+		colDefs[i] = col + " text"
+	}
+	colDefs[0] += " PRIMARY KEY"
+	cql := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", tableName, strings.Join(colDefs, ", "))
+	if err := session.Query(cql).Exec(); err != nil {
+		panic(err)
+	}
+}
+
+// cassandraApplied executes a lightweight transaction (INSERT ... IF NOT EXISTS, UPDATE/DELETE ... IF EXISTS) and
+// returns true if it was applied.
+func cassandraApplied(session *gocql.Session, cql string, values ...interface{}) (bool, error) {
+	return session.Query(cql, values...).MapScanCAS(map[string]interface{}{})
+}
+
+// cassandraGet fetches a row by primary key into dest, returns false if the row does not exist.
+func cassandraGet(session *gocql.Session, cql string, key string, dest ...interface{}) (bool, error) {
+	err := session.Query(cql, key).Scan(dest...)
+	if err == gocql.ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// cassandraScanAll fetches all rows of a query, scanFunc is called for each row.
+//
+// Rows are returned in token order of their partition keys: like DynamoDB, items are sorted and paged on client side.
+func cassandraScanAll(session *gocql.Session, cql string, scanFunc func(scanner gocql.Scanner) error) error {
+	scanner := session.Query(cql).Iter().Scanner()
+	for scanner.Next() {
+		if err := scanFunc(scanner); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+/*----------------------------------------------------------------------*/
+
+const (
+	cassandraTableGroup = namespace + "_group"
+)
+
+func cassandraInitTableGroup(session *gocql.Session, tableName string) {
+	cassandraInitTable(session, tableName, fieldGroupId, fieldGroupName, fieldGroupAttrs)
+}
+
+func newGroupDaoCassandra(session *gocql.Session, tableName string) GroupDao {
+	return &GroupDaoCassandra{session: session, tableName: tableName}
+}
+
+// GroupDaoCassandra is Apache Cassandra-based implementation of GroupDao.
+type GroupDaoCassandra struct {
+	session   *gocql.Session
+	tableName string
+}
+
+// Delete implements GroupDao.Delete
+func (dao *GroupDaoCassandra) Delete(bo *Group) (bool, error) {
+	cql := fmt.Sprintf("DELETE FROM %s WHERE %s=? IF EXISTS", dao.tableName, fieldGroupId)
+	return cassandraApplied(dao.session, cql, bo.Id)
+}
+
+// Create implements GroupDao.Create
+func (dao *GroupDaoCassandra) Create(id, name string) (bool, error) {
+	bo := &Group{
+		Id:   strings.ToLower(strings.TrimSpace(id)),
+		Name: strings.TrimSpace(name),
+	}
+	cql := fmt.Sprintf("INSERT INTO %s (%s, %s, %s) VALUES (?, ?, ?) IF NOT EXISTS", dao.tableName,
+		fieldGroupId, fieldGroupName, fieldGroupAttrs)
+	return cassandraApplied(dao.session, cql, bo.Id, bo.Name, encodeGroupAttrs(bo.Attrs))
+}
+
+// Get implements GroupDao.Get
+func (dao *GroupDaoCassandra) Get(id string) (*Group, error) {
+	cql := fmt.Sprintf("SELECT %s, %s, %s FROM %s WHERE %s=?", fieldGroupId, fieldGroupName, fieldGroupAttrs,
+		dao.tableName, fieldGroupId)
+	bo, attrs := &Group{}, ""
+	if found, err := cassandraGet(dao.session, cql, id, &bo.Id, &bo.Name, &attrs); !found {
+		return nil, err
+	}
+	bo.Attrs = decodeGroupAttrs(attrs)
+	return bo, nil
+}
+
+// GetN implements GroupDao.GetN
+func (dao *GroupDaoCassandra) GetN(fromOffset, maxNumRows int) ([]*Group, error) {
+	cql := fmt.Sprintf("SELECT %s, %s, %s FROM %s", fieldGroupId, fieldGroupName, fieldGroupAttrs, dao.tableName)
+	result := make([]*Group, 0)
+	err := cassandraScanAll(dao.session, cql, func(scanner gocql.Scanner) error {
+		bo, attrs := &Group{}, ""
+		if err := scanner.Scan(&bo.Id, &bo.Name, &attrs); err != nil {
+			return err
+		}
+		bo.Attrs = decodeGroupAttrs(attrs)
+		result = append(result, bo)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Id < result[j].Id })
+	from, to := dynamodbGetN(len(result), fromOffset, maxNumRows)
+	return result[from:to], nil
+}
+
+// GetAll implements GroupDao.GetAll
+func (dao *GroupDaoCassandra) GetAll() ([]*Group, error) {
+	return dao.GetN(0, 0)
+}
+
+// Update implements GroupDao.Update
+func (dao *GroupDaoCassandra) Update(bo *Group) (bool, error) {
+	cql := fmt.Sprintf("UPDATE %s SET %s=?, %s=? WHERE %s=? IF EXISTS", dao.tableName, fieldGroupName, fieldGroupAttrs,
+		fieldGroupId)
+	return cassandraApplied(dao.session, cql, bo.Name, encodeGroupAttrs(bo.Attrs), bo.Id)
+}
+
+/*----------------------------------------------------------------------*/
+
+const (
+	cassandraTableUser = namespace + "_user"
+)
+
+var cassandraUserColumns = []string{fieldUserUsername, fieldUserPassword, fieldUserName, fieldUserGroupId,
+	fieldUserFlags, fieldUserNotes, fieldUserTags}
+
+func cassandraInitTableUser(session *gocql.Session, tableName string) {
+	cassandraInitTable(session, tableName, cassandraUserColumns...)
+}
+
+func newUserDaoCassandra(session *gocql.Session, tableName string) UserDao {
+	return &UserDaoCassandra{session: session, tableName: tableName}
+}
+
+// UserDaoCassandra is Apache Cassandra-based implementation of UserDao.
+type UserDaoCassandra struct {
+	session   *gocql.Session
+	tableName string
+}
+
+// fields returns pointers to the user's fields, in the order of cassandraUserColumns.
+func (dao *UserDaoCassandra) fields(bo *User) []interface{} {
+	return []interface{}{&bo.Username, &bo.Password, &bo.Name, &bo.GroupId, &bo.Flags, &bo.Notes, &bo.Tags}
+}
+
+// Delete implements UserDao.Delete
+func (dao *UserDaoCassandra) Delete(bo *User) (bool, error) {
+	cql := fmt.Sprintf("DELETE FROM %s WHERE %s=? IF EXISTS", dao.tableName, fieldUserUsername)
+	return cassandraApplied(dao.session, cql, bo.Username)
+}
+
+// Create implements UserDao.Create
+func (dao *UserDaoCassandra) Create(username, encryptedPassword, name, groupId string) (bool, error) {
+	bo := &User{
+		Username: strings.ToLower(strings.TrimSpace(username)),
+		Password: strings.TrimSpace(encryptedPassword),
+		Name:     strings.TrimSpace(name),
+		GroupId:  strings.ToLower(strings.TrimSpace(groupId)),
+	}
+	cql := fmt.Sprintf("INSERT INTO %s (%s) VALUES (?%s) IF NOT EXISTS", dao.tableName,
+		strings.Join(cassandraUserColumns, ", "), strings.Repeat(", ?", len(cassandraUserColumns)-1))
+	return cassandraApplied(dao.session, cql, bo.Username, bo.Password, bo.Name, bo.GroupId, bo.Flags, bo.Notes, bo.Tags)
+}
+
+// Get implements UserDao.Get
+func (dao *UserDaoCassandra) Get(username string) (*User, error) {
+	cql := fmt.Sprintf("SELECT %s FROM %s WHERE %s=?", strings.Join(cassandraUserColumns, ", "), dao.tableName,
+		fieldUserUsername)
+	bo := &User{}
+	if found, err := cassandraGet(dao.session, cql, username, dao.fields(bo)...); !found {
+		return nil, err
+	}
+	return bo, nil
+}
+
+// GetN implements UserDao.GetN
+func (dao *UserDaoCassandra) GetN(fromOffset, maxNumRows int) ([]*User, error) {
+	cql := fmt.Sprintf("SELECT %s FROM %s", strings.Join(cassandraUserColumns, ", "), dao.tableName)
+	result := make([]*User, 0)
+	err := cassandraScanAll(dao.session, cql, func(scanner gocql.Scanner) error {
+		bo := &User{}
+		if err := scanner.Scan(dao.fields(bo)...); err != nil {
+			return err
+		}
+		result = append(result, bo)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Username < result[j].Username })
+	from, to := dynamodbGetN(len(result), fromOffset, maxNumRows)
+	return result[from:to], nil
+}
+
+// GetAll implements UserDao.GetAll
+func (dao *UserDaoCassandra) GetAll() ([]*User, error) {
+	return dao.GetN(0, 0)
+}
+
+// Update implements UserDao.Update
+func (dao *UserDaoCassandra) Update(bo *User) (bool, error) {
+	cql := fmt.Sprintf("UPDATE %s SET %s=?, %s=?, %s=?, %s=?, %s=?, %s=? WHERE %s=? IF EXISTS", dao.tableName,
+		fieldUserPassword, fieldUserName, fieldUserGroupId, fieldUserFlags, fieldUserNotes, fieldUserTags,
+		fieldUserUsername)
+	return cassandraApplied(dao.session, cql, bo.Password, bo.Name, bo.GroupId, bo.Flags, bo.Notes, bo.Tags, bo.Username)
+}
+
+/*----------------------------------------------------------------------*/
+
+const (
+	cassandraTableMessage = namespace + "_message"
+)
+
+func cassandraInitTableMessage(session *gocql.Session, tableName string) {
+	cassandraInitTable(session, tableName, fieldMessageId, fieldMessageLocale, fieldMessageKey, fieldMessageText)
+}
+
+func newMessageDaoCassandra(session *gocql.Session, tableName string) MessageDao {
+	return &MessageDaoCassandra{session: session, tableName: tableName}
+}
+
+// MessageDaoCassandra is Apache Cassandra-based implementation of MessageDao.
+type MessageDaoCassandra struct {
+	session   *gocql.Session
+	tableName string
+}
+
+// Delete implements MessageDao.Delete
+func (dao *MessageDaoCassandra) Delete(bo *Message) (bool, error) {
+	cql := fmt.Sprintf("DELETE FROM %s WHERE %s=? IF EXISTS", dao.tableName, fieldMessageId)
+	return cassandraApplied(dao.session, cql, bo.Id())
+}
+
+// Get implements MessageDao.Get
+func (dao *MessageDaoCassandra) Get(locale, key string) (*Message, error) {
+	cql := fmt.Sprintf("SELECT %s, %s, %s FROM %s WHERE %s=?", fieldMessageLocale, fieldMessageKey, fieldMessageText,
+		dao.tableName, fieldMessageId)
+	bo := &Message{}
+	id := (&Message{Locale: locale, Key: key}).Id()
+	if found, err := cassandraGet(dao.session, cql, id, &bo.Locale, &bo.Key, &bo.Text); !found {
+		return nil, err
+	}
+	return bo, nil
+}
+
+// GetAll implements MessageDao.GetAll
+func (dao *MessageDaoCassandra) GetAll() ([]*Message, error) {
+	cql := fmt.Sprintf("SELECT %s, %s, %s FROM %s", fieldMessageLocale, fieldMessageKey, fieldMessageText, dao.tableName)
+	result := make([]*Message, 0)
+	err := cassandraScanAll(dao.session, cql, func(scanner gocql.Scanner) error {
+		bo := &Message{}
+		if err := scanner.Scan(&bo.Locale, &bo.Key, &bo.Text); err != nil {
+			return err
+		}
+		result = append(result, bo)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Id() < result[j].Id() })
+	return result, nil
+}
+
+// Save implements MessageDao.Save
+func (dao *MessageDaoCassandra) Save(bo *Message) (bool, error) {
+	cql := fmt.Sprintf("INSERT INTO %s (%s, %s, %s, %s) VALUES (?, ?, ?, ?)", dao.tableName,
+		fieldMessageId, fieldMessageLocale, fieldMessageKey, fieldMessageText)
+	err := dao.session.Query(cql, bo.Id(), bo.Locale, bo.Key, bo.Text).Exec()
+	return err == nil, err
+}
+
+/*----------------------------------------------------------------------*/
+
+const (
+	cassandraTableSetting = namespace + "_setting"
+)
+
+func cassandraInitTableSetting(session *gocql.Session, tableName string) {
+	cassandraInitTable(session, tableName, fieldSettingId, fieldSettingValue)
+}
+
+func newSettingDaoCassandra(session *gocql.Session, tableName string) SettingDao {
+	return &SettingDaoCassandra{session: session, tableName: tableName}
+}
+
+// SettingDaoCassandra is Apache Cassandra-based implementation of SettingDao.
+type SettingDaoCassandra struct {
+	session   *gocql.Session
+	tableName string
+}
+
+// Delete implements SettingDao.Delete
+func (dao *SettingDaoCassandra) Delete(bo *Setting) (bool, error) {
+	cql := fmt.Sprintf("DELETE FROM %s WHERE %s=? IF EXISTS", dao.tableName, fieldSettingId)
+	return cassandraApplied(dao.session, cql, bo.Id)
+}
+
+// Get implements SettingDao.Get
+func (dao *SettingDaoCassandra) Get(id string) (*Setting, error) {
+	cql := fmt.Sprintf("SELECT %s, %s FROM %s WHERE %s=?", fieldSettingId, fieldSettingValue, dao.tableName,
+		fieldSettingId)
+	bo := &Setting{}
+	if found, err := cassandraGet(dao.session, cql, id, &bo.Id, &bo.Value); !found {
+		return nil, err
+	}
+	return bo, nil
+}
+
+// GetAll implements SettingDao.GetAll
+func (dao *SettingDaoCassandra) GetAll() ([]*Setting, error) {
+	cql := fmt.Sprintf("SELECT %s, %s FROM %s", fieldSettingId, fieldSettingValue, dao.tableName)
+	result := make([]*Setting, 0)
+	err := cassandraScanAll(dao.session, cql, func(scanner gocql.Scanner) error {
+		bo := &Setting{}
+		if err := scanner.Scan(&bo.Id, &bo.Value); err != nil {
+			return err
+		}
+		result = append(result, bo)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Id < result[j].Id })
+	return result, nil
+}
+
+// Save implements SettingDao.Save
+func (dao *SettingDaoCassandra) Save(bo *Setting) (bool, error) {
+	cql := fmt.Sprintf("INSERT INTO %s (%s, %s) VALUES (?, ?)", dao.tableName, fieldSettingId, fieldSettingValue)
+	err := dao.session.Query(cql, bo.Id, bo.Value).Exec()
+	return err == nil, err
+}
@@ -0,0 +1,190 @@
+package myapp
+
+import (
+	"os"
+	"testing"
+)
+
+func TestGroupDaoCassandra_GetNotExists(t *testing.T) {
+	testName := "TestGroupDaoCassandra_GetNotExists"
+	dao := _initGroupDaoCassandra(os.Getenv(envCassandraHosts), os.Getenv(envCassandraKeyspace), testCassandraTableNameGroup)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testGroupDaoGetNotExists(t, testName, dao)
+}
+
+func TestGroupDaoCassandra_CreateGet(t *testing.T) {
+	testName := "TestGroupDaoCassandra_CreateGet"
+	dao := _initGroupDaoCassandra(os.Getenv(envCassandraHosts), os.Getenv(envCassandraKeyspace), testCassandraTableNameGroup)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testGroupDaoCreateGet(t, testName, dao)
+}
+
+func TestGroupDaoCassandra_DeleteNotExists(t *testing.T) {
+	testName := "TestGroupDaoCassandra_DeleteNotExists"
+	dao := _initGroupDaoCassandra(os.Getenv(envCassandraHosts), os.Getenv(envCassandraKeyspace), testCassandraTableNameGroup)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testGroupDaoDeleteNotExists(t, testName, dao)
+}
+
+func TestGroupDaoCassandra_CreateDelete(t *testing.T) {
+	testName := "TestGroupDaoCassandra_CreateDelete"
+	dao := _initGroupDaoCassandra(os.Getenv(envCassandraHosts), os.Getenv(envCassandraKeyspace), testCassandraTableNameGroup)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testGroupDaoCreateDelete(t, testName, dao)
+}
+
+func TestGroupDaoCassandra_UpdateNotExists(t *testing.T) {
+	testName := "TestGroupDaoCassandra_UpdateNotExists"
+	dao := _initGroupDaoCassandra(os.Getenv(envCassandraHosts), os.Getenv(envCassandraKeyspace), testCassandraTableNameGroup)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testGroupDaoUpdateNotExists(t, testName, dao)
+}
+
+func TestGroupDaoCassandra_CreateUpdate(t *testing.T) {
+	testName := "TestGroupDaoCassandra_CreateUpdate"
+	dao := _initGroupDaoCassandra(os.Getenv(envCassandraHosts), os.Getenv(envCassandraKeyspace), testCassandraTableNameGroup)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testGroupDaoCreateUpdate(t, testName, dao)
+}
+
+func TestGroupDaoCassandra_GetN(t *testing.T) {
+	testName := "TestGroupDaoCassandra_GetN"
+	dao := _initGroupDaoCassandra(os.Getenv(envCassandraHosts), os.Getenv(envCassandraKeyspace), testCassandraTableNameGroup)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testGroupDaoGetN(t, testName, dao)
+}
+
+func TestGroupDaoCassandra_GetAll(t *testing.T) {
+	testName := "TestGroupDaoCassandra_GetAll"
+	dao := _initGroupDaoCassandra(os.Getenv(envCassandraHosts), os.Getenv(envCassandraKeyspace), testCassandraTableNameGroup)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testGroupDaoGetAll(t, testName, dao)
+}
+
+/*----------------------------------------------------------------------*/
+
+func TestUserDaoCassandra_GetNotExists(t *testing.T) {
+	testName := "TestUserDaoCassandra_GetNotExists"
+	dao := _initUserDaoCassandra(os.Getenv(envCassandraHosts), os.Getenv(envCassandraKeyspace), testCassandraTableNameUser)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testUserDaoGetNotExists(t, testName, dao)
+}
+
+func TestUserDaoCassandra_CreateGet(t *testing.T) {
+	testName := "TestUserDaoCassandra_CreateGet"
+	dao := _initUserDaoCassandra(os.Getenv(envCassandraHosts), os.Getenv(envCassandraKeyspace), testCassandraTableNameUser)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testUserDaoCreateGet(t, testName, dao)
+}
+
+func TestUserDaoCassandra_DeleteNotExists(t *testing.T) {
+	testName := "TestUserDaoCassandra_DeleteNotExists"
+	dao := _initUserDaoCassandra(os.Getenv(envCassandraHosts), os.Getenv(envCassandraKeyspace), testCassandraTableNameUser)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testUserDaoDeleteNotExists(t, testName, dao)
+}
+
+func TestUserDaoCassandra_CreateDelete(t *testing.T) {
+	testName := "TestUserDaoCassandra_CreateDelete"
+	dao := _initUserDaoCassandra(os.Getenv(envCassandraHosts), os.Getenv(envCassandraKeyspace), testCassandraTableNameUser)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testUserDaoCreateDelete(t, testName, dao)
+}
+
+func TestUserDaoCassandra_UpdateNotExists(t *testing.T) {
+	testName := "TestUserDaoCassandra_UpdateNotExists"
+	dao := _initUserDaoCassandra(os.Getenv(envCassandraHosts), os.Getenv(envCassandraKeyspace), testCassandraTableNameUser)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testUserDaoUpdateNotExists(t, testName, dao)
+}
+
+func TestUserDaoCassandra_CreateUpdate(t *testing.T) {
+	testName := "TestUserDaoCassandra_CreateUpdate"
+	dao := _initUserDaoCassandra(os.Getenv(envCassandraHosts), os.Getenv(envCassandraKeyspace), testCassandraTableNameUser)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testUserDaoCreateUpdate(t, testName, dao)
+}
+
+func TestUserDaoCassandra_GetN(t *testing.T) {
+	testName := "TestUserDaoCassandra_GetN"
+	dao := _initUserDaoCassandra(os.Getenv(envCassandraHosts), os.Getenv(envCassandraKeyspace), testCassandraTableNameUser)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testUserDaoGetN(t, testName, dao)
+}
+
+func TestUserDaoCassandra_GetAll(t *testing.T) {
+	testName := "TestUserDaoCassandra_GetAll"
+	dao := _initUserDaoCassandra(os.Getenv(envCassandraHosts), os.Getenv(envCassandraKeyspace), testCassandraTableNameUser)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testUserDaoGetAll(t, testName, dao)
+}
+
+/*----------------------------------------------------------------------*/
+
+func TestMessageDaoCassandra_SaveGetDelete(t *testing.T) {
+	testName := "TestMessageDaoCassandra_SaveGetDelete"
+	dao := _initMessageDaoCassandra(os.Getenv(envCassandraHosts), os.Getenv(envCassandraKeyspace), testCassandraTableNameMessage)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testMessageDaoSaveGetDelete(t, testName, dao)
+}
+
+func TestMessageDaoCassandra_GetAll(t *testing.T) {
+	testName := "TestMessageDaoCassandra_GetAll"
+	dao := _initMessageDaoCassandra(os.Getenv(envCassandraHosts), os.Getenv(envCassandraKeyspace), testCassandraTableNameMessage)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testMessageDaoGetAll(t, testName, dao)
+}
+
+func TestSettingDaoCassandra_SaveGetDelete(t *testing.T) {
+	testName := "TestSettingDaoCassandra_SaveGetDelete"
+	dao := _initSettingDaoCassandra(os.Getenv(envCassandraHosts), os.Getenv(envCassandraKeyspace), testCassandraTableNameSetting)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testSettingDaoSaveGetDelete(t, testName, dao)
+}
+
+func TestSettingDaoCassandra_GetAll(t *testing.T) {
+	testName := "TestSettingDaoCassandra_GetAll"
+	dao := _initSettingDaoCassandra(os.Getenv(envCassandraHosts), os.Getenv(envCassandraKeyspace), testCassandraTableNameSetting)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testSettingDaoGetAll(t, testName, dao)
+}
@@ -0,0 +1,471 @@
+package myapp
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	awsdynamodb "github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/btnguyen2k/consu/reddo"
+	"github.com/btnguyen2k/godal"
+	"github.com/btnguyen2k/godal/dynamodb"
+	prom "github.com/btnguyen2k/prom/dynamodb"
+)
+
+// newAwsDynamodbConnection creates a connection to AWS DynamoDB.
+//
+// AWS credentials are read from env AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY. If endpoint is not empty (e.g. a
+// local DynamoDB instance at http://localhost:8000), it is used instead of AWS's default endpoint.
+func newAwsDynamodbConnection(region, endpoint string) *prom.AwsDynamodbConnect {
+	cfg := &aws.Config{
+		Region:      aws.String(region),
+		Credentials: credentials.NewEnvCredentials(),
+	}
+	if endpoint != "" {
+		cfg.Endpoint = aws.String(endpoint)
+		if strings.HasPrefix(endpoint, "http://") {
+			cfg.DisableSSL = aws.Bool(true)
+		}
+	}
+	adc, err := prom.NewAwsDynamodbConnect(cfg, nil, nil, 10000)
+	if err != nil {
+		panic(err)
+	}
+	return adc
+}
+
+// dynamodbInitTable creates a table (if not exists) whose partition key is a string attribute, and waits until the
+// table is ready.
+func dynamodbInitTable(adc *prom.AwsDynamodbConnect, tableName, pkAttr string) {
+	err := adc.CreateTable(nil, tableName, 2, 2,
+		[]prom.AwsDynamodbNameAndType{{Name: pkAttr, Type: prom.AwsAttrTypeString}},
+		[]prom.AwsDynamodbNameAndType{{Name: pkAttr, Type: prom.AwsKeyTypePartition}})
+	if prom.AwsIgnoreErrorIfMatched(err, awsdynamodb.ErrCodeResourceInUseException) != nil {
+		panic(err)
+	}
+	prom.AwsDynamodbWaitForTableStatus(adc, tableName, []string{"ACTIVE"}, 1*time.Second, 30*time.Second)
+}
+
+// dynamodbGetN returns the lower (inclusive) and upper (exclusive) bounds of the page [fromOffset, fromOffset+maxNumRows)
+// within a list of numItems items.
+//
+// DynamoDB does not support custom sorting of scanned items, hence items are fetched, sorted and paged on client side.
+func dynamodbGetN(numItems, fromOffset, maxNumRows int) (int, int) {
+	if fromOffset < 0 {
+		fromOffset = 0
+	}
+	if fromOffset > numItems {
+		fromOffset = numItems
+	}
+	toOffset := numItems
+	if maxNumRows > 0 && fromOffset+maxNumRows < numItems {
+		toOffset = fromOffset + maxNumRows
+	}
+	return fromOffset, toOffset
+}
+
+/*----------------------------------------------------------------------*/
+
+const (
+	dynamodbTableGroup = namespace + "_group"
+)
+
+func dynamodbInitTableGroup(adc *prom.AwsDynamodbConnect, tableName string) {
+	dynamodbInitTable(adc, tableName, fieldGroupId)
+}
+
+func newGroupDaoDynamodb(adc *prom.AwsDynamodbConnect, tableName string) GroupDao {
+	dao := &GroupDaoDynamodb{tableName: tableName}
+	dao.GenericDaoDynamodb = dynamodb.NewGenericDaoDynamodb(adc, godal.NewAbstractGenericDao(dao))
+	dao.SetRowMapper(&dynamodb.GenericRowMapperDynamodb{ColumnsListMap: map[string][]string{tableName: {fieldGroupId}}})
+	return dao
+}
+
+// GroupDaoDynamodb is AWS DynamoDB-based implementation of GroupDao.
+type GroupDaoDynamodb struct {
+	*dynamodb.GenericDaoDynamodb
+	tableName string
+}
+
+// GdaoCreateFilter implements IGenericDao.GdaoCreateFilter
+func (dao *GroupDaoDynamodb) GdaoCreateFilter(tableName string, bo godal.IGenericBo) godal.FilterOpt {
+	id, _ := bo.GboGetAttr(fieldGroupId, reddo.TypeString)
+	return godal.MakeFilter(map[string]interface{}{fieldGroupId: id})
+}
+
+// it is recommended to have a function that transforms godal.IGenericBo to business object and vice versa.
+func (dao *GroupDaoDynamodb) toBo(gbo godal.IGenericBo) *Group {
+	if gbo == nil {
+		return nil
+	}
+	bo := &Group{
+		Id:   gbo.GboGetAttrUnsafe(fieldGroupId, reddo.TypeString).(string),
+		Name: gbo.GboGetAttrUnsafe(fieldGroupName, reddo.TypeString).(string),
+	}
+	// records created before custom fields were introduced have no attributes
+	attrs, _ := gbo.GboGetAttrUnsafe(fieldGroupAttrs, reddo.TypeString).(string)
+	bo.Attrs = decodeGroupAttrs(attrs)
+	return bo
+}
+
+// it is recommended to have a function that transforms godal.IGenericBo to business object and vice versa.
+func (dao *GroupDaoDynamodb) toGbo(bo *Group) godal.IGenericBo {
+	if bo == nil {
+		return nil
+	}
+	gbo := godal.NewGenericBo()
+	gbo.GboSetAttr(fieldGroupId, bo.Id)
+	gbo.GboSetAttr(fieldGroupName, bo.Name)
+	gbo.GboSetAttr(fieldGroupAttrs, encodeGroupAttrs(bo.Attrs))
+	return gbo
+}
+
+// Delete implements GroupDao.Delete
+func (dao *GroupDaoDynamodb) Delete(bo *Group) (bool, error) {
+	numRows, err := dao.GdaoDelete(dao.tableName, dao.toGbo(bo))
+	return numRows > 0, err
+}
+
+// Create implements GroupDao.Create
+func (dao *GroupDaoDynamodb) Create(id, name string) (bool, error) {
+	bo := &Group{
+		Id:   strings.ToLower(strings.TrimSpace(id)),
+		Name: strings.TrimSpace(name),
+	}
+	numRows, err := dao.GdaoCreate(dao.tableName, dao.toGbo(bo))
+	return numRows > 0, err
+}
+
+// Get implements GroupDao.Get
+func (dao *GroupDaoDynamodb) Get(id string) (*Group, error) {
+	filter := godal.MakeFilter(map[string]interface{}{fieldGroupId: id})
+	gbo, err := dao.GdaoFetchOne(dao.tableName, filter)
+	if err != nil {
+		return nil, err
+	}
+	return dao.toBo(gbo), nil
+}
+
+// GetN implements GroupDao.GetN
+func (dao *GroupDaoDynamodb) GetN(fromOffset, maxNumRows int) ([]*Group, error) {
+	gboList, err := dao.GdaoFetchMany(dao.tableName, nil, nil, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*Group, len(gboList))
+	for i, gbo := range gboList {
+		result[i] = dao.toBo(gbo)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Id < result[j].Id })
+	from, to := dynamodbGetN(len(result), fromOffset, maxNumRows)
+	return result[from:to], nil
+}
+
+// GetAll implements GroupDao.GetAll
+func (dao *GroupDaoDynamodb) GetAll() ([]*Group, error) {
+	return dao.GetN(0, 0)
+}
+
+// Update implements GroupDao.Update
+func (dao *GroupDaoDynamodb) Update(bo *Group) (bool, error) {
+	numRows, err := dao.GdaoUpdate(dao.tableName, dao.toGbo(bo))
+	return numRows > 0, err
+}
+
+/*----------------------------------------------------------------------*/
+
+const (
+	dynamodbTableUser = namespace + "_user"
+)
+
+func dynamodbInitTableUser(adc *prom.AwsDynamodbConnect, tableName string) {
+	dynamodbInitTable(adc, tableName, fieldUserUsername)
+}
+
+func newUserDaoDynamodb(adc *prom.AwsDynamodbConnect, tableName string) UserDao {
+	dao := &UserDaoDynamodb{tableName: tableName}
+	dao.GenericDaoDynamodb = dynamodb.NewGenericDaoDynamodb(adc, godal.NewAbstractGenericDao(dao))
+	dao.SetRowMapper(&dynamodb.GenericRowMapperDynamodb{ColumnsListMap: map[string][]string{tableName: {fieldUserUsername}}})
+	return dao
+}
+
+// UserDaoDynamodb is AWS DynamoDB-based implementation of UserDao.
+type UserDaoDynamodb struct {
+	*dynamodb.GenericDaoDynamodb
+	tableName string
+}
+
+// GdaoCreateFilter implements IGenericDao.GdaoCreateFilter
+func (dao *UserDaoDynamodb) GdaoCreateFilter(tableName string, bo godal.IGenericBo) godal.FilterOpt {
+	username, _ := bo.GboGetAttr(fieldUserUsername, reddo.TypeString)
+	return godal.MakeFilter(map[string]interface{}{fieldUserUsername: username})
+}
+
+// it is recommended to have a function that transforms godal.IGenericBo to business object and vice versa.
+func (dao *UserDaoDynamodb) toBo(gbo godal.IGenericBo) *User {
+	if gbo == nil {
+		return nil
+	}
+	bo := &User{
+		Username: gbo.GboGetAttrUnsafe(fieldUserUsername, reddo.TypeString).(string),
+		Password: gbo.GboGetAttrUnsafe(fieldUserPassword, reddo.TypeString).(string),
+		Name:     gbo.GboGetAttrUnsafe(fieldUserName, reddo.TypeString).(string),
+		GroupId:  gbo.GboGetAttrUnsafe(fieldUserGroupId, reddo.TypeString).(string),
+	}
+	// records created before flags, notes and tags were introduced have none
+	bo.Flags, _ = gbo.GboGetAttrUnsafe(fieldUserFlags, reddo.TypeString).(string)
+	bo.Notes, _ = gbo.GboGetAttrUnsafe(fieldUserNotes, reddo.TypeString).(string)
+	bo.Tags, _ = gbo.GboGetAttrUnsafe(fieldUserTags, reddo.TypeString).(string)
+	return bo
+}
+
+// it is recommended to have a function that transforms godal.IGenericBo to business object and vice versa.
+func (dao *UserDaoDynamodb) toGbo(bo *User) godal.IGenericBo {
+	if bo == nil {
+		return nil
+	}
+	gbo := godal.NewGenericBo()
+	gbo.GboSetAttr(fieldUserUsername, bo.Username)
+	gbo.GboSetAttr(fieldUserPassword, bo.Password)
+	gbo.GboSetAttr(fieldUserName, bo.Name)
+	gbo.GboSetAttr(fieldUserGroupId, bo.GroupId)
+	gbo.GboSetAttr(fieldUserFlags, bo.Flags)
+	gbo.GboSetAttr(fieldUserNotes, bo.Notes)
+	gbo.GboSetAttr(fieldUserTags, bo.Tags)
+	return gbo
+}
+
+// Delete implements UserDao.Delete
+func (dao *UserDaoDynamodb) Delete(bo *User) (bool, error) {
+	numRows, err := dao.GdaoDelete(dao.tableName, dao.toGbo(bo))
+	return numRows > 0, err
+}
+
+// Create implements UserDao.Create
+func (dao *UserDaoDynamodb) Create(username, encryptedPassword, name, groupId string) (bool, error) {
+	bo := &User{
+		Username: strings.ToLower(strings.TrimSpace(username)),
+		Password: strings.TrimSpace(encryptedPassword),
+		Name:     strings.TrimSpace(name),
+		GroupId:  strings.ToLower(strings.TrimSpace(groupId)),
+	}
+	numRows, err := dao.GdaoCreate(dao.tableName, dao.toGbo(bo))
+	return numRows > 0, err
+}
+
+// Get implements UserDao.Get
+func (dao *UserDaoDynamodb) Get(username string) (*User, error) {
+	filter := godal.MakeFilter(map[string]interface{}{fieldUserUsername: username})
+	gbo, err := dao.GdaoFetchOne(dao.tableName, filter)
+	if err != nil {
+		return nil, err
+	}
+	return dao.toBo(gbo), nil
+}
+
+// GetN implements UserDao.GetN
+func (dao *UserDaoDynamodb) GetN(fromOffset, maxNumRows int) ([]*User, error) {
+	gboList, err := dao.GdaoFetchMany(dao.tableName, nil, nil, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*User, len(gboList))
+	for i, gbo := range gboList {
+		result[i] = dao.toBo(gbo)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Username < result[j].Username })
+	from, to := dynamodbGetN(len(result), fromOffset, maxNumRows)
+	return result[from:to], nil
+}
+
+// GetAll implements UserDao.GetAll
+func (dao *UserDaoDynamodb) GetAll() ([]*User, error) {
+	return dao.GetN(0, 0)
+}
+
+// Update implements UserDao.Update
+func (dao *UserDaoDynamodb) Update(bo *User) (bool, error) {
+	numRows, err := dao.GdaoUpdate(dao.tableName, dao.toGbo(bo))
+	return numRows > 0, err
+}
+
+/*----------------------------------------------------------------------*/
+
+const (
+	dynamodbTableMessage = namespace + "_message"
+)
+
+func dynamodbInitTableMessage(adc *prom.AwsDynamodbConnect, tableName string) {
+	dynamodbInitTable(adc, tableName, fieldMessageId)
+}
+
+func newMessageDaoDynamodb(adc *prom.AwsDynamodbConnect, tableName string) MessageDao {
+	dao := &MessageDaoDynamodb{tableName: tableName}
+	dao.GenericDaoDynamodb = dynamodb.NewGenericDaoDynamodb(adc, godal.NewAbstractGenericDao(dao))
+	dao.SetRowMapper(&dynamodb.GenericRowMapperDynamodb{ColumnsListMap: map[string][]string{tableName: {fieldMessageId}}})
+	return dao
+}
+
+// MessageDaoDynamodb is AWS DynamoDB-based implementation of MessageDao.
+type MessageDaoDynamodb struct {
+	*dynamodb.GenericDaoDynamodb
+	tableName string
+}
+
+// GdaoCreateFilter implements IGenericDao.GdaoCreateFilter
+func (dao *MessageDaoDynamodb) GdaoCreateFilter(tableName string, bo godal.IGenericBo) godal.FilterOpt {
+	id, _ := bo.GboGetAttr(fieldMessageId, reddo.TypeString)
+	return godal.MakeFilter(map[string]interface{}{fieldMessageId: id})
+}
+
+// it is recommended to have a function that transforms godal.IGenericBo to business object and vice versa.
+func (dao *MessageDaoDynamodb) toBo(gbo godal.IGenericBo) *Message {
+	if gbo == nil {
+		return nil
+	}
+	bo := &Message{
+		Locale: gbo.GboGetAttrUnsafe(fieldMessageLocale, reddo.TypeString).(string),
+		Key:    gbo.GboGetAttrUnsafe(fieldMessageKey, reddo.TypeString).(string),
+		Text:   gbo.GboGetAttrUnsafe(fieldMessageText, reddo.TypeString).(string),
+	}
+	return bo
+}
+
+// it is recommended to have a function that transforms godal.IGenericBo to business object and vice versa.
+func (dao *MessageDaoDynamodb) toGbo(bo *Message) godal.IGenericBo {
+	if bo == nil {
+		return nil
+	}
+	gbo := godal.NewGenericBo()
+	gbo.GboSetAttr(fieldMessageId, bo.Id())
+	gbo.GboSetAttr(fieldMessageLocale, bo.Locale)
+	gbo.GboSetAttr(fieldMessageKey, bo.Key)
+	gbo.GboSetAttr(fieldMessageText, bo.Text)
+	return gbo
+}
+
+// Delete implements MessageDao.Delete
+func (dao *MessageDaoDynamodb) Delete(bo *Message) (bool, error) {
+	numRows, err := dao.GdaoDelete(dao.tableName, dao.toGbo(bo))
+	return numRows > 0, err
+}
+
+// Get implements MessageDao.Get
+func (dao *MessageDaoDynamodb) Get(locale, key string) (*Message, error) {
+	filter := godal.MakeFilter(map[string]interface{}{fieldMessageId: (&Message{Locale: locale, Key: key}).Id()})
+	gbo, err := dao.GdaoFetchOne(dao.tableName, filter)
+	if err != nil {
+		return nil, err
+	}
+	return dao.toBo(gbo), nil
+}
+
+// GetAll implements MessageDao.GetAll
+func (dao *MessageDaoDynamodb) GetAll() ([]*Message, error) {
+	gboList, err := dao.GdaoFetchMany(dao.tableName, nil, nil, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*Message, len(gboList))
+	for i, gbo := range gboList {
+		result[i] = dao.toBo(gbo)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Id() < result[j].Id() })
+	return result, nil
+}
+
+// Save implements MessageDao.Save
+func (dao *MessageDaoDynamodb) Save(bo *Message) (bool, error) {
+	numRows, err := dao.GdaoSave(dao.tableName, dao.toGbo(bo))
+	return numRows > 0, err
+}
+
+/*----------------------------------------------------------------------*/
+
+const (
+	dynamodbTableSetting = namespace + "_setting"
+)
+
+func dynamodbInitTableSetting(adc *prom.AwsDynamodbConnect, tableName string) {
+	dynamodbInitTable(adc, tableName, fieldSettingId)
+}
+
+func newSettingDaoDynamodb(adc *prom.AwsDynamodbConnect, tableName string) SettingDao {
+	dao := &SettingDaoDynamodb{tableName: tableName}
+	dao.GenericDaoDynamodb = dynamodb.NewGenericDaoDynamodb(adc, godal.NewAbstractGenericDao(dao))
+	dao.SetRowMapper(&dynamodb.GenericRowMapperDynamodb{ColumnsListMap: map[string][]string{tableName: {fieldSettingId}}})
+	return dao
+}
+
+// SettingDaoDynamodb is AWS DynamoDB-based implementation of SettingDao.
+type SettingDaoDynamodb struct {
+	*dynamodb.GenericDaoDynamodb
+	tableName string
+}
+
+// GdaoCreateFilter implements IGenericDao.GdaoCreateFilter
+func (dao *SettingDaoDynamodb) GdaoCreateFilter(tableName string, bo godal.IGenericBo) godal.FilterOpt {
+	id, _ := bo.GboGetAttr(fieldSettingId, reddo.TypeString)
+	return godal.MakeFilter(map[string]interface{}{fieldSettingId: id})
+}
+
+// it is recommended to have a function that transforms godal.IGenericBo to business object and vice versa.
+func (dao *SettingDaoDynamodb) toBo(gbo godal.IGenericBo) *Setting {
+	if gbo == nil {
+		return nil
+	}
+	bo := &Setting{
+		Id:    gbo.GboGetAttrUnsafe(fieldSettingId, reddo.TypeString).(string),
+		Value: gbo.GboGetAttrUnsafe(fieldSettingValue, reddo.TypeString).(string),
+	}
+	return bo
+}
+
+// it is recommended to have a function that transforms godal.IGenericBo to business object and vice versa.
+func (dao *SettingDaoDynamodb) toGbo(bo *Setting) godal.IGenericBo {
+	if bo == nil {
+		return nil
+	}
+	gbo := godal.NewGenericBo()
+	gbo.GboSetAttr(fieldSettingId, bo.Id)
+	gbo.GboSetAttr(fieldSettingValue, bo.Value)
+	return gbo
+}
+
+// Delete implements SettingDao.Delete
+func (dao *SettingDaoDynamodb) Delete(bo *Setting) (bool, error) {
+	numRows, err := dao.GdaoDelete(dao.tableName, dao.toGbo(bo))
+	return numRows > 0, err
+}
+
+// Get implements SettingDao.Get
+func (dao *SettingDaoDynamodb) Get(id string) (*Setting, error) {
+	filter := godal.MakeFilter(map[string]interface{}{fieldSettingId: id})
+	gbo, err := dao.GdaoFetchOne(dao.tableName, filter)
+	if err != nil {
+		return nil, err
+	}
+	return dao.toBo(gbo), nil
+}
+
+// GetAll implements SettingDao.GetAll
+func (dao *SettingDaoDynamodb) GetAll() ([]*Setting, error) {
+	gboList, err := dao.GdaoFetchMany(dao.tableName, nil, nil, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*Setting, len(gboList))
+	for i, gbo := range gboList {
+		result[i] = dao.toBo(gbo)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Id < result[j].Id })
+	return result, nil
+}
+
+// Save implements SettingDao.Save
+func (dao *SettingDaoDynamodb) Save(bo *Setting) (bool, error) {
+	numRows, err := dao.GdaoSave(dao.tableName, dao.toGbo(bo))
+	return numRows > 0, err
+}
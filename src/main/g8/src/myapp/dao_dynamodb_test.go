@@ -0,0 +1,190 @@
+package myapp
+
+import (
+	"os"
+	"testing"
+)
+
+func TestGroupDaoDynamodb_GetNotExists(t *testing.T) {
+	testName := "TestGroupDaoDynamodb_GetNotExists"
+	dao := _initGroupDaoDynamodb(os.Getenv(envAwsRegion), os.Getenv(envAwsDynamodbEndpoint), testDynamodbTableNameGroup)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testGroupDaoGetNotExists(t, testName, dao)
+}
+
+func TestGroupDaoDynamodb_CreateGet(t *testing.T) {
+	testName := "TestGroupDaoDynamodb_CreateGet"
+	dao := _initGroupDaoDynamodb(os.Getenv(envAwsRegion), os.Getenv(envAwsDynamodbEndpoint), testDynamodbTableNameGroup)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testGroupDaoCreateGet(t, testName, dao)
+}
+
+func TestGroupDaoDynamodb_DeleteNotExists(t *testing.T) {
+	testName := "TestGroupDaoDynamodb_DeleteNotExists"
+	dao := _initGroupDaoDynamodb(os.Getenv(envAwsRegion), os.Getenv(envAwsDynamodbEndpoint), testDynamodbTableNameGroup)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testGroupDaoDeleteNotExists(t, testName, dao)
+}
+
+func TestGroupDaoDynamodb_CreateDelete(t *testing.T) {
+	testName := "TestGroupDaoDynamodb_CreateDelete"
+	dao := _initGroupDaoDynamodb(os.Getenv(envAwsRegion), os.Getenv(envAwsDynamodbEndpoint), testDynamodbTableNameGroup)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testGroupDaoCreateDelete(t, testName, dao)
+}
+
+func TestGroupDaoDynamodb_UpdateNotExists(t *testing.T) {
+	testName := "TestGroupDaoDynamodb_UpdateNotExists"
+	dao := _initGroupDaoDynamodb(os.Getenv(envAwsRegion), os.Getenv(envAwsDynamodbEndpoint), testDynamodbTableNameGroup)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testGroupDaoUpdateNotExists(t, testName, dao)
+}
+
+func TestGroupDaoDynamodb_CreateUpdate(t *testing.T) {
+	testName := "TestGroupDaoDynamodb_CreateUpdate"
+	dao := _initGroupDaoDynamodb(os.Getenv(envAwsRegion), os.Getenv(envAwsDynamodbEndpoint), testDynamodbTableNameGroup)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testGroupDaoCreateUpdate(t, testName, dao)
+}
+
+func TestGroupDaoDynamodb_GetN(t *testing.T) {
+	testName := "TestGroupDaoDynamodb_GetN"
+	dao := _initGroupDaoDynamodb(os.Getenv(envAwsRegion), os.Getenv(envAwsDynamodbEndpoint), testDynamodbTableNameGroup)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testGroupDaoGetN(t, testName, dao)
+}
+
+func TestGroupDaoDynamodb_GetAll(t *testing.T) {
+	testName := "TestGroupDaoDynamodb_GetAll"
+	dao := _initGroupDaoDynamodb(os.Getenv(envAwsRegion), os.Getenv(envAwsDynamodbEndpoint), testDynamodbTableNameGroup)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testGroupDaoGetAll(t, testName, dao)
+}
+
+/*----------------------------------------------------------------------*/
+
+func TestUserDaoDynamodb_GetNotExists(t *testing.T) {
+	testName := "TestUserDaoDynamodb_GetNotExists"
+	dao := _initUserDaoDynamodb(os.Getenv(envAwsRegion), os.Getenv(envAwsDynamodbEndpoint), testDynamodbTableNameUser)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testUserDaoGetNotExists(t, testName, dao)
+}
+
+func TestUserDaoDynamodb_CreateGet(t *testing.T) {
+	testName := "TestUserDaoDynamodb_CreateGet"
+	dao := _initUserDaoDynamodb(os.Getenv(envAwsRegion), os.Getenv(envAwsDynamodbEndpoint), testDynamodbTableNameUser)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testUserDaoCreateGet(t, testName, dao)
+}
+
+func TestUserDaoDynamodb_DeleteNotExists(t *testing.T) {
+	testName := "TestUserDaoDynamodb_DeleteNotExists"
+	dao := _initUserDaoDynamodb(os.Getenv(envAwsRegion), os.Getenv(envAwsDynamodbEndpoint), testDynamodbTableNameUser)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testUserDaoDeleteNotExists(t, testName, dao)
+}
+
+func TestUserDaoDynamodb_CreateDelete(t *testing.T) {
+	testName := "TestUserDaoDynamodb_CreateDelete"
+	dao := _initUserDaoDynamodb(os.Getenv(envAwsRegion), os.Getenv(envAwsDynamodbEndpoint), testDynamodbTableNameUser)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testUserDaoCreateDelete(t, testName, dao)
+}
+
+func TestUserDaoDynamodb_UpdateNotExists(t *testing.T) {
+	testName := "TestUserDaoDynamodb_UpdateNotExists"
+	dao := _initUserDaoDynamodb(os.Getenv(envAwsRegion), os.Getenv(envAwsDynamodbEndpoint), testDynamodbTableNameUser)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testUserDaoUpdateNotExists(t, testName, dao)
+}
+
+func TestUserDaoDynamodb_CreateUpdate(t *testing.T) {
+	testName := "TestUserDaoDynamodb_CreateUpdate"
+	dao := _initUserDaoDynamodb(os.Getenv(envAwsRegion), os.Getenv(envAwsDynamodbEndpoint), testDynamodbTableNameUser)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testUserDaoCreateUpdate(t, testName, dao)
+}
+
+func TestUserDaoDynamodb_GetN(t *testing.T) {
+	testName := "TestUserDaoDynamodb_GetN"
+	dao := _initUserDaoDynamodb(os.Getenv(envAwsRegion), os.Getenv(envAwsDynamodbEndpoint), testDynamodbTableNameUser)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testUserDaoGetN(t, testName, dao)
+}
+
+func TestUserDaoDynamodb_GetAll(t *testing.T) {
+	testName := "TestUserDaoDynamodb_GetAll"
+	dao := _initUserDaoDynamodb(os.Getenv(envAwsRegion), os.Getenv(envAwsDynamodbEndpoint), testDynamodbTableNameUser)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testUserDaoGetAll(t, testName, dao)
+}
+
+/*----------------------------------------------------------------------*/
+
+func TestMessageDaoDynamodb_SaveGetDelete(t *testing.T) {
+	testName := "TestMessageDaoDynamodb_SaveGetDelete"
+	dao := _initMessageDaoDynamodb(os.Getenv(envAwsRegion), os.Getenv(envAwsDynamodbEndpoint), testDynamodbTableNameMessage)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testMessageDaoSaveGetDelete(t, testName, dao)
+}
+
+func TestMessageDaoDynamodb_GetAll(t *testing.T) {
+	testName := "TestMessageDaoDynamodb_GetAll"
+	dao := _initMessageDaoDynamodb(os.Getenv(envAwsRegion), os.Getenv(envAwsDynamodbEndpoint), testDynamodbTableNameMessage)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testMessageDaoGetAll(t, testName, dao)
+}
+
+func TestSettingDaoDynamodb_SaveGetDelete(t *testing.T) {
+	testName := "TestSettingDaoDynamodb_SaveGetDelete"
+	dao := _initSettingDaoDynamodb(os.Getenv(envAwsRegion), os.Getenv(envAwsDynamodbEndpoint), testDynamodbTableNameSetting)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testSettingDaoSaveGetDelete(t, testName, dao)
+}
+
+func TestSettingDaoDynamodb_GetAll(t *testing.T) {
+	testName := "TestSettingDaoDynamodb_GetAll"
+	dao := _initSettingDaoDynamodb(os.Getenv(envAwsRegion), os.Getenv(envAwsDynamodbEndpoint), testDynamodbTableNameSetting)
+	if dao == nil {
+		t.SkipNow()
+	}
+	testSettingDaoGetAll(t, testName, dao)
+}
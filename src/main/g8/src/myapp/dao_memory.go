@@ -0,0 +1,411 @@
+package myapp
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// in-memory DAO implementations: data is kept in process memory and is lost when the application stops.
+// They are meant for unit tests and local development; do not use them in production!
+
+func memoryGetN(ids []string, fromOffset, maxNumRows int) []string {
+	sort.Strings(ids)
+	if fromOffset < 0 {
+		fromOffset = 0
+	}
+	if fromOffset >= len(ids) {
+		return []string{}
+	}
+	ids = ids[fromOffset:]
+	if maxNumRows > 0 && maxNumRows < len(ids) {
+		ids = ids[:maxNumRows]
+	}
+	return ids
+}
+
+// memoryGetNAfter returns at most maxNumRows ids (all if maxNumRows <= 0) sorting after afterId.
+func memoryGetNAfter(ids []string, afterId string, maxNumRows int) []string {
+	sort.Strings(ids)
+	ids = ids[sort.Search(len(ids), func(i int) bool { return ids[i] > afterId }):]
+	if maxNumRows > 0 && maxNumRows < len(ids) {
+		ids = ids[:maxNumRows]
+	}
+	return ids
+}
+
+/*----------------------------------------------------------------------*/
+
+func newGroupDaoMemory() GroupDao {
+	return &GroupDaoMemory{storage: make(map[string]Group)}
+}
+
+// GroupDaoMemory is an in-memory implementation of GroupDao.
+type GroupDaoMemory struct {
+	lock    sync.RWMutex
+	storage map[string]Group
+}
+
+// Delete implements GroupDao.Delete
+func (dao *GroupDaoMemory) Delete(bo *Group) (bool, error) {
+	dao.lock.Lock()
+	defer dao.lock.Unlock()
+	if _, ok := dao.storage[bo.Id]; !ok {
+		return false, nil
+	}
+	delete(dao.storage, bo.Id)
+	return true, nil
+}
+
+// Create implements GroupDao.Create
+func (dao *GroupDaoMemory) Create(id, name string) (bool, error) {
+	bo := Group{
+		Id:   strings.ToLower(strings.TrimSpace(id)),
+		Name: strings.TrimSpace(name),
+	}
+	dao.lock.Lock()
+	defer dao.lock.Unlock()
+	if _, ok := dao.storage[bo.Id]; ok {
+		return false, nil
+	}
+	dao.storage[bo.Id] = bo
+	return true, nil
+}
+
+// Get implements GroupDao.Get
+func (dao *GroupDaoMemory) Get(id string) (*Group, error) {
+	dao.lock.RLock()
+	defer dao.lock.RUnlock()
+	if bo, ok := dao.storage[id]; ok {
+		return &bo, nil
+	}
+	return nil, nil
+}
+
+// GetN implements GroupDao.GetN
+func (dao *GroupDaoMemory) GetN(fromOffset, maxNumRows int) ([]*Group, error) {
+	dao.lock.RLock()
+	defer dao.lock.RUnlock()
+	ids := make([]string, 0, len(dao.storage))
+	for id := range dao.storage {
+		ids = append(ids, id)
+	}
+	ids = memoryGetN(ids, fromOffset, maxNumRows)
+	result := make([]*Group, len(ids))
+	for i, id := range ids {
+		bo := dao.storage[id]
+		result[i] = &bo
+	}
+	return result, nil
+}
+
+// GetAll implements GroupDao.GetAll
+func (dao *GroupDaoMemory) GetAll() ([]*Group, error) {
+	return dao.GetN(0, 0)
+}
+
+// GetByIds implements GroupBatchGetter.GetByIds
+func (dao *GroupDaoMemory) GetByIds(ids []string) (map[string]*Group, error) {
+	dao.lock.RLock()
+	defer dao.lock.RUnlock()
+	result := make(map[string]*Group)
+	for _, id := range ids {
+		if bo, ok := dao.storage[id]; ok {
+			result[id] = &bo
+		}
+	}
+	return result, nil
+}
+
+// GetNAfter implements GroupKeysetPager.GetNAfter
+func (dao *GroupDaoMemory) GetNAfter(afterId string, maxNumRows int) ([]*Group, error) {
+	dao.lock.RLock()
+	defer dao.lock.RUnlock()
+	ids := make([]string, 0, len(dao.storage))
+	for id := range dao.storage {
+		ids = append(ids, id)
+	}
+	ids = memoryGetNAfter(ids, afterId, maxNumRows)
+	result := make([]*Group, len(ids))
+	for i, id := range ids {
+		bo := dao.storage[id]
+		result[i] = &bo
+	}
+	return result, nil
+}
+
+// Update implements GroupDao.Update
+func (dao *GroupDaoMemory) Update(bo *Group) (bool, error) {
+	dao.lock.Lock()
+	defer dao.lock.Unlock()
+	if _, ok := dao.storage[bo.Id]; !ok {
+		return false, nil
+	}
+	stored := *bo
+	// attributes are copied so that the stored group is not modified through the caller's map
+	stored.Attrs = decodeGroupAttrs(encodeGroupAttrs(bo.Attrs))
+	dao.storage[bo.Id] = stored
+	return true, nil
+}
+
+/*----------------------------------------------------------------------*/
+
+func newUserDaoMemory() UserDao {
+	return &UserDaoMemory{storage: make(map[string]User)}
+}
+
+// UserDaoMemory is an in-memory implementation of UserDao.
+type UserDaoMemory struct {
+	lock    sync.RWMutex
+	storage map[string]User
+}
+
+// Delete implements UserDao.Delete
+func (dao *UserDaoMemory) Delete(bo *User) (bool, error) {
+	dao.lock.Lock()
+	defer dao.lock.Unlock()
+	if _, ok := dao.storage[bo.Username]; !ok {
+		return false, nil
+	}
+	delete(dao.storage, bo.Username)
+	return true, nil
+}
+
+// Create implements UserDao.Create
+func (dao *UserDaoMemory) Create(username, encryptedPassword, name, groupId string) (bool, error) {
+	bo := User{
+		Username: strings.ToLower(strings.TrimSpace(username)),
+		Password: strings.TrimSpace(encryptedPassword),
+		Name:     strings.TrimSpace(name),
+		GroupId:  strings.ToLower(strings.TrimSpace(groupId)),
+	}
+	dao.lock.Lock()
+	defer dao.lock.Unlock()
+	if _, ok := dao.storage[bo.Username]; ok {
+		return false, nil
+	}
+	dao.storage[bo.Username] = bo
+	return true, nil
+}
+
+// Get implements UserDao.Get
+func (dao *UserDaoMemory) Get(username string) (*User, error) {
+	dao.lock.RLock()
+	defer dao.lock.RUnlock()
+	if bo, ok := dao.storage[username]; ok {
+		return &bo, nil
+	}
+	return nil, nil
+}
+
+// GetN implements UserDao.GetN
+func (dao *UserDaoMemory) GetN(fromOffset, maxNumRows int) ([]*User, error) {
+	dao.lock.RLock()
+	defer dao.lock.RUnlock()
+	ids := make([]string, 0, len(dao.storage))
+	for id := range dao.storage {
+		ids = append(ids, id)
+	}
+	ids = memoryGetN(ids, fromOffset, maxNumRows)
+	result := make([]*User, len(ids))
+	for i, id := range ids {
+		bo := dao.storage[id]
+		result[i] = &bo
+	}
+	return result, nil
+}
+
+// GetAll implements UserDao.GetAll
+func (dao *UserDaoMemory) GetAll() ([]*User, error) {
+	return dao.GetN(0, 0)
+}
+
+// Update implements UserDao.Update
+func (dao *UserDaoMemory) Update(bo *User) (bool, error) {
+	dao.lock.Lock()
+	defer dao.lock.Unlock()
+	if _, ok := dao.storage[bo.Username]; !ok {
+		return false, nil
+	}
+	dao.storage[bo.Username] = *bo
+	return true, nil
+}
+
+// GetNByGroup implements UserGroupPager.GetNByGroup
+func (dao *UserDaoMemory) GetNByGroup(groupId string, fromOffset, maxNumRows int) ([]*User, error) {
+	dao.lock.RLock()
+	defer dao.lock.RUnlock()
+	ids := make([]string, 0)
+	for id, bo := range dao.storage {
+		if bo.GroupId == groupId {
+			ids = append(ids, id)
+		}
+	}
+	ids = memoryGetN(ids, fromOffset, maxNumRows)
+	result := make([]*User, len(ids))
+	for i, id := range ids {
+		bo := dao.storage[id]
+		result[i] = &bo
+	}
+	return result, nil
+}
+
+// GetNAfter implements UserKeysetPager.GetNAfter
+func (dao *UserDaoMemory) GetNAfter(afterUsername string, maxNumRows int) ([]*User, error) {
+	dao.lock.RLock()
+	defer dao.lock.RUnlock()
+	ids := make([]string, 0, len(dao.storage))
+	for id := range dao.storage {
+		ids = append(ids, id)
+	}
+	ids = memoryGetNAfter(ids, afterUsername, maxNumRows)
+	result := make([]*User, len(ids))
+	for i, id := range ids {
+		bo := dao.storage[id]
+		result[i] = &bo
+	}
+	return result, nil
+}
+
+// CountByGroup implements UserCounter.CountByGroup
+func (dao *UserDaoMemory) CountByGroup() (map[string]int, error) {
+	dao.lock.RLock()
+	defer dao.lock.RUnlock()
+	result := make(map[string]int)
+	for _, bo := range dao.storage {
+		result[bo.GroupId]++
+	}
+	return result, nil
+}
+
+/*----------------------------------------------------------------------*/
+
+func newMessageDaoMemory() MessageDao {
+	return &MessageDaoMemory{storage: make(map[string]Message)}
+}
+
+// MessageDaoMemory is an in-memory implementation of MessageDao.
+type MessageDaoMemory struct {
+	lock    sync.RWMutex
+	storage map[string]Message
+}
+
+// Delete implements MessageDao.Delete
+func (dao *MessageDaoMemory) Delete(bo *Message) (bool, error) {
+	dao.lock.Lock()
+	defer dao.lock.Unlock()
+	if _, ok := dao.storage[bo.Id()]; !ok {
+		return false, nil
+	}
+	delete(dao.storage, bo.Id())
+	return true, nil
+}
+
+// Get implements MessageDao.Get
+func (dao *MessageDaoMemory) Get(locale, key string) (*Message, error) {
+	dao.lock.RLock()
+	defer dao.lock.RUnlock()
+	if bo, ok := dao.storage[(&Message{Locale: locale, Key: key}).Id()]; ok {
+		return &bo, nil
+	}
+	return nil, nil
+}
+
+// GetAll implements MessageDao.GetAll
+func (dao *MessageDaoMemory) GetAll() ([]*Message, error) {
+	dao.lock.RLock()
+	defer dao.lock.RUnlock()
+	ids := make([]string, 0, len(dao.storage))
+	for id := range dao.storage {
+		ids = append(ids, id)
+	}
+	result := make([]*Message, 0, len(ids))
+	for _, id := range memoryGetN(ids, 0, 0) {
+		bo := dao.storage[id]
+		result = append(result, &bo)
+	}
+	return result, nil
+}
+
+// Save implements MessageDao.Save
+func (dao *MessageDaoMemory) Save(bo *Message) (bool, error) {
+	dao.lock.Lock()
+	defer dao.lock.Unlock()
+	dao.storage[bo.Id()] = *bo
+	return true, nil
+}
+
+/*----------------------------------------------------------------------*/
+
+func newSettingDaoMemory() SettingDao {
+	return &SettingDaoMemory{storage: make(map[string]Setting)}
+}
+
+// SettingDaoMemory is an in-memory implementation of SettingDao.
+type SettingDaoMemory struct {
+	lock    sync.RWMutex
+	storage map[string]Setting
+}
+
+// Delete implements SettingDao.Delete
+func (dao *SettingDaoMemory) Delete(bo *Setting) (bool, error) {
+	dao.lock.Lock()
+	defer dao.lock.Unlock()
+	if _, ok := dao.storage[bo.Id]; !ok {
+		return false, nil
+	}
+	delete(dao.storage, bo.Id)
+	return true, nil
+}
+
+// Get implements SettingDao.Get
+func (dao *SettingDaoMemory) Get(id string) (*Setting, error) {
+	dao.lock.RLock()
+	defer dao.lock.RUnlock()
+	if bo, ok := dao.storage[id]; ok {
+		return &bo, nil
+	}
+	return nil, nil
+}
+
+// GetAll implements SettingDao.GetAll
+func (dao *SettingDaoMemory) GetAll() ([]*Setting, error) {
+	dao.lock.RLock()
+	defer dao.lock.RUnlock()
+	ids := make([]string, 0, len(dao.storage))
+	for id := range dao.storage {
+		ids = append(ids, id)
+	}
+	result := make([]*Setting, 0, len(ids))
+	for _, id := range memoryGetN(ids, 0, 0) {
+		bo := dao.storage[id]
+		result = append(result, &bo)
+	}
+	return result, nil
+}
+
+// GetByPrefix implements SettingPrefixGetter.GetByPrefix
+func (dao *SettingDaoMemory) GetByPrefix(prefix string) ([]*Setting, error) {
+	dao.lock.RLock()
+	defer dao.lock.RUnlock()
+	ids := make([]string, 0)
+	for id := range dao.storage {
+		if strings.HasPrefix(id, prefix) {
+			ids = append(ids, id)
+		}
+	}
+	result := make([]*Setting, 0, len(ids))
+	for _, id := range memoryGetN(ids, 0, 0) {
+		bo := dao.storage[id]
+		result = append(result, &bo)
+	}
+	return result, nil
+}
+
+// Save implements SettingDao.Save
+func (dao *SettingDaoMemory) Save(bo *Setting) (bool, error) {
+	dao.lock.Lock()
+	defer dao.lock.Unlock()
+	dao.storage[bo.Id] = *bo
+	return true, nil
+}
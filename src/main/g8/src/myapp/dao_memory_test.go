@@ -0,0 +1,171 @@
+package myapp
+
+import (
+	"testing"
+)
+
+func TestGroupDaoMemory_GetNotExists(t *testing.T) {
+	testName := "TestGroupDaoMemory_GetNotExists"
+	dao := newGroupDaoMemory()
+	testGroupDaoGetNotExists(t, testName, dao)
+}
+
+func TestGroupDaoMemory_CreateGet(t *testing.T) {
+	testName := "TestGroupDaoMemory_CreateGet"
+	dao := newGroupDaoMemory()
+	testGroupDaoCreateGet(t, testName, dao)
+}
+
+func TestGroupDaoMemory_DeleteNotExists(t *testing.T) {
+	testName := "TestGroupDaoMemory_DeleteNotExists"
+	dao := newGroupDaoMemory()
+	testGroupDaoDeleteNotExists(t, testName, dao)
+}
+
+func TestGroupDaoMemory_CreateDelete(t *testing.T) {
+	testName := "TestGroupDaoMemory_CreateDelete"
+	dao := newGroupDaoMemory()
+	testGroupDaoCreateDelete(t, testName, dao)
+}
+
+func TestGroupDaoMemory_UpdateNotExists(t *testing.T) {
+	testName := "TestGroupDaoMemory_UpdateNotExists"
+	dao := newGroupDaoMemory()
+	testGroupDaoUpdateNotExists(t, testName, dao)
+}
+
+func TestGroupDaoMemory_CreateUpdate(t *testing.T) {
+	testName := "TestGroupDaoMemory_CreateUpdate"
+	dao := newGroupDaoMemory()
+	testGroupDaoCreateUpdate(t, testName, dao)
+}
+
+func TestGroupDaoMemory_GetN(t *testing.T) {
+	testName := "TestGroupDaoMemory_GetN"
+	dao := newGroupDaoMemory()
+	testGroupDaoGetN(t, testName, dao)
+}
+
+func TestGroupDaoMemory_GetAll(t *testing.T) {
+	testName := "TestGroupDaoMemory_GetAll"
+	dao := newGroupDaoMemory()
+	testGroupDaoGetAll(t, testName, dao)
+}
+
+func TestGroupDaoMemory_GetByIds(t *testing.T) {
+	testName := "TestGroupDaoMemory_GetByIds"
+	dao := newGroupDaoMemory()
+	testGroupDaoGetByIds(t, testName, dao)
+}
+
+func TestGroupDaoMemory_GetNAfter(t *testing.T) {
+	testName := "TestGroupDaoMemory_GetNAfter"
+	dao := newGroupDaoMemory()
+	testGroupDaoGetNAfter(t, testName, dao)
+}
+
+func TestGroupDaoMemory_Attrs(t *testing.T) {
+	testName := "TestGroupDaoMemory_Attrs"
+	dao := newGroupDaoMemory()
+	testGroupDaoAttrs(t, testName, dao)
+}
+
+/*----------------------------------------------------------------------*/
+
+func TestUserDaoMemory_GetNotExists(t *testing.T) {
+	testName := "TestUserDaoMemory_GetNotExists"
+	dao := newUserDaoMemory()
+	testUserDaoGetNotExists(t, testName, dao)
+}
+
+func TestUserDaoMemory_CreateGet(t *testing.T) {
+	testName := "TestUserDaoMemory_CreateGet"
+	dao := newUserDaoMemory()
+	testUserDaoCreateGet(t, testName, dao)
+}
+
+func TestUserDaoMemory_DeleteNotExists(t *testing.T) {
+	testName := "TestUserDaoMemory_DeleteNotExists"
+	dao := newUserDaoMemory()
+	testUserDaoDeleteNotExists(t, testName, dao)
+}
+
+func TestUserDaoMemory_CreateDelete(t *testing.T) {
+	testName := "TestUserDaoMemory_CreateDelete"
+	dao := newUserDaoMemory()
+	testUserDaoCreateDelete(t, testName, dao)
+}
+
+func TestUserDaoMemory_UpdateNotExists(t *testing.T) {
+	testName := "TestUserDaoMemory_UpdateNotExists"
+	dao := newUserDaoMemory()
+	testUserDaoUpdateNotExists(t, testName, dao)
+}
+
+func TestUserDaoMemory_CreateUpdate(t *testing.T) {
+	testName := "TestUserDaoMemory_CreateUpdate"
+	dao := newUserDaoMemory()
+	testUserDaoCreateUpdate(t, testName, dao)
+}
+
+func TestUserDaoMemory_GetN(t *testing.T) {
+	testName := "TestUserDaoMemory_GetN"
+	dao := newUserDaoMemory()
+	testUserDaoGetN(t, testName, dao)
+}
+
+func TestUserDaoMemory_GetAll(t *testing.T) {
+	testName := "TestUserDaoMemory_GetAll"
+	dao := newUserDaoMemory()
+	testUserDaoGetAll(t, testName, dao)
+}
+
+func TestUserDaoMemory_CountByGroup(t *testing.T) {
+	testName := "TestUserDaoMemory_CountByGroup"
+	dao := newUserDaoMemory()
+	testUserDaoCountByGroup(t, testName, dao)
+}
+
+func TestUserDaoMemory_GetNByGroup(t *testing.T) {
+	testName := "TestUserDaoMemory_GetNByGroup"
+	dao := newUserDaoMemory()
+	testUserDaoGetNByGroup(t, testName, dao)
+}
+
+func TestUserDaoMemory_GetNAfter(t *testing.T) {
+	testName := "TestUserDaoMemory_GetNAfter"
+	dao := newUserDaoMemory()
+	testUserDaoGetNAfter(t, testName, dao)
+}
+
+func TestUserDaoMemory_NotesTags(t *testing.T) {
+	testName := "TestUserDaoMemory_NotesTags"
+	dao := newUserDaoMemory()
+	testUserDaoNotesTags(t, testName, dao)
+}
+
+/*----------------------------------------------------------------------*/
+
+func TestMessageDaoMemory_SaveGetDelete(t *testing.T) {
+	testName := "TestMessageDaoMemory_SaveGetDelete"
+	dao := newMessageDaoMemory()
+	testMessageDaoSaveGetDelete(t, testName, dao)
+}
+
+func TestMessageDaoMemory_GetAll(t *testing.T) {
+	testName := "TestMessageDaoMemory_GetAll"
+	dao := newMessageDaoMemory()
+	testMessageDaoGetAll(t, testName, dao)
+}
+
+func TestSettingDaoMemory_SaveGetDelete(t *testing.T) {
+	testName := "TestSettingDaoMemory_SaveGetDelete"
+	dao := newSettingDaoMemory()
+	testSettingDaoSaveGetDelete(t, testName, dao)
+}
+
+func TestSettingDaoMemory_GetAll(t *testing.T) {
+	testName := "TestSettingDaoMemory_GetAll"
+	dao := newSettingDaoMemory()
+	testSettingDaoGetAll(t, testName, dao)
+}
@@ -69,6 +69,9 @@ func (dao *GroupDaoMongo) toBo(gbo godal.IGenericBo) *Group {
 		Id:   gbo.GboGetAttrUnsafe(fieldGroupId, reddo.TypeString).(string),
 		Name: gbo.GboGetAttrUnsafe(fieldGroupName, reddo.TypeString).(string),
 	}
+	// records created before custom fields were introduced have no attributes
+	attrs, _ := gbo.GboGetAttrUnsafe(fieldGroupAttrs, reddo.TypeString).(string)
+	bo.Attrs = decodeGroupAttrs(attrs)
 	return bo
 }
 
@@ -81,6 +84,7 @@ func (dao *GroupDaoMongo) toGbo(bo *Group) godal.IGenericBo {
 	gbo.GboSetAttr(mongoFieldId, bo.Id) // special case for MongoDB
 	gbo.GboSetAttr(fieldGroupId, bo.Id)
 	gbo.GboSetAttr(fieldGroupName, bo.Name)
+	gbo.GboSetAttr(fieldGroupAttrs, encodeGroupAttrs(bo.Attrs))
 	return gbo
 }
 
@@ -187,6 +191,10 @@ func (dao *UserDaoMongo) toBo(gbo godal.IGenericBo) *User {
 		Name:     gbo.GboGetAttrUnsafe(fieldUserName, reddo.TypeString).(string),
 		GroupId:  gbo.GboGetAttrUnsafe(fieldUserGroupId, reddo.TypeString).(string),
 	}
+	// records created before flags, notes and tags were introduced have none
+	bo.Flags, _ = gbo.GboGetAttrUnsafe(fieldUserFlags, reddo.TypeString).(string)
+	bo.Notes, _ = gbo.GboGetAttrUnsafe(fieldUserNotes, reddo.TypeString).(string)
+	bo.Tags, _ = gbo.GboGetAttrUnsafe(fieldUserTags, reddo.TypeString).(string)
 	return bo
 }
 
@@ -201,6 +209,9 @@ func (dao *UserDaoMongo) toGbo(bo *User) godal.IGenericBo {
 	gbo.GboSetAttr(fieldUserPassword, bo.Password)
 	gbo.GboSetAttr(fieldUserName, bo.Name)
 	gbo.GboSetAttr(fieldUserGroupId, bo.GroupId)
+	gbo.GboSetAttr(fieldUserFlags, bo.Flags)
+	gbo.GboSetAttr(fieldUserNotes, bo.Notes)
+	gbo.GboSetAttr(fieldUserTags, bo.Tags)
 	return gbo
 }
 
@@ -255,3 +266,202 @@ func (dao *UserDaoMongo) Update(bo *User) (bool, error) {
 	numRows, err := dao.GdaoUpdate(dao.collectionName, dao.toGbo(bo))
 	return numRows > 0, err
 }
+
+/*----------------------------------------------------------------------*/
+
+const (
+	mongoCollectionMessage = namespace + "_message"
+)
+
+var (
+	mongoDefaultSoringMessage = (&godal.SortingField{FieldName: mongoFieldId}).ToSortingOpt()
+)
+
+func mongoInitCollectionMessage(mc *prom.MongoConnect, collectionName string) {
+	err := mc.CreateCollection(collectionName)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func newMessageDaoMongo(mc *prom.MongoConnect, collectionName string) MessageDao {
+	dao := &MessageDaoMongo{collectionName: collectionName}
+	dao.GenericDaoMongo = mongo.NewGenericDaoMongo(mc, godal.NewAbstractGenericDao(dao))
+	dao.SetRowMapper(mongo.GenericRowMapperMongoInstance)
+	if strings.Index(mc.GetUrl(), "replicaSet=") > 0 {
+		dao.SetTxModeOnWrite(true)
+	}
+	return dao
+}
+
+type MessageDaoMongo struct {
+	*mongo.GenericDaoMongo
+	collectionName string
+}
+
+// GdaoCreateFilter implements IGenericDao.GdaoCreateFilter
+func (dao *MessageDaoMongo) GdaoCreateFilter(collectionName string, bo godal.IGenericBo) godal.FilterOpt {
+	// special case for MongoDB: GBO's fieldMessageId <--> MongoDB's _id
+	id, _ := bo.GboGetAttr(fieldMessageId, reddo.TypeString)
+	return godal.MakeFilter(map[string]interface{}{mongoFieldId: id})
+}
+
+// it is recommended to have a function that transforms godal.IGenericBo to business object and vice versa.
+func (dao *MessageDaoMongo) toBo(gbo godal.IGenericBo) *Message {
+	if gbo == nil {
+		return nil
+	}
+	bo := &Message{
+		Locale: gbo.GboGetAttrUnsafe(fieldMessageLocale, reddo.TypeString).(string),
+		Key:    gbo.GboGetAttrUnsafe(fieldMessageKey, reddo.TypeString).(string),
+		Text:   gbo.GboGetAttrUnsafe(fieldMessageText, reddo.TypeString).(string),
+	}
+	return bo
+}
+
+// it is recommended to have a function that transforms godal.IGenericBo to business object and vice versa.
+func (dao *MessageDaoMongo) toGbo(bo *Message) godal.IGenericBo {
+	if bo == nil {
+		return nil
+	}
+	gbo := godal.NewGenericBo()
+	gbo.GboSetAttr(mongoFieldId, bo.Id()) // special case for MongoDB
+	gbo.GboSetAttr(fieldMessageId, bo.Id())
+	gbo.GboSetAttr(fieldMessageLocale, bo.Locale)
+	gbo.GboSetAttr(fieldMessageKey, bo.Key)
+	gbo.GboSetAttr(fieldMessageText, bo.Text)
+	return gbo
+}
+
+// Delete implements MessageDao.Delete
+func (dao *MessageDaoMongo) Delete(bo *Message) (bool, error) {
+	numRows, err := dao.GdaoDelete(dao.collectionName, dao.toGbo(bo))
+	return numRows > 0, err
+}
+
+// Get implements MessageDao.Get
+func (dao *MessageDaoMongo) Get(locale, key string) (*Message, error) {
+	filter := godal.MakeFilter(map[string]interface{}{mongoFieldId: (&Message{Locale: locale, Key: key}).Id()})
+	gbo, err := dao.GdaoFetchOne(dao.collectionName, filter)
+	if err != nil {
+		return nil, err
+	}
+	return dao.toBo(gbo), nil
+}
+
+// GetAll implements MessageDao.GetAll
+func (dao *MessageDaoMongo) GetAll() ([]*Message, error) {
+	gboList, err := dao.GdaoFetchMany(dao.collectionName, nil, mongoDefaultSoringMessage, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*Message, len(gboList))
+	for i, gbo := range gboList {
+		result[i] = dao.toBo(gbo)
+	}
+	return result, nil
+}
+
+// Save implements MessageDao.Save
+func (dao *MessageDaoMongo) Save(bo *Message) (bool, error) {
+	numRows, err := dao.GdaoSave(dao.collectionName, dao.toGbo(bo))
+	return numRows > 0, err
+}
+
+/*----------------------------------------------------------------------*/
+
+const (
+	mongoCollectionSetting = namespace + "_setting"
+)
+
+var (
+	mongoDefaultSoringSetting = (&godal.SortingField{FieldName: mongoFieldId}).ToSortingOpt()
+)
+
+func mongoInitCollectionSetting(mc *prom.MongoConnect, collectionName string) {
+	err := mc.CreateCollection(collectionName)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func newSettingDaoMongo(mc *prom.MongoConnect, collectionName string) SettingDao {
+	dao := &SettingDaoMongo{collectionName: collectionName}
+	dao.GenericDaoMongo = mongo.NewGenericDaoMongo(mc, godal.NewAbstractGenericDao(dao))
+	dao.SetRowMapper(mongo.GenericRowMapperMongoInstance)
+	if strings.Index(mc.GetUrl(), "replicaSet=") > 0 {
+		dao.SetTxModeOnWrite(true)
+	}
+	return dao
+}
+
+type SettingDaoMongo struct {
+	*mongo.GenericDaoMongo
+	collectionName string
+}
+
+// GdaoCreateFilter implements IGenericDao.GdaoCreateFilter
+func (dao *SettingDaoMongo) GdaoCreateFilter(collectionName string, bo godal.IGenericBo) godal.FilterOpt {
+	// special case for MongoDB: GBO's fieldSettingId <--> MongoDB's _id
+	id, _ := bo.GboGetAttr(fieldSettingId, reddo.TypeString)
+	return godal.MakeFilter(map[string]interface{}{mongoFieldId: id})
+}
+
+// it is recommended to have a function that transforms godal.IGenericBo to business object and vice versa.
+func (dao *SettingDaoMongo) toBo(gbo godal.IGenericBo) *Setting {
+	if gbo == nil {
+		return nil
+	}
+	bo := &Setting{
+		Id:    gbo.GboGetAttrUnsafe(fieldSettingId, reddo.TypeString).(string),
+		Value: gbo.GboGetAttrUnsafe(fieldSettingValue, reddo.TypeString).(string),
+	}
+	return bo
+}
+
+// it is recommended to have a function that transforms godal.IGenericBo to business object and vice versa.
+func (dao *SettingDaoMongo) toGbo(bo *Setting) godal.IGenericBo {
+	if bo == nil {
+		return nil
+	}
+	gbo := godal.NewGenericBo()
+	gbo.GboSetAttr(mongoFieldId, bo.Id) // special case for MongoDB
+	gbo.GboSetAttr(fieldSettingId, bo.Id)
+	gbo.GboSetAttr(fieldSettingValue, bo.Value)
+	return gbo
+}
+
+// Delete implements SettingDao.Delete
+func (dao *SettingDaoMongo) Delete(bo *Setting) (bool, error) {
+	numRows, err := dao.GdaoDelete(dao.collectionName, dao.toGbo(bo))
+	return numRows > 0, err
+}
+
+// Get implements SettingDao.Get
+func (dao *SettingDaoMongo) Get(id string) (*Setting, error) {
+	filter := godal.MakeFilter(map[string]interface{}{mongoFieldId: id})
+	gbo, err := dao.GdaoFetchOne(dao.collectionName, filter)
+	if err != nil {
+		return nil, err
+	}
+	return dao.toBo(gbo), nil
+}
+
+// GetAll implements SettingDao.GetAll
+func (dao *SettingDaoMongo) GetAll() ([]*Setting, error) {
+	gboList, err := dao.GdaoFetchMany(dao.collectionName, nil, mongoDefaultSoringSetting, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*Setting, len(gboList))
+	for i, gbo := range gboList {
+		result[i] = dao.toBo(gbo)
+	}
+	return result, nil
+}
+
+// Save implements SettingDao.Save
+func (dao *SettingDaoMongo) Save(bo *Setting) (bool, error) {
+	numRows, err := dao.GdaoSave(dao.collectionName, dao.toGbo(bo))
+	return numRows > 0, err
+}
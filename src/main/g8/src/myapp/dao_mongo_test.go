@@ -166,3 +166,45 @@ func TestUserDaoMongo_GetAll(t *testing.T) {
 	defer dao.(*UserDaoMongo).GetMongoConnect().Close(nil)
 	testUserDaoGetAll(t, testName, dao)
 }
+
+/*----------------------------------------------------------------------*/
+
+func TestMessageDaoMongo_SaveGetDelete(t *testing.T) {
+	testName := "TestMessageDaoMongo_SaveGetDelete"
+	dao := _initMessageDaoMongo(os.Getenv(envMongoUrl), os.Getenv(envMongoDb), testMongoCollectionNameMessage)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*MessageDaoMongo).GetMongoConnect().Close(nil)
+	testMessageDaoSaveGetDelete(t, testName, dao)
+}
+
+func TestMessageDaoMongo_GetAll(t *testing.T) {
+	testName := "TestMessageDaoMongo_GetAll"
+	dao := _initMessageDaoMongo(os.Getenv(envMongoUrl), os.Getenv(envMongoDb), testMongoCollectionNameMessage)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*MessageDaoMongo).GetMongoConnect().Close(nil)
+	testMessageDaoGetAll(t, testName, dao)
+}
+
+func TestSettingDaoMongo_SaveGetDelete(t *testing.T) {
+	testName := "TestSettingDaoMongo_SaveGetDelete"
+	dao := _initSettingDaoMongo(os.Getenv(envMongoUrl), os.Getenv(envMongoDb), testMongoCollectionNameSetting)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*SettingDaoMongo).GetMongoConnect().Close(nil)
+	testSettingDaoSaveGetDelete(t, testName, dao)
+}
+
+func TestSettingDaoMongo_GetAll(t *testing.T) {
+	testName := "TestSettingDaoMongo_GetAll"
+	dao := _initSettingDaoMongo(os.Getenv(envMongoUrl), os.Getenv(envMongoDb), testMongoCollectionNameSetting)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*SettingDaoMongo).GetMongoConnect().Close(nil)
+	testSettingDaoGetAll(t, testName, dao)
+}
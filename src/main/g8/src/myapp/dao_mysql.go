@@ -22,20 +22,30 @@ const (
 )
 
 var (
-	mysqlColNamesAndTypesGroup = []string{"%s VARCHAR(64)", "%s VARCHAR(255)"}
+	mysqlColNamesAndTypesGroup = []string{"%s VARCHAR(64)", "%s VARCHAR(255)", "%s TEXT"}
 )
 
-func mysqlInitTableGroup(sqlc *prom.SqlConnect, tableName string) {
+// mysqlSchemaTableGroup returns the DDL statement that creates the group table.
+func mysqlSchemaTableGroup(tableName string) string {
 	sqlStm := "CREATE TABLE IF NOT EXISTS %s (" + strings.Join(mysqlColNamesAndTypesGroup, ",") + ",PRIMARY KEY (%s))"
-	sqlStm = fmt.Sprintf(sqlStm, tableName, sqlColGroupId, sqlColGroupName, sqlColGroupId)
-	_, err := sqlc.GetDB().Exec(sqlStm)
+	return fmt.Sprintf(sqlStm, tableName, sqlColGroupId, sqlColGroupName, sqlColGroupAttrs, sqlColGroupId)
+}
+
+// mysqlSchemaGroupAttrs returns the DDL statement that adds the attributes column to group tables created before it
+// existed.
+func mysqlSchemaGroupAttrs(tableName string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s TEXT", tableName, sqlColGroupAttrs)
+}
+
+func mysqlInitTableGroup(sqlc *prom.SqlConnect, tableName string) {
+	_, err := sqlc.GetDB().Exec(mysqlSchemaTableGroup(tableName))
 	if err != nil {
 		panic(err)
 	}
 }
 
 func newGroupDaoMysql(sqlc *prom.SqlConnect, tableName string) GroupDao {
-	return newGroupDaoSql(sqlc, tableName)
+	return newGroupDaoSql(sqlc, tableName, prom.FlavorMySql)
 }
 
 /*----------------------------------------------------------------------*/
@@ -45,18 +55,97 @@ const (
 )
 
 var (
-	mysqlColNamesAndTypesUser = []string{"%s VARCHAR(64)", "%s VARCHAR(64)", "%s VARCHAR(64)", "%s VARCHAR(64)"}
+	mysqlColNamesAndTypesUser = []string{"%s VARCHAR(64)", "%s VARCHAR(64)", "%s VARCHAR(255)", "%s VARCHAR(64)", "%s VARCHAR(255) DEFAULT ''", "%s TEXT", "%s VARCHAR(255) DEFAULT ''"}
 )
 
-func mysqlInitTableUser(sqlc *prom.SqlConnect, tableName string) {
+// mysqlSchemaTableUser returns the DDL statement that creates the user table.
+func mysqlSchemaTableUser(tableName string) string {
 	sqlStm := "CREATE TABLE IF NOT EXISTS %s (" + strings.Join(mysqlColNamesAndTypesUser, ",") + ",PRIMARY KEY (%s))"
-	sqlStm = fmt.Sprintf(sqlStm, tableName, sqlColUserUsername, sqlColUserPassword, sqlColUserName, sqlColUserGroupId, sqlColUserUsername)
-	_, err := sqlc.GetDB().Exec(sqlStm)
+	return fmt.Sprintf(sqlStm, tableName, sqlColUserUsername, sqlColUserPassword, sqlColUserName, sqlColUserGroupId, sqlColUserFlags, sqlColUserNotes, sqlColUserTags, sqlColUserUsername)
+}
+
+// mysqlSchemaUserFlags returns the DDL statement that adds the flags column to user tables created before it existed.
+func mysqlSchemaUserFlags(tableName string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s VARCHAR(255) DEFAULT ''", tableName, sqlColUserFlags)
+}
+
+// mysqlSchemaWidenUserName returns the DDL statement that widens the name column of user tables created when it was
+// VARCHAR(64), too short for encrypted names (see encryptedUserDao).
+func mysqlSchemaWidenUserName(tableName string) string {
+	return fmt.Sprintf("ALTER TABLE %s MODIFY %s VARCHAR(255)", tableName, sqlColUserName)
+}
+
+// mysqlSchemaUserNotes returns the DDL statement that adds the notes column to user tables created before it existed.
+func mysqlSchemaUserNotes(tableName string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s TEXT", tableName, sqlColUserNotes)
+}
+
+// mysqlSchemaUserTags returns the DDL statement that adds the tags column to user tables created before it existed.
+func mysqlSchemaUserTags(tableName string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s VARCHAR(255) DEFAULT ''", tableName, sqlColUserTags)
+}
+
+func mysqlInitTableUser(sqlc *prom.SqlConnect, tableName string) {
+	_, err := sqlc.GetDB().Exec(mysqlSchemaTableUser(tableName))
 	if err != nil {
 		panic(err)
 	}
 }
 
 func newUserDaoMysql(sqlc *prom.SqlConnect, tableName string) UserDao {
-	return newUserDaoSql(sqlc, tableName)
+	return newUserDaoSql(sqlc, tableName, prom.FlavorMySql)
+}
+
+/*----------------------------------------------------------------------*/
+
+const (
+	mysqlTableMessage = namespace + "_message"
+)
+
+var (
+	mysqlColNamesAndTypesMessage = []string{"%s VARCHAR(160)", "%s VARCHAR(32)", "%s VARCHAR(128)", "%s TEXT"}
+)
+
+// mysqlSchemaTableMessage returns the DDL statement that creates the message table.
+func mysqlSchemaTableMessage(tableName string) string {
+	sqlStm := "CREATE TABLE IF NOT EXISTS %s (" + strings.Join(mysqlColNamesAndTypesMessage, ",") + ",PRIMARY KEY (%s))"
+	return fmt.Sprintf(sqlStm, tableName, sqlColMessageId, sqlColMessageLocale, sqlColMessageKey, sqlColMessageText, sqlColMessageId)
+}
+
+func mysqlInitTableMessage(sqlc *prom.SqlConnect, tableName string) {
+	_, err := sqlc.GetDB().Exec(mysqlSchemaTableMessage(tableName))
+	if err != nil {
+		panic(err)
+	}
+}
+
+func newMessageDaoMysql(sqlc *prom.SqlConnect, tableName string) MessageDao {
+	return newMessageDaoSql(sqlc, tableName)
+}
+
+/*----------------------------------------------------------------------*/
+
+const (
+	mysqlTableSetting = namespace + "_setting"
+)
+
+var (
+	mysqlColNamesAndTypesSetting = []string{"%s VARCHAR(160)", "%s TEXT"}
+)
+
+// mysqlSchemaTableSetting returns the DDL statement that creates the setting table.
+func mysqlSchemaTableSetting(tableName string) string {
+	sqlStm := "CREATE TABLE IF NOT EXISTS %s (" + strings.Join(mysqlColNamesAndTypesSetting, ",") + ",PRIMARY KEY (%s))"
+	return fmt.Sprintf(sqlStm, tableName, sqlColSettingId, sqlColSettingValue, sqlColSettingId)
+}
+
+func mysqlInitTableSetting(sqlc *prom.SqlConnect, tableName string) {
+	_, err := sqlc.GetDB().Exec(mysqlSchemaTableSetting(tableName))
+	if err != nil {
+		panic(err)
+	}
+}
+
+func newSettingDaoMysql(sqlc *prom.SqlConnect, tableName string) SettingDao {
+	return newSettingDaoSql(sqlc, tableName)
 }
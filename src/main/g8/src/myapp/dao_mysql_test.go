@@ -87,6 +87,36 @@ func TestGroupDaoMysql_GetAll(t *testing.T) {
 	testGroupDaoGetAll(t, testName, dao)
 }
 
+func TestGroupDaoMysql_GetByIds(t *testing.T) {
+	testName := "TestGroupDaoMysql_GetByIds"
+	dao := _initGroupDaoSql(os.Getenv(envMysqlDriver), os.Getenv(envMysqlUrl), testSqlTableNameGroup, sql.FlavorMySql)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*GroupDaoSql).GetSqlConnect().Close()
+	testGroupDaoGetByIds(t, testName, dao)
+}
+
+func TestGroupDaoMysql_GetNAfter(t *testing.T) {
+	testName := "TestGroupDaoMysql_GetNAfter"
+	dao := _initGroupDaoSql(os.Getenv(envMysqlDriver), os.Getenv(envMysqlUrl), testSqlTableNameGroup, sql.FlavorMySql)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*GroupDaoSql).GetSqlConnect().Close()
+	testGroupDaoGetNAfter(t, testName, dao)
+}
+
+func TestGroupDaoMysql_Attrs(t *testing.T) {
+	testName := "TestGroupDaoMysql_Attrs"
+	dao := _initGroupDaoSql(os.Getenv(envMysqlDriver), os.Getenv(envMysqlUrl), testSqlTableNameGroup, sql.FlavorMySql)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*GroupDaoSql).GetSqlConnect().Close()
+	testGroupDaoAttrs(t, testName, dao)
+}
+
 /*----------------------------------------------------------------------*/
 
 func TestUserDaoMysql_GetNotExists(t *testing.T) {
@@ -168,3 +198,85 @@ func TestUserDaoMysql_GetAll(t *testing.T) {
 	defer dao.(*UserDaoSql).GetSqlConnect().Close()
 	testUserDaoGetAll(t, testName, dao)
 }
+
+func TestUserDaoMysql_CountByGroup(t *testing.T) {
+	testName := "TestUserDaoMysql_CountByGroup"
+	dao := _initUserDaoSql(os.Getenv(envMysqlDriver), os.Getenv(envMysqlUrl), testSqlTableNameGroup, sql.FlavorMySql)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*UserDaoSql).GetSqlConnect().Close()
+	testUserDaoCountByGroup(t, testName, dao)
+}
+
+func TestUserDaoMysql_GetNByGroup(t *testing.T) {
+	testName := "TestUserDaoMysql_GetNByGroup"
+	dao := _initUserDaoSql(os.Getenv(envMysqlDriver), os.Getenv(envMysqlUrl), testSqlTableNameGroup, sql.FlavorMySql)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*UserDaoSql).GetSqlConnect().Close()
+	testUserDaoGetNByGroup(t, testName, dao)
+}
+
+func TestUserDaoMysql_GetNAfter(t *testing.T) {
+	testName := "TestUserDaoMysql_GetNAfter"
+	dao := _initUserDaoSql(os.Getenv(envMysqlDriver), os.Getenv(envMysqlUrl), testSqlTableNameGroup, sql.FlavorMySql)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*UserDaoSql).GetSqlConnect().Close()
+	testUserDaoGetNAfter(t, testName, dao)
+}
+
+func TestUserDaoMysql_NotesTags(t *testing.T) {
+	testName := "TestUserDaoMysql_NotesTags"
+	dao := _initUserDaoSql(os.Getenv(envMysqlDriver), os.Getenv(envMysqlUrl), testSqlTableNameGroup, sql.FlavorMySql)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*UserDaoSql).GetSqlConnect().Close()
+	testUserDaoNotesTags(t, testName, dao)
+}
+
+/*----------------------------------------------------------------------*/
+
+func TestMessageDaoMysql_SaveGetDelete(t *testing.T) {
+	testName := "TestMessageDaoMysql_SaveGetDelete"
+	dao := _initMessageDaoSql(os.Getenv(envMysqlDriver), os.Getenv(envMysqlUrl), testSqlTableNameMessage, sql.FlavorMySql)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*MessageDaoSql).GetSqlConnect().Close()
+	testMessageDaoSaveGetDelete(t, testName, dao)
+}
+
+func TestMessageDaoMysql_GetAll(t *testing.T) {
+	testName := "TestMessageDaoMysql_GetAll"
+	dao := _initMessageDaoSql(os.Getenv(envMysqlDriver), os.Getenv(envMysqlUrl), testSqlTableNameMessage, sql.FlavorMySql)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*MessageDaoSql).GetSqlConnect().Close()
+	testMessageDaoGetAll(t, testName, dao)
+}
+
+func TestSettingDaoMysql_SaveGetDelete(t *testing.T) {
+	testName := "TestSettingDaoMysql_SaveGetDelete"
+	dao := _initSettingDaoSql(os.Getenv(envMysqlDriver), os.Getenv(envMysqlUrl), testSqlTableNameSetting, sql.FlavorMySql)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*SettingDaoSql).GetSqlConnect().Close()
+	testSettingDaoSaveGetDelete(t, testName, dao)
+}
+
+func TestSettingDaoMysql_GetAll(t *testing.T) {
+	testName := "TestSettingDaoMysql_GetAll"
+	dao := _initSettingDaoSql(os.Getenv(envMysqlDriver), os.Getenv(envMysqlUrl), testSqlTableNameSetting, sql.FlavorMySql)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*SettingDaoSql).GetSqlConnect().Close()
+	testSettingDaoGetAll(t, testName, dao)
+}
@@ -22,20 +22,30 @@ const (
 )
 
 var (
-	pgsqlColNamesAndTypesGroup = []string{"%s VARCHAR(64)", "%s VARCHAR(255)"}
+	pgsqlColNamesAndTypesGroup = []string{"%s VARCHAR(64)", "%s VARCHAR(255)", "%s TEXT"}
 )
 
-func pgsqlInitTableGroup(sqlc *prom.SqlConnect, tableName string) {
+// pgsqlSchemaTableGroup returns the DDL statement that creates the group table.
+func pgsqlSchemaTableGroup(tableName string) string {
 	sqlStm := "CREATE TABLE IF NOT EXISTS %s (" + strings.Join(pgsqlColNamesAndTypesGroup, ",") + ",PRIMARY KEY (%s))"
-	sqlStm = fmt.Sprintf(sqlStm, tableName, sqlColGroupId, sqlColGroupName, sqlColGroupId)
-	_, err := sqlc.GetDB().Exec(sqlStm)
+	return fmt.Sprintf(sqlStm, tableName, sqlColGroupId, sqlColGroupName, sqlColGroupAttrs, sqlColGroupId)
+}
+
+// pgsqlSchemaGroupAttrs returns the DDL statement that adds the attributes column to group tables created before it
+// existed.
+func pgsqlSchemaGroupAttrs(tableName string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s TEXT", tableName, sqlColGroupAttrs)
+}
+
+func pgsqlInitTableGroup(sqlc *prom.SqlConnect, tableName string) {
+	_, err := sqlc.GetDB().Exec(pgsqlSchemaTableGroup(tableName))
 	if err != nil {
 		panic(err)
 	}
 }
 
 func newGroupDaoPgsql(sqlc *prom.SqlConnect, tableName string) GroupDao {
-	return newGroupDaoSql(sqlc, tableName)
+	return newGroupDaoSql(sqlc, tableName, prom.FlavorPgSql)
 }
 
 /*----------------------------------------------------------------------*/
@@ -45,18 +55,97 @@ const (
 )
 
 var (
-	pgsqlColNamesAndTypesUser = []string{"%s VARCHAR(64)", "%s VARCHAR(64)", "%s VARCHAR(64)", "%s VARCHAR(64)"}
+	pgsqlColNamesAndTypesUser = []string{"%s VARCHAR(64)", "%s VARCHAR(64)", "%s VARCHAR(255)", "%s VARCHAR(64)", "%s VARCHAR(255) DEFAULT ''", "%s TEXT", "%s VARCHAR(255) DEFAULT ''"}
 )
 
-func pgsqlInitTableUser(sqlc *prom.SqlConnect, tableName string) {
+// pgsqlSchemaTableUser returns the DDL statement that creates the user table.
+func pgsqlSchemaTableUser(tableName string) string {
 	sqlStm := "CREATE TABLE IF NOT EXISTS %s (" + strings.Join(pgsqlColNamesAndTypesUser, ",") + ",PRIMARY KEY (%s))"
-	sqlStm = fmt.Sprintf(sqlStm, tableName, sqlColUserUsername, sqlColUserPassword, sqlColUserName, sqlColUserGroupId, sqlColUserUsername)
-	_, err := sqlc.GetDB().Exec(sqlStm)
+	return fmt.Sprintf(sqlStm, tableName, sqlColUserUsername, sqlColUserPassword, sqlColUserName, sqlColUserGroupId, sqlColUserFlags, sqlColUserNotes, sqlColUserTags, sqlColUserUsername)
+}
+
+// pgsqlSchemaUserFlags returns the DDL statement that adds the flags column to user tables created before it existed.
+func pgsqlSchemaUserFlags(tableName string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s VARCHAR(255) DEFAULT ''", tableName, sqlColUserFlags)
+}
+
+// pgsqlSchemaWidenUserName returns the DDL statement that widens the name column of user tables created when it was
+// VARCHAR(64), too short for encrypted names (see encryptedUserDao).
+func pgsqlSchemaWidenUserName(tableName string) string {
+	return fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE VARCHAR(255)", tableName, sqlColUserName)
+}
+
+// pgsqlSchemaUserNotes returns the DDL statement that adds the notes column to user tables created before it existed.
+func pgsqlSchemaUserNotes(tableName string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s TEXT", tableName, sqlColUserNotes)
+}
+
+// pgsqlSchemaUserTags returns the DDL statement that adds the tags column to user tables created before it existed.
+func pgsqlSchemaUserTags(tableName string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s VARCHAR(255) DEFAULT ''", tableName, sqlColUserTags)
+}
+
+func pgsqlInitTableUser(sqlc *prom.SqlConnect, tableName string) {
+	_, err := sqlc.GetDB().Exec(pgsqlSchemaTableUser(tableName))
 	if err != nil {
 		panic(err)
 	}
 }
 
 func newUserDaoPgsql(sqlc *prom.SqlConnect, tableName string) UserDao {
-	return newUserDaoSql(sqlc, tableName)
+	return newUserDaoSql(sqlc, tableName, prom.FlavorPgSql)
+}
+
+/*----------------------------------------------------------------------*/
+
+const (
+	pgsqlTableMessage = namespace + "_message"
+)
+
+var (
+	pgsqlColNamesAndTypesMessage = []string{"%s VARCHAR(160)", "%s VARCHAR(32)", "%s VARCHAR(128)", "%s TEXT"}
+)
+
+// pgsqlSchemaTableMessage returns the DDL statement that creates the message table.
+func pgsqlSchemaTableMessage(tableName string) string {
+	sqlStm := "CREATE TABLE IF NOT EXISTS %s (" + strings.Join(pgsqlColNamesAndTypesMessage, ",") + ",PRIMARY KEY (%s))"
+	return fmt.Sprintf(sqlStm, tableName, sqlColMessageId, sqlColMessageLocale, sqlColMessageKey, sqlColMessageText, sqlColMessageId)
+}
+
+func pgsqlInitTableMessage(sqlc *prom.SqlConnect, tableName string) {
+	_, err := sqlc.GetDB().Exec(pgsqlSchemaTableMessage(tableName))
+	if err != nil {
+		panic(err)
+	}
+}
+
+func newMessageDaoPgsql(sqlc *prom.SqlConnect, tableName string) MessageDao {
+	return newMessageDaoSql(sqlc, tableName)
+}
+
+/*----------------------------------------------------------------------*/
+
+const (
+	pgsqlTableSetting = namespace + "_setting"
+)
+
+var (
+	pgsqlColNamesAndTypesSetting = []string{"%s VARCHAR(160)", "%s TEXT"}
+)
+
+// pgsqlSchemaTableSetting returns the DDL statement that creates the setting table.
+func pgsqlSchemaTableSetting(tableName string) string {
+	sqlStm := "CREATE TABLE IF NOT EXISTS %s (" + strings.Join(pgsqlColNamesAndTypesSetting, ",") + ",PRIMARY KEY (%s))"
+	return fmt.Sprintf(sqlStm, tableName, sqlColSettingId, sqlColSettingValue, sqlColSettingId)
+}
+
+func pgsqlInitTableSetting(sqlc *prom.SqlConnect, tableName string) {
+	_, err := sqlc.GetDB().Exec(pgsqlSchemaTableSetting(tableName))
+	if err != nil {
+		panic(err)
+	}
+}
+
+func newSettingDaoPgsql(sqlc *prom.SqlConnect, tableName string) SettingDao {
+	return newSettingDaoSql(sqlc, tableName)
 }
@@ -87,6 +87,36 @@ func TestGroupDaoPgsql_GetAll(t *testing.T) {
 	testGroupDaoGetAll(t, testName, dao)
 }
 
+func TestGroupDaoPgsql_GetByIds(t *testing.T) {
+	testName := "TestGroupDaoPgsql_GetByIds"
+	dao := _initGroupDaoSql(os.Getenv(envPgsqlDriver), os.Getenv(envPgsqlUrl), testSqlTableNameGroup, sql.FlavorPgSql)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*GroupDaoSql).GetSqlConnect().Close()
+	testGroupDaoGetByIds(t, testName, dao)
+}
+
+func TestGroupDaoPgsql_GetNAfter(t *testing.T) {
+	testName := "TestGroupDaoPgsql_GetNAfter"
+	dao := _initGroupDaoSql(os.Getenv(envPgsqlDriver), os.Getenv(envPgsqlUrl), testSqlTableNameGroup, sql.FlavorPgSql)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*GroupDaoSql).GetSqlConnect().Close()
+	testGroupDaoGetNAfter(t, testName, dao)
+}
+
+func TestGroupDaoPgsql_Attrs(t *testing.T) {
+	testName := "TestGroupDaoPgsql_Attrs"
+	dao := _initGroupDaoSql(os.Getenv(envPgsqlDriver), os.Getenv(envPgsqlUrl), testSqlTableNameGroup, sql.FlavorPgSql)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*GroupDaoSql).GetSqlConnect().Close()
+	testGroupDaoAttrs(t, testName, dao)
+}
+
 /*----------------------------------------------------------------------*/
 
 func TestUserDaoPgsql_GetNotExists(t *testing.T) {
@@ -168,3 +198,85 @@ func TestUserDaoPgsql_GetAll(t *testing.T) {
 	defer dao.(*UserDaoSql).GetSqlConnect().Close()
 	testUserDaoGetAll(t, testName, dao)
 }
+
+func TestUserDaoPgsql_CountByGroup(t *testing.T) {
+	testName := "TestUserDaoPgsql_CountByGroup"
+	dao := _initUserDaoSql(os.Getenv(envPgsqlDriver), os.Getenv(envPgsqlUrl), testSqlTableNameGroup, sql.FlavorPgSql)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*UserDaoSql).GetSqlConnect().Close()
+	testUserDaoCountByGroup(t, testName, dao)
+}
+
+func TestUserDaoPgsql_GetNByGroup(t *testing.T) {
+	testName := "TestUserDaoPgsql_GetNByGroup"
+	dao := _initUserDaoSql(os.Getenv(envPgsqlDriver), os.Getenv(envPgsqlUrl), testSqlTableNameGroup, sql.FlavorPgSql)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*UserDaoSql).GetSqlConnect().Close()
+	testUserDaoGetNByGroup(t, testName, dao)
+}
+
+func TestUserDaoPgsql_GetNAfter(t *testing.T) {
+	testName := "TestUserDaoPgsql_GetNAfter"
+	dao := _initUserDaoSql(os.Getenv(envPgsqlDriver), os.Getenv(envPgsqlUrl), testSqlTableNameGroup, sql.FlavorPgSql)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*UserDaoSql).GetSqlConnect().Close()
+	testUserDaoGetNAfter(t, testName, dao)
+}
+
+func TestUserDaoPgsql_NotesTags(t *testing.T) {
+	testName := "TestUserDaoPgsql_NotesTags"
+	dao := _initUserDaoSql(os.Getenv(envPgsqlDriver), os.Getenv(envPgsqlUrl), testSqlTableNameGroup, sql.FlavorPgSql)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*UserDaoSql).GetSqlConnect().Close()
+	testUserDaoNotesTags(t, testName, dao)
+}
+
+/*----------------------------------------------------------------------*/
+
+func TestMessageDaoPgsql_SaveGetDelete(t *testing.T) {
+	testName := "TestMessageDaoPgsql_SaveGetDelete"
+	dao := _initMessageDaoSql(os.Getenv(envPgsqlDriver), os.Getenv(envPgsqlUrl), testSqlTableNameMessage, sql.FlavorPgSql)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*MessageDaoSql).GetSqlConnect().Close()
+	testMessageDaoSaveGetDelete(t, testName, dao)
+}
+
+func TestMessageDaoPgsql_GetAll(t *testing.T) {
+	testName := "TestMessageDaoPgsql_GetAll"
+	dao := _initMessageDaoSql(os.Getenv(envPgsqlDriver), os.Getenv(envPgsqlUrl), testSqlTableNameMessage, sql.FlavorPgSql)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*MessageDaoSql).GetSqlConnect().Close()
+	testMessageDaoGetAll(t, testName, dao)
+}
+
+func TestSettingDaoPgsql_SaveGetDelete(t *testing.T) {
+	testName := "TestSettingDaoPgsql_SaveGetDelete"
+	dao := _initSettingDaoSql(os.Getenv(envPgsqlDriver), os.Getenv(envPgsqlUrl), testSqlTableNameSetting, sql.FlavorPgSql)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*SettingDaoSql).GetSqlConnect().Close()
+	testSettingDaoSaveGetDelete(t, testName, dao)
+}
+
+func TestSettingDaoPgsql_GetAll(t *testing.T) {
+	testName := "TestSettingDaoPgsql_GetAll"
+	dao := _initSettingDaoSql(os.Getenv(envPgsqlDriver), os.Getenv(envPgsqlUrl), testSqlTableNameSetting, sql.FlavorPgSql)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*SettingDaoSql).GetSqlConnect().Close()
+	testSettingDaoGetAll(t, testName, dao)
+}
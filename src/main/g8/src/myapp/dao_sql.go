@@ -1,6 +1,9 @@
 package myapp
 
 import (
+	"context"
+	gosql "database/sql"
+	"fmt"
 	"strings"
 	"time"
 
@@ -8,6 +11,7 @@ import (
 	"github.com/btnguyen2k/godal"
 	"github.com/btnguyen2k/godal/sql"
 	prom "github.com/btnguyen2k/prom/sql"
+	"main/src/goadmin"
 )
 
 func newSqlConnection(driver, dsn string, flavor prom.DbFlavor, loc *time.Location) *prom.SqlConnect {
@@ -23,22 +27,98 @@ func newSqlConnection(driver, dsn string, flavor prom.DbFlavor, loc *time.Locati
 	return sqlConnect
 }
 
+// sqlPagingQuery builds the statement that fetches a page of rows ordered by sortCol, optionally filtered by
+// filterCol=<first placeholder>; args are the paging arguments, following the filter value. Statements are the same
+// whatever the page so that they are prepared once. False is returned for an offset without limit, which the flavors
+// do not express alike.
+func sqlPagingQuery(flavor prom.DbFlavor, cols, tableName, filterCol, sortCol string, fromOffset, maxNumRows int) (string, []interface{}, bool) {
+	sqlStm, i := fmt.Sprintf("SELECT %s FROM %s", cols, tableName), 1
+	if filterCol != "" {
+		sqlStm += fmt.Sprintf(" WHERE %s=%s", filterCol, sqlPlaceholder(flavor, i))
+		i++
+	}
+	sqlStm += " ORDER BY " + sortCol
+	if maxNumRows <= 0 {
+		return sqlStm, nil, fromOffset <= 0
+	}
+	if fromOffset < 0 {
+		fromOffset = 0
+	}
+	sqlStm += fmt.Sprintf(" LIMIT %s OFFSET %s", sqlPlaceholder(flavor, i), sqlPlaceholder(flavor, i+1))
+	return sqlStm, []interface{}{maxNumRows, fromOffset}, true
+}
+
+// sqlKeysetQuery builds the statement that fetches rows whose keyCol sorts after the first placeholder, ordered by
+// keyCol; args are the paging arguments, following the key. Rows are located by the primary key index whatever the
+// page, unlike with OFFSET which scans all skipped rows.
+func sqlKeysetQuery(flavor prom.DbFlavor, cols, tableName, keyCol string, maxNumRows int) (string, []interface{}) {
+	sqlStm := fmt.Sprintf("SELECT %s FROM %s WHERE %s>%s ORDER BY %s", cols, tableName, keyCol, sqlPlaceholder(flavor, 1), keyCol)
+	if maxNumRows <= 0 {
+		return sqlStm, nil
+	}
+	return sqlStm + " LIMIT " + sqlPlaceholder(flavor, 2), []interface{}{maxNumRows}
+}
+
+// sqlOutbox lets SQL DAOs store outbox messages in the transaction of their changes (see txOutboxWriter), if the
+// database also holds settings.
+type sqlOutbox struct {
+	settingDao *SettingDaoSql           // nil if settings are stored elsewhere
+	msgs       []*goadmin.OutboxMessage // messages bound to changes of the DAO, see withOutbox
+}
+
+// write makes a change, in a transaction also storing the bound outbox messages if any; messages are not stored if
+// the change affects no row.
+func (o sqlOutbox) write(dao *sql.GenericDaoSql, change func(ctx context.Context, tx *gosql.Tx) (int, error)) (bool, error) {
+	if len(o.msgs) == 0 {
+		numRows, err := change(nil, nil)
+		return numRows > 0, err
+	}
+	numRows := 0
+	err := dao.WrapTransaction(nil, func(ctx context.Context, tx *gosql.Tx) error {
+		var err error
+		if numRows, err = change(ctx, tx); err != nil || numRows == 0 {
+			return err
+		}
+		for _, msg := range o.msgs {
+			setting, err := outboxSetting(msg)
+			if err != nil {
+				return err
+			}
+			if _, err := o.settingDao.GdaoSaveWithTx(ctx, tx, o.settingDao.tableName, o.settingDao.toGbo(setting)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return numRows > 0 && err == nil, err
+}
+
+// bind returns a copy of the outbox storing msgs with changes, nil if settings are stored elsewhere.
+func (o sqlOutbox) bind(msgs []*goadmin.OutboxMessage) *sqlOutbox {
+	if o.settingDao == nil {
+		return nil
+	}
+	o.msgs = msgs
+	return &o
+}
+
 /*----------------------------------------------------------------------*/
 
 const (
-	sqlColGroupId   = "gid"
-	sqlColGroupName = "gname"
+	sqlColGroupId    = "gid"
+	sqlColGroupName  = "gname"
+	sqlColGroupAttrs = "gattrs"
 )
 
 var (
-	sqlColsGroup              = []string{sqlColGroupId, sqlColGroupName}
-	sqlMapFieldToColNameGroup = map[string]interface{}{fieldGroupId: sqlColGroupId, fieldGroupName: sqlColGroupName}
-	sqlMapColNameToFieldGroup = map[string]interface{}{sqlColGroupId: fieldGroupId, sqlColGroupName: fieldGroupName}
+	sqlColsGroup              = []string{sqlColGroupId, sqlColGroupName, sqlColGroupAttrs}
+	sqlMapFieldToColNameGroup = map[string]interface{}{fieldGroupId: sqlColGroupId, fieldGroupName: sqlColGroupName, fieldGroupAttrs: sqlColGroupAttrs}
+	sqlMapColNameToFieldGroup = map[string]interface{}{sqlColGroupId: fieldGroupId, sqlColGroupName: fieldGroupName, sqlColGroupAttrs: fieldGroupAttrs}
 	sqlDefaultSoringGroup     = (&godal.SortingOpt{}).Add(&godal.SortingField{FieldName: fieldGroupId})
 )
 
-func newGroupDaoSql(sqlc *prom.SqlConnect, tableName string) GroupDao {
-	dao := &GroupDaoSql{tableName: tableName}
+func newGroupDaoSql(sqlc *prom.SqlConnect, tableName string, flavor prom.DbFlavor) GroupDao {
+	dao := &GroupDaoSql{tableName: tableName, flavor: flavor, stmts: newSqlStmtCache(sqlc.GetDB())}
 	dao.GenericDaoSql = sql.NewGenericDaoSql(sqlc, godal.NewAbstractGenericDao(dao))
 	dao.SetRowMapper(&sql.GenericRowMapperSql{
 		NameTransformation:          sql.NameTransfLowerCase,
@@ -52,6 +132,46 @@ func newGroupDaoSql(sqlc *prom.SqlConnect, tableName string) GroupDao {
 type GroupDaoSql struct {
 	*sql.GenericDaoSql
 	tableName string
+	flavor    prom.DbFlavor
+	stmts     *sqlStmtCache // prepared statements of hot queries, see sqlStmtCache
+	outbox    sqlOutbox
+}
+
+// withOutbox implements txOutboxWriter.withOutbox
+func (dao *GroupDaoSql) withOutbox(msgs []*goadmin.OutboxMessage) interface{} {
+	outbox := dao.outbox.bind(msgs)
+	if outbox == nil {
+		return nil
+	}
+	clone := *dao
+	clone.outbox = *outbox
+	return &clone
+}
+
+// stmtCache implements sqlStmtCacher.stmtCache
+func (dao *GroupDaoSql) stmtCache() *sqlStmtCache {
+	return dao.stmts
+}
+
+// queryGroups fetches groups with a cached prepared statement.
+func (dao *GroupDaoSql) queryGroups(sqlStm string, args ...interface{}) ([]*Group, error) {
+	rows, err := dao.stmts.query(sqlStm, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	result := make([]*Group, 0)
+	for rows.Next() {
+		bo := &Group{}
+		// records created before custom fields were introduced have no attributes
+		var attrs gosql.NullString
+		if err := rows.Scan(&bo.Id, &bo.Name, &attrs); err != nil {
+			return nil, err
+		}
+		bo.Attrs = decodeGroupAttrs(attrs.String)
+		result = append(result, bo)
+	}
+	return result, rows.Err()
 }
 
 // GdaoCreateFilter implements IGenericDao.GdaoCreateFilter
@@ -69,6 +189,9 @@ func (dao *GroupDaoSql) toBo(gbo godal.IGenericBo) *Group {
 		Id:   gbo.GboGetAttrUnsafe(fieldGroupId, reddo.TypeString).(string),
 		Name: gbo.GboGetAttrUnsafe(fieldGroupName, reddo.TypeString).(string),
 	}
+	// records created before custom fields were introduced have no attributes
+	attrs, _ := gbo.GboGetAttrUnsafe(fieldGroupAttrs, reddo.TypeString).(string)
+	bo.Attrs = decodeGroupAttrs(attrs)
 	return bo
 }
 
@@ -80,13 +203,15 @@ func (dao *GroupDaoSql) toGbo(bo *Group) godal.IGenericBo {
 	gbo := godal.NewGenericBo()
 	gbo.GboSetAttr(fieldGroupId, bo.Id)
 	gbo.GboSetAttr(fieldGroupName, bo.Name)
+	gbo.GboSetAttr(fieldGroupAttrs, encodeGroupAttrs(bo.Attrs))
 	return gbo
 }
 
 // Delete implements GroupDao.Delete
 func (dao *GroupDaoSql) Delete(bo *Group) (bool, error) {
-	numRows, err := dao.GdaoDelete(dao.tableName, dao.toGbo(bo))
-	return numRows > 0, err
+	return dao.outbox.write(dao.GenericDaoSql, func(ctx context.Context, tx *gosql.Tx) (int, error) {
+		return dao.GdaoDeleteWithTx(ctx, tx, dao.tableName, dao.toGbo(bo))
+	})
 }
 
 // Create implements GroupDao.Create
@@ -95,22 +220,26 @@ func (dao *GroupDaoSql) Create(id, name string) (bool, error) {
 		Id:   strings.ToLower(strings.TrimSpace(id)),
 		Name: strings.TrimSpace(name),
 	}
-	numRows, err := dao.GdaoCreate(dao.tableName, dao.toGbo(bo))
-	return numRows > 0, err
+	return dao.outbox.write(dao.GenericDaoSql, func(ctx context.Context, tx *gosql.Tx) (int, error) {
+		return dao.GdaoCreateWithTx(ctx, tx, dao.tableName, dao.toGbo(bo))
+	})
 }
 
 // Get implements GroupDao.Get
 func (dao *GroupDaoSql) Get(id string) (*Group, error) {
-	filter := &godal.FilterOptFieldOpValue{FieldName: fieldGroupId, Operator: godal.FilterOpEqual, Value: id}
-	gbo, err := dao.GdaoFetchOne(dao.tableName, filter)
-	if err != nil {
+	sqlStm := fmt.Sprintf("SELECT %s FROM %s WHERE %s=%s", strings.Join(sqlColsGroup, ","), dao.tableName, sqlColGroupId, sqlPlaceholder(dao.flavor, 1))
+	result, err := dao.queryGroups(sqlStm, id)
+	if err != nil || len(result) == 0 {
 		return nil, err
 	}
-	return dao.toBo(gbo), nil
+	return result[0], nil
 }
 
 // GetN implements GroupDao.GetN
 func (dao *GroupDaoSql) GetN(fromOffset, maxNumRows int) ([]*Group, error) {
+	if sqlStm, args, ok := sqlPagingQuery(dao.flavor, strings.Join(sqlColsGroup, ","), dao.tableName, "", sqlColGroupId, fromOffset, maxNumRows); ok {
+		return dao.queryGroups(sqlStm, args...)
+	}
 	gboList, err := dao.GdaoFetchMany(dao.tableName, nil, sqlDefaultSoringGroup, fromOffset, maxNumRows)
 	if err != nil {
 		return nil, err
@@ -122,15 +251,50 @@ func (dao *GroupDaoSql) GetN(fromOffset, maxNumRows int) ([]*Group, error) {
 	return result, nil
 }
 
+// GetNAfter implements GroupKeysetPager.GetNAfter
+func (dao *GroupDaoSql) GetNAfter(afterId string, maxNumRows int) ([]*Group, error) {
+	sqlStm, args := sqlKeysetQuery(dao.flavor, strings.Join(sqlColsGroup, ","), dao.tableName, sqlColGroupId, maxNumRows)
+	return dao.queryGroups(sqlStm, append([]interface{}{afterId}, args...)...)
+}
+
 // GetAll implements GroupDao.GetAll
 func (dao *GroupDaoSql) GetAll() ([]*Group, error) {
 	return dao.GetN(0, 0)
 }
 
+// sqlMaxIdsPerQuery is the max number of ids looked up by a single query, to keep statements within the limits of
+// all supported databases.
+const sqlMaxIdsPerQuery = 100
+
+// GetByIds implements GroupBatchGetter.GetByIds
+func (dao *GroupDaoSql) GetByIds(ids []string) (map[string]*Group, error) {
+	result := make(map[string]*Group)
+	for start := 0; start < len(ids); start += sqlMaxIdsPerQuery {
+		end := start + sqlMaxIdsPerQuery
+		if end > len(ids) {
+			end = len(ids)
+		}
+		filter := &godal.FilterOptOr{}
+		for _, id := range ids[start:end] {
+			filter.Add(&godal.FilterOptFieldOpValue{FieldName: fieldGroupId, Operator: godal.FilterOpEqual, Value: id})
+		}
+		gboList, err := dao.GdaoFetchMany(dao.tableName, filter, nil, 0, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, gbo := range gboList {
+			bo := dao.toBo(gbo)
+			result[bo.Id] = bo
+		}
+	}
+	return result, nil
+}
+
 // Update implements GroupDao.Update
 func (dao *GroupDaoSql) Update(bo *Group) (bool, error) {
-	numRows, err := dao.GdaoUpdate(dao.tableName, dao.toGbo(bo))
-	return numRows > 0, err
+	return dao.outbox.write(dao.GenericDaoSql, func(ctx context.Context, tx *gosql.Tx) (int, error) {
+		return dao.GdaoUpdateWithTx(ctx, tx, dao.tableName, dao.toGbo(bo))
+	})
 }
 
 /*----------------------------------------------------------------------*/
@@ -140,17 +304,20 @@ const (
 	sqlColUserPassword = "upwd"
 	sqlColUserName     = "display_name"
 	sqlColUserGroupId  = "gid"
+	sqlColUserFlags    = "uflags"
+	sqlColUserNotes    = "unotes"
+	sqlColUserTags     = "utags"
 )
 
 var (
-	sqlColsUser              = []string{sqlColUserUsername, sqlColUserPassword, sqlColUserName, sqlColUserGroupId}
-	sqlMapFieldToColNameUser = map[string]interface{}{fieldUserUsername: sqlColUserUsername, fieldUserPassword: sqlColUserPassword, fieldUserName: sqlColUserName, fieldUserGroupId: sqlColUserGroupId}
-	sqlMapColNameToFieldUser = map[string]interface{}{sqlColUserUsername: fieldUserUsername, sqlColUserPassword: fieldUserPassword, sqlColUserName: fieldUserName, sqlColUserGroupId: fieldUserGroupId}
+	sqlColsUser              = []string{sqlColUserUsername, sqlColUserPassword, sqlColUserName, sqlColUserGroupId, sqlColUserFlags, sqlColUserNotes, sqlColUserTags}
+	sqlMapFieldToColNameUser = map[string]interface{}{fieldUserUsername: sqlColUserUsername, fieldUserPassword: sqlColUserPassword, fieldUserName: sqlColUserName, fieldUserGroupId: sqlColUserGroupId, fieldUserFlags: sqlColUserFlags, fieldUserNotes: sqlColUserNotes, fieldUserTags: sqlColUserTags}
+	sqlMapColNameToFieldUser = map[string]interface{}{sqlColUserUsername: fieldUserUsername, sqlColUserPassword: fieldUserPassword, sqlColUserName: fieldUserName, sqlColUserGroupId: fieldUserGroupId, sqlColUserFlags: fieldUserFlags, sqlColUserNotes: fieldUserNotes, sqlColUserTags: fieldUserTags}
 	sqlDefaultSoringUser     = (&godal.SortingOpt{}).Add(&godal.SortingField{FieldName: fieldUserUsername})
 )
 
-func newUserDaoSql(sqlc *prom.SqlConnect, tableName string) UserDao {
-	dao := &UserDaoSql{tableName: tableName}
+func newUserDaoSql(sqlc *prom.SqlConnect, tableName string, flavor prom.DbFlavor) UserDao {
+	dao := &UserDaoSql{tableName: tableName, flavor: flavor, stmts: newSqlStmtCache(sqlc.GetDB())}
 	dao.GenericDaoSql = sql.NewGenericDaoSql(sqlc, godal.NewAbstractGenericDao(dao))
 	dao.SetRowMapper(&sql.GenericRowMapperSql{
 		NameTransformation:          sql.NameTransfLowerCase,
@@ -164,6 +331,46 @@ func newUserDaoSql(sqlc *prom.SqlConnect, tableName string) UserDao {
 type UserDaoSql struct {
 	*sql.GenericDaoSql
 	tableName string
+	flavor    prom.DbFlavor
+	stmts     *sqlStmtCache // prepared statements of hot queries, see sqlStmtCache
+	outbox    sqlOutbox
+}
+
+// withOutbox implements txOutboxWriter.withOutbox
+func (dao *UserDaoSql) withOutbox(msgs []*goadmin.OutboxMessage) interface{} {
+	outbox := dao.outbox.bind(msgs)
+	if outbox == nil {
+		return nil
+	}
+	clone := *dao
+	clone.outbox = *outbox
+	return &clone
+}
+
+// stmtCache implements sqlStmtCacher.stmtCache
+func (dao *UserDaoSql) stmtCache() *sqlStmtCache {
+	return dao.stmts
+}
+
+// queryUsers fetches users with a cached prepared statement.
+func (dao *UserDaoSql) queryUsers(sqlStm string, args ...interface{}) ([]*User, error) {
+	rows, err := dao.stmts.query(sqlStm, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	result := make([]*User, 0)
+	for rows.Next() {
+		bo := &User{}
+		// records created before flags, notes and tags were introduced have none
+		var flags, notes, tags gosql.NullString
+		if err := rows.Scan(&bo.Username, &bo.Password, &bo.Name, &bo.GroupId, &flags, &notes, &tags); err != nil {
+			return nil, err
+		}
+		bo.Flags, bo.Notes, bo.Tags = flags.String, notes.String, tags.String
+		result = append(result, bo)
+	}
+	return result, rows.Err()
 }
 
 // GdaoCreateFilter implements IGenericDao.GdaoCreateFilter
@@ -186,6 +393,10 @@ func (dao *UserDaoSql) toBo(gbo godal.IGenericBo) *User {
 		Name:     gbo.GboGetAttrUnsafe(fieldUserName, reddo.TypeString).(string),
 		GroupId:  gbo.GboGetAttrUnsafe(fieldUserGroupId, reddo.TypeString).(string),
 	}
+	// records created before flags, notes and tags were introduced have none
+	bo.Flags, _ = gbo.GboGetAttrUnsafe(fieldUserFlags, reddo.TypeString).(string)
+	bo.Notes, _ = gbo.GboGetAttrUnsafe(fieldUserNotes, reddo.TypeString).(string)
+	bo.Tags, _ = gbo.GboGetAttrUnsafe(fieldUserTags, reddo.TypeString).(string)
 	return bo
 }
 
@@ -199,13 +410,17 @@ func (dao *UserDaoSql) toGbo(bo *User) godal.IGenericBo {
 	gbo.GboSetAttr(fieldUserPassword, bo.Password)
 	gbo.GboSetAttr(fieldUserName, bo.Name)
 	gbo.GboSetAttr(fieldUserGroupId, bo.GroupId)
+	gbo.GboSetAttr(fieldUserFlags, bo.Flags)
+	gbo.GboSetAttr(fieldUserNotes, bo.Notes)
+	gbo.GboSetAttr(fieldUserTags, bo.Tags)
 	return gbo
 }
 
 // Delete implements UserDao.Delete
 func (dao *UserDaoSql) Delete(bo *User) (bool, error) {
-	numRows, err := dao.GdaoDelete(dao.tableName, dao.toGbo(bo))
-	return numRows > 0, err
+	return dao.outbox.write(dao.GenericDaoSql, func(ctx context.Context, tx *gosql.Tx) (int, error) {
+		return dao.GdaoDeleteWithTx(ctx, tx, dao.tableName, dao.toGbo(bo))
+	})
 }
 
 // Create implements UserDao.Create
@@ -216,22 +431,26 @@ func (dao *UserDaoSql) Create(username, encryptedPassword, name, groupId string)
 		Name:     strings.TrimSpace(name),
 		GroupId:  strings.ToLower(strings.TrimSpace(groupId)),
 	}
-	numRows, err := dao.GdaoCreate(dao.tableName, dao.toGbo(bo))
-	return numRows > 0, err
+	return dao.outbox.write(dao.GenericDaoSql, func(ctx context.Context, tx *gosql.Tx) (int, error) {
+		return dao.GdaoCreateWithTx(ctx, tx, dao.tableName, dao.toGbo(bo))
+	})
 }
 
 // Get implements UserDao.Get
 func (dao *UserDaoSql) Get(username string) (*User, error) {
-	filter := &godal.FilterOptFieldOpValue{FieldName: fieldUserUsername, Operator: godal.FilterOpEqual, Value: username}
-	gbo, err := dao.GdaoFetchOne(dao.tableName, filter)
-	if err != nil {
+	sqlStm := fmt.Sprintf("SELECT %s FROM %s WHERE %s=%s", strings.Join(sqlColsUser, ","), dao.tableName, sqlColUserUsername, sqlPlaceholder(dao.flavor, 1))
+	result, err := dao.queryUsers(sqlStm, username)
+	if err != nil || len(result) == 0 {
 		return nil, err
 	}
-	return dao.toBo(gbo), nil
+	return result[0], nil
 }
 
 // GetN implements UserDao.GetN
 func (dao *UserDaoSql) GetN(fromOffset, maxNumRows int) ([]*User, error) {
+	if sqlStm, args, ok := sqlPagingQuery(dao.flavor, strings.Join(sqlColsUser, ","), dao.tableName, "", sqlColUserUsername, fromOffset, maxNumRows); ok {
+		return dao.queryUsers(sqlStm, args...)
+	}
 	gboList, err := dao.GdaoFetchMany(dao.tableName, nil, sqlDefaultSoringUser, fromOffset, maxNumRows)
 	if err != nil {
 		return nil, err
@@ -250,6 +469,267 @@ func (dao *UserDaoSql) GetAll() ([]*User, error) {
 
 // Update implements UserDao.Update
 func (dao *UserDaoSql) Update(bo *User) (bool, error) {
-	numRows, err := dao.GdaoUpdate(dao.tableName, dao.toGbo(bo))
+	return dao.outbox.write(dao.GenericDaoSql, func(ctx context.Context, tx *gosql.Tx) (int, error) {
+		return dao.GdaoUpdateWithTx(ctx, tx, dao.tableName, dao.toGbo(bo))
+	})
+}
+
+// GetNByGroup implements UserGroupPager.GetNByGroup
+func (dao *UserDaoSql) GetNByGroup(groupId string, fromOffset, maxNumRows int) ([]*User, error) {
+	if sqlStm, args, ok := sqlPagingQuery(dao.flavor, strings.Join(sqlColsUser, ","), dao.tableName, sqlColUserGroupId, sqlColUserUsername, fromOffset, maxNumRows); ok {
+		return dao.queryUsers(sqlStm, append([]interface{}{groupId}, args...)...)
+	}
+	filter := &godal.FilterOptFieldOpValue{FieldName: fieldUserGroupId, Operator: godal.FilterOpEqual, Value: groupId}
+	gboList, err := dao.GdaoFetchMany(dao.tableName, filter, sqlDefaultSoringUser, fromOffset, maxNumRows)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*User, len(gboList))
+	for i, gbo := range gboList {
+		result[i] = dao.toBo(gbo)
+	}
+	return result, nil
+}
+
+// GetNAfter implements UserKeysetPager.GetNAfter
+func (dao *UserDaoSql) GetNAfter(afterUsername string, maxNumRows int) ([]*User, error) {
+	sqlStm, args := sqlKeysetQuery(dao.flavor, strings.Join(sqlColsUser, ","), dao.tableName, sqlColUserUsername, maxNumRows)
+	return dao.queryUsers(sqlStm, append([]interface{}{afterUsername}, args...)...)
+}
+
+// CountByGroup implements UserCounter.CountByGroup
+func (dao *UserDaoSql) CountByGroup() (map[string]int, error) {
+	sqlStm := fmt.Sprintf("SELECT %s, COUNT(*) FROM %s GROUP BY %s", sqlColUserGroupId, dao.tableName, sqlColUserGroupId)
+	rows, err := dao.GetSqlConnect().GetDB().Query(sqlStm)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	result := make(map[string]int)
+	for rows.Next() {
+		var groupId string
+		var count int
+		if err := rows.Scan(&groupId, &count); err != nil {
+			return nil, err
+		}
+		result[groupId] = count
+	}
+	return result, rows.Err()
+}
+
+/*----------------------------------------------------------------------*/
+
+const (
+	sqlColMessageId     = "mid"
+	sqlColMessageLocale = "mlocale"
+	sqlColMessageKey    = "mkey"
+	sqlColMessageText   = "mtext"
+)
+
+var (
+	sqlColsMessage              = []string{sqlColMessageId, sqlColMessageLocale, sqlColMessageKey, sqlColMessageText}
+	sqlMapFieldToColNameMessage = map[string]interface{}{fieldMessageId: sqlColMessageId, fieldMessageLocale: sqlColMessageLocale, fieldMessageKey: sqlColMessageKey, fieldMessageText: sqlColMessageText}
+	sqlMapColNameToFieldMessage = map[string]interface{}{sqlColMessageId: fieldMessageId, sqlColMessageLocale: fieldMessageLocale, sqlColMessageKey: fieldMessageKey, sqlColMessageText: fieldMessageText}
+	sqlDefaultSoringMessage     = (&godal.SortingOpt{}).Add(&godal.SortingField{FieldName: fieldMessageId})
+)
+
+func newMessageDaoSql(sqlc *prom.SqlConnect, tableName string) MessageDao {
+	dao := &MessageDaoSql{tableName: tableName}
+	dao.GenericDaoSql = sql.NewGenericDaoSql(sqlc, godal.NewAbstractGenericDao(dao))
+	dao.SetRowMapper(&sql.GenericRowMapperSql{
+		NameTransformation:          sql.NameTransfLowerCase,
+		GboFieldToColNameTranslator: map[string]map[string]interface{}{tableName: sqlMapFieldToColNameMessage},
+		ColNameToGboFieldTranslator: map[string]map[string]interface{}{tableName: sqlMapColNameToFieldMessage},
+		ColumnsListMap:              map[string][]string{tableName: sqlColsMessage},
+	})
+	return dao
+}
+
+type MessageDaoSql struct {
+	*sql.GenericDaoSql
+	tableName string
+}
+
+// GdaoCreateFilter implements IGenericDao.GdaoCreateFilter
+func (dao *MessageDaoSql) GdaoCreateFilter(tableName string, bo godal.IGenericBo) godal.FilterOpt {
+	id, _ := bo.GboGetAttr(fieldMessageId, reddo.TypeString)
+	return &godal.FilterOptFieldOpValue{FieldName: fieldMessageId, Operator: godal.FilterOpEqual, Value: id}
+}
+
+// it is recommended to have a function that transforms godal.IGenericBo to business object and vice versa.
+func (dao *MessageDaoSql) toBo(gbo godal.IGenericBo) *Message {
+	if gbo == nil {
+		return nil
+	}
+	bo := &Message{
+		Locale: gbo.GboGetAttrUnsafe(fieldMessageLocale, reddo.TypeString).(string),
+		Key:    gbo.GboGetAttrUnsafe(fieldMessageKey, reddo.TypeString).(string),
+		Text:   gbo.GboGetAttrUnsafe(fieldMessageText, reddo.TypeString).(string),
+	}
+	return bo
+}
+
+// it is recommended to have a function that transforms godal.IGenericBo to business object and vice versa.
+func (dao *MessageDaoSql) toGbo(bo *Message) godal.IGenericBo {
+	if bo == nil {
+		return nil
+	}
+	gbo := godal.NewGenericBo()
+	gbo.GboSetAttr(fieldMessageId, bo.Id())
+	gbo.GboSetAttr(fieldMessageLocale, bo.Locale)
+	gbo.GboSetAttr(fieldMessageKey, bo.Key)
+	gbo.GboSetAttr(fieldMessageText, bo.Text)
+	return gbo
+}
+
+// Delete implements MessageDao.Delete
+func (dao *MessageDaoSql) Delete(bo *Message) (bool, error) {
+	numRows, err := dao.GdaoDelete(dao.tableName, dao.toGbo(bo))
+	return numRows > 0, err
+}
+
+// Get implements MessageDao.Get
+func (dao *MessageDaoSql) Get(locale, key string) (*Message, error) {
+	id := (&Message{Locale: locale, Key: key}).Id()
+	filter := &godal.FilterOptFieldOpValue{FieldName: fieldMessageId, Operator: godal.FilterOpEqual, Value: id}
+	gbo, err := dao.GdaoFetchOne(dao.tableName, filter)
+	if err != nil {
+		return nil, err
+	}
+	return dao.toBo(gbo), nil
+}
+
+// GetAll implements MessageDao.GetAll
+func (dao *MessageDaoSql) GetAll() ([]*Message, error) {
+	gboList, err := dao.GdaoFetchMany(dao.tableName, nil, sqlDefaultSoringMessage, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*Message, len(gboList))
+	for i, gbo := range gboList {
+		result[i] = dao.toBo(gbo)
+	}
+	return result, nil
+}
+
+// Save implements MessageDao.Save
+func (dao *MessageDaoSql) Save(bo *Message) (bool, error) {
+	numRows, err := dao.GdaoSave(dao.tableName, dao.toGbo(bo))
+	return numRows > 0, err
+}
+
+/*----------------------------------------------------------------------*/
+
+const (
+	sqlColSettingId    = "sid"
+	sqlColSettingValue = "svalue"
+)
+
+var (
+	sqlColsSetting              = []string{sqlColSettingId, sqlColSettingValue}
+	sqlMapFieldToColNameSetting = map[string]interface{}{fieldSettingId: sqlColSettingId, fieldSettingValue: sqlColSettingValue}
+	sqlMapColNameToFieldSetting = map[string]interface{}{sqlColSettingId: fieldSettingId, sqlColSettingValue: fieldSettingValue}
+	sqlDefaultSoringSetting     = (&godal.SortingOpt{}).Add(&godal.SortingField{FieldName: fieldSettingId})
+)
+
+func newSettingDaoSql(sqlc *prom.SqlConnect, tableName string) SettingDao {
+	dao := &SettingDaoSql{tableName: tableName}
+	dao.GenericDaoSql = sql.NewGenericDaoSql(sqlc, godal.NewAbstractGenericDao(dao))
+	dao.SetRowMapper(&sql.GenericRowMapperSql{
+		NameTransformation:          sql.NameTransfLowerCase,
+		GboFieldToColNameTranslator: map[string]map[string]interface{}{tableName: sqlMapFieldToColNameSetting},
+		ColNameToGboFieldTranslator: map[string]map[string]interface{}{tableName: sqlMapColNameToFieldSetting},
+		ColumnsListMap:              map[string][]string{tableName: sqlColsSetting},
+	})
+	return dao
+}
+
+type SettingDaoSql struct {
+	*sql.GenericDaoSql
+	tableName string
+}
+
+// GdaoCreateFilter implements IGenericDao.GdaoCreateFilter
+func (dao *SettingDaoSql) GdaoCreateFilter(tableName string, bo godal.IGenericBo) godal.FilterOpt {
+	id, _ := bo.GboGetAttr(fieldSettingId, reddo.TypeString)
+	return &godal.FilterOptFieldOpValue{FieldName: fieldSettingId, Operator: godal.FilterOpEqual, Value: id}
+}
+
+// it is recommended to have a function that transforms godal.IGenericBo to business object and vice versa.
+func (dao *SettingDaoSql) toBo(gbo godal.IGenericBo) *Setting {
+	if gbo == nil {
+		return nil
+	}
+	bo := &Setting{
+		Id:    gbo.GboGetAttrUnsafe(fieldSettingId, reddo.TypeString).(string),
+		Value: gbo.GboGetAttrUnsafe(fieldSettingValue, reddo.TypeString).(string),
+	}
+	return bo
+}
+
+// it is recommended to have a function that transforms godal.IGenericBo to business object and vice versa.
+func (dao *SettingDaoSql) toGbo(bo *Setting) godal.IGenericBo {
+	if bo == nil {
+		return nil
+	}
+	gbo := godal.NewGenericBo()
+	gbo.GboSetAttr(fieldSettingId, bo.Id)
+	gbo.GboSetAttr(fieldSettingValue, bo.Value)
+	return gbo
+}
+
+// Delete implements SettingDao.Delete
+func (dao *SettingDaoSql) Delete(bo *Setting) (bool, error) {
+	numRows, err := dao.GdaoDelete(dao.tableName, dao.toGbo(bo))
+	return numRows > 0, err
+}
+
+// Get implements SettingDao.Get
+func (dao *SettingDaoSql) Get(id string) (*Setting, error) {
+	filter := &godal.FilterOptFieldOpValue{FieldName: fieldSettingId, Operator: godal.FilterOpEqual, Value: id}
+	gbo, err := dao.GdaoFetchOne(dao.tableName, filter)
+	if err != nil {
+		return nil, err
+	}
+	return dao.toBo(gbo), nil
+}
+
+// GetAll implements SettingDao.GetAll
+func (dao *SettingDaoSql) GetAll() ([]*Setting, error) {
+	gboList, err := dao.GdaoFetchMany(dao.tableName, nil, sqlDefaultSoringSetting, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*Setting, len(gboList))
+	for i, gbo := range gboList {
+		result[i] = dao.toBo(gbo)
+	}
+	return result, nil
+}
+
+// GetByPrefix implements SettingPrefixGetter.GetByPrefix
+func (dao *SettingDaoSql) GetByPrefix(prefix string) ([]*Setting, error) {
+	if prefix == "" {
+		return dao.GetAll()
+	}
+	// a range on the primary key rather than LIKE, whose wildcards may appear in prefixes
+	upper := prefix[:len(prefix)-1] + string([]byte{prefix[len(prefix)-1] + 1})
+	filter := (&godal.FilterOptAnd{}).
+		Add(&godal.FilterOptFieldOpValue{FieldName: fieldSettingId, Operator: godal.FilterOpGreaterOrEqual, Value: prefix}).
+		Add(&godal.FilterOptFieldOpValue{FieldName: fieldSettingId, Operator: godal.FilterOpLess, Value: upper})
+	gboList, err := dao.GdaoFetchMany(dao.tableName, filter, sqlDefaultSoringSetting, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*Setting, len(gboList))
+	for i, gbo := range gboList {
+		result[i] = dao.toBo(gbo)
+	}
+	return result, nil
+}
+
+// Save implements SettingDao.Save
+func (dao *SettingDaoSql) Save(bo *Setting) (bool, error) {
+	numRows, err := dao.GdaoSave(dao.tableName, dao.toGbo(bo))
 	return numRows > 0, err
 }
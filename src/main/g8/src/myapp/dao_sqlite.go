@@ -4,13 +4,26 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	prom "github.com/btnguyen2k/prom/sql"
 	_ "github.com/mattn/go-sqlite3"
 )
 
+// sqliteInMemory is the special value of "root directory" to create an in-memory SQLite database (e.g. for testing).
+const sqliteInMemory = ":memory:"
+
+// sqliteInMemoryDbs counts in-memory databases created by the process, to name them: random names would collide in
+// end-to-end test mode (see utils.SeedRandom).
+var sqliteInMemoryDbs int64
+
 func newSqliteConnection(dir, dbName string, loc *time.Location) *prom.SqlConnect {
+	if dir == sqliteInMemory {
+		// each in-memory connection gets its own database, shared by all connections of the pool
+		dsn := fmt.Sprintf("file:%s_%d?mode=memory&cache=shared", dbName, atomic.AddInt64(&sqliteInMemoryDbs, 1))
+		return newSqlConnection("sqlite3", dsn, prom.FlavorSqlite, loc)
+	}
 	err := os.MkdirAll(dir, 0711)
 	if err != nil {
 		panic(err)
@@ -25,20 +38,30 @@ const (
 )
 
 var (
-	sqliteColNamesAndTypesGroup = []string{"%s VARCHAR(64)", "%s VARCHAR(255)"}
+	sqliteColNamesAndTypesGroup = []string{"%s VARCHAR(64)", "%s VARCHAR(255)", "%s TEXT"}
 )
 
-func sqliteInitTableGroup(sqlc *prom.SqlConnect, tableName string) {
+// sqliteSchemaTableGroup returns the DDL statement that creates the group table.
+func sqliteSchemaTableGroup(tableName string) string {
 	sqlStm := "CREATE TABLE IF NOT EXISTS %s (" + strings.Join(sqliteColNamesAndTypesGroup, ",") + ",PRIMARY KEY (%s))"
-	sqlStm = fmt.Sprintf(sqlStm, tableName, sqlColGroupId, sqlColGroupName, sqlColGroupId)
-	_, err := sqlc.GetDB().Exec(sqlStm)
+	return fmt.Sprintf(sqlStm, tableName, sqlColGroupId, sqlColGroupName, sqlColGroupAttrs, sqlColGroupId)
+}
+
+// sqliteSchemaGroupAttrs returns the DDL statement that adds the attributes column to group tables created before it
+// existed.
+func sqliteSchemaGroupAttrs(tableName string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s TEXT", tableName, sqlColGroupAttrs)
+}
+
+func sqliteInitTableGroup(sqlc *prom.SqlConnect, tableName string) {
+	_, err := sqlc.GetDB().Exec(sqliteSchemaTableGroup(tableName))
 	if err != nil {
 		panic(err)
 	}
 }
 
 func newGroupDaoSqlite(sqlc *prom.SqlConnect, tableName string) GroupDao {
-	return newGroupDaoSql(sqlc, tableName)
+	return newGroupDaoSql(sqlc, tableName, prom.FlavorSqlite)
 }
 
 /*----------------------------------------------------------------------*/
@@ -48,18 +71,91 @@ const (
 )
 
 var (
-	sqliteColNamesAndTypesUser = []string{"%s VARCHAR(64)", "%s VARCHAR(64)", "%s VARCHAR(64)", "%s VARCHAR(64)"}
+	sqliteColNamesAndTypesUser = []string{"%s VARCHAR(64)", "%s VARCHAR(64)", "%s VARCHAR(255)", "%s VARCHAR(64)", "%s VARCHAR(255) DEFAULT ''", "%s TEXT", "%s VARCHAR(255) DEFAULT ''"}
 )
 
-func sqliteInitTableUser(sqlc *prom.SqlConnect, tableName string) {
+// sqliteSchemaTableUser returns the DDL statement that creates the user table.
+func sqliteSchemaTableUser(tableName string) string {
 	sqlStm := "CREATE TABLE IF NOT EXISTS %s (" + strings.Join(sqliteColNamesAndTypesUser, ",") + ",PRIMARY KEY (%s))"
-	sqlStm = fmt.Sprintf(sqlStm, tableName, sqlColUserUsername, sqlColUserPassword, sqlColUserName, sqlColUserGroupId, sqlColUserUsername)
-	_, err := sqlc.GetDB().Exec(sqlStm)
+	return fmt.Sprintf(sqlStm, tableName, sqlColUserUsername, sqlColUserPassword, sqlColUserName, sqlColUserGroupId, sqlColUserFlags, sqlColUserNotes, sqlColUserTags, sqlColUserUsername)
+}
+
+// sqliteSchemaUserFlags returns the DDL statement that adds the flags column to user tables created before it existed.
+func sqliteSchemaUserFlags(tableName string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s VARCHAR(255) DEFAULT ''", tableName, sqlColUserFlags)
+}
+
+// sqliteSchemaUserNotes returns the DDL statement that adds the notes column to user tables created before it existed.
+func sqliteSchemaUserNotes(tableName string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s TEXT", tableName, sqlColUserNotes)
+}
+
+// sqliteSchemaUserTags returns the DDL statement that adds the tags column to user tables created before it existed.
+func sqliteSchemaUserTags(tableName string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s VARCHAR(255) DEFAULT ''", tableName, sqlColUserTags)
+}
+
+func sqliteInitTableUser(sqlc *prom.SqlConnect, tableName string) {
+	_, err := sqlc.GetDB().Exec(sqliteSchemaTableUser(tableName))
 	if err != nil {
 		panic(err)
 	}
 }
 
 func newUserDaoSqlite(sqlc *prom.SqlConnect, tableName string) UserDao {
-	return newUserDaoSql(sqlc, tableName)
+	return newUserDaoSql(sqlc, tableName, prom.FlavorSqlite)
+}
+
+/*----------------------------------------------------------------------*/
+
+const (
+	sqliteTableMessage = namespace + "_message"
+)
+
+var (
+	sqliteColNamesAndTypesMessage = []string{"%s VARCHAR(160)", "%s VARCHAR(32)", "%s VARCHAR(128)", "%s TEXT"}
+)
+
+// sqliteSchemaTableMessage returns the DDL statement that creates the message table.
+func sqliteSchemaTableMessage(tableName string) string {
+	sqlStm := "CREATE TABLE IF NOT EXISTS %s (" + strings.Join(sqliteColNamesAndTypesMessage, ",") + ",PRIMARY KEY (%s))"
+	return fmt.Sprintf(sqlStm, tableName, sqlColMessageId, sqlColMessageLocale, sqlColMessageKey, sqlColMessageText, sqlColMessageId)
+}
+
+func sqliteInitTableMessage(sqlc *prom.SqlConnect, tableName string) {
+	_, err := sqlc.GetDB().Exec(sqliteSchemaTableMessage(tableName))
+	if err != nil {
+		panic(err)
+	}
+}
+
+func newMessageDaoSqlite(sqlc *prom.SqlConnect, tableName string) MessageDao {
+	return newMessageDaoSql(sqlc, tableName)
+}
+
+/*----------------------------------------------------------------------*/
+
+const (
+	sqliteTableSetting = namespace + "_setting"
+)
+
+var (
+	sqliteColNamesAndTypesSetting = []string{"%s VARCHAR(160)", "%s TEXT"}
+)
+
+// sqliteSchemaTableSetting returns the DDL statement that creates the setting table.
+func sqliteSchemaTableSetting(tableName string) string {
+	sqlStm := "CREATE TABLE IF NOT EXISTS %s (" + strings.Join(sqliteColNamesAndTypesSetting, ",") + ",PRIMARY KEY (%s))"
+	return fmt.Sprintf(sqlStm, tableName, sqlColSettingId, sqlColSettingValue, sqlColSettingId)
+}
+
+func sqliteInitTableSetting(sqlc *prom.SqlConnect, tableName string) {
+	_, err := sqlc.GetDB().Exec(sqliteSchemaTableSetting(tableName))
+	if err != nil {
+		panic(err)
+	}
+}
+
+func newSettingDaoSqlite(sqlc *prom.SqlConnect, tableName string) SettingDao {
+	return newSettingDaoSql(sqlc, tableName)
 }
@@ -87,6 +87,36 @@ func TestGroupDaoSqlite_GetAll(t *testing.T) {
 	testGroupDaoGetAll(t, testName, dao)
 }
 
+func TestGroupDaoSqlite_GetByIds(t *testing.T) {
+	testName := "TestGroupDaoSqlite_GetByIds"
+	dao := _initGroupDaoSql(os.Getenv(envSqliteDriver), os.Getenv(envSqliteUrl), testSqlTableNameGroup, sql.FlavorSqlite)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*GroupDaoSql).GetSqlConnect().Close()
+	testGroupDaoGetByIds(t, testName, dao)
+}
+
+func TestGroupDaoSqlite_GetNAfter(t *testing.T) {
+	testName := "TestGroupDaoSqlite_GetNAfter"
+	dao := _initGroupDaoSql(os.Getenv(envSqliteDriver), os.Getenv(envSqliteUrl), testSqlTableNameGroup, sql.FlavorSqlite)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*GroupDaoSql).GetSqlConnect().Close()
+	testGroupDaoGetNAfter(t, testName, dao)
+}
+
+func TestGroupDaoSqlite_Attrs(t *testing.T) {
+	testName := "TestGroupDaoSqlite_Attrs"
+	dao := _initGroupDaoSql(os.Getenv(envSqliteDriver), os.Getenv(envSqliteUrl), testSqlTableNameGroup, sql.FlavorSqlite)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*GroupDaoSql).GetSqlConnect().Close()
+	testGroupDaoAttrs(t, testName, dao)
+}
+
 /*----------------------------------------------------------------------*/
 
 func TestUserDaoSqlite_GetNotExists(t *testing.T) {
@@ -168,3 +198,85 @@ func TestUserDaoSqlite_GetAll(t *testing.T) {
 	defer dao.(*UserDaoSql).GetSqlConnect().Close()
 	testUserDaoGetAll(t, testName, dao)
 }
+
+func TestUserDaoSqlite_CountByGroup(t *testing.T) {
+	testName := "TestUserDaoSqlite_CountByGroup"
+	dao := _initUserDaoSql(os.Getenv(envSqliteDriver), os.Getenv(envSqliteUrl), testSqlTableNameGroup, sql.FlavorSqlite)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*UserDaoSql).GetSqlConnect().Close()
+	testUserDaoCountByGroup(t, testName, dao)
+}
+
+func TestUserDaoSqlite_GetNByGroup(t *testing.T) {
+	testName := "TestUserDaoSqlite_GetNByGroup"
+	dao := _initUserDaoSql(os.Getenv(envSqliteDriver), os.Getenv(envSqliteUrl), testSqlTableNameGroup, sql.FlavorSqlite)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*UserDaoSql).GetSqlConnect().Close()
+	testUserDaoGetNByGroup(t, testName, dao)
+}
+
+func TestUserDaoSqlite_GetNAfter(t *testing.T) {
+	testName := "TestUserDaoSqlite_GetNAfter"
+	dao := _initUserDaoSql(os.Getenv(envSqliteDriver), os.Getenv(envSqliteUrl), testSqlTableNameGroup, sql.FlavorSqlite)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*UserDaoSql).GetSqlConnect().Close()
+	testUserDaoGetNAfter(t, testName, dao)
+}
+
+func TestUserDaoSqlite_NotesTags(t *testing.T) {
+	testName := "TestUserDaoSqlite_NotesTags"
+	dao := _initUserDaoSql(os.Getenv(envSqliteDriver), os.Getenv(envSqliteUrl), testSqlTableNameGroup, sql.FlavorSqlite)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*UserDaoSql).GetSqlConnect().Close()
+	testUserDaoNotesTags(t, testName, dao)
+}
+
+/*----------------------------------------------------------------------*/
+
+func TestMessageDaoSqlite_SaveGetDelete(t *testing.T) {
+	testName := "TestMessageDaoSqlite_SaveGetDelete"
+	dao := _initMessageDaoSql(os.Getenv(envSqliteDriver), os.Getenv(envSqliteUrl), testSqlTableNameMessage, sql.FlavorSqlite)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*MessageDaoSql).GetSqlConnect().Close()
+	testMessageDaoSaveGetDelete(t, testName, dao)
+}
+
+func TestMessageDaoSqlite_GetAll(t *testing.T) {
+	testName := "TestMessageDaoSqlite_GetAll"
+	dao := _initMessageDaoSql(os.Getenv(envSqliteDriver), os.Getenv(envSqliteUrl), testSqlTableNameMessage, sql.FlavorSqlite)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*MessageDaoSql).GetSqlConnect().Close()
+	testMessageDaoGetAll(t, testName, dao)
+}
+
+func TestSettingDaoSqlite_SaveGetDelete(t *testing.T) {
+	testName := "TestSettingDaoSqlite_SaveGetDelete"
+	dao := _initSettingDaoSql(os.Getenv(envSqliteDriver), os.Getenv(envSqliteUrl), testSqlTableNameSetting, sql.FlavorSqlite)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*SettingDaoSql).GetSqlConnect().Close()
+	testSettingDaoSaveGetDelete(t, testName, dao)
+}
+
+func TestSettingDaoSqlite_GetAll(t *testing.T) {
+	testName := "TestSettingDaoSqlite_GetAll"
+	dao := _initSettingDaoSql(os.Getenv(envSqliteDriver), os.Getenv(envSqliteUrl), testSqlTableNameSetting, sql.FlavorSqlite)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.(*SettingDaoSql).GetSqlConnect().Close()
+	testSettingDaoGetAll(t, testName, dao)
+}
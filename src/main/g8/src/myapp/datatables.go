@@ -0,0 +1,343 @@
+package myapp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"main/src/goadmin"
+	"main/src/utils"
+)
+
+// maxDataTablesPageSize is the maximum number of rows returned by a draw, including draws requesting all rows
+// (length=-1).
+const maxDataTablesPageSize = 500
+
+// dataTablesRow is a row of a data set served to DataTables, rendered with its canonical JSON representation.
+type dataTablesRow interface {
+	ToMap() map[string]interface{}
+}
+
+// dataTablesSource is a data set served with the DataTables server-side processing protocol, see
+// actionCpDataTables.
+//
+// Draws without search and ordered by the first column ascending (the initial draw of a table) are paged by the DAO
+// (see count and getN). Other draws are searched and ordered in memory.
+type dataTablesSource struct {
+	permission string   // permission required to read the data set, empty if none
+	columns    []string // keys of ToMap rows can be searched and ordered by, rows are sorted by the first one
+	// count returns the total number of rows, nil if rows must be counted by getAll
+	count func(c echo.Context) (int, error)
+	// getN returns at most n rows starting from offset, sorted by the first column; nil if not supported
+	getN   func(c echo.Context, offset, n int) ([]dataTablesRow, error)
+	getAll func(c echo.Context) ([]dataTablesRow, error)
+}
+
+// dataTablesSources lists data sets served by actionCpDataTables, by name.
+var dataTablesSources = map[string]*dataTablesSource{
+	"users": {
+		columns: []string{"username", "name", "group_id"},
+		count: func(c echo.Context) (int, error) {
+			counts, err := countUsersByGroup(getUserDao(c))
+			total := 0
+			for _, n := range counts {
+				total += n
+			}
+			return total, err
+		},
+		getN: func(c echo.Context, offset, n int) ([]dataTablesRow, error) {
+			users, err := getUserDao(c).GetN(offset, n)
+			return toUserRows(c, users), err
+		},
+		getAll: func(c echo.Context) ([]dataTablesRow, error) {
+			users, err := getUserDao(c).GetAll()
+			return toUserRows(c, users), err
+		},
+	},
+	"groups": {
+		// groups are few, they are not counted natively
+		columns: []string{"id", "name"},
+		getAll: func(c echo.Context) ([]dataTablesRow, error) {
+			groups, err := getGroupDao(c).GetAll()
+			return toGroupRows(c, groups), err
+		},
+	},
+	"audit": {
+		permission: permAuditView,
+		columns:    []string{"time", "message"},
+		getAll: func(c echo.Context) ([]dataTablesRow, error) {
+			return getRegistry(c).auditEntries()
+		},
+	},
+}
+
+func toUserRows(c echo.Context, users []*User) []dataTablesRow {
+	result := make([]dataTablesRow, len(users))
+	for i, u := range users {
+		result[i] = toUserModel(c, u)
+	}
+	return result
+}
+
+func toGroupRows(c echo.Context, groups []*Group) []dataTablesRow {
+	result := make([]dataTablesRow, len(groups))
+	for i, g := range groups {
+		result[i] = toGroupModel(c, g)
+	}
+	return result
+}
+
+/*----------------------------------------------------------------------*/
+
+// AuditEntry is an audit entry read from the log file, see auditf.
+//
+// available since template-r5
+type AuditEntry struct {
+	Time    string
+	Message string
+}
+
+// ToMap returns the JSON representation of the entry.
+func (e *AuditEntry) ToMap() map[string]interface{} {
+	return map[string]interface{}{"time": e.Time, "message": e.Message}
+}
+
+// auditEntries reads audit entries from the current log file (setting goadmin.log_file.path), oldest first. Rotated
+// files are not read. Signatures of signed entries are stripped, they are verified by command "verify-audit".
+func (r *myRegistry) auditEntries() ([]dataTablesRow, error) {
+	result := make([]dataTablesRow, 0)
+	err := r.eachAuditEntry(func(entry *AuditEntry) error {
+		result = append(result, entry)
+		return nil
+	})
+	return result, err
+}
+
+// eachAuditEntry passes audit entries of the current log file to fn one at a time, oldest first, see auditEntries.
+// Reading stops at the first error returned by fn.
+func (r *myRegistry) eachAuditEntry(fn func(entry *AuditEntry) error) error {
+	fileSink := false
+	for _, name := range strings.Split(r.AppConfig.GetString("goadmin.log_sinks", goadmin.LogSinkConsole), ",") {
+		fileSink = fileSink || strings.TrimSpace(name) == goadmin.LogSinkFile
+	}
+	if !fileSink {
+		return nil
+	}
+	f, err := os.Open(r.AppConfig.GetString("goadmin.log_file.path", "./logs/goadmin.log"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		msg := auditMessageOf(line)
+		if msg == "" {
+			continue
+		}
+		if m := reAuditSignature.FindStringSubmatch(msg); m != nil {
+			msg = m[1]
+		}
+		entry := &AuditEntry{Message: msg}
+		if strings.HasPrefix(line, "{") {
+			doc := struct {
+				Time string `json:"time"`
+			}{}
+			json.Unmarshal([]byte(line), &doc)
+			entry.Time = doc.Time
+		} else if i := strings.Index(line, auditTag); i > 0 {
+			entry.Time = strings.TrimSpace(line[:i])
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// actionCpAuditExport streams audit entries of the current log file as CSV (default) or XLSX (query parameter
+// "format=xlsx"), oldest first. Entries are read and written one at a time, whatever the size of the log file.
+//
+// available since template-r5
+func actionCpAuditExport(c echo.Context) error {
+	if err := checkPermission(c, permAuditView); err != nil {
+		AddFlash(c, FlashWarning, "error_no_permission")
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpDashboard)+"?r="+utils.RandomString(4))
+	}
+	format := reportFormatCsv
+	if strings.ToLower(c.QueryParam("format")) == reportFormatXlsx {
+		format = reportFormatXlsx
+	}
+	myReg, locale := getRegistry(c), getContextString(c, ctxLocale)
+	filename := fmt.Sprintf("audit-%s.%s", utils.Now().In(utils.Location).Format("20060102-150405"), format)
+	err := streamReport(c, filename, format, func(w reportWriter) error {
+		header := []string{myReg.i18n.Localize(locale, "report_audit_time"), myReg.i18n.Localize(locale, "report_audit_message")}
+		if err := w.WriteRow(header); err != nil {
+			return err
+		}
+		err := myReg.eachAuditEntry(func(entry *AuditEntry) error {
+			return w.WriteRow([]string{entry.Time, entry.Message})
+		})
+		if err != nil {
+			return err
+		}
+		return w.Close()
+	})
+	if err != nil {
+		// headers have been sent, the truncated download is the best we can signal
+		log.Printf("[ERROR] cannot export audit entries: %s", err)
+	}
+	return nil
+}
+
+/*----------------------------------------------------------------------*/
+
+// DataTablesResponse is the response of the DataTables server-side processing protocol.
+//
+// available since template-r5
+type DataTablesResponse struct {
+	Draw            int                      `json:"draw"`
+	RecordsTotal    int                      `json:"recordsTotal"`
+	RecordsFiltered int                      `json:"recordsFiltered"`
+	Data            []map[string]interface{} `json:"data"`
+	Error           string                   `json:"error,omitempty"`
+}
+
+// dataTablesQuery holds parameters of a draw: paging, global search and ordering (first column only).
+type dataTablesQuery struct {
+	draw, start, length int
+	search              string
+	orderColumn         string // empty for the default order
+	orderDesc           bool
+}
+
+func parseDataTablesQuery(c echo.Context) *dataTablesQuery {
+	q := &dataTablesQuery{}
+	// "draw" is echoed back, it is parsed as a number so that no user input is reflected (as DataTables recommends)
+	q.draw, _ = strconv.Atoi(c.QueryParam("draw"))
+	q.start, _ = strconv.Atoi(c.QueryParam("start"))
+	if q.start < 0 {
+		q.start = 0
+	}
+	q.length, _ = strconv.Atoi(c.QueryParam("length"))
+	if q.length == 0 {
+		// no page size requested: the current user's preferred one
+		q.length = getPreferences(c).PageSize
+	}
+	if q.length <= 0 || q.length > maxDataTablesPageSize {
+		q.length = maxDataTablesPageSize
+	}
+	q.search = strings.ToLower(strings.TrimSpace(c.QueryParam("search[value]")))
+	if i, err := strconv.Atoi(c.QueryParam("order[0][column]")); err == nil {
+		q.orderColumn = c.QueryParam(fmt.Sprintf("columns[%d][data]", i))
+		q.orderDesc = c.QueryParam("order[0][dir]") == "desc"
+	}
+	return q
+}
+
+// draw answers a draw: rows are paged natively if possible, searched and ordered in memory otherwise.
+func (s *dataTablesSource) draw(c echo.Context, q *dataTablesQuery) (*DataTablesResponse, error) {
+	orderable := false
+	for _, col := range s.columns {
+		orderable = orderable || col == q.orderColumn
+	}
+	if !orderable {
+		q.orderColumn, q.orderDesc = s.columns[0], false
+	}
+	resp := &DataTablesResponse{Draw: q.draw, Data: make([]map[string]interface{}, 0)}
+	var rows []dataTablesRow
+	if q.search == "" && q.orderColumn == s.columns[0] && !q.orderDesc && s.count != nil && s.getN != nil {
+		total, err := s.count(c)
+		if err != nil {
+			return nil, err
+		}
+		if rows, err = s.getN(c, q.start, q.length); err != nil {
+			return nil, err
+		}
+		resp.RecordsTotal, resp.RecordsFiltered = total, total
+	} else {
+		all, err := s.getAll(c)
+		if err != nil {
+			return nil, err
+		}
+		resp.RecordsTotal = len(all)
+		values := make(map[dataTablesRow]map[string]interface{}, len(all))
+		for _, row := range all {
+			m := row.ToMap()
+			if q.search == "" || s.matches(m, q.search) {
+				values[row] = m
+				rows = append(rows, row)
+			}
+		}
+		sort.SliceStable(rows, func(i, j int) bool {
+			vi := strings.ToLower(fmt.Sprint(values[rows[i]][q.orderColumn]))
+			vj := strings.ToLower(fmt.Sprint(values[rows[j]][q.orderColumn]))
+			if q.orderDesc {
+				return vi > vj
+			}
+			return vi < vj
+		})
+		resp.RecordsFiltered = len(rows)
+		if q.start >= len(rows) {
+			rows = nil
+		} else {
+			rows = rows[q.start:]
+			if len(rows) > q.length {
+				rows = rows[:q.length]
+			}
+		}
+	}
+	for _, row := range rows {
+		resp.Data = append(resp.Data, row.ToMap())
+	}
+	return resp, nil
+}
+
+// matches checks if any column of a row contains the search term (lower-cased).
+func (s *dataTablesSource) matches(values map[string]interface{}, search string) bool {
+	for _, col := range s.columns {
+		if v, ok := values[col]; ok && strings.Contains(strings.ToLower(fmt.Sprint(v)), search) {
+			return true
+		}
+	}
+	return false
+}
+
+// actionCpDataTables serves users, groups and audit entries (path parameter "name") with the DataTables server-side
+// processing protocol (https://datatables.net/manual/server-side): parameters draw, start, length, search[value],
+// order[0][column], order[0][dir] and columns[i][data] are honored, rows are the canonical JSON representations
+// (ToMap) of the items, e.g.
+//
+//	$("#users").DataTable({serverSide: true, ajax: "/cp/datatables/users", columns: [{data: "username"}, {data: "name"}]})
+//
+// available since template-r5
+func actionCpDataTables(c echo.Context) error {
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	s, ok := dataTablesSources[c.Param("name")]
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotFound)
+	}
+	q := parseDataTablesQuery(c)
+	if s.permission != "" {
+		if err := checkPermission(c, s.permission); err != nil {
+			return c.JSON(http.StatusForbidden, &DataTablesResponse{Draw: q.draw, Data: []map[string]interface{}{}, Error: err.Error()})
+		}
+	}
+	resp, err := s.draw(c, q)
+	if err != nil {
+		// DataTables reports errors found in the envelope
+		return c.JSON(http.StatusOK, &DataTablesResponse{Draw: q.draw, Data: []map[string]interface{}{}, Error: err.Error()})
+	}
+	return c.JSON(http.StatusOK, resp)
+}
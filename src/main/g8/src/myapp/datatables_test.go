@@ -0,0 +1,52 @@
+package myapp
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestActionCpDataTables(t *testing.T) {
+	testName := "TestActionCpDataTables"
+	h := _newHarness(t)
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	for _, name := range []string{"alice", "bob", "carol"} {
+		myReg.userDao.Create(name+"@local", "", strings.ToUpper(name[:1])+name[1:], systemGroupId)
+	}
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	draw := func(name, query string) *DataTablesResponse {
+		resp := h.Get(h.Reverse(actionNameCpDataTables, name) + "?" + query)
+		h.AssertStatus(resp, http.StatusOK)
+		result := &DataTablesResponse{}
+		if err := json.Unmarshal(resp.Body.Bytes(), result); err != nil {
+			t.Fatalf("%s failed: %s", testName, err)
+		}
+		return result
+	}
+
+	// paged by the DAO
+	result := draw("users", "draw=3&start=1&length=2")
+	if result.Draw != 3 || result.RecordsTotal != 4 || result.RecordsFiltered != 4 || len(result.Data) != 2 || result.Data[0]["username"] != "alice@local" || result.Data[1]["username"] != "bob@local" {
+		t.Fatalf("%s failed: unexpected response %#v", testName, result)
+	}
+	// searched and ordered in memory
+	result = draw("users", "draw=4&start=0&length=10&search[value]=CAROL")
+	if result.RecordsTotal != 4 || result.RecordsFiltered != 1 || result.Data[0]["username"] != "carol@local" {
+		t.Fatalf("%s failed: unexpected response %#v", testName, result)
+	}
+	// recordsFiltered counts the searched rows, not the returned page
+	result = draw("users", "draw=4&start=0&length=1&search[value]=r")
+	if result.RecordsTotal != 4 || result.RecordsFiltered != 2 || len(result.Data) != 1 || result.Data[0]["username"] != "admin@local" {
+		t.Fatalf("%s failed: unexpected response %#v", testName, result)
+	}
+	result = draw("users", "draw=5&start=0&length=-1&columns[0][data]=username&columns[1][data]=name&order[0][column]=1&order[0][dir]=desc")
+	if len(result.Data) != 4 || result.Data[0]["name"] != "Carol" || result.Data[3]["name"] != "Administrator" {
+		t.Fatalf("%s failed: unexpected response %#v", testName, result)
+	}
+	result = draw("groups", "draw=1&search[value]="+systemGroupId)
+	if result.RecordsFiltered != 1 || result.Data[0]["id"] != systemGroupId {
+		t.Fatalf("%s failed: unexpected response %#v", testName, result)
+	}
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpDataTables, "unknown")), http.StatusNotFound)
+}
@@ -0,0 +1,260 @@
+package myapp
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/btnguyen2k/goyai"
+	"github.com/labstack/echo/v4"
+	"main/src/utils"
+)
+
+// In demo mode (setting myapp.demo_mode), the application can run as a public demo: the database is seeded with fake
+// but realistic groups and users (block myapp.demo), reset to them on a schedule so that whatever visitors do is
+// undone, and pages carry a watermark. The admin account (see "init" section) cannot be changed.
+//
+// Fake data is generated from a fixed seed, hence every reset restores the same groups and users. Only users and
+// groups are reset: changes of other data (e.g. settings, pages, files) are disabled, see demoLockedRoutes.
+
+// demoSeed seeds the generator of fake data, so that demo data is the same after every reset.
+const demoSeed = 20220101
+
+var (
+	demoGroups = []*Group{
+		{Id: "engineering", Name: "Engineering"},
+		{Id: "sales", Name: "Sales"},
+		{Id: "marketing", Name: "Marketing"},
+		{Id: "support", Name: "Customer Support"},
+		{Id: "finance", Name: "Finance"},
+		{Id: "hr", Name: "Human Resources"},
+		{Id: "legal", Name: "Legal"},
+		{Id: "operations", Name: "Operations"},
+	}
+	demoFirstNames = []string{"Alice", "Bob", "Carol", "David", "Emma", "Farid", "Grace", "Hiro", "Isabel", "James",
+		"Kim", "Linh", "Maria", "Noah", "Olivia", "Pedro", "Quynh", "Rahul", "Sofia", "Thomas", "Uma", "Victor", "Wei",
+		"Yasmin", "Zoe"}
+	demoLastNames = []string{"Anderson", "Brown", "Chen", "Dubois", "Evans", "Fischer", "Garcia", "Hoang", "Ivanova",
+		"Johnson", "Kowalski", "Lopez", "Martin", "Nguyen", "Okafor", "Patel", "Rossi", "Silva", "Tanaka", "Williams"}
+	demoTags  = []string{"vip", "beta", "remote", "contractor", "mentor", "on-call"}
+	demoNotes = []string{"Prefers to be contacted by email", "Account reviewed last quarter", "Travels frequently",
+		"Asked for a training session", "Temporary access until end of project"}
+)
+
+// generateDemoData returns fake groups and users, the same ones for the same seed: groups are the first numGroups of
+// demoGroups, users are spread randomly over them.
+func generateDemoData(seed int64, numGroups, numUsers int) ([]*Group, []*User) {
+	rnd := rand.New(rand.NewSource(seed))
+	if numGroups > len(demoGroups) {
+		numGroups = len(demoGroups)
+	}
+	groups := demoGroups[:numGroups]
+	users := make([]*User, 0, numUsers)
+	if numGroups <= 0 {
+		return groups, users
+	}
+	taken := make(map[string]bool)
+	for i := 0; i < numUsers; i++ {
+		first, last := demoFirstNames[rnd.Intn(len(demoFirstNames))], demoLastNames[rnd.Intn(len(demoLastNames))]
+		username := strings.ToLower(first + "." + last)
+		for n := 2; taken[username]; n++ {
+			username = strings.ToLower(fmt.Sprintf("%s.%s%d", first, last, n))
+		}
+		taken[username] = true
+		user := &User{Username: username + "@example.com", Name: first + " " + last, GroupId: groups[rnd.Intn(numGroups)].Id}
+		tags := make([]string, 0, 2)
+		for j := rnd.Intn(3); j > 0; j-- {
+			if tag := demoTags[rnd.Intn(len(demoTags))]; !containsString(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+		user.Tags = strings.Join(tags, ",")
+		if rnd.Intn(4) == 0 {
+			user.Notes = demoNotes[rnd.Intn(len(demoNotes))]
+		}
+		users = append(users, user)
+	}
+	return groups, users
+}
+
+/*----------------------------------------------------------------------*/
+
+// demoData seeds demo data and resets the database to it on a schedule.
+type demoData struct {
+	r         *myRegistry
+	groups    int
+	users     int
+	interval  time.Duration // 0 if the database is never reset
+	watermark bool
+
+	lock sync.Mutex
+	next time.Time // time of the next reset
+}
+
+// newDemoData returns the demo data of the application, nil if demo mode is off.
+func newDemoData(r *myRegistry) *demoData {
+	if !r.demoMode {
+		return nil
+	}
+	conf := r.AppConfig
+	return &demoData{
+		r:         r,
+		groups:    int(conf.GetInt32(namespace+".demo.groups", 5)),
+		users:     int(conf.GetInt32(namespace+".demo.users", 50)),
+		interval:  conf.GetTimeDuration(namespace+".demo.reset_interval", 6*time.Hour),
+		watermark: conf.GetBoolean(namespace+".demo.watermark", true),
+	}
+}
+
+// seed creates the demo groups and users that do not exist yet, and writes an audit entry per created record.
+func (d *demoData) seed() (*FixtureResult, error) {
+	groups, users := generateDemoData(demoSeed, d.groups, d.users)
+	result := &FixtureResult{}
+	for _, g := range groups {
+		ok, err := d.r.groupDao.Create(g.Id, g.Name)
+		if err != nil {
+			return result, err
+		}
+		if ok {
+			result.Groups++
+			d.r.auditf("user [%s] created group [%s] (demo data)", systemUserUsername, g.Id)
+		}
+	}
+	for _, u := range users {
+		ok, err := d.r.userDao.Create(u.Username, "", u.Name, u.GroupId)
+		if err != nil {
+			return result, err
+		}
+		if !ok {
+			continue
+		}
+		if u.Tags != "" || u.Notes != "" {
+			if _, err := d.r.userDao.Update(u); err != nil {
+				return result, err
+			}
+		}
+		result.Users++
+		d.r.auditf("user [%s] created user [%s] in group [%s] (demo data)", systemUserUsername, u.Username, u.GroupId)
+	}
+	return result, nil
+}
+
+// reset deletes all users and groups but the admin account and the system group, then seeds the demo data again.
+func (d *demoData) reset() (*FixtureResult, error) {
+	users, err := d.r.userDao.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	numUsers, numGroups := 0, 0
+	for _, u := range users {
+		if u.Username != systemUserUsername {
+			if _, err := d.r.userDao.Delete(u); err != nil {
+				return nil, err
+			}
+			numUsers++
+		}
+	}
+	groups, err := d.r.groupDao.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range groups {
+		if g.Id != systemGroupId {
+			if _, err := d.r.groupDao.Delete(g); err != nil {
+				return nil, err
+			}
+			numGroups++
+		}
+	}
+	d.r.auditf("demo data reset: %d user(s) and %d group(s) deleted", numUsers, numGroups)
+	return d.seed()
+}
+
+// start seeds the demo data and, if setting myapp.demo.reset_interval is positive, resets the database every
+// interval. Seeded users have no password, they cannot sign in.
+func (d *demoData) start() {
+	// replicas starting simultaneously must not seed the demo data twice
+	if err := d.r.WithLock(namespace+":demo_reset", time.Minute, func() error { _, err := d.seed(); return err }); err != nil {
+		log.Printf("[ERROR] cannot seed demo data: %s", err)
+	}
+	if d.interval <= 0 {
+		return
+	}
+	d.next = utils.Now().Add(d.interval)
+	go func() {
+		ticker := time.NewTicker(d.interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			d.lock.Lock()
+			d.next = utils.Now().Add(d.interval)
+			d.lock.Unlock()
+			// in clustered deployments, only the elected leader resets the database
+			if !d.r.IsLeader() {
+				continue
+			}
+			if _, err := d.r.TryWithLock(namespace+":demo_reset", time.Hour, func() error {
+				result, err := d.reset()
+				if err == nil {
+					log.Printf("Demo data reset: %d group(s) and %d user(s) created", result.Groups, result.Users)
+				}
+				return err
+			}); err != nil {
+				log.Printf("[ERROR] cannot reset demo data: %s", err)
+			}
+		}
+	}()
+}
+
+// nextReset returns time of the next reset, zero if the database is never reset.
+func (d *demoData) nextReset() time.Time {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.next
+}
+
+// demoLockedRoutes are state-changing control panel routes disabled in demo mode: they change data that is not reset
+// with users and groups, which visitors could otherwise deface for good.
+var demoLockedRoutes = []string{
+	actionNameCpProfileAvatarSubmit, actionNameCpProfileAvatarDeleteSubmit, actionNameCpGroupFieldsSubmit,
+	actionNameCpGroupInviteSubmit, actionNameCpGroupInviteRevoke,
+	actionNameCpUserAttachmentSubmit, actionNameCpUserAttachmentDeleteSubmit, actionNameCpTranslationsSubmit,
+	actionNameCpSecuritySettingsSubmit, actionNameCpSmsTestSubmit, actionNameCpReadOnlySubmit,
+	actionNameCpBrandingSettingsSubmit, actionNameCpLoginPageSubmit, actionNameCpTermsSettingsSubmit,
+	actionNameCpLoggingSettingsSubmit, actionNameCpConfigBundleImport,
+	actionNameCpCreateTokenSubmit, actionNameCpRevokeTokenSubmit, actionNameCpTokenLimitsSubmit,
+	actionNameCpCreatePageSubmit, actionNameCpEditPageSubmit, actionNameCpDeletePageSubmit,
+}
+
+// middlewareDemo rejects requests to demoLockedRoutes in demo mode, the same way as read-only mode does.
+//
+// available since template-r5
+func middlewareDemo(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !getRegistry(c).demoMode {
+			return next(c)
+		}
+		for _, name := range demoLockedRoutes {
+			if c.Path() == c.Echo().Reverse(name) {
+				return rejectStateChange(c, "error_demo_locked")
+			}
+		}
+		return next(c)
+	}
+}
+
+// banner returns the localized text of the demo watermark, empty if pages are not watermarked.
+func (d *demoData) banner(c echo.Context) string {
+	if !d.watermark {
+		return ""
+	}
+	locale := getContextString(c, ctxLocale)
+	if next := d.nextReset(); !next.IsZero() {
+		return getI18n(c).Localize(locale, "demo_banner_reset", &goyai.LocalizeConfig{
+			TemplateData: map[string]interface{}{"next": next.In(utils.Location).Format("2006-01-02 15:04")},
+		})
+	}
+	return getI18n(c).Localize(locale, "demo_banner")
+}
@@ -0,0 +1,65 @@
+package myapp
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"main/src/apptest"
+)
+
+func TestDemoMode(t *testing.T) {
+	testName := "TestDemoMode"
+	conf := apptest.SqliteInMemoryConfig + "\nmyapp.demo_mode = true\nmyapp.demo { groups = 3, users = 20, reset_interval = 1h }\n"
+	h := apptest.New(t, conf, NewBootstrapper(nil, nil))
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+
+	// fake data is the same for the same seed
+	groups1, users1 := generateDemoData(demoSeed, 3, 20)
+	groups2, users2 := generateDemoData(demoSeed, 3, 20)
+	if !reflect.DeepEqual(groups1, groups2) || !reflect.DeepEqual(users1, users2) {
+		t.Fatalf("%s failed: demo data must be deterministic", testName)
+	}
+	countData := func() (int, int) {
+		groups, _ := myReg.groupDao.GetAll()
+		users, _ := myReg.userDao.GetAll()
+		return len(groups), len(users)
+	}
+	if numGroups, numUsers := countData(); numGroups != 3+1 || numUsers != 20+1 {
+		t.Fatalf("%s failed: expected 4 groups and 21 users but received %d/%d", testName, numGroups, numUsers)
+	}
+	if user, _ := myReg.userDao.Get(users1[0].Username); user == nil || user.GroupId != users1[0].GroupId || user.Tags != users1[0].Tags {
+		t.Fatalf("%s failed: demo user %#v not seeded, received %#v", testName, users1[0], user)
+	}
+
+	// pages are watermarked
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	resp := h.Get(h.Reverse(actionNameCpDashboard))
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertBodyContains(resp, "users and groups will be reset at")
+
+	// changes of visitors are undone by the reset
+	myReg.userDao.Create("visitor@local", "", "Visitor", groups1[0].Id)
+	myReg.groupDao.Delete(groups1[1])
+	if _, err := myReg.demo.reset(); err != nil {
+		t.Fatalf("%s failed: %s", testName, err)
+	}
+	if numGroups, numUsers := countData(); numGroups != 3+1 || numUsers != 20+1 {
+		t.Fatalf("%s failed: expected 4 groups and 21 users after reset but received %d/%d", testName, numGroups, numUsers)
+	}
+	if user, _ := myReg.userDao.Get("visitor@local"); user != nil {
+		t.Fatalf("%s failed: users of visitors must be deleted by the reset", testName)
+	}
+	if admin, _ := myReg.userDao.Get(testAdminUsername); admin == nil {
+		t.Fatalf("%s failed: admin account must survive the reset", testName)
+	}
+
+	// data that is not reset can not be changed
+	h.AssertStatus(h.PostForm(h.Reverse(actionNameCpCreateTokenSubmit), url.Values{"name": {"visitor"}}), http.StatusSeeOther)
+	h.AssertStatus(h.PostForm(h.Reverse(actionNameCpReadOnlySubmit), url.Values{"enabled": {"1"}}), http.StatusSeeOther)
+	if tokens, _ := myReg.settingsWithPrefix(settingPrefixApiToken); len(tokens) != 0 || myReg.isReadOnly() {
+		t.Fatalf("%s failed: changes of data that is not reset must be rejected in demo mode", testName)
+	}
+	h.AssertRedirect(h.PostForm(h.Reverse(actionNameCpCreateGroupSubmit), url.Values{"id": {"visitors"}, "name": {"Visitors"}}), h.Reverse(actionNameCpGroups))
+}
@@ -0,0 +1,369 @@
+package myapp
+
+import (
+	"bytes"
+	"html/template"
+	"io"
+	"net/http"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"main/src/goadmin"
+)
+
+// ctxRequestProfile is the context key of the *RequestProfile of the current request, set in dev mode only.
+const ctxRequestProfile = "req_profile"
+
+// RequestProfile holds what a request cost, shown by the debug toolbar: database queries issued through the DAOs,
+// template rendering and the size of the session cookie.
+//
+// available since template-r5
+type RequestProfile struct {
+	Queries     int           // number of DAO calls that reached the database (cache hits are not counted)
+	DbTime      time.Duration // total time spent in those calls
+	RenderTime  time.Duration // time spent executing templates, DAO calls made by templates included
+	SessionSize int           // size of the session cookie, in bytes
+	start       time.Time
+}
+
+// Elapsed returns the time elapsed since the request started.
+func (p *RequestProfile) Elapsed() time.Duration {
+	return time.Since(p.start)
+}
+
+// devProfiler profiles requests in dev mode (setting dev_mode) to help module authors spot costly patterns, e.g. a
+// page calling AllUsers() once per row. DAOs are wrapped so that their calls are counted (see wrapProfiledDaos) and
+// rendered pages carry a toolbar showing the profile of the request (setting myapp.dev_toolbar).
+//
+// DAO methods do not receive the request, calls are attributed to the request served by the calling goroutine:
+// calls made by goroutines the handler spawns (e.g. background tasks) are not counted. Finding out the goroutine is
+// not cheap, which is fine in dev mode only.
+//
+// available since template-r5
+type devProfiler struct {
+	lock     sync.Mutex
+	profiles map[uint64]*RequestProfile // per goroutine id
+}
+
+func newDevProfiler() *devProfiler {
+	return &devProfiler{profiles: make(map[uint64]*RequestProfile)}
+}
+
+// goroutineId returns the id of the calling goroutine, parsed from the header of its stack trace
+// (e.g. "goroutine 42 [running]:").
+func goroutineId() uint64 {
+	buf := make([]byte, 64)
+	buf = bytes.TrimPrefix(buf[:runtime.Stack(buf, false)], []byte("goroutine "))
+	if i := bytes.IndexByte(buf, ' '); i > 0 {
+		id, _ := strconv.ParseUint(string(buf[:i]), 10, 64)
+		return id
+	}
+	return 0
+}
+
+// track counts a DAO call started at start, if the calling goroutine is serving a request.
+func (p *devProfiler) track(start time.Time) {
+	elapsed, gid := time.Since(start), goroutineId()
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if prof := p.profiles[gid]; prof != nil {
+		prof.Queries++
+		prof.DbTime += elapsed
+	}
+}
+
+// middleware profiles the request, see ctxRequestProfile.
+func (p *devProfiler) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		prof, gid := &RequestProfile{start: time.Now()}, goroutineId()
+		p.lock.Lock()
+		p.profiles[gid] = prof
+		p.lock.Unlock()
+		defer func() {
+			p.lock.Lock()
+			delete(p.profiles, gid)
+			p.lock.Unlock()
+		}()
+		c.Set(ctxRequestProfile, prof)
+		return next(c)
+	}
+}
+
+// sessionCookieSize returns the size of the session cookie: the one being set by the response if any, the one sent
+// with the request otherwise.
+func sessionCookieSize(c echo.Context) int {
+	size := 0
+	if cookie, err := c.Cookie(namespace); err == nil {
+		size = len(cookie.Value)
+	}
+	resp := &http.Response{Header: http.Header{echo.HeaderSetCookie: c.Response().Header().Values(echo.HeaderSetCookie)}}
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == namespace {
+			size = len(cookie.Value)
+		}
+	}
+	return size
+}
+
+// renderWithToolbar renders a page into a buffer, so that the toolbar showing the profile of the request (template
+// dev_toolbar) can be injected right before the closing </body> tag once rendering, and its DAO calls, are done.
+// Documents without </body> are written as rendered.
+func (r *myRenderer) renderWithToolbar(w io.Writer, prof *RequestProfile, c echo.Context, render func(w io.Writer) error) error {
+	buf := &bytes.Buffer{}
+	start := time.Now()
+	if err := render(buf); err != nil {
+		return err
+	}
+	prof.RenderTime = time.Since(start)
+	prof.SessionSize = sessionCookieSize(c)
+	page := buf.Bytes()
+	i := bytes.LastIndex(page, []byte("</body>"))
+	if i < 0 {
+		_, err := w.Write(page)
+		return err
+	}
+	tpl, err := template.New("dev_toolbar.html").Funcs(template.FuncMap{
+		"ms": func(d time.Duration) string {
+			return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 1, 64) + "ms"
+		},
+	}).ParseFiles(r.templateFile("dev_toolbar"))
+	if err != nil {
+		return err
+	}
+	toolbar := &bytes.Buffer{}
+	if err := tpl.Execute(toolbar, map[string]interface{}{"profile": prof, "route": c.Path(), "method": c.Request().Method}); err != nil {
+		return err
+	}
+	for _, chunk := range [][]byte{page[:i], toolbar.Bytes(), page[i:]} {
+		if _, err := w.Write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*----------------------------------------------------------------------*/
+
+// profiledGroupDao is a GroupDao whose calls are counted by the dev profiler.
+type profiledGroupDao struct {
+	GroupDao
+	p *devProfiler
+}
+
+// Delete implements GroupDao.Delete
+func (dao *profiledGroupDao) Delete(bo *Group) (bool, error) {
+	defer dao.p.track(time.Now())
+	return dao.GroupDao.Delete(bo)
+}
+
+// Create implements GroupDao.Create
+func (dao *profiledGroupDao) Create(id, name string) (bool, error) {
+	defer dao.p.track(time.Now())
+	return dao.GroupDao.Create(id, name)
+}
+
+// Get implements GroupDao.Get
+func (dao *profiledGroupDao) Get(id string) (*Group, error) {
+	defer dao.p.track(time.Now())
+	return dao.GroupDao.Get(id)
+}
+
+// GetN implements GroupDao.GetN
+func (dao *profiledGroupDao) GetN(fromOffset, maxNumRows int) ([]*Group, error) {
+	defer dao.p.track(time.Now())
+	return dao.GroupDao.GetN(fromOffset, maxNumRows)
+}
+
+// GetAll implements GroupDao.GetAll
+func (dao *profiledGroupDao) GetAll() ([]*Group, error) {
+	defer dao.p.track(time.Now())
+	return dao.GroupDao.GetAll()
+}
+
+// Update implements GroupDao.Update
+func (dao *profiledGroupDao) Update(bo *Group) (bool, error) {
+	defer dao.p.track(time.Now())
+	return dao.GroupDao.Update(bo)
+}
+
+// withOutbox implements txOutboxWriter.withOutbox
+func (dao *profiledGroupDao) withOutbox(msgs []*goadmin.OutboxMessage) interface{} {
+	if bound := boundGroupDao(dao.GroupDao, msgs); bound != nil {
+		return &profiledGroupDao{GroupDao: bound, p: dao.p}
+	}
+	return nil
+}
+
+// GetByIds implements GroupBatchGetter.GetByIds
+func (dao *profiledGroupDao) GetByIds(ids []string) (map[string]*Group, error) {
+	if getter, ok := dao.GroupDao.(GroupBatchGetter); ok {
+		defer dao.p.track(time.Now())
+		return getter.GetByIds(ids)
+	}
+	return getGroupsByIds(struct{ GroupDao }{dao}, ids)
+}
+
+// GetNAfter implements GroupKeysetPager.GetNAfter
+func (dao *profiledGroupDao) GetNAfter(afterId string, maxNumRows int) ([]*Group, error) {
+	if pager, ok := dao.GroupDao.(GroupKeysetPager); ok {
+		defer dao.p.track(time.Now())
+		return pager.GetNAfter(afterId, maxNumRows)
+	}
+	return getNGroupsAfter(struct{ GroupDao }{dao}, afterId, maxNumRows)
+}
+
+// profiledUserDao is a UserDao whose calls are counted by the dev profiler.
+type profiledUserDao struct {
+	UserDao
+	p *devProfiler
+}
+
+// Delete implements UserDao.Delete
+func (dao *profiledUserDao) Delete(bo *User) (bool, error) {
+	defer dao.p.track(time.Now())
+	return dao.UserDao.Delete(bo)
+}
+
+// Create implements UserDao.Create
+func (dao *profiledUserDao) Create(username, encryptedPassword, name, groupId string) (bool, error) {
+	defer dao.p.track(time.Now())
+	return dao.UserDao.Create(username, encryptedPassword, name, groupId)
+}
+
+// Get implements UserDao.Get
+func (dao *profiledUserDao) Get(username string) (*User, error) {
+	defer dao.p.track(time.Now())
+	return dao.UserDao.Get(username)
+}
+
+// GetN implements UserDao.GetN
+func (dao *profiledUserDao) GetN(fromOffset, maxNumRows int) ([]*User, error) {
+	defer dao.p.track(time.Now())
+	return dao.UserDao.GetN(fromOffset, maxNumRows)
+}
+
+// GetAll implements UserDao.GetAll
+func (dao *profiledUserDao) GetAll() ([]*User, error) {
+	defer dao.p.track(time.Now())
+	return dao.UserDao.GetAll()
+}
+
+// Update implements UserDao.Update
+func (dao *profiledUserDao) Update(bo *User) (bool, error) {
+	defer dao.p.track(time.Now())
+	return dao.UserDao.Update(bo)
+}
+
+// withOutbox implements txOutboxWriter.withOutbox
+func (dao *profiledUserDao) withOutbox(msgs []*goadmin.OutboxMessage) interface{} {
+	if bound := boundUserDao(dao.UserDao, msgs); bound != nil {
+		return &profiledUserDao{UserDao: bound, p: dao.p}
+	}
+	return nil
+}
+
+// CountByGroup implements UserCounter.CountByGroup
+func (dao *profiledUserDao) CountByGroup() (map[string]int, error) {
+	if counter, ok := dao.UserDao.(UserCounter); ok {
+		defer dao.p.track(time.Now())
+		return counter.CountByGroup()
+	}
+	// users are fetched through the profiled DAO, hiding its own CountByGroup
+	return countUsersByGroup(struct{ UserDao }{dao})
+}
+
+// GetNByGroup implements UserGroupPager.GetNByGroup
+func (dao *profiledUserDao) GetNByGroup(groupId string, fromOffset, maxNumRows int) ([]*User, error) {
+	if pager, ok := dao.UserDao.(UserGroupPager); ok {
+		defer dao.p.track(time.Now())
+		return pager.GetNByGroup(groupId, fromOffset, maxNumRows)
+	}
+	return getNUsersByGroup(struct{ UserDao }{dao}, groupId, fromOffset, maxNumRows)
+}
+
+// GetNAfter implements UserKeysetPager.GetNAfter
+func (dao *profiledUserDao) GetNAfter(afterUsername string, maxNumRows int) ([]*User, error) {
+	if pager, ok := dao.UserDao.(UserKeysetPager); ok {
+		defer dao.p.track(time.Now())
+		return pager.GetNAfter(afterUsername, maxNumRows)
+	}
+	return getNUsersAfter(struct{ UserDao }{dao}, afterUsername, maxNumRows)
+}
+
+// profiledMessageDao is a MessageDao whose calls are counted by the dev profiler.
+type profiledMessageDao struct {
+	MessageDao
+	p *devProfiler
+}
+
+// Delete implements MessageDao.Delete
+func (dao *profiledMessageDao) Delete(bo *Message) (bool, error) {
+	defer dao.p.track(time.Now())
+	return dao.MessageDao.Delete(bo)
+}
+
+// Get implements MessageDao.Get
+func (dao *profiledMessageDao) Get(locale, key string) (*Message, error) {
+	defer dao.p.track(time.Now())
+	return dao.MessageDao.Get(locale, key)
+}
+
+// GetAll implements MessageDao.GetAll
+func (dao *profiledMessageDao) GetAll() ([]*Message, error) {
+	defer dao.p.track(time.Now())
+	return dao.MessageDao.GetAll()
+}
+
+// Save implements MessageDao.Save
+func (dao *profiledMessageDao) Save(bo *Message) (bool, error) {
+	defer dao.p.track(time.Now())
+	return dao.MessageDao.Save(bo)
+}
+
+// profiledSettingDao is a SettingDao whose calls are counted by the dev profiler.
+type profiledSettingDao struct {
+	SettingDao
+	p *devProfiler
+}
+
+// Delete implements SettingDao.Delete
+func (dao *profiledSettingDao) Delete(bo *Setting) (bool, error) {
+	defer dao.p.track(time.Now())
+	return dao.SettingDao.Delete(bo)
+}
+
+// Get implements SettingDao.Get
+func (dao *profiledSettingDao) Get(id string) (*Setting, error) {
+	defer dao.p.track(time.Now())
+	return dao.SettingDao.Get(id)
+}
+
+// GetAll implements SettingDao.GetAll
+func (dao *profiledSettingDao) GetAll() ([]*Setting, error) {
+	defer dao.p.track(time.Now())
+	return dao.SettingDao.GetAll()
+}
+
+// Save implements SettingDao.Save
+func (dao *profiledSettingDao) Save(bo *Setting) (bool, error) {
+	defer dao.p.track(time.Now())
+	return dao.SettingDao.Save(bo)
+}
+
+// GetByPrefix implements SettingPrefixGetter.GetByPrefix
+func (dao *profiledSettingDao) GetByPrefix(prefix string) ([]*Setting, error) {
+	defer dao.p.track(time.Now())
+	return getSettingsByPrefix(dao.SettingDao, prefix)
+}
+
+// wrapProfiledDaos wraps the registry's storage DAOs so that their calls are counted by the dev profiler. It must be
+// called before DAOs are wrapped by caches, so that cache hits are not counted.
+func wrapProfiledDaos(r *myRegistry) {
+	r.groupDao = &profiledGroupDao{GroupDao: r.groupDao, p: r.profiler}
+	r.userDao = &profiledUserDao{UserDao: r.userDao, p: r.profiler}
+	r.messageDao = &profiledMessageDao{MessageDao: r.messageDao, p: r.profiler}
+	r.settingDao = &profiledSettingDao{SettingDao: r.settingDao, p: r.profiler}
+}
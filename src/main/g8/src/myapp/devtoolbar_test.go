@@ -0,0 +1,34 @@
+package myapp
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"main/src/apptest"
+	"main/src/utils"
+)
+
+func TestDevToolbar(t *testing.T) {
+	testName := "TestDevToolbar"
+	h := _newHarness(t)
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	if strings.Contains(h.Get(h.Reverse(actionNameCpUsers)).Body.String(), `id="dev-toolbar"`) {
+		t.Fatalf("%s failed: debug toolbar must not be shown in production mode", testName)
+	}
+
+	h = apptest.New(t, apptest.SqliteInMemoryConfig+"\ndev_mode = true\n", NewBootstrapper(nil, nil))
+	defer func() { utils.DevMode = false }()
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	resp := h.Get(h.Reverse(actionNameCpUsers))
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertBodyContains(resp, `id="dev-toolbar"`)
+	if m := regexp.MustCompile(`(\d+) queries`).FindStringSubmatch(resp.Body.String()); m == nil || m[1] == "0" {
+		t.Fatalf("%s failed: expected queries to be counted, got %v", testName, m)
+	}
+	// fragments are left untouched
+	if strings.Contains(h.Get(h.Reverse(actionNameCpFragment, "groups_table")).Body.String(), `id="dev-toolbar"`) {
+		t.Fatalf("%s failed: debug toolbar must not be injected in fragments", testName)
+	}
+}
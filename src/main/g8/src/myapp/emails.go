@@ -0,0 +1,186 @@
+package myapp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"main/src/goadmin"
+	"main/src/utils"
+)
+
+// EmailModel represents an email waiting in the outbox (see goadmin.OutboxChannelEmail) in views: either pending
+// (queued or being retried) or failed (a dead letter, given up after too many attempts).
+//
+// available since template-r5
+type EmailModel struct {
+	c echo.Context
+	*goadmin.OutboxMessage
+	Email *goadmin.EmailPayload
+}
+
+func toEmailModel(c echo.Context, msg *goadmin.OutboxMessage) *EmailModel {
+	email := &goadmin.EmailPayload{}
+	json.Unmarshal(msg.Payload, email)
+	return &EmailModel{c: c, OutboxMessage: msg, Email: email}
+}
+
+// To returns recipients of the email.
+func (m *EmailModel) To() string {
+	return strings.Join(m.Email.To, ", ")
+}
+
+// CreatedStr returns the time the email was queued, in the application's timezone.
+func (m *EmailModel) CreatedStr() string {
+	return m.Created.In(utils.Location).Format("2006-01-02 15:04:05")
+}
+
+// NextAttemptStr returns the time of the next delivery attempt, empty for failed emails.
+func (m *EmailModel) NextAttemptStr() string {
+	if m.IsDead() {
+		return ""
+	}
+	return m.NextAttempt.In(utils.Location).Format("2006-01-02 15:04:05")
+}
+
+// GaveUpStr returns the time the email was given up, empty for pending emails.
+func (m *EmailModel) GaveUpStr() string {
+	if !m.IsDead() {
+		return ""
+	}
+	return m.GaveUp.In(utils.Location).Format("2006-01-02 15:04:05")
+}
+
+// UrlView returns the URL to inspect the email.
+func (m *EmailModel) UrlView() string {
+	return m.c.Echo().Reverse(actionNameCpEmail) + "?id=" + url.QueryEscape(m.Id)
+}
+
+// UrlResend returns the URL to re-send the email.
+func (m *EmailModel) UrlResend() string {
+	return m.c.Echo().Reverse(actionNameCpResendEmailSubmit) + "?id=" + url.QueryEscape(m.Id)
+}
+
+/*----------------------------------------------------------------------*/
+
+// countDeadLetters counts outbox messages given up after too many failed attempts.
+func countDeadLetters(r *myRegistry) (int, error) {
+	msgs, err := r.Outbox.Store.List()
+	count := 0
+	for _, msg := range msgs {
+		if msg.IsDead() {
+			count++
+		}
+	}
+	return count, err
+}
+
+// purgeDeadLetters deletes outbox messages given up before cutoff.
+func purgeDeadLetters(r *myRegistry, cutoff time.Time) (int, error) {
+	msgs, err := r.Outbox.Store.List()
+	if err != nil {
+		return 0, err
+	}
+	numDeleted := 0
+	for _, msg := range msgs {
+		if msg.IsDead() && msg.GaveUp.Before(cutoff) {
+			if err := r.Outbox.Store.Delete(msg.Id); err != nil {
+				return numDeleted, err
+			}
+			numDeleted++
+		}
+	}
+	return numDeleted, nil
+}
+
+/*----------------------------------------------------------------------*/
+
+// actionCpEmails lists emails waiting in the outbox: failed ones (dead letters) then pending ones, so that lost
+// notifications can be investigated and re-sent.
+//
+// available since template-r5
+func actionCpEmails(c echo.Context) error {
+	if err := checkPermission(c, permEmailManage); err != nil {
+		AddFlashText(c, FlashWarning, err.Error())
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpDashboard))
+	}
+	myReg := getRegistry(c)
+	failed, pending := make([]*EmailModel, 0), make([]*EmailModel, 0)
+	data := map[string]interface{}{
+		"active":  "emails",
+		"enabled": myReg.Outbox.HasSender(goadmin.OutboxChannelEmail),
+	}
+	msgs, err := myReg.Outbox.Messages(goadmin.OutboxChannelEmail)
+	if err != nil {
+		data["error"] = err.Error()
+	}
+	for _, msg := range msgs {
+		if msg.IsDead() {
+			failed = append(failed, toEmailModel(c, msg))
+		} else {
+			pending = append(pending, toEmailModel(c, msg))
+		}
+	}
+	data["failed"], data["pending"] = failed, pending
+	return c.Render(http.StatusOK, namespace+":layout:cp_emails", data)
+}
+
+// checkCpEmail returns the email of query parameter "id" if the current user can manage emails.
+func checkCpEmail(c echo.Context) (*goadmin.OutboxMessage, error) {
+	if err := checkPermission(c, permEmailManage); err != nil {
+		return nil, err
+	}
+	msg, err := getRegistry(c).Outbox.Store.Get(c.QueryParam("id"))
+	if err != nil {
+		return nil, err
+	}
+	if msg == nil || msg.Channel != goadmin.OutboxChannelEmail {
+		AddFlash(c, FlashWarning, "error_email_not_found", "id", c.QueryParam("id"))
+		return nil, nil
+	}
+	return msg, nil
+}
+
+// actionCpEmail shows an email of the outbox (query parameter "id"): recipients, subject, body and delivery attempts.
+//
+// available since template-r5
+func actionCpEmail(c echo.Context) error {
+	msg, err := checkCpEmail(c)
+	if err != nil {
+		AddFlashText(c, FlashWarning, err.Error())
+	}
+	if msg == nil {
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpEmails))
+	}
+	AddBreadcrumb(c, "", "email_inspect")
+	return c.Render(http.StatusOK, namespace+":layout:cp_email", map[string]interface{}{
+		"active": "emails",
+		"email":  toEmailModel(c, msg),
+	})
+}
+
+// actionCpResendEmailSubmit schedules an email of the outbox (query parameter "id") to be sent at the next dispatch
+// of the outbox, with a fresh count of attempts.
+//
+// available since template-r5
+func actionCpResendEmailSubmit(c echo.Context) error {
+	redirectUrl := c.Echo().Reverse(actionNameCpEmails) + "?r=" + utils.RandomString(4)
+	msg, err := checkCpEmail(c)
+	if err != nil {
+		AddFlashText(c, FlashWarning, err.Error())
+	}
+	if msg == nil {
+		return c.Redirect(http.StatusFound, redirectUrl)
+	}
+	if ok, err := getRegistry(c).Outbox.Retry(msg.Id); err != nil {
+		AddFlash(c, FlashError, "error_db_001", "err", err.Error())
+	} else if !ok {
+		AddFlash(c, FlashWarning, "error_email_not_found", "id", msg.Id)
+	} else {
+		AddFlash(c, FlashInfo, "email_resent", "id", msg.Id)
+	}
+	return c.Redirect(http.StatusFound, redirectUrl)
+}
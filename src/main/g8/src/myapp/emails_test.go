@@ -0,0 +1,55 @@
+package myapp
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"main/src/apptest"
+	"main/src/goadmin"
+)
+
+func TestEmailQueue(t *testing.T) {
+	testName := "TestEmailQueue"
+	// nothing listens on port 1: deliveries fail
+	conf := apptest.SqliteInMemoryConfig + "\ngoadmin.smtp.addr = \"127.0.0.1:1\"\ngoadmin.outbox.max_attempts = 2\n"
+	h := apptest.New(t, conf, NewBootstrapper(nil, nil))
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	myReg.Outbox.Backoff = 0
+	msg, err := myReg.Outbox.Enqueue(goadmin.OutboxChannelEmail, &goadmin.EmailPayload{To: []string{"someone@local"}, Subject: "Hello", Body: "Hello world"})
+	if err != nil {
+		t.Fatalf("%s failed: %s", testName, err)
+	}
+	myReg.Outbox.Dispatch()
+	myReg.Outbox.Dispatch()
+	if msgs, _ := myReg.Outbox.Store.Due(time.Now(), 0); len(msgs) != 0 {
+		t.Fatalf("%s failed: dead letters must not be due %#v", testName, msgs)
+	}
+	if n, _ := countDeadLetters(myReg); n != 1 {
+		t.Fatalf("%s failed: expected 1 dead letter but received %d", testName, n)
+	}
+
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpEmails)), http.StatusOK)
+	h.AssertTemplate(namespace + ":layout:cp_emails")
+	if failed, _ := h.LastData()["failed"].([]*EmailModel); len(failed) != 1 || failed[0].Attempts != 2 || failed[0].LastError == "" || failed[0].To() != "someone@local" {
+		t.Fatalf("%s failed: unexpected failed emails %#v", testName, failed)
+	}
+	resp := h.Get(h.Reverse(actionNameCpEmail) + "?id=" + msg.Id)
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertBodyContains(resp, "Hello world")
+	h.AssertRedirect(h.Get(h.Reverse(actionNameCpEmail)+"?id=not-found"), h.Reverse(actionNameCpEmails))
+
+	h.AssertRedirect(h.PostForm(h.Reverse(actionNameCpResendEmailSubmit)+"?id="+msg.Id, url.Values{}), h.Reverse(actionNameCpEmails))
+	if msgs, _ := myReg.Outbox.Store.Due(time.Now(), 0); len(msgs) != 1 || msgs[0].Attempts != 0 || msgs[0].IsDead() {
+		t.Fatalf("%s failed: re-sent email must be due %#v", testName, msgs)
+	}
+
+	// dead letters are purged by the retention job
+	myReg.Outbox.Dispatch()
+	myReg.Outbox.Dispatch()
+	if n, err := purgeDeadLetters(myReg, time.Now().Add(time.Minute)); err != nil || n != 1 {
+		t.Fatalf("%s failed: %d/%s", testName, n, err)
+	}
+}
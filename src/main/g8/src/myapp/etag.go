@@ -0,0 +1,256 @@
+package myapp
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/labstack/echo/v4"
+	"main/src/goadmin"
+	"main/src/utils"
+)
+
+// tableVersion is a counter bumped on every successful write to a table, used to compute ETags of data-driven pages.
+//
+// The counter lives in memory: writes performed by other instances of the application are not seen, hence
+// conditional rendering (setting myapp.conditional_rendering) should be disabled when running multiple instances
+// against a shared database.
+//
+// available since template-r5
+type tableVersion struct {
+	counter uint64
+}
+
+func (v *tableVersion) bump() {
+	atomic.AddUint64(&v.counter, 1)
+}
+
+func (v *tableVersion) get() uint64 {
+	return atomic.LoadUint64(&v.counter)
+}
+
+// bumpIf bumps the version if the write operation succeeded, and passes through its result.
+func (v *tableVersion) bumpIf(ok bool, err error) (bool, error) {
+	if ok && err == nil {
+		v.bump()
+	}
+	return ok, err
+}
+
+/*----------------------------------------------------------------------*/
+
+// versionedGroupDao is a GroupDao that maintains the version of the group table.
+type versionedGroupDao struct {
+	GroupDao
+	version tableVersion
+}
+
+// Delete implements GroupDao.Delete
+func (dao *versionedGroupDao) Delete(bo *Group) (bool, error) {
+	return dao.version.bumpIf(dao.GroupDao.Delete(bo))
+}
+
+// Create implements GroupDao.Create
+func (dao *versionedGroupDao) Create(id, name string) (bool, error) {
+	return dao.version.bumpIf(dao.GroupDao.Create(id, name))
+}
+
+// Update implements GroupDao.Update
+func (dao *versionedGroupDao) Update(bo *Group) (bool, error) {
+	return dao.version.bumpIf(dao.GroupDao.Update(bo))
+}
+
+// GetByIds implements GroupBatchGetter.GetByIds
+func (dao *versionedGroupDao) GetByIds(ids []string) (map[string]*Group, error) {
+	return getGroupsByIds(dao.GroupDao, ids)
+}
+
+// GetNAfter implements GroupKeysetPager.GetNAfter
+func (dao *versionedGroupDao) GetNAfter(afterId string, maxNumRows int) ([]*Group, error) {
+	return getNGroupsAfter(dao.GroupDao, afterId, maxNumRows)
+}
+
+// versionedUserDao is a UserDao that maintains the version of the user table.
+type versionedUserDao struct {
+	UserDao
+	version tableVersion
+}
+
+// Delete implements UserDao.Delete
+func (dao *versionedUserDao) Delete(bo *User) (bool, error) {
+	return dao.version.bumpIf(dao.UserDao.Delete(bo))
+}
+
+// Create implements UserDao.Create
+func (dao *versionedUserDao) Create(username, encryptedPassword, name, groupId string) (bool, error) {
+	return dao.version.bumpIf(dao.UserDao.Create(username, encryptedPassword, name, groupId))
+}
+
+// Update implements UserDao.Update
+func (dao *versionedUserDao) Update(bo *User) (bool, error) {
+	return dao.version.bumpIf(dao.UserDao.Update(bo))
+}
+
+// CountByGroup implements UserCounter.CountByGroup
+func (dao *versionedUserDao) CountByGroup() (map[string]int, error) {
+	return countUsersByGroup(dao.UserDao)
+}
+
+// GetNByGroup implements UserGroupPager.GetNByGroup
+func (dao *versionedUserDao) GetNByGroup(groupId string, fromOffset, maxNumRows int) ([]*User, error) {
+	return getNUsersByGroup(dao.UserDao, groupId, fromOffset, maxNumRows)
+}
+
+// GetNAfter implements UserKeysetPager.GetNAfter
+func (dao *versionedUserDao) GetNAfter(afterUsername string, maxNumRows int) ([]*User, error) {
+	return getNUsersAfter(dao.UserDao, afterUsername, maxNumRows)
+}
+
+// versionedMessageDao is a MessageDao that maintains the version of the message table.
+type versionedMessageDao struct {
+	MessageDao
+	version tableVersion
+}
+
+// Delete implements MessageDao.Delete
+func (dao *versionedMessageDao) Delete(bo *Message) (bool, error) {
+	return dao.version.bumpIf(dao.MessageDao.Delete(bo))
+}
+
+// Save implements MessageDao.Save
+func (dao *versionedMessageDao) Save(bo *Message) (bool, error) {
+	return dao.version.bumpIf(dao.MessageDao.Save(bo))
+}
+
+// versionedSettingDao is a SettingDao that maintains the version of the setting table.
+type versionedSettingDao struct {
+	SettingDao
+	version tableVersion
+}
+
+// Delete implements SettingDao.Delete
+func (dao *versionedSettingDao) Delete(bo *Setting) (bool, error) {
+	return dao.version.bumpIf(dao.SettingDao.Delete(bo))
+}
+
+// Save implements SettingDao.Save
+func (dao *versionedSettingDao) Save(bo *Setting) (bool, error) {
+	return dao.version.bumpIf(dao.SettingDao.Save(bo))
+}
+
+// GetByPrefix implements SettingPrefixGetter.GetByPrefix
+func (dao *versionedSettingDao) GetByPrefix(prefix string) ([]*Setting, error) {
+	return getSettingsByPrefix(dao.SettingDao, prefix)
+}
+
+// wrapVersionedDaos wraps the registry's DAOs so that table versions are maintained.
+func wrapVersionedDaos(r *myRegistry) {
+	r.groupDao = &versionedGroupDao{GroupDao: r.groupDao}
+	r.userDao = &versionedUserDao{UserDao: r.userDao}
+	r.messageDao = &versionedMessageDao{MessageDao: r.messageDao}
+	r.settingDao = &versionedSettingDao{SettingDao: r.settingDao}
+}
+
+// dataVersion returns the combined versions of all tables, pages of the control panel depend on all of them (e.g.
+// the layout renders the number of users/groups, translation overrides and notifications).
+func (r *myRegistry) dataVersion() string {
+	var versions [4]uint64
+	if dao, ok := r.groupDao.(*versionedGroupDao); ok {
+		versions[0] = dao.version.get()
+	}
+	if dao, ok := r.userDao.(*versionedUserDao); ok {
+		versions[1] = dao.version.get()
+	}
+	if dao, ok := r.messageDao.(*versionedMessageDao); ok {
+		versions[2] = dao.version.get()
+	}
+	if dao, ok := r.settingDao.(*versionedSettingDao); ok {
+		versions[3] = dao.version.get()
+	}
+	return fmt.Sprintf("%d.%d.%d.%d", versions[0], versions[1], versions[2], versions[3])
+}
+
+/*----------------------------------------------------------------------*/
+
+// sessionFlashesKey is the session key gorilla/sessions stores flash messages under.
+const sessionFlashesKey = "_flash"
+
+// pageETag computes the ETag of a data-driven page for the current request, empty string if the page must be
+// rendered anyway (conditional rendering is disabled or flash messages are pending).
+//
+// Besides table versions, the ETag covers everything else the rendered page depends on: the requested URL, the
+// current user, locale and CSRF token, users currently online as well as the instance the page was rendered by.
+// Pages carry signed links (see signUrl) that expire: the ETag also changes every half validity window of signed URLs
+// (setting goadmin.signed_url_ttl), so that a page revalidated with 304 never holds links expiring in less than that.
+func (r *myRegistry) pageETag(c echo.Context) string {
+	if !r.conditionalRendering {
+		return ""
+	}
+	if flashes, _ := getSession(c).Values[sessionFlashesKey].([]interface{}); len(flashes) > 0 {
+		return ""
+	}
+	username := ""
+	if currentUser, _ := c.Get(ctxCurrentUser).(*User); currentUser != nil {
+		username = currentUser.Username
+	}
+	csrf, _ := c.Get(goadmin.CtxCsrfToken).(string)
+	parts := []string{r.instanceId, r.dataVersion(), c.Request().URL.RequestURI(), username,
+		getContextString(c, ctxLocale), csrf, signedUrlEpoch(r.UrlSigner)}
+	if r.presence != nil {
+		// user lists flag users currently online
+		parts = append(parts, r.presence.etagPart(c))
+	}
+	sum := sha1.Sum([]byte(strings.Join(parts, "\n")))
+	return `W/"` + hex.EncodeToString(sum[:12]) + `"`
+}
+
+// signedUrlEpoch returns the number of half validity windows of signed URLs elapsed so far, empty string if URLs are
+// not signed.
+func signedUrlEpoch(signer *goadmin.UrlSigner) string {
+	if signer == nil {
+		return ""
+	}
+	window := signer.Ttl() / 2
+	if window <= 0 {
+		return ""
+	}
+	return strconv.FormatInt(utils.Now().UnixNano()/int64(window), 10)
+}
+
+// etagMatches checks if the header If-None-Match contains the ETag.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// renderConditional renders a data-driven page, or responds with 304 if the client's copy (header If-None-Match) is
+// still up-to-date. dataFunc is invoked only when the page is actually rendered; the page is streamed to the client,
+// see renderStream.
+//
+// available since template-r5
+func renderConditional(c echo.Context, name string, dataFunc func() map[string]interface{}) error {
+	if etag := getRegistry(c).pageETag(c); etag != "" {
+		header := c.Response().Header()
+		header.Set(echo.HeaderCacheControl, "private, no-cache")
+		header.Set("ETag", etag)
+		if etagMatches(c.Request().Header.Get("If-None-Match"), etag) {
+			return c.NoContent(http.StatusNotModified)
+		}
+	}
+	return renderStream(c, http.StatusOK, name, dataFunc())
+}
+
+// newInstanceId generates a random id of the running instance, so that ETags issued before a restart (when table
+// versions are reset) do not match.
+func newInstanceId() string {
+	return utils.UniqueIdSmall()
+}
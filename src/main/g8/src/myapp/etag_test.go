@@ -0,0 +1,49 @@
+package myapp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"main/src/utils"
+)
+
+func TestActionCpGroupList_ConditionalRendering(t *testing.T) {
+	h := _newHarness(t)
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	conditionalGet := func(etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, h.Reverse(actionNameCpGroups), nil)
+		req.Header.Set("If-None-Match", etag)
+		return h.Do(req)
+	}
+	resp := h.Get(h.Reverse(actionNameCpGroups))
+	h.AssertStatus(resp, http.StatusOK)
+	etag := resp.Header().Get("ETag")
+	if etag == "" {
+		t.Fatalf("TestActionCpGroupList_ConditionalRendering failed: no ETag")
+	}
+	h.AssertStatus(conditionalGet(etag), http.StatusNotModified)
+
+	h.PostForm(h.Reverse(actionNameCpCreateGroupSubmit), url.Values{"id": {"testers"}, "name": {"Testers"}})
+	resp = conditionalGet(etag)
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertBodyContains(resp, "Testers")
+	resp = conditionalGet(etag)
+	h.AssertStatus(resp, http.StatusOK)
+	if newEtag := resp.Header().Get("ETag"); newEtag == "" || newEtag == etag {
+		t.Fatalf("TestActionCpGroupList_ConditionalRendering failed: expected new ETag but received [%s]", newEtag)
+	}
+
+	// pages carry signed links: ETags change before the links expire
+	ttl := h.Registry.UrlSigner.Ttl()
+	now := utils.Now().Truncate(ttl)
+	utils.FreezeClock(now)
+	defer utils.FreezeClock(time.Time{})
+	etag = h.Get(h.Reverse(actionNameCpGroups)).Header().Get("ETag")
+	utils.FreezeClock(now.Add(ttl/2 - time.Second))
+	h.AssertStatus(conditionalGet(etag), http.StatusNotModified)
+	utils.FreezeClock(now.Add(ttl / 2))
+	h.AssertStatus(conditionalGet(etag), http.StatusOK)
+}
@@ -0,0 +1,488 @@
+package myapp
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"main/src/goadmin"
+	"main/src/utils"
+)
+
+// Names of events emitted by myapp.
+const (
+	eventUserCreated = "user.created"
+	eventUserUpdated = "user.updated"
+	eventUserDeleted = "user.deleted"
+	eventUserLogin   = "user.login"
+
+	eventGroupCreated = "group.created"
+	eventGroupUpdated = "group.updated"
+	eventGroupDeleted = "group.deleted"
+)
+
+// settingPrefixOutbox is the prefix of settings storing outbox messages (see settingOutboxStore).
+const settingPrefixOutbox = "outbox:"
+
+// Event is a change made in the application, delivered as JSON to subscribed webhooks (setting myapp.webhooks) and
+// mirrored to the message bus (setting goadmin.event_bus) on topic <topic_prefix><event name>.
+//
+// available since template-r5
+type Event struct {
+	Id   string                 `json:"id"`
+	Name string                 `json:"event"` // e.g. "user.created"
+	Time time.Time              `json:"time"`
+	Data map[string]interface{} `json:"data"`
+}
+
+// webhookSubscription is a receiver of events, configured at myapp.webhooks.<name>.
+type webhookSubscription struct {
+	name   string
+	url    string
+	events []string // names of subscribed events, empty or "*" means all events
+}
+
+func (s *webhookSubscription) subscribes(event string) bool {
+	if len(s.events) == 0 {
+		return true
+	}
+	for _, e := range s.events {
+		if e == "*" || e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// initWebhooks reads webhook subscriptions from setting myapp.webhooks, a map of names to {url, events}.
+func (r *myRegistry) initWebhooks() {
+	r.webhooks = make([]*webhookSubscription, 0)
+	confPath := namespace + ".webhooks"
+	if v := r.AppConfig.GetValue(confPath); v != nil && v.IsObject() {
+		for name := range v.GetObject().Items() {
+			sub := &webhookSubscription{
+				name:   name,
+				url:    r.AppConfig.GetString(confPath+"."+name+".url", ""),
+				events: r.AppConfig.GetStringList(confPath + "." + name + ".events"),
+			}
+			if sub.url == "" {
+				log.Printf("[WARN] webhook [%s] has no url, ignored", name)
+				continue
+			}
+			r.webhooks = append(r.webhooks, sub)
+		}
+	}
+	sort.Slice(r.webhooks, func(i, j int) bool { return r.webhooks[i].name < r.webhooks[j].name })
+}
+
+// onEvent registers a listener of the named events within the running instance (e.g. to refresh in-memory
+// snapshots), listeners are called synchronously by emitEvent. Listeners must be registered at bootstrap, before
+// events are emitted.
+func (r *myRegistry) onEvent(listener func(event *Event), names ...string) {
+	if r.eventListeners == nil {
+		r.eventListeners = make(map[string][]func(event *Event))
+	}
+	for _, name := range names {
+		r.eventListeners[name] = append(r.eventListeners[name], listener)
+	}
+}
+
+// emitEvent notifies listeners of the running instance (see onEvent), publishes the event to the message bus and
+// enqueues it in the outbox, once per subscribed webhook. Failures are logged only: the triggering change has been
+// made already and must not be reported as failed.
+func (r *myRegistry) emitEvent(name string, data map[string]interface{}) {
+	event, msgs := r.newEvent(name, data)
+	r.publishEvent(event)
+	r.enqueueEvent(event, msgs)
+}
+
+// newEvent creates an event and the outbox messages delivering it to subscribed webhooks, which are not stored yet.
+// Events of the initial data (see _initData) are not delivered to webhooks.
+func (r *myRegistry) newEvent(name string, data map[string]interface{}) (*Event, []*goadmin.OutboxMessage) {
+	event := &Event{Id: utils.UniqueId(), Name: name, Time: utils.Now(), Data: data}
+	msgs := make([]*goadmin.OutboxMessage, 0)
+	if r.seeding {
+		return event, msgs
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("[ERROR] cannot encode event [%s]: %s", name, err)
+		return event, msgs
+	}
+	for _, sub := range r.webhooks {
+		if !sub.subscribes(name) {
+			continue
+		}
+		msg, err := r.Outbox.NewMessage(goadmin.OutboxChannelWebhook, &goadmin.WebhookPayload{Id: event.Id, Url: sub.url, Body: body})
+		if err != nil {
+			log.Printf("[ERROR] cannot enqueue event [%s] for webhook [%s]: %s", name, sub.name, err)
+			continue
+		}
+		msgs = append(msgs, msg)
+	}
+	return event, msgs
+}
+
+// publishEvent notifies listeners of the running instance and publishes the event to the message bus. Events of the
+// initial data (see _initData) are not published.
+func (r *myRegistry) publishEvent(event *Event) {
+	for _, listener := range r.eventListeners[event.Name] {
+		listener(event)
+	}
+	if r.seeding {
+		return
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("[ERROR] cannot encode event [%s]: %s", event.Name, err)
+		return
+	}
+	// events are keyed by username, so that events of a user keep their order within a Kafka partition
+	username, _ := event.Data["username"].(string)
+	r.PublishEvent(event.Name, username, body)
+}
+
+// enqueueEvent stores outbox messages of an event, failures are logged only.
+func (r *myRegistry) enqueueEvent(event *Event, msgs []*goadmin.OutboxMessage) {
+	for _, msg := range msgs {
+		if err := r.Outbox.Store.Put(msg); err != nil {
+			log.Printf("[ERROR] cannot enqueue event [%s] (outbox message [%s]): %s", event.Name, msg.Id, err)
+		}
+	}
+}
+
+// changeWithEvent makes a change with the DAO and emits its event if it succeeds. If the DAO stores outbox messages in
+// the transaction of its changes (see txOutboxWriter), messages of the event are written along with the change: both
+// are made or none, even if the process crashes. Otherwise they are enqueued right after the change, and are lost if
+// the process crashes in between.
+func (r *myRegistry) changeWithEvent(dao interface{}, name string, data map[string]interface{}, change func(dao interface{}) (bool, error)) (bool, error) {
+	event, msgs := r.newEvent(name, data)
+	if w, isWriter := dao.(txOutboxWriter); isWriter && len(msgs) > 0 {
+		if bound := w.withOutbox(msgs); bound != nil {
+			ok, err := change(bound)
+			if ok && err == nil {
+				r.publishEvent(event)
+			}
+			return ok, err
+		}
+	}
+	ok, err := change(dao)
+	if ok && err == nil {
+		r.publishEvent(event)
+		r.enqueueEvent(event, msgs)
+	}
+	return ok, err
+}
+
+// txOutboxWriter is implemented by DAOs able to store outbox messages in the database transaction of their changes
+// (SQL DAOs whose database also holds settings, see writeSqlWithOutbox). Wrapping DAOs implement it by binding the DAO
+// they wrap.
+type txOutboxWriter interface {
+	// withOutbox returns a copy of the DAO whose changes also store msgs, nil if the DAO cannot store them.
+	withOutbox(msgs []*goadmin.OutboxMessage) interface{}
+}
+
+// boundUserDao returns a UserDao storing msgs in the transaction of its changes, nil if dao cannot.
+func boundUserDao(dao UserDao, msgs []*goadmin.OutboxMessage) UserDao {
+	if w, ok := dao.(txOutboxWriter); ok {
+		if bound, ok := w.withOutbox(msgs).(UserDao); ok {
+			return bound
+		}
+	}
+	return nil
+}
+
+// boundGroupDao returns a GroupDao storing msgs in the transaction of its changes, nil if dao cannot.
+func boundGroupDao(dao GroupDao, msgs []*goadmin.OutboxMessage) GroupDao {
+	if w, ok := dao.(txOutboxWriter); ok {
+		if bound, ok := w.withOutbox(msgs).(GroupDao); ok {
+			return bound
+		}
+	}
+	return nil
+}
+
+// userEventData returns the data of user events, never including the password.
+func userEventData(bo *User) map[string]interface{} {
+	return map[string]interface{}{"username": bo.Username, "name": bo.Name, "group_id": bo.GroupId}
+}
+
+/*----------------------------------------------------------------------*/
+
+// eventUserDao is a UserDao that emits user events on successful changes, whatever the handler making them (control
+// panel, configuration bundle import...).
+type eventUserDao struct {
+	UserDao
+	r *myRegistry
+}
+
+// Delete implements UserDao.Delete
+func (dao *eventUserDao) Delete(bo *User) (bool, error) {
+	return dao.r.changeWithEvent(dao.UserDao, eventUserDeleted, userEventData(bo), func(d interface{}) (bool, error) {
+		return d.(UserDao).Delete(bo)
+	})
+}
+
+// Create implements UserDao.Create
+func (dao *eventUserDao) Create(username, encryptedPassword, name, groupId string) (bool, error) {
+	data := userEventData(&User{Username: username, Name: name, GroupId: groupId})
+	return dao.r.changeWithEvent(dao.UserDao, eventUserCreated, data, func(d interface{}) (bool, error) {
+		return d.(UserDao).Create(username, encryptedPassword, name, groupId)
+	})
+}
+
+// Update implements UserDao.Update
+func (dao *eventUserDao) Update(bo *User) (bool, error) {
+	return dao.r.changeWithEvent(dao.UserDao, eventUserUpdated, userEventData(bo), func(d interface{}) (bool, error) {
+		return d.(UserDao).Update(bo)
+	})
+}
+
+// CountByGroup implements UserCounter.CountByGroup
+func (dao *eventUserDao) CountByGroup() (map[string]int, error) {
+	return countUsersByGroup(dao.UserDao)
+}
+
+// GetNByGroup implements UserGroupPager.GetNByGroup
+func (dao *eventUserDao) GetNByGroup(groupId string, fromOffset, maxNumRows int) ([]*User, error) {
+	return getNUsersByGroup(dao.UserDao, groupId, fromOffset, maxNumRows)
+}
+
+// GetNAfter implements UserKeysetPager.GetNAfter
+func (dao *eventUserDao) GetNAfter(afterUsername string, maxNumRows int) ([]*User, error) {
+	return getNUsersAfter(dao.UserDao, afterUsername, maxNumRows)
+}
+
+// groupEventData returns the data of group events.
+func groupEventData(bo *Group) map[string]interface{} {
+	return map[string]interface{}{"group_id": bo.Id, "name": bo.Name}
+}
+
+// eventGroupDao is a GroupDao that emits group events on successful changes.
+type eventGroupDao struct {
+	GroupDao
+	r *myRegistry
+}
+
+// Delete implements GroupDao.Delete
+func (dao *eventGroupDao) Delete(bo *Group) (bool, error) {
+	return dao.r.changeWithEvent(dao.GroupDao, eventGroupDeleted, groupEventData(bo), func(d interface{}) (bool, error) {
+		return d.(GroupDao).Delete(bo)
+	})
+}
+
+// Create implements GroupDao.Create
+func (dao *eventGroupDao) Create(id, name string) (bool, error) {
+	return dao.r.changeWithEvent(dao.GroupDao, eventGroupCreated, groupEventData(&Group{Id: id, Name: name}), func(d interface{}) (bool, error) {
+		return d.(GroupDao).Create(id, name)
+	})
+}
+
+// Update implements GroupDao.Update
+func (dao *eventGroupDao) Update(bo *Group) (bool, error) {
+	return dao.r.changeWithEvent(dao.GroupDao, eventGroupUpdated, groupEventData(bo), func(d interface{}) (bool, error) {
+		return d.(GroupDao).Update(bo)
+	})
+}
+
+// GetByIds implements GroupBatchGetter.GetByIds
+func (dao *eventGroupDao) GetByIds(ids []string) (map[string]*Group, error) {
+	return getGroupsByIds(dao.GroupDao, ids)
+}
+
+// GetNAfter implements GroupKeysetPager.GetNAfter
+func (dao *eventGroupDao) GetNAfter(afterId string, maxNumRows int) ([]*Group, error) {
+	return getNGroupsAfter(dao.GroupDao, afterId, maxNumRows)
+}
+
+/*----------------------------------------------------------------------*/
+
+// settingOutboxStore is a goadmin.OutboxStore keeping messages as settings, so that they are persisted in the
+// application's database whatever its type.
+//
+// With SQL databases, messages of user and group events are written in the transaction of the triggering change (see
+// changeWithEvent): they are delivered at least once, even across crashes. Other databases have no transaction
+// spanning the change and the message: messages are written right after the change, a crash in between loses them.
+type settingOutboxStore struct {
+	dao SettingDao
+}
+
+// getSettingsByPrefix returns settings whose id starts with prefix, sorted by id: looked up natively if dao
+// implements SettingPrefixGetter, filtered from all settings otherwise.
+//
+// available since template-r5
+func getSettingsByPrefix(dao SettingDao, prefix string) ([]*Setting, error) {
+	if getter, ok := dao.(SettingPrefixGetter); ok {
+		return getter.GetByPrefix(prefix)
+	}
+	all, err := dao.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*Setting, 0)
+	for _, setting := range all {
+		if strings.HasPrefix(setting.Id, prefix) {
+			result = append(result, setting)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Id < result[j].Id })
+	return result, nil
+}
+
+// outboxSetting returns the setting storing an outbox message.
+func outboxSetting(msg *goadmin.OutboxMessage) (*Setting, error) {
+	js, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	return &Setting{Id: settingPrefixOutbox + msg.Id, Value: string(js)}, nil
+}
+
+// Put implements goadmin.OutboxStore.Put
+func (s *settingOutboxStore) Put(msg *goadmin.OutboxMessage) error {
+	setting, err := outboxSetting(msg)
+	if err != nil {
+		return err
+	}
+	_, err = s.dao.Save(setting)
+	return err
+}
+
+// Due implements goadmin.OutboxStore.Due
+func (s *settingOutboxStore) Due(now time.Time, limit int) ([]*goadmin.OutboxMessage, error) {
+	all, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*goadmin.OutboxMessage, 0)
+	for _, msg := range all {
+		if !msg.IsDead() && !msg.NextAttempt.After(now) {
+			result = append(result, msg)
+		}
+	}
+	return goadmin.SortOutboxMessages(result, limit), nil
+}
+
+// List implements goadmin.OutboxStore.List
+func (s *settingOutboxStore) List() ([]*goadmin.OutboxMessage, error) {
+	// the dispatcher lists messages on every tick, other settings are not read if the DAO can skip them
+	all, err := getSettingsByPrefix(s.dao, settingPrefixOutbox)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*goadmin.OutboxMessage, 0)
+	for _, setting := range all {
+		msg := &goadmin.OutboxMessage{}
+		if err := json.Unmarshal([]byte(setting.Value), msg); err != nil {
+			log.Printf("[ERROR] invalid outbox message [%s]: %s", setting.Id, err)
+			continue
+		}
+		result = append(result, msg)
+	}
+	return goadmin.SortOutboxMessages(result, 0), nil
+}
+
+// Get implements goadmin.OutboxStore.Get
+func (s *settingOutboxStore) Get(id string) (*goadmin.OutboxMessage, error) {
+	setting, err := s.dao.Get(settingPrefixOutbox + id)
+	if err != nil || setting == nil {
+		return nil, err
+	}
+	msg := &goadmin.OutboxMessage{}
+	return msg, json.Unmarshal([]byte(setting.Value), msg)
+}
+
+// Delete implements goadmin.OutboxStore.Delete
+func (s *settingOutboxStore) Delete(id string) error {
+	_, err := s.dao.Delete(&Setting{Id: settingPrefixOutbox + id})
+	return err
+}
+
+/*----------------------------------------------------------------------*/
+
+// Names of inbound webhook handlers registered by myapp, receivers refer to them at goadmin.webhook.receivers.
+const (
+	webhookHandlerAssignGroup = namespace + ".assign_group"
+	webhookHandlerNotify      = namespace + ".notify"
+)
+
+// registerWebhookHandlers registers handlers of inbound webhooks, so that external systems can trigger admin actions.
+func (r *myRegistry) registerWebhookHandlers() {
+	r.Webhooks.RegisterHandler(webhookHandlerAssignGroup, &goadmin.WebhookHandler{
+		Schema: []goadmin.WebhookField{
+			{Name: "username", Type: goadmin.WebhookFieldString, Required: true},
+			{Name: "group_id", Type: goadmin.WebhookFieldString, Required: true},
+		},
+		Handle: r.webhookAssignGroup,
+	})
+	r.Webhooks.RegisterHandler(webhookHandlerNotify, &goadmin.WebhookHandler{
+		Schema: []goadmin.WebhookField{
+			{Name: "username", Type: goadmin.WebhookFieldString, Required: true},
+			{Name: "message", Type: goadmin.WebhookFieldString, Required: true},
+		},
+		Handle: r.webhookNotify,
+	})
+}
+
+// webhookAssignGroup moves a user to another group, payload: {"username": ..., "group_id": ...}.
+func (r *myRegistry) webhookAssignGroup(c echo.Context, payload map[string]interface{}) error {
+	if r.isReadOnly() {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "read-only mode")
+	}
+	username := strings.TrimSpace(payload["username"].(string))
+	groupId := strings.ToLower(strings.TrimSpace(payload["group_id"].(string)))
+	user, err := r.userDao.Get(username)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("user [%s] not found", username))
+	}
+	if r.demoMode && user.Username == systemUserUsername {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "group of the system admin user can not be changed")
+	}
+	group, err := r.groupDao.Get(groupId)
+	if err != nil {
+		return err
+	}
+	if group == nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("group [%s] not found", groupId))
+	}
+	user.GroupId = group.Id
+	if _, err := r.userDao.Update(user); err != nil {
+		return err
+	}
+	r.auditf("webhook [%s] from %s: assigned user [%s] to group [%s]", c.Param("name"), clientOrigin(c), user.Username, group.Id)
+	return nil
+}
+
+// webhookNotify adds a notification to a user's list, payload: {"username": ..., "message": ...}.
+func (r *myRegistry) webhookNotify(c echo.Context, payload map[string]interface{}) error {
+	username := strings.TrimSpace(payload["username"].(string))
+	message := strings.TrimSpace(payload["message"].(string))
+	if message == "" {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "field [message] must not be empty")
+	}
+	user, err := r.userDao.Get(username)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("user [%s] not found", username))
+	}
+	n := &Notification{
+		Key:    "notification_webhook",
+		Params: map[string]interface{}{"source": c.Param("name"), "message": message},
+		Time:   utils.Now(),
+	}
+	if err := r.notify(user.Username, n); err != nil {
+		return err
+	}
+	r.auditf("webhook [%s] from %s: notified user [%s]", c.Param("name"), clientOrigin(c), user.Username)
+	return nil
+}
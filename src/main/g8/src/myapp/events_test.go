@@ -0,0 +1,154 @@
+package myapp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"main/src/apptest"
+	"main/src/goadmin"
+)
+
+func TestOutboxWebhooks(t *testing.T) {
+	testName := "TestOutboxWebhooks"
+	received := make([]*Event, 0)
+	fail := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get("X-Webhook-Signature") == "" {
+			t.Errorf("%s failed: webhook request is not signed", testName)
+		}
+		event := &Event{}
+		json.NewDecoder(r.Body).Decode(event)
+		received = append(received, event)
+	}))
+	defer srv.Close()
+	conf := apptest.SqliteInMemoryConfig + "\ngoadmin.webhook.secret = \"s3cr3t\"\nmyapp.webhooks { test { url = \"" + srv.URL + "\", events = [\"" + eventUserCreated + "\"] } }\n"
+	h := apptest.New(t, conf, NewBootstrapper(nil, nil))
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	myReg.Outbox.Backoff = 0
+	myReg.userDao.Create("webhook@local", "", "Webhook", systemGroupId)
+	myReg.userDao.Delete(&User{Username: "webhook@local"})
+
+	if n := myReg.Outbox.Dispatch(); n != 0 {
+		t.Fatalf("%s failed: expected failed delivery but %d message(s) delivered", testName, n)
+	}
+	if msgs, _ := myReg.Outbox.Store.Due(time.Now(), 0); len(msgs) != 1 || msgs[0].Attempts != 1 {
+		t.Fatalf("%s failed: expected 1 message to retry but received %#v", testName, msgs)
+	}
+	fail = false
+	if n := myReg.Outbox.Dispatch(); n != 1 || len(received) != 1 || received[0].Name != eventUserCreated || received[0].Data["username"] != "webhook@local" {
+		t.Fatalf("%s failed: unexpected delivery (%d) %#v", testName, n, received)
+	}
+	if msgs, _ := myReg.Outbox.Store.Due(time.Now(), 0); len(msgs) != 0 {
+		t.Fatalf("%s failed: delivered message must be removed from outbox", testName)
+	}
+
+	// with SQL databases, messages are written in the transaction of the change: both are made or none
+	backend := myReg.userDao.(*versionedUserDao).UserDao.(*eventUserDao).UserDao
+	msg, _ := myReg.Outbox.NewMessage(goadmin.OutboxChannelWebhook, &goadmin.WebhookPayload{Id: "1", Url: srv.URL})
+	bound := boundUserDao(backend, []*goadmin.OutboxMessage{msg})
+	if bound == nil {
+		t.Fatalf("%s failed: SQL user DAO must store outbox messages in the transaction of changes", testName)
+	}
+	if ok, err := bound.Create("tx@local", "", "Tx", systemGroupId); !ok || err != nil {
+		t.Fatalf("%s failed: %v/%s", testName, ok, err)
+	}
+	if stored, _ := myReg.Outbox.Store.Get(msg.Id); stored == nil {
+		t.Fatalf("%s failed: outbox message must be stored along with the change", testName)
+	}
+	myReg.Outbox.Store.Delete(msg.Id)
+	if ok, _ := bound.Create("tx@local", "", "Tx", systemGroupId); ok {
+		t.Fatalf("%s failed: duplicated user must not be created", testName)
+	}
+	if stored, _ := myReg.Outbox.Store.Get(msg.Id); stored != nil {
+		t.Fatalf("%s failed: outbox message must not be stored if the change fails", testName)
+	}
+
+	// outbox messages are looked up by prefix rather than among all settings
+	myReg.settingDao.Save(&Setting{Id: "outbox;other", Value: "{}"})
+	if settings, err := getSettingsByPrefix(myReg.settingDao, settingPrefixOutbox); err != nil || len(settings) != 0 {
+		t.Fatalf("%s failed: expected no outbox setting but received %#v/%s", testName, settings, err)
+	}
+}
+
+func TestEventBusKafkaRest(t *testing.T) {
+	testName := "TestEventBusKafkaRest"
+	published := make(chan string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		published <- r.URL.Path + " " + string(body)
+		w.Write([]byte(`{"offsets":[{"partition":0,"offset":1}]}`))
+	}))
+	defer srv.Close()
+	conf := apptest.SqliteInMemoryConfig + "\ngoadmin.event_bus { driver = \"kafka_rest\", topic_prefix = \"admin.\", kafka_rest.url = \"" + srv.URL + "\" }\n"
+	h := apptest.New(t, conf, NewBootstrapper(nil, nil))
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	select {
+	case msg := <-published:
+		if !strings.HasPrefix(msg, "/topics/admin."+eventUserLogin+" ") || !strings.Contains(msg, `"key":"`+testAdminUsername+`"`) {
+			t.Fatalf("%s failed: unexpected record %s", testName, msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%s failed: event was not published", testName)
+	}
+}
+
+func TestWebhookReceivers(t *testing.T) {
+	testName := "TestWebhookReceivers"
+	conf := apptest.SqliteInMemoryConfig + `
+goadmin.webhook.receivers.crm { handler = "myapp.assign_group", secret = "s3cr3t" }
+goadmin.webhook.receivers.monitor { handler = "myapp.notify", secret = "m0n1t0r", signature_header = "X-Monitor-Signature" }
+`
+	h := apptest.New(t, conf, NewBootstrapper(nil, nil))
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	myReg.groupDao.Create("ops", "Operators")
+	myReg.userDao.Create("jdoe", encryptPassword("jdoe", "secret"), "John Doe", systemGroupId)
+	send := func(receiver, header, secret, id string, payload string) *httptest.ResponseRecorder {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(payload))
+		req := httptest.NewRequest(http.MethodPost, h.Reverse(goadmin.ActionNameWebhookReceive, receiver), strings.NewReader(payload))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(header, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+		if id != "" {
+			req.Header.Set("X-Webhook-Id", id)
+		}
+		return h.Do(req)
+	}
+
+	// unknown receivers, invalid signatures and invalid payloads are rejected
+	h.AssertStatus(send("unknown", "X-Webhook-Signature", "s3cr3t", "", `{}`), http.StatusNotFound)
+	h.AssertStatus(send("crm", "X-Webhook-Signature", "wrong", "", `{"username":"jdoe","group_id":"ops"}`), http.StatusUnauthorized)
+	h.AssertStatus(send("crm", "X-Webhook-Signature", "s3cr3t", "", `{"username":"jdoe"}`), http.StatusUnprocessableEntity)
+	h.AssertStatus(send("crm", "X-Webhook-Signature", "s3cr3t", "", `{"username":"jdoe","group_id":1}`), http.StatusUnprocessableEntity)
+	h.AssertStatus(send("crm", "X-Webhook-Signature", "s3cr3t", "", `{"username":"jdoe","group_id":"nope"}`), http.StatusUnprocessableEntity)
+
+	// valid payloads are dispatched to the receiver's handler, once per delivery
+	h.AssertStatus(send("crm", "X-Webhook-Signature", "s3cr3t", "d1", `{"username":"jdoe","group_id":"ops"}`), http.StatusNoContent)
+	if user, _ := myReg.userDao.Get("jdoe"); user == nil || user.GroupId != "ops" {
+		t.Fatalf("%s failed: user was not assigned to group [ops]: %#v", testName, user)
+	}
+	h.AssertStatus(send("monitor", "X-Monitor-Signature", "m0n1t0r", "d2", `{"username":"jdoe","message":"disk full"}`), http.StatusNoContent)
+	h.AssertStatus(send("monitor", "X-Monitor-Signature", "m0n1t0r", "d2", `{"username":"jdoe","message":"disk full"}`), http.StatusNoContent)
+	list := make([]*Notification, 0)
+	myReg.loadSetting(settingPrefixNotifications+"jdoe", &list)
+	if len(list) != 1 || list[0].Key != "notification_webhook" || list[0].Params["message"] != "disk full" {
+		t.Fatalf("%s failed: unexpected notifications %#v", testName, list)
+	}
+
+	// JSON numbers are accepted whatever their form
+	if err := goadmin.ValidateWebhookPayload([]goadmin.WebhookField{{Name: "n", Type: goadmin.WebhookFieldNumber, Required: true}}, map[string]interface{}{"n": 1.5}); err != nil {
+		t.Fatalf("%s failed: %s", testName, err)
+	}
+}
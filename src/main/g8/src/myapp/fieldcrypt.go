@@ -0,0 +1,376 @@
+package myapp
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	hocon "github.com/go-akka/configuration"
+	"main/src/goadmin"
+)
+
+// Sensitive fields are encrypted at rest if setting goadmin.field_encryption.current_key is set: users' display names
+// and admins' notes on user accounts are encrypted by encryptedUserDao, values of custom fields of groups (e.g.
+// contact emails, cost centers) by encryptedGroupDao. Both are the innermost wrappers of the DAOs, so that every
+// backend stores ciphertext while caches, events and handlers see plaintext. Usernames (the users' email addresses),
+// tags and group ids are not encrypted: they are looked up and sorted by value, which encrypted values (random nonces)
+// do not allow.
+//
+// Values stored before encryption was enabled are still read; command "reencrypt" (see ReencryptFields) encrypts
+// them, as well as values encrypted with previous keys after a key rotation. Encrypted names are longer than 64
+// characters: name columns of MySQL/PostgreSQL user tables created before are widened by schema migrations.
+
+// encryptedUserDao is a UserDao that encrypts users' names and notes before they are stored and decrypts them when
+// read.
+type encryptedUserDao struct {
+	UserDao
+	fc *goadmin.FieldCipher
+}
+
+func (dao *encryptedUserDao) encrypt(bo *User) (*User, error) {
+	name, err := dao.fc.Encrypt(bo.Name)
+	if err != nil {
+		return nil, err
+	}
+	notes, err := dao.fc.Encrypt(bo.Notes)
+	if err != nil {
+		return nil, err
+	}
+	// the caller's copy is left untouched
+	clone := *bo
+	clone.Name, clone.Notes = name, notes
+	return &clone, nil
+}
+
+func (dao *encryptedUserDao) decrypt(bo *User) (*User, error) {
+	if bo == nil {
+		return nil, nil
+	}
+	name, err := dao.fc.Decrypt(bo.Name)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt name of user [%s]: %w", bo.Username, err)
+	}
+	notes, err := dao.fc.Decrypt(bo.Notes)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt notes of user [%s]: %w", bo.Username, err)
+	}
+	bo.Name, bo.Notes = name, notes
+	return bo, nil
+}
+
+func (dao *encryptedUserDao) decryptAll(list []*User, err error) ([]*User, error) {
+	if err != nil {
+		return nil, err
+	}
+	for _, bo := range list {
+		if _, err := dao.decrypt(bo); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// Create implements UserDao.Create
+func (dao *encryptedUserDao) Create(username, encryptedPassword, name, groupId string) (bool, error) {
+	encName, err := dao.fc.Encrypt(name)
+	if err != nil {
+		return false, err
+	}
+	return dao.UserDao.Create(username, encryptedPassword, encName, groupId)
+}
+
+// Get implements UserDao.Get
+func (dao *encryptedUserDao) Get(username string) (*User, error) {
+	bo, err := dao.UserDao.Get(username)
+	if err != nil {
+		return nil, err
+	}
+	return dao.decrypt(bo)
+}
+
+// GetN implements UserDao.GetN
+func (dao *encryptedUserDao) GetN(fromOffset, maxNumRows int) ([]*User, error) {
+	return dao.decryptAll(dao.UserDao.GetN(fromOffset, maxNumRows))
+}
+
+// GetAll implements UserDao.GetAll
+func (dao *encryptedUserDao) GetAll() ([]*User, error) {
+	return dao.decryptAll(dao.UserDao.GetAll())
+}
+
+// Update implements UserDao.Update
+func (dao *encryptedUserDao) Update(bo *User) (bool, error) {
+	encBo, err := dao.encrypt(bo)
+	if err != nil {
+		return false, err
+	}
+	return dao.UserDao.Update(encBo)
+}
+
+// withOutbox implements txOutboxWriter.withOutbox
+func (dao *encryptedUserDao) withOutbox(msgs []*goadmin.OutboxMessage) interface{} {
+	if bound := boundUserDao(dao.UserDao, msgs); bound != nil {
+		return &encryptedUserDao{UserDao: bound, fc: dao.fc}
+	}
+	return nil
+}
+
+// CountByGroup implements UserCounter.CountByGroup
+func (dao *encryptedUserDao) CountByGroup() (map[string]int, error) {
+	return countUsersByGroup(dao.UserDao)
+}
+
+// GetNByGroup implements UserGroupPager.GetNByGroup
+func (dao *encryptedUserDao) GetNByGroup(groupId string, fromOffset, maxNumRows int) ([]*User, error) {
+	return dao.decryptAll(getNUsersByGroup(dao.UserDao, groupId, fromOffset, maxNumRows))
+}
+
+// GetNAfter implements UserKeysetPager.GetNAfter
+func (dao *encryptedUserDao) GetNAfter(afterUsername string, maxNumRows int) ([]*User, error) {
+	return dao.decryptAll(getNUsersAfter(dao.UserDao, afterUsername, maxNumRows))
+}
+
+/*----------------------------------------------------------------------*/
+
+// encryptedGroupDao is a GroupDao that encrypts values of groups' custom fields before they are stored and decrypts
+// them when read.
+type encryptedGroupDao struct {
+	GroupDao
+	fc *goadmin.FieldCipher
+}
+
+func (dao *encryptedGroupDao) encrypt(bo *Group) (*Group, error) {
+	// the caller's copy is left untouched
+	clone := *bo
+	if bo.Attrs != nil {
+		clone.Attrs = make(map[string]string, len(bo.Attrs))
+		for name, value := range bo.Attrs {
+			encValue, err := dao.fc.Encrypt(value)
+			if err != nil {
+				return nil, err
+			}
+			clone.Attrs[name] = encValue
+		}
+	}
+	return &clone, nil
+}
+
+func (dao *encryptedGroupDao) decrypt(bo *Group) (*Group, error) {
+	if bo == nil {
+		return nil, nil
+	}
+	if bo.Attrs == nil {
+		return bo, nil
+	}
+	// the map may be shared with the backend (e.g. in-memory DAO), decrypted values are put in a new one
+	attrs := make(map[string]string, len(bo.Attrs))
+	for name, value := range bo.Attrs {
+		plain, err := dao.fc.Decrypt(value)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decrypt field [%s] of group [%s]: %w", name, bo.Id, err)
+		}
+		attrs[name] = plain
+	}
+	bo.Attrs = attrs
+	return bo, nil
+}
+
+func (dao *encryptedGroupDao) decryptAll(list []*Group, err error) ([]*Group, error) {
+	if err != nil {
+		return nil, err
+	}
+	for _, bo := range list {
+		if _, err := dao.decrypt(bo); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// Get implements GroupDao.Get
+func (dao *encryptedGroupDao) Get(id string) (*Group, error) {
+	bo, err := dao.GroupDao.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	return dao.decrypt(bo)
+}
+
+// GetN implements GroupDao.GetN
+func (dao *encryptedGroupDao) GetN(fromOffset, maxNumRows int) ([]*Group, error) {
+	return dao.decryptAll(dao.GroupDao.GetN(fromOffset, maxNumRows))
+}
+
+// GetAll implements GroupDao.GetAll
+func (dao *encryptedGroupDao) GetAll() ([]*Group, error) {
+	return dao.decryptAll(dao.GroupDao.GetAll())
+}
+
+// Update implements GroupDao.Update
+func (dao *encryptedGroupDao) Update(bo *Group) (bool, error) {
+	encBo, err := dao.encrypt(bo)
+	if err != nil {
+		return false, err
+	}
+	return dao.GroupDao.Update(encBo)
+}
+
+// withOutbox implements txOutboxWriter.withOutbox
+func (dao *encryptedGroupDao) withOutbox(msgs []*goadmin.OutboxMessage) interface{} {
+	if bound := boundGroupDao(dao.GroupDao, msgs); bound != nil {
+		return &encryptedGroupDao{GroupDao: bound, fc: dao.fc}
+	}
+	return nil
+}
+
+// GetByIds implements GroupBatchGetter.GetByIds
+func (dao *encryptedGroupDao) GetByIds(ids []string) (map[string]*Group, error) {
+	groups, err := getGroupsByIds(dao.GroupDao, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, bo := range groups {
+		if _, err := dao.decrypt(bo); err != nil {
+			return nil, err
+		}
+	}
+	return groups, nil
+}
+
+// GetNAfter implements GroupKeysetPager.GetNAfter
+func (dao *encryptedGroupDao) GetNAfter(afterId string, maxNumRows int) ([]*Group, error) {
+	return dao.decryptAll(getNGroupsAfter(dao.GroupDao, afterId, maxNumRows))
+}
+
+/*----------------------------------------------------------------------*/
+
+// reencryptGroups encrypts values of groups' custom fields that are stored in plaintext or encrypted with a previous
+// key with the current key. dao is the backend's DAO (values are read as stored), it returns the number of groups
+// updated.
+func reencryptGroups(dao GroupDao, fc *goadmin.FieldCipher) (int, error) {
+	groups, err := dao.GetAll()
+	if err != nil {
+		return 0, err
+	}
+	encDao := &encryptedGroupDao{GroupDao: dao, fc: fc}
+	count := 0
+	for _, bo := range groups {
+		needed := false
+		for _, value := range bo.Attrs {
+			needed = needed || fc.NeedsReencryption(value)
+		}
+		if !needed {
+			continue
+		}
+		if _, err := encDao.decrypt(bo); err != nil {
+			return count, err
+		}
+		if ok, err := encDao.Update(bo); err != nil {
+			return count, fmt.Errorf("cannot update group [%s]: %s", bo.Id, err)
+		} else if ok {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// reencryptUsers encrypts users' names and notes that are stored in plaintext or encrypted with a previous key with the current
+// key. dao is the backend's DAO (names are read as stored), it returns the number of users updated.
+func reencryptUsers(dao UserDao, fc *goadmin.FieldCipher) (int, error) {
+	return reencryptUsersWithProgress(dao, fc, nil)
+}
+
+// reencryptUsersWithProgress is reencryptUsers reporting the number of users checked to progress (if not nil) after
+// each user; it stops at the first error returned by progress.
+func reencryptUsersWithProgress(dao UserDao, fc *goadmin.FieldCipher, progress func(done, total int) error) (int, error) {
+	users, err := dao.GetAll()
+	if err != nil {
+		return 0, err
+	}
+	encDao := &encryptedUserDao{UserDao: dao, fc: fc}
+	count := 0
+	for i, bo := range users {
+		if progress != nil {
+			if err := progress(i, len(users)); err != nil {
+				return count, err
+			}
+		}
+		if !fc.NeedsReencryption(bo.Name) && !fc.NeedsReencryption(bo.Notes) {
+			continue
+		}
+		if _, err := encDao.decrypt(bo); err != nil {
+			return count, err
+		}
+		if ok, err := encDao.Update(bo); err != nil {
+			return count, fmt.Errorf("cannot update user [%s]: %s", bo.Username, err)
+		} else if ok {
+			count++
+		}
+	}
+	if progress != nil {
+		return count, progress(len(users), len(users))
+	}
+	return count, nil
+}
+
+// backendUserDao returns the DAO encryptedUserDao wraps in a chain of wrapping DAOs (see Bootstrap), nil if names are
+// not encrypted.
+func backendUserDao(dao UserDao) UserDao {
+	for {
+		switch d := dao.(type) {
+		case *encryptedUserDao:
+			return d.UserDao
+		case *eventUserDao:
+			dao = d.UserDao
+		case *versionedUserDao:
+			dao = d.UserDao
+		default:
+			return nil
+		}
+	}
+}
+
+// backendGroupDao returns the DAO encryptedGroupDao wraps in a chain of wrapping DAOs (see Bootstrap), nil if fields
+// are not encrypted.
+func backendGroupDao(dao GroupDao) GroupDao {
+	for {
+		switch d := dao.(type) {
+		case *encryptedGroupDao:
+			return d.GroupDao
+		case *eventGroupDao:
+			dao = d.GroupDao
+		case *versionedGroupDao:
+			dao = d.GroupDao
+		default:
+			return nil
+		}
+	}
+}
+
+// ReencryptFields encrypts sensitive fields stored in plaintext (e.g. before encryption was enabled) or encrypted with
+// keys other than the current one (setting goadmin.field_encryption.current_key), so that previous keys can be
+// removed after a key rotation. It is meant to be run as a maintenance step (see command "reencrypt" in main.go) and
+// returns the number of records updated.
+//
+// available since template-r5
+func ReencryptFields(appConfig *hocon.Config) (int, error) {
+	fc, err := goadmin.NewFieldCipherFromConfig(appConfig, "goadmin.field_encryption")
+	if err != nil {
+		return 0, err
+	}
+	if fc == nil {
+		return 0, errors.New("field encryption is disabled, setting [goadmin.field_encryption.current_key] is empty")
+	}
+	myReg := &myRegistry{Registry: goadmin.NewRegistry(appConfig)}
+	if err := initDaos(myReg); err != nil {
+		return 0, err
+	}
+	count, err := reencryptUsers(myReg.userDao, fc)
+	log.Printf("%d user(s) re-encrypted with key [%s]", count, fc.CurrentKey())
+	if err != nil {
+		return count, err
+	}
+	groupCount, err := reencryptGroups(myReg.groupDao, fc)
+	log.Printf("%d group(s) re-encrypted with key [%s]", groupCount, fc.CurrentKey())
+	return count + groupCount, err
+}
@@ -0,0 +1,99 @@
+package myapp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"main/src/apptest"
+	"main/src/goadmin"
+)
+
+func TestFieldEncryption(t *testing.T) {
+	testName := "TestFieldEncryption"
+	keys := map[string][]byte{"k1": []byte("0123456789abcdef"), "k2": []byte("fedcba9876543210")}
+	fc1, err := goadmin.NewFieldCipher(keys, "k1")
+	if err != nil {
+		t.Fatalf("%s failed: %s", testName, err)
+	}
+	backend := newUserDaoMemory()
+	dao := &encryptedUserDao{UserDao: backend, fc: fc1}
+	dao.Create("enc@local", "", "Secret Name", systemGroupId)
+	if raw, _ := backend.Get("enc@local"); raw == nil || !strings.HasPrefix(raw.Name, "enc:k1:") || strings.Contains(raw.Name, "Secret") {
+		t.Fatalf("%s failed: name not encrypted at rest: %#v", testName, raw)
+	}
+	if bo, err := dao.Get("enc@local"); err != nil || bo == nil || bo.Name != "Secret Name" {
+		t.Fatalf("%s failed: unexpected user %#v (%v)", testName, bo, err)
+	}
+	// values stored before encryption was enabled are readable, then re-encrypted
+	backend.Create("plain@local", "", "Plain Name", systemGroupId)
+	if count, err := reencryptUsers(backend, fc1); err != nil || count != 1 {
+		t.Fatalf("%s failed: expected 1 user re-encrypted, got %d (%v)", testName, count, err)
+	}
+
+	// key rotation
+	fc2, _ := goadmin.NewFieldCipher(keys, "k2")
+	if count, err := reencryptUsers(backend, fc2); err != nil || count != 2 {
+		t.Fatalf("%s failed: expected 2 users re-encrypted, got %d (%v)", testName, count, err)
+	}
+	fcNew, _ := goadmin.NewFieldCipher(map[string][]byte{"k2": keys["k2"]}, "k2")
+	users, err := (&encryptedUserDao{UserDao: backend, fc: fcNew}).GetAll()
+	if err != nil || len(users) != 2 {
+		t.Fatalf("%s failed: cannot read users with the new key only: %v", testName, err)
+	}
+	for _, bo := range users {
+		if bo.Name != "Secret Name" && bo.Name != "Plain Name" {
+			t.Fatalf("%s failed: unexpected name %#v", testName, bo.Name)
+		}
+	}
+	// k1 is not in the keyring of the new cipher anymore
+	staleName, _ := fc1.Encrypt("Stale Name")
+	backend.Create("stale@local", "", staleName, systemGroupId)
+	if _, err := (&encryptedUserDao{UserDao: backend, fc: fcNew}).Get("stale@local"); !errors.Is(err, goadmin.ErrFieldKeyNotFound) {
+		t.Fatalf("%s failed: value encrypted with a removed key must not be decrypted (%v)", testName, err)
+	}
+	if _, err := fcNew.Decrypt(staleName); !errors.Is(err, goadmin.ErrFieldKeyNotFound) {
+		t.Fatalf("%s failed: value encrypted with a removed key must not be decrypted (%v)", testName, err)
+	}
+	backend.Delete(&User{Username: "stale@local"})
+
+	// values of custom fields of groups
+	groupBackend := newGroupDaoMemory()
+	groupDao := &encryptedGroupDao{GroupDao: groupBackend, fc: fc1}
+	groupDao.Create("finance", "Finance")
+	attrs := map[string]string{"contact": "cfo@example.com", "cost_center": ""}
+	if ok, err := groupDao.Update(&Group{Id: "finance", Name: "Finance", Attrs: attrs}); !ok || err != nil {
+		t.Fatalf("%s failed: %v/%s", testName, ok, err)
+	}
+	if attrs["contact"] != "cfo@example.com" {
+		t.Fatalf("%s failed: caller's copy must not be modified", testName)
+	}
+	if raw, _ := groupBackend.Get("finance"); raw == nil || !strings.HasPrefix(raw.Attrs["contact"], "enc:k1:") || raw.Name != "Finance" {
+		t.Fatalf("%s failed: custom fields of groups must be stored encrypted %#v", testName, raw)
+	}
+	if bo, err := groupDao.Get("finance"); err != nil || bo == nil || bo.Attrs["contact"] != "cfo@example.com" {
+		t.Fatalf("%s failed: unexpected group %#v (%v)", testName, bo, err)
+	}
+	if count, err := reencryptGroups(groupBackend, fc2); err != nil || count != 1 {
+		t.Fatalf("%s failed: expected 1 group re-encrypted but received %d (%v)", testName, count, err)
+	}
+	if groups, err := (&encryptedGroupDao{GroupDao: groupBackend, fc: fcNew}).GetAll(); err != nil || len(groups) != 1 || groups[0].Attrs["contact"] != "cfo@example.com" {
+		t.Fatalf("%s failed: unexpected groups %#v (%v)", testName, groups, err)
+	}
+
+	// bootstrapped from configuration
+	conf := apptest.SqliteInMemoryConfig + `
+goadmin.field_encryption {
+  current_key = "k1"
+  keys { k1 = "MDEyMzQ1Njc4OWFiY2RlZg==" }
+}
+`
+	h := apptest.New(t, conf, NewBootstrapper(nil, nil))
+	if h.Registry.FieldCipher == nil || h.Registry.FieldCipher.CurrentKey() != "k1" {
+		t.Fatalf("%s failed: field encryption not configured", testName)
+	}
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	if bo, err := myReg.userDao.Get(testAdminUsername); err != nil || bo == nil || strings.HasPrefix(bo.Name, "enc:") {
+		t.Fatalf("%s failed: unexpected admin user %#v (%v)", testName, bo, err)
+	}
+}
@@ -0,0 +1,90 @@
+package myapp
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	hocon "github.com/go-akka/configuration"
+	"main/src/goadmin"
+)
+
+// FixtureOptions tells how many groups and users GenerateFixtures creates.
+//
+// available since template-r5
+type FixtureOptions struct {
+	Groups   int    // number of groups
+	Users    int    // number of users, spread evenly over the groups
+	Password string // password of the users
+	Force    bool   // generate fixtures even if the application is not in dev mode
+}
+
+// FixtureResult is the number of groups and users created by GenerateFixtures; existing ones are not counted.
+//
+// available since template-r5
+type FixtureResult struct {
+	Groups int
+	Users  int
+}
+
+// fixtureGroupId returns id of the i-th fixture group, e.g. "fixture-0001".
+func fixtureGroupId(i int) string {
+	return fmt.Sprintf("fixture-%04d", i)
+}
+
+// fixtureUsername returns username of the i-th fixture user, e.g. "fixture-000001@example.com".
+func fixtureUsername(i int) string {
+	return fmt.Sprintf("fixture-%06d@example.com", i)
+}
+
+// generateFixtures creates groups and users for load tests and benchmarks. Fixtures have well-known ids, so that
+// generating them again only creates the missing ones.
+func generateFixtures(groupDao GroupDao, userDao UserDao, opts *FixtureOptions) (*FixtureResult, error) {
+	if opts.Users > 0 && opts.Groups <= 0 {
+		return nil, errors.New("users of fixtures need at least one group")
+	}
+	result := &FixtureResult{}
+	for i := 1; i <= opts.Groups; i++ {
+		ok, err := groupDao.Create(fixtureGroupId(i), fmt.Sprintf("Fixture group #%d", i))
+		if err != nil {
+			return result, err
+		}
+		if ok {
+			result.Groups++
+		}
+	}
+	for i := 1; i <= opts.Users; i++ {
+		username := fixtureUsername(i)
+		ok, err := userDao.Create(username, encryptPassword(username, opts.Password), fmt.Sprintf("Fixture user #%d", i), fixtureGroupId(1+(i-1)%opts.Groups))
+		if err != nil {
+			return result, err
+		}
+		if ok {
+			result.Users++
+		}
+	}
+	return result, nil
+}
+
+// GenerateFixtures seeds the database with opts.Groups groups and opts.Users users (command "gen-fixtures"), so that
+// pages and benchmarks can be exercised against realistic data volumes, e.g. thousands of users. All fixture users
+// share the same password: fixtures are refused outside of dev mode unless opts.Force is set.
+//
+// available since template-r5
+func GenerateFixtures(appConfig *hocon.Config, opts *FixtureOptions) (*FixtureResult, error) {
+	if !appConfig.GetBoolean("dev_mode", false) && !opts.Force {
+		return nil, errors.New("fixtures are meant for development and load-test environments, enable dev_mode or force the generation")
+	}
+	if opts.Password == "" {
+		return nil, errors.New("password of fixture users must not be empty")
+	}
+	myReg := &myRegistry{Registry: goadmin.NewRegistry(appConfig)}
+	if err := initDaos(myReg); err != nil {
+		return nil, err
+	}
+	result, err := generateFixtures(myReg.groupDao, myReg.userDao, opts)
+	if result != nil {
+		log.Printf("%d group(s) and %d user(s) created", result.Groups, result.Users)
+	}
+	return result, err
+}
@@ -0,0 +1,38 @@
+package myapp
+
+import (
+	"testing"
+
+	"main/src/apptest"
+	"main/src/goadmin"
+)
+
+func TestGenerateFixtures(t *testing.T) {
+	testName := "TestGenerateFixtures"
+	groupDao, userDao := newGroupDaoMemory(), newUserDaoMemory()
+	opts := &FixtureOptions{Groups: 3, Users: 10, Password: "fixture"}
+	result, err := generateFixtures(groupDao, userDao, opts)
+	if err != nil || result.Groups != 3 || result.Users != 10 {
+		t.Fatalf("%s failed: expected 3 groups and 10 users but received %#v / %v", testName, result, err)
+	}
+	counts, _ := countUsersByGroup(userDao)
+	if counts[fixtureGroupId(1)] != 4 || counts[fixtureGroupId(3)] != 3 {
+		t.Fatalf("%s failed: users must be spread over groups, received %v", testName, counts)
+	}
+	user, _ := userDao.Get(fixtureUsername(1))
+	if user == nil || user.Password != encryptPassword(user.Username, "fixture") {
+		t.Fatalf("%s failed: fixture users must sign in with the supplied password", testName)
+	}
+
+	// generating again only creates the missing fixtures
+	opts.Users = 12
+	if result, err = generateFixtures(groupDao, userDao, opts); err != nil || result.Groups != 0 || result.Users != 2 {
+		t.Fatalf("%s failed: expected 2 new users but received %#v / %v", testName, result, err)
+	}
+	if _, err := generateFixtures(groupDao, userDao, &FixtureOptions{Users: 1}); err == nil {
+		t.Fatalf("%s failed: users without groups must be refused", testName)
+	}
+	if _, err := GenerateFixtures(goadmin.ParseAppConfig(apptest.SqliteInMemoryConfig), opts); err == nil {
+		t.Fatalf("%s failed: fixtures must be refused outside of dev mode", testName)
+	}
+}
@@ -0,0 +1,136 @@
+package myapp
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+
+	"github.com/btnguyen2k/goyai"
+	"github.com/labstack/echo/v4"
+)
+
+// FlashLevel is the severity of a flash message.
+//
+// available since template-r5
+type FlashLevel string
+
+const (
+	FlashInfo    FlashLevel = "info"
+	FlashWarning FlashLevel = "warning"
+	FlashError   FlashLevel = "error"
+)
+
+// maxFlashes is the maximum number of queued flash messages, the oldest ones are dropped so that the session (which
+// may be stored in a cookie) does not grow unbounded.
+const maxFlashes = 10
+
+// flashMessage is a flash message queued in the session. Messages are localized when rendered, i.e. in the locale
+// of the page that displays them.
+type flashMessage struct {
+	Level  FlashLevel             `json:"l"`
+	Key    string                 `json:"k,omitempty"` // i18n key of the message
+	Params map[string]interface{} `json:"p,omitempty"` // template data of the i18n message
+	Text   string                 `json:"t,omitempty"` // already localized text, used if Key is empty
+}
+
+// AddFlash queues a flash message to be displayed by the next rendered page (usually the one the client is redirected
+// to). args are pairs of template data of the i18n message, e.g. AddFlash(c, FlashInfo, "create_group_successful",
+// "group", groupId).
+//
+// available since template-r5
+func AddFlash(c echo.Context, level FlashLevel, i18nKey string, args ...interface{}) {
+	queueFlash(c, flashMessage{Level: level, Key: i18nKey, Params: templateDataOf(args...)})
+}
+
+// AddFlashText queues a flash message whose text is already localized (e.g. the message of an error returned by
+// a checkCp* function).
+//
+// available since template-r5
+func AddFlashText(c echo.Context, level FlashLevel, text string) {
+	queueFlash(c, flashMessage{Level: level, Text: text})
+}
+
+func queueFlash(c echo.Context, msg flashMessage) {
+	js, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("[WARN] cannot queue flash message [%s]: %s", msg.Key, err)
+		return
+	}
+	sess := getSession(c)
+	if flashes, _ := sess.Values[sessionFlashesKey].([]interface{}); len(flashes) >= maxFlashes {
+		sess.Values[sessionFlashesKey] = flashes[len(flashes)-maxFlashes+1:]
+	}
+	sess.AddFlash(string(js))
+	sess.Save(c.Request(), c.Response())
+}
+
+// ctxFlashes is the context key flash messages popped from the session are kept under, see popFlashes.
+const ctxFlashes = "flashes"
+
+// popFlashes removes all queued flash messages from the session and returns them, oldest first. Popped messages are
+// kept in the request context, later calls of the same request return them again: streamed pages pop them before the
+// response is committed (the session cookie can not be updated afterwards) and render them later.
+func popFlashes(c echo.Context) []flashMessage {
+	if popped, ok := c.Get(ctxFlashes).([]flashMessage); ok {
+		return popped
+	}
+	sess := getSession(c)
+	flashes := sess.Flashes()
+	if len(flashes) == 0 {
+		c.Set(ctxFlashes, []flashMessage(nil))
+		return nil
+	}
+	sess.Save(c.Request(), c.Response())
+	result := make([]flashMessage, 0, len(flashes))
+	for _, f := range flashes {
+		str, _ := f.(string)
+		var msg flashMessage
+		if err := json.Unmarshal([]byte(str), &msg); err != nil || msg.Level == "" {
+			// message queued by a previous version of the application (severity encoded as prefix)
+			msg = legacyFlashMessage(str)
+		}
+		result = append(result, msg)
+	}
+	c.Set(ctxFlashes, result)
+	return result
+}
+
+// legacyFlashMessage parses a flash message whose severity is encoded as a prefix of its text.
+func legacyFlashMessage(str string) flashMessage {
+	for prefix, level := range map[string]FlashLevel{flashPrefixWarning: FlashWarning, flashPrefixError: FlashError, flashPrefixInfo: FlashInfo} {
+		if strings.HasPrefix(str, prefix) {
+			return flashMessage{Level: level, Text: str[len(prefix):]}
+		}
+	}
+	return flashMessage{Level: FlashInfo, Text: str}
+}
+
+// FlashModel is a flash message to be used in view.
+//
+// available since template-r5
+type FlashModel struct {
+	Level FlashLevel
+	Text  string
+}
+
+// AlertClass returns the CSS class of the alert box displaying the message.
+func (m *FlashModel) AlertClass() string {
+	if m.Level == FlashError {
+		return "alert-danger"
+	}
+	return "alert-" + string(m.Level)
+}
+
+// toFlashModelList localizes flash messages in the locale of the current request.
+func toFlashModelList(c echo.Context, list []flashMessage) []*FlashModel {
+	i18n, locale := getI18n(c), getContextString(c, ctxLocale)
+	result := make([]*FlashModel, 0, len(list))
+	for _, msg := range list {
+		text := msg.Text
+		if msg.Key != "" {
+			text = i18n.Localize(locale, msg.Key, &goyai.LocalizeConfig{TemplateData: msg.Params})
+		}
+		result = append(result, &FlashModel{Level: msg.Level, Text: text})
+	}
+	return result
+}
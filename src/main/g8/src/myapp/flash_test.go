@@ -0,0 +1,29 @@
+package myapp
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestAddFlash_MultipleMessages(t *testing.T) {
+	h := _newHarness(t)
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	h.PostForm(h.Reverse(actionNameCpCreateGroupSubmit), url.Values{"id": {"testers"}, "name": {"Testers"}})
+	h.PostForm(h.Reverse(actionNameCpCreateGroupSubmit), url.Values{"id": {"reviewers"}, "name": {"Reviewers"}})
+
+	resp := h.Get(h.Reverse(actionNameCpGroups))
+	h.AssertStatus(resp, http.StatusOK)
+	flashes, _ := h.LastData()["flashes"].([]*FlashModel)
+	if len(flashes) != 2 || flashes[0].Level != FlashInfo || !strings.Contains(flashes[0].Text, "testers") || !strings.Contains(flashes[1].Text, "reviewers") {
+		t.Fatalf("TestAddFlash_MultipleMessages failed: expected 2 messages but received %#v", flashes)
+	}
+	h.AssertBodyContains(resp, "reviewers")
+
+	// flash messages are displayed only once
+	h.Get(h.Reverse(actionNameCpGroups))
+	if flashes, _ := h.LastData()["flashes"].([]*FlashModel); len(flashes) != 0 {
+		t.Fatalf("TestAddFlash_MultipleMessages failed: expected no message but received %#v", flashes)
+	}
+}
@@ -0,0 +1,53 @@
+package myapp
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// cpFragment is an HTML fragment of control panel pages (defined in view cp_fragments) that can be rendered on its own
+// via route cp_fragment, so that pages can be updated partially.
+//
+// available since template-r5
+type cpFragment struct {
+	dataFunc    func(c echo.Context) map[string]interface{}
+	conditional bool // the fragment depends only on stored data, see renderConditional
+}
+
+var cpFragments = map[string]cpFragment{
+	"groups_table":  {dataFunc: fragmentUserGroups, conditional: true},
+	"users_table":   {dataFunc: fragmentUsers, conditional: true},
+	"tasks_table":   {dataFunc: fragmentTasks},
+	"widget_groups": {dataFunc: fragmentUserGroups, conditional: true},
+	"widget_users":  {dataFunc: fragmentUsers, conditional: true},
+	"widget_online": {dataFunc: fragmentOnlineUsers},
+	"widget_system": {dataFunc: func(c echo.Context) map[string]interface{} {
+		return map[string]interface{}{"osUtils": &OsUtils{}}
+	}},
+}
+
+func fragmentUserGroups(c echo.Context) map[string]interface{} {
+	u := &MyAppUtils{c: c}
+	return map[string]interface{}{"userGroups": u.AllUserGroups()}
+}
+
+func fragmentUsers(c echo.Context) map[string]interface{} {
+	return userListData(c)
+}
+
+// actionCpFragment renders a single HTML fragment (see cpFragment), without the page layout.
+//
+// available since template-r5
+func actionCpFragment(c echo.Context) error {
+	fragment, ok := cpFragments[c.Param("name")]
+	if !ok {
+		return echo.ErrNotFound
+	}
+	name := namespace + ":cp_fragments#" + c.Param("name")
+	if fragment.conditional {
+		return renderConditional(c, name, func() map[string]interface{} { return fragment.dataFunc(c) })
+	}
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	return c.Render(http.StatusOK, name, fragment.dataFunc(c))
+}
@@ -0,0 +1,28 @@
+package myapp
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestActionCpFragment(t *testing.T) {
+	h := _newHarness(t)
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	h.PostForm(h.Reverse(actionNameCpCreateGroupSubmit), url.Values{"id": {"testers"}, "name": {"Testers"}})
+
+	resp := h.Get(h.Reverse(actionNameCpFragment, "groups_table"))
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertBodyContains(resp, `id="fragment-groups_table"`)
+	h.AssertBodyContains(resp, "Testers")
+	if strings.Contains(resp.Body.String(), "<html") {
+		t.Fatalf("TestActionCpFragment failed: fragment is rendered with layout")
+	}
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpFragment, "widget_system")), http.StatusOK)
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpFragment, "no_such_fragment")), http.StatusNotFound)
+
+	// fragments leave flash messages for the next full page
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpGroups)), http.StatusOK)
+	h.AssertData("flashInfo", "testers")
+}
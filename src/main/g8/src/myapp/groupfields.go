@@ -0,0 +1,308 @@
+package myapp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/btnguyen2k/goyai"
+	"github.com/labstack/echo/v4"
+	"main/src/utils"
+)
+
+// Groups carry custom fields (e.g. cost center, contact email) defined by admins: the fields are a schema stored in the
+// setting table (setting "group_fields", managed on the page of group fields), their values are stored as a JSON object
+// along with each group (Group.Attrs) and edited on the group form.
+//
+// Values of fields removed from the schema are kept, so that fields removed by mistake can be added back.
+
+const (
+	// settingIdGroupFields is the id of the setting that stores the custom fields of groups.
+	settingIdGroupFields = "group_fields"
+
+	// groupFieldsMax is the maximum number of custom fields, groupAttrMaxLength the maximum length, in characters, of
+	// a value.
+	groupFieldsMax     = 20
+	groupAttrMaxLength = 255
+
+	// groupFieldsBlankRows is the number of blank rows to add fields on the page of group fields.
+	groupFieldsBlankRows = 3
+)
+
+// groupFieldTypes lists the types of custom fields, values are validated against their type.
+var groupFieldTypes = []string{"text", "number", "email", "url", "date"}
+
+var (
+	errGroupFieldsTooMany  = errors.New("too many fields")
+	errGroupFieldName      = errors.New("invalid field name")
+	errGroupFieldDuplicate = errors.New("duplicated field name")
+	errGroupFieldType      = errors.New("invalid field type")
+
+	reGroupFieldName = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+)
+
+// GroupField is a custom field of groups.
+//
+// available since template-r5
+type GroupField struct {
+	Name     string `json:"name"` // key of the value in Group.Attrs
+	Label    string `json:"label"`
+	Type     string `json:"type"` // one of groupFieldTypes
+	Required bool   `json:"required,omitempty"`
+}
+
+// DisplayLabel returns the label of the field, its name if it has no label.
+func (f *GroupField) DisplayLabel() string {
+	if f.Label != "" {
+		return f.Label
+	}
+	return f.Name
+}
+
+// InputType returns the type of the HTML input of the field.
+func (f *GroupField) InputType() string {
+	if f.Type == "number" {
+		return "text" // numbers are validated server-side, "number" inputs do not accept every float
+	}
+	return f.Type
+}
+
+// validate checks a value against the type of the field and returns its canonical form.
+func (f *GroupField) validate(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		if f.Required {
+			return "", errors.New("required")
+		}
+		return "", nil
+	}
+	if utf8.RuneCountInString(value) > groupAttrMaxLength {
+		return "", fmt.Errorf("longer than %d characters", groupAttrMaxLength)
+	}
+	var err error
+	switch f.Type {
+	case "number":
+		_, err = strconv.ParseFloat(value, 64)
+	case "email":
+		if !reEmail.MatchString(value) {
+			err = errors.New("invalid email address")
+		}
+	case "url":
+		if u, e := url.ParseRequestURI(value); e != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			err = errors.New("invalid URL")
+		}
+	case "date":
+		_, err = time.Parse("2006-01-02", value)
+	}
+	return value, err
+}
+
+// encodeGroupAttrs returns the JSON form of group attributes as stored in databases, empty if there is none. Keys are
+// sorted, so that equal attributes have the same JSON form.
+func encodeGroupAttrs(attrs map[string]string) string {
+	if len(attrs) == 0 {
+		return ""
+	}
+	js, _ := json.Marshal(attrs)
+	return string(js)
+}
+
+// decodeGroupAttrs parses group attributes stored in databases, nil if there is none.
+func decodeGroupAttrs(js string) map[string]string {
+	if js == "" {
+		return nil
+	}
+	attrs := make(map[string]string)
+	if err := json.Unmarshal([]byte(js), &attrs); err != nil {
+		log.Printf("[WARN] cannot parse group attributes [%s]: %s", js, err)
+		return nil
+	}
+	return attrs
+}
+
+// groupFields returns the custom fields of groups, in the order of the group form.
+func (r *myRegistry) groupFields() ([]*GroupField, error) {
+	fields := make([]*GroupField, 0)
+	_, err := r.loadSetting(settingIdGroupFields, &fields)
+	return fields, err
+}
+
+// groupFieldsFromForm parses custom fields from the page of group fields: row i has form fields "field_name_<i>",
+// "field_label_<i>", "field_type_<i>" and "field_required_<i>". Rows without name are dropped.
+func groupFieldsFromForm(formData url.Values) ([]*GroupField, error) {
+	fields := make([]*GroupField, 0)
+	names := make(map[string]bool)
+	for i := 0; formData.Has(fmt.Sprintf("field_name_%d", i)); i++ {
+		name := strings.ToLower(strings.TrimSpace(formData.Get(fmt.Sprintf("field_name_%d", i))))
+		if name == "" {
+			continue
+		}
+		if !reGroupFieldName.MatchString(name) {
+			return nil, fmt.Errorf("%w: %s", errGroupFieldName, name)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("%w: %s", errGroupFieldDuplicate, name)
+		}
+		names[name] = true
+		field := &GroupField{
+			Name:     name,
+			Label:    strings.TrimSpace(formData.Get(fmt.Sprintf("field_label_%d", i))),
+			Type:     formData.Get(fmt.Sprintf("field_type_%d", i)),
+			Required: formData.Get(fmt.Sprintf("field_required_%d", i)) != "",
+		}
+		if !containsString(groupFieldTypes, field.Type) {
+			return nil, fmt.Errorf("%w: %s", errGroupFieldType, field.Type)
+		}
+		fields = append(fields, field)
+	}
+	if len(fields) > groupFieldsMax {
+		return nil, errGroupFieldsTooMany
+	}
+	return fields, nil
+}
+
+// groupAttrsFromForm returns the attributes of a group submitted on the group form (form fields "attr_<name>"),
+// starting from the current ones so that values of fields removed from the schema are kept. The label of the first
+// invalid field is returned along with the error.
+func groupAttrsFromForm(fields []*GroupField, formData url.Values, current map[string]string) (map[string]string, string, error) {
+	attrs := make(map[string]string)
+	for k, v := range current {
+		attrs[k] = v
+	}
+	for _, f := range fields {
+		value, err := f.validate(formData.Get("attr_" + f.Name))
+		if err != nil {
+			return nil, f.DisplayLabel(), err
+		}
+		if value == "" {
+			delete(attrs, f.Name)
+		} else {
+			attrs[f.Name] = value
+		}
+	}
+	return attrs, "", nil
+}
+
+// setGroupAttrsForm sets the form fields of the custom fields of a group on the group form.
+func setGroupAttrsForm(formData url.Values, attrs map[string]string) {
+	for k, v := range attrs {
+		formData.Set("attr_"+k, v)
+	}
+}
+
+// localizeGroupAttrsError returns the localized error of an invalid value of a custom field.
+func localizeGroupAttrsError(c echo.Context, label string, err error) string {
+	return getI18n(c).Localize(getContextString(c, ctxLocale), "error_group_field_invalid", &goyai.LocalizeConfig{
+		TemplateData: map[string]interface{}{"field": label, "err": err.Error()},
+	})
+}
+
+/*----------------------------------------------------------------------*/
+
+// ctxGroupFields caches the custom fields of groups for the duration of a request, see groupFieldsOf.
+const ctxGroupFields = "groupFields"
+
+// groupFieldsOf returns the custom fields of groups, loaded once per request.
+func groupFieldsOf(c echo.Context) []*GroupField {
+	if fields, ok := c.Get(ctxGroupFields).([]*GroupField); ok {
+		return fields
+	}
+	fields, err := getRegistry(c).groupFields()
+	if err != nil {
+		log.Printf("[ERROR] cannot load setting [%s]: %s", settingIdGroupFields, err)
+	}
+	c.Set(ctxGroupFields, fields)
+	return fields
+}
+
+// AttrList returns the non-empty values of custom fields of the group, in the order of the schema, to be shown on
+// the group list.
+func (m *GroupModel) AttrList() []*GroupAttr {
+	result := make([]*GroupAttr, 0)
+	if len(m.Attrs) == 0 {
+		return result
+	}
+	for _, f := range groupFieldsOf(m.c) {
+		if v := m.Attrs[f.Name]; v != "" {
+			result = append(result, &GroupAttr{Field: f, Value: v})
+		}
+	}
+	return result
+}
+
+// GroupAttr is the value of a custom field of a group.
+//
+// available since template-r5
+type GroupAttr struct {
+	Field *GroupField
+	Value string
+}
+
+/*----------------------------------------------------------------------*/
+
+// actionCpGroupFields renders the page of group fields: existing fields followed by blank rows to add new ones.
+//
+// available since template-r5
+func actionCpGroupFields(c echo.Context) error {
+	AddBreadcrumb(c, "", "group_fields")
+	if err := checkCpManageSecurity(c); err != nil {
+		AddFlashText(c, FlashWarning, err.Error())
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpGroups)+"?r="+utils.RandomString(4))
+	}
+	var errMsg string
+	fields, err := getRegistry(c).groupFields()
+	if err != nil {
+		errMsg = getI18n(c).Localize(getContextString(c, ctxLocale), "error_db_001", &goyai.LocalizeConfig{
+			TemplateData: map[string]interface{}{"err": settingIdGroupFields + "/" + err.Error()},
+		})
+	}
+	for i := 0; i < groupFieldsBlankRows && len(fields) < groupFieldsMax; i++ {
+		fields = append(fields, &GroupField{Type: "text"})
+	}
+	return c.Render(http.StatusOK, namespace+":layout:cp_group_fields", map[string]interface{}{
+		"active":     "groups",
+		"fields":     fields,
+		"fieldTypes": groupFieldTypes,
+		"error":      errMsg,
+	})
+}
+
+// actionCpGroupFieldsSubmit saves the custom fields of groups, see groupFieldsFromForm.
+//
+// available since template-r5
+func actionCpGroupFieldsSubmit(c echo.Context) error {
+	if err := checkCpManageSecurity(c); err != nil {
+		AddFlashText(c, FlashWarning, err.Error())
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpGroups)+"?r="+utils.RandomString(4))
+	}
+	formData, err := c.FormParams()
+	var fields []*GroupField
+	if err == nil {
+		fields, err = groupFieldsFromForm(formData)
+	}
+	if err != nil {
+		AddFlash(c, FlashError, "error_group_fields_invalid", "err", err.Error())
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpGroupFields)+"?r="+utils.RandomString(4))
+	}
+	myReg := getRegistry(c)
+	if err := myReg.saveSetting(settingIdGroupFields, fields); err != nil {
+		AddFlash(c, FlashError, "error_db_001", "err", settingIdGroupFields+"/"+err.Error())
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpGroupFields)+"?r="+utils.RandomString(4))
+	}
+	currentUser, _ := c.Get(ctxCurrentUser).(*User)
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = f.Name
+	}
+	myReg.auditf("user [%s] updated custom fields of groups to [%s]", currentUser.Username, strings.Join(names, ","))
+	AddFlash(c, FlashInfo, "update_group_fields_successful")
+	return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpGroupFields)+"?r="+utils.RandomString(4))
+}
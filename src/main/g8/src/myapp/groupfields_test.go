@@ -0,0 +1,58 @@
+package myapp
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestGroupFields(t *testing.T) {
+	testName := "TestGroupFields"
+	h := _newHarness(t)
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+
+	// invalid schemas are rejected
+	for _, form := range []url.Values{
+		{"field_name_0": {"Cost Center"}, "field_type_0": {"text"}},
+		{"field_name_0": {"email"}, "field_type_0": {"email"}, "field_name_1": {"email"}, "field_type_1": {"text"}},
+		{"field_name_0": {"email"}, "field_type_0": {"phone"}},
+	} {
+		h.AssertRedirect(h.PostForm(h.Reverse(actionNameCpGroupFieldsSubmit), form), h.Reverse(actionNameCpGroupFields))
+		if fields, _ := myReg.groupFields(); len(fields) != 0 {
+			t.Fatalf("%s failed: schema %v must be rejected, received %#v", testName, form, fields)
+		}
+	}
+	form := url.Values{
+		"field_name_0": {"cost_center"}, "field_label_0": {"Cost center"}, "field_type_0": {"text"}, "field_required_0": {"1"},
+		"field_name_1": {""}, "field_type_1": {"text"},
+		"field_name_2": {"Email"}, "field_label_2": {"Contact email"}, "field_type_2": {"email"},
+	}
+	h.AssertRedirect(h.PostForm(h.Reverse(actionNameCpGroupFieldsSubmit), form), h.Reverse(actionNameCpGroupFields))
+	expected := []*GroupField{{Name: "cost_center", Label: "Cost center", Type: "text", Required: true}, {Name: "email", Label: "Contact email", Type: "email"}}
+	if fields, err := myReg.groupFields(); err != nil || !reflect.DeepEqual(fields, expected) {
+		t.Fatalf("%s failed: expected fields %#v but received %#v (%v)", testName, expected, fields, err)
+	}
+
+	// values are validated against the schema
+	form = url.Values{"id": {"ops"}, "name": {"Operations"}, "attr_email": {"ops@local"}}
+	h.AssertBodyContains(h.PostForm(h.Reverse(actionNameCpCreateGroupSubmit), form), "Invalid value of field Cost center")
+	form.Set("attr_cost_center", "CC-42")
+	form.Set("attr_email", "not an email")
+	h.AssertBodyContains(h.PostForm(h.Reverse(actionNameCpCreateGroupSubmit), form), "Invalid value of field Contact email")
+	form.Set("attr_email", "ops@local")
+	h.AssertRedirect(h.PostForm(h.Reverse(actionNameCpCreateGroupSubmit), form), h.Reverse(actionNameCpGroups))
+	if group, _ := myReg.groupDao.Get("ops"); group == nil || !reflect.DeepEqual(group.Attrs, map[string]string{"cost_center": "CC-42", "email": "ops@local"}) {
+		t.Fatalf("%s failed: unexpected attributes of group %#v", testName, group)
+	}
+	h.AssertBodyContains(h.Get(h.Reverse(actionNameCpGroups)), "Contact email: ops@local")
+
+	// values of fields removed from the schema are kept
+	form = url.Values{"field_name_0": {"cost_center"}, "field_type_0": {"text"}}
+	h.AssertRedirect(h.PostForm(h.Reverse(actionNameCpGroupFieldsSubmit), form), h.Reverse(actionNameCpGroupFields))
+	form = url.Values{"name": {"Ops"}, "attr_cost_center": {"CC-7"}}
+	h.AssertRedirect(h.PostForm(h.Reverse(actionNameCpEditGroupSubmit)+"?id=ops", form), h.Reverse(actionNameCpGroups))
+	if group, _ := myReg.groupDao.Get("ops"); group.Name != "Ops" || !reflect.DeepEqual(group.Attrs, map[string]string{"cost_center": "CC-7", "email": "ops@local"}) {
+		t.Fatalf("%s failed: unexpected attributes of group %#v", testName, group)
+	}
+}
@@ -0,0 +1,214 @@
+package myapp
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"main/src/utils"
+)
+
+const (
+	helpIndexSlug      = "index"
+	helpSnippetLength  = 160
+	helpFileSuffix     = ".md"
+	defaultHelpDocsDir = "./docs"
+)
+
+var reHelpSlug = regexp.MustCompile(`^[\w-]+$`)
+
+// HelpPage is a page of the built-in help module, read from a Markdown file in the help directory:
+//
+//   - <slug>.md is the default variant of the page, e.g. "01-getting-started.md".
+//   - <slug>.<locale>.md is the variant of the page for a locale, e.g. "01-getting-started.vi.md".
+//
+// Pages are listed in the order of their slugs; the page "index" (if any) is the home page of the help module.
+//
+// available since template-r5
+type HelpPage struct {
+	Slug    string // name of the file without locale suffix and extension
+	Locale  string // locale of the variant, empty for the default variant
+	Title   string // first heading of the page, or the slug if the page has none
+	Content string // Markdown content
+}
+
+// HelpSearchResult is a page matching a search query.
+//
+// available since template-r5
+type HelpSearchResult struct {
+	Page    *HelpPage
+	Snippet string // excerpt of the content around the first match
+}
+
+// helpDocs holds pages of the help module, indexed by slug then locale ("" for the default variant).
+type helpDocs struct {
+	pages map[string]map[string]*HelpPage
+	slugs []string
+}
+
+// loadHelpDocs reads pages of the help module from the supplied directory; locales are the ids of available locales,
+// used to detect locale variants. An empty help module is returned if the directory does not exist.
+func loadHelpDocs(dir string, locales []string) (*helpDocs, error) {
+	docs := &helpDocs{pages: make(map[string]map[string]*HelpPage), slugs: make([]string, 0)}
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return docs, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	isLocale := make(map[string]bool)
+	for _, locale := range locales {
+		isLocale[locale] = true
+	}
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), helpFileSuffix) {
+			continue
+		}
+		slug, locale := strings.TrimSuffix(f.Name(), helpFileSuffix), ""
+		if i := strings.LastIndex(slug, "."); i > 0 && isLocale[slug[i+1:]] {
+			slug, locale = slug[:i], slug[i+1:]
+		}
+		if !reHelpSlug.MatchString(slug) {
+			log.Printf("[WARN] ignored help page [%s]: invalid file name", f.Name())
+			continue
+		}
+		content, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if docs.pages[slug] == nil {
+			docs.pages[slug] = make(map[string]*HelpPage)
+			docs.slugs = append(docs.slugs, slug)
+		}
+		docs.pages[slug][locale] = &HelpPage{Slug: slug, Locale: locale, Title: helpPageTitle(string(content), slug), Content: string(content)}
+	}
+	sort.Strings(docs.slugs)
+	return docs, nil
+}
+
+// helpPageTitle returns the first heading of the page.
+func helpPageTitle(content, defaultTitle string) string {
+	for _, line := range strings.Split(content, "\n") {
+		if line = strings.TrimSpace(line); strings.HasPrefix(line, "#") {
+			if title := strings.TrimSpace(strings.Trim(line, "#")); title != "" {
+				return title
+			}
+		}
+	}
+	return defaultTitle
+}
+
+// page returns the variant of the page for the supplied locale, falling back to the default variant and then to the
+// default locale. nil is returned if the page does not exist.
+func (d *helpDocs) page(slug, locale string) *HelpPage {
+	variants := d.pages[slug]
+	for _, l := range []string{locale, "", defaultLocale} {
+		if p, ok := variants[l]; ok {
+			return p
+		}
+	}
+	for _, p := range variants {
+		return p
+	}
+	return nil
+}
+
+// index lists all pages, in the locale's variants.
+func (d *helpDocs) index(locale string) []*HelpPage {
+	result := make([]*HelpPage, 0, len(d.slugs))
+	for _, slug := range d.slugs {
+		result = append(result, d.page(slug, locale))
+	}
+	return result
+}
+
+// home returns the home page of the help module: the page "index" if any, otherwise the first page.
+func (d *helpDocs) home(locale string) *HelpPage {
+	if p := d.page(helpIndexSlug, locale); p != nil {
+		return p
+	}
+	if len(d.slugs) > 0 {
+		return d.page(d.slugs[0], locale)
+	}
+	return nil
+}
+
+// search finds pages (in the locale's variants) whose title or content contains all words of the query, case
+// insensitively.
+func (d *helpDocs) search(locale, query string) []*HelpSearchResult {
+	words := strings.Fields(strings.ToLower(query))
+	result := make([]*HelpSearchResult, 0)
+	if len(words) == 0 {
+		return result
+	}
+	for _, p := range d.index(locale) {
+		text := strings.ToLower(p.Title + "\n" + p.Content)
+		matched := true
+		for _, w := range words {
+			if !strings.Contains(text, w) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			result = append(result, &HelpSearchResult{Page: p, Snippet: helpSnippet(p.Content, words[0])})
+		}
+	}
+	return result
+}
+
+// helpSnippet returns an excerpt of the content around the first occurrence of the (lower-cased) word.
+func helpSnippet(content, word string) string {
+	runes := []rune(strings.Join(strings.Fields(content), " "))
+	lower := []rune(strings.ToLower(string(runes)))
+	pos := 0
+	if i := strings.Index(string(lower), word); i >= 0 {
+		pos = len([]rune(string(lower)[:i]))
+	}
+	if pos > len(runes) {
+		pos = len(runes)
+	}
+	start := pos - helpSnippetLength/2
+	if start < 0 {
+		start = 0
+	}
+	end := start + helpSnippetLength
+	if end > len(runes) {
+		end = len(runes)
+	}
+	snippet := string(runes[start:end])
+	if start > 0 {
+		snippet = "..." + snippet
+	}
+	if end < len(runes) {
+		snippet += "..."
+	}
+	return snippet
+}
+
+// getHelpDocs returns pages of the help module; pages are re-read on every call in development mode.
+func (r *myRegistry) getHelpDocs() *helpDocs {
+	if !utils.DevMode {
+		return r.helpDocs
+	}
+	docs, err := loadHelpDocs(r.helpDir, r.localeIds())
+	if err != nil {
+		log.Printf("[ERROR] error loading help pages from [%s]: %s", r.helpDir, err)
+		return r.helpDocs
+	}
+	return docs
+}
+
+// localeIds returns ids of available locales.
+func (r *myRegistry) localeIds() []string {
+	result := make([]string, 0)
+	for _, localeInfo := range r.i18n.AvailableLocales() {
+		result = append(result, localeInfo.Id)
+	}
+	return result
+}
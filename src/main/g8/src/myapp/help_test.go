@@ -0,0 +1,75 @@
+package myapp
+
+import (
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadHelpDocs(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"index.md":    "# Welcome\nHello",
+		"index.vi.md": "# Chào mừng\nXin chào",
+		"02-setup.md": "Install the application, then configure it.",
+		"01-users.md": "# Users\nManage user accounts",
+		"notes.txt":   "not a help page",
+		"bad name.md": "# Invalid file name",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("TestLoadHelpDocs failed: %s", err)
+		}
+	}
+	docs, err := loadHelpDocs(dir, []string{"en", "vi"})
+	if err != nil {
+		t.Fatalf("TestLoadHelpDocs failed: %s", err)
+	}
+	index := docs.index("vi")
+	expected := []string{"Users", "02-setup", "Chào mừng"}
+	if len(index) != len(expected) {
+		t.Fatalf("TestLoadHelpDocs failed: expected %d pages but received %d", len(expected), len(index))
+	}
+	for i, p := range index {
+		if p.Title != expected[i] {
+			t.Fatalf("TestLoadHelpDocs failed: expected page [%s] at position %d but received [%s]", expected[i], i, p.Title)
+		}
+	}
+	if p := docs.home("en"); p == nil || p.Title != "Welcome" {
+		t.Fatalf("TestLoadHelpDocs failed: expected home page [Welcome] but received %#v", p)
+	}
+	if results := docs.search("en", "CONFIGURE install"); len(results) != 1 || results[0].Page.Slug != "02-setup" {
+		t.Fatalf("TestLoadHelpDocs failed: expected 1 search result but received %#v", results)
+	}
+
+	docs, err = loadHelpDocs(filepath.Join(dir, "not_exist"), []string{"en"})
+	if err != nil || docs.home("en") != nil {
+		t.Fatalf("TestLoadHelpDocs failed: expected empty help module but received %#v/%s", docs, err)
+	}
+}
+
+func TestActionCpHelp(t *testing.T) {
+	h := _newHarness(t)
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	resp := h.Get(h.Reverse(actionNameCpHelp))
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertTemplate(namespace + ":layout:cp_help")
+	h.AssertBodyContains(resp, "<h1>Welcome</h1>")
+
+	resp = h.Get(h.Reverse(actionNameCpHelp) + "?_l=vi")
+	h.AssertBodyContains(resp, "<h1>Chào mừng</h1>")
+
+	resp = h.Get(h.Reverse(actionNameCpHelpPage, "users-and-groups") + "?_l=en")
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertBodyContains(resp, "<h1>Users and groups</h1>")
+
+	resp = h.Get(h.Reverse(actionNameCpHelp) + "?q=system+group")
+	h.AssertStatus(resp, http.StatusOK)
+	if results, ok := h.LastData()["results"].([]*HelpSearchResult); !ok || len(results) != 1 || results[0].Page.Slug != "users-and-groups" {
+		t.Fatalf("TestActionCpHelp failed: unexpected search results %#v", h.LastData()["results"])
+	}
+
+	resp = h.Get(h.Reverse(actionNameCpHelpPage, "not-exist"))
+	h.AssertStatus(resp, http.StatusNotFound)
+}
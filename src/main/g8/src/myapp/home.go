@@ -0,0 +1,105 @@
+package myapp
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// modes of the home page (setting myapp.home.mode)
+const (
+	homeModeLanding  = "landing"   // render the packaged landing page
+	homeModeRedirect = "redirect"  // redirect to myapp.home.redirect_url, the control panel by default
+	homeModeTemplate = "template"  // render the template myapp.home.template
+	homeModeProxy    = "proxy"     // proxy requests to myapp.home.proxy_url
+	homeModeNotFound = "not_found" // respond with 404, e.g. for purely internal admin tools
+)
+
+// homePage serves "/" as configured in the configuration block myapp.home.
+//
+// available since template-r5
+type homePage struct {
+	mode        string
+	redirectUrl string                 // empty to redirect to the control panel
+	template    string                 // name of the rendered template, in the views directory
+	proxy       *httputil.ReverseProxy // nil unless mode is proxy
+}
+
+// newHomePage creates a homePage from the configuration block myapp.home.
+func newHomePage(r *myRegistry, renderer *myRenderer) (*homePage, error) {
+	conf := r.AppConfig
+	h := &homePage{mode: strings.ToLower(strings.TrimSpace(conf.GetString(namespace+".home.mode", homeModeLanding)))}
+	switch h.mode {
+	case homeModeLanding, homeModeNotFound:
+	case homeModeRedirect:
+		h.redirectUrl = strings.TrimSpace(conf.GetString(namespace+".home.redirect_url", ""))
+	case homeModeTemplate:
+		h.template = strings.TrimSpace(conf.GetString(namespace+".home.template", ""))
+		if h.template == "" || strings.ContainsAny(h.template, `:/\`) {
+			return nil, fmt.Errorf("invalid template name [%s] in setting [%s.home.template]", h.template, namespace)
+		}
+		if fi, err := os.Stat(renderer.templateFile(h.template)); err != nil || fi.IsDir() {
+			return nil, fmt.Errorf("template [%s] not found at [%s]", h.template, renderer.templateFile(h.template))
+		}
+	case homeModeProxy:
+		target, err := url.Parse(strings.TrimSpace(conf.GetString(namespace+".home.proxy_url", "")))
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			return nil, fmt.Errorf("setting [%s.home.proxy_url] must be an absolute http(s) URL", namespace)
+		}
+		h.proxy = newHomeProxy(target)
+	default:
+		return nil, fmt.Errorf("unknown home page mode [%s], valid values are %s, %s, %s, %s and %s", h.mode,
+			homeModeLanding, homeModeRedirect, homeModeTemplate, homeModeProxy, homeModeNotFound)
+	}
+	return h, nil
+}
+
+// newHomeProxy creates a reverse proxy that forwards requests to exactly the target URL, query strings of requests are
+// appended to the target's. Credentials of the application (session cookie, Authorization header) are not forwarded
+// to the target, and the target can not set cookies on the application's domain.
+func newHomeProxy(target *url.URL) *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{Director: func(req *http.Request) {
+		req.Header.Del("Cookie")
+		req.Header.Del(echo.HeaderAuthorization)
+		query := req.URL.RawQuery
+		req.URL.Scheme, req.URL.Host, req.URL.Path, req.URL.RawPath = target.Scheme, target.Host, target.Path, target.RawPath
+		req.URL.RawQuery = target.RawQuery
+		if req.URL.RawQuery == "" || query == "" {
+			req.URL.RawQuery += query
+		} else {
+			req.URL.RawQuery += "&" + query
+		}
+		req.Host = target.Host
+		if _, ok := req.Header["User-Agent"]; !ok {
+			// explicitly disable the default User-Agent of the http client
+			req.Header.Set("User-Agent", "")
+		}
+	}, ModifyResponse: func(resp *http.Response) error {
+		resp.Header.Del("Set-Cookie")
+		return nil
+	}}
+}
+
+func (h *homePage) serve(c echo.Context) error {
+	switch h.mode {
+	case homeModeRedirect:
+		target := h.redirectUrl
+		if target == "" {
+			target = c.Echo().Reverse(actionNameCpDashboard)
+		}
+		return c.Redirect(http.StatusFound, target)
+	case homeModeTemplate:
+		return c.Render(http.StatusOK, namespace+":"+h.template, map[string]interface{}{})
+	case homeModeProxy:
+		h.proxy.ServeHTTP(c.Response(), c.Request())
+		return nil
+	case homeModeNotFound:
+		return echo.NewHTTPError(http.StatusNotFound)
+	}
+	return c.Render(http.StatusOK, namespace+":landing", nil)
+}
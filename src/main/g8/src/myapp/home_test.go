@@ -0,0 +1,56 @@
+package myapp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"main/src/apptest"
+)
+
+func TestHomePage(t *testing.T) {
+	testName := "TestHomePage"
+	h := _newHarness(t)
+	h.AssertStatus(h.Get("/"), http.StatusOK)
+	h.AssertTemplate(namespace + ":landing")
+
+	h = apptest.New(t, apptest.SqliteInMemoryConfig+"\nmyapp.home.mode = redirect\n", NewBootstrapper(nil, nil))
+	h.AssertRedirect(h.Get("/"), h.Reverse(actionNameCpDashboard))
+
+	h = apptest.New(t, apptest.SqliteInMemoryConfig+"\nmyapp.home.mode = not_found\n", NewBootstrapper(nil, nil))
+	h.AssertStatus(h.Get("/"), http.StatusNotFound)
+
+	h = apptest.New(t, apptest.SqliteInMemoryConfig+"\nmyapp.home.mode = template\nmyapp.home.template = login_link\n", NewBootstrapper(nil, nil))
+	h.AssertStatus(h.Get("/"), http.StatusOK)
+	h.AssertTemplate(namespace + ":login_link")
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "upstream", Value: "1"})
+		w.Write([]byte("upstream " + r.URL.Path + "?" + r.URL.RawQuery))
+		if r.Header.Get("Cookie") != "" || r.Header.Get(echo.HeaderAuthorization) != "" {
+			w.Write([]byte(" with credentials"))
+		}
+	}))
+	defer upstream.Close()
+	h = apptest.New(t, apptest.SqliteInMemoryConfig+"\nmyapp.home { mode = proxy, proxy_url = \""+upstream.URL+"/site?a=1\" }\n", NewBootstrapper(nil, nil))
+	req := httptest.NewRequest(http.MethodGet, "/?b=2", nil)
+	req.Header.Set("Cookie", "session=secret")
+	req.Header.Set(echo.HeaderAuthorization, "Bearer secret")
+	resp := h.Do(req)
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertBodyContains(resp, "upstream /site?a=1&b=2")
+	if strings.Contains(resp.Body.String(), "with credentials") || strings.Contains(strings.Join(resp.Header().Values("Set-Cookie"), ";"), "upstream=") {
+		t.Fatalf("%s failed: credentials must not be forwarded to nor cookies set by the proxied site", testName)
+	}
+
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatalf("%s failed: a missing template must fail the startup", testName)
+			}
+		}()
+		apptest.New(t, apptest.SqliteInMemoryConfig+"\nmyapp.home.mode = template\nmyapp.home.template = not_exists\n", NewBootstrapper(nil, nil))
+	}()
+}
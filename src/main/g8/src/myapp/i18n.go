@@ -0,0 +1,191 @@
+package myapp
+
+import (
+	"bytes"
+	"log"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/btnguyen2k/goyai"
+)
+
+const (
+	defaultLocale = "en"
+	i18nConfigDir = "./config/i18n_" + namespace
+
+	textDirLtr = "ltr"
+	textDirRtl = "rtl"
+)
+
+// rtlLanguages are languages written from right to left, used if a locale does not specify its direction.
+var rtlLanguages = map[string]bool{"ar": true, "fa": true, "he": true, "ur": true, "yi": true}
+
+// LocaleMeta holds presentation metadata of a locale, read from i18n keys "locale_dir", "locale_date_format" and
+// "locale_datetime_format".
+//
+// available since template-r5
+type LocaleMeta struct {
+	Id             string // locale id, e.g. "en"
+	Dir            string // text direction, either "ltr" or "rtl"
+	DateFormat     string // layout to format dates (see time.Time.Format)
+	DateTimeFormat string // layout to format date-times (see time.Time.Format)
+}
+
+// IsRtl returns true if text of the locale is written from right to left.
+func (m *LocaleMeta) IsRtl() bool {
+	return m.Dir == textDirRtl
+}
+
+// FormatDate formats a date using the locale's date format.
+func (m *LocaleMeta) FormatDate(t time.Time) string {
+	return t.Format(m.DateFormat)
+}
+
+// FormatDateTime formats a date-time using the locale's date-time format.
+func (m *LocaleMeta) FormatDateTime(t time.Time) string {
+	return t.Format(m.DateTimeFormat)
+}
+
+// buildLocaleMetas reads metadata of all available locales.
+func buildLocaleMetas(i18n goyai.I18n) map[string]*LocaleMeta {
+	result := make(map[string]*LocaleMeta)
+	for _, localeInfo := range i18n.AvailableLocales() {
+		id := localeInfo.Id
+		dir := textDirLtr
+		if rtlLanguages[id] {
+			dir = textDirRtl
+		}
+		if dir = localizeOrDefault(i18n, id, "locale_dir", dir); dir != textDirRtl {
+			dir = textDirLtr
+		}
+		result[id] = &LocaleMeta{
+			Id:             id,
+			Dir:            dir,
+			DateFormat:     localizeOrDefault(i18n, id, "locale_date_format", "2006-01-02"),
+			DateTimeFormat: localizeOrDefault(i18n, id, "locale_datetime_format", "2006-01-02 15:04:05"),
+		}
+	}
+	return result
+}
+
+// localizeOrDefault returns the localized message of a key, or defaultValue if the key is not defined.
+func localizeOrDefault(i18n goyai.I18n, locale, key, defaultValue string) string {
+	if msg := i18n.Localize(locale, key); msg != "" && msg != key {
+		return msg
+	}
+	return defaultValue
+}
+
+// getLocaleMeta returns metadata of the supplied locale, falling back to the default locale.
+func (r *myRegistry) getLocaleMeta(locale string) *LocaleMeta {
+	if meta, ok := r.localeMetas[locale]; ok {
+		return meta
+	}
+	if meta, ok := r.localeMetas[defaultLocale]; ok {
+		return meta
+	}
+	return &LocaleMeta{Id: defaultLocale, Dir: textDirLtr, DateFormat: "2006-01-02", DateTimeFormat: "2006-01-02 15:04:05"}
+}
+
+/*----------------------------------------------------------------------*/
+
+// layeredI18n serves translations overridden via the translation management page (stored in database, see MessageDao)
+// on top of the file-based i18n bundles.
+//
+// available since template-r5
+type layeredI18n struct {
+	goyai.I18n
+	dao       MessageDao
+	lock      sync.RWMutex
+	overrides map[string]*template.Template // overridden messages, indexed by Message.Id()
+}
+
+func newLayeredI18n(base goyai.I18n, dao MessageDao) (*layeredI18n, error) {
+	if layered, ok := base.(*layeredI18n); ok {
+		base = layered.I18n
+	}
+	i18n := &layeredI18n{I18n: base, dao: dao, overrides: make(map[string]*template.Template)}
+	msgList, err := dao.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, msg := range msgList {
+		if err := i18n.setOverride(msg); err != nil {
+			log.Printf("[WARN] ignored invalid translation override [%s]: %s", msg.Id(), err)
+		}
+	}
+	return i18n, nil
+}
+
+func (i *layeredI18n) setOverride(msg *Message) error {
+	tpl, err := template.New(msg.Id()).Parse(msg.Text)
+	if err != nil {
+		return err
+	}
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.overrides[msg.Id()] = tpl
+	return nil
+}
+
+// Localize overrides goyai.I18n.Localize: overridden messages take precedence over the ones of i18n bundles.
+func (i *layeredI18n) Localize(locale, msgId string, params ...interface{}) string {
+	lookupLocale := locale
+	if lookupLocale == "" {
+		lookupLocale = defaultLocale
+	}
+	i.lock.RLock()
+	tpl := i.overrides[(&Message{Locale: lookupLocale, Key: msgId}).Id()]
+	i.lock.RUnlock()
+	if tpl == nil {
+		return i.I18n.Localize(locale, msgId, params...)
+	}
+	var data interface{}
+	for _, p := range params {
+		if config, ok := p.(*goyai.LocalizeConfig); ok && config != nil {
+			data = config.TemplateData
+		}
+	}
+	buf := &bytes.Buffer{}
+	if err := tpl.Execute(buf, data); err != nil {
+		log.Printf("[WARN] error rendering translation override [%s:%s]: %s", lookupLocale, msgId, err)
+		return i.I18n.Localize(locale, msgId, params...)
+	}
+	return buf.String()
+}
+
+// Override stores a translation override in database and makes it effective immediately. An empty text removes the
+// override, i.e. the message of the i18n bundle is used again.
+func (i *layeredI18n) Override(locale, key, text string) error {
+	msg := &Message{Locale: locale, Key: key, Text: text}
+	if text == "" {
+		if _, err := i.dao.Delete(msg); err != nil {
+			return err
+		}
+		i.lock.Lock()
+		defer i.lock.Unlock()
+		delete(i.overrides, msg.Id())
+		return nil
+	}
+	if _, err := template.New(msg.Id()).Parse(text); err != nil {
+		return err
+	}
+	if _, err := i.dao.Save(msg); err != nil {
+		return err
+	}
+	return i.setOverride(msg)
+}
+
+// Overrides returns overridden texts, indexed by Message.Id().
+func (i *layeredI18n) Overrides() (map[string]string, error) {
+	msgList, err := i.dao.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]string, len(msgList))
+	for _, msg := range msgList {
+		result[msg.Id()] = msg.Text
+	}
+	return result, nil
+}
@@ -0,0 +1,53 @@
+package myapp
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestActionCpTranslations(t *testing.T) {
+	h := _newHarness(t)
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpTranslations)), http.StatusOK)
+	h.AssertTemplate(namespace + ":layout:cp_translations")
+	h.AssertBodyContains(h.Get(h.Reverse(actionNameCpTranslations)), "remember_login")
+
+	i18n := h.Registry.Get(namespace).(*myRegistry).i18n
+	h.AssertRedirect(h.PostForm(h.Reverse(actionNameCpTranslationsSubmit), url.Values{"locale": {"en"}, "key": {"home"}, "text": {"Start"}}), h.Reverse(actionNameCpTranslations))
+	if msg := i18n.Localize("en", "home"); msg != "Start" {
+		t.Fatalf("expected overridden translation [Start] but received [%s]", msg)
+	}
+	if msg := i18n.Localize("vi", "home"); msg == "Start" {
+		t.Fatalf("translation of other locales must not be overridden")
+	}
+
+	h.PostForm(h.Reverse(actionNameCpTranslationsSubmit), url.Values{"locale": {"en"}, "key": {"home"}, "text": {""}})
+	if msg := i18n.Localize("en", "home"); msg != "Home" {
+		t.Fatalf("expected translation [Home] of the i18n bundle but received [%s]", msg)
+	}
+}
+
+func TestI18n_Namespaces(t *testing.T) {
+	h := _newHarness(t)
+	i18n := h.Registry.I18n
+	testCases := []struct{ msgId, expected string }{
+		{"home", "Home"},                            // own namespace
+		{"error_http_404", "Page not found"},        // falls back to the common namespace
+		{namespace + ":home", "Home"},               // namespaced message id
+		{"common:error_http_404", "Page not found"}, // namespaced message id
+		{"common:home", "common:home"},              // namespaced message ids do not fall back
+		{"not_exist", "not_exist"},
+	}
+	for _, tc := range testCases {
+		if msg := i18n.Localize(namespace, "en", tc.msgId); msg != tc.expected {
+			t.Fatalf("%s: expected [%s] but received [%s]", tc.msgId, tc.expected, msg)
+		}
+	}
+	if msg := i18n.Namespace(namespace).Localize("vi", "error_http_404"); msg != "Không tìm thấy trang" {
+		t.Fatalf("expected message of the common namespace but received [%s]", msg)
+	}
+	if messages := i18n.Messages(namespace); messages["en"]["home"] != "Home" || messages["en"]["error_http_404"] != "Page not found" {
+		t.Fatalf("expected messages of both own and common namespaces")
+	}
+}
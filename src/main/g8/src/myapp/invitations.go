@@ -0,0 +1,454 @@
+package myapp
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/btnguyen2k/goyai"
+	"github.com/labstack/echo/v4"
+	"main/src/utils"
+)
+
+// settingPrefixGroupInvite prefixes ids of settings that store invitation links of groups, e.g.
+// "group_invite:<hash>".
+const settingPrefixGroupInvite = "group_invite:"
+
+const (
+	// groupInviteMaxDays is the maximum validity of an invitation link, in days.
+	groupInviteMaxDays = 90
+)
+
+var (
+	errGroupInviteSystemGroup = errors.New("invitation links cannot be created for the system group")
+	errGroupInviteUnusable    = errors.New("invitation link is invalid, expired or used up")
+)
+
+// GroupInvite is an invitation link of a group: people opening the link register themselves as members of the group.
+// Invitations are stored by the hash of their token, so that links cannot be rebuilt from the database.
+//
+// available since template-r5
+type GroupInvite struct {
+	Id        string    `json:"id"` // hash of the token
+	GroupId   string    `json:"gid"`
+	CreatedBy string    `json:"by"`
+	Created   time.Time `json:"created"`
+	Expires   time.Time `json:"expires"`
+	MaxUses   int       `json:"max_uses"` // 0 = unlimited
+	Uses      int       `json:"uses"`
+}
+
+// usable checks if the invitation can still be used to register at now.
+func (i *GroupInvite) usable(now time.Time) bool {
+	return now.Before(i.Expires) && (i.MaxUses <= 0 || i.Uses < i.MaxUses)
+}
+
+// CreatedStr returns the creation time in the application's timezone.
+func (i *GroupInvite) CreatedStr() string {
+	return i.Created.In(utils.Location).Format("2006-01-02 15:04:05")
+}
+
+// ExpiresStr returns the expiry time in the application's timezone.
+func (i *GroupInvite) ExpiresStr() string {
+	return i.Expires.In(utils.Location).Format("2006-01-02 15:04:05")
+}
+
+// Usable checks if the invitation can still be used to register.
+func (i *GroupInvite) Usable() bool {
+	return i.usable(utils.Now())
+}
+
+/*----------------------------------------------------------------------*/
+
+// groupInvitations lets admins create invitation links of groups (configuration block myapp.invitations): people
+// opening a link register themselves (self-registration) as members of the link's group, until the link expires, its
+// usage limit is reached or it is revoked. Links are managed on the group list (/cp/groups) and require permission
+// group.edit; links of the system group cannot be created.
+//
+// available since template-r5
+type groupInvitations struct {
+	r       *myRegistry
+	ttl     time.Duration // default validity of new links
+	baseUrl string        // base URL of links, empty to use the one of the request
+}
+
+// newGroupInvitations creates a groupInvitations from the configuration block myapp.invitations, nil is returned if
+// self-registration with invitation links is disabled.
+func newGroupInvitations(r *myRegistry) *groupInvitations {
+	conf := r.AppConfig
+	confPath := namespace + ".invitations"
+	if !conf.GetBoolean(confPath+".enabled", false) {
+		return nil
+	}
+	inv := &groupInvitations{
+		r:       r,
+		ttl:     conf.GetTimeDuration(confPath+".ttl", 7*24*time.Hour),
+		baseUrl: strings.TrimSuffix(conf.GetString(confPath+".base_url", ""), "/"),
+	}
+	if inv.ttl <= 0 || inv.ttl > groupInviteMaxDays*24*time.Hour {
+		inv.ttl = 7 * 24 * time.Hour
+	}
+	// links of deleted groups are of no use
+	r.onEvent(func(event *Event) {
+		groupId, _ := event.Data["group_id"].(string)
+		if err := inv.revokeAll(groupId); err != nil {
+			log.Printf("[WARN] cannot revoke invitation links of deleted group [%s]: %s", groupId, err)
+		}
+	}, eventGroupDeleted)
+	return inv
+}
+
+// defaultDays returns the default validity of new links, in days.
+func (inv *groupInvitations) defaultDays() int {
+	if days := int(inv.ttl / (24 * time.Hour)); days > 0 {
+		return days
+	}
+	return 1
+}
+
+// create creates an invitation link of a group, valid for days and usable maxUses times (0 = unlimited), and returns
+// its token.
+func (inv *groupInvitations) create(groupId, by string, days, maxUses int) (string, *GroupInvite, error) {
+	if groupId == systemGroupId {
+		return "", nil, errGroupInviteSystemGroup
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, err
+	}
+	token := hex.EncodeToString(buf)
+	now := utils.Now()
+	invite := &GroupInvite{
+		Id:        hashLoginToken(token),
+		GroupId:   groupId,
+		CreatedBy: by,
+		Created:   now,
+		Expires:   now.AddDate(0, 0, days),
+		MaxUses:   maxUses,
+	}
+	return token, invite, inv.r.saveSetting(settingPrefixGroupInvite+invite.Id, invite)
+}
+
+// get returns an invitation by id, nil if not found.
+func (inv *groupInvitations) get(id string) (*GroupInvite, error) {
+	invite := &GroupInvite{}
+	if found, err := inv.r.loadSetting(settingPrefixGroupInvite+id, invite); err != nil || !found {
+		return nil, err
+	}
+	return invite, nil
+}
+
+// list returns invitations, most recent first.
+func (inv *groupInvitations) list() ([]*GroupInvite, error) {
+	settings, err := inv.r.settingsWithPrefix(settingPrefixGroupInvite)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*GroupInvite, 0, len(settings))
+	for _, s := range settings {
+		invite := &GroupInvite{}
+		if err := json.Unmarshal([]byte(s.Value), invite); err != nil {
+			log.Printf("[WARN] cannot parse setting [%s]: %s", s.Id, err)
+			continue
+		}
+		result = append(result, invite)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Created.After(result[j].Created) })
+	return result, nil
+}
+
+// revoke deletes an invitation, the link can no longer be used.
+func (inv *groupInvitations) revoke(id string) (bool, error) {
+	return inv.r.settingDao.Delete(&Setting{Id: settingPrefixGroupInvite + id})
+}
+
+// revokeAll deletes invitations of a group.
+func (inv *groupInvitations) revokeAll(groupId string) error {
+	list, err := inv.list()
+	if err != nil {
+		return err
+	}
+	for _, invite := range list {
+		if invite.GroupId == groupId {
+			if _, err := inv.revoke(invite.Id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// lookup returns the invitation of a token if it can still be used, nil otherwise.
+func (inv *groupInvitations) lookup(token string) (*GroupInvite, error) {
+	if token == "" {
+		return nil, nil
+	}
+	invite, err := inv.get(hashLoginToken(token))
+	if err != nil || invite == nil || !invite.usable(utils.Now()) {
+		return nil, err
+	}
+	return invite, nil
+}
+
+// use registers with the invitation of a token: register is invoked with the invitation and, if it succeeds, the
+// usage of the invitation is counted. Registrations with the same invitation are serialized (also across instances),
+// so that usage limits hold.
+func (inv *groupInvitations) use(token string, register func(invite *GroupInvite) error) error {
+	id := hashLoginToken(token)
+	return inv.r.WithLock(namespace+":"+settingPrefixGroupInvite+id, 10*time.Second, func() error {
+		invite, err := inv.lookup(token)
+		if err != nil {
+			return err
+		}
+		if invite == nil {
+			return errGroupInviteUnusable
+		}
+		if err := register(invite); err != nil {
+			return err
+		}
+		invite.Uses++
+		return inv.r.saveSetting(settingPrefixGroupInvite+id, invite)
+	})
+}
+
+// link returns the URL of the invitation link of a token.
+func (inv *groupInvitations) link(c echo.Context, token string) string {
+	baseUrl := inv.baseUrl
+	if baseUrl == "" {
+		baseUrl = c.Scheme() + "://" + c.Request().Host
+	}
+	return baseUrl + c.Echo().Reverse(actionNameJoin) + "?token=" + url.QueryEscape(token)
+}
+
+// purgeExpiredGroupInvites deletes invitations expired at now, also if invitation links have been disabled since
+// they were created.
+func purgeExpiredGroupInvites(r *myRegistry, now time.Time) (int, error) {
+	list, err := r.settingsWithPrefix(settingPrefixGroupInvite)
+	if err != nil {
+		return 0, err
+	}
+	numDeleted := 0
+	for _, s := range list {
+		invite := &GroupInvite{}
+		if err := json.Unmarshal([]byte(s.Value), invite); err == nil && invite.Expires.After(now) {
+			continue
+		}
+		if _, err := r.settingDao.Delete(s); err != nil {
+			return numDeleted, err
+		}
+		numDeleted++
+	}
+	return numDeleted, nil
+}
+
+/*----------------------------------------------------------------------*/
+
+// GroupInviteModel is an invitation link to be shown on the group list.
+//
+// available since template-r5
+type GroupInviteModel struct {
+	*GroupInvite
+	GroupName string
+}
+
+// groupInviteModels returns invitation links to be shown on the group list, nil if the current user cannot manage
+// them.
+func groupInviteModels(c echo.Context) []*GroupInviteModel {
+	inv := getRegistry(c).invitations
+	if inv == nil || !can(c, permGroupEdit) {
+		return nil
+	}
+	list, err := inv.list()
+	if err != nil {
+		log.Printf("[ERROR] cannot load invitation links: %s", err)
+	}
+	names := make(map[string]string)
+	if groups, err := getRegistry(c).allGroups(); err == nil {
+		for _, g := range groups {
+			names[g.Id] = g.Name
+		}
+	}
+	result := make([]*GroupInviteModel, 0, len(list))
+	for _, invite := range list {
+		result = append(result, &GroupInviteModel{GroupInvite: invite, GroupName: names[invite.GroupId]})
+	}
+	return result
+}
+
+// groupListData returns data of the group list: groups and, if enabled, invitation links.
+func groupListData(c echo.Context) map[string]interface{} {
+	u := &MyAppUtils{c: c}
+	data := map[string]interface{}{
+		"active":     "groups",
+		"userGroups": u.AllUserGroups(),
+	}
+	if inv := getRegistry(c).invitations; inv != nil && can(c, permGroupEdit) {
+		data["invitesEnabled"] = true
+		data["invites"] = groupInviteModels(c)
+		data["inviteDays"] = inv.defaultDays()
+		data["inviteMaxDays"] = groupInviteMaxDays
+	}
+	return data
+}
+
+// actionCpGroupInviteSubmit creates an invitation link (form fields "group", "days" and "max_uses") and renders the
+// group list with the link: it is shown only once.
+//
+// available since template-r5
+func actionCpGroupInviteSubmit(c echo.Context) error {
+	myReg := getRegistry(c)
+	if err := checkPermission(c, permGroupEdit); err != nil {
+		AddFlashText(c, FlashWarning, err.Error())
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpGroups)+"?r="+utils.RandomString(4))
+	}
+	groupId := strings.ToLower(strings.TrimSpace(c.FormValue("group")))
+	days, _ := strconv.Atoi(c.FormValue("days"))
+	maxUses, _ := strconv.Atoi(c.FormValue("max_uses"))
+	if days <= 0 || days > groupInviteMaxDays {
+		days = myReg.invitations.defaultDays()
+	}
+	if maxUses < 0 {
+		maxUses = 0
+	}
+	group, err := getGroupDao(c).Get(groupId)
+	if err != nil {
+		AddFlash(c, FlashError, "error_db_301", "err", groupId+"/"+err.Error())
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpGroups)+"?r="+utils.RandomString(4))
+	}
+	if group == nil {
+		AddFlash(c, FlashError, "error_group_not_found", "group", groupId)
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpGroups)+"?r="+utils.RandomString(4))
+	}
+	currentUser, _ := c.Get(ctxCurrentUser).(*User)
+	token, invite, err := myReg.invitations.create(group.Id, currentUser.Username, days, maxUses)
+	if err != nil {
+		AddFlash(c, FlashError, "error_group_invite", "err", err.Error())
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpGroups)+"?r="+utils.RandomString(4))
+	}
+	myReg.auditf("user [%s] created invitation link [%s] of group [%s] (expires %s, max uses %d)",
+		currentUser.Username, invite.Id, group.Id, invite.ExpiresStr(), invite.MaxUses)
+	data := groupListData(c)
+	data["newInviteLink"] = myReg.invitations.link(c, token)
+	return c.Render(http.StatusOK, namespace+":layout:cp_groups:cp_fragments", data)
+}
+
+// actionCpGroupInviteRevokeSubmit revokes an invitation link (form field "id").
+//
+// available since template-r5
+func actionCpGroupInviteRevokeSubmit(c echo.Context) error {
+	myReg := getRegistry(c)
+	if err := checkPermission(c, permGroupEdit); err != nil {
+		AddFlashText(c, FlashWarning, err.Error())
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpGroups)+"?r="+utils.RandomString(4))
+	}
+	id := c.FormValue("id")
+	if ok, err := myReg.invitations.revoke(id); err != nil {
+		AddFlash(c, FlashError, "error_db_001", "err", settingPrefixGroupInvite+id+"/"+err.Error())
+	} else if ok {
+		currentUser, _ := c.Get(ctxCurrentUser).(*User)
+		myReg.auditf("user [%s] revoked invitation link [%s]", currentUser.Username, id)
+		AddFlash(c, FlashInfo, "group_invite_revoked")
+	}
+	return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpGroups)+"?r="+utils.RandomString(4))
+}
+
+/*----------------------------------------------------------------------*/
+
+// renderJoin renders the registration page of invitation links.
+func renderJoin(c echo.Context, data map[string]interface{}) error {
+	data["botGuard"] = getRegistry(c).botGuardForm()
+	return c.Render(http.StatusOK, namespace+":join", data)
+}
+
+// actionJoin renders the page invitation links point to: people register as members of the link's group.
+//
+// available since template-r5
+func actionJoin(c echo.Context) error {
+	myReg := getRegistry(c)
+	token := c.QueryParam("token")
+	invite, err := myReg.invitations.lookup(token)
+	if err != nil {
+		log.Printf("[ERROR] cannot look up invitation link: %s", err)
+	}
+	if invite == nil {
+		return renderJoin(c, map[string]interface{}{
+			"error": getI18n(c).Localize(getContextString(c, ctxLocale), "error_group_invite_invalid"),
+		})
+	}
+	return renderJoin(c, map[string]interface{}{"token": token, "form": url.Values{}})
+}
+
+// actionJoinSubmit registers a user with an invitation link (form fields "token", "username", "name", "password" and
+// "password2") and signs the user in.
+//
+// available since template-r5
+func actionJoinSubmit(c echo.Context) error {
+	myReg := getRegistry(c)
+	locale := getContextString(c, ctxLocale)
+	formData, _ := c.FormParams()
+	token := formData.Get("token")
+	user := &User{
+		Username: strings.ToLower(strings.TrimSpace(formData.Get("username"))),
+		Name:     strings.TrimSpace(formData.Get("name")),
+	}
+	pwd, pwd2 := strings.TrimSpace(formData.Get("password")), strings.TrimSpace(formData.Get("password2"))
+	var errMsg string
+	switch {
+	case myReg.isReadOnly():
+		errMsg = getI18n(c).Localize(locale, "read_only_banner")
+	case !reEmail.MatchString(user.Username):
+		errMsg = getI18n(c).Localize(locale, "error_join_username")
+	case pwd == "":
+		errMsg = getI18n(c).Localize(locale, "error_empty_user_password")
+	case pwd != pwd2:
+		errMsg = getI18n(c).Localize(locale, "error_mismatched_passwords")
+	default:
+		errMsg = checkPasswordBreach(c, pwd)
+	}
+	if errMsg != "" {
+		return renderJoin(c, map[string]interface{}{"token": token, "form": formData, "error": errMsg})
+	}
+	if user.Name == "" {
+		user.Name = user.Username
+	}
+	user.Password = encryptPassword(user.Username, pwd)
+
+	err := myReg.invitations.use(token, func(invite *GroupInvite) error {
+		if existing, err := getUserDao(c).Get(user.Username); err != nil {
+			return err
+		} else if existing != nil {
+			errMsg = getI18n(c).Localize(locale, "error_user_existed", &goyai.LocalizeConfig{
+				TemplateData: map[string]interface{}{"user": user.Username},
+			})
+			return errors.New(errMsg)
+		}
+		user.GroupId = invite.GroupId
+		_, err := getUserDao(c).Create(user.Username, user.Password, user.Name, user.GroupId)
+		return err
+	})
+	switch {
+	case errors.Is(err, errGroupInviteUnusable):
+		return renderJoin(c, map[string]interface{}{
+			"error": getI18n(c).Localize(locale, "error_group_invite_invalid"),
+		})
+	case err != nil && errMsg == "":
+		log.Printf("[ERROR] cannot register user [%s] with invitation link: %s", user.Username, err)
+		errMsg = getI18n(c).Localize(locale, "error_db_121", &goyai.LocalizeConfig{
+			TemplateData: map[string]interface{}{"err": user.Username + "/" + err.Error()},
+		})
+		fallthrough
+	case err != nil:
+		return renderJoin(c, map[string]interface{}{"token": token, "form": formData, "error": errMsg})
+	}
+	myReg.recordDailyStat(statSignups)
+	myReg.auditf("user [%s] registered with an invitation link of group [%s] from %s", user.Username, user.GroupId, clientOrigin(c))
+	return completeSignIn(c, user, "invitation link")
+}
@@ -0,0 +1,82 @@
+package myapp
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"main/src/apptest"
+)
+
+func TestGroupInvitations(t *testing.T) {
+	testName := "TestGroupInvitations"
+	h := apptest.New(t, apptest.SqliteInMemoryConfig+"\nmyapp.invitations.enabled = true\n", NewBootstrapper(nil, nil))
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	myReg.groupDao.Create("staff", "Staff")
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+
+	// the system group cannot be targeted
+	h.AssertRedirect(h.PostForm(h.Reverse(actionNameCpGroupInviteSubmit), url.Values{"group": {systemGroupId}}), h.Reverse(actionNameCpGroups))
+	if list, _ := myReg.invitations.list(); len(list) != 0 {
+		t.Fatalf("%s failed: no invitation link expected for the system group, received %#v", testName, list)
+	}
+
+	// the link is shown once, only the hash of its token is stored
+	form := url.Values{"group": {"staff"}, "days": {"3"}, "max_uses": {"1"}}
+	h.AssertStatus(h.PostForm(h.Reverse(actionNameCpGroupInviteSubmit), form), http.StatusOK)
+	link, _ := h.LastData()["newInviteLink"].(string)
+	m := regexp.MustCompile(`\?token=([0-9a-zA-Z]+)$`).FindStringSubmatch(link)
+	if m == nil {
+		t.Fatalf("%s failed: unexpected invitation link [%s]", testName, link)
+	}
+	token := m[1]
+	list, _ := myReg.invitations.list()
+	if len(list) != 1 || list[0].GroupId != "staff" || list[0].MaxUses != 1 || strings.Contains(list[0].Id, token) {
+		t.Fatalf("%s failed: unexpected invitation links %#v", testName, list)
+	}
+	if d := time.Until(list[0].Expires); d < 71*time.Hour || d > 73*time.Hour {
+		t.Fatalf("%s failed: invitation link must expire in 3 days, received %s", testName, list[0].ExpiresStr())
+	}
+	h.PostForm(h.Reverse(actionNameCpLogout), url.Values{})
+
+	// people register as members of the group and are signed in
+	h.AssertStatus(h.Get(h.Reverse(actionNameJoin)+"?token="+token), http.StatusOK)
+	if h.LastData()["token"] != token {
+		t.Fatalf("%s failed: join form expected", testName)
+	}
+	join := url.Values{"token": {token}, "username": {"new@local"}, "name": {"Newbie"}, "password": {"N3wbie!"}, "password2": {"N3wbie!"}}
+	h.AssertStatus(h.PostForm(h.Reverse(actionNameJoinSubmit), url.Values{"token": {token}, "username": {"not an email"}, "password": {"x"}, "password2": {"x"}}), http.StatusOK)
+	h.AssertStatus(h.PostForm(h.Reverse(actionNameJoinSubmit), join), http.StatusFound)
+	if user, _ := myReg.userDao.Get("new@local"); user == nil || user.GroupId != "staff" || user.Name != "Newbie" {
+		t.Fatalf("%s failed: expected user registered into group staff, received %#v", testName, user)
+	}
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpDashboard)), http.StatusOK)
+	h.PostForm(h.Reverse(actionNameCpLogout), url.Values{})
+
+	// the link is used up
+	join.Set("username", "other@local")
+	h.AssertStatus(h.PostForm(h.Reverse(actionNameJoinSubmit), join), http.StatusOK)
+	if user, _ := myReg.userDao.Get("other@local"); user != nil {
+		t.Fatalf("%s failed: used up invitation link must not register users, received %#v", testName, user)
+	}
+	h.AssertStatus(h.Get(h.Reverse(actionNameJoin)+"?token="+token), http.StatusOK)
+	if h.LastData()["token"] != nil {
+		t.Fatalf("%s failed: used up invitation link must not show the join form", testName)
+	}
+
+	// revoked links and links of deleted groups are removed
+	token, _, _ = myReg.invitations.create("staff", testAdminUsername, 1, 0)
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	h.AssertRedirect(h.PostForm(h.Reverse(actionNameCpGroupInviteRevoke), url.Values{"id": {hashLoginToken(token)}}), h.Reverse(actionNameCpGroups))
+	if invite, _ := myReg.invitations.lookup(token); invite != nil {
+		t.Fatalf("%s failed: revoked invitation link must be unusable, received %#v", testName, invite)
+	}
+	myReg.invitations.create("staff", testAdminUsername, 1, 0)
+	myReg.groupDao.Delete(&Group{Id: "staff"})
+	if list, _ = myReg.invitations.list(); len(list) != 0 {
+		t.Fatalf("%s failed: invitation links of deleted group must be removed, received %#v", testName, list)
+	}
+}
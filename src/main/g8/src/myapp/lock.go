@@ -0,0 +1,85 @@
+package myapp
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	prom "github.com/btnguyen2k/prom/sql"
+)
+
+// sqlTableLock stores locks acquired via goadmin.Registry.WithLock when the application runs on a SQL backend.
+const sqlTableLock = namespace + "_lock"
+
+// sqlSchemaTableLock returns the DDL of the lock table, it is the same for all SQL flavors.
+func sqlSchemaTableLock() string {
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (lock_id VARCHAR(128), lock_owner VARCHAR(128), expires_at BIGINT, PRIMARY KEY (lock_id))", sqlTableLock)
+}
+
+// newSqlLocker creates a goadmin.Locker whose locks are rows of the lock table, so that they are shared by all
+// replicas using the same database.
+func newSqlLocker(db *sql.DB, flavor prom.DbFlavor) *sqlLocker {
+	p1, p2, p3 := sqlPlaceholder(flavor, 1), sqlPlaceholder(flavor, 2), sqlPlaceholder(flavor, 3)
+	return &sqlLocker{
+		db:           db,
+		sqlDeleteOld: fmt.Sprintf("DELETE FROM %s WHERE lock_id=%s AND expires_at<%s", sqlTableLock, p1, p2),
+		sqlInsert:    fmt.Sprintf("INSERT INTO %s (lock_id, lock_owner, expires_at) VALUES (%s, %s, %s)", sqlTableLock, p1, p2, p3),
+		sqlCount:     fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE lock_id=%s", sqlTableLock, p1),
+		sqlRefresh:   fmt.Sprintf("UPDATE %s SET expires_at=%s WHERE lock_id=%s AND lock_owner=%s AND expires_at>=%s", sqlTableLock, p1, p2, p3, sqlPlaceholder(flavor, 4)),
+		sqlRelease:   fmt.Sprintf("DELETE FROM %s WHERE lock_id=%s AND lock_owner=%s", sqlTableLock, p1, p2),
+		sqlPurge:     fmt.Sprintf("DELETE FROM %s WHERE expires_at<%s", sqlTableLock, p1),
+	}
+}
+
+// sqlLocker is the goadmin.Locker of SQL backends.
+type sqlLocker struct {
+	db                                                        *sql.DB
+	sqlDeleteOld, sqlInsert, sqlCount, sqlRefresh, sqlRelease string
+	sqlPurge                                                  string
+}
+
+// TryLock implements goadmin.Locker.TryLock
+func (l *sqlLocker) TryLock(name, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	// expired locks are left over by instances that died while holding them
+	if _, err := l.db.Exec(l.sqlDeleteOld, name, now.UnixNano()); err != nil {
+		return false, err
+	}
+	if _, err := l.db.Exec(l.sqlInsert, name, owner, now.Add(ttl).UnixNano()); err != nil {
+		// the insert fails with a duplicated key error while another owner holds the lock, any other error is reported
+		var count int
+		if errCount := l.db.QueryRow(l.sqlCount, name).Scan(&count); errCount != nil || count == 0 {
+			return false, err
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
+// Refresh implements goadmin.Locker.Refresh
+func (l *sqlLocker) Refresh(name, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	result, err := l.db.Exec(l.sqlRefresh, now.Add(ttl).UnixNano(), name, owner, now.UnixNano())
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// Unlock implements goadmin.Locker.Unlock
+func (l *sqlLocker) Unlock(name, owner string) error {
+	_, err := l.db.Exec(l.sqlRelease, name, owner)
+	return err
+}
+
+// PurgeExpired implements goadmin.ExpiredPurger.PurgeExpired: locks of names that are not acquired again (e.g. one-off
+// jobs) would stay in the table forever otherwise.
+func (l *sqlLocker) PurgeExpired(now time.Time) (int, error) {
+	result, err := l.db.Exec(l.sqlPurge, now.UnixNano())
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
@@ -0,0 +1,59 @@
+package myapp
+
+import (
+	"testing"
+	"time"
+
+	"main/src/goadmin"
+)
+
+func TestSqlLocker(t *testing.T) {
+	testName := "TestSqlLocker"
+	h := _newHarness(t)
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	if _, ok := myReg.Locker.(*sqlLocker); !ok {
+		t.Fatalf("%s failed: expected locks to be kept in database but locker is %T", testName, myReg.Locker)
+	}
+	err := myReg.WithLock("test", time.Minute, func() error {
+		if ok, err := myReg.TryWithLock("test", time.Minute, func() error { return nil }); ok || err != nil {
+			t.Fatalf("%s failed: lock must not be acquired twice (%v / %v)", testName, ok, err)
+		}
+		if err := myReg.WithLock("test", 300*time.Millisecond, func() error { return nil }); err != goadmin.ErrLockTimeout {
+			t.Fatalf("%s failed: expected error [%s] but received [%v]", testName, goadmin.ErrLockTimeout, err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("%s failed: %s", testName, err)
+	}
+	if ok, err := myReg.TryWithLock("test", time.Minute, func() error { return nil }); !ok || err != nil {
+		t.Fatalf("%s failed: lock must be released (%v / %v)", testName, ok, err)
+	}
+}
+
+func TestLeaderElection_SqlLocker(t *testing.T) {
+	testName := "TestLeaderElection_SqlLocker"
+	h := _newHarness(t)
+	if !h.Registry.IsLeader() {
+		t.Fatalf("%s failed: single instance must be the leader", testName)
+	}
+	h.Registry.Leader.Stop()
+
+	locker := h.Registry.Locker
+	first, second := goadmin.NewLeaderElection(locker, time.Minute), goadmin.NewLeaderElection(locker, time.Minute)
+	first.Start()
+	defer first.Stop()
+	second.Start()
+	defer second.Stop()
+	if !first.IsLeader() || second.IsLeader() {
+		t.Fatalf("%s failed: expected exactly one leader (%v / %v)", testName, first.IsLeader(), second.IsLeader())
+	}
+	first.Stop()
+	second.Stop()
+	third := goadmin.NewLeaderElection(locker, time.Minute)
+	third.Start()
+	defer third.Stop()
+	if !third.IsLeader() {
+		t.Fatalf("%s failed: leadership must be taken over after the leader stopped", testName)
+	}
+}
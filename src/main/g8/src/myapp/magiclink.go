@@ -0,0 +1,280 @@
+package myapp
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/btnguyen2k/goyai"
+	"github.com/labstack/echo/v4"
+	"main/src/goadmin"
+	"main/src/utils"
+)
+
+// settingPrefixLoginToken prefixes ids of settings that store one-time login tokens, e.g. "login_token:<hash>".
+const settingPrefixLoginToken = "login_token:"
+
+// LoginToken is a one-time token of a sign-in link, see magicLinkLogin.
+//
+// available since template-r5
+type LoginToken struct {
+	Username string    `json:"username"`
+	Expires  time.Time `json:"expires"`
+}
+
+// loginTokenDao stores one-time login tokens. Tokens are stored by their hash, so that tokens cannot be read from the
+// database.
+//
+// available since template-r5
+type loginTokenDao interface {
+	// Put stores a token.
+	Put(hash string, token *LoginToken) error
+	// Take removes a token and returns it, nil if not found (e.g. already taken): a token can be taken only once.
+	Take(hash string) (*LoginToken, error)
+	// PurgeExpired removes tokens expired at now and returns the number of removed ones.
+	PurgeExpired(now time.Time) (int, error)
+}
+
+// settingLoginTokenDao is a loginTokenDao keeping tokens as settings, so that they are persisted in the application's
+// database whatever its type.
+type settingLoginTokenDao struct {
+	r *myRegistry
+}
+
+// Put implements loginTokenDao.Put
+func (dao *settingLoginTokenDao) Put(hash string, token *LoginToken) error {
+	return dao.r.saveSetting(settingPrefixLoginToken+hash, token)
+}
+
+// Take implements loginTokenDao.Take
+func (dao *settingLoginTokenDao) Take(hash string) (*LoginToken, error) {
+	setting, err := dao.r.settingDao.Get(settingPrefixLoginToken + hash)
+	if err != nil || setting == nil {
+		return nil, err
+	}
+	// the token is taken by whoever deletes it
+	if ok, err := dao.r.settingDao.Delete(setting); err != nil || !ok {
+		return nil, err
+	}
+	token := &LoginToken{}
+	return token, json.Unmarshal([]byte(setting.Value), token)
+}
+
+// PurgeExpired implements loginTokenDao.PurgeExpired
+func (dao *settingLoginTokenDao) PurgeExpired(now time.Time) (int, error) {
+	list, err := dao.r.settingsWithPrefix(settingPrefixLoginToken)
+	if err != nil {
+		return 0, err
+	}
+	numDeleted := 0
+	for _, s := range list {
+		token := &LoginToken{}
+		if err := json.Unmarshal([]byte(s.Value), token); err == nil && token.Expires.After(now) {
+			continue
+		}
+		if _, err := dao.r.settingDao.Delete(s); err != nil {
+			return numDeleted, err
+		}
+		numDeleted++
+	}
+	return numDeleted, nil
+}
+
+/*----------------------------------------------------------------------*/
+
+// magicLinkLogin lets users sign in without password (configuration block myapp.magic_link): they enter their email
+// address (username) and receive a sign-in link carrying a one-time token that expires after ttl. Links are sent via
+// the outbox (see goadmin.OutboxChannelEmail), hence emails must be enabled (setting goadmin.smtp.addr).
+//
+// Opening the link shows a confirmation button rather than signing in right away: mail scanners following links must
+// not consume the token.
+//
+// available since template-r5
+type magicLinkLogin struct {
+	r        *myRegistry
+	dao      loginTokenDao
+	ttl      time.Duration
+	interval time.Duration // minimum interval between two links sent to the same user
+	baseUrl  string        // base URL of links, never taken from the request: Host headers can be forged
+}
+
+// newMagicLinkLogin creates a magicLinkLogin from the configuration block myapp.magic_link, nil is returned if
+// sign-in links are disabled.
+//
+// Setting myapp.magic_link.base_url is required: the sign-in form is public, links built from the Host header of its
+// requests would let anyone send a user a valid token pointing to another host.
+func newMagicLinkLogin(r *myRegistry) (*magicLinkLogin, error) {
+	conf := r.AppConfig
+	if !conf.GetBoolean(namespace+".magic_link.enabled", false) {
+		return nil, nil
+	}
+	baseUrl := strings.TrimSuffix(strings.TrimSpace(conf.GetString(namespace+".magic_link.base_url", "")), "/")
+	if u, err := url.Parse(baseUrl); baseUrl == "" || err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("setting [%s.magic_link.base_url] must be the absolute URL of the application (e.g. https://admin.example.com) to enable sign-in links", namespace)
+	}
+	return &magicLinkLogin{
+		r:        r,
+		dao:      &settingLoginTokenDao{r: r},
+		ttl:      conf.GetTimeDuration(namespace+".magic_link.ttl", 15*time.Minute),
+		interval: conf.GetTimeDuration(namespace+".magic_link.resend_interval", time.Minute),
+		baseUrl:  baseUrl,
+	}, nil
+}
+
+// available checks if sign-in links can be sent.
+func (m *magicLinkLogin) available() bool {
+	return m != nil && m.r.Outbox.HasSender(goadmin.OutboxChannelEmail)
+}
+
+func hashLoginToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issue creates a token for the user and returns it.
+func (m *magicLinkLogin) issue(username string) (string, error) {
+	if _, err := m.dao.PurgeExpired(utils.Now()); err != nil {
+		log.Printf("[WARN] cannot purge expired login tokens: %s", err)
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+	return token, m.dao.Put(hashLoginToken(token), &LoginToken{Username: username, Expires: utils.Now().Add(m.ttl)})
+}
+
+// redeem consumes a token and returns the username it was issued for, empty if the token is unknown, already used or
+// expired.
+func (m *magicLinkLogin) redeem(token string) (string, error) {
+	if token == "" {
+		return "", nil
+	}
+	t, err := m.dao.Take(hashLoginToken(token))
+	if err != nil || t == nil || utils.Now().After(t.Expires) {
+		return "", err
+	}
+	return t.Username, nil
+}
+
+// send emails a sign-in link to the user, unless one has been sent within the resend interval.
+func (m *magicLinkLogin) send(c echo.Context, user *User) error {
+	cacheKey := namespace + ":magic_link_sent:" + strings.ToLower(user.Username)
+	if v, err := m.r.Cache.Get(cacheKey); err == nil && v != nil {
+		log.Printf("[LOGIN] sign-in link for user [%s] requested from %s, not sent: one has been sent recently", user.Username, clientOrigin(c))
+		return nil
+	}
+	token, err := m.issue(user.Username)
+	if err != nil {
+		return err
+	}
+	link := m.baseUrl + c.Echo().Reverse(actionNameCpLoginLinkConfirm) + "?token=" + url.QueryEscape(token)
+	locale := getContextString(c, ctxLocale)
+	tplData := &goyai.LocalizeConfig{TemplateData: map[string]interface{}{"link": link, "minutes": int(m.ttl.Minutes())}}
+	email := &goadmin.EmailPayload{
+		To:      []string{user.Username},
+		Subject: m.r.AppConfig.GetString("app.name") + ": " + m.r.i18n.Localize(locale, "login_link_email_subject"),
+		Body:    m.r.i18n.Localize(locale, "login_link_email_body", tplData),
+	}
+	if _, err := m.r.Outbox.Enqueue(goadmin.OutboxChannelEmail, email); err != nil {
+		return err
+	}
+	if m.interval > 0 {
+		m.r.Cache.Set(cacheKey, []byte("1"), m.interval)
+	}
+	log.Printf("[LOGIN] sign-in link for user [%s] requested from %s, sent", user.Username, clientOrigin(c))
+	return nil
+}
+
+/*----------------------------------------------------------------------*/
+
+// actionCpLoginLink renders the form to request a sign-in link.
+//
+// available since template-r5
+func actionCpLoginLink(c echo.Context) error {
+	if !getRegistry(c).magicLink.available() {
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpLogin))
+	}
+	return c.Render(http.StatusOK, namespace+":login_link", map[string]interface{}{
+		"botGuard": getRegistry(c).botGuardForm(),
+	})
+}
+
+// actionCpLoginLinkSubmit emails a sign-in link to the submitted username. The same message is shown whether the
+// user exists or not, so that the form cannot be used to find out usernames.
+//
+// available since template-r5
+func actionCpLoginLinkSubmit(c echo.Context) error {
+	myReg := getRegistry(c)
+	if !myReg.magicLink.available() {
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpLogin))
+	}
+	username := strings.TrimSpace(c.FormValue("username"))
+	data := map[string]interface{}{"botGuard": myReg.botGuardForm()}
+	user, err := getUserDao(c).Get(username)
+	if err == nil && user != nil && strings.Contains(user.Username, "@") {
+		err = myReg.magicLink.send(c, user)
+	}
+	if err != nil {
+		log.Printf("[ERROR] cannot send sign-in link to user [%s]: %s", username, err)
+		data["error"] = getI18n(c).Localize(getContextString(c, ctxLocale), "error_login_link_failed")
+	} else {
+		data["sent"] = true
+	}
+	return c.Render(http.StatusOK, namespace+":login_link", data)
+}
+
+// actionCpLoginLinkConfirm renders the page sign-in links point to: the user confirms signing in, see
+// actionCpLoginLinkConfirmSubmit.
+//
+// available since template-r5
+func actionCpLoginLinkConfirm(c echo.Context) error {
+	if !getRegistry(c).magicLink.available() {
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpLogin))
+	}
+	return c.Render(http.StatusOK, namespace+":login_link", map[string]interface{}{
+		"token":     c.QueryParam("token"),
+		"loginPage": getRegistry(c).loginPage(),
+	})
+}
+
+// actionCpLoginLinkConfirmSubmit consumes the token of a sign-in link and signs the user in.
+//
+// available since template-r5
+func actionCpLoginLinkConfirmSubmit(c echo.Context) error {
+	myReg := getRegistry(c)
+	if !myReg.magicLink.available() {
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpLogin))
+	}
+	if !myReg.checkLoginNotice(c) {
+		// the token is not redeemed, so that the user can accept the notice and use the link again
+		return c.Render(http.StatusOK, namespace+":login_link", map[string]interface{}{
+			"token":     c.FormValue("token"),
+			"loginPage": myReg.loginPage(),
+			"error":     getI18n(c).Localize(getContextString(c, ctxLocale), "error_login_notice_not_accepted"),
+		})
+	}
+	var user *User
+	username, err := myReg.magicLink.redeem(c.FormValue("token"))
+	if err == nil && username != "" {
+		user, err = getUserDao(c).Get(username)
+	}
+	if err != nil {
+		log.Printf("[ERROR] cannot redeem sign-in link: %s", err)
+	}
+	if user == nil {
+		log.Printf("[LOGIN] invalid or expired sign-in link used from %s", clientOrigin(c))
+		return c.Render(http.StatusOK, namespace+":login_link", map[string]interface{}{
+			"botGuard": myReg.botGuardForm(),
+			"error":    getI18n(c).Localize(getContextString(c, ctxLocale), "error_login_link_invalid"),
+		})
+	}
+	return completeSignIn(c, user, "email link")
+}
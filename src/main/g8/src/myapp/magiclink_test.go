@@ -0,0 +1,89 @@
+package myapp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"main/src/apptest"
+	"main/src/goadmin"
+)
+
+func TestMagicLinkLogin(t *testing.T) {
+	testName := "TestMagicLinkLogin"
+	conf := apptest.SqliteInMemoryConfig + "\ngoadmin.smtp.addr = \"127.0.0.1:1\"\nmyapp.magic_link.enabled = true\nmyapp.magic_link.base_url = \"https://admin.example.com\"\n"
+	h := apptest.New(t, conf, NewBootstrapper(nil, nil))
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	emails := func() []*goadmin.EmailPayload {
+		msgs, _ := myReg.Outbox.Messages(goadmin.OutboxChannelEmail)
+		result := make([]*goadmin.EmailPayload, len(msgs))
+		for i, msg := range msgs {
+			result[i] = &goadmin.EmailPayload{}
+			json.Unmarshal(msg.Payload, result[i])
+		}
+		return result
+	}
+
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpLogin)), http.StatusOK)
+	if h.LastData()["loginLink"] != true {
+		t.Fatalf("%s failed: login form does not offer sign-in links", testName)
+	}
+	// unknown users are answered the same way, no email is sent
+	h.AssertStatus(h.PostForm(h.Reverse(actionNameCpLoginLinkSubmit), url.Values{"username": {"nobody@local"}}), http.StatusOK)
+	if h.LastData()["sent"] != true || len(emails()) != 0 {
+		t.Fatalf("%s failed: unexpected emails %#v", testName, emails())
+	}
+	h.AssertStatus(h.PostForm(h.Reverse(actionNameCpLoginLinkSubmit), url.Values{"username": {testAdminUsername}}), http.StatusOK)
+	// a single link is sent within the resend interval
+	h.AssertStatus(h.PostForm(h.Reverse(actionNameCpLoginLinkSubmit), url.Values{"username": {testAdminUsername}}), http.StatusOK)
+	sent := emails()
+	if len(sent) != 1 || sent[0].To[0] != testAdminUsername {
+		t.Fatalf("%s failed: unexpected emails %#v", testName, sent)
+	}
+	m := regexp.MustCompile(`https://admin\.example\.com\S*\?token=([0-9a-f]+)`).FindStringSubmatch(sent[0].Body)
+	if m == nil {
+		t.Fatalf("%s failed: no sign-in link in email %q", testName, sent[0].Body)
+	}
+	token := m[1]
+	if list, _ := myReg.settingsWithPrefix(settingPrefixLoginToken); len(list) != 1 || strings.Contains(list[0].Id, token) {
+		t.Fatalf("%s failed: tokens must be stored hashed %#v", testName, list)
+	}
+
+	// opening the link does not consume the token
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpLoginLinkConfirm)+"?token="+token), http.StatusOK)
+	h.AssertData("token", token)
+	h.AssertRedirect(h.PostForm(h.Reverse(actionNameCpLoginLinkConfirmSubmit), url.Values{"token": {token}}), h.Reverse(actionNameCpDashboard))
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpDashboard)), http.StatusOK)
+	h.AssertTemplate(namespace + ":layout:cp_dashboard:cp_fragments")
+
+	// tokens are one-time
+	h.AssertStatus(h.PostForm(h.Reverse(actionNameCpLoginLinkConfirmSubmit), url.Values{"token": {token}}), http.StatusOK)
+	if h.LastData()["error"] == nil {
+		t.Fatalf("%s failed: token used twice", testName)
+	}
+	// expired tokens are rejected and purged
+	expired, _ := myReg.magicLink.issue(testAdminUsername)
+	myReg.magicLink.dao.Put(hashLoginToken(expired), &LoginToken{Username: testAdminUsername, Expires: time.Now().Add(-time.Second)})
+	if username, _ := myReg.magicLink.redeem(expired); username != "" {
+		t.Fatalf("%s failed: expired token accepted", testName)
+	}
+	myReg.magicLink.issue(testAdminUsername)
+	myReg.magicLink.dao.Put(hashLoginToken("old"), &LoginToken{Username: testAdminUsername, Expires: time.Now().Add(-time.Second)})
+	if n, _ := myReg.magicLink.dao.PurgeExpired(time.Now()); n != 1 {
+		t.Fatalf("%s failed: expected 1 expired token purged but %d", testName, n)
+	}
+
+	// links must not be built from the Host header of the public form
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatalf("%s failed: sign-in links must not be enabled without a base URL", testName)
+			}
+		}()
+		apptest.New(t, apptest.SqliteInMemoryConfig+"\ngoadmin.smtp.addr = \"127.0.0.1:1\"\nmyapp.magic_link.enabled = true\n", NewBootstrapper(nil, nil))
+	}()
+}
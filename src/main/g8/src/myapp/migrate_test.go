@@ -0,0 +1,53 @@
+package myapp
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	prom "github.com/btnguyen2k/prom/sql"
+)
+
+func TestRunSqlMigrations(t *testing.T) {
+	statements := sqliteSchemaStatements()
+	out := &bytes.Buffer{}
+	if err := runSqlMigrations(nil, prom.FlavorSqlite, statements, &MigrateOptions{DryRun: true, Out: out}); err != nil {
+		t.Fatalf("TestRunSqlMigrations failed: %s", err)
+	}
+	if !strings.Contains(out.String(), "CREATE TABLE IF NOT EXISTS "+sqliteTableUser) {
+		t.Fatalf("TestRunSqlMigrations failed: dry-run output does not contain DDL of table [%s]", sqliteTableUser)
+	}
+
+	// widening columns is not destructive, changing their type otherwise is
+	for _, sqlStm := range []string{mysqlSchemaWidenUserName(mysqlTableUser), pgsqlSchemaWidenUserName(pgsqlTableUser)} {
+		if isDestructiveSql(sqlStm) {
+			t.Fatalf("TestRunSqlMigrations failed: [%s] should not be destructive", sqlStm)
+		}
+	}
+	if !isDestructiveSql("ALTER TABLE " + mysqlTableUser + " MODIFY " + sqlColUserName + " VARCHAR(32)") {
+		t.Fatalf("TestRunSqlMigrations failed: narrowing a column should be destructive")
+	}
+
+	destructive := append(statements, "ALTER TABLE "+sqliteTableUser+" DROP COLUMN "+sqlColUserName)
+	if err := runSqlMigrations(nil, prom.FlavorSqlite, destructive, &MigrateOptions{DryRun: true, Out: out}); err == nil {
+		t.Fatalf("TestRunSqlMigrations failed: destructive statement should have been refused")
+	}
+
+	sqlc := newSqliteConnection(sqliteInMemory, namespace, nil)
+	defer sqlc.GetDB().Close()
+	if err := runSqlMigrations(sqlc, prom.FlavorSqlite, statements, &MigrateOptions{}); err != nil {
+		t.Fatalf("TestRunSqlMigrations failed: %s", err)
+	}
+	release, err := acquireMigrationLock(sqlc.GetDB(), prom.FlavorSqlite, time.Second)
+	if err != nil {
+		t.Fatalf("TestRunSqlMigrations failed: %s", err)
+	}
+	if err := runSqlMigrations(sqlc, prom.FlavorSqlite, statements, &MigrateOptions{LockTimeout: time.Second}); err != errMigrationLocked {
+		t.Fatalf("TestRunSqlMigrations failed: expected error [%s] but received [%v]", errMigrationLocked, err)
+	}
+	release()
+	if err := runSqlMigrations(sqlc, prom.FlavorSqlite, statements, &MigrateOptions{}); err != nil {
+		t.Fatalf("TestRunSqlMigrations failed: %s", err)
+	}
+}
@@ -1,6 +1,38 @@
 package myapp
 
-import "github.com/labstack/echo/v4"
+import (
+	"encoding/json"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/btnguyen2k/goyai"
+	"github.com/labstack/echo/v4"
+	"main/src/goadmin"
+	"main/src/utils"
+)
+
+// viewerIsSystemUser checks if the user viewing the page (set by middlewareRequiredAuth) belongs to the system group.
+// Permissions to perform actions are checked by can.
+func viewerIsSystemUser(c echo.Context) bool {
+	viewer, _ := c.Get(ctxCurrentUser).(*User)
+	return viewer != nil && viewer.GroupId == systemGroupId
+}
+
+// wantsJson checks if the client asks for the JSON representation of a page (query parameter format=json).
+//
+// available since template-r5
+func wantsJson(c echo.Context) bool {
+	return c.QueryParam("format") == "json"
+}
+
+// signUrl signs URL of a sensitive GET action (see goadmin.UrlSigner) and prefixes it with the base path.
+func signUrl(c echo.Context, rawUrl string) string {
+	registry := goadmin.GetRegistry(c)
+	return registry.Url(registry.UrlSigner.Sign(rawUrl))
+}
 
 func toGroupModel(c echo.Context, g *Group) *GroupModel {
 	if g == nil {
@@ -23,32 +55,151 @@ type GroupModel struct {
 	*Group
 }
 
+// CanEdit checks if the viewer is allowed to edit the group.
+func (m *GroupModel) CanEdit() bool {
+	return can(m.c, permGroupEdit)
+}
+
+// CanDelete checks if the viewer is allowed to delete the group.
 func (m *GroupModel) CanDelete() bool {
 	// cannot delete system-group
-	return m.Id != systemGroupId
+	return m.Id != systemGroupId && can(m.c, permGroupDelete)
 }
 
 func (m *GroupModel) UrlDelete() string {
-	return m.c.Echo().Reverse(actionNameCpDeleteGroup) + "?id=" + m.Id
+	return signUrl(m.c, m.c.Echo().Reverse(actionNameCpDeleteGroup)+"?id="+url.QueryEscape(m.Id))
 }
 
 func (m *GroupModel) UrlEdit() string {
-	return m.c.Echo().Reverse(actionNameCpEditGroup) + "?id=" + m.Id
+	return signUrl(m.c, m.c.Echo().Reverse(actionNameCpEditGroup)+"?id="+url.QueryEscape(m.Id))
+}
+
+// ToMap returns the canonical JSON representation of the group, shared by all JSON views. Links to actions are
+// included only if the viewer is allowed to perform them, the same way HTML views show them.
+//
+// available since template-r5
+func (m *GroupModel) ToMap() map[string]interface{} {
+	result := map[string]interface{}{
+		"id":   m.Id,
+		"name": m.Name,
+	}
+	if m.CanEdit() {
+		result["url_edit"] = m.UrlEdit()
+	}
+	if m.CanDelete() {
+		result["url_delete"] = m.UrlDelete()
+	}
+	return result
+}
+
+// MarshalJSON implements json.Marshaler.
+func (m *GroupModel) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.ToMap())
 }
 
 /*----------------------------------------------------------------------*/
 
+// getNGroupsAfter returns at most maxNumRows groups (all if maxNumRows <= 0) whose id sorts after afterId. DAOs
+// implementing GroupKeysetPager page natively, groups of other DAOs are fetched all at once.
+//
+// available since template-r5
+func getNGroupsAfter(dao GroupDao, afterId string, maxNumRows int) ([]*Group, error) {
+	if pager, ok := dao.(GroupKeysetPager); ok {
+		return pager.GetNAfter(afterId, maxNumRows)
+	}
+	groups, err := dao.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*Group, 0)
+	for _, g := range groups {
+		if g.Id > afterId {
+			result = append(result, g)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Id < result[j].Id })
+	if maxNumRows > 0 && len(result) > maxNumRows {
+		result = result[:maxNumRows]
+	}
+	return result, nil
+}
+
+// getGroupsByIds returns the groups of the supplied ids, keyed by group id. DAOs implementing GroupBatchGetter look
+// them up natively, groups of other DAOs are fetched all at once.
+//
+// available since template-r5
+func getGroupsByIds(dao GroupDao, ids []string) (map[string]*Group, error) {
+	if getter, ok := dao.(GroupBatchGetter); ok {
+		return getter.GetByIds(ids)
+	}
+	groups, err := dao.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	result := make(map[string]*Group)
+	for _, g := range groups {
+		if wanted[g.Id] {
+			result[g.Id] = g
+		}
+	}
+	return result, nil
+}
+
+// userGroupNames resolves names of the groups of a list of users, shared by their models: groups are looked up with
+// a single query the first time a name is needed, rather than once per user.
+type userGroupNames struct {
+	c     echo.Context
+	ids   []string
+	once  sync.Once
+	names map[string]string
+}
+
+func newUserGroupNames(c echo.Context, userList []*User) *userGroupNames {
+	seen := make(map[string]bool)
+	ids := make([]string, 0)
+	for _, u := range userList {
+		if u != nil && !seen[u.GroupId] {
+			seen[u.GroupId] = true
+			ids = append(ids, u.GroupId)
+		}
+	}
+	return &userGroupNames{c: c, ids: ids}
+}
+
+// get returns name of the group, empty if the group does not exist.
+func (g *userGroupNames) get(groupId string) string {
+	g.once.Do(func() {
+		g.names = make(map[string]string)
+		groups, err := getGroupsByIds(getGroupDao(g.c), g.ids)
+		if err != nil {
+			log.Printf("[ERROR] cannot load groups %v: %s", g.ids, err)
+			return
+		}
+		for id, group := range groups {
+			g.names[id] = group.Name
+		}
+	})
+	return g.names[groupId]
+}
+
 func toUserModel(c echo.Context, u *User) *UserModel {
 	if u == nil {
 		return nil
 	}
-	return &UserModel{c: c, User: u}
+	return &UserModel{c: c, User: u, groups: newUserGroupNames(c, []*User{u})}
 }
 
 func toUserModelList(c echo.Context, userList []*User) []*UserModel {
+	groups := newUserGroupNames(c, userList)
 	result := make([]*UserModel, 0)
 	for _, u := range userList {
-		result = append(result, toUserModel(c, u))
+		if u != nil {
+			result = append(result, &UserModel{c: c, User: u, groups: groups})
+		}
 	}
 	return result
 }
@@ -57,26 +208,110 @@ func toUserModelList(c echo.Context, userList []*User) []*UserModel {
 type UserModel struct {
 	c echo.Context
 	*User
+	groups *userGroupNames
+}
+
+// GroupName returns name of the user's group, its id if the group does not exist.
+//
+// available since template-r5
+func (m *UserModel) GroupName() string {
+	if name := m.groups.get(m.GroupId); name != "" {
+		return name
+	}
+	return m.GroupId
 }
 
 func (m *UserModel) IsSystemUser() bool {
 	return m.GroupId == systemGroupId
 }
 
+// CanDelete checks if the viewer is allowed to delete the user.
 func (m *UserModel) CanDelete() bool {
 	// cannot delete system-user
-	return m.Username != systemUserUsername
+	return m.Username != systemUserUsername && can(m.c, permUserDelete)
 }
 
+// CanEdit checks if the viewer is allowed to edit the user.
 func (m *UserModel) CanEdit() bool {
 	// cannot edit system-user
-	return m.Username != systemUserUsername
+	return m.Username != systemUserUsername && can(m.c, permUserEdit)
 }
 
 func (m *UserModel) UrlDelete() string {
-	return m.c.Echo().Reverse(actionNameCpDeleteUser) + "?u=" + m.Username
+	return signUrl(m.c, m.c.Echo().Reverse(actionNameCpDeleteUser)+"?u="+url.QueryEscape(m.Username))
 }
 
 func (m *UserModel) UrlEdit() string {
-	return m.c.Echo().Reverse(actionNameCpEditUser) + "?u=" + m.Username
+	return signUrl(m.c, m.c.Echo().Reverse(actionNameCpEditUser)+"?u="+url.QueryEscape(m.Username))
+}
+
+// ToMap returns the canonical JSON representation of the user, shared by all JSON views. The password is never
+// included; the group is visible to system users only, notes to viewers allowed to edit users, and links to actions
+// are included only if the viewer is allowed to perform them, the same way HTML views show them.
+//
+// available since template-r5
+func (m *UserModel) ToMap() map[string]interface{} {
+	result := map[string]interface{}{
+		"username": m.Username,
+		"name":     m.Name,
+	}
+	if viewerIsSystemUser(m.c) {
+		result["group_id"] = m.GroupId
+		result["group_name"] = m.GroupName()
+	}
+	if m.Tags != "" {
+		result["tags"] = m.TagList()
+	}
+	if m.Notes != "" && can(m.c, permUserEdit) {
+		result["notes"] = m.Notes
+	}
+	if m.CanEdit() {
+		result["url_edit"] = m.UrlEdit()
+	}
+	if m.CanDelete() {
+		result["url_delete"] = m.UrlDelete()
+	}
+	return result
+}
+
+// MarshalJSON implements json.Marshaler.
+func (m *UserModel) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.ToMap())
+}
+
+/*----------------------------------------------------------------------*/
+
+func toNotificationModelList(c echo.Context, list []*Notification) []*NotificationModel {
+	result := make([]*NotificationModel, 0)
+	for _, n := range list {
+		result = append(result, &NotificationModel{c: c, Notification: n})
+	}
+	return result
+}
+
+// NotificationModel represents a notification model to be used in view
+//
+// available since template-r5
+type NotificationModel struct {
+	c echo.Context
+	*Notification
+}
+
+// Message returns the notification's message, followed by its notes, in the locale of the current request.
+func (m *NotificationModel) Message() string {
+	i18n, locale := getI18n(m.c), getContextString(m.c, ctxLocale)
+	msg := i18n.Localize(locale, m.Key, &goyai.LocalizeConfig{TemplateData: m.Params})
+	notes := make([]string, 0, len(m.Notes))
+	for _, note := range m.Notes {
+		notes = append(notes, i18n.Localize(locale, note))
+	}
+	if len(notes) > 0 {
+		msg += ": " + strings.Join(notes, ", ")
+	}
+	return msg
+}
+
+// TimeStr returns the notification's time, in the application's timezone.
+func (m *NotificationModel) TimeStr() string {
+	return m.Time.In(utils.Location).Format("2006-01-02 15:04")
 }
@@ -0,0 +1,248 @@
+package myapp
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// The OpenAPI spec of the JSON API is built from apiEndpoints and apiSchemas rather than written by hand, so that it
+// follows changes of the endpoints: served at /api/openapi.json, it is also what command "gen-api-client" generates
+// client packages from (see GenerateApiClients). The Postman collection of the API is built the same way, see
+// apiCollection.
+
+// defaultApiSpecTitle is the title of the spec if the application has no name, e.g. when clients are generated.
+const defaultApiSpecTitle = "Admin API"
+
+// apiProperty is a property of a resource returned by the JSON API.
+type apiProperty struct {
+	name     string
+	typ      string // "string", "integer", "boolean" or "array" (of strings)
+	desc     string
+	required bool
+	example  interface{} // value of example documents, nil to leave the property out of them
+}
+
+// apiSchema is a resource returned by the JSON API, properties mirror ToMap of its model.
+type apiSchema struct {
+	name       string
+	desc       string
+	properties []*apiProperty
+}
+
+// apiSchemas lists resources returned by the JSON API, referenced by apiEndpoint.schema.
+var apiSchemas = []*apiSchema{
+	{name: "User", desc: "A user account, the password is never returned", properties: []*apiProperty{
+		{name: "username", typ: "string", desc: "Username, the email address of the user", required: true, example: "jane.doe@example.com"},
+		{name: "name", typ: "string", desc: "Display name", required: true, example: "Jane Doe"},
+		{name: "group_id", typ: "string", desc: "Id of the user's group, returned to system users only", example: "staff"},
+		{name: "group_name", typ: "string", desc: "Name of the user's group, returned to system users only", example: "Staff"},
+		{name: "tags", typ: "array", desc: "Tags of the user", example: []string{"vip"}},
+		{name: "notes", typ: "string", desc: "Notes of admins, returned to users allowed to edit users", example: "Prefers to be contacted by email"},
+		{name: "url_edit", typ: "string", desc: "Link to edit the user, returned to users allowed to"},
+		{name: "url_delete", typ: "string", desc: "Link to delete the user, returned to users allowed to"},
+	}},
+	{name: "Group", desc: "A group of users", properties: []*apiProperty{
+		{name: "id", typ: "string", desc: "Id of the group", required: true, example: "staff"},
+		{name: "name", typ: "string", desc: "Name of the group", required: true, example: "Staff"},
+		{name: "url_edit", typ: "string", desc: "Link to edit the group, returned to users allowed to"},
+		{name: "url_delete", typ: "string", desc: "Link to delete the group, returned to users allowed to"},
+	}},
+}
+
+// apiSchemaByName returns the resource of apiSchemas of a name, nil if not found.
+func apiSchemaByName(name string) *apiSchema {
+	for _, s := range apiSchemas {
+		if s.name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// example returns an example of the resource, made of examples of its properties.
+func (s *apiSchema) example() map[string]interface{} {
+	result := make(map[string]interface{})
+	for _, p := range s.properties {
+		if p.example != nil {
+			result[p.name] = p.example
+		}
+	}
+	return result
+}
+
+// apiExample returns an example document returned by an endpoint in the latest version, the whole list for list
+// endpoints.
+func apiExample(endpoint *apiEndpoint) map[string]interface{} {
+	item := apiSchemaByName(endpoint.schema).example()
+	if endpoint.list {
+		return map[string]interface{}{"data": []interface{}{item}, "count": 1}
+	}
+	return map[string]interface{}{"data": item}
+}
+
+/*----------------------------------------------------------------------*/
+
+// openApiDoc is an OpenAPI 3.0 document, limited to what the JSON API uses.
+type openApiDoc struct {
+	OpenApi    string                                  `json:"openapi"`
+	Info       *openApiInfo                            `json:"info"`
+	Paths      map[string]map[string]*openApiOperation `json:"paths"`
+	Components *openApiComponents                      `json:"components"`
+	Security   []map[string][]string                   `json:"security,omitempty"`
+}
+
+// openApiInfo is the "info" object of an OpenAPI document.
+type openApiInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// openApiComponents is the "components" object of an OpenAPI document.
+type openApiComponents struct {
+	Schemas         map[string]*openApiSchema    `json:"schemas"`
+	SecuritySchemes map[string]map[string]string `json:"securitySchemes,omitempty"`
+}
+
+// openApiOperation is an operation of an OpenAPI document.
+type openApiOperation struct {
+	OperationId string                      `json:"operationId"`
+	Summary     string                      `json:"summary,omitempty"`
+	Parameters  []*openApiParameter         `json:"parameters,omitempty"`
+	Responses   map[string]*openApiResponse `json:"responses"`
+}
+
+// openApiParameter is a parameter of an operation.
+type openApiParameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Description string         `json:"description,omitempty"`
+	Schema      *openApiSchema `json:"schema"`
+}
+
+// openApiResponse is a response of an operation, its content is JSON.
+type openApiResponse struct {
+	Description string                       `json:"description"`
+	Content     map[string]*openApiMediaType `json:"content,omitempty"`
+}
+
+// openApiMediaType is the schema of a response content.
+type openApiMediaType struct {
+	Schema  *openApiSchema `json:"schema"`
+	Example interface{}    `json:"example,omitempty"`
+}
+
+// openApiSchema is a schema of an OpenAPI document: a reference, a scalar, an array or an object.
+type openApiSchema struct {
+	Ref         string                    `json:"$ref,omitempty"`
+	Type        string                    `json:"type,omitempty"`
+	Description string                    `json:"description,omitempty"`
+	Nullable    bool                      `json:"nullable,omitempty"`
+	Items       *openApiSchema            `json:"items,omitempty"`
+	Properties  map[string]*openApiSchema `json:"properties,omitempty"`
+	Required    []string                  `json:"required,omitempty"`
+	Example     interface{}               `json:"example,omitempty"`
+}
+
+// refName returns the name of the referenced schema, empty if the schema is not a reference.
+func (s *openApiSchema) refName() string {
+	return strings.TrimPrefix(s.Ref, "#/components/schemas/")
+}
+
+// propertyNames returns names of the properties of the schema, sorted.
+func (s *openApiSchema) propertyNames() []string {
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// isRequired checks if a property of the schema is always returned.
+func (s *openApiSchema) isRequired(name string) bool {
+	return containsString(s.Required, name)
+}
+
+func openApiRef(schema string) *openApiSchema {
+	return &openApiSchema{Ref: "#/components/schemas/" + schema}
+}
+
+// apiSpec returns the OpenAPI spec of the latest version of the JSON API, served under /api/<version>.
+func apiSpec(title string) *openApiDoc {
+	doc := &openApiDoc{
+		OpenApi: "3.0.3",
+		Info: &openApiInfo{
+			Title:       title,
+			Version:     apiLatestVersion,
+			Description: "Requests are authenticated with API tokens created on page /cp/tokens, sent in header \"Authorization: Bearer <token>\".",
+		},
+		Paths: make(map[string]map[string]*openApiOperation),
+		Components: &openApiComponents{
+			Schemas: map[string]*openApiSchema{
+				"ApiError": {Type: "object", Description: "An error, the message is localized", Required: []string{"error"},
+					Properties: map[string]*openApiSchema{"error": {Type: "string"}}},
+			},
+			SecuritySchemes: map[string]map[string]string{"bearer": {"type": "http", "scheme": "bearer"}},
+		},
+		Security: []map[string][]string{{"bearer": {}}},
+	}
+	for _, s := range apiSchemas {
+		schema := &openApiSchema{Type: "object", Description: s.desc, Properties: make(map[string]*openApiSchema)}
+		for _, p := range s.properties {
+			prop := &openApiSchema{Type: p.typ, Description: p.desc, Example: p.example}
+			if p.typ == "array" {
+				prop.Items = &openApiSchema{Type: "string"}
+			}
+			schema.Properties[p.name] = prop
+			if p.required {
+				schema.Required = append(schema.Required, p.name)
+			}
+		}
+		doc.Components.Schemas[s.name] = schema
+	}
+	for _, endpoint := range apiEndpoints {
+		op := &openApiOperation{
+			OperationId: endpoint.operation,
+			Summary:     endpoint.summary,
+			Responses: map[string]*openApiResponse{
+				"default": {Description: "Error", Content: map[string]*openApiMediaType{"application/json": {Schema: openApiRef("ApiError")}}},
+			},
+		}
+		// documents of the latest version wrap resources in field "data", see apiShimV1
+		var result *openApiSchema
+		resultName := endpoint.schema + "Response"
+		if endpoint.list {
+			resultName = endpoint.schema + "ListResponse"
+			result = &openApiSchema{Type: "object", Description: "List of " + endpoint.schema + " resources", Required: []string{"data", "count"}, Properties: map[string]*openApiSchema{
+				"data":        {Type: "array", Items: openApiRef(endpoint.schema)},
+				"count":       {Type: "integer", Description: "Number of items of this page"},
+				"next_cursor": {Type: "string", Nullable: true, Description: "Cursor of the next page, null on the last page; returned only if a page is requested"},
+			}}
+			op.Parameters = []*openApiParameter{
+				{Name: "limit", In: "query", Description: "Page size, the whole list is returned if neither limit nor cursor is supplied", Schema: &openApiSchema{Type: "integer"}},
+				{Name: "cursor", In: "query", Description: "Field next_cursor of the previous page", Schema: &openApiSchema{Type: "string"}},
+			}
+		} else {
+			result = &openApiSchema{Type: "object", Description: "Document of a " + endpoint.schema + " resource", Required: []string{"data"}, Properties: map[string]*openApiSchema{
+				"data": openApiRef(endpoint.schema),
+			}}
+		}
+		doc.Components.Schemas[resultName] = result
+		op.Responses["200"] = &openApiResponse{Description: "OK", Content: map[string]*openApiMediaType{
+			"application/json": {Schema: openApiRef(resultName), Example: apiExample(endpoint)},
+		}}
+		doc.Paths["/api/"+apiLatestVersion+endpoint.path] = map[string]*openApiOperation{"get": op}
+	}
+	return doc
+}
+
+// actionApiSpec serves the OpenAPI spec of the JSON API.
+//
+// available since template-r5
+func actionApiSpec(c echo.Context) error {
+	return c.JSON(http.StatusOK, apiSpec(apiTitle(c)))
+}
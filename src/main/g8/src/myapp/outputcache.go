@@ -0,0 +1,170 @@
+package myapp
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"main/src/utils"
+)
+
+// outputCacheGenerationKey is the cache key of the current generation of the output cache: cached pages are keyed by
+// generation, so that all of them are invalidated at once (on all instances with the Redis cache driver) by starting a
+// new generation.
+const outputCacheGenerationKey = namespace + ":output:gen"
+
+// outputCacheHeaders are response headers kept with cached pages.
+var outputCacheHeaders = []string{echo.HeaderContentType, echo.HeaderCacheControl, "ETag"}
+
+// outputCache keeps pages rendered for anonymous visitors (e.g. the landing page and public pages) in the
+// application's cache (setting goadmin.cache), keyed by host, path and locale, so that traffic spikes are absorbed
+// without rendering templates nor touching the database (configuration block myapp.output_cache).
+//
+// Only GET requests without query string nor session cookie are served from the cache, and only complete 200
+// responses that do not set cookies are cached. Cached pages expire after the TTL and are invalidated when content
+// they depend on changes: public pages, branding settings, translations and imported configuration bundles.
+//
+// available since template-r5
+type outputCache struct {
+	r       *myRegistry
+	ttl     time.Duration
+	maxSize int // max size of cached pages, in bytes
+}
+
+// newOutputCache creates an outputCache from the configuration block myapp.output_cache, nil is returned if output
+// caching is disabled.
+func newOutputCache(r *myRegistry) *outputCache {
+	conf := r.AppConfig
+	confPath := namespace + ".output_cache"
+	ttl := conf.GetTimeDuration(confPath+".ttl", 0)
+	if ttl <= 0 {
+		return nil
+	}
+	oc := &outputCache{r: r, ttl: ttl, maxSize: 1024 * 1024}
+	if maxSize := conf.GetByteSize(confPath + ".max_size"); maxSize != nil {
+		oc.maxSize = int(maxSize.Int64())
+	}
+	return oc
+}
+
+// cachedOutput is a page kept in the output cache.
+type cachedOutput struct {
+	Headers map[string]string `json:"headers"`
+	Body    []byte            `json:"body"`
+}
+
+// generation returns the current generation of the output cache.
+func (oc *outputCache) generation() string {
+	if v, err := oc.r.Cache.Get(outputCacheGenerationKey); err == nil {
+		return string(v)
+	}
+	return "0"
+}
+
+// invalidate drops all cached pages, it is a no-op if output caching is disabled.
+func (oc *outputCache) invalidate() {
+	if oc == nil {
+		return
+	}
+	if err := oc.r.Cache.Set(outputCacheGenerationKey, []byte(utils.UniqueIdSmall()), 0); err != nil {
+		log.Printf("[WARN] cannot invalidate output cache: %s", err)
+	}
+}
+
+// cacheable checks if the response to the request may be served from, and stored to, the output cache.
+func (oc *outputCache) cacheable(c echo.Context) bool {
+	req := c.Request()
+	if req.Method != http.MethodGet || req.URL.RawQuery != "" {
+		// query strings may select the locale (parameter "_l") and would let clients fill the cache at will
+		return false
+	}
+	if _, err := c.Cookie(namespace); err == nil {
+		// visitors with a session may see personalized content, e.g. flash messages
+		return false
+	}
+	// pages carrying the debug toolbar are specific to the request
+	return c.Get(ctxRequestProfile) == nil
+}
+
+func (oc *outputCache) key(c echo.Context) string {
+	req := c.Request()
+	return namespace + ":output:" + oc.generation() + ":" + req.Host + ":" + getContextString(c, ctxLocale) + ":" + req.URL.Path
+}
+
+// outputRecorder captures a response while it is written to the client, up to max bytes.
+type outputRecorder struct {
+	http.ResponseWriter
+	buf      bytes.Buffer
+	max      int
+	overflow bool
+}
+
+// Write implements http.ResponseWriter.Write
+func (w *outputRecorder) Write(p []byte) (int, error) {
+	if !w.overflow {
+		if w.max > 0 && w.buf.Len()+len(p) > w.max {
+			w.overflow = true
+			w.buf.Reset()
+		} else {
+			w.buf.Write(p)
+		}
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush implements http.Flusher.Flush, so that streamed pages are still streamed to the client.
+func (w *outputRecorder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// middleware serves cacheable requests from the output cache, and caches responses to those that miss. Header
+// "X-Cache" tells whether the page was served from the cache ("HIT") or rendered ("MISS").
+func (oc *outputCache) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !oc.cacheable(c) {
+			return next(c)
+		}
+		key, resp := oc.key(c), c.Response()
+		if v, err := oc.r.Cache.Get(key); err == nil {
+			entry := &cachedOutput{}
+			if err := json.Unmarshal(v, entry); err == nil {
+				for name, value := range entry.Headers {
+					resp.Header().Set(name, value)
+				}
+				resp.Header().Set("X-Cache", "HIT")
+				if etag := entry.Headers["ETag"]; etag != "" && etagMatches(c.Request().Header.Get("If-None-Match"), etag) {
+					return c.NoContent(http.StatusNotModified)
+				}
+				resp.WriteHeader(http.StatusOK)
+				_, err := resp.Write(entry.Body)
+				return err
+			}
+		}
+
+		resp.Header().Set("X-Cache", "MISS")
+		rec := &outputRecorder{ResponseWriter: resp.Writer, max: oc.maxSize}
+		resp.Writer = rec
+		err := next(c)
+		resp.Writer = rec.ResponseWriter
+		if err != nil || resp.Status != http.StatusOK || rec.overflow || resp.Header().Get(echo.HeaderSetCookie) != "" {
+			return err
+		}
+		entry := &cachedOutput{Headers: make(map[string]string), Body: rec.buf.Bytes()}
+		for _, name := range outputCacheHeaders {
+			if value := resp.Header().Get(name); value != "" {
+				entry.Headers[name] = value
+			}
+		}
+		if v, err := json.Marshal(entry); err == nil {
+			if err := oc.r.Cache.Set(key, v, oc.ttl); err != nil {
+				log.Printf("[WARN] cannot cache output of [%s]: %s", c.Request().URL.Path, err)
+			}
+		}
+		return nil
+	}
+}
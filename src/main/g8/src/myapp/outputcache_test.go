@@ -0,0 +1,50 @@
+package myapp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"main/src/apptest"
+)
+
+func TestOutputCache(t *testing.T) {
+	testName := "TestOutputCache"
+	h := apptest.New(t, apptest.SqliteInMemoryConfig+"\nmyapp.output_cache.ttl = 1m\n", NewBootstrapper(nil, nil))
+	anonymousGet := func(path string) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		h.Echo.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, path, nil))
+		return resp
+	}
+	assertCache := func(resp *httptest.ResponseRecorder, expected string) {
+		if v := resp.Header().Get("X-Cache"); v != expected {
+			t.Fatalf("%s failed: expected X-Cache [%s] but received [%s] for %s", testName, expected, v, resp.Body.String())
+		}
+	}
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	form := url.Values{"slug": {"about"}, "title": {"About"}, "content": {"We are **Acme**."}, "published": {"1"}}
+	h.AssertRedirect(h.PostForm(h.Reverse(actionNameCpCreatePageSubmit), form), h.Reverse(actionNameCpPages))
+
+	resp := anonymousGet(h.Reverse(actionNamePage, "about"))
+	h.AssertStatus(resp, http.StatusOK)
+	assertCache(resp, "MISS")
+	resp = anonymousGet(h.Reverse(actionNamePage, "about"))
+	h.AssertStatus(resp, http.StatusOK)
+	assertCache(resp, "HIT")
+	h.AssertBodyContains(resp, "<strong>Acme</strong>")
+	assertCache(anonymousGet(h.Reverse(actionNameHome)), "MISS")
+	assertCache(anonymousGet(h.Reverse(actionNameHome)), "HIT")
+
+	// visitors with a session and requests with a query string are not served from the cache
+	assertCache(h.Get(h.Reverse(actionNamePage, "about")), "")
+	assertCache(anonymousGet(h.Reverse(actionNamePage, "about")+"?_l=vi"), "")
+
+	// updated pages are served right away
+	form.Set("content", "We are **Acme Corp**.")
+	h.AssertRedirect(h.PostForm(h.Reverse(actionNameCpEditPageSubmit)+"?slug=about", form), h.Reverse(actionNameCpPages))
+	resp = anonymousGet(h.Reverse(actionNamePage, "about"))
+	assertCache(resp, "MISS")
+	h.AssertBodyContains(resp, "<strong>Acme Corp</strong>")
+	assertCache(anonymousGet(h.Reverse(actionNameHome)), "MISS")
+}
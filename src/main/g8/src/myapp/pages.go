@@ -0,0 +1,411 @@
+package myapp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/btnguyen2k/goyai"
+	"github.com/labstack/echo/v4"
+	"main/src/goadmin"
+	"main/src/utils"
+)
+
+// settingPrefixPage prefixes ids of settings that store public pages, e.g. "page:about".
+const settingPrefixPage = "page:"
+
+// reSlug matches valid slugs of public pages, e.g. "about" or "service-status".
+var reSlug = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,63}$`)
+
+// Page is a page of public content (e.g. "About" or "Status") managed at /cp/pages and served at /p/<slug>.
+//
+// available since template-r5
+type Page struct {
+	Slug        string    `json:"slug"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"` // meta description, see PageMeta
+	Content     string    `json:"content"`     // Markdown
+	Published   bool      `json:"published"`   // unpublished pages are not served
+	By          string    `json:"by"`
+	Updated     time.Time `json:"updated"`
+}
+
+// validate normalizes the page.
+func (p *Page) validate() {
+	p.Slug = strings.ToLower(strings.TrimSpace(p.Slug))
+	p.Title = strings.TrimSpace(p.Title)
+	p.Description = strings.TrimSpace(p.Description)
+	p.Content = strings.TrimSpace(p.Content)
+}
+
+// PageDao stores public pages.
+//
+// available since template-r5
+type PageDao interface {
+	// Get returns the page with the supplied slug, nil if not found.
+	Get(slug string) (*Page, error)
+	// GetAll returns all pages, ordered by slug.
+	GetAll() ([]*Page, error)
+	// Save creates or updates a page.
+	Save(page *Page) error
+	// Delete removes a page, returns false if not found.
+	Delete(slug string) (bool, error)
+}
+
+// settingPageDao is a PageDao keeping pages as settings, so that they are persisted in the application's database
+// whatever its type (and exported with configuration bundles).
+type settingPageDao struct {
+	r *myRegistry
+}
+
+// Get implements PageDao.Get
+func (dao *settingPageDao) Get(slug string) (*Page, error) {
+	page := &Page{}
+	if found, err := dao.r.loadSetting(settingPrefixPage+slug, page); err != nil || !found {
+		return nil, err
+	}
+	return page, nil
+}
+
+// GetAll implements PageDao.GetAll
+func (dao *settingPageDao) GetAll() ([]*Page, error) {
+	list, err := dao.r.settingsWithPrefix(settingPrefixPage)
+	if err != nil {
+		return nil, err
+	}
+	pages := make([]*Page, 0, len(list))
+	for _, s := range list {
+		page := &Page{}
+		if err := json.Unmarshal([]byte(s.Value), page); err != nil {
+			log.Printf("[WARN] cannot decode setting [%s]: %s", s.Id, err)
+			continue
+		}
+		pages = append(pages, page)
+	}
+	sort.Slice(pages, func(i, j int) bool { return pages[i].Slug < pages[j].Slug })
+	return pages, nil
+}
+
+// Save implements PageDao.Save
+func (dao *settingPageDao) Save(page *Page) error {
+	defer dao.r.outputCache.invalidate()
+	return dao.r.saveSetting(settingPrefixPage+page.Slug, page)
+}
+
+// Delete implements PageDao.Delete
+func (dao *settingPageDao) Delete(slug string) (bool, error) {
+	setting, err := dao.r.settingDao.Get(settingPrefixPage + slug)
+	if err != nil || setting == nil {
+		return false, err
+	}
+	defer dao.r.outputCache.invalidate()
+	return dao.r.settingDao.Delete(setting)
+}
+
+/*----------------------------------------------------------------------*/
+
+// pageETag returns the ETag of a version of the page.
+func pageETag(page *Page) string {
+	return fmt.Sprintf(`"%s-%d"`, page.Slug, page.Updated.UnixNano())
+}
+
+// renderPageContent renders the Markdown content of the page, rendered content is kept in the application's cache
+// for setting myapp.pages.max_age.
+func (r *myRegistry) renderPageContent(page *Page) template.HTML {
+	ttl := r.AppConfig.GetTimeDuration(namespace+".pages.max_age", 5*time.Minute)
+	if ttl <= 0 {
+		return goadmin.RenderMarkdown(page.Content)
+	}
+	// the key changes whenever the page is updated, so that updates are served right away
+	key := fmt.Sprintf("%s:page:%s:%d", namespace, page.Slug, page.Updated.UnixNano())
+	html, err := r.Cache.Remember(key, ttl, func() ([]byte, error) {
+		return []byte(goadmin.RenderMarkdown(page.Content)), nil
+	})
+	if err != nil {
+		return goadmin.RenderMarkdown(page.Content)
+	}
+	return template.HTML(html)
+}
+
+// actionPage serves a published page (path parameter "slug"). Browsers and proxies may cache pages for setting
+// myapp.pages.max_age, and revalidate them with their ETag.
+//
+// available since template-r5
+func actionPage(c echo.Context) error {
+	myReg := getRegistry(c)
+	page, err := myReg.pageDao.Get(strings.ToLower(c.Param("slug")))
+	if err != nil {
+		return err
+	}
+	if page == nil || !page.Published {
+		return echo.NewHTTPError(http.StatusNotFound)
+	}
+	etag := pageETag(page)
+	header := c.Response().Header()
+	if maxAge := myReg.AppConfig.GetTimeDuration(namespace+".pages.max_age", 5*time.Minute); maxAge > 0 {
+		header.Set(echo.HeaderCacheControl, fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+	} else {
+		header.Set(echo.HeaderCacheControl, "no-cache")
+	}
+	header.Set("ETag", etag)
+	if etagMatches(c.Request().Header.Get("If-None-Match"), etag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+	c.Set(ctxPageMeta, &PageMeta{Title: page.Title, Description: page.Description, Type: "article"})
+	return c.Render(http.StatusOK, namespace+":page", map[string]interface{}{
+		"page":    page,
+		"content": myReg.renderPageContent(page),
+	})
+}
+
+/*----------------------------------------------------------------------*/
+
+// PageModel is the view model of a page.
+//
+// available since template-r5
+type PageModel struct {
+	*Page
+	c echo.Context
+}
+
+func toPageModel(c echo.Context, page *Page) *PageModel {
+	return &PageModel{Page: page, c: c}
+}
+
+// UpdatedStr returns the time the page was last updated, in the application's timezone.
+func (m *PageModel) UpdatedStr() string {
+	return m.Updated.In(utils.Location).Format("2006-01-02 15:04:05")
+}
+
+// UrlView returns the public URL of the page.
+func (m *PageModel) UrlView() string {
+	return getRegistry(m.c).Reverse(actionNamePage, m.Slug)
+}
+
+// UrlEdit returns the URL to edit the page.
+func (m *PageModel) UrlEdit() string {
+	return m.c.Echo().Reverse(actionNameCpEditPage) + "?slug=" + url.QueryEscape(m.Slug)
+}
+
+// UrlDelete returns the URL to delete the page.
+func (m *PageModel) UrlDelete() string {
+	return m.c.Echo().Reverse(actionNameCpDeletePageSubmit) + "?slug=" + url.QueryEscape(m.Slug)
+}
+
+// actionCpPages lists public pages.
+//
+// available since template-r5
+func actionCpPages(c echo.Context) error {
+	if err := checkPermission(c, permPageManage); err != nil {
+		AddFlashText(c, FlashWarning, err.Error())
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpDashboard))
+	}
+	data := map[string]interface{}{"active": "pages"}
+	pages, err := getRegistry(c).pageDao.GetAll()
+	if err != nil {
+		data["error"] = getI18n(c).Localize(getContextString(c, ctxLocale), "error_db_001", &goyai.LocalizeConfig{
+			TemplateData: map[string]interface{}{"err": settingPrefixPage + "/" + err.Error()},
+		})
+	}
+	models := make([]*PageModel, 0, len(pages))
+	for _, page := range pages {
+		models = append(models, toPageModel(c, page))
+	}
+	data["pages"] = models
+	return c.Render(http.StatusOK, namespace+":layout:cp_pages", data)
+}
+
+// pageFromForm reads a page from form fields "slug", "title", "description", "content" and "published".
+func pageFromForm(formData url.Values) *Page {
+	page := &Page{
+		Slug:        formData.Get("slug"),
+		Title:       formData.Get("title"),
+		Description: formData.Get("description"),
+		Content:     formData.Get("content"),
+		Published:   formData.Get("published") == "1",
+	}
+	page.validate()
+	return page
+}
+
+// setPageForm sets form fields of the page, see pageFromForm.
+func setPageForm(formData url.Values, page *Page) {
+	formData.Set("slug", page.Slug)
+	formData.Set("title", page.Title)
+	formData.Set("description", page.Description)
+	formData.Set("content", page.Content)
+	if page.Published {
+		formData.Set("published", "1")
+	}
+}
+
+// renderCpCreateEditPage renders the form to create or edit a page.
+func renderCpCreateEditPage(c echo.Context, editMode bool, formData url.Values, errMsg string) error {
+	return c.Render(http.StatusOK, namespace+":layout:cp_create_edit_page", map[string]interface{}{
+		"active":   "pages",
+		"editMode": editMode,
+		"form":     formData,
+		"error":    errMsg,
+	})
+}
+
+// actionCpCreatePage shows the form to create a page.
+//
+// available since template-r5
+func actionCpCreatePage(c echo.Context) error {
+	if err := checkPermission(c, permPageManage); err != nil {
+		AddFlashText(c, FlashWarning, err.Error())
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpDashboard))
+	}
+	AddBreadcrumb(c, "", "create_page")
+	return renderCpCreateEditPage(c, false, url.Values{}, "")
+}
+
+// actionCpCreatePageSubmit creates a page, see pageFromForm.
+//
+// available since template-r5
+func actionCpCreatePageSubmit(c echo.Context) error {
+	if err := checkPermission(c, permPageManage); err != nil {
+		AddFlashText(c, FlashWarning, err.Error())
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpDashboard))
+	}
+	AddBreadcrumb(c, "", "create_page")
+	formData, _ := c.FormParams()
+	page := pageFromForm(formData)
+	myReg := getRegistry(c)
+	if !reSlug.MatchString(page.Slug) {
+		return renderCpCreateEditPage(c, false, formData, getI18n(c).Localize(getContextString(c, ctxLocale), "error_invalid_page_slug"))
+	}
+	if page.Title == "" {
+		return renderCpCreateEditPage(c, false, formData, getI18n(c).Localize(getContextString(c, ctxLocale), "error_empty_page_title"))
+	}
+	if existing, err := myReg.pageDao.Get(page.Slug); err != nil {
+		return renderCpCreateEditPage(c, false, formData, getI18n(c).Localize(getContextString(c, ctxLocale), "error_db_001", &goyai.LocalizeConfig{
+			TemplateData: map[string]interface{}{"err": settingPrefixPage + page.Slug + "/" + err.Error()},
+		}))
+	} else if existing != nil {
+		return renderCpCreateEditPage(c, false, formData, getI18n(c).Localize(getContextString(c, ctxLocale), "error_page_existed", &goyai.LocalizeConfig{
+			TemplateData: map[string]interface{}{"slug": page.Slug},
+		}))
+	}
+	if currentUser, _ := c.Get(ctxCurrentUser).(*User); currentUser != nil {
+		page.By = currentUser.Username
+	}
+	page.Updated = utils.Now()
+	if err := myReg.pageDao.Save(page); err != nil {
+		return renderCpCreateEditPage(c, false, formData, getI18n(c).Localize(getContextString(c, ctxLocale), "error_db_001", &goyai.LocalizeConfig{
+			TemplateData: map[string]interface{}{"err": settingPrefixPage + page.Slug + "/" + err.Error()},
+		}))
+	}
+	myReg.auditf("user [%s] created page [%s]", page.By, page.Slug)
+	AddFlash(c, FlashInfo, "create_page_successful", "slug", page.Slug)
+	return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpPages)+"?r="+utils.RandomString(4))
+}
+
+// checkCpEditPage returns the page of query parameter "slug" if the current user can manage pages.
+func checkCpEditPage(c echo.Context) (*Page, error) {
+	if err := checkPermission(c, permPageManage); err != nil {
+		return nil, err
+	}
+	slug := c.QueryParam("slug")
+	page, err := getRegistry(c).pageDao.Get(slug)
+	if err != nil {
+		return nil, errors.New(getI18n(c).Localize(getContextString(c, ctxLocale), "error_db_001", &goyai.LocalizeConfig{
+			TemplateData: map[string]interface{}{"err": settingPrefixPage + slug + "/" + err.Error()},
+		}))
+	}
+	if page == nil {
+		return nil, errors.New(getI18n(c).Localize(getContextString(c, ctxLocale), "error_page_not_found", &goyai.LocalizeConfig{
+			TemplateData: map[string]interface{}{"slug": slug},
+		}))
+	}
+	return page, nil
+}
+
+// actionCpEditPage shows the form to edit the page of query parameter "slug".
+//
+// available since template-r5
+func actionCpEditPage(c echo.Context) error {
+	page, err := checkCpEditPage(c)
+	if err != nil {
+		AddFlashText(c, FlashWarning, err.Error())
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpPages)+"?r="+utils.RandomString(4))
+	}
+	AddBreadcrumb(c, "", "edit_page")
+	formData := url.Values{}
+	setPageForm(formData, page)
+	return renderCpCreateEditPage(c, true, formData, "")
+}
+
+// actionCpEditPageSubmit updates the page of query parameter "slug", see pageFromForm. The slug cannot be changed.
+//
+// available since template-r5
+func actionCpEditPageSubmit(c echo.Context) error {
+	page, err := checkCpEditPage(c)
+	if err != nil {
+		AddFlashText(c, FlashWarning, err.Error())
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpPages)+"?r="+utils.RandomString(4))
+	}
+	AddBreadcrumb(c, "", "edit_page")
+	formData, _ := c.FormParams()
+	updated := pageFromForm(formData)
+	formData.Set("slug", page.Slug)
+	if updated.Title == "" {
+		return renderCpCreateEditPage(c, true, formData, getI18n(c).Localize(getContextString(c, ctxLocale), "error_empty_page_title"))
+	}
+	page.Title, page.Description, page.Content, page.Published = updated.Title, updated.Description, updated.Content, updated.Published
+	if currentUser, _ := c.Get(ctxCurrentUser).(*User); currentUser != nil {
+		page.By = currentUser.Username
+	}
+	page.Updated = utils.Now()
+	myReg := getRegistry(c)
+	if err := myReg.pageDao.Save(page); err != nil {
+		return renderCpCreateEditPage(c, true, formData, getI18n(c).Localize(getContextString(c, ctxLocale), "error_db_001", &goyai.LocalizeConfig{
+			TemplateData: map[string]interface{}{"err": settingPrefixPage + page.Slug + "/" + err.Error()},
+		}))
+	}
+	myReg.auditf("user [%s] updated page [%s]", page.By, page.Slug)
+	AddFlash(c, FlashInfo, "update_page_successful", "slug", page.Slug)
+	return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpPages)+"?r="+utils.RandomString(4))
+}
+
+// actionCpDeletePageSubmit deletes the page of query parameter "slug".
+//
+// available since template-r5
+func actionCpDeletePageSubmit(c echo.Context) error {
+	page, err := checkCpEditPage(c)
+	if err != nil {
+		AddFlashText(c, FlashWarning, err.Error())
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpPages)+"?r="+utils.RandomString(4))
+	}
+	myReg := getRegistry(c)
+	if _, err := myReg.pageDao.Delete(page.Slug); err != nil {
+		AddFlash(c, FlashError, "error_db_001", "err", settingPrefixPage+page.Slug+"/"+err.Error())
+	} else {
+		username := ""
+		if currentUser, _ := c.Get(ctxCurrentUser).(*User); currentUser != nil {
+			username = currentUser.Username
+		}
+		myReg.auditf("user [%s] deleted page [%s]", username, page.Slug)
+		AddFlash(c, FlashInfo, "delete_page_successful", "slug", page.Slug)
+	}
+	return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpPages)+"?r="+utils.RandomString(4))
+}
+
+// actionCpPagePreviewSubmit renders Markdown (form field "content") for the preview tab of the page editor.
+//
+// available since template-r5
+func actionCpPagePreviewSubmit(c echo.Context) error {
+	if err := checkPermission(c, permPageManage); err != nil {
+		return echo.NewHTTPError(http.StatusForbidden, err.Error())
+	}
+	return c.HTML(http.StatusOK, string(goadmin.RenderMarkdown(c.FormValue("content"))))
+}
@@ -0,0 +1,48 @@
+package myapp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestPages(t *testing.T) {
+	testName := "TestPages"
+	h := _newHarness(t)
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+
+	form := url.Values{"slug": {"About Us"}, "title": {"About"}, "content": {"We are **Acme**."}}
+	h.AssertBodyContains(h.PostForm(h.Reverse(actionNameCpCreatePageSubmit), form), "Slug must contain only lowercase letters")
+	form.Set("slug", "about")
+	h.AssertRedirect(h.PostForm(h.Reverse(actionNameCpCreatePageSubmit), form), h.Reverse(actionNameCpPages))
+	h.AssertBodyContains(h.PostForm(h.Reverse(actionNameCpCreatePageSubmit), form), "already exists")
+
+	// unpublished pages are not served
+	h.AssertStatus(h.Get(h.Reverse(actionNamePage, "about")), http.StatusNotFound)
+	form.Set("published", "1")
+	h.AssertRedirect(h.PostForm(h.Reverse(actionNameCpEditPageSubmit)+"?slug=about", form), h.Reverse(actionNameCpPages))
+	resp := h.Get(h.Reverse(actionNamePage, "about"))
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertBodyContains(resp, "<strong>Acme</strong>")
+	etag := resp.Header().Get("ETag")
+	if etag == "" || !strings.HasPrefix(resp.Header().Get(echo.HeaderCacheControl), "public") {
+		t.Fatalf("%s failed: expected published pages to be cacheable, got headers %v", testName, resp.Header())
+	}
+	req := httptest.NewRequest(http.MethodGet, h.Reverse(actionNamePage, "about"), nil)
+	req.Header.Set("If-None-Match", etag)
+	h.AssertStatus(h.Do(req), http.StatusNotModified)
+	h.AssertBodyContains(h.Get("/sitemap.xml"), "/p/about</loc>")
+
+	h.AssertBodyContains(h.PostForm(h.Reverse(actionNameCpPagePreviewSubmit), url.Values{"content": {"# Title"}}), "<h1")
+
+	h.AssertRedirect(h.PostForm(h.Reverse(actionNameCpDeletePageSubmit)+"?slug=about", url.Values{}), h.Reverse(actionNameCpPages))
+	if page, _ := myReg.pageDao.Get("about"); page != nil {
+		t.Fatalf("%s failed: page must be deleted", testName)
+	}
+	h.AssertStatus(h.Get(h.Reverse(actionNamePage, "about")), http.StatusNotFound)
+}
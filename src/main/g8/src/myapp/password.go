@@ -0,0 +1,107 @@
+package myapp
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultPwnedPasswordsApiUrl  = "https://api.pwnedpasswords.com/range/"
+	defaultPwnedPasswordsTimeout = 3 * time.Second
+)
+
+// pwnedPasswordChecker checks passwords against the HaveIBeenPwned "Pwned Passwords" API using k-anonymity: only the
+// first 5 characters of the password's SHA-1 hash are sent, the API returns suffixes of all known-compromised hashes
+// sharing that prefix and the match is done locally.
+//
+// See: https://haveibeenpwned.com/API/v3#PwnedPasswords
+//
+// available since template-r5
+type pwnedPasswordChecker struct {
+	apiUrl   string // base URL of the range API, the hash prefix is appended to it
+	failOpen bool   // if true, passwords are accepted when the API cannot be reached
+	client   *http.Client
+}
+
+// newPwnedPasswordChecker creates a pwnedPasswordChecker from the configuration block myapp.password_breach_check,
+// nil is returned if the check is disabled.
+func newPwnedPasswordChecker(r *myRegistry) *pwnedPasswordChecker {
+	conf := r.AppConfig
+	if !conf.GetBoolean(namespace+".password_breach_check.enabled", false) {
+		return nil
+	}
+	apiUrl := conf.GetString(namespace+".password_breach_check.api_url", defaultPwnedPasswordsApiUrl)
+	if !strings.HasSuffix(apiUrl, "/") {
+		apiUrl += "/"
+	}
+	return &pwnedPasswordChecker{
+		apiUrl:   apiUrl,
+		failOpen: conf.GetBoolean(namespace+".password_breach_check.fail_open", true),
+		client:   &http.Client{Timeout: conf.GetTimeDuration(namespace+".password_breach_check.timeout", defaultPwnedPasswordsTimeout)},
+	}
+}
+
+// isCompromised returns true if the password appears in known data breaches.
+func (p *pwnedPasswordChecker) isCompromised(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+	req, err := http.NewRequest(http.MethodGet, p.apiUrl+prefix, nil)
+	if err != nil {
+		return false, err
+	}
+	// padding hides the number of suffixes sharing the prefix from observers; padded entries have count 0
+	req.Header.Set("Add-Padding", "true")
+	req.Header.Set("User-Agent", namespace)
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("unexpected response status %d from [%s]", resp.StatusCode, p.apiUrl)
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		// each line is in format <hash-suffix>:<count>
+		tokens := strings.SplitN(strings.TrimSpace(scanner.Text()), ":", 2)
+		if len(tokens) != 2 || !strings.EqualFold(tokens[0], suffix) {
+			continue
+		}
+		count, _ := strconv.Atoi(strings.TrimSpace(tokens[1]))
+		return count > 0, nil
+	}
+	return false, scanner.Err()
+}
+
+// checkPasswordBreach returns the (localized) error message if the candidate password is known to be compromised,
+// empty string if the password is accepted or the check is disabled (setting myapp.password_breach_check.enabled).
+//
+// If the API cannot be reached, the password is accepted when fail-open is enabled, rejected otherwise.
+func checkPasswordBreach(c echo.Context, password string) string {
+	checker := getRegistry(c).pwnedChecker
+	if checker == nil {
+		return ""
+	}
+	compromised, err := checker.isCompromised(password)
+	if err != nil {
+		log.Printf("[WARN] error checking password against breached passwords: %s", err)
+		if checker.failOpen {
+			return ""
+		}
+		return getI18n(c).Localize(getContextString(c, ctxLocale), "error_password_check_unavailable")
+	}
+	if compromised {
+		return getI18n(c).Localize(getContextString(c, ctxLocale), "error_password_compromised")
+	}
+	return ""
+}
@@ -0,0 +1,87 @@
+package myapp
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"main/src/apptest"
+)
+
+const testPwnedPassword = "P@ssw0rd"
+
+// _newPwnedPasswordsServer starts a fake Pwned Passwords range API that knows testPwnedPassword only.
+func _newPwnedPasswordsServer(t *testing.T) *httptest.Server {
+	sum := sha1.Sum([]byte(testPwnedPassword))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefix := strings.TrimPrefix(r.URL.Path, "/range/")
+		if len(prefix) != 5 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		// a padded entry (count 0) must not be reported as compromised
+		fmt.Fprintf(w, "0018A45C4D1DEF81644B54AB7F969B88D65:0\r\n")
+		if prefix == hash[:5] {
+			fmt.Fprintf(w, "%s:42\r\n", hash[5:])
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPwnedPasswordChecker(t *testing.T) {
+	server := _newPwnedPasswordsServer(t)
+	checker := &pwnedPasswordChecker{apiUrl: server.URL + "/range/", client: &http.Client{Timeout: time.Second}}
+	if compromised, err := checker.isCompromised(testPwnedPassword); err != nil || !compromised {
+		t.Fatalf("TestPwnedPasswordChecker failed: expected compromised password but received %#v/%s", compromised, err)
+	}
+	if compromised, err := checker.isCompromised("a-l0ng-and-un1que-passphrase"); err != nil || compromised {
+		t.Fatalf("TestPwnedPasswordChecker failed: expected safe password but received %#v/%s", compromised, err)
+	}
+	checker.apiUrl = server.URL + "/not-found/"
+	if _, err := checker.isCompromised(testPwnedPassword); err == nil {
+		t.Fatalf("TestPwnedPasswordChecker failed: expected error")
+	}
+}
+
+func TestActionCpChangePasswordSubmit_Compromised(t *testing.T) {
+	server := _newPwnedPasswordsServer(t)
+	conf := apptest.SqliteInMemoryConfig + `myapp.password_breach_check { enabled = true, api_url = "` + server.URL + `/range/" }`
+	h := apptest.New(t, conf, NewBootstrapper(nil, nil))
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	form := url.Values{"currentPassword": {testAdminPassword}, "password": {testPwnedPassword}, "password2": {testPwnedPassword}}
+	resp := h.PostForm(h.Reverse(actionNameCpChangePasswordSubmit), form)
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertData("error", "data breach")
+
+	form.Set("password", "a-l0ng-and-un1que-passphrase")
+	form.Set("password2", "a-l0ng-and-un1que-passphrase")
+	resp = h.PostForm(h.Reverse(actionNameCpChangePasswordSubmit), form)
+	h.AssertStatus(resp, http.StatusOK)
+	if errMsg, _ := h.LastData()["error"].(string); errMsg != "" {
+		t.Fatalf("TestActionCpChangePasswordSubmit_Compromised failed: unexpected error [%s]", errMsg)
+	}
+}
+
+func TestActionCpCreateUserSubmit_BreachCheckUnavailable(t *testing.T) {
+	for _, failOpen := range []bool{true, false} {
+		conf := apptest.SqliteInMemoryConfig + fmt.Sprintf(`myapp.password_breach_check { enabled = true, api_url = "http://127.0.0.1:1/range/", timeout = 1s, fail_open = %v }`, failOpen)
+		h := apptest.New(t, conf, NewBootstrapper(nil, nil))
+		h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+		form := url.Values{"username": {"tester"}, "name": {"Tester"}, "group": {systemGroupId}, "password": {"secret"}, "password2": {"secret"}}
+		resp := h.PostForm(h.Reverse(actionNameCpCreateUserSubmit), form)
+		if failOpen {
+			h.AssertRedirect(resp, h.Reverse(actionNameCpUsers))
+		} else {
+			h.AssertStatus(resp, http.StatusOK)
+			h.AssertData("error", "try again later")
+		}
+	}
+}
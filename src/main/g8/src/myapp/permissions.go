@@ -0,0 +1,119 @@
+package myapp
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/btnguyen2k/goyai"
+	"github.com/labstack/echo/v4"
+
+	"main/src/goadmin"
+)
+
+// Permissions of control panel actions. They are checked by action handlers (see checkPermission) and by views via
+// template function "can" (e.g. {{if can "user.delete"}}), so that pages only show what the current user can do.
+const (
+	permGroupCreate       = "group.create"
+	permGroupEdit         = "group.edit"
+	permGroupDelete       = "group.delete"
+	permGroupReport       = "group.report"
+	permUserCreate        = "user.create"
+	permUserEdit          = "user.edit"
+	permUserDelete        = "user.delete"
+	permUserAttachment    = "user.attachment" // download files attached to user accounts
+	permTranslationManage = "translation.manage"
+	permAnalyticsView     = "analytics.view"
+	permAuditView         = "audit.view"
+	permEmailManage       = "email.manage"
+	permApiManage         = "api.manage"      // API tokens of all users and their limits
+	permPageManage        = "page.manage"     // public pages served at /p/<slug>
+	permInboxManage       = "inbox.manage"    // messages of the contact form
+	permSettingsManage    = "settings.manage" // security, logging, retention, read-only mode and config bundle
+)
+
+var allPermissions = []string{
+	permGroupCreate, permGroupEdit, permGroupDelete, permGroupReport,
+	permUserCreate, permUserEdit, permUserDelete, permUserAttachment,
+	permTranslationManage, permAnalyticsView, permAuditView, permEmailManage, permApiManage, permSettingsManage,
+	permPageManage, permInboxManage,
+}
+
+// loadGroupPermissions reads permissions granted to groups other than the system group (configuration block
+// myapp.permissions, group id -> list of permissions). Members of the system group are granted all permissions.
+//
+// available since template-r5
+func (r *myRegistry) loadGroupPermissions() error {
+	r.permissions = make(map[string]map[string]bool)
+	confPath := namespace + ".permissions"
+	v := r.AppConfig.GetValue(confPath)
+	if v == nil || !v.IsObject() {
+		return nil
+	}
+	for gid := range v.GetObject().Items() {
+		if node := r.AppConfig.GetValue(confPath + "." + gid); node == nil || !node.IsArray() {
+			return fmt.Errorf("permissions of group [%s] must be a list", gid)
+		}
+		granted, err := parsePermissions(gid, r.AppConfig.GetStringList(confPath+"."+gid))
+		if err != nil {
+			return err
+		}
+		r.permissions[strings.ToLower(gid)] = granted
+	}
+	return nil
+}
+
+// parsePermissions normalizes the list of permissions granted to a group, unknown permissions are rejected.
+func parsePermissions(gid string, perms []string) (map[string]bool, error) {
+	granted := make(map[string]bool, len(perms))
+	for _, p := range perms {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if !containsString(allPermissions, p) {
+			sorted := append([]string{}, allPermissions...)
+			sort.Strings(sorted)
+			return nil, fmt.Errorf("unknown permission [%s] granted to group [%s], valid values are %s", p, gid, strings.Join(sorted, ", "))
+		}
+		granted[p] = true
+	}
+	return granted, nil
+}
+
+// hasPermission checks if a user is granted a permission.
+func (r *myRegistry) hasPermission(user *User, perm string) bool {
+	if user == nil {
+		return false
+	}
+	if user.GroupId == systemGroupId {
+		return true
+	}
+	return r.permissions[strings.ToLower(user.GroupId)][perm]
+}
+
+// can checks if the user viewing the page (set by middlewareRequiredAuth) is granted a permission. It backs template
+// function "can". Outside of requests served by the application (no registry attached to the context), only
+// members of the system group are granted permissions.
+func can(c echo.Context, perm string) bool {
+	viewer, _ := c.Get(ctxCurrentUser).(*User)
+	if viewer == nil {
+		return false
+	}
+	if goadmin.GetRegistry(c) == nil {
+		return viewer.GroupId == systemGroupId
+	}
+	return getRegistry(c).hasPermission(viewer, perm)
+}
+
+// checkPermission checks if the current user is granted a permission, the returned error is localized.
+func checkPermission(c echo.Context, perm string) error {
+	if currentUser, err := getCurrentUser(c); err != nil {
+		errMsg := getI18n(c).Localize(getContextString(c, ctxLocale), "error_db_101", &goyai.LocalizeConfig{
+			TemplateData: map[string]interface{}{"err": "current_user/" + err.Error()},
+		})
+		return errors.New(errMsg)
+	} else if !getRegistry(c).hasPermission(currentUser, perm) {
+		errMsg := getI18n(c).Localize(getContextString(c, ctxLocale), "error_no_permission")
+		return errors.New(errMsg)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package myapp
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"main/src/apptest"
+)
+
+func TestPermissions(t *testing.T) {
+	testName := "TestPermissions"
+	h := apptest.New(t, apptest.SqliteInMemoryConfig+"\nmyapp.permissions { editors = [\"user.create\", \"user.edit\"] }\n", NewBootstrapper(nil, nil))
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	myReg.groupDao.Create("editors", "Editors")
+	myReg.userDao.Create("editor@local", encryptPassword("editor@local", "Ed1t0r"), "Editor", "editors")
+	editor, _ := myReg.userDao.Get("editor@local")
+	if !myReg.hasPermission(editor, permUserEdit) || myReg.hasPermission(editor, permUserDelete) || myReg.hasPermission(nil, permUserEdit) {
+		t.Fatalf("%s failed: unexpected permissions of group [editors]", testName)
+	}
+
+	h.Login(h.Reverse(actionNameCpLoginSubmit), "editor@local", "Ed1t0r")
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpCreateUser)), http.StatusOK)
+	h.AssertRedirect(h.Get(h.Reverse(actionNameCpCreateGroup)), h.Reverse(actionNameCpGroups))
+
+	// views show only actions the user is granted
+	h.AssertBodyContains(h.Get(h.Reverse(actionNameCpUsers)), `href="`+h.Reverse(actionNameCpCreateUser)+`"`)
+	resp := h.Get(h.Reverse(actionNameCpGroups))
+	h.AssertStatus(resp, http.StatusOK)
+	for _, action := range []string{actionNameCpCreateGroup, actionNameCpGroupsReport, actionNameCpSecuritySettings} {
+		if strings.Contains(resp.Body.String(), `"`+h.Reverse(action)+`"`) {
+			t.Fatalf("%s failed: link to [%s] should not be shown", testName, action)
+		}
+	}
+}
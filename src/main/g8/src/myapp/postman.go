@@ -0,0 +1,146 @@
+package myapp
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"main/src/goadmin"
+)
+
+// The JSON API is also exported as a Postman collection (format v2.1, which Insomnia imports as well), built from the
+// same endpoint metadata as the OpenAPI spec: a request per endpoint, authenticated with variable {{apiToken}}, with an
+// example response.
+
+// postmanSchemaUrl identifies the format of exported collections.
+const postmanSchemaUrl = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+// postmanCollection is a Postman collection, limited to what the JSON API uses.
+type postmanCollection struct {
+	Info     *postmanInfo       `json:"info"`
+	Auth     *postmanAuth       `json:"auth"`
+	Variable []*postmanVariable `json:"variable"`
+	Item     []*postmanItem     `json:"item"`
+}
+
+type postmanInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Schema      string `json:"schema"`
+}
+
+type postmanAuth struct {
+	Type   string             `json:"type"`
+	Bearer []*postmanVariable `json:"bearer"`
+}
+
+// postmanVariable is a key/value pair of a collection: variable, header or query parameter.
+type postmanVariable struct {
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+	Disabled    bool   `json:"disabled,omitempty"`
+}
+
+type postmanItem struct {
+	Name     string             `json:"name"`
+	Request  *postmanRequest    `json:"request"`
+	Response []*postmanResponse `json:"response"`
+}
+
+type postmanRequest struct {
+	Method      string             `json:"method"`
+	Header      []*postmanVariable `json:"header"`
+	Url         *postmanUrl        `json:"url"`
+	Description string             `json:"description,omitempty"`
+}
+
+type postmanUrl struct {
+	Raw   string             `json:"raw"`
+	Host  []string           `json:"host"`
+	Path  []string           `json:"path"`
+	Query []*postmanVariable `json:"query,omitempty"`
+}
+
+// postmanResponse is an example response of a request.
+type postmanResponse struct {
+	Name            string             `json:"name"`
+	OriginalRequest *postmanRequest    `json:"originalRequest"`
+	Status          string             `json:"status"`
+	Code            int                `json:"code"`
+	PreviewLanguage string             `json:"_postman_previewlanguage"`
+	Header          []*postmanVariable `json:"header"`
+	Body            string             `json:"body"`
+}
+
+// apiCollection returns the Postman collection of the latest version of the JSON API, requests are sent to baseUrl
+// (variable {{baseUrl}} of the collection).
+func apiCollection(title, baseUrl string) *postmanCollection {
+	collection := &postmanCollection{
+		Info: &postmanInfo{Name: title, Description: apiSpec(title).Info.Description, Schema: postmanSchemaUrl},
+		Auth: &postmanAuth{Type: "bearer", Bearer: []*postmanVariable{{Key: "token", Value: "{{apiToken}}", Type: "string"}}},
+		Variable: []*postmanVariable{
+			{Key: "baseUrl", Value: baseUrl, Type: "string"},
+			{Key: "apiToken", Value: "", Type: "string", Description: "API token, created on page /cp/tokens"},
+		},
+		Item: make([]*postmanItem, 0, len(apiEndpoints)),
+	}
+	for _, endpoint := range apiEndpoints {
+		path := "/api/" + apiLatestVersion + endpoint.path
+		req := &postmanRequest{
+			Method:      http.MethodGet,
+			Header:      []*postmanVariable{{Key: echo.HeaderAccept, Value: echo.MIMEApplicationJSON}},
+			Url:         &postmanUrl{Raw: "{{baseUrl}}" + path, Host: []string{"{{baseUrl}}"}, Path: strings.Split(strings.TrimPrefix(path, "/"), "/")},
+			Description: endpoint.summary,
+		}
+		if endpoint.list {
+			// paging is disabled in the request, enable the parameters to iterate over large lists
+			req.Url.Query = []*postmanVariable{
+				{Key: "limit", Value: "100", Description: "Page size, the whole list is returned if neither limit nor cursor is supplied", Disabled: true},
+				{Key: "cursor", Value: "", Description: "Field next_cursor of the previous page", Disabled: true},
+			}
+		}
+		body, _ := json.MarshalIndent(apiExample(endpoint), "", "  ")
+		collection.Item = append(collection.Item, &postmanItem{
+			Name:    endpoint.summary,
+			Request: req,
+			Response: []*postmanResponse{{
+				Name:            "OK",
+				OriginalRequest: req,
+				Status:          http.StatusText(http.StatusOK),
+				Code:            http.StatusOK,
+				PreviewLanguage: "json",
+				Header:          []*postmanVariable{{Key: echo.HeaderContentType, Value: echo.MIMEApplicationJSON}},
+				Body:            string(body),
+			}},
+		})
+	}
+	return collection
+}
+
+// apiTitle returns the title of the JSON API in the OpenAPI spec and the Postman collection.
+func apiTitle(c echo.Context) string {
+	if name := getRegistry(c).AppConfig.GetString("app.name", ""); name != "" {
+		return name + " API"
+	}
+	return defaultApiSpecTitle
+}
+
+// actionCpApiCollection downloads the Postman collection of the JSON API, requests are sent to the host the collection
+// is downloaded from.
+//
+// available since template-r5
+func actionCpApiCollection(c echo.Context) error {
+	if getRegistry(c).api == nil {
+		return echo.ErrNotFound
+	}
+	baseUrl := c.Scheme() + "://" + c.Request().Host + goadmin.GetRegistry(c).Url("")
+	js, err := json.MarshalIndent(apiCollection(apiTitle(c), baseUrl), "", "  ")
+	if err != nil {
+		return err
+	}
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="api.postman_collection.json"`)
+	return c.Blob(http.StatusOK, echo.MIMEApplicationJSONCharsetUTF8, js)
+}
@@ -0,0 +1,51 @@
+package myapp
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestApiCollection(t *testing.T) {
+	testName := "TestApiCollection"
+	h := _newHarness(t)
+	h.AssertRedirect(h.Get(h.Reverse(actionNameCpApiCollection)), h.Reverse(actionNameCpLogin))
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	resp := h.Get(h.Reverse(actionNameCpApiCollection))
+	h.AssertStatus(resp, http.StatusOK)
+	if disposition := resp.Header().Get(echo.HeaderContentDisposition); !strings.HasPrefix(disposition, "attachment") {
+		t.Fatalf("%s failed: collection must be downloaded as attachment, received [%s]", testName, disposition)
+	}
+	collection := &postmanCollection{}
+	if err := json.Unmarshal(resp.Body.Bytes(), collection); err != nil {
+		t.Fatalf("%s failed: cannot parse collection: %s", testName, err)
+	}
+	if collection.Info.Schema != postmanSchemaUrl || collection.Auth.Type != "bearer" || len(collection.Item) != len(apiEndpoints) {
+		t.Fatalf("%s failed: unexpected collection %#v", testName, collection)
+	}
+	if collection.Variable[0].Key != "baseUrl" || !strings.HasPrefix(collection.Variable[0].Value, "http") {
+		t.Fatalf("%s failed: unexpected variables %#v", testName, collection.Variable)
+	}
+
+	// requests and example payloads follow the endpoints
+	for i, endpoint := range apiEndpoints {
+		item := collection.Item[i]
+		if path := "/" + strings.Join(item.Request.Url.Path, "/"); path != h.Reverse(endpoint.name+"_"+apiLatestVersion) {
+			t.Fatalf("%s failed: unexpected path [%s] of endpoint [%s]", testName, path, endpoint.name)
+		}
+		if endpoint.list != (len(item.Request.Url.Query) > 0) {
+			t.Fatalf("%s failed: paging parameters expected for list endpoints only, endpoint [%s]", testName, endpoint.name)
+		}
+		doc := map[string]interface{}{}
+		if len(item.Response) != 1 || json.Unmarshal([]byte(item.Response[0].Body), &doc) != nil || doc["data"] == nil {
+			t.Fatalf("%s failed: unexpected example responses of endpoint [%s] %#v", testName, endpoint.name, item.Response)
+		}
+	}
+	body := collection.Item[1].Response[0].Body
+	if !strings.Contains(body, `"username": "jane.doe@example.com"`) || strings.Contains(body, "url_edit") {
+		t.Fatalf("%s failed: unexpected example payload\n%s", testName, body)
+	}
+}
@@ -0,0 +1,214 @@
+package myapp
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"main/src/utils"
+)
+
+const (
+	// settingPrefixGroupPreferences prefixes ids of settings that store default preferences of members of a group.
+	settingPrefixGroupPreferences = "group_preferences:"
+	// settingPrefixUserPreferences prefixes ids of settings that store preferences of a user.
+	settingPrefixUserPreferences = "user_preferences:"
+
+	themeLight = "light"
+	themeDark  = "dark"
+
+	defaultPageSize = 25
+	maxPageSize     = maxDataTablesPageSize
+
+	ctxPreferences = "prefs"
+)
+
+// themes lists the supported themes of the control panel.
+var themes = []string{themeLight, themeDark}
+
+// Preferences are display preferences of the control panel. They are resolved for the current user from the
+// application's defaults (configuration block myapp.preferences), overridden by the defaults of the user's group
+// (see actionCpEditGroup), overridden by the user's own choices (see actionCpProfile); empty values are inherited.
+//
+// available since template-r5
+type Preferences struct {
+	Locale   string `json:"locale,omitempty"`    // empty to detect from the browser (see detectLocale)
+	Theme    string `json:"theme,omitempty"`     // "light" or "dark"
+	PageSize int    `json:"page_size,omitempty"` // default number of rows of paged tables
+}
+
+// validate clears invalid values, so that they are inherited.
+func (p *Preferences) validate(r *myRegistry) {
+	if p.Locale = strings.TrimSpace(p.Locale); !isValidLocale(p.Locale, r.i18n) {
+		p.Locale = ""
+	}
+	if p.Theme = strings.ToLower(strings.TrimSpace(p.Theme)); !containsString(themes, p.Theme) {
+		p.Theme = ""
+	}
+	if p.PageSize < 0 || p.PageSize > maxPageSize {
+		p.PageSize = 0
+	}
+}
+
+// merge overrides the preferences with non-empty values of other.
+func (p *Preferences) merge(other *Preferences) {
+	if other == nil {
+		return
+	}
+	if other.Locale != "" {
+		p.Locale = other.Locale
+	}
+	if other.Theme != "" {
+		p.Theme = other.Theme
+	}
+	if other.PageSize > 0 {
+		p.PageSize = other.PageSize
+	}
+}
+
+// preferencesFromForm reads preferences from form fields "pref_locale", "pref_theme" and "pref_page_size".
+func (r *myRegistry) preferencesFromForm(formData url.Values) *Preferences {
+	p := &Preferences{Locale: formData.Get("pref_locale"), Theme: formData.Get("pref_theme")}
+	p.PageSize, _ = strconv.Atoi(strings.TrimSpace(formData.Get("pref_page_size")))
+	p.validate(r)
+	return p
+}
+
+// setPreferencesForm sets form fields of the preferences, see preferencesFromForm.
+func setPreferencesForm(formData url.Values, p *Preferences) {
+	formData.Set("pref_locale", p.Locale)
+	formData.Set("pref_theme", p.Theme)
+	if p.PageSize > 0 {
+		formData.Set("pref_page_size", strconv.Itoa(p.PageSize))
+	}
+}
+
+// currentUserPreferencesForm returns form fields of the preferences chosen by the current user.
+func currentUserPreferencesForm(c echo.Context) url.Values {
+	formData := url.Values{}
+	if currentUser, _ := c.Get(ctxCurrentUser).(*User); currentUser != nil {
+		if p, err := getRegistry(c).userPreferences(currentUser.Username); err != nil {
+			log.Printf("[ERROR] cannot load preferences of user [%s]: %s", currentUser.Username, err)
+		} else {
+			setPreferencesForm(formData, p)
+		}
+	}
+	return formData
+}
+
+// defaultPreferences returns the application's default preferences, from the configuration block myapp.preferences.
+func (r *myRegistry) defaultPreferences() *Preferences {
+	p := &Preferences{
+		Locale:   r.AppConfig.GetString(namespace+".preferences.locale", ""),
+		Theme:    r.AppConfig.GetString(namespace+".preferences.theme", themeLight),
+		PageSize: int(r.AppConfig.GetInt32(namespace+".preferences.page_size", defaultPageSize)),
+	}
+	p.validate(r)
+	if p.Theme == "" {
+		p.Theme = themeLight
+	}
+	if p.PageSize <= 0 {
+		p.PageSize = defaultPageSize
+	}
+	return p
+}
+
+// loadPreferences reads preferences stored in the setting, empty if none.
+func (r *myRegistry) loadPreferences(id string) (*Preferences, error) {
+	p := &Preferences{}
+	if _, err := r.loadSetting(id, p); err != nil {
+		return &Preferences{}, err
+	}
+	p.validate(r)
+	return p, nil
+}
+
+// groupPreferences returns the default preferences of members of the group, empty if none.
+func (r *myRegistry) groupPreferences(groupId string) (*Preferences, error) {
+	return r.loadPreferences(settingPrefixGroupPreferences + groupId)
+}
+
+// userPreferences returns the preferences chosen by the user, empty if none.
+func (r *myRegistry) userPreferences(username string) (*Preferences, error) {
+	return r.loadPreferences(settingPrefixUserPreferences + username)
+}
+
+// ResolvePreferences returns the effective preferences of the user: application defaults, overridden by the defaults
+// of the user's group, overridden by the user's preferences. Defaults are returned if user is nil.
+//
+// available since template-r5
+func (r *myRegistry) ResolvePreferences(user *User) *Preferences {
+	result := r.defaultPreferences()
+	if user == nil {
+		return result
+	}
+	if p, err := r.groupPreferences(user.GroupId); err != nil {
+		log.Printf("[ERROR] cannot load preferences of group [%s]: %s", user.GroupId, err)
+	} else {
+		result.merge(p)
+	}
+	if p, err := r.userPreferences(user.Username); err != nil {
+		log.Printf("[ERROR] cannot load preferences of user [%s]: %s", user.Username, err)
+	} else {
+		result.merge(p)
+	}
+	return result
+}
+
+// getPreferences returns the effective preferences of the current user, resolved once per request.
+func getPreferences(c echo.Context) *Preferences {
+	if p, ok := c.Get(ctxPreferences).(*Preferences); ok {
+		return p
+	}
+	currentUser, _ := c.Get(ctxCurrentUser).(*User)
+	p := getRegistry(c).ResolvePreferences(currentUser)
+	c.Set(ctxPreferences, p)
+	return p
+}
+
+// Preferences returns the effective preferences of the current user, see myRegistry.ResolvePreferences.
+//
+// available since template-r5
+func (u *MyAppUtils) Preferences() *Preferences {
+	return getPreferences(u.c)
+}
+
+// middlewarePreferences applies the preferred locale of the current user to requests that do not choose one
+// explicitly (query parameter "_l" or the locale cookie set by the language menu).
+//
+// available since template-r5
+func middlewarePreferences(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		p := getPreferences(c)
+		if p.Locale != "" && !isValidLocale(c.QueryParam("_l"), getI18n(c)) && !isValidLocale(getCookieString(c, cookieLocale), getI18n(c)) {
+			c.Set(ctxLocale, p.Locale)
+		}
+		return next(c)
+	}
+}
+
+// actionCpPreferencesSubmit saves the preferences of the current user (see preferencesFromForm).
+//
+// available since template-r5
+func actionCpPreferencesSubmit(c echo.Context) error {
+	currentUser, _ := c.Get(ctxCurrentUser).(*User)
+	if currentUser == nil {
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpLogin))
+	}
+	formData, _ := c.FormParams()
+	myReg := getRegistry(c)
+	p := myReg.preferencesFromForm(formData)
+	if err := myReg.saveSetting(settingPrefixUserPreferences+currentUser.Username, p); err != nil {
+		AddFlash(c, FlashError, "error_db_001", "err", settingPrefixUserPreferences+currentUser.Username+"/"+err.Error())
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpProfile)+"?r="+utils.RandomString(4))
+	}
+	if p.Locale != "" {
+		// the language menu may have set another locale on this browser
+		setCookie(c, cookieLocale, p.Locale)
+	}
+	AddFlash(c, FlashInfo, "update_preferences_successful")
+	return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpProfile)+"?r="+utils.RandomString(4))
+}
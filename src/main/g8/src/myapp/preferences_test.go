@@ -0,0 +1,46 @@
+package myapp
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestPreferences(t *testing.T) {
+	testName := "TestPreferences"
+	p := &Preferences{Locale: "en", Theme: themeLight, PageSize: 25}
+	p.merge(&Preferences{Theme: themeDark})
+	if p.Locale != "en" || p.Theme != themeDark || p.PageSize != 25 {
+		t.Fatalf("%s failed: unexpected merged preferences %#v", testName, p)
+	}
+
+	h := _newHarness(t)
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	if prefs := myReg.ResolvePreferences(nil); prefs.Theme != themeLight || prefs.PageSize != defaultPageSize {
+		t.Fatalf("%s failed: unexpected default preferences %#v", testName, prefs)
+	}
+
+	// defaults of the group apply to its members
+	group, _ := myReg.groupDao.Get(systemGroupId)
+	form := url.Values{"name": {group.Name}, "pref_locale": {"vi"}, "pref_theme": {themeDark}, "pref_page_size": {"10"}}
+	h.AssertRedirect(h.PostForm(h.Reverse(actionNameCpEditGroupSubmit)+"?id="+systemGroupId, form), h.Reverse(actionNameCpGroups))
+	resp := h.Get(h.Reverse(actionNameCpDashboard))
+	h.AssertBodyContains(resp, "dark-mode")
+	h.AssertBodyContains(resp, `lang="vi"`)
+	if prefs, _ := h.LastData()["prefs"].(*Preferences); prefs == nil || prefs.PageSize != 10 {
+		t.Fatalf("%s failed: expected page size of the group, got %#v", testName, prefs)
+	}
+
+	// users override defaults of their group, empty fields are inherited
+	form = url.Values{"pref_theme": {themeLight}, "pref_page_size": {"100"}}
+	h.AssertRedirect(h.PostForm(h.Reverse(actionNameCpPreferencesSubmit), form), h.Reverse(actionNameCpProfile))
+	resp = h.Get(h.Reverse(actionNameCpDashboard))
+	if strings.Contains(resp.Body.String(), "dark-mode") {
+		t.Fatalf("%s failed: expected theme of the user to override the group's", testName)
+	}
+	h.AssertBodyContains(resp, `lang="vi"`)
+	if prefs, _ := h.LastData()["prefs"].(*Preferences); prefs == nil || prefs.PageSize != 100 {
+		t.Fatalf("%s failed: expected page size of the user, got %#v", testName, prefs)
+	}
+}
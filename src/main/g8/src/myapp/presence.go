@@ -0,0 +1,230 @@
+package myapp
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"main/src/goadmin"
+	"main/src/utils"
+)
+
+const (
+	// presenceCacheKey is the cache key of the presence roster, shared by all instances with the Redis cache driver.
+	presenceCacheKey = namespace + ":presence"
+
+	// presenceMaxAge is how long "last seen" times are kept in the roster.
+	presenceMaxAge = 30 * 24 * time.Hour
+
+	// ctxPresenceRoster caches the roster for the duration of a request, see presenceTracker.rosterOf.
+	ctxPresenceRoster = "presence"
+)
+
+// PresenceEntry is the last heartbeat of a user, i.e. the last time the user requested a page of the control panel.
+//
+// available since template-r5
+type PresenceEntry struct {
+	Username string    `json:"u"`
+	Name     string    `json:"n"`
+	LastSeen time.Time `json:"t"`
+}
+
+// LastSeenStr returns the time of the last heartbeat in the application's timezone.
+func (e *PresenceEntry) LastSeenStr() string {
+	return e.LastSeen.In(utils.Location).Format("2006-01-02 15:04:05")
+}
+
+// presenceTracker records heartbeats of signed-in users (configuration block myapp.presence), so that users who are
+// currently online are shown on the dashboard and flagged on the user list.
+//
+// Heartbeats are kept in a roster stored in the application's cache (setting goadmin.cache): with the Redis cache
+// driver, users signed in to any instance are seen by all of them. Each instance writes the heartbeat of a user at
+// most once per heartbeat interval, users are online if their last heartbeat is within the online window. Presence is
+// soft real-time: users closing their browser are shown online until the window elapses.
+//
+// available since template-r5
+type presenceTracker struct {
+	r                 *myRegistry
+	onlineWindow      time.Duration
+	heartbeatInterval time.Duration
+
+	lock  sync.Mutex
+	beats map[string]time.Time // last heartbeat written by this instance, per user
+}
+
+// newPresenceTracker creates a presenceTracker from the configuration block myapp.presence, nil is returned if
+// presence tracking is disabled.
+func newPresenceTracker(r *myRegistry) *presenceTracker {
+	conf := r.AppConfig
+	confPath := namespace + ".presence"
+	if !conf.GetBoolean(confPath+".enabled", true) {
+		return nil
+	}
+	p := &presenceTracker{
+		r:                 r,
+		onlineWindow:      conf.GetTimeDuration(confPath+".online_window", 5*time.Minute),
+		heartbeatInterval: conf.GetTimeDuration(confPath+".heartbeat_interval", 30*time.Second),
+		beats:             make(map[string]time.Time),
+	}
+	if p.onlineWindow <= 0 {
+		p.onlineWindow = 5 * time.Minute
+	}
+	return p
+}
+
+// middleware records a heartbeat of the signed-in user on every request to the control panel, before the request is
+// served so that the user is already online on the page being rendered.
+func (p *presenceTracker) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if user, _ := c.Get(ctxCurrentUser).(*User); user != nil {
+			p.heartbeat(user, utils.Now())
+		}
+		return next(c)
+	}
+}
+
+// heartbeat records that the user is online at now. Failures are logged but not returned: presence must not break
+// the control panel.
+func (p *presenceTracker) heartbeat(user *User, now time.Time) {
+	p.lock.Lock()
+	if last, ok := p.beats[user.Username]; ok && now.Sub(last) < p.heartbeatInterval {
+		p.lock.Unlock()
+		return
+	}
+	p.beats[user.Username] = now
+	p.lock.Unlock()
+
+	// the roster is read, modified and written back while holding the lock, so that concurrent heartbeats of
+	// different users (possibly on different instances) are not lost
+	err := p.r.WithLock(presenceCacheKey, 5*time.Second, func() error {
+		roster, err := p.load()
+		if err != nil {
+			return err
+		}
+		entry := &PresenceEntry{Username: user.Username, Name: user.Name, LastSeen: now}
+		if p.r.FieldCipher != nil {
+			// names are encrypted at rest, they must not be kept in the cache in plaintext
+			entry.Name = ""
+		}
+		roster[user.Username] = entry
+		for username, entry := range roster {
+			if now.Sub(entry.LastSeen) > presenceMaxAge {
+				delete(roster, username)
+			}
+		}
+		js, err := json.Marshal(roster)
+		if err != nil {
+			return err
+		}
+		return p.r.Cache.Set(presenceCacheKey, js, presenceMaxAge)
+	})
+	if err != nil {
+		log.Printf("[WARN] cannot record heartbeat of user [%s]: %s", user.Username, err)
+		p.lock.Lock()
+		delete(p.beats, user.Username)
+		p.lock.Unlock()
+	}
+}
+
+// load reads the roster from the cache, keyed by username.
+func (p *presenceTracker) load() (map[string]*PresenceEntry, error) {
+	roster := make(map[string]*PresenceEntry)
+	js, err := p.r.Cache.Get(presenceCacheKey)
+	if errors.Is(err, goadmin.ErrCacheMiss) {
+		return roster, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return roster, json.Unmarshal(js, &roster)
+}
+
+// rosterOf returns the roster, loaded once per request.
+func (p *presenceTracker) rosterOf(c echo.Context) map[string]*PresenceEntry {
+	if roster, ok := c.Get(ctxPresenceRoster).(map[string]*PresenceEntry); ok {
+		return roster
+	}
+	roster, err := p.load()
+	if err != nil {
+		log.Printf("[WARN] cannot load presence roster: %s", err)
+		roster = make(map[string]*PresenceEntry)
+	}
+	c.Set(ctxPresenceRoster, roster)
+	return roster
+}
+
+// isOnline checks if the last heartbeat of the entry is within the online window.
+func (p *presenceTracker) isOnline(entry *PresenceEntry, now time.Time) bool {
+	return entry != nil && now.Sub(entry.LastSeen) <= p.onlineWindow
+}
+
+// online returns users currently online, most recently seen first.
+func (p *presenceTracker) online(c echo.Context) []*PresenceEntry {
+	now := utils.Now()
+	result := make([]*PresenceEntry, 0)
+	for _, entry := range p.rosterOf(c) {
+		if p.isOnline(entry, now) {
+			result = append(result, entry)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].LastSeen.After(result[j].LastSeen) })
+	return result
+}
+
+// etagPart returns the users currently online, part of ETags of pages showing presence (see pageETag).
+func (p *presenceTracker) etagPart(c echo.Context) string {
+	online := p.online(c)
+	usernames := make([]string, len(online))
+	for i, entry := range online {
+		usernames[i] = entry.Username
+	}
+	sort.Strings(usernames)
+	return strings.Join(usernames, ",")
+}
+
+/*----------------------------------------------------------------------*/
+
+// userPresence returns the last heartbeat of a user, nil if the user has not been seen or presence tracking is
+// disabled.
+func userPresence(c echo.Context, username string) *PresenceEntry {
+	p := getRegistry(c).presence
+	if p == nil {
+		return nil
+	}
+	return p.rosterOf(c)[username]
+}
+
+// PresenceEnabled checks if presence of users is tracked, see presenceTracker.
+func (m *UserModel) PresenceEnabled() bool {
+	return getRegistry(m.c).presence != nil
+}
+
+// Online checks if the user is currently online.
+func (m *UserModel) Online() bool {
+	p := getRegistry(m.c).presence
+	return p != nil && p.isOnline(userPresence(m.c, m.Username), utils.Now())
+}
+
+// LastSeen returns the last time the user was seen, nil if unknown.
+func (m *UserModel) LastSeen() *PresenceEntry {
+	return userPresence(m.c, m.Username)
+}
+
+// OnlineUsers returns users currently online, most recently seen first, empty if presence tracking is disabled.
+func (u *MyAppUtils) OnlineUsers() []*PresenceEntry {
+	p := getRegistry(u.c).presence
+	if p == nil {
+		return make([]*PresenceEntry, 0)
+	}
+	return p.online(u.c)
+}
+
+func fragmentOnlineUsers(c echo.Context) map[string]interface{} {
+	u := &MyAppUtils{c: c}
+	return map[string]interface{}{"onlineUsers": u.OnlineUsers(), "presenceEnabled": getRegistry(c).presence != nil}
+}
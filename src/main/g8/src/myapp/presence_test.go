@@ -0,0 +1,48 @@
+package myapp
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestPresence(t *testing.T) {
+	testName := "TestPresence"
+	h := _newHarness(t)
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	if myReg.presence == nil {
+		t.Fatalf("%s failed: presence must be enabled by default", testName)
+	}
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpDashboard)), http.StatusOK)
+	online, _ := h.LastData()["onlineUsers"].([]*PresenceEntry)
+	if len(online) != 1 || online[0].Username != testAdminUsername {
+		t.Fatalf("%s failed: signed-in user must be online, received %#v", testName, online)
+	}
+	resp := h.Get(h.Reverse(actionNameCpUsers))
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertBodyContains(resp, "badge-success")
+
+	// users are offline once their last heartbeat is out of the online window
+	now := time.Now()
+	user := &User{Username: "away@local", Name: "Away"}
+	myReg.presence.heartbeat(user, now.Add(-myReg.presence.onlineWindow-time.Minute))
+	roster, err := myReg.presence.load()
+	if err != nil || roster[user.Username] == nil {
+		t.Fatalf("%s failed: heartbeat must be recorded (error %s)", testName, err)
+	}
+	if myReg.presence.isOnline(roster[user.Username], now) {
+		t.Fatalf("%s failed: user seen before the online window must be offline", testName)
+	}
+
+	// heartbeats of a user are written at most once per interval
+	last := roster[testAdminUsername].LastSeen
+	myReg.presence.heartbeat(&User{Username: testAdminUsername}, last.Add(myReg.presence.heartbeatInterval/2))
+	if roster, _ = myReg.presence.load(); !roster[testAdminUsername].LastSeen.Equal(last) {
+		t.Fatalf("%s failed: heartbeat within the interval must be skipped", testName)
+	}
+	myReg.presence.heartbeat(&User{Username: testAdminUsername}, last.Add(myReg.presence.heartbeatInterval))
+	if roster, _ = myReg.presence.load(); roster[testAdminUsername].LastSeen.Equal(last) {
+		t.Fatalf("%s failed: heartbeat after the interval must be recorded", testName)
+	}
+}
@@ -0,0 +1,55 @@
+package myapp
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/btnguyen2k/consu/reddo"
+	"github.com/labstack/echo/v4"
+	"main/src/goadmin"
+)
+
+// permissionSystem is the permission of protected resources (setting protected_resources) that only system users can
+// access. Other non-empty permissions are group ids: members of the group (and system users) can access the resource.
+const permissionSystem = "system"
+
+// authorizeResource implements goadmin.ResourceAuthorizer: files of protected resources are served to signed-in users
+// whose group satisfies the resource's permission. Requests via signed URLs do not reach here.
+//
+// available since template-r5
+func (r *myRegistry) authorizeResource(c echo.Context, res *goadmin.ProtectedResource, file string) error {
+	sess := getSession(c)
+	uid, _ := reddo.ToString(sess.Values[sessionMyUid])
+	if uid == "" {
+		return echo.ErrUnauthorized
+	}
+	if reverify, _ := sess.Values[sessionReverify].(bool); reverify {
+		// the sign-in has not been confirmed yet, see SecuritySettings.RequireReverification
+		return echo.ErrForbidden
+	}
+	user, err := r.userDao.Get(uid)
+	if err != nil {
+		log.Printf("[ERROR] error while fetching user [%s]: %s", uid, err)
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+	if user == nil {
+		return echo.ErrUnauthorized
+	}
+	switch {
+	case res.Permission == "", user.GroupId == systemGroupId:
+		return nil
+	case res.Permission != permissionSystem && user.GroupId == res.Permission:
+		return nil
+	}
+	log.Printf("[WARN] user [%s] is not allowed to access [%s] of protected resource [%s]", uid, file, res.Uri)
+	return echo.ErrForbidden
+}
+
+// SignedResourceUrl returns a temporary URL of a protected resource's file (e.g. "/files/exports/report.csv") that
+// can be accessed without signing in, see goadmin.Registry.SignResourceUrl.
+//
+// available since template-r5
+func (u *MyAppUtils) SignedResourceUrl(path string) string {
+	myReg := getRegistry(u.c)
+	return myReg.Url(myReg.SignResourceUrl(path, 0))
+}
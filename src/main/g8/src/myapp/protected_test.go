@@ -0,0 +1,27 @@
+package myapp
+
+import (
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"main/src/apptest"
+)
+
+func TestProtectedResources(t *testing.T) {
+	dir := t.TempDir()
+	ioutil.WriteFile(dir+"/report.csv", []byte("id,name"), 0600)
+	conf := apptest.SqliteInMemoryConfig + "\nprotected_resources { \"/files/exports\": {dir = \"" + dir + "\", permission = \"system\"} }\n"
+	h := apptest.New(t, conf, NewBootstrapper(nil, nil))
+	h.AssertStatus(h.Get("/files/exports/report.csv"), http.StatusUnauthorized)
+
+	signed := h.Registry.SignResourceUrl("/files/exports/report.csv", time.Minute)
+	resp := h.Get(signed)
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertBodyContains(resp, "id,name")
+
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	h.AssertStatus(h.Get("/files/exports/report.csv"), http.StatusOK)
+	h.AssertStatus(h.Get("/files/exports/missing.csv"), http.StatusNotFound)
+}
@@ -0,0 +1,184 @@
+package myapp
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"main/src/goadmin"
+	"main/src/utils"
+)
+
+// settingIdReadOnly is the id of the setting that stores the read-only mode switched on at /cp/settings/security.
+const settingIdReadOnly = "read_only"
+
+// ReadOnlySettings records who switched the read-only mode on and when.
+//
+// available since template-r5
+type ReadOnlySettings struct {
+	Enabled bool      `json:"enabled"`
+	By      string    `json:"by"`
+	Since   time.Time `json:"since"`
+}
+
+// readOnlyReloadInterval is how often the runtime read-only flag is reloaded from database.
+const readOnlyReloadInterval = 10 * time.Second
+
+// readOnlyMode is the app-wide read-only flag: while on, state-changing requests are rejected (see
+// middlewareReadOnly), browsing is unaffected. The mode is on if setting myapp.read_only is true (it can not be
+// switched off at runtime then, e.g. when pointing at a read replica) or if an admin switched it on.
+//
+// The runtime flag lives in memory and is reloaded from database every readOnlyReloadInterval, so that a switch made
+// on an instance is seen by the others; settings are cached (setting myapp.cache_ttl), with the in-memory cache the
+// switch may take until cached entries expire.
+type readOnlyMode struct {
+	forced  bool  // set by configuration
+	enabled int32 // switched at runtime, accessed atomically
+}
+
+func (m *readOnlyMode) isOn() bool {
+	return m.forced || atomic.LoadInt32(&m.enabled) != 0
+}
+
+// set switches the runtime flag, returns true if it changed.
+func (m *readOnlyMode) set(enabled bool) bool {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	return atomic.SwapInt32(&m.enabled, v) != v
+}
+
+// initReadOnlyMode reads the read-only mode from configuration and database, and starts reloading the runtime flag
+// until the application shuts down.
+func (r *myRegistry) initReadOnlyMode() {
+	r.readOnly.forced = r.AppConfig.GetBoolean(namespace+".read_only", false)
+	r.reloadReadOnlyMode()
+	if r.readOnly.isOn() {
+		log.Printf("[WARN] application is running in read-only mode")
+	}
+	if r.readOnly.forced {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(readOnlyReloadInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.Done():
+				return
+			case <-ticker.C:
+				if r.reloadReadOnlyMode() {
+					log.Printf("[WARN] read-only mode switched %v on another instance", r.readOnly.isOn())
+				}
+			}
+		}
+	}()
+}
+
+// reloadReadOnlyMode loads the runtime flag from database, returns true if it changed. The flag is kept if the
+// setting can not be loaded, e.g. the database is under maintenance.
+func (r *myRegistry) reloadReadOnlyMode() bool {
+	settings := &ReadOnlySettings{}
+	if _, err := r.loadSetting(settingIdReadOnly, settings); err != nil {
+		log.Printf("[ERROR] cannot load setting [%s]: %s", settingIdReadOnly, err)
+		return false
+	}
+	return r.readOnly.set(settings.Enabled)
+}
+
+// isReadOnly checks if the application is in read-only mode.
+func (r *myRegistry) isReadOnly() bool {
+	return r.readOnly.isOn()
+}
+
+// readOnlyExemptRoutes are state-changing routes that stay available in read-only mode: signing in/out,
+// re-verifying and switching the mode off.
+var readOnlyExemptRoutes = []string{actionNameCpLoginSubmit, actionNameCpLoginLinkSubmit, actionNameCpLoginLinkConfirmSubmit,
+	actionNameCpLogout, actionNameCpVerifyLoginSubmit, actionNameCpReadOnlySubmit}
+
+// middlewareReadOnly rejects state-changing requests to any route (control panel, public forms such as the contact
+// form, inbound webhooks...) while the application is in read-only mode. API/AJAX clients receive 403 with a JSON
+// error, browsers are redirected back to the referring page with a flash message; inbound webhooks receive 503 so
+// that senders retry later.
+//
+// available since template-r5
+func middlewareReadOnly(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		method := c.Request().Method
+		if method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions || !getRegistry(c).isReadOnly() {
+			return next(c)
+		}
+		for _, name := range readOnlyExemptRoutes {
+			if c.Path() == c.Echo().Reverse(name) {
+				return next(c)
+			}
+		}
+		if c.Path() == c.Echo().Reverse(goadmin.ActionNameWebhookReceive) {
+			c.Response().Header().Set(echo.HeaderRetryAfter, strconv.Itoa(int(readOnlyReloadInterval.Seconds())))
+			return c.NoContent(http.StatusServiceUnavailable)
+		}
+		return rejectStateChange(c, "error_read_only")
+	}
+}
+
+// rejectStateChange responds to a state-changing request that is not allowed: API/AJAX clients receive 403 with a
+// JSON error, browsers are redirected back to the referring page with a flash message.
+func rejectStateChange(c echo.Context, i18nKey string) error {
+	req := c.Request()
+	if req.Header.Get(echo.HeaderXRequestedWith) == "XMLHttpRequest" || strings.Contains(req.Header.Get(echo.HeaderAccept), echo.MIMEApplicationJSON) {
+		msg := getI18n(c).Localize(getContextString(c, ctxLocale), i18nKey)
+		return c.JSON(http.StatusForbidden, map[string]interface{}{"error": msg})
+	}
+	AddFlash(c, FlashWarning, i18nKey)
+	return c.Redirect(http.StatusSeeOther, readOnlyRedirectUrl(c))
+}
+
+// readOnlyRedirectUrl returns the path of the referring page, so that the user lands back on the form they submitted.
+// Only the path is kept to not redirect to other sites, the dashboard is used if there is no referring page.
+func readOnlyRedirectUrl(c echo.Context) string {
+	if ref, err := url.Parse(c.Request().Referer()); err == nil && strings.HasPrefix(ref.Path, "/") && !strings.HasPrefix(ref.Path, "//") {
+		if ref.RawQuery != "" {
+			return ref.Path + "?" + ref.RawQuery
+		}
+		return ref.Path
+	}
+	return c.Echo().Reverse(actionNameCpDashboard)
+}
+
+// actionCpReadOnlySubmit switches the read-only mode on or off (form field "enabled").
+//
+// available since template-r5
+func actionCpReadOnlySubmit(c echo.Context) error {
+	if err := checkCpManageSecurity(c); err != nil {
+		AddFlashText(c, FlashWarning, err.Error())
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpDashboard))
+	}
+	myReg := getRegistry(c)
+	if myReg.readOnly.forced {
+		AddFlash(c, FlashWarning, "read_only_forced")
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpSecuritySettings)+"?r="+utils.RandomString(4))
+	}
+	currentUser, _ := getCurrentUser(c)
+	settings := &ReadOnlySettings{Enabled: c.FormValue("enabled") == "1", Since: utils.Now()}
+	if currentUser != nil {
+		settings.By = currentUser.Username
+	}
+	// the switch takes effect even if it can not be persisted (e.g. the database is under maintenance), until the flag
+	// is reloaded from database
+	myReg.readOnly.set(settings.Enabled)
+	myReg.auditf("user [%s] switched read-only mode %v", settings.By, settings.Enabled)
+	if err := myReg.saveSetting(settingIdReadOnly, settings); err != nil {
+		AddFlash(c, FlashError, "error_db_001", "err", settingIdReadOnly+"/"+err.Error())
+	} else if settings.Enabled {
+		AddFlash(c, FlashInfo, "read_only_enabled")
+	} else {
+		AddFlash(c, FlashInfo, "read_only_disabled")
+	}
+	return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpSecuritySettings)+"?r="+utils.RandomString(4))
+}
@@ -0,0 +1,40 @@
+package myapp
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestMiddlewareReadOnly(t *testing.T) {
+	h := _newHarness(t)
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	h.AssertRedirect(h.PostForm(h.Reverse(actionNameCpReadOnlySubmit), url.Values{"enabled": {"1"}}), h.Reverse(actionNameCpSecuritySettings))
+	if !myReg.isReadOnly() {
+		t.Fatalf("TestMiddlewareReadOnly failed: read-only mode should have been switched on")
+	}
+
+	h.AssertRedirect(h.PostForm(h.Reverse(actionNameCpCreateGroupSubmit), url.Values{"id": {"testers"}, "name": {"Testers"}}), h.Reverse(actionNameCpDashboard))
+	if group, _ := myReg.groupDao.Get("testers"); group != nil {
+		t.Fatalf("TestMiddlewareReadOnly failed: group [testers] must not be created in read-only mode")
+	}
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpGroups)), http.StatusOK)
+	h.AssertData("flashWarning", "read-only")
+
+	h.AssertRedirect(h.PostForm(h.Reverse(actionNameCpReadOnlySubmit), url.Values{"enabled": {"0"}}), h.Reverse(actionNameCpSecuritySettings))
+	h.AssertRedirect(h.PostForm(h.Reverse(actionNameCpCreateGroupSubmit), url.Values{"id": {"testers"}, "name": {"Testers"}}), h.Reverse(actionNameCpGroups))
+
+	// switches made on other instances are picked up when the flag is reloaded
+	myReg.saveSetting(settingIdReadOnly, &ReadOnlySettings{Enabled: true})
+	if !myReg.reloadReadOnlyMode() || !myReg.isReadOnly() {
+		t.Fatalf("TestMiddlewareReadOnly failed: read-only mode should have been reloaded")
+	}
+	// signing in stays available
+	form := url.Values{"username": {testAdminUsername}, "password": {testAdminPassword}}
+	h.AssertRedirect(h.PostForm(h.Reverse(actionNameCpLoginSubmit), form), h.Reverse(actionNameCpDashboard))
+	myReg.saveSetting(settingIdReadOnly, &ReadOnlySettings{Enabled: false})
+	if !myReg.reloadReadOnlyMode() || myReg.isReadOnly() {
+		t.Fatalf("TestMiddlewareReadOnly failed: read-only mode should have been switched off")
+	}
+}
@@ -0,0 +1,320 @@
+package myapp
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"main/src/utils"
+)
+
+// reportPageSize is the number of users fetched from storage at a time while generating reports, so that large groups
+// are never loaded into memory at once.
+const reportPageSize = 500
+
+const (
+	reportFormatCsv  = "csv"
+	reportFormatXlsx = "xlsx"
+)
+
+// reportContentTypes are content types of reports, per format.
+var reportContentTypes = map[string]string{
+	reportFormatCsv:  "text/csv; charset=utf-8",
+	reportFormatXlsx: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// getNUsersByGroup returns at most maxNumRows users of a group (all if maxNumRows <= 0) starting from fromOffset,
+// sorted by username. DAOs implementing UserGroupPager page natively, other DAOs are scanned page by page.
+//
+// available since template-r5
+func getNUsersByGroup(dao UserDao, groupId string, fromOffset, maxNumRows int) ([]*User, error) {
+	if pager, ok := dao.(UserGroupPager); ok {
+		return pager.GetNByGroup(groupId, fromOffset, maxNumRows)
+	}
+	result := make([]*User, 0)
+	for offset := 0; ; offset += reportPageSize {
+		page, err := dao.GetN(offset, reportPageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range page {
+			if u.GroupId != groupId {
+				continue
+			}
+			if fromOffset > 0 {
+				fromOffset--
+				continue
+			}
+			result = append(result, u)
+			if maxNumRows > 0 && len(result) >= maxNumRows {
+				return result, nil
+			}
+		}
+		if len(page) < reportPageSize {
+			return result, nil
+		}
+	}
+}
+
+// getNUsersAfter returns at most maxNumRows users (all if maxNumRows <= 0) whose username sorts after afterUsername,
+// sorted by username. DAOs implementing UserKeysetPager page natively, other DAOs are scanned page by page.
+//
+// available since template-r5
+func getNUsersAfter(dao UserDao, afterUsername string, maxNumRows int) ([]*User, error) {
+	if pager, ok := dao.(UserKeysetPager); ok {
+		return pager.GetNAfter(afterUsername, maxNumRows)
+	}
+	result := make([]*User, 0)
+	for offset := 0; ; offset += reportPageSize {
+		page, err := dao.GetN(offset, reportPageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range page {
+			if u.Username <= afterUsername {
+				continue
+			}
+			result = append(result, u)
+			if maxNumRows > 0 && len(result) >= maxNumRows {
+				return result, nil
+			}
+		}
+		if len(page) < reportPageSize {
+			return result, nil
+		}
+	}
+}
+
+/*----------------------------------------------------------------------*/
+
+// reportWriter writes rows of a tabular report to an output stream.
+type reportWriter interface {
+	WriteRow(cells []string) error
+	// Close flushes pending data and finishes the report, it does not close the underlying stream.
+	Close() error
+}
+
+// csvReportWriter writes reports in CSV format.
+type csvReportWriter struct {
+	w *csv.Writer
+}
+
+func newCsvReportWriter(out io.Writer) reportWriter {
+	return &csvReportWriter{w: csv.NewWriter(out)}
+}
+
+// csvFormulaPrefixes are leading characters that make spreadsheet applications evaluate a cell as a formula.
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// WriteRow implements reportWriter.WriteRow
+//
+// Cells starting with a formula character are prefixed with a single quote, so that data entered by users (e.g.
+// names) can not inject formulas into the spreadsheet opening the report.
+func (w *csvReportWriter) WriteRow(cells []string) error {
+	escaped := make([]string, len(cells))
+	for i, cell := range cells {
+		if cell != "" && strings.ContainsRune(csvFormulaPrefixes, rune(cell[0])) {
+			cell = "'" + cell
+		}
+		escaped[i] = cell
+	}
+	return w.w.Write(escaped)
+}
+
+// Close implements reportWriter.Close
+func (w *csvReportWriter) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// xlsxReportWriter writes reports as a single-sheet Office Open XML workbook. Rows are streamed into the sheet as they
+// are written, cells are inline strings so that no shared string table needs to be kept in memory.
+type xlsxReportWriter struct {
+	zw    *zip.Writer
+	sheet io.Writer
+	row   int
+}
+
+var xlsxStaticParts = []struct{ name, content string }{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Report" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`},
+}
+
+func newXlsxReportWriter(out io.Writer) (reportWriter, error) {
+	zw := zip.NewWriter(out)
+	for _, part := range xlsxStaticParts {
+		w, err := zw.Create(part.name)
+		if err == nil {
+			_, err = io.WriteString(w, part.content)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	sheet, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err == nil {
+		_, err = io.WriteString(sheet, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &xlsxReportWriter{zw: zw, sheet: sheet}, nil
+}
+
+// WriteRow implements reportWriter.WriteRow
+func (w *xlsxReportWriter) WriteRow(cells []string) error {
+	w.row++
+	if _, err := fmt.Fprintf(w.sheet, `<row r="%d">`, w.row); err != nil {
+		return err
+	}
+	for _, cell := range cells {
+		if _, err := io.WriteString(w.sheet, `<c t="inlineStr"><is><t xml:space="preserve">`); err != nil {
+			return err
+		}
+		if err := xml.EscapeText(w.sheet, []byte(cell)); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w.sheet, `</t></is></c>`); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w.sheet, `</row>`)
+	return err
+}
+
+// Close implements reportWriter.Close
+func (w *xlsxReportWriter) Close() error {
+	if _, err := io.WriteString(w.sheet, `</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	return w.zw.Close()
+}
+
+/*----------------------------------------------------------------------*/
+
+// MembershipReportFilter selects rows of the group membership report.
+//
+// available since template-r5
+type MembershipReportFilter struct {
+	GroupIds []string // groups to include, empty means all groups
+	Query    string   // if not empty, only users whose username or name contains it (case-insensitive) are included
+}
+
+// match checks if a user passes the filter's query.
+func (f *MembershipReportFilter) match(u *User) bool {
+	if f.Query == "" {
+		return true
+	}
+	q := strings.ToLower(f.Query)
+	return strings.Contains(strings.ToLower(u.Username), q) || strings.Contains(strings.ToLower(u.Name), q)
+}
+
+// writeMembershipReport writes the group membership report: a header row followed by one row per member (group id,
+// group name, username, name), groups sorted by id and members by username. Members are fetched reportPageSize at a
+// time; the number of members written so far is reported to progress (if not nil) after each page, the report stops
+// at the first error returned by progress.
+func (r *myRegistry) writeMembershipReport(locale string, w reportWriter, filter *MembershipReportFilter, progress func(done, total int) error) error {
+	i18n := r.i18n
+	header := []string{i18n.Localize(locale, "report_group_id"), i18n.Localize(locale, "report_group_name"),
+		i18n.Localize(locale, "user_username"), i18n.Localize(locale, "user_name")}
+	if err := w.WriteRow(header); err != nil {
+		return err
+	}
+	groups, err := r.groupDao.GetAll()
+	if err != nil {
+		return err
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Id < groups[j].Id })
+	wanted := make(map[string]bool)
+	for _, id := range filter.GroupIds {
+		wanted[id] = true
+	}
+	userDao := r.userDao
+	done, total := 0, 0
+	if progress != nil {
+		counts, err := countUsersByGroup(userDao)
+		if err != nil {
+			return err
+		}
+		for _, g := range groups {
+			if len(wanted) == 0 || wanted[g.Id] {
+				total += counts[g.Id]
+			}
+		}
+	}
+	for _, g := range groups {
+		if len(wanted) > 0 && !wanted[g.Id] {
+			continue
+		}
+		for offset := 0; ; offset += reportPageSize {
+			users, err := getNUsersByGroup(userDao, g.Id, offset, reportPageSize)
+			if err != nil {
+				return err
+			}
+			for _, u := range users {
+				if filter.match(u) {
+					if err := w.WriteRow([]string{g.Id, g.Name, u.Username, u.Name}); err != nil {
+						return err
+					}
+				}
+			}
+			if done += len(users); progress != nil {
+				if err := progress(done, total); err != nil {
+					return err
+				}
+			}
+			if len(users) < reportPageSize {
+				break
+			}
+		}
+	}
+	return w.Close()
+}
+
+// actionCpGroupsReport streams the group membership report as CSV (default) or XLSX (query parameter
+// "format=xlsx"). Query parameters "group" (repeatable) and "q" filter the report, see MembershipReportFilter.
+//
+// Exporting the report requires permission group.report as it reveals group membership of all users.
+//
+// available since template-r5
+func actionCpGroupsReport(c echo.Context) error {
+	if err := checkPermission(c, permGroupReport); err != nil {
+		AddFlash(c, FlashWarning, "error_no_permission")
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpGroups)+"?r="+utils.RandomString(4))
+	}
+	filter := &MembershipReportFilter{Query: strings.TrimSpace(c.QueryParam("q"))}
+	for _, id := range c.QueryParams()["group"] {
+		if id = strings.ToLower(strings.TrimSpace(id)); id != "" {
+			filter.GroupIds = append(filter.GroupIds, id)
+		}
+	}
+
+	format := reportFormatCsv
+	if strings.ToLower(c.QueryParam("format")) == reportFormatXlsx {
+		format = reportFormatXlsx
+	}
+	filename := fmt.Sprintf("group-membership-%s.%s", utils.Now().In(utils.Location).Format("20060102-150405"), format)
+	locale := getContextString(c, ctxLocale)
+	err := streamReport(c, filename, format, func(w reportWriter) error {
+		return getRegistry(c).writeMembershipReport(locale, w, filter, nil)
+	})
+	if err != nil {
+		// headers have been sent, the truncated download is the best we can signal
+		log.Printf("[ERROR] cannot generate group membership report: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package myapp
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestActionCpGroupsReport(t *testing.T) {
+	h := _newHarness(t)
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	resp := h.Get(h.Reverse(actionNameCpGroupsReport) + "?group=" + systemGroupId + "&q=ADMIN")
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertBodyContains(resp, "Group id,Group name,Username,Name")
+	h.AssertBodyContains(resp, systemGroupId+",System User Group,"+testAdminUsername+",")
+
+	// user data is not evaluated as formulas by spreadsheet applications
+	h.Registry.Get(namespace).(*myRegistry).userDao.Create("formula@local", "", "=1+2", systemGroupId)
+	resp = h.Get(h.Reverse(actionNameCpGroupsReport) + "?group=" + systemGroupId + "&q=formula")
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertBodyContains(resp, "formula@local,'=1+2")
+
+	resp = h.Get(h.Reverse(actionNameCpGroupsReport) + "?q=nobody")
+	h.AssertStatus(resp, http.StatusOK)
+	if strings.Contains(resp.Body.String(), testAdminUsername) {
+		t.Fatalf("TestActionCpGroupsReport failed: filtered report contains %s", testAdminUsername)
+	}
+
+	resp = h.Get(h.Reverse(actionNameCpGroupsReport) + "?format=xlsx")
+	h.AssertStatus(resp, http.StatusOK)
+	zr, err := zip.NewReader(bytes.NewReader(resp.Body.Bytes()), int64(resp.Body.Len()))
+	if err != nil {
+		t.Fatalf("TestActionCpGroupsReport failed: %s", err)
+	}
+	for _, f := range zr.File {
+		if f.Name == "xl/worksheets/sheet1.xml" {
+			rc, _ := f.Open()
+			sheet, _ := ioutil.ReadAll(rc)
+			rc.Close()
+			if !strings.Contains(string(sheet), testAdminUsername) {
+				t.Fatalf("TestActionCpGroupsReport failed: sheet does not contain %s", testAdminUsername)
+			}
+			return
+		}
+	}
+	t.Fatalf("TestActionCpGroupsReport failed: sheet not found")
+}
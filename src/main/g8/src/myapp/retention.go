@@ -0,0 +1,453 @@
+package myapp
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"main/src/goadmin"
+	"main/src/utils"
+)
+
+// retentionLogType is a kind of history stored in the setting table that is purged once entries are older than its
+// retention window (setting myapp.retention.<name>, in days).
+//
+// Audit entries (see auditf) are written to the log output and not stored, their retention is up to the log
+// shipping/rotation infrastructure.
+type retentionLogType struct {
+	name        string // also the name of the setting under myapp.retention
+	i18nKey     string
+	defaultDays int
+	// count returns the number of stored entries.
+	count func(r *myRegistry) (int, error)
+	// purge deletes entries older than cutoff and returns the number of deleted entries.
+	purge func(r *myRegistry, cutoff time.Time) (int, error)
+}
+
+// retentionLogTypes lists the kinds of history subject to retention policies.
+var retentionLogTypes = []*retentionLogType{
+	{name: "login_history", i18nKey: "retention_login_history", defaultDays: 365, count: countDailyStats, purge: purgeDailyStats},
+	{name: "notifications", i18nKey: "retention_notifications", defaultDays: 90, count: countNotifications, purge: purgeNotifications},
+	{name: "usage_analytics", i18nKey: "retention_usage_analytics", defaultDays: 90, count: countUsageStats, purge: purgeUsageStats},
+	{name: "dead_letters", i18nKey: "retention_dead_letters", defaultDays: 30, count: countDeadLetters, purge: purgeDeadLetters},
+	{name: "tasks", i18nKey: "retention_tasks", defaultDays: 30, count: countTasks, purge: purgeTasks},
+	{name: "api_usage", i18nKey: "retention_api_usage", defaultDays: 365, count: countApiUsages, purge: purgeApiUsages},
+}
+
+// expiringRecordType is a kind of record that expires on its own, e.g. one-time sign-in tokens: expired records are
+// of no use but stay in their store until deleted, so the purge job deletes them on every run whatever the retention
+// settings. Sessions are not concerned: they are kept in cookies (see cocostore), not on the server.
+type expiringRecordType struct {
+	name    string
+	i18nKey string
+	// purge deletes records expired at now and returns the number of deleted records.
+	purge func(r *myRegistry, now time.Time) (int, error)
+}
+
+// expiringRecordTypes lists the kinds of records purged once expired.
+var expiringRecordTypes = []*expiringRecordType{
+	{name: "login_tokens", i18nKey: "retention_login_tokens", purge: purgeExpiredLoginTokens},
+	{name: "group_invites", i18nKey: "retention_group_invites", purge: purgeExpiredGroupInvites},
+	{name: "locks", i18nKey: "retention_locks", purge: func(r *myRegistry, now time.Time) (int, error) { return purgeExpiredEntries(r.Locker, now) }},
+	{name: "cache", i18nKey: "retention_cache", purge: func(r *myRegistry, now time.Time) (int, error) { return purgeExpiredEntries(r.Cache, now) }},
+}
+
+// purgeExpiredLoginTokens deletes expired one-time sign-in tokens (see magicLinkLogin), also if sign-in links have been
+// disabled since they were issued.
+func purgeExpiredLoginTokens(r *myRegistry, now time.Time) (int, error) {
+	var dao loginTokenDao = &settingLoginTokenDao{r: r}
+	if r.magicLink != nil {
+		dao = r.magicLink.dao
+	}
+	return dao.PurgeExpired(now)
+}
+
+// purgeExpiredEntries deletes expired entries of a cache or locker, if it keeps them (see goadmin.ExpiredPurger).
+func purgeExpiredEntries(store interface{}, now time.Time) (int, error) {
+	if purger, ok := store.(goadmin.ExpiredPurger); ok {
+		return purger.PurgeExpired(now)
+	}
+	return 0, nil
+}
+
+// settingsWithPrefix returns settings whose ids start with prefix.
+func (r *myRegistry) settingsWithPrefix(prefix string) ([]*Setting, error) {
+	all, err := r.settingDao.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*Setting, 0)
+	for _, s := range all {
+		if strings.HasPrefix(s.Id, prefix) {
+			result = append(result, s)
+		}
+	}
+	return result, nil
+}
+
+// countDailyStats counts days with recorded sign-ins/sign-ups (see recordDailyStat).
+func countDailyStats(r *myRegistry) (int, error) {
+	list, err := r.settingsWithPrefix(settingPrefixDailyStats)
+	return len(list), err
+}
+
+// purgeDailyStats deletes counters of days before cutoff.
+func purgeDailyStats(r *myRegistry, cutoff time.Time) (int, error) {
+	return purgeDatedSettings(r, settingPrefixDailyStats, cutoff)
+}
+
+// countUsageStats counts days with recorded page views (see usageTracker).
+func countUsageStats(r *myRegistry) (int, error) {
+	list, err := r.settingsWithPrefix(settingPrefixUsageStats)
+	return len(list), err
+}
+
+// purgeUsageStats deletes page views of days before cutoff.
+func purgeUsageStats(r *myRegistry, cutoff time.Time) (int, error) {
+	return purgeDatedSettings(r, settingPrefixUsageStats, cutoff)
+}
+
+// purgeDatedSettings deletes settings whose ids are prefix followed by a date before cutoff.
+func purgeDatedSettings(r *myRegistry, prefix string, cutoff time.Time) (int, error) {
+	list, err := r.settingsWithPrefix(prefix)
+	if err != nil {
+		return 0, err
+	}
+	cutoffDate := cutoff.In(utils.Location).Format(statsDateLayout)
+	numDeleted := 0
+	for _, s := range list {
+		// dates are formatted as yyyy-mm-dd, hence comparable as strings
+		if date := strings.TrimPrefix(s.Id, prefix); date < cutoffDate {
+			if _, err := r.settingDao.Delete(s); err != nil {
+				return numDeleted, err
+			}
+			numDeleted++
+		}
+	}
+	return numDeleted, nil
+}
+
+// countNotifications counts notifications of all users.
+func countNotifications(r *myRegistry) (int, error) {
+	list, err := r.settingsWithPrefix(settingPrefixNotifications)
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, s := range list {
+		notifications := make([]*Notification, 0)
+		if _, err := r.loadSetting(s.Id, &notifications); err != nil {
+			return total, err
+		}
+		total += len(notifications)
+	}
+	return total, nil
+}
+
+// purgeNotifications deletes notifications created before cutoff, users left without notifications have their list
+// removed.
+func purgeNotifications(r *myRegistry, cutoff time.Time) (int, error) {
+	list, err := r.settingsWithPrefix(settingPrefixNotifications)
+	if err != nil {
+		return 0, err
+	}
+	numDeleted := 0
+	for _, s := range list {
+		err = r.withSettingLock(s.Id, func() error {
+			notifications := make([]*Notification, 0)
+			if found, err := r.loadSetting(s.Id, &notifications); err != nil || !found {
+				return err
+			}
+			kept := make([]*Notification, 0, len(notifications))
+			for _, n := range notifications {
+				if !n.Time.Before(cutoff) {
+					kept = append(kept, n)
+				}
+			}
+			if len(kept) == len(notifications) {
+				return nil
+			}
+			var err error
+			if len(kept) == 0 {
+				_, err = r.settingDao.Delete(s)
+			} else {
+				err = r.saveSetting(s.Id, kept)
+			}
+			if err == nil {
+				numDeleted += len(notifications) - len(kept)
+			}
+			return err
+		})
+		if err != nil {
+			return numDeleted, err
+		}
+	}
+	return numDeleted, nil
+}
+
+/*----------------------------------------------------------------------*/
+
+// retentionDays returns the retention window of a log type in days, 0 means entries are kept forever.
+func (r *myRegistry) retentionDays(t *retentionLogType) int {
+	days := int(r.AppConfig.GetInt32(namespace+".retention."+t.name, int32(t.defaultDays)))
+	if days < 0 {
+		days = 0
+	}
+	return days
+}
+
+// PurgeResult summarizes a run of the purge job.
+//
+// available since template-r5
+type PurgeResult struct {
+	Time    time.Time
+	Deleted map[string]int // number of deleted entries, per log type or expiring record type
+	Err     error
+}
+
+// retentionJob purges expired history periodically (setting myapp.retention.purge_interval).
+type retentionJob struct {
+	lock   sync.Mutex
+	next   time.Time
+	last   *PurgeResult
+	totals map[string]int // number of entries deleted since startup, per log type or expiring record type
+}
+
+// purge runs the retention policies of all log types once.
+func (r *myRegistry) purge() *PurgeResult {
+	result := &PurgeResult{Time: utils.Now(), Deleted: make(map[string]int)}
+	for _, t := range retentionLogTypes {
+		days := r.retentionDays(t)
+		if days <= 0 {
+			continue
+		}
+		cutoff := utils.Now().In(utils.Location).AddDate(0, 0, -days)
+		n, err := t.purge(r, cutoff)
+		result.Deleted[t.name] = n
+		if err != nil {
+			log.Printf("[ERROR] error purging [%s] older than %d days: %s", t.name, days, err)
+			result.Err = err
+		} else if n > 0 {
+			log.Printf("Purged %d entries of [%s] older than %d days", n, t.name, days)
+		}
+	}
+	for _, t := range expiringRecordTypes {
+		n, err := t.purge(r, result.Time)
+		result.Deleted[t.name] = n
+		if err != nil {
+			log.Printf("[ERROR] error purging expired [%s]: %s", t.name, err)
+			result.Err = err
+		} else if n > 0 {
+			log.Printf("Purged %d expired [%s]", n, t.name)
+		}
+	}
+	if job := r.retentionJob; job != nil {
+		job.lock.Lock()
+		job.last = result
+		for name, n := range result.Deleted {
+			job.totals[name] += n
+		}
+		job.lock.Unlock()
+	}
+	return result
+}
+
+// startRetentionJob starts the purge job if setting myapp.retention.purge_interval is positive. The first run is
+// scheduled one interval after startup.
+func (r *myRegistry) startRetentionJob() {
+	interval := r.AppConfig.GetTimeDuration(namespace+".retention.purge_interval", 24*time.Hour)
+	if interval <= 0 {
+		log.Printf("[WARN] purge job is disabled, history is kept forever")
+		return
+	}
+	job := &retentionJob{next: utils.Now().Add(interval), totals: make(map[string]int)}
+	r.retentionJob = job
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			job.lock.Lock()
+			job.next = utils.Now().Add(interval)
+			job.lock.Unlock()
+			// in clustered deployments, only the elected leader purges history
+			if !r.IsLeader() {
+				continue
+			}
+			// right after a failover, the former leader may still be purging
+			if ok, err := r.TryWithLock(namespace+":purge", time.Hour, func() error { r.purge(); return nil }); err != nil {
+				log.Printf("[ERROR] cannot acquire lock to purge history: %s", err)
+			} else if !ok {
+				log.Printf("Purge job is running on another instance, skipped")
+			}
+		}
+	}()
+}
+
+// nextPurge returns time of the next scheduled purge (zero if the purge job is disabled) and result of the last one
+// (nil if none has run yet).
+func (r *myRegistry) nextPurge() (time.Time, *PurgeResult) {
+	job := r.retentionJob
+	if job == nil {
+		return time.Time{}, nil
+	}
+	job.lock.Lock()
+	defer job.lock.Unlock()
+	return job.next, job.last
+}
+
+// purgeTotals returns the number of entries deleted since startup, per log type or expiring record type (nil if the
+// purge job is disabled).
+func (r *myRegistry) purgeTotals() map[string]int {
+	job := r.retentionJob
+	if job == nil {
+		return nil
+	}
+	job.lock.Lock()
+	defer job.lock.Unlock()
+	result := make(map[string]int, len(job.totals))
+	for name, n := range job.totals {
+		result[name] = n
+	}
+	return result
+}
+
+/*----------------------------------------------------------------------*/
+
+// RetentionRow is a row of the retention settings page.
+//
+// available since template-r5
+type RetentionRow struct {
+	Name       string
+	I18nKey    string
+	Days       int // 0 = kept forever
+	NumEntries int
+}
+
+// PurgeStat is a row of the purge metrics on the retention settings page.
+//
+// available since template-r5
+type PurgeStat struct {
+	Name    string
+	I18nKey string
+	Last    int // number of entries deleted by the last run
+	Total   int // number of entries deleted since startup
+}
+
+// purgeStats returns the number of entries deleted by the last run of the purge job and since startup, nil if the job
+// has not run yet.
+func (r *myRegistry) purgeStats() []*PurgeStat {
+	_, last := r.nextPurge()
+	if last == nil {
+		return nil
+	}
+	totals := r.purgeTotals()
+	result := make([]*PurgeStat, 0, len(retentionLogTypes)+len(expiringRecordTypes))
+	for _, t := range retentionLogTypes {
+		result = append(result, &PurgeStat{Name: t.name, I18nKey: t.i18nKey, Last: last.Deleted[t.name], Total: totals[t.name]})
+	}
+	for _, t := range expiringRecordTypes {
+		result = append(result, &PurgeStat{Name: t.name, I18nKey: t.i18nKey, Last: last.Deleted[t.name], Total: totals[t.name]})
+	}
+	return result
+}
+
+// TableSize is the number of rows of a table.
+//
+// available since template-r5
+type TableSize struct {
+	Name    string
+	NumRows int
+}
+
+// tableSizes returns number of rows of the tables managed by myapp.
+func (r *myRegistry) tableSizes() ([]*TableSize, error) {
+	result := make([]*TableSize, 0, 4)
+	groups, err := r.groupDao.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	result = append(result, &TableSize{Name: "groups", NumRows: len(groups)})
+	counts, err := countUsersByGroup(r.userDao)
+	if err != nil {
+		return nil, err
+	}
+	numUsers := 0
+	for _, n := range counts {
+		numUsers += n
+	}
+	result = append(result, &TableSize{Name: "users", NumRows: numUsers})
+	messages, err := r.messageDao.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	result = append(result, &TableSize{Name: "messages", NumRows: len(messages)})
+	settings, err := r.settingDao.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	result = append(result, &TableSize{Name: "settings", NumRows: len(settings)})
+	return result, nil
+}
+
+// actionCpRetentionSettings shows retention windows of history kept in database, number of stored entries and
+// table sizes, as well as the schedule of the purge job and metrics of the prepared statements of SQL DAOs.
+//
+// available since template-r5
+func actionCpRetentionSettings(c echo.Context) error {
+	// viewing retention settings requires the same permission as managing security settings
+	if err := checkCpManageSecurity(c); err != nil {
+		AddFlashText(c, FlashWarning, err.Error())
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpDashboard))
+	}
+	myReg := getRegistry(c)
+	data := map[string]interface{}{"active": "retention"}
+	rows := make([]*RetentionRow, 0, len(retentionLogTypes))
+	var errMsg string
+	for _, t := range retentionLogTypes {
+		n, err := t.count(myReg)
+		if err != nil {
+			errMsg = err.Error()
+		}
+		rows = append(rows, &RetentionRow{Name: t.name, I18nKey: t.i18nKey, Days: myReg.retentionDays(t), NumEntries: n})
+	}
+	tables, err := myReg.tableSizes()
+	if err != nil {
+		errMsg = err.Error()
+	}
+	data["rows"], data["tables"], data["error"] = rows, tables, errMsg
+	if stats, ok := myReg.sqlStmtStats(); ok {
+		data["stmtStats"] = stats
+	}
+	if next, last := myReg.nextPurge(); !next.IsZero() {
+		data["nextPurge"] = next.In(utils.Location).Format("2006-01-02 15:04:05")
+		if last != nil {
+			data["lastPurge"] = last.Time.In(utils.Location).Format("2006-01-02 15:04:05")
+			data["purgeStats"] = myReg.purgeStats()
+		}
+	}
+	return c.Render(http.StatusOK, namespace+":layout:cp_retention_settings", data)
+}
+
+// actionCpRetentionPurgeSubmit runs the purge job immediately.
+//
+// available since template-r5
+func actionCpRetentionPurgeSubmit(c echo.Context) error {
+	if err := checkCpManageSecurity(c); err != nil {
+		AddFlashText(c, FlashWarning, err.Error())
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpDashboard))
+	}
+	result := getRegistry(c).purge()
+	if result.Err != nil {
+		AddFlash(c, FlashError, "error_db_001", "err", result.Err.Error())
+	} else {
+		total := 0
+		for _, n := range result.Deleted {
+			total += n
+		}
+		AddFlash(c, FlashInfo, "retention_purge_successful", "count", total)
+	}
+	return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpRetentionSettings)+"?r="+utils.RandomString(4))
+}
@@ -0,0 +1,71 @@
+package myapp
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestActionCpRetentionPurgeSubmit(t *testing.T) {
+	h := _newHarness(t)
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	expired := time.Now().AddDate(-2, 0, 0)
+	myReg.saveSetting(settingPrefixDailyStats+expired.Format(statsDateLayout), &DailyStat{Logins: 1})
+	myReg.saveSetting(settingPrefixNotifications+testAdminUsername, []*Notification{
+		{Key: "notification_suspicious_login", Time: time.Now()},
+		{Key: "notification_suspicious_login", Time: expired},
+	})
+
+	resp := h.Get(h.Reverse(actionNameCpRetentionSettings))
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertTemplate(namespace + ":layout:cp_retention_settings")
+
+	h.AssertRedirect(h.PostForm(h.Reverse(actionNameCpRetentionPurgeSubmit), url.Values{}), h.Reverse(actionNameCpRetentionSettings))
+	if found, _ := myReg.loadSetting(settingPrefixDailyStats+expired.Format(statsDateLayout), &DailyStat{}); found {
+		t.Fatalf("TestActionCpRetentionPurgeSubmit failed: expired daily stats have not been purged")
+	}
+	if list, _ := myReg.notifications(testAdminUsername); len(list) != 1 {
+		t.Fatalf("TestActionCpRetentionPurgeSubmit failed: expected 1 notification but received %d", len(list))
+	}
+}
+
+func TestPurge_ExpiredRecords(t *testing.T) {
+	testName := "TestPurge_ExpiredRecords"
+	h := _newHarness(t)
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	now := time.Now()
+	tokens := &settingLoginTokenDao{r: myReg}
+	tokens.Put(hashLoginToken("expired"), &LoginToken{Username: testAdminUsername, Expires: now.Add(-time.Second)})
+	tokens.Put(hashLoginToken("valid"), &LoginToken{Username: testAdminUsername, Expires: now.Add(time.Hour)})
+	myReg.Cache.Set(namespace+":test:expired", []byte("1"), time.Millisecond)
+	myReg.Cache.Set(namespace+":test:valid", []byte("1"), time.Hour)
+	myReg.Locker.TryLock(namespace+":test:expired", "owner", time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+
+	result := myReg.purge()
+	if result.Err != nil {
+		t.Fatalf("%s failed: %s", testName, result.Err)
+	}
+	for _, name := range []string{"login_tokens", "cache", "locks"} {
+		if result.Deleted[name] < 1 {
+			t.Fatalf("%s failed: expected expired [%s] to be purged but received %v", testName, name, result.Deleted)
+		}
+	}
+	if list, _ := myReg.settingsWithPrefix(settingPrefixLoginToken); len(list) != 1 {
+		t.Fatalf("%s failed: expected 1 login token left but received %d", testName, len(list))
+	}
+	if _, err := myReg.Cache.Get(namespace + ":test:valid"); err != nil {
+		t.Fatalf("%s failed: valid cache entry must be kept", testName)
+	}
+	if totals := myReg.purgeTotals(); totals["login_tokens"] != 1 {
+		t.Fatalf("%s failed: expected purged login tokens to be counted but received %v", testName, totals)
+	}
+
+	// purge metrics are shown on the retention settings page
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	resp := h.Get(h.Reverse(actionNameCpRetentionSettings))
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertBodyContains(resp, "(login_tokens)")
+}
@@ -0,0 +1,426 @@
+package myapp
+
+import (
+	"encoding/json"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/btnguyen2k/goyai"
+	"github.com/labstack/echo/v4"
+	"main/src/goadmin"
+	"main/src/utils"
+)
+
+const (
+	settingIdSecurity          = "security"
+	settingPrefixLoginProfile  = "login_profile:"
+	settingPrefixNotifications = "notifications:"
+
+	maxLoginProfileEntries = 20 // max number of countries/devices remembered per user
+	maxNotifications       = 50 // max number of notifications kept per user
+
+	sessionReverify = "reverify"
+)
+
+// SecuritySettings controls how sign-ins are inspected for suspicious activity. Defaults are read from the
+// configuration block myapp.security and can be overridden by admins at /cp/settings/security.
+//
+// available since template-r5
+type SecuritySettings struct {
+	AlertNewCountry       bool `json:"alert_new_country"`      // flag sign-ins from a country the user has not signed in from
+	AlertNewDevice        bool `json:"alert_new_device"`       // flag sign-ins from a device (browser/OS) the user has not used
+	AlertOutsideHours     bool `json:"alert_outside_hours"`    // flag sign-ins outside of login hours
+	LoginHoursFrom        int  `json:"login_hours_from"`       // start of login hours (0-23), in the application's timezone
+	LoginHoursTo          int  `json:"login_hours_to"`         // end of login hours (1-24, exclusive), wraps past midnight if not after LoginHoursFrom
+	NotifyAdmins          bool `json:"notify_admins"`          // notify admins of flagged sign-ins, in addition to the user
+	RequireReverification bool `json:"require_reverification"` // ask the user to confirm their password after a flagged sign-in
+
+	AlertSmsNumbers []string `json:"alert_sms_numbers"` // phone numbers flagged sign-ins are texted to, if SMS are enabled
+}
+
+// withinLoginHours returns true if the hour (0-23) is within login hours.
+func (s *SecuritySettings) withinLoginHours(hour int) bool {
+	if s.LoginHoursFrom < s.LoginHoursTo {
+		return hour >= s.LoginHoursFrom && hour < s.LoginHoursTo
+	}
+	// e.g. 22-06: login hours span midnight
+	return hour >= s.LoginHoursFrom || hour < s.LoginHoursTo
+}
+
+// validate normalizes login hours into their valid ranges.
+func (s *SecuritySettings) validate() {
+	if s.LoginHoursFrom < 0 || s.LoginHoursFrom > 23 {
+		s.LoginHoursFrom = 0
+	}
+	if s.LoginHoursTo < 1 || s.LoginHoursTo > 24 {
+		s.LoginHoursTo = 24
+	}
+	numbers := make([]string, 0, len(s.AlertSmsNumbers))
+	for _, number := range s.AlertSmsNumbers {
+		if number = strings.TrimSpace(number); number != "" {
+			numbers = append(numbers, number)
+		}
+	}
+	s.AlertSmsNumbers = numbers
+}
+
+// reE164 matches phone numbers in E.164 format, the format SMS providers expect.
+var reE164 = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// isValidPhoneNumber checks if number is a phone number in E.164 format (e.g. "+84901234567").
+func isValidPhoneNumber(number string) bool {
+	return reE164.MatchString(number)
+}
+
+// LoginAttempt describes a successful sign-in to be inspected by login rules.
+//
+// available since template-r5
+type LoginAttempt struct {
+	Username string
+	Ip       string
+	Country  string // ISO country code of the client, empty if unknown (e.g. GeoIP lookup is disabled)
+	Origin   string // IP address of the client followed by its location, see clientOrigin
+	Device   string // browser and OS of the client, see deviceOf
+	Time     time.Time
+}
+
+// LoginProfile remembers where a user has signed in from.
+//
+// available since template-r5
+type LoginProfile struct {
+	Countries []string `json:"countries"`
+	Devices   []string `json:"devices"`
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// rememberString appends s to the list if not already there, dropping the oldest entries beyond maxLoginProfileEntries.
+func rememberString(list []string, s string) []string {
+	if s == "" || containsString(list, s) {
+		return list
+	}
+	list = append(list, s)
+	if len(list) > maxLoginProfileEntries {
+		list = list[len(list)-maxLoginProfileEntries:]
+	}
+	return list
+}
+
+// remember records the country and device of the sign-in.
+func (p *LoginProfile) remember(attempt *LoginAttempt) {
+	p.Countries = rememberString(p.Countries, attempt.Country)
+	p.Devices = rememberString(p.Devices, attempt.Device)
+}
+
+// LoginRule inspects a successful sign-in and returns the i18n key of the reason why it is suspicious, or an empty
+// string if it is not. The profile is the one recorded before the sign-in.
+//
+// available since template-r5
+type LoginRule func(settings *SecuritySettings, profile *LoginProfile, attempt *LoginAttempt) string
+
+// loginRules are evaluated in order on every successful sign-in.
+var loginRules = []LoginRule{loginRuleNewCountry, loginRuleNewDevice, loginRuleOutsideHours}
+
+// loginRuleNewCountry flags sign-ins from a country the user has not signed in from. The first known country of a
+// user is never flagged.
+func loginRuleNewCountry(settings *SecuritySettings, profile *LoginProfile, attempt *LoginAttempt) string {
+	if settings.AlertNewCountry && attempt.Country != "" && len(profile.Countries) > 0 && !containsString(profile.Countries, attempt.Country) {
+		return "login_alert_new_country"
+	}
+	return ""
+}
+
+// loginRuleNewDevice flags sign-ins from a device the user has not used. The first known device of a user is never
+// flagged.
+func loginRuleNewDevice(settings *SecuritySettings, profile *LoginProfile, attempt *LoginAttempt) string {
+	if settings.AlertNewDevice && attempt.Device != "" && len(profile.Devices) > 0 && !containsString(profile.Devices, attempt.Device) {
+		return "login_alert_new_device"
+	}
+	return ""
+}
+
+// loginRuleOutsideHours flags sign-ins outside of login hours.
+func loginRuleOutsideHours(settings *SecuritySettings, _ *LoginProfile, attempt *LoginAttempt) string {
+	if settings.AlertOutsideHours && !settings.withinLoginHours(attempt.Time.In(utils.Location).Hour()) {
+		return "login_alert_outside_hours"
+	}
+	return ""
+}
+
+// evaluateLoginRules returns the reasons (i18n keys) why the sign-in is suspicious, empty if it is not.
+func evaluateLoginRules(rules []LoginRule, settings *SecuritySettings, profile *LoginProfile, attempt *LoginAttempt) []string {
+	reasons := make([]string, 0)
+	for _, rule := range rules {
+		if reason := rule(settings, profile, attempt); reason != "" {
+			reasons = append(reasons, reason)
+		}
+	}
+	return reasons
+}
+
+// deviceOf returns a short description of the client's device (e.g. "Chrome on Windows") from its User-Agent header.
+func deviceOf(userAgent string) string {
+	if userAgent == "" {
+		return ""
+	}
+	browser, os := "Other", "Other"
+	for _, b := range [][2]string{{"Edg/", "Edge"}, {"OPR/", "Opera"}, {"Firefox/", "Firefox"}, {"Chrome/", "Chrome"}, {"Safari/", "Safari"}} {
+		if strings.Contains(userAgent, b[0]) {
+			browser = b[1]
+			break
+		}
+	}
+	for _, o := range [][2]string{{"Windows", "Windows"}, {"Android", "Android"}, {"iPhone", "iOS"}, {"iPad", "iOS"}, {"Mac OS X", "macOS"}, {"Linux", "Linux"}} {
+		if strings.Contains(userAgent, o[0]) {
+			os = o[1]
+			break
+		}
+	}
+	return browser + " on " + os
+}
+
+/*----------------------------------------------------------------------*/
+
+// Notification is a message pushed to a user, displayed in the navbar of the control panel. Messages are localized
+// when displayed, in the locale of the viewer.
+//
+// available since template-r5
+type Notification struct {
+	Key    string                 `json:"key"`    // i18n key of the message
+	Params map[string]interface{} `json:"params"` // template data of the message
+	Notes  []string               `json:"notes"`  // i18n keys of additional notes, e.g. reasons of a login alert
+	Time   time.Time              `json:"time"`
+	Read   bool                   `json:"read"`
+}
+
+// settingLockTtl is how long a read-modify-write update of a setting may hold the lock of the setting.
+const settingLockTtl = 10 * time.Second
+
+// withSettingLock runs fn while holding the lock of the setting (see goadmin.Registry.WithLock), so that
+// read-modify-write updates of the setting (e.g. login profiles and notification lists) are serialized, across
+// instances if locks are shared. Updates of different settings do not wait for each other.
+func (r *myRegistry) withSettingLock(id string, fn func() error) error {
+	return r.WithLock(namespace+":"+id, settingLockTtl, fn)
+}
+
+// loadSetting reads the setting into target, returns false if the setting does not exist.
+func (r *myRegistry) loadSetting(id string, target interface{}) (bool, error) {
+	bo, err := r.settingDao.Get(id)
+	if err != nil || bo == nil {
+		return false, err
+	}
+	return true, json.Unmarshal([]byte(bo.Value), target)
+}
+
+// saveSetting stores value as the setting.
+func (r *myRegistry) saveSetting(id string, value interface{}) error {
+	js, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = r.settingDao.Save(&Setting{Id: id, Value: string(js)})
+	return err
+}
+
+// defaultSecuritySettings returns security settings of the configuration block myapp.security.
+func (r *myRegistry) defaultSecuritySettings() *SecuritySettings {
+	conf := r.AppConfig
+	settings := &SecuritySettings{
+		AlertNewCountry:       conf.GetBoolean(namespace+".security.alert_new_country", true),
+		AlertNewDevice:        conf.GetBoolean(namespace+".security.alert_new_device", true),
+		AlertOutsideHours:     conf.GetBoolean(namespace+".security.alert_outside_hours", false),
+		LoginHoursFrom:        int(conf.GetInt32(namespace+".security.login_hours_from", 0)),
+		LoginHoursTo:          int(conf.GetInt32(namespace+".security.login_hours_to", 24)),
+		NotifyAdmins:          conf.GetBoolean(namespace+".security.notify_admins", true),
+		RequireReverification: conf.GetBoolean(namespace+".security.require_reverification", false),
+		AlertSmsNumbers:       conf.GetStringList(namespace + ".security.alert_sms_numbers"),
+	}
+	settings.validate()
+	return settings
+}
+
+// securitySettings returns the security settings saved by admins, or the default ones if none.
+func (r *myRegistry) securitySettings() (*SecuritySettings, error) {
+	settings := r.defaultSecuritySettings()
+	if _, err := r.loadSetting(settingIdSecurity, settings); err != nil {
+		return r.defaultSecuritySettings(), err
+	}
+	settings.validate()
+	return settings, nil
+}
+
+// saveSecuritySettings stores the security settings, overriding the default ones.
+func (r *myRegistry) saveSecuritySettings(settings *SecuritySettings) error {
+	settings.validate()
+	return r.saveSetting(settingIdSecurity, settings)
+}
+
+// notify pushes a notification to the user, dropping the oldest ones beyond maxNotifications.
+func (r *myRegistry) notify(username string, n *Notification) error {
+	err := r.withSettingLock(settingPrefixNotifications+username, func() error {
+		list := make([]*Notification, 0)
+		if _, err := r.loadSetting(settingPrefixNotifications+username, &list); err != nil {
+			return err
+		}
+		list = append([]*Notification{n}, list...)
+		if len(list) > maxNotifications {
+			list = list[:maxNotifications]
+		}
+		return r.saveSetting(settingPrefixNotifications+username, list)
+	})
+	if err != nil {
+		return err
+	}
+	r.emailNotification(username, n)
+	return nil
+}
+
+// emailNotification also sends the notification by email if emails are enabled (setting goadmin.smtp.addr), usernames
+// being email addresses. Emails are written in the default locale, the user's one is not known outside of requests.
+func (r *myRegistry) emailNotification(username string, n *Notification) {
+	if !strings.Contains(username, "@") || !r.Outbox.HasSender(goadmin.OutboxChannelEmail) {
+		return
+	}
+	lines := []string{r.i18n.Localize(defaultLocale, n.Key, &goyai.LocalizeConfig{TemplateData: n.Params})}
+	for _, note := range n.Notes {
+		lines = append(lines, "- "+r.i18n.Localize(defaultLocale, note))
+	}
+	email := &goadmin.EmailPayload{
+		To:      []string{username},
+		Subject: r.AppConfig.GetString("app.name") + ": " + lines[0],
+		Body:    strings.Join(lines, "\n"),
+	}
+	if _, err := r.Outbox.Enqueue(goadmin.OutboxChannelEmail, email); err != nil {
+		log.Printf("[ERROR] cannot enqueue notification email to [%s]: %s", username, err)
+	}
+}
+
+// notifications returns notifications of the user, newest first.
+func (r *myRegistry) notifications(username string) ([]*Notification, error) {
+	list := make([]*Notification, 0)
+	_, err := r.loadSetting(settingPrefixNotifications+username, &list)
+	return list, err
+}
+
+// markNotificationsRead marks all notifications of the user as read.
+func (r *myRegistry) markNotificationsRead(username string) error {
+	return r.withSettingLock(settingPrefixNotifications+username, func() error {
+		list := make([]*Notification, 0)
+		if found, err := r.loadSetting(settingPrefixNotifications+username, &list); err != nil || !found {
+			return err
+		}
+		for _, n := range list {
+			n.Read = true
+		}
+		return r.saveSetting(settingPrefixNotifications+username, list)
+	})
+}
+
+// newLoginAttempt describes the current sign-in of the user.
+func newLoginAttempt(c echo.Context, user *User) *LoginAttempt {
+	attempt := &LoginAttempt{
+		Username: user.Username,
+		Ip:       c.RealIP(),
+		Origin:   clientOrigin(c),
+		Device:   deviceOf(c.Request().UserAgent()),
+		Time:     utils.Now(),
+	}
+	if geoIp := getRegistry(c).GeoIp; geoIp != nil {
+		if loc, err := geoIp.Lookup(attempt.Ip); err == nil && loc != nil {
+			attempt.Country = loc.CountryCode
+		}
+	}
+	return attempt
+}
+
+// signedIn inspects a successful sign-in (see inspectLogin) and emits event user.login, it returns true if the user
+// must re-verify the sign-in.
+func (r *myRegistry) signedIn(attempt *LoginAttempt) bool {
+	settings, reasons := r.inspectLogin(attempt)
+	r.emitEvent(eventUserLogin, map[string]interface{}{
+		"username": attempt.Username, "ip": attempt.Ip, "country": attempt.Country, "device": attempt.Device, "suspicious": len(reasons) > 0,
+	})
+	return len(reasons) > 0 && settings.RequireReverification
+}
+
+// inspectLogin evaluates login rules against the sign-in and records it in the user's login profile. If the sign-in
+// is suspicious, the user (and admins, if configured) are notified. The returned settings tell the caller whether
+// re-verification is required; reasons is empty if the sign-in is not suspicious.
+func (r *myRegistry) inspectLogin(attempt *LoginAttempt) (settings *SecuritySettings, reasons []string) {
+	settings, err := r.securitySettings()
+	if err != nil {
+		log.Printf("[ERROR] error loading security settings: %s", err)
+	}
+
+	err = r.withSettingLock(settingPrefixLoginProfile+attempt.Username, func() error {
+		profile := &LoginProfile{}
+		if _, err := r.loadSetting(settingPrefixLoginProfile+attempt.Username, profile); err != nil {
+			log.Printf("[ERROR] error loading login profile of user [%s]: %s", attempt.Username, err)
+		}
+		reasons = evaluateLoginRules(loginRules, settings, profile, attempt)
+		profile.remember(attempt)
+		if err := r.saveSetting(settingPrefixLoginProfile+attempt.Username, profile); err != nil {
+			log.Printf("[ERROR] error saving login profile of user [%s]: %s", attempt.Username, err)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("[ERROR] cannot lock login profile of user [%s]: %s", attempt.Username, err)
+	}
+
+	if len(reasons) == 0 {
+		return settings, reasons
+	}
+	log.Printf("[LOGIN] suspicious sign-in of user [%s] from %s (%s): %s", attempt.Username, attempt.Origin, attempt.Device, strings.Join(reasons, ","))
+	recipients := []string{attempt.Username}
+	if settings.NotifyAdmins {
+		if users, err := r.userDao.GetAll(); err != nil {
+			log.Printf("[ERROR] error loading admins to notify: %s", err)
+		} else {
+			for _, u := range users {
+				if u.GroupId == systemGroupId && u.Username != attempt.Username {
+					recipients = append(recipients, u.Username)
+				}
+			}
+		}
+	}
+	for _, username := range recipients {
+		n := &Notification{
+			Key:    "notification_suspicious_login",
+			Params: map[string]interface{}{"user": attempt.Username, "origin": attempt.Origin, "device": attempt.Device},
+			Notes:  reasons,
+			Time:   attempt.Time,
+		}
+		if err := r.notify(username, n); err != nil {
+			log.Printf("[ERROR] error notifying user [%s]: %s", username, err)
+		}
+	}
+	r.smsLoginAlert(settings, attempt)
+	return settings, reasons
+}
+
+// smsLoginAlert also texts a flagged sign-in to the numbers of SecuritySettings.AlertSmsNumbers if SMS are enabled
+// (setting goadmin.sms.provider).
+func (r *myRegistry) smsLoginAlert(settings *SecuritySettings, attempt *LoginAttempt) {
+	if len(settings.AlertSmsNumbers) == 0 || !r.Outbox.HasSender(goadmin.OutboxChannelSms) {
+		return
+	}
+	msg := r.i18n.Localize(defaultLocale, "notification_suspicious_login", &goyai.LocalizeConfig{
+		TemplateData: map[string]interface{}{"user": attempt.Username, "origin": attempt.Origin, "device": attempt.Device},
+	})
+	for _, number := range settings.AlertSmsNumbers {
+		sms := &goadmin.SmsPayload{To: number, Body: r.AppConfig.GetString("app.name") + ": " + msg}
+		if _, err := r.Outbox.Enqueue(goadmin.OutboxChannelSms, sms); err != nil {
+			log.Printf("[ERROR] cannot enqueue sign-in alert SMS to [%s]: %s", number, err)
+		}
+	}
+}
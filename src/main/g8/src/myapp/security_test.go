@@ -0,0 +1,186 @@
+package myapp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"main/src/apptest"
+	"main/src/goadmin"
+	"main/src/utils"
+)
+
+const (
+	testUserAgentChrome  = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/100.0 Safari/537.36"
+	testUserAgentFirefox = "Mozilla/5.0 (X11; Linux x86_64; rv:100.0) Gecko/20100101 Firefox/100.0"
+)
+
+func TestDeviceOf(t *testing.T) {
+	testCases := map[string]string{
+		testUserAgentChrome:  "Chrome on Windows",
+		testUserAgentFirefox: "Firefox on Linux",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 15_0 like Mac OS X) AppleWebKit/605.1.15 Version/15.0 Mobile/15E148 Safari/604.1": "Safari on iOS",
+		"curl/7.68.0": "Other on Other",
+		"":            "",
+	}
+	for ua, expected := range testCases {
+		if device := deviceOf(ua); device != expected {
+			t.Fatalf("TestDeviceOf failed: expected [%s] for [%s] but received [%s]", expected, ua, device)
+		}
+	}
+}
+
+func TestEvaluateLoginRules(t *testing.T) {
+	if utils.Location == nil {
+		utils.Location = time.UTC
+	}
+	settings := &SecuritySettings{AlertNewCountry: true, AlertNewDevice: true, AlertOutsideHours: true, LoginHoursFrom: 22, LoginHoursTo: 6}
+	at := func(hour int) time.Time { return time.Date(2022, 1, 1, hour, 0, 0, 0, utils.Location) }
+	testCases := []struct {
+		profile  *LoginProfile
+		attempt  *LoginAttempt
+		expected string
+	}{
+		// first sign-in: country and device are not flagged
+		{&LoginProfile{}, &LoginAttempt{Country: "VN", Device: "Chrome on Windows", Time: at(23)}, ""},
+		{&LoginProfile{Countries: []string{"VN"}, Devices: []string{"Chrome on Windows"}}, &LoginAttempt{Country: "VN", Device: "Chrome on Windows", Time: at(2)}, ""},
+		{&LoginProfile{Countries: []string{"VN"}, Devices: []string{"Chrome on Windows"}}, &LoginAttempt{Country: "US", Device: "Chrome on Windows", Time: at(23)}, "login_alert_new_country"},
+		{&LoginProfile{Countries: []string{"VN"}, Devices: []string{"Chrome on Windows"}}, &LoginAttempt{Country: "", Device: "Firefox on Linux", Time: at(23)}, "login_alert_new_device"},
+		{&LoginProfile{Countries: []string{"VN"}, Devices: []string{"Chrome on Windows"}}, &LoginAttempt{Country: "US", Device: "Firefox on Linux", Time: at(12)}, "login_alert_new_country,login_alert_new_device,login_alert_outside_hours"},
+	}
+	for i, tc := range testCases {
+		reasons := strings.Join(evaluateLoginRules(loginRules, settings, tc.profile, tc.attempt), ",")
+		if reasons != tc.expected {
+			t.Fatalf("TestEvaluateLoginRules failed: expected [%s] for case %d but received [%s]", tc.expected, i, reasons)
+		}
+	}
+}
+
+func _loginWithUserAgent(h *apptest.Harness, userAgent string) *httptest.ResponseRecorder {
+	form := url.Values{"username": {testAdminUsername}, "password": {testAdminPassword}}
+	req := httptest.NewRequest(http.MethodPost, h.Reverse(actionNameCpLoginSubmit), strings.NewReader(form.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	req.Header.Set("User-Agent", userAgent)
+	return h.Do(req)
+}
+
+func TestSuspiciousLogin(t *testing.T) {
+	h := _newHarness(t)
+	resp := _loginWithUserAgent(h, testUserAgentChrome)
+	h.AssertRedirect(resp, h.Reverse(actionNameCpDashboard))
+
+	resp = h.PostForm(h.Reverse(actionNameCpSecuritySettingsSubmit), url.Values{
+		"alert_new_device":       {"1"},
+		"require_reverification": {"1"},
+		"login_hours_from":       {"0"},
+		"login_hours_to":         {"24"},
+	})
+	h.AssertRedirect(resp, h.Reverse(actionNameCpSecuritySettings))
+	resp = h.Get(h.Reverse(actionNameCpSecuritySettings))
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertData("flashInfo", "updated successfully")
+
+	// sign-in from a new device: the user must confirm their password before accessing the control panel
+	h.PostForm(h.Reverse(actionNameCpLogout), nil)
+	resp = _loginWithUserAgent(h, testUserAgentFirefox)
+	h.AssertRedirect(resp, h.Reverse(actionNameCpVerifyLogin))
+	resp = h.Get(h.Reverse(actionNameCpUsers))
+	h.AssertRedirect(resp, h.Reverse(actionNameCpVerifyLogin))
+	resp = h.PostForm(h.Reverse(actionNameCpVerifyLoginSubmit), url.Values{"password": {"wrong"}})
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertTemplate(namespace + ":cp_verify_login")
+	resp = h.PostForm(h.Reverse(actionNameCpVerifyLoginSubmit), url.Values{"password": {testAdminPassword}})
+	h.AssertRedirect(resp, h.Reverse(actionNameCpDashboard))
+
+	resp = h.Get(h.Reverse(actionNameCpDashboard))
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertBodyContains(resp, "Firefox on Linux")
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	if list, err := myReg.notifications(testAdminUsername); err != nil || len(list) != 1 || list[0].Read {
+		t.Fatalf("TestSuspiciousLogin failed: expected 1 unread notification but received %#v/%s", list, err)
+	}
+	resp = h.PostForm(h.Reverse(actionNameCpReadNotifications), nil)
+	h.AssertRedirect(resp, h.Reverse(actionNameCpDashboard))
+	if list, err := myReg.notifications(testAdminUsername); err != nil || len(list) != 1 || !list[0].Read {
+		t.Fatalf("TestSuspiciousLogin failed: expected notification to be read but received %#v/%s", list, err)
+	}
+}
+
+func TestSmsAlerts(t *testing.T) {
+	received := make([]map[string]string, 0)
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sms := map[string]string{}
+		json.NewDecoder(r.Body).Decode(&sms)
+		if r.Header.Get("Authorization") != "Bearer gw-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		received = append(received, sms)
+	}))
+	defer gateway.Close()
+	conf := apptest.SqliteInMemoryConfig + `
+goadmin.sms { provider = "http", from = "GoAdmin", http { url = "` + gateway.URL + `", authorization = "Bearer gw-token" } }
+`
+	h := apptest.New(t, conf, NewBootstrapper(nil, nil))
+	h.AssertRedirect(_loginWithUserAgent(h, testUserAgentChrome), h.Reverse(actionNameCpDashboard))
+
+	// alert numbers must be in E.164 format
+	form := url.Values{"alert_new_device": {"1"}, "login_hours_from": {"0"}, "login_hours_to": {"24"}, "alert_sms_numbers": {"0901234567"}}
+	h.AssertStatus(h.PostForm(h.Reverse(actionNameCpSecuritySettingsSubmit), form), http.StatusOK)
+	h.AssertData("error", "0901234567")
+	form.Set("alert_sms_numbers", "+84901234567\n")
+	h.AssertRedirect(h.PostForm(h.Reverse(actionNameCpSecuritySettingsSubmit), form), h.Reverse(actionNameCpSecuritySettings))
+
+	// test SMS are sent right away
+	h.AssertRedirect(h.PostForm(h.Reverse(actionNameCpSmsTestSubmit), url.Values{"number": {"+84907654321"}}), h.Reverse(actionNameCpSecuritySettings))
+	if len(received) != 1 || received[0]["to"] != "+84907654321" || received[0]["from"] != "GoAdmin" {
+		t.Fatalf("TestSmsAlerts failed: unexpected test SMS %#v", received)
+	}
+
+	// suspicious sign-ins are texted to alert numbers via the outbox
+	h.PostForm(h.Reverse(actionNameCpLogout), nil)
+	_loginWithUserAgent(h, testUserAgentFirefox)
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	msgs, err := myReg.Outbox.Messages(goadmin.OutboxChannelSms)
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("TestSmsAlerts failed: expected 1 queued SMS but received %#v/%s", msgs, err)
+	}
+	myReg.Outbox.Dispatch()
+	if len(received) != 2 || received[1]["to"] != "+84901234567" || !strings.Contains(received[1]["body"], "Firefox on Linux") {
+		t.Fatalf("TestSmsAlerts failed: unexpected alert SMS %#v", received)
+	}
+}
+
+func TestWithSettingLock(t *testing.T) {
+	testName := "TestWithSettingLock"
+	h := _newHarness(t)
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+
+	// concurrent updates of a setting are serialized
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			myReg.notify(testAdminUsername, &Notification{Key: "notification_suspicious_login", Time: time.Now()})
+		}()
+	}
+	wg.Wait()
+	if list, _ := myReg.notifications(testAdminUsername); len(list) != 5 {
+		t.Fatalf("%s failed: expected 5 notifications but received %d", testName, len(list))
+	}
+
+	// updates of different settings do not wait for each other
+	err := myReg.withSettingLock(settingPrefixNotifications+testAdminUsername, func() error {
+		return myReg.withSettingLock(settingPrefixDailyStats+"test", func() error { return nil })
+	})
+	if err != nil {
+		t.Fatalf("%s failed: %s", testName, err)
+	}
+}
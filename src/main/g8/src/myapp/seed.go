@@ -0,0 +1,234 @@
+package myapp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SeedUser is a user account declared in the seed file. Password is only used to create the account: passwords of
+// existing users are never reset, so that users can change them.
+//
+// available since template-r5
+type SeedUser struct {
+	Username string `yaml:"username"`
+	Name     string `yaml:"name"`
+	Group    string `yaml:"group"`
+	Password string `yaml:"password"`
+}
+
+// SeedWebhook is a webhook subscription declared in the seed file, see setting myapp.webhooks.
+//
+// available since template-r5
+type SeedWebhook struct {
+	Url    string   `yaml:"url"`
+	Events []string `yaml:"events"`
+}
+
+// Seed is the baseline state of the application declared in the seed file (setting myapp.seed_file), so that
+// infrastructure-as-code pipelines can manage it. Example:
+//
+//	groups:
+//	  - { id: ops, name: Operators }
+//	roles:
+//	  ops: [user.create, user.edit]
+//	users:
+//	  - { username: alice@example.com, name: Alice, group: ops, password: "${ALICE_PASSWORD}" }
+//	settings:
+//	  security: { alert_new_device: true, notify_admins: true }
+//	webhooks:
+//	  crm: { url: "https://crm.example.com/hooks/admin", events: [user.created] }
+//
+// available since template-r5
+type Seed struct {
+	Groups   []*Group                `yaml:"groups"`
+	Roles    map[string][]string     `yaml:"roles"` // group id -> permissions, in addition to setting myapp.permissions
+	Users    []*SeedUser             `yaml:"users"`
+	Settings map[string]interface{}  `yaml:"settings"` // setting id -> value, stored as JSON
+	Webhooks map[string]*SeedWebhook `yaml:"webhooks"` // in addition to setting myapp.webhooks
+}
+
+// reSeedEnv matches references to environment variables in the seed file, e.g. "${ALICE_PASSWORD}".
+var reSeedEnv = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// parseSeed decodes a seed file, references to environment variables are replaced by their values so that secrets
+// (e.g. passwords) are not written in the file.
+func parseSeed(data []byte) (*Seed, error) {
+	var missing []string
+	data = reSeedEnv.ReplaceAllFunc(data, func(ref []byte) []byte {
+		name := string(ref[2 : len(ref)-1])
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return []byte(value)
+	})
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("environment variables %s are not set", strings.Join(missing, ", "))
+	}
+	seed := &Seed{}
+	if err := yaml.Unmarshal(data, seed); err != nil {
+		return nil, err
+	}
+	for _, g := range seed.Groups {
+		if g.Id = strings.ToLower(strings.TrimSpace(g.Id)); g.Id == "" {
+			return nil, fmt.Errorf("groups must have an id")
+		}
+		if g.Name = strings.TrimSpace(g.Name); g.Name == "" {
+			g.Name = g.Id
+		}
+	}
+	for _, u := range seed.Users {
+		u.Username = strings.ToLower(strings.TrimSpace(u.Username))
+		u.Group = strings.ToLower(strings.TrimSpace(u.Group))
+		if u.Username == "" || u.Group == "" {
+			return nil, fmt.Errorf("users must have a username and a group")
+		}
+	}
+	for id := range seed.Settings {
+		if isBundleExcludedSetting(id) {
+			return nil, fmt.Errorf("setting [%s] is not a configuration setting", id)
+		}
+	}
+	for name, w := range seed.Webhooks {
+		if w == nil || w.Url == "" {
+			return nil, fmt.Errorf("webhook [%s] has no url", name)
+		}
+	}
+	return seed, nil
+}
+
+// applySeedFile reconciles the database with the seed file (setting myapp.seed_file) at startup, if any.
+func (r *myRegistry) applySeedFile() error {
+	path := r.AppConfig.GetString(namespace+".seed_file", "")
+	if path == "" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	seed, err := parseSeed(data)
+	if err != nil {
+		return fmt.Errorf("invalid seed file [%s]: %s", path, err)
+	}
+	// replicas starting simultaneously must not create the same records twice
+	return r.WithLock(namespace+":seed", time.Minute, func() error {
+		changes, err := r.applySeed(seed)
+		for _, change := range changes {
+			r.auditf("seed file [%s]: %s %s [%s]%s", path, change.Action, change.Kind, change.Id, seedChangeNote(change))
+		}
+		return err
+	})
+}
+
+func seedChangeNote(change *BundleChange) string {
+	if change.Note == "" {
+		return ""
+	}
+	return " (" + change.Note + ")"
+}
+
+// applySeed creates or updates groups, users and settings of the seed, and grants its roles and subscribes its
+// webhooks. Records missing from the seed are never deleted.
+func (r *myRegistry) applySeed(seed *Seed) ([]*BundleChange, error) {
+	// roles and webhooks are not stored in database: they are applied again at every startup, like settings
+	// myapp.permissions and myapp.webhooks
+	for gid, perms := range seed.Roles {
+		granted, err := parsePermissions(gid, perms)
+		if err != nil {
+			return nil, err
+		}
+		gid = strings.ToLower(gid)
+		if r.permissions[gid] == nil {
+			r.permissions[gid] = make(map[string]bool)
+		}
+		for p := range granted {
+			r.permissions[gid][p] = true
+		}
+	}
+	for name, w := range seed.Webhooks {
+		sub := &webhookSubscription{name: name, url: w.Url, events: w.Events}
+		replaced := false
+		for i, existing := range r.webhooks {
+			if existing.name == name {
+				r.webhooks[i], replaced = sub, true
+			}
+		}
+		if !replaced {
+			r.webhooks = append(r.webhooks, sub)
+		}
+	}
+	sort.Slice(r.webhooks, func(i, j int) bool { return r.webhooks[i].name < r.webhooks[j].name })
+
+	// groups and settings are reconciled the same way configuration bundles are imported
+	content := &BundleContent{Groups: seed.Groups}
+	ids := make([]string, 0, len(seed.Settings))
+	for id := range seed.Settings {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		js, err := json.Marshal(seed.Settings[id])
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of setting [%s]: %s", id, err)
+		}
+		content.Settings = append(content.Settings, &Setting{Id: id, Value: string(js)})
+	}
+	changes, err := r.importConfigBundle(&ConfigBundle{Content: content}, false)
+	if err != nil {
+		return changes, err
+	}
+
+	for _, u := range seed.Users {
+		if g, err := r.groupDao.Get(u.Group); err != nil {
+			return changes, err
+		} else if g == nil {
+			return changes, fmt.Errorf("group [%s] of user [%s] does not exist", u.Group, u.Username)
+		}
+		current, err := r.userDao.Get(u.Username)
+		if err != nil {
+			return changes, err
+		}
+		if current == nil {
+			if u.Password == "" {
+				return changes, fmt.Errorf("user [%s] does not exist and has no password to be created with", u.Username)
+			}
+			changes = append(changes, &BundleChange{Kind: "user", Id: u.Username, Action: bundleActionCreate, To: u.Group})
+			if _, err := r.userDao.Create(u.Username, encryptPassword(u.Username, u.Password), u.Name, u.Group); err != nil {
+				return changes, err
+			}
+			continue
+		}
+		updated := false
+		if u.Name != "" && current.Name != u.Name {
+			changes = append(changes, &BundleChange{Kind: "user", Id: u.Username, Action: bundleActionUpdate, From: current.Name, To: u.Name, Note: "name"})
+			current.Name, updated = u.Name, true
+		}
+		if current.GroupId != u.Group {
+			if current.Username == systemUserUsername {
+				changes = append(changes, &BundleChange{Kind: "user", Id: u.Username, Action: bundleActionSkip, Note: "system user's group cannot be changed"})
+			} else {
+				changes = append(changes, &BundleChange{Kind: "user", Id: u.Username, Action: bundleActionUpdate, From: current.GroupId, To: u.Group, Note: "group"})
+				current.GroupId, updated = u.Group, true
+			}
+		}
+		if updated {
+			if _, err := r.userDao.Update(current); err != nil {
+				return changes, err
+			}
+		}
+	}
+	if len(changes) > 0 {
+		log.Printf("Seed file applied: %d change(s)", len(changes))
+	}
+	return changes, nil
+}
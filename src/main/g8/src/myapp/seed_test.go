@@ -0,0 +1,80 @@
+package myapp
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"main/src/apptest"
+)
+
+func TestSeedFile(t *testing.T) {
+	testName := "TestSeedFile"
+	os.Setenv("TEST_SEED_PASSWORD", "s33d-pwd")
+	defer os.Unsetenv("TEST_SEED_PASSWORD")
+	seedFile := filepath.Join(t.TempDir(), "bootstrap.yaml")
+	ioutil.WriteFile(seedFile, []byte(`
+groups:
+  - { id: Ops, name: Operators }
+roles:
+  ops: [user.create]
+users:
+  - { username: alice@example.com, name: Alice, group: ops, password: "${TEST_SEED_PASSWORD}" }
+settings:
+  security: { alert_new_device: true, login_hours_from: 0, login_hours_to: 24 }
+webhooks:
+  crm: { url: "http://localhost/hooks", events: [user.created] }
+`), 0600)
+	h := apptest.New(t, apptest.SqliteInMemoryConfig+"\nmyapp.seed_file = \""+seedFile+"\"\n", NewBootstrapper(nil, nil))
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	if g, _ := myReg.groupDao.Get("ops"); g == nil || g.Name != "Operators" {
+		t.Fatalf("%s failed: group was not created: %#v", testName, g)
+	}
+	user, _ := myReg.userDao.Get("alice@example.com")
+	if user == nil || user.GroupId != "ops" || user.Password != encryptPassword(user.Username, "s33d-pwd") {
+		t.Fatalf("%s failed: user was not created: %#v", testName, user)
+	}
+	if !myReg.hasPermission(user, permUserCreate) || myReg.hasPermission(user, permUserDelete) {
+		t.Fatalf("%s failed: unexpected permissions %#v", testName, myReg.permissions)
+	}
+	if settings, _ := myReg.securitySettings(); !settings.AlertNewDevice {
+		t.Fatalf("%s failed: setting was not created: %#v", testName, settings)
+	}
+	if len(myReg.webhooks) != 1 || myReg.webhooks[0].name != "crm" {
+		t.Fatalf("%s failed: unexpected webhooks %#v", testName, myReg.webhooks)
+	}
+
+	// records are updated, passwords are not reset, records missing from the seed are kept
+	myReg.groupDao.Create("extra", "Extra")
+	seed, err := parseSeed([]byte(`
+groups: [{ id: ops, name: Operations }]
+users: [{ username: alice@example.com, name: Alice Doe, group: ops, password: other }]
+`))
+	if err != nil {
+		t.Fatalf("%s failed: %s", testName, err)
+	}
+	changes, err := myReg.applySeed(seed)
+	if err != nil || len(changes) != 2 {
+		t.Fatalf("%s failed: expected 2 changes but received %#v/%s", testName, changes, err)
+	}
+	user, _ = myReg.userDao.Get("alice@example.com")
+	if user.Name != "Alice Doe" || user.Password != encryptPassword(user.Username, "s33d-pwd") {
+		t.Fatalf("%s failed: unexpected user %#v", testName, user)
+	}
+	if g, _ := myReg.groupDao.Get("extra"); g == nil {
+		t.Fatalf("%s failed: group missing from the seed was deleted", testName)
+	}
+	if changes, _ := myReg.applySeed(seed); len(changes) != 0 {
+		t.Fatalf("%s failed: applying the same seed twice must not change anything: %#v", testName, changes)
+	}
+
+	// invalid seeds are rejected
+	for _, invalid := range []string{"roles: { ops: [nope] }", "settings: { notifications:admin: [] }", "users: [{ username: bob }]", "groups: [{ id: x, name: \"${TEST_SEED_UNDEFINED}\" }]"} {
+		if seed, err := parseSeed([]byte(invalid)); err == nil {
+			if _, err = myReg.applySeed(seed); err == nil {
+				t.Fatalf("%s failed: seed [%s] should be rejected", testName, invalid)
+			}
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package myapp
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ctxPageMeta is the context key handlers set a *PageMeta to, overriding the configured metadata of the page.
+const ctxPageMeta = "page_meta"
+
+// PageMeta is the metadata of a public page rendered as meta tags (title, description, Open Graph and canonical URL),
+// passed to templates as "meta".
+//
+// available since template-r5
+type PageMeta struct {
+	Title       string
+	Description string
+	Image       string // absolute URL of the Open Graph image, empty if none
+	Type        string // Open Graph type, "website" by default
+	Canonical   string // absolute URL of the page, see seoConfig.absoluteUrl
+	NoIndex     bool   // ask search engines not to index the page
+}
+
+// seoConfig is the configuration block myapp.seo: metadata of public pages, per route name, and the sitemap listing
+// them.
+//
+// available since template-r5
+type seoConfig struct {
+	baseUrl  string               // canonical base URL, e.g. "https://www.example.com", empty to use the one of the request
+	defaults *PageMeta            // metadata of pages without their own
+	pages    map[string]*PageMeta // per route name
+	sitemap  bool
+}
+
+// newSeoConfig creates a seoConfig from the configuration block myapp.seo.
+func newSeoConfig(r *myRegistry) (*seoConfig, error) {
+	conf := r.AppConfig
+	confPath := namespace + ".seo"
+	s := &seoConfig{
+		baseUrl: strings.TrimSuffix(strings.TrimSpace(conf.GetString(confPath+".base_url", "")), "/"),
+		defaults: &PageMeta{
+			Title:       conf.GetString(confPath+".title", ""),
+			Description: conf.GetString(confPath+".description", conf.GetString("app.desc", "")),
+			Image:       conf.GetString(confPath+".image", ""),
+			Type:        "website",
+		},
+		pages:   make(map[string]*PageMeta),
+		sitemap: conf.GetBoolean(confPath+".sitemap", true),
+	}
+	if s.baseUrl != "" {
+		if u, err := url.Parse(s.baseUrl); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("setting [%s.base_url] must be an absolute http(s) URL", confPath)
+		}
+	}
+	if v := conf.GetValue(confPath + ".pages"); v != nil && v.IsObject() {
+		for name := range v.GetObject().Items() {
+			if node := conf.GetValue(confPath + ".pages." + name); node == nil || !node.IsObject() {
+				return nil, fmt.Errorf("metadata of page [%s] must be an object", name)
+			}
+			p := confPath + ".pages." + name
+			s.pages[name] = &PageMeta{
+				Title:       conf.GetString(p+".title", s.defaults.Title),
+				Description: conf.GetString(p+".description", s.defaults.Description),
+				Image:       conf.GetString(p+".image", s.defaults.Image),
+				Type:        conf.GetString(p+".type", s.defaults.Type),
+				NoIndex:     conf.GetBoolean(p+".noindex", false),
+			}
+		}
+	}
+	return s, nil
+}
+
+// absoluteUrl returns the absolute URL of an application path (e.g. "/"), based on setting myapp.seo.base_url or, if
+// empty, on the scheme and host of the request and the base path the application is mounted under.
+func (s *seoConfig) absoluteUrl(c echo.Context, path string) string {
+	if s.baseUrl != "" {
+		return s.baseUrl + path
+	}
+	return c.Scheme() + "://" + c.Request().Host + getRegistry(c).Url(path)
+}
+
+// routePath returns the path of the named route, empty if the route does not exist or has parameters.
+func routePath(c echo.Context, name string) string {
+	path := c.Echo().Reverse(name)
+	if path == "" || strings.Contains(path, ":") || strings.Contains(path, "*") {
+		return ""
+	}
+	return path
+}
+
+// pageMeta returns the metadata of the current page: the one set by the handler (see ctxPageMeta), the one configured
+// for the route or the defaults, with the canonical URL filled in. siteName is the default title.
+func (s *seoConfig) pageMeta(c echo.Context, siteName string) *PageMeta {
+	meta := *s.defaults
+	if m, ok := c.Get(ctxPageMeta).(*PageMeta); ok && m != nil {
+		meta = *m
+	} else {
+		for name, m := range s.pages {
+			if path := routePath(c, name); path != "" && path == c.Path() {
+				meta = *m
+				break
+			}
+		}
+	}
+	if meta.Title == "" {
+		meta.Title = siteName
+	}
+	if meta.Type == "" {
+		meta.Type = "website"
+	}
+	if meta.Canonical == "" {
+		// query strings are not part of canonical URLs
+		meta.Canonical = s.absoluteUrl(c, c.Request().URL.Path)
+	} else if strings.HasPrefix(meta.Canonical, "/") {
+		meta.Canonical = s.absoluteUrl(c, meta.Canonical)
+	}
+	if strings.HasPrefix(meta.Image, "/") {
+		meta.Image = s.absoluteUrl(c, meta.Image)
+	}
+	return &meta
+}
+
+/*----------------------------------------------------------------------*/
+
+// sitemapUrlSet is the document served at /sitemap.xml, see https://www.sitemaps.org/protocol.html
+type sitemapUrlSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Urls    []sitemapUrl `xml:"url"`
+}
+
+type sitemapUrl struct {
+	Loc string `xml:"loc"`
+}
+
+// sitemapUrls lists absolute URLs of the public pages: the home page if it has content (see homePage), pages
+// configured in myapp.seo.pages, except those not to be indexed, the contact form if enabled and published pages of the
+// Pages module.
+func (s *seoConfig) sitemapUrls(c echo.Context) []string {
+	paths := make(map[string]bool)
+	if home := getRegistry(c).home; home != nil && home.mode != homeModeRedirect && home.mode != homeModeNotFound {
+		if m, ok := s.pages[actionNameHome]; !ok || !m.NoIndex {
+			paths[routePath(c, actionNameHome)] = true
+		}
+	}
+	for name, m := range s.pages {
+		if path := routePath(c, name); path != "" && !m.NoIndex && name != actionNameHome {
+			paths[path] = true
+		}
+	}
+	if getRegistry(c).contact != nil {
+		if m, ok := s.pages[actionNameContact]; !ok || !m.NoIndex {
+			paths[routePath(c, actionNameContact)] = true
+		}
+	}
+	if pages, err := getRegistry(c).pageDao.GetAll(); err != nil {
+		log.Printf("[ERROR] cannot load pages: %s", err)
+	} else {
+		for _, page := range pages {
+			if page.Published {
+				paths[c.Echo().Reverse(actionNamePage, page.Slug)] = true
+			}
+		}
+	}
+	urls := make([]string, 0, len(paths))
+	for path := range paths {
+		urls = append(urls, s.absoluteUrl(c, path))
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+// actionSitemap serves the sitemap of the public pages.
+//
+// available since template-r5
+func actionSitemap(c echo.Context) error {
+	s := getRegistry(c).seo
+	doc := &sitemapUrlSet{Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9", Urls: make([]sitemapUrl, 0)}
+	for _, loc := range s.sitemapUrls(c) {
+		doc.Urls = append(doc.Urls, sitemapUrl{Loc: loc})
+	}
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	return c.Blob(http.StatusOK, echo.MIMEApplicationXMLCharsetUTF8, append([]byte(xml.Header), body...))
+}
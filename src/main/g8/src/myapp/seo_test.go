@@ -0,0 +1,49 @@
+package myapp
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"main/src/apptest"
+)
+
+func TestSeo(t *testing.T) {
+	testName := "TestSeo"
+	conf := apptest.SqliteInMemoryConfig + `
+myapp.seo {
+  base_url = "https://www.example.com/"
+  description = "Default description"
+  pages {
+    home { title = "Acme Admin", image = "/og.png" }
+    cp_login { noindex = true }
+  }
+}
+`
+	h := apptest.New(t, conf, NewBootstrapper(nil, nil))
+	resp := h.Get("/?utm_source=test")
+	h.AssertBodyContains(resp, `<link rel="canonical" href="https://www.example.com/" />`)
+	h.AssertBodyContains(resp, `<meta property="og:title" content="Acme Admin" />`)
+	h.AssertBodyContains(resp, `<meta property="og:image" content="https://www.example.com/og.png" />`)
+	h.AssertBodyContains(resp, `<meta name="description" content="Default description" />`)
+
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpLogin)), http.StatusOK)
+	if meta, _ := h.LastData()["meta"].(*PageMeta); meta == nil || !meta.NoIndex {
+		t.Fatalf("%s failed: expected login page not to be indexed, got %#v", testName, meta)
+	}
+
+	resp = h.Get("/sitemap.xml")
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertBodyContains(resp, "<loc>https://www.example.com/</loc>")
+	if strings.Contains(resp.Body.String(), "/cp/login") {
+		t.Fatalf("%s failed: pages not to be indexed must not be listed in the sitemap", testName)
+	}
+
+	// the home page is not listed if it has no content
+	h = apptest.New(t, apptest.SqliteInMemoryConfig+"\nmyapp.home.mode = not_found\n", NewBootstrapper(nil, nil))
+	resp = h.Get("/sitemap.xml")
+	h.AssertStatus(resp, http.StatusOK)
+	if strings.Contains(resp.Body.String(), "<loc>") {
+		t.Fatalf("%s failed: unexpected sitemap %s", testName, resp.Body.String())
+	}
+}
@@ -0,0 +1,112 @@
+package myapp
+
+import (
+	"database/sql"
+	"sync"
+	"sync/atomic"
+)
+
+// sqlStmtCache keeps prepared statements of hot queries (e.g. fetching a row by primary key, paging through a table),
+// so that they are parsed and planned once instead of on every call. Statements are prepared on the connection pool:
+// database/sql prepares each of them again on every pooled connection it runs on and keeps them per connection, the
+// cache only holds one statement per query.
+//
+// available since template-r5
+type sqlStmtCache struct {
+	db     *sql.DB
+	lock   sync.RWMutex
+	stmts  map[string]*sql.Stmt
+	hits   int64
+	misses int64
+	errors int64
+}
+
+func newSqlStmtCache(db *sql.DB) *sqlStmtCache {
+	return &sqlStmtCache{db: db, stmts: make(map[string]*sql.Stmt)}
+}
+
+// SqlStmtStats are metrics of cached prepared statements.
+//
+// available since template-r5
+type SqlStmtStats struct {
+	Statements int   // number of cached statements
+	Hits       int64 // number of executions of already prepared statements
+	Misses     int64 // number of statements prepared
+	Errors     int64 // number of statements that could not be prepared
+}
+
+// add sums metrics of several caches.
+func (s SqlStmtStats) add(other SqlStmtStats) SqlStmtStats {
+	return SqlStmtStats{Statements: s.Statements + other.Statements, Hits: s.Hits + other.Hits, Misses: s.Misses + other.Misses, Errors: s.Errors + other.Errors}
+}
+
+// HitRatio returns the percentage of executions that reused a prepared statement.
+func (s SqlStmtStats) HitRatio() float64 {
+	if total := s.Hits + s.Misses; total > 0 {
+		return float64(s.Hits*1000/total) / 10
+	}
+	return 0
+}
+
+// prepare returns the prepared statement of a query, preparing it on first use.
+func (c *sqlStmtCache) prepare(query string) (*sql.Stmt, error) {
+	c.lock.RLock()
+	stmt := c.stmts[query]
+	c.lock.RUnlock()
+	if stmt != nil {
+		atomic.AddInt64(&c.hits, 1)
+		return stmt, nil
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if stmt = c.stmts[query]; stmt != nil {
+		atomic.AddInt64(&c.hits, 1)
+		return stmt, nil
+	}
+	stmt, err := c.db.Prepare(query)
+	if err != nil {
+		atomic.AddInt64(&c.errors, 1)
+		return nil, err
+	}
+	atomic.AddInt64(&c.misses, 1)
+	c.stmts[query] = stmt
+	return stmt, nil
+}
+
+// query runs a cached query with the supplied arguments.
+func (c *sqlStmtCache) query(query string, args ...interface{}) (*sql.Rows, error) {
+	stmt, err := c.prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.Query(args...)
+}
+
+// stats returns metrics of the cache.
+func (c *sqlStmtCache) stats() SqlStmtStats {
+	c.lock.RLock()
+	numStmts := len(c.stmts)
+	c.lock.RUnlock()
+	return SqlStmtStats{
+		Statements: numStmts,
+		Hits:       atomic.LoadInt64(&c.hits),
+		Misses:     atomic.LoadInt64(&c.misses),
+		Errors:     atomic.LoadInt64(&c.errors),
+	}
+}
+
+// sqlStmtCacher is implemented by SQL DAOs that cache prepared statements, see myRegistry.sqlStmtStats.
+type sqlStmtCacher interface {
+	stmtCache() *sqlStmtCache
+}
+
+// sqlStmtStats returns metrics of prepared statements cached by myapp's DAOs, false if the DAOs do not cache
+// statements (e.g. NoSQL databases).
+func (r *myRegistry) sqlStmtStats() (SqlStmtStats, bool) {
+	var result SqlStmtStats
+	for _, cache := range r.stmtCaches {
+		result = result.add(cache.stats())
+	}
+	return result, len(r.stmtCaches) > 0
+}
@@ -0,0 +1,47 @@
+package myapp
+
+import (
+	"net/http"
+	"testing"
+
+	prom "github.com/btnguyen2k/prom/sql"
+)
+
+func TestSqlStmtCache(t *testing.T) {
+	testName := "TestSqlStmtCache"
+	h := _newHarness(t)
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	if _, ok := myReg.sqlStmtStats(); !ok {
+		t.Fatalf("%s failed: SQL DAOs must cache prepared statements", testName)
+	}
+	for i := 0; i < 3; i++ {
+		user, err := myReg.userDao.Get(testAdminUsername)
+		if err != nil || user == nil || user.GroupId != systemGroupId {
+			t.Fatalf("%s failed: expected user [%s] but received %#v / %v", testName, testAdminUsername, user, err)
+		}
+		if users, err := myReg.userDao.GetN(0, 10); err != nil || len(users) == 0 {
+			t.Fatalf("%s failed: expected a page of users but received %d / %v", testName, len(users), err)
+		}
+	}
+	if user, err := myReg.userDao.Get("not-exists"); err != nil || user != nil {
+		t.Fatalf("%s failed: expected no user but received %#v / %v", testName, user, err)
+	}
+	if stats, _ := myReg.sqlStmtStats(); stats.Hits < 4 || stats.Errors != 0 {
+		t.Fatalf("%s failed: expected statements to be reused but received %#v", testName, stats)
+	}
+
+	// offset without limit is left to godal
+	if sqlStm, _, ok := sqlPagingQuery(prom.FlavorPgSql, "*", "t", "gid", "uname", 10, 20); !ok || sqlStm != "SELECT * FROM t WHERE gid=$1 ORDER BY uname LIMIT $2 OFFSET $3" {
+		t.Fatalf("%s failed: unexpected paging statement [%s]", testName, sqlStm)
+	}
+	if _, _, ok := sqlPagingQuery(prom.FlavorMySql, "*", "t", "", "uname", 10, 0); ok {
+		t.Fatalf("%s failed: offset without limit must not be expressed by a prepared statement", testName)
+	}
+
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	resp := h.Get(h.Reverse(actionNameCpRetentionSettings))
+	h.AssertStatus(resp, http.StatusOK)
+	if _, ok := h.LastData()["stmtStats"]; !ok {
+		t.Fatalf("%s failed: metrics of prepared statements must be shown", testName)
+	}
+}
@@ -0,0 +1,158 @@
+package myapp
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"main/src/utils"
+)
+
+const (
+	// settingPrefixDailyStats prefixes ids of settings that store daily counters, e.g. "stats:2006-01-02".
+	settingPrefixDailyStats = "stats:"
+
+	statSignups = "signups"
+	statLogins  = "logins"
+
+	// defaultStatsDays and maxStatsDays bound the number of days returned by actionCpStatsData.
+	defaultStatsDays = 30
+	maxStatsDays     = 365
+
+	statsDateLayout = "2006-01-02"
+)
+
+// countUsersByGroup returns number of users of each group, keyed by group id. DAOs implementing UserCounter aggregate
+// natively, users of other DAOs are fetched and counted.
+//
+// available since template-r5
+func countUsersByGroup(dao UserDao) (map[string]int, error) {
+	if counter, ok := dao.(UserCounter); ok {
+		return counter.CountByGroup()
+	}
+	users, err := dao.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]int)
+	for _, u := range users {
+		result[u.GroupId]++
+	}
+	return result, nil
+}
+
+// DailyStat holds counters of a day (in the application's timezone).
+//
+// available since template-r5
+type DailyStat struct {
+	Date    string `json:"date"`
+	Signups int    `json:"signups"`
+	Logins  int    `json:"logins"`
+}
+
+// recordDailyStat increases today's counter of the specified kind (statSignups or statLogins). Failures are logged
+// but not returned: statistics must not break sign-ins or sign-ups.
+func (r *myRegistry) recordDailyStat(kind string) {
+	date := utils.Now().In(utils.Location).Format(statsDateLayout)
+	err := r.withSettingLock(settingPrefixDailyStats+date, func() error {
+		stat := &DailyStat{Date: date}
+		if _, err := r.loadSetting(settingPrefixDailyStats+date, stat); err != nil {
+			return err
+		}
+		switch kind {
+		case statSignups:
+			stat.Signups++
+		case statLogins:
+			stat.Logins++
+		}
+		return r.saveSetting(settingPrefixDailyStats+date, stat)
+	})
+	if err != nil {
+		log.Printf("[ERROR] cannot save daily stats [%s]: %s", date, err)
+	}
+}
+
+// dailyStats returns counters of the last n days, oldest first and ending today. Days without activity are included
+// with zero counters.
+func (r *myRegistry) dailyStats(n int) ([]*DailyStat, error) {
+	today := utils.Now().In(utils.Location)
+	result := make([]*DailyStat, n)
+	for i := 0; i < n; i++ {
+		date := today.AddDate(0, 0, i-n+1).Format(statsDateLayout)
+		stat := &DailyStat{Date: date}
+		if _, err := r.loadSetting(settingPrefixDailyStats+date, stat); err != nil {
+			return nil, err
+		}
+		stat.Date = date
+		result[i] = stat
+	}
+	return result, nil
+}
+
+/*----------------------------------------------------------------------*/
+
+// GroupStat is the number of users of a group.
+//
+// available since template-r5
+type GroupStat struct {
+	Id    string `json:"id"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// groupStats returns number of users of each group, sorted by group name.
+func groupStats(c echo.Context) ([]*GroupStat, error) {
+	counts, err := countUsersByGroup(getUserDao(c))
+	if err != nil {
+		return nil, err
+	}
+	groups, err := getRegistry(c).groupDao.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*GroupStat, 0, len(groups))
+	for _, g := range groups {
+		result = append(result, &GroupStat{Id: g.Id, Name: g.Name, Count: counts[g.Id]})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// actionCpStats renders the statistics page, charts are fed by actionCpStatsData.
+//
+// available since template-r5
+func actionCpStats(c echo.Context) error {
+	return c.Render(http.StatusOK, namespace+":layout:cp_stats", map[string]interface{}{
+		"active":      "stats",
+		"defaultDays": defaultStatsDays,
+	})
+}
+
+// actionCpStatsData responds with users per group and daily sign-ups/logins of the last "days" days (query
+// parameter, default 30, at most 365) in JSON format.
+//
+// available since template-r5
+func actionCpStatsData(c echo.Context) error {
+	days, err := strconv.Atoi(c.QueryParam("days"))
+	if err != nil || days <= 0 {
+		days = defaultStatsDays
+	}
+	if days > maxStatsDays {
+		days = maxStatsDays
+	}
+	perGroup, err := groupStats(c)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": err.Error()})
+	}
+	daily, err := getRegistry(c).dailyStats(days)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": err.Error()})
+	}
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"users_per_group": perGroup,
+		"daily":           daily,
+	})
+}
@@ -0,0 +1,27 @@
+package myapp
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestActionCpStatsData(t *testing.T) {
+	h := _newHarness(t)
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	resp := h.Get(h.Reverse(actionNameCpStatsData) + "?days=7")
+	h.AssertStatus(resp, http.StatusOK)
+	var result struct {
+		UsersPerGroup []*GroupStat `json:"users_per_group"`
+		Daily         []*DailyStat `json:"daily"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &result); err != nil {
+		t.Fatalf("TestActionCpStatsData failed: %s", err)
+	}
+	if len(result.UsersPerGroup) != 1 || result.UsersPerGroup[0].Id != systemGroupId || result.UsersPerGroup[0].Count != 1 {
+		t.Fatalf("TestActionCpStatsData failed: unexpected users per group %s", resp.Body.String())
+	}
+	if len(result.Daily) != 7 || result.Daily[6].Logins < 1 {
+		t.Fatalf("TestActionCpStatsData failed: unexpected daily stats %s", resp.Body.String())
+	}
+}
@@ -0,0 +1,95 @@
+package myapp
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// streamFlushSize is the number of bytes written to a streamed response between flushes, see chunkedWriter.
+const streamFlushSize = 32 * 1024
+
+// chunkedWriter writes a response incrementally: the response is flushed to the client (with chunked transfer
+// encoding) every streamFlushSize bytes, so that large documents are neither kept in memory nor delayed until they
+// are complete.
+//
+// available since template-r5
+type chunkedWriter struct {
+	resp    *echo.Response
+	pending int // bytes written since the last flush
+}
+
+func newChunkedWriter(resp *echo.Response) *chunkedWriter {
+	return &chunkedWriter{resp: resp}
+}
+
+// Write implements io.Writer.Write
+func (w *chunkedWriter) Write(p []byte) (int, error) {
+	n, err := w.resp.Write(p)
+	if w.pending += n; err == nil && w.pending >= streamFlushSize {
+		w.Flush()
+	}
+	return n, err
+}
+
+// Flush sends data written so far to the client, if the underlying writer supports flushing.
+func (w *chunkedWriter) Flush() {
+	if f, ok := w.resp.Writer.(http.Flusher); ok {
+		f.Flush()
+	}
+	w.pending = 0
+}
+
+// renderStream is the streaming counterpart of echo.Context.Render: the page is written to the client as templates
+// are executed instead of being rendered into memory first, for pages that list many rows.
+//
+// Once the first bytes are sent the status can not be changed anymore: an error occurring while rendering results in
+// a truncated page, it is returned to be logged. In dev mode pages carrying the debug toolbar are still rendered into
+// memory, see renderWithToolbar.
+//
+// available since template-r5
+func renderStream(c echo.Context, code int, name string, data interface{}) error {
+	if c.Echo().Renderer == nil {
+		return echo.ErrRendererNotRegistered
+	}
+	if !strings.Contains(name, "#") {
+		// flash messages are removed from the session while its cookie can still be updated, see popFlashes
+		popFlashes(c)
+	}
+	resp := c.Response()
+	resp.Header().Set(echo.HeaderContentType, echo.MIMETextHTMLCharsetUTF8)
+	resp.WriteHeader(code)
+	w := newChunkedWriter(resp)
+	if err := c.Echo().Renderer.Render(w, name, data, c); err != nil {
+		return err
+	}
+	w.Flush()
+	return nil
+}
+
+// streamReport writes a tabular report as an attachment, in the supplied format (reportFormatCsv or
+// reportFormatXlsx), rows being sent to the client as write produces them.
+//
+// available since template-r5
+func streamReport(c echo.Context, filename, format string, write func(w reportWriter) error) error {
+	resp := c.Response()
+	resp.Header().Set(echo.HeaderContentType, reportContentTypes[format])
+	resp.Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+	resp.Header().Set(echo.HeaderCacheControl, "no-store")
+	resp.WriteHeader(http.StatusOK)
+
+	out := newChunkedWriter(resp)
+	var w reportWriter
+	var err error
+	if format == reportFormatXlsx {
+		w, err = newXlsxReportWriter(out)
+	} else {
+		w = newCsvReportWriter(out)
+	}
+	if err == nil {
+		err = write(w)
+	}
+	out.Flush()
+	return err
+}
@@ -0,0 +1,29 @@
+package myapp
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestRenderStream(t *testing.T) {
+	testName := "TestRenderStream"
+	h := _newHarness(t)
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	for i := 0; i < 500; i++ {
+		myReg.userDao.Create(fmt.Sprintf("user%03d@local", i), "", strings.Repeat("x", 64), systemGroupId)
+	}
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	resp := h.Get(h.Reverse(actionNameCpUsers))
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertBodyContains(resp, "user499@local")
+	if !resp.Flushed || resp.Header().Get(echo.HeaderContentLength) != "" {
+		t.Fatalf("%s failed: expected the page to be streamed", testName)
+	}
+	if !strings.HasSuffix(strings.TrimSpace(resp.Body.String()), "</html>") {
+		t.Fatalf("%s failed: page is truncated", testName)
+	}
+}
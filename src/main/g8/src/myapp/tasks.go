@@ -0,0 +1,565 @@
+package myapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/btnguyen2k/goyai"
+	"github.com/labstack/echo/v4"
+	"main/src/utils"
+)
+
+const (
+	// settingPrefixTask prefixes ids of settings that store background tasks, e.g. "task:<task-id>".
+	settingPrefixTask = "task:"
+
+	taskStatusRunning   = "running"
+	taskStatusSucceeded = "succeeded"
+	taskStatusFailed    = "failed"
+	taskStatusCanceled  = "canceled"
+	// taskStatusInterrupted is the status of running tasks whose instance stopped reporting, e.g. after a restart.
+	// It is not stored, see Task.CurrentStatus.
+	taskStatusInterrupted = "interrupted"
+
+	// taskSaveInterval throttles how often progress is stored, taskHeartbeat is how often running tasks are stored
+	// even without progress; tasks not stored for taskStaleAfter are considered interrupted.
+	taskSaveInterval = time.Second
+	taskHeartbeat    = 30 * time.Second
+	taskStaleAfter   = 3 * taskHeartbeat
+
+	// taskResultDir is the directory of the file store results of tasks are stored in.
+	taskResultDir = "tasks"
+)
+
+var errTaskCanceled = errors.New("task canceled")
+
+// Task is an operation running in background (e.g. a purge or a report export) with its progress. Tasks are stored as
+// settings, so that their progress can be followed from any instance at /cp/tasks.
+//
+// available since template-r5
+type Task struct {
+	Id              string    `json:"id"`
+	Kind            string    `json:"kind"`
+	Owner           string    `json:"owner"`
+	Status          string    `json:"status"`
+	Done            int       `json:"done"`
+	Total           int       `json:"total"` // 0 if unknown
+	Message         string    `json:"message,omitempty"`
+	ResultName      string    `json:"result,omitempty"` // file name of the result, empty if none
+	CancelRequested bool      `json:"cancel,omitempty"`
+	Created         time.Time `json:"created"`
+	Updated         time.Time `json:"updated"`
+}
+
+// CurrentStatus returns the status of the task, running tasks that have not been updated for a while are reported as
+// interrupted.
+func (t *Task) CurrentStatus() string {
+	if t.Status == taskStatusRunning && utils.Now().Sub(t.Updated) > taskStaleAfter {
+		return taskStatusInterrupted
+	}
+	return t.Status
+}
+
+// IsRunning checks if the task is still running.
+func (t *Task) IsRunning() bool {
+	return t.CurrentStatus() == taskStatusRunning
+}
+
+// Percent returns the progress of the task, -1 if unknown.
+func (t *Task) Percent() int {
+	switch {
+	case t.Status == taskStatusSucceeded:
+		return 100
+	case t.Total <= 0:
+		return -1
+	case t.Done >= t.Total:
+		return 100
+	}
+	return t.Done * 100 / t.Total
+}
+
+/*----------------------------------------------------------------------*/
+
+// taskKind is a kind of operation that can be started from /cp/tasks.
+type taskKind struct {
+	name       string
+	i18nKey    string
+	permission string
+	// available checks if the operation can run with the current configuration, nil if always available
+	available func(r *myRegistry) bool
+	run       func(tc *taskContext) error
+}
+
+// taskKinds lists operations that can be started from /cp/tasks.
+var taskKinds = []*taskKind{
+	{name: "purge", i18nKey: "task_kind_purge", permission: permSettingsManage, run: runPurgeTask},
+	{name: "reencrypt", i18nKey: "task_kind_reencrypt", permission: permSettingsManage, run: runReencryptTask,
+		available: func(r *myRegistry) bool { return r.FieldCipher != nil }},
+	{name: "membership_report", i18nKey: "task_kind_membership_report", permission: permGroupReport, run: runMembershipReportTask},
+}
+
+func findTaskKind(name string) *taskKind {
+	for _, k := range taskKinds {
+		if k.name == name {
+			return k
+		}
+	}
+	return nil
+}
+
+// taskContext is handed to running tasks to report progress, write results and detect cancellation.
+type taskContext struct {
+	context.Context
+	r      *myRegistry
+	task   *Task
+	locale string     // locale of the user who started the task
+	params url.Values // parameters of the form the task was started with
+	lock   sync.Mutex
+	saved  time.Time
+	cancel context.CancelFunc
+}
+
+// Progress reports the progress of the task (total is 0 if unknown), it returns errTaskCanceled if the task has been
+// canceled: tasks must stop as soon as possible.
+func (tc *taskContext) Progress(done, total int) error {
+	tc.lock.Lock()
+	tc.task.Done, tc.task.Total = done, total
+	due := time.Since(tc.saved) >= taskSaveInterval
+	tc.lock.Unlock()
+	if due {
+		tc.save()
+	}
+	if tc.Err() != nil {
+		return errTaskCanceled
+	}
+	return nil
+}
+
+// SetMessage sets the message shown next to the task's status (e.g. a summary of its result), localized in the locale
+// of the user who started the task. args are pairs of keys and values of the message's template data.
+func (tc *taskContext) SetMessage(i18nKey string, args ...interface{}) {
+	msg := tc.r.i18n.Localize(tc.locale, i18nKey, &goyai.LocalizeConfig{TemplateData: templateDataOf(args...)})
+	tc.lock.Lock()
+	tc.task.Message = msg
+	tc.lock.Unlock()
+}
+
+// WriteResult stores the result of the task, which users can download from /cp/tasks.
+func (tc *taskContext) WriteResult(name string, write func(w io.Writer) error) error {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(write(pw))
+	}()
+	if err := tc.r.FileStore.Put(taskResultDir+"/"+tc.task.Id+"/"+name, pr); err != nil {
+		pr.CloseWithError(err)
+		return err
+	}
+	tc.lock.Lock()
+	tc.task.ResultName = name
+	tc.lock.Unlock()
+	return nil
+}
+
+// save stores the task. Cancellation requested from another instance is detected here.
+func (tc *taskContext) save() {
+	id := tc.task.Id
+	err := tc.r.withSettingLock(settingPrefixTask+id, func() error {
+		tc.lock.Lock()
+		defer tc.lock.Unlock()
+		stored := &Task{}
+		if found, err := tc.r.loadSetting(settingPrefixTask+id, stored); err == nil && found && stored.CancelRequested {
+			tc.task.CancelRequested = true
+			tc.cancel()
+		}
+		tc.task.Updated = utils.Now()
+		tc.saved = time.Now()
+		return tc.r.saveSetting(settingPrefixTask+id, tc.task)
+	})
+	if err != nil {
+		log.Printf("[ERROR] cannot save task [%s]: %s", id, err)
+	}
+}
+
+// taskRunner runs tasks of this instance in goroutines.
+type taskRunner struct {
+	lock    sync.Mutex
+	cancels map[string]context.CancelFunc // cancel functions of running tasks, by task id
+}
+
+func newTaskRunner() *taskRunner {
+	return &taskRunner{cancels: make(map[string]context.CancelFunc)}
+}
+
+// startTask starts a task in background and returns it.
+func (r *myRegistry) startTask(kind *taskKind, owner, locale string, params url.Values) (*Task, error) {
+	now := utils.Now()
+	task := &Task{Id: utils.UniqueId(), Kind: kind.name, Owner: owner, Status: taskStatusRunning, Created: now, Updated: now}
+	if err := r.saveSetting(settingPrefixTask+task.Id, task); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	tc := &taskContext{Context: ctx, r: r, task: task, locale: locale, params: params, saved: now, cancel: cancel}
+	r.tasks.lock.Lock()
+	r.tasks.cancels[task.Id] = cancel
+	r.tasks.lock.Unlock()
+	go func() {
+		defer func() {
+			r.tasks.lock.Lock()
+			delete(r.tasks.cancels, task.Id)
+			r.tasks.lock.Unlock()
+			cancel()
+		}()
+		heartbeat := time.NewTicker(taskHeartbeat)
+		defer heartbeat.Stop()
+		go func() {
+			for {
+				select {
+				case <-heartbeat.C:
+					tc.save()
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		err := r.runTask(kind, tc)
+		tc.lock.Lock()
+		switch {
+		case err == nil:
+			task.Status = taskStatusSucceeded
+		case errors.Is(err, errTaskCanceled) || ctx.Err() != nil:
+			task.Status = taskStatusCanceled
+		default:
+			task.Status, task.Message = taskStatusFailed, err.Error()
+		}
+		tc.lock.Unlock()
+		tc.save()
+		log.Printf("Task [%s] (%s) of user [%s] %s", task.Id, task.Kind, task.Owner, task.Status)
+	}()
+	return task, nil
+}
+
+// runTask runs a task, panics are reported as failures.
+func (r *myRegistry) runTask(kind *taskKind, tc *taskContext) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return kind.run(tc)
+}
+
+// cancelTask cancels a running task: immediately if it runs on this instance, at its next progress report otherwise.
+func (r *myRegistry) cancelTask(task *Task) error {
+	r.tasks.lock.Lock()
+	cancel := r.tasks.cancels[task.Id]
+	r.tasks.lock.Unlock()
+	if cancel != nil {
+		cancel()
+		return nil
+	}
+	return r.withSettingLock(settingPrefixTask+task.Id, func() error {
+		task.CancelRequested = true
+		return r.saveSetting(settingPrefixTask+task.Id, task)
+	})
+}
+
+// getTask returns a stored task, nil if not found.
+func (r *myRegistry) getTask(id string) (*Task, error) {
+	task := &Task{}
+	if found, err := r.loadSetting(settingPrefixTask+id, task); err != nil || !found {
+		return nil, err
+	}
+	return task, nil
+}
+
+// listTasks returns stored tasks, most recent first.
+func (r *myRegistry) listTasks() ([]*Task, error) {
+	list, err := r.settingsWithPrefix(settingPrefixTask)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*Task, 0, len(list))
+	for _, s := range list {
+		task := &Task{}
+		if _, err := r.loadSetting(s.Id, task); err != nil {
+			return nil, err
+		}
+		result = append(result, task)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Created.After(result[j].Created) })
+	return result, nil
+}
+
+// countTasks counts stored tasks.
+func countTasks(r *myRegistry) (int, error) {
+	list, err := r.settingsWithPrefix(settingPrefixTask)
+	return len(list), err
+}
+
+// purgeTasks deletes tasks that were created before cutoff and are not running, as well as their results.
+func purgeTasks(r *myRegistry, cutoff time.Time) (int, error) {
+	tasks, err := r.listTasks()
+	if err != nil {
+		return 0, err
+	}
+	numDeleted := 0
+	for _, task := range tasks {
+		if task.IsRunning() || !task.Created.Before(cutoff) {
+			continue
+		}
+		if task.ResultName != "" {
+			if err := r.FileStore.Delete(taskResultDir + "/" + task.Id + "/" + task.ResultName); err != nil {
+				return numDeleted, err
+			}
+		}
+		if _, err := r.settingDao.Delete(&Setting{Id: settingPrefixTask + task.Id}); err != nil {
+			return numDeleted, err
+		}
+		numDeleted++
+	}
+	return numDeleted, nil
+}
+
+/*----------------------------------------------------------------------*/
+
+// runPurgeTask runs the retention policies of all log types, see myRegistry.purge.
+func runPurgeTask(tc *taskContext) error {
+	if err := tc.Progress(0, 1); err != nil {
+		return err
+	}
+	result := tc.r.purge()
+	if result.Err != nil {
+		return result.Err
+	}
+	total := 0
+	for _, n := range result.Deleted {
+		total += n
+	}
+	tc.SetMessage("task_msg_purged", "count", total)
+	return tc.Progress(1, 1)
+}
+
+// runReencryptTask re-encrypts users' names and groups' custom fields with the current key, see reencryptUsers and
+// reencryptGroups.
+func runReencryptTask(tc *taskContext) error {
+	dao, groupDao := backendUserDao(tc.r.userDao), backendGroupDao(tc.r.groupDao)
+	if dao == nil || groupDao == nil || tc.r.FieldCipher == nil {
+		return errors.New("field encryption is disabled")
+	}
+	count, err := reencryptUsersWithProgress(dao, tc.r.FieldCipher, tc.Progress)
+	groupCount := 0
+	if err == nil {
+		groupCount, err = reencryptGroups(groupDao, tc.r.FieldCipher)
+	}
+	tc.SetMessage("task_msg_reencrypted", "count", count, "groups", groupCount)
+	return err
+}
+
+// runMembershipReportTask exports the group membership report, see writeMembershipReport.
+func runMembershipReportTask(tc *taskContext) error {
+	format := reportFormatCsv
+	if tc.params.Get("format") == reportFormatXlsx {
+		format = reportFormatXlsx
+	}
+	name := fmt.Sprintf("group-membership-%s.%s", utils.Now().In(utils.Location).Format("20060102-150405"), format)
+	return tc.WriteResult(name, func(out io.Writer) error {
+		var w reportWriter
+		if format == reportFormatXlsx {
+			var err error
+			if w, err = newXlsxReportWriter(out); err != nil {
+				return err
+			}
+		} else {
+			w = newCsvReportWriter(out)
+		}
+		return tc.r.writeMembershipReport(tc.locale, w, &MembershipReportFilter{}, tc.Progress)
+	})
+}
+
+/*----------------------------------------------------------------------*/
+
+// TaskModel represents a task in views.
+//
+// available since template-r5
+type TaskModel struct {
+	c echo.Context
+	*Task
+}
+
+// I18nKey returns the i18n key of the task's kind.
+func (m *TaskModel) I18nKey() string {
+	if kind := findTaskKind(m.Kind); kind != nil {
+		return kind.i18nKey
+	}
+	return m.Kind
+}
+
+// CanCancel checks if the viewer can cancel the task.
+func (m *TaskModel) CanCancel() bool {
+	return m.IsRunning() && !m.CancelRequested
+}
+
+// UrlResult returns the URL to download the task's result, empty if the task has no result.
+func (m *TaskModel) UrlResult() string {
+	if m.ResultName == "" || m.Status != taskStatusSucceeded {
+		return ""
+	}
+	return m.c.Echo().Reverse(actionNameCpTaskResult) + "?id=" + url.QueryEscape(m.Id)
+}
+
+// CreatedStr returns the creation time of the task, in the application's timezone.
+func (m *TaskModel) CreatedStr() string {
+	return m.Created.In(utils.Location).Format("2006-01-02 15:04:05")
+}
+
+// canViewTask checks if the current user can view (and cancel) a task: tasks are visible to their owner and to users
+// allowed to manage settings.
+func canViewTask(c echo.Context, task *Task) bool {
+	viewer, _ := c.Get(ctxCurrentUser).(*User)
+	return viewer != nil && (viewer.Username == task.Owner || can(c, permSettingsManage))
+}
+
+// availableTaskKinds returns kinds of tasks the current user can start.
+func availableTaskKinds(c echo.Context) []*taskKind {
+	myReg := getRegistry(c)
+	result := make([]*taskKind, 0, len(taskKinds))
+	for _, k := range taskKinds {
+		if can(c, k.permission) && (k.available == nil || k.available(myReg)) {
+			result = append(result, k)
+		}
+	}
+	return result
+}
+
+// TaskKindModel is a kind of task the current user can start.
+//
+// available since template-r5
+type TaskKindModel struct {
+	Name    string
+	I18nKey string
+}
+
+func fragmentTasks(c echo.Context) map[string]interface{} {
+	tasks, err := getRegistry(c).listTasks()
+	result := make([]*TaskModel, 0, len(tasks))
+	for _, t := range tasks {
+		if canViewTask(c, t) {
+			result = append(result, &TaskModel{c: c, Task: t})
+		}
+	}
+	data := map[string]interface{}{"tasks": result}
+	if err != nil {
+		data["error"] = err.Error()
+	}
+	return data
+}
+
+// actionCpTasks lists background tasks the current user can view and the ones they can start. The list is refreshed
+// periodically (fragment tasks_table).
+//
+// available since template-r5
+func actionCpTasks(c echo.Context) error {
+	kinds := make([]*TaskKindModel, 0)
+	for _, k := range availableTaskKinds(c) {
+		kinds = append(kinds, &TaskKindModel{Name: k.name, I18nKey: k.i18nKey})
+	}
+	data := fragmentTasks(c)
+	data["active"] = "tasks"
+	data["kinds"] = kinds
+	return c.Render(http.StatusOK, namespace+":layout:cp_tasks:cp_fragments", data)
+}
+
+// actionCpStartTaskSubmit starts a task of the kind submitted in form field "kind".
+//
+// available since template-r5
+func actionCpStartTaskSubmit(c echo.Context) error {
+	redirectUrl := c.Echo().Reverse(actionNameCpTasks) + "?r=" + utils.RandomString(4)
+	var kind *taskKind
+	for _, k := range availableTaskKinds(c) {
+		if k.name == c.FormValue("kind") {
+			kind = k
+		}
+	}
+	if kind == nil {
+		AddFlash(c, FlashWarning, "error_no_permission")
+		return c.Redirect(http.StatusFound, redirectUrl)
+	}
+	currentUser, _ := c.Get(ctxCurrentUser).(*User)
+	params, _ := c.FormParams()
+	task, err := getRegistry(c).startTask(kind, currentUser.Username, getContextString(c, ctxLocale), params)
+	if err != nil {
+		AddFlash(c, FlashError, "error_db_001", "err", err.Error())
+	} else {
+		AddFlash(c, FlashInfo, "task_started", "id", task.Id)
+	}
+	return c.Redirect(http.StatusFound, redirectUrl)
+}
+
+// checkCpTask returns the task of query parameter "id" if the current user can view it.
+func checkCpTask(c echo.Context) (*Task, error) {
+	task, err := getRegistry(c).getTask(c.QueryParam("id"))
+	if err != nil {
+		return nil, err
+	}
+	if task == nil || !canViewTask(c, task) {
+		return nil, echo.ErrNotFound
+	}
+	return task, nil
+}
+
+// actionCpCancelTaskSubmit cancels the task of query parameter "id".
+//
+// available since template-r5
+func actionCpCancelTaskSubmit(c echo.Context) error {
+	task, err := checkCpTask(c)
+	if err != nil {
+		return err
+	}
+	if task.IsRunning() {
+		if err := getRegistry(c).cancelTask(task); err != nil {
+			AddFlash(c, FlashError, "error_db_001", "err", err.Error())
+		} else {
+			AddFlash(c, FlashInfo, "task_cancel_requested", "id", task.Id)
+		}
+	}
+	return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpTasks)+"?r="+utils.RandomString(4))
+}
+
+// actionCpTaskResult downloads the result of the task of query parameter "id".
+//
+// available since template-r5
+func actionCpTaskResult(c echo.Context) error {
+	task, err := checkCpTask(c)
+	if err != nil {
+		return err
+	}
+	if task.ResultName == "" || task.Status != taskStatusSucceeded {
+		return echo.ErrNotFound
+	}
+	f, err := getRegistry(c).FileStore.Open(taskResultDir + "/" + task.Id + "/" + task.ResultName)
+	if os.IsNotExist(err) {
+		return echo.ErrNotFound
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+	resp := c.Response()
+	resp.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, strings.ReplaceAll(task.ResultName, `"`, "")))
+	resp.Header().Set(echo.HeaderCacheControl, "no-store")
+	contentType := reportContentTypes[strings.TrimPrefix(path.Ext(task.ResultName), ".")]
+	if contentType == "" {
+		contentType = echo.MIMEOctetStream
+	}
+	return c.Stream(http.StatusOK, contentType, f)
+}
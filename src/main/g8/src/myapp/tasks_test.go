@@ -0,0 +1,71 @@
+package myapp
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"main/src/apptest"
+)
+
+func TestTasks(t *testing.T) {
+	testName := "TestTasks"
+	conf := apptest.SqliteInMemoryConfig + "\ngoadmin.file_store_dir = \"" + t.TempDir() + "\"\n"
+	h := apptest.New(t, conf, NewBootstrapper(nil, nil))
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpTasks)), http.StatusOK)
+	h.AssertTemplate(namespace + ":layout:cp_tasks:cp_fragments")
+
+	resp := h.PostForm(h.Reverse(actionNameCpStartTaskSubmit), url.Values{"kind": {"membership_report"}, "format": {reportFormatCsv}})
+	h.AssertRedirect(resp, h.Reverse(actionNameCpTasks))
+	var task *Task
+	for i := 0; i < 100; i++ {
+		tasks, err := myReg.listTasks()
+		if err != nil || len(tasks) != 1 {
+			t.Fatalf("%s failed: unexpected tasks %#v / %s", testName, tasks, err)
+		}
+		if task = tasks[0]; !task.IsRunning() {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if task.Status != taskStatusSucceeded || task.Owner != testAdminUsername || task.Done != 1 || task.Total != 1 {
+		t.Fatalf("%s failed: unexpected task %#v", testName, task)
+	}
+	resp = h.Get(h.Reverse(actionNameCpTaskResult) + "?id=" + task.Id)
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertBodyContains(resp, systemGroupId+",System User Group,"+testAdminUsername+",")
+	// tasks that are not available (field encryption is disabled) cannot be started
+	h.PostForm(h.Reverse(actionNameCpStartTaskSubmit), url.Values{"kind": {"reencrypt"}})
+	if tasks, _ := myReg.listTasks(); len(tasks) != 1 {
+		t.Fatalf("%s failed: unexpected tasks %#v", testName, tasks)
+	}
+
+	// cancellation stops tasks at their next progress report
+	started := make(chan bool)
+	kind := &taskKind{name: "test", run: func(tc *taskContext) error {
+		close(started)
+		<-tc.Done()
+		return tc.Progress(1, 2)
+	}}
+	running, _ := myReg.startTask(kind, testAdminUsername, "en", nil)
+	<-started
+	h.AssertStatus(h.PostForm(h.Reverse(actionNameCpCancelTaskSubmit)+"?id="+running.Id, url.Values{}), http.StatusFound)
+	for i := 0; i < 100; i++ {
+		if running, _ = myReg.getTask(running.Id); !running.IsRunning() {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if running.Status != taskStatusCanceled {
+		t.Fatalf("%s failed: unexpected task %#v", testName, running)
+	}
+
+	// finished tasks are purged with their results
+	if n, err := purgeTasks(myReg, time.Now().Add(time.Minute)); err != nil || n != 2 {
+		t.Fatalf("%s failed: %d/%s", testName, n, err)
+	}
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpTaskResult)+"?id="+task.Id), http.StatusNotFound)
+}
@@ -0,0 +1,301 @@
+package myapp
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/btnguyen2k/goyai"
+	"github.com/labstack/echo/v4"
+	"main/src/utils"
+)
+
+const (
+	// settingIdTerms is the id of the setting that stores the current terms of service.
+	settingIdTerms = "terms"
+	// settingPrefixTermsAcceptance prefixes ids of settings that store acceptances of the terms of service, per user.
+	settingPrefixTermsAcceptance = "terms_acceptance:"
+
+	maxTermsAcceptances = 20 // max number of acceptances kept per user
+	maxTermsVersionLen  = 32
+
+	// sessionTermsAccepted caches "<username>:<version>" of the terms the signed-in user accepted, so that acceptances
+	// are not looked up on every request.
+	sessionTermsAccepted = "terms_accepted"
+)
+
+// TermsSettings is the terms of service users must accept before entering the control panel, managed at
+// /cp/settings/terms. Users are asked again whenever the version changes; no terms are enforced while the version is
+// empty.
+//
+// available since template-r5
+type TermsSettings struct {
+	Version string    `json:"version"` // e.g. "2024-01", empty to not enforce the terms
+	Text    string    `json:"text"`    // Markdown
+	By      string    `json:"by"`
+	Updated time.Time `json:"updated"`
+}
+
+// validate normalizes the settings.
+func (s *TermsSettings) validate() {
+	s.Version = strings.TrimSpace(s.Version)
+	if len(s.Version) > maxTermsVersionLen {
+		s.Version = s.Version[:maxTermsVersionLen]
+	}
+	s.Text = strings.TrimSpace(s.Text)
+}
+
+// UpdatedStr returns the time the terms were last updated, in the application's timezone.
+func (s *TermsSettings) UpdatedStr() string {
+	return s.Updated.In(utils.Location).Format("2006-01-02 15:04:05")
+}
+
+// TermsAcceptance records that a user accepted a version of the terms of service.
+//
+// available since template-r5
+type TermsAcceptance struct {
+	Version  string    `json:"version"`
+	Time     time.Time `json:"time"`
+	ClientIp string    `json:"client_ip"`
+}
+
+// termsSettings returns the current terms of service, empty if none.
+func (r *myRegistry) termsSettings() (*TermsSettings, error) {
+	settings := &TermsSettings{}
+	if _, err := r.loadSetting(settingIdTerms, settings); err != nil {
+		return &TermsSettings{}, err
+	}
+	settings.validate()
+	return settings, nil
+}
+
+// termsAcceptances returns acceptances of the terms of service by the user, latest first.
+func (r *myRegistry) termsAcceptances(username string) ([]*TermsAcceptance, error) {
+	list := make([]*TermsAcceptance, 0)
+	_, err := r.loadSetting(settingPrefixTermsAcceptance+username, &list)
+	return list, err
+}
+
+// hasAcceptedTerms checks if the user accepted the version of the terms of service.
+func (r *myRegistry) hasAcceptedTerms(username, version string) (bool, error) {
+	list, err := r.termsAcceptances(username)
+	if err != nil {
+		return false, err
+	}
+	for _, a := range list {
+		if a.Version == version {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// acceptTerms records that the user accepted the version of the terms of service, dropping the oldest records beyond
+// maxTermsAcceptances.
+func (r *myRegistry) acceptTerms(username string, acceptance *TermsAcceptance) error {
+	return r.withSettingLock(settingPrefixTermsAcceptance+username, func() error {
+		list, err := r.termsAcceptances(username)
+		if err != nil {
+			return err
+		}
+		list = append([]*TermsAcceptance{acceptance}, list...)
+		if len(list) > maxTermsAcceptances {
+			list = list[:maxTermsAcceptances]
+		}
+		return r.saveSetting(settingPrefixTermsAcceptance+username, list)
+	})
+}
+
+// termsExemptRoutes are control panel routes available to users who have not accepted the current terms of service.
+var termsExemptRoutes = []string{actionNameCpTerms, actionNameCpTermsSubmit, actionNameCpLogout}
+
+// checkTerms returns false if the user must accept the current terms of service before entering the control panel.
+// Terms are not enforced in read-only mode as acceptances could not be recorded.
+func (r *myRegistry) checkTerms(c echo.Context, user *User) bool {
+	for _, name := range termsExemptRoutes {
+		if c.Path() == c.Echo().Reverse(name) {
+			return true
+		}
+	}
+	if r.isReadOnly() {
+		return true
+	}
+	settings, err := r.termsSettings()
+	if err != nil {
+		log.Printf("[ERROR] cannot load setting [%s]: %s", settingIdTerms, err)
+		return true
+	}
+	if settings.Version == "" {
+		return true
+	}
+	accepted := user.Username + ":" + settings.Version
+	if v, _ := getSession(c).Values[sessionTermsAccepted].(string); v == accepted {
+		return true
+	}
+	ok, err := r.hasAcceptedTerms(user.Username, settings.Version)
+	if err != nil {
+		log.Printf("[ERROR] cannot load acceptances of terms of user [%s]: %s", user.Username, err)
+		return true
+	}
+	if ok {
+		setSessionValue(c, sessionTermsAccepted, accepted)
+	}
+	return ok
+}
+
+// actionCpTerms asks the user to accept the current terms of service.
+//
+// available since template-r5
+func actionCpTerms(c echo.Context) error {
+	settings, err := getRegistry(c).termsSettings()
+	if err != nil || settings.Version == "" {
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpDashboard))
+	}
+	return c.Render(http.StatusOK, namespace+":cp_terms", map[string]interface{}{
+		"terms": settings,
+	})
+}
+
+// actionCpTermsSubmit records the acceptance of the current terms of service (form fields "version", which must be
+// the current one, and "accept").
+//
+// available since template-r5
+func actionCpTermsSubmit(c echo.Context) error {
+	var errMsg string
+	myReg := getRegistry(c)
+	currentUser, _ := c.Get(ctxCurrentUser).(*User)
+	settings, err := myReg.termsSettings()
+	if err != nil {
+		errMsg = getI18n(c).Localize(getContextString(c, ctxLocale), "error_db_001", &goyai.LocalizeConfig{
+			TemplateData: map[string]interface{}{"err": settingIdTerms + "/" + err.Error()},
+		})
+		goto end
+	}
+	if currentUser == nil || settings.Version == "" {
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpDashboard))
+	}
+	if c.FormValue("version") != settings.Version {
+		// terms changed while the user was reading them
+		errMsg = getI18n(c).Localize(getContextString(c, ctxLocale), "error_terms_changed")
+		goto end
+	}
+	if c.FormValue("accept") != "1" {
+		errMsg = getI18n(c).Localize(getContextString(c, ctxLocale), "error_terms_not_accepted")
+		goto end
+	}
+	if err := myReg.acceptTerms(currentUser.Username, &TermsAcceptance{Version: settings.Version, Time: utils.Now(), ClientIp: c.RealIP()}); err != nil {
+		errMsg = getI18n(c).Localize(getContextString(c, ctxLocale), "error_db_001", &goyai.LocalizeConfig{
+			TemplateData: map[string]interface{}{"err": settingPrefixTermsAcceptance + currentUser.Username + "/" + err.Error()},
+		})
+		goto end
+	}
+	myReg.auditf("user [%s] accepted terms of service [%s] from %s", currentUser.Username, settings.Version, clientOrigin(c))
+	setSessionValue(c, sessionTermsAccepted, currentUser.Username+":"+settings.Version)
+	return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpDashboard))
+end:
+	return c.Render(http.StatusOK, namespace+":cp_terms", map[string]interface{}{
+		"terms": settings,
+		"error": errMsg,
+	})
+}
+
+/*----------------------------------------------------------------------*/
+
+// termsAcceptanceModel represents, in views, the latest acceptance of the terms of service by a user.
+type termsAcceptanceModel struct {
+	Username string
+	Name     string
+	Latest   *TermsAcceptance // nil if the user never accepted the terms
+	Current  bool             // the user accepted the current version
+}
+
+// TimeStr returns the time the terms were accepted, in the application's timezone.
+func (m *termsAcceptanceModel) TimeStr() string {
+	if m.Latest == nil {
+		return ""
+	}
+	return m.Latest.Time.In(utils.Location).Format("2006-01-02 15:04:05")
+}
+
+// termsAcceptanceRows lists the latest acceptance of each user, users who have not accepted the current version
+// first.
+func (r *myRegistry) termsAcceptanceRows(version string) ([]*termsAcceptanceModel, error) {
+	users, err := r.userDao.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]*termsAcceptanceModel, 0, len(users))
+	for _, user := range users {
+		list, err := r.termsAcceptances(user.Username)
+		if err != nil {
+			return nil, err
+		}
+		row := &termsAcceptanceModel{Username: user.Username, Name: user.Name}
+		if len(list) > 0 {
+			row.Latest = list[0]
+			row.Current = version != "" && list[0].Version == version
+		}
+		rows = append(rows, row)
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return !rows[i].Current && rows[j].Current })
+	return rows, nil
+}
+
+// actionCpTermsSettings shows the current terms of service and which users accepted them.
+//
+// available since template-r5
+func actionCpTermsSettings(c echo.Context) error {
+	if err := checkCpManageSecurity(c); err != nil {
+		AddFlashText(c, FlashWarning, err.Error())
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpDashboard))
+	}
+	var errMsg string
+	myReg := getRegistry(c)
+	settings, err := myReg.termsSettings()
+	if err != nil {
+		errMsg = getI18n(c).Localize(getContextString(c, ctxLocale), "error_db_001", &goyai.LocalizeConfig{
+			TemplateData: map[string]interface{}{"err": settingIdTerms + "/" + err.Error()},
+		})
+	}
+	rows, err := myReg.termsAcceptanceRows(settings.Version)
+	if err != nil && errMsg == "" {
+		errMsg = getI18n(c).Localize(getContextString(c, ctxLocale), "error_db_001", &goyai.LocalizeConfig{
+			TemplateData: map[string]interface{}{"err": settingPrefixTermsAcceptance + "/" + err.Error()},
+		})
+	}
+	return c.Render(http.StatusOK, namespace+":layout:cp_terms_settings", map[string]interface{}{
+		"active":   "terms",
+		"settings": settings,
+		"rows":     rows,
+		"error":    errMsg,
+	})
+}
+
+// actionCpTermsSettingsSubmit saves the terms of service (form fields "version" and "text").
+//
+// available since template-r5
+func actionCpTermsSettingsSubmit(c echo.Context) error {
+	if err := checkCpManageSecurity(c); err != nil {
+		AddFlashText(c, FlashWarning, err.Error())
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpDashboard))
+	}
+	settings := &TermsSettings{Version: c.FormValue("version"), Text: c.FormValue("text"), Updated: utils.Now()}
+	settings.validate()
+	if settings.Version != "" && settings.Text == "" {
+		AddFlash(c, FlashError, "error_terms_text_empty")
+		return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpTermsSettings)+"?r="+utils.RandomString(4))
+	}
+	myReg := getRegistry(c)
+	if currentUser, _ := getCurrentUser(c); currentUser != nil {
+		settings.By = currentUser.Username
+	}
+	if err := myReg.saveSetting(settingIdTerms, settings); err != nil {
+		AddFlash(c, FlashError, "error_db_001", "err", settingIdTerms+"/"+err.Error())
+	} else {
+		myReg.auditf("user [%s] updated terms of service to version [%s]", settings.By, settings.Version)
+		AddFlash(c, FlashInfo, "update_terms_successful")
+	}
+	return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpTermsSettings)+"?r="+utils.RandomString(4))
+}
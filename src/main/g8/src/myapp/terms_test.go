@@ -0,0 +1,47 @@
+package myapp
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"main/src/apptest"
+)
+
+func TestTermsOfService(t *testing.T) {
+	testName := "TestTermsOfService"
+	h := apptest.New(t, apptest.SqliteInMemoryConfig, NewBootstrapper(nil, nil))
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpDashboard)), http.StatusOK)
+
+	form := url.Values{"version": {"v1"}, "text": {"Use **responsibly**"}}
+	h.AssertRedirect(h.PostForm(h.Reverse(actionNameCpTermsSettingsSubmit), form), h.Reverse(actionNameCpTermsSettings))
+	h.AssertRedirect(h.Get(h.Reverse(actionNameCpDashboard)), h.Reverse(actionNameCpTerms))
+	resp := h.Get(h.Reverse(actionNameCpTerms))
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertBodyContains(resp, "<strong>responsibly</strong>")
+
+	// the checkbox must be ticked
+	resp = h.PostForm(h.Reverse(actionNameCpTermsSubmit), url.Values{"version": {"v1"}})
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertBodyContains(resp, "Please accept the terms of service to continue")
+	h.AssertRedirect(h.PostForm(h.Reverse(actionNameCpTermsSubmit), url.Values{"version": {"v1"}, "accept": {"1"}}), h.Reverse(actionNameCpDashboard))
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpDashboard)), http.StatusOK)
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	if ok, err := myReg.hasAcceptedTerms(testAdminUsername, "v1"); err != nil || !ok {
+		t.Fatalf("%s failed: expected acceptance of [v1] to be recorded: %v / %v", testName, ok, err)
+	}
+	resp = h.Get(h.Reverse(actionNameCpTermsSettings))
+	h.AssertStatus(resp, http.StatusOK)
+	h.AssertBodyContains(resp, testAdminUsername)
+
+	// acceptance is remembered across sessions, until the version changes
+	h.PostForm(h.Reverse(actionNameCpLogout), url.Values{})
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpDashboard)), http.StatusOK)
+	form.Set("version", "v2")
+	h.AssertRedirect(h.PostForm(h.Reverse(actionNameCpTermsSettingsSubmit), form), h.Reverse(actionNameCpTermsSettings))
+	h.AssertRedirect(h.Get(h.Reverse(actionNameCpDashboard)), h.Reverse(actionNameCpTerms))
+	resp = h.PostForm(h.Reverse(actionNameCpTermsSubmit), url.Values{"version": {"v1"}, "accept": {"1"}})
+	h.AssertBodyContains(resp, "Terms of service have changed")
+}
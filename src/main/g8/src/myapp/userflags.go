@@ -0,0 +1,73 @@
+package myapp
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/labstack/echo/v4"
+)
+
+// userFlagModel represents a user flag in the form editing a user account.
+type userFlagModel struct {
+	Name    string
+	I18nKey string
+	Checked bool
+}
+
+// userFlagModels lists the supported user flags, checked if set in flags (comma-separated).
+func userFlagModels(flags string) []*userFlagModel {
+	u := &User{Flags: flags}
+	result := make([]*userFlagModel, 0, len(userFlags))
+	for _, f := range userFlags {
+		result = append(result, &userFlagModel{Name: f, I18nKey: "user_flag_" + f, Checked: u.HasFlag(f)})
+	}
+	return result
+}
+
+// userFlagsFromForm returns the user flags ticked in the form (field "flags"), comma-separated.
+func userFlagsFromForm(formData url.Values) string {
+	u := &User{}
+	for _, f := range formData["flags"] {
+		u.SetFlag(f, true)
+	}
+	return u.Flags
+}
+
+// mustChangePasswordExemptRoutes are control panel routes available to users who must change their password.
+var mustChangePasswordExemptRoutes = []string{actionNameCpProfile, actionNameCpChangePasswordSubmit, actionNameCpLogout}
+
+// userReadOnlyExemptRoutes are state-changing control panel routes available to read-only users: signing out and
+// securing their own account.
+var userReadOnlyExemptRoutes = []string{actionNameCpLogout, actionNameCpVerifyLoginSubmit, actionNameCpTermsSubmit, actionNameCpChangePasswordSubmit}
+
+// isRouteOneOf checks if the current route is one of the named routes.
+func isRouteOneOf(c echo.Context, names []string) bool {
+	for _, name := range names {
+		if c.Path() == c.Echo().Reverse(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// middlewareUserFlags enforces the flags admins set on the current user's account: users who must change their
+// password are sent to their profile page until they do, read-only users can not submit changes.
+//
+// available since template-r5
+func middlewareUserFlags(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		currentUser, _ := c.Get(ctxCurrentUser).(*User)
+		if currentUser == nil {
+			return next(c)
+		}
+		if currentUser.HasFlag(userFlagMustChangePassword) && !isRouteOneOf(c, mustChangePasswordExemptRoutes) {
+			AddFlash(c, FlashWarning, "must_change_password")
+			return c.Redirect(http.StatusFound, c.Echo().Reverse(actionNameCpProfile))
+		}
+		method := c.Request().Method
+		if currentUser.HasFlag(userFlagReadOnly) && method != http.MethodGet && method != http.MethodHead && method != http.MethodOptions && !isRouteOneOf(c, userReadOnlyExemptRoutes) {
+			return rejectStateChange(c, "error_user_read_only")
+		}
+		return next(c)
+	}
+}
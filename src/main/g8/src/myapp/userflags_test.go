@@ -0,0 +1,55 @@
+package myapp
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestUserFlags(t *testing.T) {
+	testName := "TestUserFlags"
+	u := &User{}
+	u.SetFlag(userFlagReadOnly, true)
+	u.SetFlag(userFlagMustChangePassword, true)
+	u.SetFlag("unknown", true)
+	if u.Flags != userFlagMustChangePassword+","+userFlagReadOnly {
+		t.Fatalf("%s failed: unexpected flags [%s]", testName, u.Flags)
+	}
+	u.SetFlag(userFlagMustChangePassword, false)
+	if u.HasFlag(userFlagMustChangePassword) || !u.HasFlag(userFlagReadOnly) {
+		t.Fatalf("%s failed: unexpected flags [%s]", testName, u.Flags)
+	}
+
+	h := _newHarness(t)
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	const username, password = "bob@local", "B0bS3cr3t"
+	myReg.userDao.Create(username, encryptPassword(username, password), "Bob", systemGroupId)
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	form := url.Values{"name": {"Bob"}, "group": {systemGroupId}, "flags": {userFlagMustChangePassword, userFlagReadOnly}}
+	h.AssertRedirect(h.PostForm(h.Reverse(actionNameCpEditUserSubmit)+"?u="+username, form), h.Reverse(actionNameCpUsers))
+	if user, _ := myReg.userDao.Get(username); user.Flags != userFlagMustChangePassword+","+userFlagReadOnly {
+		t.Fatalf("%s failed: unexpected flags [%s]", testName, user.Flags)
+	}
+	h.PostForm(h.Reverse(actionNameCpLogout), url.Values{})
+
+	// the password must be changed, to a different one, before using the control panel
+	h.Login(h.Reverse(actionNameCpLoginSubmit), username, password)
+	h.AssertRedirect(h.Get(h.Reverse(actionNameCpDashboard)), h.Reverse(actionNameCpProfile))
+	pwdForm := url.Values{"currentPassword": {password}, "password": {password}, "password2": {password}}
+	h.AssertBodyContains(h.PostForm(h.Reverse(actionNameCpChangePasswordSubmit), pwdForm), "New password must be different from the current one")
+	pwdForm.Set("password", "N3wB0bS3cr3t")
+	pwdForm.Set("password2", "N3wB0bS3cr3t")
+	h.AssertStatus(h.PostForm(h.Reverse(actionNameCpChangePasswordSubmit), pwdForm), http.StatusOK)
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpDashboard)), http.StatusOK)
+
+	// read-only users can browse but not submit changes
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpUsers)), http.StatusOK)
+	resp := h.PostForm(h.Reverse(actionNameCpCreateGroupSubmit), url.Values{"id": {"readers"}, "name": {"Readers"}})
+	h.AssertStatus(resp, http.StatusSeeOther)
+	if group, _ := myReg.groupDao.Get("readers"); group != nil {
+		t.Fatalf("%s failed: read-only user must not create groups", testName)
+	}
+	if user, _ := myReg.userDao.Get(username); user.Flags != userFlagReadOnly {
+		t.Fatalf("%s failed: expected flag [%s] to be cleared, got [%s]", testName, userFlagMustChangePassword, user.Flags)
+	}
+}
@@ -0,0 +1,107 @@
+package myapp
+
+import (
+	"errors"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Admins annotate user accounts with free-form notes (e.g. support history) and tags (e.g. "vip", "beta") to
+// categorize them; the user list can be filtered by tag. Both are edited on the form editing a user account, notes are
+// encrypted at rest along with names if field encryption is enabled (see encryptedUserDao).
+
+const (
+	// userNotesMaxLength is the maximum length, in characters, of notes on a user account.
+	userNotesMaxLength = 4000
+
+	// userTagsMax is the maximum number of tags on a user account, userTagMaxLength the maximum length of a tag: the
+	// comma-separated list must fit in 255 characters.
+	userTagsMax      = 10
+	userTagMaxLength = 24
+)
+
+var (
+	errUserTagsTooMany = errors.New("too many tags")
+	errUserTagInvalid  = errors.New("invalid tag")
+
+	reUserTag = regexp.MustCompile(`^[\p{L}\p{N}][\p{L}\p{N}_.\-]*$`)
+)
+
+// normalizeUserTags parses tags separated by commas into their canonical comma-separated form: lower-cased, spaces
+// inside a tag replaced by dashes, duplicates removed and sorted. Tags must start with a letter or a digit and contain
+// only letters, digits, dashes, underscores and dots.
+//
+// available since template-r5
+func normalizeUserTags(input string) (string, error) {
+	set := make(map[string]bool)
+	for _, tag := range strings.Split(input, ",") {
+		tag = strings.Join(strings.Fields(strings.ToLower(tag)), "-")
+		if tag == "" {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > userTagMaxLength || !reUserTag.MatchString(tag) {
+			return "", errUserTagInvalid
+		}
+		set[tag] = true
+	}
+	if len(set) > userTagsMax {
+		return "", errUserTagsTooMany
+	}
+	result := make([]string, 0, len(set))
+	for tag := range set {
+		result = append(result, tag)
+	}
+	sort.Strings(result)
+	return strings.Join(result, ","), nil
+}
+
+// filterUsersByTag returns users tagged with tag, all of them if tag is empty.
+func filterUsersByTag(users []*User, tag string) []*User {
+	if tag == "" {
+		return users
+	}
+	result := make([]*User, 0)
+	for _, u := range users {
+		if u.HasTag(tag) {
+			result = append(result, u)
+		}
+	}
+	return result
+}
+
+// userTagsInUse returns the distinct tags of users, sorted.
+func userTagsInUse(users []*User) []string {
+	set := make(map[string]bool)
+	for _, u := range users {
+		for _, tag := range u.TagList() {
+			set[tag] = true
+		}
+	}
+	result := make([]string, 0, len(set))
+	for tag := range set {
+		result = append(result, tag)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// userListData returns data of the user list: users, filtered by the tag of query parameter "tag" if any, and the tags
+// in use to filter by.
+func userListData(c echo.Context) map[string]interface{} {
+	tag := strings.ToLower(strings.TrimSpace(c.QueryParam("tag")))
+	users, err := getUserDao(c).GetAll()
+	if err != nil {
+		log.Printf("error while getting users: %s", err)
+		users = make([]*User, 0)
+	}
+	return map[string]interface{}{
+		"users":    toUserModelList(c, filterUsersByTag(users, tag)),
+		"userTags": userTagsInUse(users),
+		"tag":      tag,
+	}
+}
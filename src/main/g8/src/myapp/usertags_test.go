@@ -0,0 +1,81 @@
+package myapp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"main/src/goadmin"
+)
+
+func TestUserNotesTags(t *testing.T) {
+	testName := "TestUserNotesTags"
+	for input, expected := range map[string]string{"": "", " VIP , beta,vip,, ": "beta,vip", "Early Adopter": "early-adopter", "v1.2_x": "v1.2_x"} {
+		if tags, err := normalizeUserTags(input); err != nil || tags != expected {
+			t.Fatalf("%s failed: expected tags [%s] of [%s] but received [%s] (%v)", testName, expected, input, tags, err)
+		}
+	}
+	for _, input := range []string{"-vip", "vip!", strings.Repeat("x", userTagMaxLength+1), "a,b,c,d,e,f,g,h,i,j,k"} {
+		if _, err := normalizeUserTags(input); err == nil {
+			t.Fatalf("%s failed: tags [%s] must be rejected", testName, input)
+		}
+	}
+
+	h := _newHarness(t)
+	myReg := h.Registry.Get(namespace).(*myRegistry)
+	myReg.userDao.Create("alice@local", "", "Alice", systemGroupId)
+	myReg.userDao.Create("bob@local", "", "Bob", systemGroupId)
+	h.Login(h.Reverse(actionNameCpLoginSubmit), testAdminUsername, testAdminPassword)
+	form := url.Values{"name": {"Alice"}, "group": {systemGroupId}, "tags": {"VIP, beta"}, "notes": {"Refunded twice"}}
+	h.AssertRedirect(h.PostForm(h.Reverse(actionNameCpEditUserSubmit)+"?u=alice@local", form), h.Reverse(actionNameCpUsers))
+	if user, _ := myReg.userDao.Get("alice@local"); user.Tags != "beta,vip" || user.Notes != "Refunded twice" {
+		t.Fatalf("%s failed: unexpected notes and tags %#v", testName, user)
+	}
+	form.Set("notes", strings.Repeat("x", userNotesMaxLength+1))
+	h.AssertBodyContains(h.PostForm(h.Reverse(actionNameCpEditUserSubmit)+"?u=alice@local", form), "Notes must not be longer than")
+	form.Set("notes", "")
+	form.Set("tags", "vip!")
+	h.AssertBodyContains(h.PostForm(h.Reverse(actionNameCpEditUserSubmit)+"?u=alice@local", form), "At most 10 tags")
+	if user, _ := myReg.userDao.Get("alice@local"); user.Tags != "beta,vip" || user.Notes != "Refunded twice" {
+		t.Fatalf("%s failed: invalid notes and tags must not be saved, received %#v", testName, user)
+	}
+	h.AssertBodyContains(h.Get(h.Registry.UrlSigner.Sign(h.Reverse(actionNameCpEditUser)+"?u=alice@local")), "Refunded twice")
+
+	// the user list is filtered by tag
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpUsers)+"?tag=VIP"), http.StatusOK)
+	users, _ := h.LastData()["users"].([]*UserModel)
+	if len(users) != 1 || users[0].Username != "alice@local" {
+		t.Fatalf("%s failed: expected only alice@local but received %#v", testName, users)
+	}
+	if tags, _ := h.LastData()["userTags"].([]string); !reflect.DeepEqual(tags, []string{"beta", "vip"}) {
+		t.Fatalf("%s failed: unexpected tags in use %v", testName, tags)
+	}
+	h.AssertStatus(h.Get(h.Reverse(actionNameCpUsers)), http.StatusOK)
+	if users, _ := h.LastData()["users"].([]*UserModel); len(users) != 3 {
+		t.Fatalf("%s failed: expected all users without filter but received %d", testName, len(users))
+	}
+	resp := h.Get(h.Reverse(actionNameCpUsers) + "?format=json&tag=beta")
+	var doc map[string][]map[string]interface{}
+	if err := json.Unmarshal(resp.Body.Bytes(), &doc); err != nil || len(doc["users"]) != 1 {
+		t.Fatalf("%s failed: unexpected response %s / %v", testName, resp.Body.String(), err)
+	}
+	if u := doc["users"][0]; !reflect.DeepEqual(u["tags"], []interface{}{"beta", "vip"}) || u["notes"] != "Refunded twice" {
+		t.Fatalf("%s failed: unexpected JSON representation %v", testName, u)
+	}
+
+	// notes are encrypted at rest along with names
+	fc, _ := goadmin.NewFieldCipher(map[string][]byte{"k1": []byte("0123456789abcdef")}, "k1")
+	backend := newUserDaoMemory()
+	dao := &encryptedUserDao{UserDao: backend, fc: fc}
+	dao.Create("enc@local", "", "Name", systemGroupId)
+	dao.Update(&User{Username: "enc@local", Name: "Name", GroupId: systemGroupId, Notes: "Secret notes", Tags: "vip"})
+	if raw, _ := backend.Get("enc@local"); !strings.HasPrefix(raw.Notes, "enc:k1:") || raw.Tags != "vip" {
+		t.Fatalf("%s failed: notes must be encrypted at rest, tags must not: %#v", testName, raw)
+	}
+	if bo, err := dao.Get("enc@local"); err != nil || bo.Notes != "Secret notes" {
+		t.Fatalf("%s failed: unexpected user %#v (%v)", testName, bo, err)
+	}
+}
@@ -3,6 +3,7 @@ package myapp
 import (
 	"crypto/sha1"
 	"encoding/hex"
+	"fmt"
 	"log"
 	"math"
 	"net/http"
@@ -27,6 +28,7 @@ var (
 	systemUserName     = "Administrator" // reserved name for the "system" user
 )
 
+// prefixes that encoded severity of flash messages before template-r5, see AddFlash
 const (
 	flashPrefixInfo    = "_I_:"
 	flashPrefixWarning = "_W_:"
@@ -42,6 +44,27 @@ func isValidLocale(localeId string, i18n goyai.I18n) bool {
 	return false
 }
 
+// detectLocale returns the locale of the current request: the one stored in cookie if valid, otherwise the best
+// match of the Accept-Language header. Empty string is returned if none matches (i.e. the default locale is used).
+//
+// available since template-r5
+func detectLocale(c echo.Context, i18n goyai.I18n) string {
+	if locale := getCookieString(c, cookieLocale); isValidLocale(locale, i18n) {
+		return locale
+	}
+	for _, lang := range strings.Split(c.Request().Header.Get("Accept-Language"), ",") {
+		// e.g. "vi-VN;q=0.9": quality values are ignored, browsers list languages by preference
+		lang = strings.TrimSpace(strings.SplitN(lang, ";", 2)[0])
+		if isValidLocale(lang, i18n) {
+			return lang
+		}
+		if primary := strings.SplitN(lang, "-", 2)[0]; isValidLocale(primary, i18n) {
+			return primary
+		}
+	}
+	return ""
+}
+
 func getSession(c echo.Context) *sessions.Session {
 	sess, _ := session.Get(namespace, c)
 	return sess
@@ -57,10 +80,18 @@ func setSessionValue(c echo.Context, key string, value interface{}) {
 	sess.Save(c.Request(), c.Response())
 }
 
-func addFlashMsg(c echo.Context, msg string) {
-	sess := getSession(c)
-	sess.AddFlash(msg)
-	sess.Save(c.Request(), c.Response())
+// templateDataOf builds template data of an i18n message from pairs of keys and values, nil if there is no pair.
+//
+// available since template-r5
+func templateDataOf(args ...interface{}) map[string]interface{} {
+	if len(args) < 2 {
+		return nil
+	}
+	result := make(map[string]interface{}, len(args)/2)
+	for i := 0; i+1 < len(args); i += 2 {
+		result[fmt.Sprint(args[i])] = args[i+1]
+	}
+	return result
 }
 
 func encryptPassword(salt, rawPassword string) string {
@@ -75,7 +106,7 @@ func getCurrentUser(c echo.Context) (*User, error) {
 		uid, _ = reddo.ToString(uid)
 		if uid != nil {
 			username := uid.(string)
-			return userDao.Get(username)
+			return getUserDao(c).Get(username)
 		}
 	}
 	return nil, nil
@@ -204,7 +235,7 @@ type MyAppUtils struct {
 }
 
 func (u *MyAppUtils) NumUserGroups() int {
-	if groupList, err := groupDao.GetAll(); err != nil {
+	if groupList, err := getRegistry(u.c).allGroups(); err != nil {
 		log.Printf("error while getting user groups: %e", err)
 		return -1
 	} else {
@@ -212,8 +243,10 @@ func (u *MyAppUtils) NumUserGroups() int {
 	}
 }
 
+// AllUserGroups returns all groups, e.g. to populate group selects. Groups are read from the group snapshot (setting
+// myapp.group_snapshot_ttl), so that forms are rendered without querying the database.
 func (u *MyAppUtils) AllUserGroups() []*GroupModel {
-	if groupList, err := groupDao.GetAll(); err != nil {
+	if groupList, err := getRegistry(u.c).allGroups(); err != nil {
 		log.Printf("error while getting user groups: %e", err)
 		return make([]*GroupModel, 0)
 	} else {
@@ -222,7 +255,7 @@ func (u *MyAppUtils) AllUserGroups() []*GroupModel {
 }
 
 func (u *MyAppUtils) NumUsers() int {
-	if userList, err := userDao.GetAll(); err != nil {
+	if userList, err := getUserDao(u.c).GetAll(); err != nil {
 		log.Printf("error while getting users: %e", err)
 		return -1
 	} else {
@@ -231,10 +264,51 @@ func (u *MyAppUtils) NumUsers() int {
 }
 
 func (u *MyAppUtils) AllUsers() []*UserModel {
-	if userList, err := userDao.GetAll(); err != nil {
+	if userList, err := getUserDao(u.c).GetAll(); err != nil {
 		log.Printf("error while getting users: %e", err)
 		return make([]*UserModel, 0)
 	} else {
 		return toUserModelList(u.c, userList)
 	}
 }
+
+// Notifications returns notifications of the current user, newest first.
+//
+// available since template-r5
+func (u *MyAppUtils) Notifications() []*NotificationModel {
+	currentUser, _ := u.c.Get(ctxCurrentUser).(*User)
+	if currentUser == nil {
+		return make([]*NotificationModel, 0)
+	}
+	if list, err := getRegistry(u.c).notifications(currentUser.Username); err != nil {
+		log.Printf("error while getting notifications: %e", err)
+		return make([]*NotificationModel, 0)
+	} else {
+		return toNotificationModelList(u.c, list)
+	}
+}
+
+// NumUnreadNotifications returns the number of unread notifications of the current user.
+//
+// available since template-r5
+func (u *MyAppUtils) NumUnreadNotifications() int {
+	count := 0
+	for _, n := range u.Notifications() {
+		if !n.Read {
+			count++
+		}
+	}
+	return count
+}
+
+// clientOrigin returns IP address of the client, followed by its location (e.g. "1.2.3.4 (Hanoi, Vietnam)") if GeoIP
+// lookup is enabled (setting goadmin.geoip.db_path).
+//
+// available since template-r5
+func clientOrigin(c echo.Context) string {
+	ip := c.RealIP()
+	if loc := getRegistry(c).GeoIp.Describe(ip); loc != "" {
+		return ip + " (" + loc + ")"
+	}
+	return ip
+}
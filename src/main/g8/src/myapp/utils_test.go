@@ -0,0 +1,106 @@
+package myapp
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"main/src/apptest"
+	"main/src/goadmin"
+)
+
+// _mmdbString encodes a short string in MaxMind DB format.
+func _mmdbString(s string) []byte {
+	return append([]byte{0x40 | byte(len(s))}, s...)
+}
+
+// _mmdbMap encodes a map (pairs of encoded keys and values) in MaxMind DB format.
+func _mmdbMap(pairs ...[]byte) []byte {
+	result := []byte{0xE0 | byte(len(pairs)/2)}
+	for _, p := range pairs {
+		result = append(result, p...)
+	}
+	return result
+}
+
+// _writeGeoIpDb writes a MaxMind DB file (IPv4, 24-bit records) that locates network 1.2.3.0/24 in Hanoi, Vietnam.
+func _writeGeoIpDb(t *testing.T) string {
+	prefix := []byte{1, 2, 3}
+	nodeCount := len(prefix) * 8
+	tree := make([]byte, 0, nodeCount*6)
+	for i := 0; i < nodeCount; i++ {
+		next, other := i+1, nodeCount // other branch: not found
+		if i == nodeCount-1 {
+			next = nodeCount + 16 // pointer to the first record of the data section
+		}
+		left, right := next, other
+		if prefix[i/8]>>(7-uint(i%8))&1 == 1 {
+			left, right = other, next
+		}
+		tree = append(tree, byte(left>>16), byte(left>>8), byte(left), byte(right>>16), byte(right>>8), byte(right))
+	}
+	record := _mmdbMap(
+		_mmdbString("country"), _mmdbMap(_mmdbString("iso_code"), _mmdbString("VN"), _mmdbString("names"), _mmdbMap(_mmdbString("en"), _mmdbString("Vietnam"))),
+		_mmdbString("city"), _mmdbMap(_mmdbString("names"), _mmdbMap(_mmdbString("en"), _mmdbString("Hanoi"))),
+	)
+	metadata := _mmdbMap(
+		_mmdbString("node_count"), []byte{0xC1, byte(nodeCount)},
+		_mmdbString("record_size"), []byte{0xA1, 24},
+		_mmdbString("ip_version"), []byte{0xA1, 4},
+	)
+	data := append(tree, make([]byte, 16)...)
+	data = append(data, record...)
+	data = append(data, "\xAB\xCD\xEFMaxMind.com"...)
+	data = append(data, metadata...)
+	path := filepath.Join(t.TempDir(), "geoip.mmdb")
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("error writing GeoIP database: %s", err)
+	}
+	return path
+}
+
+func TestGeoIp_Lookup(t *testing.T) {
+	geoIp, err := goadmin.OpenGeoIp(_writeGeoIpDb(t))
+	if err != nil {
+		t.Fatalf("TestGeoIp_Lookup failed: %s", err)
+	}
+	loc, err := geoIp.Lookup("1.2.3.4")
+	if err != nil || loc == nil {
+		t.Fatalf("TestGeoIp_Lookup failed: %#v/%s", loc, err)
+	}
+	if loc.CountryCode != "VN" || loc.CountryName("vi") != "Vietnam" || loc.String() != "Hanoi, Vietnam" {
+		t.Fatalf("TestGeoIp_Lookup failed: unexpected location %#v", loc)
+	}
+	if loc, err := geoIp.Lookup("1.2.4.1"); err != nil || loc != nil {
+		t.Fatalf("TestGeoIp_Lookup failed: expected no location but received %#v/%s", loc, err)
+	}
+	if _, err := geoIp.Lookup("not-an-ip"); err == nil {
+		t.Fatalf("TestGeoIp_Lookup failed: expected error for invalid IP address")
+	}
+}
+
+func TestClientOrigin_GeoIp(t *testing.T) {
+	// test requests come from 192.0.2.1, trusted as a reverse proxy
+	conf := apptest.SqliteInMemoryConfig + "\ngoadmin.geoip.db_path = \"" + filepath.ToSlash(_writeGeoIpDb(t)) + "\"\nhttp.trusted_proxies = [\"192.0.2.1\"]\n"
+	h := apptest.New(t, conf, NewBootstrapper(nil, nil))
+	buf := &bytes.Buffer{}
+	log.SetOutput(buf)
+	defer log.SetOutput(os.Stderr)
+
+	form := url.Values{"username": {testAdminUsername}, "password": {testAdminPassword}}
+	req := httptest.NewRequest(http.MethodPost, h.Reverse(actionNameCpLoginSubmit), strings.NewReader(form.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	req.Header.Set(echo.HeaderXForwardedFor, "1.2.3.4")
+	h.Do(req)
+	if !strings.Contains(buf.String(), "signed in from 1.2.3.4 (Hanoi, Vietnam)") {
+		t.Fatalf("TestClientOrigin_GeoIp failed: login record is not enriched with location\n%s", buf.String())
+	}
+}
@@ -8,10 +8,12 @@ package utils
 
 import (
 	"bytes"
+	"fmt"
 	"math/rand"
 	"net"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	olaf2 "github.com/btnguyen2k/consu/olaf"
@@ -46,22 +48,104 @@ func getMacAddrAsLong() int64 {
 var olaf = olaf2.NewOlaf(getMacAddrAsLong())
 
 // UniqueId generates a unique id.
+//
+// After SeedRandom, ids are taken from a counter instead: the same sequence of ids is generated on every run.
 func UniqueId() string {
+	if n, ok := nextSeededId(); ok {
+		return fmt.Sprintf("%032x", n)
+	}
 	return olaf.Id128Hex()
 }
 
 // UniqueIdSmall generates a unique id, shorter length than which is generated by UniqueId.
 func UniqueIdSmall() string {
+	if n, ok := nextSeededId(); ok {
+		return strconv.FormatUint(n, 36)
+	}
 	return olaf.Id64Ascii()
 }
 
 const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 
 // RandomString generates a random string with specified length.
+//
+// Strings are not suitable for secrets (use crypto/rand), and are reproducible after SeedRandom.
 func RandomString(l int) string {
 	b := make([]byte, l)
+	randomLock.Lock()
+	defer randomLock.Unlock()
 	for i := range b {
-		b[i] = letters[rand.Intn(len(letters))]
+		b[i] = letters[random.Intn(len(letters))]
 	}
 	return string(b)
 }
+
+/*----------------------------------------------------------------------*/
+
+// Clock and random sources can be made deterministic, so that end-to-end tests against rendered pages are reproducible
+// (see setting goadmin.e2e): timestamps are taken from Now, which returns a fixed time after FreezeClock, and random
+// strings and ids are reproducible after SeedRandom.
+//
+// Durations (e.g. timeouts, cache expiry, request timing) keep being measured with the real clock.
+
+var (
+	clockLock  sync.RWMutex
+	frozenTime time.Time
+
+	randomLock sync.Mutex
+	random     = rand.New(rand.NewSource(time.Now().UnixNano()))
+	seeded     bool
+	lastId     uint64
+)
+
+// Now returns the current time, or the time the clock is frozen at (see FreezeClock).
+//
+// Available since template-r5
+func Now() time.Time {
+	clockLock.RLock()
+	defer clockLock.RUnlock()
+	if !frozenTime.IsZero() {
+		return frozenTime
+	}
+	return time.Now()
+}
+
+// FreezeClock makes Now return t from now on, zero value to unfreeze the clock.
+//
+// Available since template-r5
+func FreezeClock(t time.Time) {
+	clockLock.Lock()
+	defer clockLock.Unlock()
+	frozenTime = t
+}
+
+// SeedRandom restarts RandomString from a seed, and makes UniqueId and UniqueIdSmall return ids from a counter
+// restarted at 1: the same strings and ids are generated in the same order after every call with the same seed.
+//
+// Available since template-r5
+func SeedRandom(seed int64) {
+	randomLock.Lock()
+	defer randomLock.Unlock()
+	random = rand.New(rand.NewSource(seed))
+	seeded, lastId = true, 0
+}
+
+// RestoreRandom undoes SeedRandom: random strings are seeded from the current time and ids are unique again.
+//
+// Available since template-r5
+func RestoreRandom() {
+	randomLock.Lock()
+	defer randomLock.Unlock()
+	random = rand.New(rand.NewSource(time.Now().UnixNano()))
+	seeded = false
+}
+
+func nextSeededId() (uint64, bool) {
+	randomLock.Lock()
+	defer randomLock.Unlock()
+	if !seeded {
+		return 0, false
+	}
+	lastId++
+	return lastId, true
+}